@@ -25,11 +25,18 @@ all VolumeAttachment objects associated with that node are removed, thereby indi
 
 No unnecessary delay is added to the termination workflow, as the PreStop hook logic is only executed when the node is being drained
 (thus preventing delays in termination where the node pod is killed due to a rolling restart, or during driver upgrades, but the workload pods are expected to be running).
-If the PreStop hook hangs during its execution, the driver node pod will be forcefully terminated after 32 seconds (30s terminationGracePeriodSeconds + 2 second grace period extension from Kubelet).
+A node is being drained when it is cordoned, or when it has one of the drainTaints that node autoscalers (e.g. Karpenter or Cluster Autoscaler)
+add to the nodes they are about to terminate.
+If the PreStop hook hangs during its execution, the driver node pod will be forcefully terminated after its terminationGracePeriodSeconds
+plus a 2 second grace period extension from Kubelet, so timeout should be shorter than terminationGracePeriodSeconds.
 */
 
-func PreStop(clientset kubernetes.Interface, timeout time.Duration) error {
+func PreStop(clientset kubernetes.Interface, timeout time.Duration, drainTaints []string) error {
 	klog.InfoS("PreStop: executing PreStop lifecycle hook", "timeout", timeout)
+	if timeout <= 0 {
+		klog.InfoS("PreStop: timeout is 0, skipping VolumeAttachments check")
+		return nil
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -44,7 +51,7 @@ func PreStop(clientset kubernetes.Interface, timeout time.Duration) error {
 		return err
 	}
 
-	if isNodeBeingDrained(node) {
+	if isNodeBeingDrained(node, drainTaints) {
 		klog.InfoS("PreStop: node is being drained, checking for remaining VolumeAttachments", "node", nodeName)
 		return waitForVolumeAttachments(ctx, clientset, nodeName)
 	}
@@ -61,11 +68,17 @@ func fetchNode(ctx context.Context, clientset kubernetes.Interface, nodeName str
 	return node, nil
 }
 
-func isNodeBeingDrained(node *v1.Node) bool {
+func isNodeBeingDrained(node *v1.Node, drainTaints []string) bool {
 	for _, taint := range node.Spec.Taints {
 		if taint.Key == v1.TaintNodeUnschedulable && taint.Effect == v1.TaintEffectNoSchedule {
 			return true
 		}
+		for _, key := range drainTaints {
+			if taint.Key == key {
+				klog.V(4).InfoS("isNodeBeingDrained: node has drain taint", "node", node.Name, "taint", taint.Key)
+				return true
+			}
+		}
 	}
 	return false
 }
@@ -15,10 +15,11 @@ import (
 
 func TestPreStopHook(t *testing.T) {
 	testCases := []struct {
-		name     string
-		nodeName string
-		expErr   error
-		mockFunc func(string, *driver.MockKubernetesClient, *driver.MockCoreV1Interface, *driver.MockNodeInterface, *driver.MockVolumeAttachmentInterface, *driver.MockStorageV1Interface) error
+		name        string
+		nodeName    string
+		drainTaints []string
+		expErr      error
+		mockFunc    func(string, *driver.MockKubernetesClient, *driver.MockCoreV1Interface, *driver.MockNodeInterface, *driver.MockVolumeAttachmentInterface, *driver.MockStorageV1Interface) error
 	}{
 		{
 			name:     "TestPreStopHook: CSI_NODE_NAME not set",
@@ -81,6 +82,71 @@ func TestPreStopHook(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:        "TestPreStopHook: node is not being drained, skipping VolumeAttachments check - taint is not a drain taint",
+			nodeName:    "test-node",
+			drainTaints: []string{"ToBeDeletedByClusterAutoscaler"},
+			expErr:      nil,
+			mockFunc: func(nodeName string, mockClient *driver.MockKubernetesClient, mockCoreV1 *driver.MockCoreV1Interface, mockNode *driver.MockNodeInterface, mockStorageV1 *driver.MockVolumeAttachmentInterface, mockStorageV1Interface *driver.MockStorageV1Interface) error {
+				mockNodeObj := &v1.Node{
+					Spec: v1.NodeSpec{
+						Taints: []v1.Taint{
+							{
+								Key:    "karpenter.sh/disrupted",
+								Effect: v1.TaintEffectNoSchedule,
+							},
+						},
+					},
+				}
+
+				mockClient.EXPECT().CoreV1().Return(mockCoreV1).Times(1)
+				mockCoreV1.EXPECT().Nodes().Return(mockNode).Times(1)
+				mockNode.EXPECT().Get(gomock.Any(), gomock.Eq(nodeName), gomock.Any()).Return(mockNodeObj, nil).Times(1)
+
+				return nil
+			},
+		},
+		{
+			name:        "TestPreStopHook: node has drain taint, volume attachments remain -- timeout exceeded",
+			nodeName:    "test-node",
+			drainTaints: []string{"ToBeDeletedByClusterAutoscaler", "karpenter.sh/disrupted"},
+			expErr:      fmt.Errorf("waitForVolumeAttachments: timed out waiting for preStopHook to complete: context deadline exceeded"),
+			mockFunc: func(nodeName string, mockClient *driver.MockKubernetesClient, mockCoreV1 *driver.MockCoreV1Interface, mockNode *driver.MockNodeInterface, mockVolumeAttachments *driver.MockVolumeAttachmentInterface, mockStorageV1Interface *driver.MockStorageV1Interface) error {
+
+				fakeNode := &v1.Node{
+					Spec: v1.NodeSpec{
+						Taints: []v1.Taint{
+							{
+								Key:    "karpenter.sh/disrupted",
+								Effect: v1.TaintEffectNoSchedule,
+							},
+						},
+					},
+				}
+
+				fakeVolumeAttachments := &storagev1.VolumeAttachmentList{
+					Items: []storagev1.VolumeAttachment{
+						{
+							Spec: storagev1.VolumeAttachmentSpec{
+								NodeName: "test-node",
+							},
+						},
+					},
+				}
+
+				mockClient.EXPECT().CoreV1().Return(mockCoreV1).AnyTimes()
+				mockClient.EXPECT().StorageV1().Return(mockStorageV1Interface).AnyTimes()
+
+				mockCoreV1.EXPECT().Nodes().Return(mockNode).AnyTimes()
+				mockNode.EXPECT().Get(gomock.Any(), gomock.Eq(nodeName), gomock.Any()).Return(fakeNode, nil).AnyTimes()
+
+				mockStorageV1Interface.EXPECT().VolumeAttachments().Return(mockVolumeAttachments).AnyTimes()
+				mockVolumeAttachments.EXPECT().List(gomock.Any(), gomock.Any()).Return(fakeVolumeAttachments, nil).AnyTimes()
+				mockVolumeAttachments.EXPECT().Watch(gomock.Any(), gomock.Any()).Return(watch.NewFake(), nil).AnyTimes()
+
+				return nil
+			},
+		},
 		{
 			name:     "TestPreStopHook: node is being drained, no volume attachments remain",
 			nodeName: "test-node",
@@ -274,7 +340,7 @@ func TestPreStopHook(t *testing.T) {
 				t.Setenv("CSI_NODE_NAME", tc.nodeName)
 			}
 
-			err := PreStop(mockClient, 5*time.Second)
+			err := PreStop(mockClient, 5*time.Second, tc.drainTaints)
 
 			if tc.expErr != nil {
 				assert.Error(t, err)
@@ -285,3 +351,14 @@ func TestPreStopHook(t *testing.T) {
 		})
 	}
 }
+
+func TestPreStopHookDisabled(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	// The node is not even looked up without a timeout
+	mockClient := driver.NewMockKubernetesClient(mockCtl)
+	t.Setenv("CSI_NODE_NAME", "test-node")
+
+	assert.NoError(t, PreStop(mockClient, 0, nil))
+}
@@ -18,10 +18,15 @@ package main
 
 import (
 	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	flag "github.com/spf13/pflag"
 
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	logsapi "k8s.io/component-base/logs/api/v1"
@@ -73,14 +78,114 @@ func main() {
 		driver.WithWarnOnInvalidTag(options.ControllerOptions.WarnOnInvalidTag),
 		driver.WithUserAgentExtra(options.ControllerOptions.UserAgentExtra),
 		driver.WithOtelTracing(options.ServerOptions.EnableOtelTracing),
+		driver.WithRPCSummaryLogLevel(options.ServerOptions.RPCSummaryLogLevel),
+		driver.WithMetadataSources(options.ServerOptions.MetadataSources),
+		driver.WithIMDSVersion(options.ServerOptions.IMDSVersion),
+		driver.WithEndpointOptions(cloud.EndpointOptions{
+			EC2Endpoint:           options.ServerOptions.EC2Endpoint,
+			RegionalEC2Endpoints:  options.ServerOptions.RegionalEC2Endpoints,
+			UseFIPSEndpoints:      options.ServerOptions.UseFIPSEndpoints,
+			UseDualStackEndpoints: options.ServerOptions.UseDualStackEndpoints,
+		}),
 		driver.WithBatching(options.ControllerOptions.Batching),
+		driver.WithBatchingWindow(options.ControllerOptions.BatchingWindow),
+		driver.WithMetadataRetryAttempts(options.NodeOptions.MetadataRetryAttempts),
+		driver.WithKMSAccessCheck(options.ControllerOptions.KMSAccessCheck),
+		driver.WithKMSAccessCheckFailOpen(options.ControllerOptions.KMSAccessCheckFailOpen),
+		driver.WithShutdownGracePeriod(options.ControllerOptions.ShutdownGracePeriod),
+		driver.WithVolumeSizeGranularity(options.ControllerOptions.VolumeSizeGranularity),
+		driver.WithSnapshotPVCNameTag(options.ControllerOptions.SnapshotPVCNameTag),
+		driver.WithMaxConcurrentAttaches(options.ControllerOptions.MaxConcurrentAttaches),
+		driver.WithFSRWarmSnapshots(options.ControllerOptions.FSRWarmSnapshots),
+		driver.WithFSRWarmCacheInterval(options.ControllerOptions.FSRWarmCacheInterval),
+		driver.WithFSRWaitTimeout(options.ControllerOptions.FSRWaitTimeout),
+		driver.WithMaintenanceMode(options.ControllerOptions.MaintenanceMode),
+		driver.WithAdminEndpoint(options.ControllerOptions.AdminEndpoint),
+		driver.WithAttachRetryBudget(options.ControllerOptions.AttachRetryBudget),
+		driver.WithAttachRetryDeadline(options.ControllerOptions.AttachRetryDeadline),
+		driver.WithValidateStorageClasses(options.ControllerOptions.ValidateStorageClasses),
+		driver.WithDropExcessTags(options.ControllerOptions.DropExcessTags),
+		driver.WithCreateVolumeRetries(options.ControllerOptions.CreateVolumeRetries),
+		driver.WithKubernetesAPIRetryAttempts(options.ControllerOptions.KubernetesAPIRetryAttempts),
+		driver.WithDeviceNameLeaseTimeout(options.ControllerOptions.DeviceNameLeaseTimeout),
+		driver.WithForceDetachTimeout(options.ControllerOptions.ForceDetachTimeout),
+		driver.WithEC2RateLimits(cloud.RateLimits{
+			MutatingQPS:   options.ControllerOptions.EC2MutatingQPS,
+			MutatingBurst: options.ControllerOptions.EC2MutatingBurst,
+			ReadOnlyQPS:   options.ControllerOptions.EC2ReadOnlyQPS,
+			ReadOnlyBurst: options.ControllerOptions.EC2ReadOnlyBurst,
+		}),
+		driver.WithNodeOperationWorkers(options.ControllerOptions.NodeOperationWorkers),
+		driver.WithRequestCacheTTL(options.ControllerOptions.RequestCacheTTL),
+		driver.WithDescribeCacheTTL(options.ControllerOptions.DescribeCacheTTL),
+		driver.WithRoleOptions(cloud.RoleOptions{
+			RoleARN:     options.ControllerOptions.AWSRoleARN,
+			ExternalID:  options.ControllerOptions.AWSRoleExternalID,
+			SessionName: cloud.RoleSessionName(options.ControllerOptions.KubernetesClusterID),
+		}),
+		driver.WithReportOrphanedVolumes(options.ControllerOptions.ReportOrphanedVolumes),
+		driver.WithVolumePlacementStrategy(options.ControllerOptions.VolumePlacementStrategy),
+		driver.WithDefaultMountOptions(options.ControllerOptions.DefaultMountOptions),
+		driver.WithDiscardVolumeTypes(options.ControllerOptions.DiscardVolumeTypes),
+		driver.WithLeaderElection(driver.LeaderElectionOptions{
+			Enabled:       options.ControllerOptions.LeaderElection,
+			Namespace:     options.ControllerOptions.LeaderElectionNamespace,
+			LeaseName:     options.ControllerOptions.LeaderElectionLeaseName,
+			LeaseDuration: options.ControllerOptions.LeaderElectionLeaseDuration,
+			RenewDeadline: options.ControllerOptions.LeaderElectionRenewDeadline,
+			RetryPeriod:   options.ControllerOptions.LeaderElectionRetryPeriod,
+		}),
+		driver.WithAvailabilityZoneOverride(options.NodeOptions.AvailabilityZoneOverride),
+		driver.WithMountNamespace(options.NodeOptions.MountNamespace),
+		driver.WithDeviceSizeCheckTimeout(options.NodeOptions.DeviceSizeCheckTimeout),
+		driver.WithDeviceAttachTimeout(options.NodeOptions.DeviceAttachTimeout),
+		driver.WithDeviceReattachTimeout(options.NodeOptions.DeviceReattachTimeout),
+		driver.WithSCSIFallbackWait(options.NodeOptions.SCSIFallbackWait),
+		driver.WithUnmountDetachedVolumes(options.NodeOptions.UnmountDetachedVolumes),
+		driver.WithReportIOUtilization(options.NodeOptions.ReportIOUtilization),
+		driver.WithResolveDevicesByUUID(options.NodeOptions.ResolveDevicesByUUID),
+		driver.WithResizeFilesystemOnStage(options.NodeOptions.ResizeFilesystemOnStage),
+		driver.WithCloudProvider(options.ServerOptions.CloudProvider),
+		driver.WithFakeCloudOptions(cloud.FakeCloudOptions{
+			Region:          os.Getenv("AWS_REGION"),
+			Latency:         options.ServerOptions.FakeCloudLatency,
+			TransitionDelay: options.ServerOptions.FakeCloudTransitionDelay,
+			Errors:          fakeCloudErrors(options.ServerOptions.FakeCloudErrors),
+		}),
 	)
 	if err != nil {
 		klog.ErrorS(err, "failed to create driver")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
+	preStopOnSIGTERM := options.DriverMode != driver.ControllerMode && options.NodeOptions.PreStopOnSIGTERM
+	if options.ControllerOptions.ShutdownGracePeriod > 0 || preStopOnSIGTERM {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		go func() {
+			sig := <-signals
+			if preStopOnSIGTERM && sig == syscall.SIGTERM {
+				// The driver keeps serving requests, e.g. to unstage volumes, while it waits
+				klog.InfoS("Received signal, waiting for VolumeAttachments of the node before stopping driver", "signal", sig)
+				if err := preStop(options.NodeOptions); err != nil {
+					klog.ErrorS(err, "failed to wait for VolumeAttachments of the node")
+				}
+			}
+			klog.InfoS("Received signal, stopping driver", "signal", sig)
+			drv.Stop()
+		}()
+	}
 	if err := drv.Run(); err != nil {
 		klog.ErrorS(err, "failed to run driver")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 }
+
+// fakeCloudErrors returns the errors of the fake cloud provider by method from
+// their messages.
+func fakeCloudErrors(messages map[string]string) map[string]error {
+	errs := make(map[string]error, len(messages))
+	for method, message := range messages {
+		errs[method] = errors.New(message)
+	}
+	return errs
+}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
 
 	flag "github.com/spf13/pflag"
 
@@ -34,10 +33,6 @@ import (
 	"k8s.io/klog/v2"
 )
 
-const (
-	preStopTimeout = 30 * time.Second
-)
-
 // Options is the combined set of options for all operating modes.
 type Options struct {
 	DriverMode driver.Mode
@@ -75,6 +70,12 @@ func GetOptions(fs *flag.FlagSet) *Options {
 	if err != nil {
 		klog.ErrorS(err, "failed to add feature gates")
 	}
+	// The RPCs pass loggers with their request ID down to the EC2 calls in
+	// their contexts
+	err = featureGate.SetFromMap(map[string]bool{string(logsapi.ContextualLogging): true})
+	if err != nil {
+		klog.ErrorS(err, "failed to enable contextual logging")
+	}
 
 	logsapi.AddFlags(c, fs)
 
@@ -103,15 +104,17 @@ func GetOptions(fs *flag.FlagSet) *Options {
 			args = os.Args[1:]
 
 		case cmd == "pre-stop-hook":
-			clientset, clientErr := cloud.DefaultKubernetesAPIClient()
-			if clientErr != nil {
-				klog.ErrorS(err, "unable to communicate with k8s API")
-			} else {
-				err = hooks.PreStop(clientset, preStopTimeout)
-				if err != nil {
-					klog.ErrorS(err, "failed to execute PreStop lifecycle hook")
-					klog.FlushAndExit(klog.ExitFlushTimeout, 1)
-				}
+			nodeOptions.AddFlags(fs)
+			if err = fs.Parse(os.Args[2:]); err != nil {
+				panic(err)
+			}
+			if err = nodeOptions.Validate(); err != nil {
+				klog.Error(err.Error())
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+			}
+			if err = preStop(&nodeOptions); err != nil {
+				klog.ErrorS(err, "failed to execute PreStop lifecycle hook")
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 			}
 			klog.FlushAndExit(klog.ExitFlushTimeout, 0)
 
@@ -160,3 +163,14 @@ func GetOptions(fs *flag.FlagSet) *Options {
 		NodeOptions:       &nodeOptions,
 	}
 }
+
+// preStop waits, if the node is being drained, for the VolumeAttachments of
+// the node to be deleted, as configured by nodeOptions.
+func preStop(nodeOptions *options.NodeOptions) error {
+	clientset, err := cloud.DefaultKubernetesAPIClient()
+	if err != nil {
+		klog.ErrorS(err, "unable to communicate with k8s API")
+		return nil
+	}
+	return hooks.PreStop(clientset, nodeOptions.PreStopTimeout, nodeOptions.PreStopDrainTaints)
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package options
 
 import (
+	"time"
+
 	flag "github.com/spf13/pflag"
 
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver"
 	cliflag "k8s.io/component-base/cli/flag"
 )
 
@@ -41,14 +45,130 @@ type ControllerOptions struct {
 	UserAgentExtra string
 	// flag to enable batching of API calls
 	Batching bool
+	// BatchingWindow is how long concurrent EC2 describe calls are collected into a single call.
+	BatchingWindow time.Duration
+	// flag to verify the node's instance role can use the volume's KMS key before attaching
+	KMSAccessCheck bool
+	// flag to attach volumes whose KMS key access check cannot be completed
+	KMSAccessCheckFailOpen bool
+	// ShutdownGracePeriod is how long in-flight attach/detach operations may run after a shutdown signal
+	ShutdownGracePeriod time.Duration
+	// VolumeSizeGranularity is the size in GiB that volume sizes are rounded up to a multiple of
+	VolumeSizeGranularity int64
+	// flag to tag snapshots with the PVC name tag of their source volume
+	SnapshotPVCNameTag bool
+	// MaxConcurrentAttaches is the maximum number of volumes the controller attaches at the same time
+	MaxConcurrentAttaches int
+	// FSRWarmSnapshots is the set of snapshot IDs that fast snapshot restores are kept enabled on
+	FSRWarmSnapshots []string
+	// FSRWarmCacheInterval is the interval between reconciliations of fast snapshot restores on FSRWarmSnapshots
+	FSRWarmCacheInterval time.Duration
+	// flag to start the controller in maintenance mode, rejecting mutating RPCs
+	MaintenanceMode bool
+	// AdminEndpoint is the TCP address of the HTTP server that toggles maintenance mode at runtime
+	AdminEndpoint string
+	// AttachRetryBudget is the number of failed attach attempts of a volume after which it is no longer attached
+	AttachRetryBudget int
+	// AttachRetryDeadline is how long after its first failed attach attempt a volume is no longer attached
+	AttachRetryDeadline time.Duration
+	// flag to validate the parameters of the driver's StorageClasses at startup
+	ValidateStorageClasses bool
+	// flag to drop the lowest priority tags of volumes with more tags than EC2 allows
+	DropExcessTags bool
+	// CreateVolumeRetries is the number of times CreateVolume retries to create a volume after a transient error
+	CreateVolumeRetries int
+	// KubernetesAPIRetryAttempts is the number of times the controller retries looking up Kubernetes objects
+	// when the Kubernetes API is transiently unavailable
+	KubernetesAPIRetryAttempts int
+	// DeviceNameLeaseTimeout is how long a device name reserved for an attach that is neither released nor confirmed stays reserved
+	DeviceNameLeaseTimeout time.Duration
+	// EC2MutatingQPS and EC2MutatingBurst limit the rate of mutating EC2 API requests
+	EC2MutatingQPS   float64
+	EC2MutatingBurst int
+	// EC2ReadOnlyQPS and EC2ReadOnlyBurst limit the rate of read-only EC2 API requests
+	EC2ReadOnlyQPS   float64
+	EC2ReadOnlyBurst int
+	// ForceDetachTimeout is how long a volume may stay detaching before its detachment is forced
+	ForceDetachTimeout time.Duration
+	// NodeOperationWorkers is the number of nodes the controller attaches volumes to and detaches volumes from at the same time
+	NodeOperationWorkers int
+	// flag to report orphaned volumes of the cluster when the account reached its volume count limit
+	ReportOrphanedVolumes bool
+	// FSRWaitTimeout is how long CreateVolume waits for fast snapshot restores of the source snapshot to be enabled
+	FSRWaitTimeout time.Duration
+	// RequestCacheTTL is how long the results of completed CreateVolume, DeleteVolume and CreateSnapshot requests are remembered for retries
+	RequestCacheTTL time.Duration
+	// DescribeCacheTTL is how long described instances and volumes are cached
+	DescribeCacheTTL time.Duration
+	// AWSRoleARN is the ARN of the IAM role assumed to call AWS, e.g. in another account
+	AWSRoleARN string
+	// AWSRoleExternalID is the external ID passed to STS when assuming AWSRoleARN
+	AWSRoleExternalID string
+	// VolumePlacementStrategy chooses the availability zone of new volumes among the allowed ones
+	VolumePlacementStrategy string
+	// DefaultMountOptions are the mount options added to those of the volumes the controller creates
+	DefaultMountOptions []string
+	// DiscardVolumeTypes are the volume types whose volumes are mounted with the discard option by default
+	DiscardVolumeTypes []string
+	// LeaderElection enables the election of the replica that serves mutating requests
+	LeaderElection bool
+	// LeaderElectionNamespace is the namespace of the Lease of the leader election
+	LeaderElectionNamespace string
+	// LeaderElectionLeaseName is the name of the Lease of the leader election
+	LeaderElectionLeaseName string
+	// LeaderElectionLeaseDuration is how long non-leaders wait before trying to acquire the leadership
+	LeaderElectionLeaseDuration time.Duration
+	// LeaderElectionRenewDeadline is how long the leader retries renewing the leadership before giving it up
+	LeaderElectionRenewDeadline time.Duration
+	// LeaderElectionRetryPeriod is how long to wait between attempts to acquire or renew the leadership
+	LeaderElectionRetryPeriod time.Duration
 }
 
 func (s *ControllerOptions) AddFlags(fs *flag.FlagSet) {
-	fs.Var(cliflag.NewMapStringString(&s.ExtraTags), "extra-tags", "Extra tags to attach to each dynamically provisioned resource. It is a comma separated list of key value pairs like '<key1>=<value1>,<key2>=<value2>'")
+	fs.Var(cliflag.NewMapStringString(&s.ExtraTags), "extra-tags", "Extra tags to attach to each dynamically provisioned resource. It is a comma separated list of key value pairs like '<key1>=<value1>,<key2>=<value2>'. Values may refer to the PVC, e.g. '{{ .PVCNamespace }}', see docs/tagging.md")
 	fs.Var(cliflag.NewMapStringString(&s.ExtraVolumeTags), "extra-volume-tags", "DEPRECATED: Please use --extra-tags instead. Extra volume tags to attach to each dynamically provisioned volume. It is a comma separated list of key value pairs like '<key1>=<value1>,<key2>=<value2>'")
 	fs.StringVar(&s.KubernetesClusterID, "k8s-tag-cluster-id", "", "ID of the Kubernetes cluster used for tagging provisioned EBS volumes (optional).")
 	fs.BoolVar(&s.AwsSdkDebugLog, "aws-sdk-debug-log", false, "To enable the aws sdk debug log level (default to false).")
 	fs.BoolVar(&s.WarnOnInvalidTag, "warn-on-invalid-tag", false, "To warn on invalid tags, instead of returning an error")
 	fs.StringVar(&s.UserAgentExtra, "user-agent-extra", "", "Extra string appended to user agent.")
 	fs.BoolVar(&s.Batching, "batching", false, "To enable batching of API calls. This is especially helpful for improving performance in workloads that are sensitive to EC2 rate limits.")
+	fs.DurationVar(&s.BatchingWindow, "batching-window", cloud.DefaultBatchingWindow, "With --batching, how long concurrent DescribeVolumes and DescribeInstances calls are collected before they are made as a single call. Longer windows make fewer calls at the cost of latency.")
+	fs.BoolVar(&s.KMSAccessCheck, "kms-access-check", false, "To verify, before attaching an encrypted volume, that the target node's instance role is allowed to use the volume's KMS key. A denial fails the attachment with PermissionDenied, a check that cannot be completed, e.g. on throttling, with Unavailable so that it is retried. Requires iam:GetInstanceProfile, iam:SimulatePrincipalPolicy and kms:GetKeyPolicy permissions.")
+	fs.BoolVar(&s.KMSAccessCheckFailOpen, "kms-access-check-fail-open", false, "With --kms-access-check, to attach a volume anyway, logging an error, when its KMS key access check cannot be completed, e.g. for lack of permissions, instead of failing the attachment with Unavailable.")
+	fs.DurationVar(&s.ShutdownGracePeriod, "shutdown-grace-period", 0, "How long the controller waits, after receiving SIGTERM, for in-flight attach and detach operations to finish before aborting them. New attach and detach requests are rejected meanwhile. Disabled if 0.")
+	fs.Int64Var(&s.VolumeSizeGranularity, "volume-size-granularity", 0, "Size in GiB that CreateVolume rounds requested volume sizes up to a multiple of, as long as the result does not exceed the requested capacity limit. Volume sizes are rounded up to whole GiB if 0.")
+	fs.BoolVar(&s.SnapshotPVCNameTag, "snapshot-pvc-name-tag", false, "To tag each snapshot with the name of the PVC its source volume was provisioned for, as recorded in the volume's kubernetes.io/created-for/pvc/name tag.")
+	fs.IntVar(&s.MaxConcurrentAttaches, "max-concurrent-attaches", 0, "Maximum number of volumes the controller attaches at the same time. Further attach requests wait for a slot in the order they arrived. Unlimited if 0.")
+	fs.StringSliceVar(&s.FSRWarmSnapshots, "fsr-warm-snapshots", nil, "Comma separated list of snapshot IDs to keep fast snapshot restores enabled on in the availability zones of the cluster's nodes. Requires --fsr-warm-cache-interval.")
+	fs.DurationVar(&s.FSRWarmCacheInterval, "fsr-warm-cache-interval", 0, "Interval at which fast snapshot restores are enabled on the snapshots of --fsr-warm-snapshots in newly used availability zones, and disabled on snapshots removed from the list. Requires --k8s-tag-cluster-id. Disabled if 0.")
+	fs.DurationVar(&s.FSRWaitTimeout, "fsr-wait-timeout", 0, "How long CreateVolume waits, before restoring a volume from a snapshot whose fast snapshot restores are being enabled in the volume's availability zone, for them to be enabled, so that the volume is fully initialized at creation. The volume is restored without fast snapshot restores if they are not enabled in time. Requires ec2:DescribeFastSnapshotRestores. Disabled if 0.")
+	fs.BoolVar(&s.MaintenanceMode, "maintenance-mode", false, "To start the controller in maintenance mode, in which requests that create, delete, attach, detach, expand or modify volumes and snapshots fail with Unavailable. Read-only requests are served as usual. Can be toggled at runtime through --admin-endpoint.")
+	fs.StringVar(&s.AdminEndpoint, "admin-endpoint", "", "The TCP network address where the HTTP server for administrative requests, such as toggling maintenance mode at /maintenance or reporting the leadership at /leader, will listen (example: `127.0.0.1:8082`). The server is unauthenticated and must listen on a loopback address. The default is empty string, which means the server is disabled.")
+	fs.IntVar(&s.AttachRetryBudget, "attach-retry-budget", 0, "Number of attempts, across all nodes, to attach a volume that EC2 rejected or that did not complete in time, not counting throttled requests, AWS server and network errors, canceled requests or volumes not found, after which further attach requests for it fail with FailedPrecondition without calling AWS. The budget is restored when the volume attaches, or 10 minutes after its last failed attempt. Unlimited if 0.")
+	fs.DurationVar(&s.DeviceNameLeaseTimeout, "device-name-lease-timeout", 0, "How long a device name stays reserved on a node for an attach that was neither released nor confirmed, e.g. an attach that did not complete in time. Expired reservations are released the next time a volume is attached to or detached from the node. Reservations never expire if 0.")
+	fs.Float64Var(&s.EC2MutatingQPS, "ec2-mutating-qps", 0, "Maximum number of mutating EC2 API requests, such as CreateVolume or AttachVolume, per second, including retries. The rate is decreased when EC2 throttles requests and recovers as requests succeed. Unlimited if 0.")
+	fs.IntVar(&s.EC2MutatingBurst, "ec2-mutating-burst", 0, "Maximum number of mutating EC2 API requests sent at once within --ec2-mutating-qps. Defaults to --ec2-mutating-qps if 0.")
+	fs.Float64Var(&s.EC2ReadOnlyQPS, "ec2-read-only-qps", 0, "Maximum number of read-only EC2 API requests, such as DescribeVolumes, per second, including retries. The rate is decreased when EC2 throttles requests and recovers as requests succeed. Unlimited if 0.")
+	fs.IntVar(&s.EC2ReadOnlyBurst, "ec2-read-only-burst", 0, "Maximum number of read-only EC2 API requests sent at once within --ec2-read-only-qps. Defaults to --ec2-read-only-qps if 0.")
+	fs.DurationVar(&s.ForceDetachTimeout, "force-detach-timeout", 0, "How long a volume may stay in the detaching state, across ControllerUnpublishVolume calls, before its detachment is forced, e.g. because its instance is unreachable. A forced detachment does not give the instance the chance to flush its file system caches and may result in data loss. Detachments are never forced if 0.")
+	fs.IntVar(&s.NodeOperationWorkers, "node-operation-workers", 0, "To serialize the attachments and detachments of each node, as EC2 fails concurrent attach and detach requests of an instance with IncorrectState errors, and to run those of at most this many nodes at the same time. Further requests wait in the order they arrived. Not serialized if 0.")
+	fs.IntVar(&s.CreateVolumeRetries, "create-volume-retries", 0, "Number of times CreateVolume retries to create a volume after a transient error. Before retrying after an error that leaves unknown whether the volume was created, e.g. a timeout, the volume is looked up by name so that no duplicate is created, and a volume deleted because it did not become available is created again with another client token. Throttling and insufficient capacity errors are retried right away, invalid parameters and quota errors never. No retries if 0.")
+	fs.IntVar(&s.KubernetesAPIRetryAttempts, "kubernetes-api-retry-attempts", 0, "Number of times the controller retries looking up Kubernetes objects, such as Nodes, StorageClasses and PersistentVolumes, when the Kubernetes API is transiently unavailable, e.g. throttled or timing out. Permanent failures are never retried. No retries if 0.")
+	fs.BoolVar(&s.DropExcessTags, "drop-excess-tags", false, "To drop the lowest priority tags of volumes with more tags than the EC2 limit of 50 per resource, instead of failing CreateVolume. Tags of the driver are kept first, then tags with the kubernetes.io prefix, then all other tags in alphabetical order of their keys.")
+	fs.BoolVar(&s.ValidateStorageClasses, "validate-storage-classes", false, "To validate, at startup, the parameters of the StorageClasses of the driver with an EC2 CreateVolume dry run, and log the StorageClasses that volumes would fail to be provisioned with. Requires permissions to list StorageClasses, and kms:DescribeKey for StorageClasses with a kmsKeyId.")
+	fs.StringVar(&s.AWSRoleARN, "aws-role-arn", "", "ARN of an IAM role to assume to call AWS, e.g. to provision volumes in another account than the one of the cluster. The credentials of the controller must be allowed to assume the role, whose credentials are refreshed automatically. The role session name includes --k8s-tag-cluster-id, if set.")
+	fs.StringVar(&s.VolumePlacementStrategy, "volume-placement-strategy", driver.PlacementStrategyFirst, "How to choose the availability zone of a new volume among the zones allowed by its accessibility requirements: 'first' uses the first preferred zone, 'round-robin' uses the allowed zones in turn and 'least-used' uses the allowed zone the controller created the fewest volumes in since it started. If EC2 lacks the capacity for the volume in the chosen zone, the next allowed zone is tried.")
+	fs.StringSliceVar(&s.DefaultMountOptions, "default-mount-options", nil, "Comma separated list of mount options, e.g. 'noatime', that the volumes the controller creates are staged with in addition to the mount options of their StorageClass, unless those include the same or a conflicting option. Options the fstype of a volume does not support are left out for it.")
+	fs.StringSliceVar(&s.DiscardVolumeTypes, "discard-volume-types", nil, "Comma separated list of volume types, e.g. 'gp3,io2', whose volumes the controller creates are staged with the discard mount option, so that the blocks of deleted files are freed, unless the mount options of their StorageClass include nodiscard.")
+	fs.BoolVar(&s.LeaderElection, "leader-election", false, "To serve requests that create, delete, attach, detach, expand or modify volumes and snapshots only on the replica of the controller whose sidecar that sends them holds its Lease, so that several replicas can run without calling EC2 for the same operation, and to elect, among the replicas, the leader that runs background tasks. Other replicas fail these requests with Unavailable. Requires permissions to get, list, watch, create and update Leases.")
+	fs.StringVar(&s.LeaderElectionNamespace, "leader-election-namespace", "kube-system", "Namespace of the Lease of --leader-election and of the Leases of the sidecars.")
+	fs.StringVar(&s.LeaderElectionLeaseName, "leader-election-lease-name", "ebs-csi-controller", "Name of the Lease of --leader-election.")
+	fs.DurationVar(&s.LeaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second, "With --leader-election, how long replicas that are not the leader wait, after the leader last renewed the leadership, before trying to acquire it.")
+	fs.DurationVar(&s.LeaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second, "With --leader-election, how long the leader retries renewing the leadership before giving it up. It is also how long a stopping leader waits for its in-flight requests to finish before releasing the leadership.")
+	fs.DurationVar(&s.LeaderElectionRetryPeriod, "leader-election-retry-period", 5*time.Second, "With --leader-election, how long replicas wait between attempts to acquire or renew the leadership.")
+	fs.StringVar(&s.AWSRoleExternalID, "aws-role-external-id", "", "External ID to pass when assuming --aws-role-arn, if the trust policy of the role requires one.")
+	fs.DurationVar(&s.DescribeCacheTTL, "describe-cache-ttl", 0, "How long instances and volumes described by the controller are cached, so that e.g. repeated attachments of volumes to the same node do not describe the node again. The cache is kept up to date with the attachments, detachments, modifications and deletions of the driver; changes made outside the driver are seen once the cached descriptions expire. Nothing is cached if 0.")
+	fs.DurationVar(&s.RequestCacheTTL, "request-cache-ttl", 0, "How long the results of completed CreateVolume, DeleteVolume and CreateSnapshot requests are remembered, so that retries of the same request are answered without calling AWS again. Snapshots are remembered once ready to use. Results are not remembered if 0.")
+	fs.BoolVar(&s.ReportOrphanedVolumes, "report-orphaned-volumes", false, "To log and count in the ebs_csi_orphaned_volumes metric, when CreateVolume fails because the account reached its limit of volumes in the region, the available volumes tagged as owned by the cluster that no PersistentVolume references and that are older than an hour. They are not deleted. Requires --k8s-tag-cluster-id and permissions to list PersistentVolumes.")
+	fs.DurationVar(&s.AttachRetryDeadline, "attach-retry-deadline", 0, "How long after the first failed attempt, across all nodes, to attach a volume further attach requests for it fail with FailedPrecondition without calling AWS. The deadline is reset when the volume attaches, or 10 minutes after its last failed attempt. Unlimited if 0.")
 }
@@ -48,6 +48,201 @@ func TestControllerOptions(t *testing.T) {
 			flag:  "batching",
 			found: true,
 		},
+		{
+			name:  "lookup batching-window",
+			flag:  "batching-window",
+			found: true,
+		},
+		{
+			name:  "lookup kms-access-check",
+			flag:  "kms-access-check",
+			found: true,
+		},
+		{
+			name:  "lookup kms-access-check-fail-open",
+			flag:  "kms-access-check-fail-open",
+			found: true,
+		},
+		{
+			name:  "lookup shutdown-grace-period",
+			flag:  "shutdown-grace-period",
+			found: true,
+		},
+		{
+			name:  "lookup volume-size-granularity",
+			flag:  "volume-size-granularity",
+			found: true,
+		},
+		{
+			name:  "lookup snapshot-pvc-name-tag",
+			flag:  "snapshot-pvc-name-tag",
+			found: true,
+		},
+		{
+			name:  "lookup max-concurrent-attaches",
+			flag:  "max-concurrent-attaches",
+			found: true,
+		},
+		{
+			name:  "lookup fsr-warm-snapshots",
+			flag:  "fsr-warm-snapshots",
+			found: true,
+		},
+		{
+			name:  "lookup fsr-wait-timeout",
+			flag:  "fsr-wait-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup fsr-warm-cache-interval",
+			flag:  "fsr-warm-cache-interval",
+			found: true,
+		},
+		{
+			name:  "lookup maintenance-mode",
+			flag:  "maintenance-mode",
+			found: true,
+		},
+		{
+			name:  "lookup admin-endpoint",
+			flag:  "admin-endpoint",
+			found: true,
+		},
+		{
+			name:  "lookup attach-retry-budget",
+			flag:  "attach-retry-budget",
+			found: true,
+		},
+		{
+			name:  "lookup attach-retry-deadline",
+			flag:  "attach-retry-deadline",
+			found: true,
+		},
+		{
+			name:  "lookup device-name-lease-timeout",
+			flag:  "device-name-lease-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup ec2-mutating-qps",
+			flag:  "ec2-mutating-qps",
+			found: true,
+		},
+		{
+			name:  "lookup ec2-mutating-burst",
+			flag:  "ec2-mutating-burst",
+			found: true,
+		},
+		{
+			name:  "lookup ec2-read-only-qps",
+			flag:  "ec2-read-only-qps",
+			found: true,
+		},
+		{
+			name:  "lookup ec2-read-only-burst",
+			flag:  "ec2-read-only-burst",
+			found: true,
+		},
+		{
+			name:  "lookup force-detach-timeout",
+			flag:  "force-detach-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup node-operation-workers",
+			flag:  "node-operation-workers",
+			found: true,
+		},
+		{
+			name:  "lookup aws-role-arn",
+			flag:  "aws-role-arn",
+			found: true,
+		},
+		{
+			name:  "lookup aws-role-external-id",
+			flag:  "aws-role-external-id",
+			found: true,
+		},
+		{
+			name:  "lookup describe-cache-ttl",
+			flag:  "describe-cache-ttl",
+			found: true,
+		},
+		{
+			name:  "lookup request-cache-ttl",
+			flag:  "request-cache-ttl",
+			found: true,
+		},
+		{
+			name:  "lookup report-orphaned-volumes",
+			flag:  "report-orphaned-volumes",
+			found: true,
+		},
+		{
+			name:  "lookup volume-placement-strategy",
+			flag:  "volume-placement-strategy",
+			found: true,
+		},
+		{
+			name:  "lookup default-mount-options",
+			flag:  "default-mount-options",
+			found: true,
+		},
+		{
+			name:  "lookup discard-volume-types",
+			flag:  "discard-volume-types",
+			found: true,
+		},
+		{
+			name:  "lookup leader-election",
+			flag:  "leader-election",
+			found: true,
+		},
+		{
+			name:  "lookup leader-election-namespace",
+			flag:  "leader-election-namespace",
+			found: true,
+		},
+		{
+			name:  "lookup leader-election-lease-name",
+			flag:  "leader-election-lease-name",
+			found: true,
+		},
+		{
+			name:  "lookup leader-election-lease-duration",
+			flag:  "leader-election-lease-duration",
+			found: true,
+		},
+		{
+			name:  "lookup leader-election-renew-deadline",
+			flag:  "leader-election-renew-deadline",
+			found: true,
+		},
+		{
+			name:  "lookup leader-election-retry-period",
+			flag:  "leader-election-retry-period",
+			found: true,
+		},
+		{
+			name:  "lookup create-volume-retries",
+			flag:  "create-volume-retries",
+			found: true,
+		},
+		{
+			name:  "lookup kubernetes-api-retry-attempts",
+			flag:  "kubernetes-api-retry-attempts",
+			found: true,
+		},
+		{
+			name:  "lookup drop-excess-tags",
+			flag:  "drop-excess-tags",
+			found: true,
+		},
+		{
+			name:  "lookup validate-storage-classes",
+			flag:  "validate-storage-classes",
+			found: true,
+		},
 		{
 			name:  "lookup user-agent-extra",
 			flag:  "user-agent-extra",
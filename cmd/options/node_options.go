@@ -18,6 +18,7 @@ package options
 
 import (
 	"fmt"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -40,16 +41,111 @@ type NodeOptions struct {
 	// When -1, the amount of reserved attachments is loaded from instance metadata that captured state at node boot
 	// and may include not only system disks but also CSI volumes (and therefore it may be wrong).
 	ReservedVolumeAttachments int
+
+	// MetadataRetryAttempts is the number of additional attempts made to retrieve instance metadata
+	// when it is transiently unavailable (e.g. IMDS throttling or a brief network hiccup).
+	// Permanent failures, such as an incomplete instance identity document, are not retried.
+	MetadataRetryAttempts int
+
+	// AvailabilityZoneOverride is reported as the node's zone when the zone from instance metadata
+	// does not belong to the node's region, instead of failing NodeGetInfo.
+	AvailabilityZoneOverride string
+
+	// MountNamespace is the path to a mount namespace in which volumes flagged for mount namespace
+	// isolation are staged and published.
+	MountNamespace string
+
+	// DeviceSizeCheckTimeout is how long NodeStageVolume waits for an attached device to report
+	// at least the volume size recorded in the volume context before failing. Disabled if 0.
+	DeviceSizeCheckTimeout time.Duration
+
+	// DeviceAttachTimeout is how long NodeStageVolume waits for the device of a newly attached
+	// volume to appear. The device is looked up only once if 0.
+	DeviceAttachTimeout time.Duration
+
+	// DeviceReattachTimeout is how long NodeStageVolume waits for the device of a volume that was
+	// unstaged from the node before to reappear. DeviceAttachTimeout is used if 0.
+	DeviceReattachTimeout time.Duration
+
+	// SCSIFallbackWait is how long NodeStageVolume looks for the NVMe device of a volume before
+	// also looking for its legacy SCSI device path. Disabled if 0.
+	SCSIFallbackWait time.Duration
+
+	// UnmountDetachedVolumes makes NodeGetVolumeStats lazily unmount volumes that were detached
+	// from the node while still mounted.
+	UnmountDetachedVolumes bool
+
+	// ReportIOUtilization makes NodeGetVolumeStats report the IOPS and throughput observed on
+	// volumes since the previous call.
+	ReportIOUtilization bool
+
+	// ResizeFilesystemOnStage makes NodeStageVolume grow the filesystem of a volume to the size of
+	// its device, e.g. after the volume was expanded while detached from the node.
+	ResizeFilesystemOnStage bool
+
+	// ResolveDevicesByUUID makes NodeStageVolume find the device of a volume by the UUID of its
+	// filesystem when it is not found by device path or volume ID.
+	ResolveDevicesByUUID bool
+
+	// PreStopTimeout is how long the pre-stop-hook command, or the node service on SIGTERM when
+	// PreStopOnSIGTERM is set, waits for the VolumeAttachments of a node being drained to be
+	// deleted. Waiting is disabled if 0.
+	PreStopTimeout time.Duration
+
+	// PreStopDrainTaints are the keys of the taints, in addition to the unschedulable taint of
+	// cordoned nodes, that mark a node as being drained, e.g. by a node autoscaler.
+	PreStopDrainTaints []string
+
+	// PreStopOnSIGTERM makes the node service, when it receives SIGTERM, wait for the
+	// VolumeAttachments of a node being drained to be deleted before it stops, for deployments
+	// without the pre-stop-hook lifecycle hook.
+	PreStopOnSIGTERM bool
 }
 
+// defaultPreStopDrainTaints are the taints Karpenter and Cluster Autoscaler add to the nodes
+// they are about to terminate.
+var defaultPreStopDrainTaints = []string{"karpenter.sh/disrupted", "karpenter.sh/disruption", "ToBeDeletedByClusterAutoscaler"}
+
 func (o *NodeOptions) AddFlags(fs *flag.FlagSet) {
 	fs.Int64Var(&o.VolumeAttachLimit, "volume-attach-limit", -1, "Value for the maximum number of volumes attachable per node. If specified, the limit applies to all nodes and overrides --reserved-volume-attachments. If not specified, the value is approximated from the instance type.")
 	fs.IntVar(&o.ReservedVolumeAttachments, "reserved-volume-attachments", -1, "Number of volume attachments reserved for system use. Not used when --volume-attach-limit is specified. The total amount of volume attachments for a node is computed as: <nr. of attachments for corresponding instance type> - <number of NICs, if relevant to the instance type> - <reserved-volume-attachments value>. When -1, the amount of reserved attachments is loaded from instance metadata that captured state at node boot and may include not only system disks but also CSI volumes.")
+	fs.IntVar(&o.MetadataRetryAttempts, "metadata-retry-attempts", 0, "Number of times to retry retrieving instance metadata when it is transiently unavailable. Permanent failures are never retried. The default of 0 disables retries.")
+	fs.StringVar(&o.AvailabilityZoneOverride, "availability-zone-override", "", "Availability zone to report in the node's topology when the zone from instance metadata does not belong to the node's region. If not set, NodeGetInfo fails on such a mismatch.")
+	fs.StringVar(&o.MountNamespace, "mount-namespace", "", "Path to a mount namespace (e.g. /proc/1/ns/mnt) in which volumes with the isolateMountNamespace StorageClass parameter are staged and published. Requires nsenter in the driver image.")
+	fs.DurationVar(&o.DeviceSizeCheckTimeout, "device-size-check-timeout", 0, "How long to wait, before staging a volume, for the attached device to report the volume's provisioned size. Staging fails if the device is still smaller when the timeout expires. Disabled if 0.")
+	fs.DurationVar(&o.DeviceAttachTimeout, "device-attach-timeout", 0, "How long to wait, before staging a volume attached to the node for the first time, for its device to appear. The device is looked up only once if 0.")
+	fs.DurationVar(&o.DeviceReattachTimeout, "device-reattach-timeout", 0, "How long to wait, before staging or, for a block volume, publishing a volume that was unstaged from the node in the last hour, for its device to reappear after the volume was reattached. --device-attach-timeout is used if 0.")
+	fs.DurationVar(&o.SCSIFallbackWait, "scsi-fallback-wait", 0, "How long to wait, before staging a volume, for its NVMe device to appear before also looking for its legacy SCSI device path (/dev/sd* or /dev/xvd*), e.g. on instances moving between virtualization types. Extends the device lookup to at least this duration. Disabled if 0.")
+	fs.BoolVar(&o.ReportIOUtilization, "report-io-utilization", false, "To report, in the volume condition message of NodeGetVolumeStats, the IOPS and throughput observed on the device of a volume since the previous NodeGetVolumeStats call, e.g. for an external autoscaler of volume performance.")
+	fs.BoolVar(&o.ResizeFilesystemOnStage, "resize-filesystem-on-stage", true, "To grow, when staging a volume, its filesystem if it is smaller than the device of the volume, e.g. after the volume was expanded while detached from the node and NodeExpandVolume was never called. Runs resize2fs or xfs_growfs.")
+	fs.BoolVar(&o.ResolveDevicesByUUID, "resolve-devices-by-uuid", false, "To find the device of a volume, when it is not found by device path or volume ID, by the UUID of its filesystem, recorded when the volume was first staged on the node or set in the filesystemUUID volume attribute. The device is only used if it reports the ID of the volume.")
+	fs.DurationVar(&o.PreStopTimeout, "pre-stop-timeout", 30*time.Second, "How long to wait, when the node is being drained and the node pod is stopped, for all VolumeAttachments of the node to be deleted, so that its volumes are detached cleanly before the node terminates. Should be shorter than the terminationGracePeriodSeconds of the node pod. Waiting is disabled if 0.")
+	fs.StringSliceVar(&o.PreStopDrainTaints, "pre-stop-drain-taints", defaultPreStopDrainTaints, "Comma-separated keys of the taints that mark a node as being drained, in addition to the unschedulable taint of cordoned nodes, e.g. the taints node autoscalers add to the nodes they are about to terminate.")
+	fs.BoolVar(&o.PreStopOnSIGTERM, "pre-stop-on-sigterm", false, "To wait, when the node service receives SIGTERM while the node is being drained, for all VolumeAttachments of the node to be deleted before stopping, like the pre-stop-hook command, for deployments without the preStop lifecycle hook. The node service keeps serving requests while it waits.")
+	fs.BoolVar(&o.UnmountDetachedVolumes, "unmount-detached-volumes", false, "To lazily unmount, when reporting volume stats, volumes whose device was detached from the node while still mounted, e.g. by a force detach, so that the node recovers from the I/O errors on them. Such volumes are reported with an abnormal volume condition regardless.")
 }
 
 func (o *NodeOptions) Validate() error {
 	if o.VolumeAttachLimit != -1 && o.ReservedVolumeAttachments != -1 {
 		return fmt.Errorf("only one of --volume-attach-limit and --reserved-volume-attachments may be specified")
 	}
+	if o.MetadataRetryAttempts < 0 {
+		return fmt.Errorf("--metadata-retry-attempts must not be negative")
+	}
+	if o.DeviceSizeCheckTimeout < 0 {
+		return fmt.Errorf("--device-size-check-timeout must not be negative")
+	}
+	if o.DeviceAttachTimeout < 0 {
+		return fmt.Errorf("--device-attach-timeout must not be negative")
+	}
+	if o.DeviceReattachTimeout < 0 {
+		return fmt.Errorf("--device-reattach-timeout must not be negative")
+	}
+	if o.SCSIFallbackWait < 0 {
+		return fmt.Errorf("--scsi-fallback-wait must not be negative")
+	}
+	if o.PreStopTimeout < 0 {
+		return fmt.Errorf("--pre-stop-timeout must not be negative")
+	}
 	return nil
 }
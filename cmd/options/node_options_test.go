@@ -18,6 +18,7 @@ package options
 
 import (
 	"testing"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -33,6 +34,76 @@ func TestNodeOptions(t *testing.T) {
 			flag:  "volume-attach-limit",
 			found: true,
 		},
+		{
+			name:  "lookup metadata-retry-attempts",
+			flag:  "metadata-retry-attempts",
+			found: true,
+		},
+		{
+			name:  "lookup availability-zone-override",
+			flag:  "availability-zone-override",
+			found: true,
+		},
+		{
+			name:  "lookup mount-namespace",
+			flag:  "mount-namespace",
+			found: true,
+		},
+		{
+			name:  "lookup device-size-check-timeout",
+			flag:  "device-size-check-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup device-attach-timeout",
+			flag:  "device-attach-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup device-reattach-timeout",
+			flag:  "device-reattach-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup scsi-fallback-wait",
+			flag:  "scsi-fallback-wait",
+			found: true,
+		},
+		{
+			name:  "lookup report-io-utilization",
+			flag:  "report-io-utilization",
+			found: true,
+		},
+		{
+			name:  "lookup resize-filesystem-on-stage",
+			flag:  "resize-filesystem-on-stage",
+			found: true,
+		},
+		{
+			name:  "lookup resolve-devices-by-uuid",
+			flag:  "resolve-devices-by-uuid",
+			found: true,
+		},
+		{
+			name:  "lookup unmount-detached-volumes",
+			flag:  "unmount-detached-volumes",
+			found: true,
+		},
+		{
+			name:  "lookup pre-stop-timeout",
+			flag:  "pre-stop-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup pre-stop-drain-taints",
+			flag:  "pre-stop-drain-taints",
+			found: true,
+		},
+		{
+			name:  "lookup pre-stop-on-sigterm",
+			flag:  "pre-stop-on-sigterm",
+			found: true,
+		},
 		{
 			name:  "fail for non-desired flag",
 			flag:  "some-flag",
@@ -94,6 +165,60 @@ func TestValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "negative MetadataRetryAttempts",
+			options: &NodeOptions{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				MetadataRetryAttempts:     -1,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative DeviceSizeCheckTimeout",
+			options: &NodeOptions{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				DeviceSizeCheckTimeout:    -time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative DeviceAttachTimeout",
+			options: &NodeOptions{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				DeviceAttachTimeout:       -time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative DeviceReattachTimeout",
+			options: &NodeOptions{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				DeviceReattachTimeout:     -time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative SCSIFallbackWait",
+			options: &NodeOptions{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				SCSIFallbackWait:          -time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative PreStopTimeout",
+			options: &NodeOptions{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				PreStopTimeout:            -time.Second,
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
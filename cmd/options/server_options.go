@@ -17,8 +17,12 @@ limitations under the License.
 package options
 
 import (
+	"os"
+	"time"
+
 	flag "github.com/spf13/pflag"
 
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver"
 )
 
@@ -30,10 +34,46 @@ type ServerOptions struct {
 	HttpEndpoint string
 	// EnableOtelTracing enables opentelemetry tracing.
 	EnableOtelTracing bool
+	// RPCSummaryLogLevel is the log level of the summary logged for every RPC.
+	RPCSummaryLogLevel int
+	// MetadataSources are the sources of instance data in order of preference.
+	MetadataSources []string
+	// IMDSVersion is the version of the EC2 instance metadata service to use.
+	IMDSVersion string
+	// EC2Endpoint is the URL of the EC2 API to use instead of the default one.
+	EC2Endpoint string
+	// RegionalEC2Endpoints are the URLs of the EC2 API to use by region.
+	RegionalEC2Endpoints map[string]string
+	// UseFIPSEndpoints resolves FIPS endpoints of the EC2 API.
+	UseFIPSEndpoints bool
+	// UseDualStackEndpoints resolves dual-stack endpoints of the EC2 API and IMDS.
+	UseDualStackEndpoints bool
+	// CloudProvider is the backend of the driver, aws or fake for tests.
+	CloudProvider string
+	// FakeCloudLatency is added to every call of the fake cloud provider.
+	FakeCloudLatency time.Duration
+	// FakeCloudTransitionDelay is how long the state transitions of the fake cloud provider take.
+	FakeCloudTransitionDelay time.Duration
+	// FakeCloudErrors are the error messages returned by the fake cloud provider by method.
+	FakeCloudErrors map[string]string
 }
 
 func (s *ServerOptions) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&s.Endpoint, "endpoint", driver.DefaultCSIEndpoint, "Endpoint for the CSI driver server")
 	fs.StringVar(&s.HttpEndpoint, "http-endpoint", "", "The TCP network address where the HTTP server for metrics will listen (example: `:8080`). The default is empty string, which means the server is disabled.")
 	fs.BoolVar(&s.EnableOtelTracing, "enable-otel-tracing", false, "To enable opentelemetry tracing for the driver. The tracing is disabled by default. Configure the exporter endpoint with OTEL_EXPORTER_OTLP_ENDPOINT and other env variables, see https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/#general-sdk-configuration.")
+	fs.StringSliceVar(&s.MetadataSources, "metadata-sources", cloud.DefaultMetadataSources, "Comma separated list of the sources of instance data (instance ID and type, region and availability zone) in order of preference: imds for the EC2 instance metadata service, kubernetes for the provider ID and labels of the instance's Node. The next source is tried if one is unavailable or fails, e.g. on nodes without access to IMDS.")
+	fs.StringVar(&s.IMDSVersion, "imds-version", cloud.IMDSVersionAuto, "The version of the EC2 instance metadata service (IMDS) to use: auto for IMDSv2 with a fallback to IMDSv1 if no session token can be retrieved, v2 for IMDSv2 only, e.g. on instances configured with HttpTokens=required.")
+	fs.StringVar(&s.EC2Endpoint, "aws-ec2-endpoint", os.Getenv("AWS_EC2_ENDPOINT"), "The URL of the EC2 API to use in all regions instead of the default endpoint of the region, e.g. a VPC endpoint in an air-gapped cluster. Defaults to the AWS_EC2_ENDPOINT environment variable. Takes precedence over FIPS and dual-stack endpoints.")
+	fs.StringToStringVar(&s.RegionalEC2Endpoints, "aws-ec2-regional-endpoints", nil, "Comma separated list of region=URL pairs of the EC2 API to use in the given regions, e.g. for the source region of snapshots copied from another region. Takes precedence over --aws-ec2-endpoint.")
+	fs.BoolVar(&s.UseFIPSEndpoints, "use-fips-endpoints", false, "If set to true, the FIPS 140-2 endpoints of the EC2 API are used, e.g. in GovCloud. The AWS_USE_FIPS_ENDPOINT environment variable is honored if false.")
+	fs.BoolVar(&s.UseDualStackEndpoints, "use-dualstack-endpoints", false, "If set to true, the dual-stack (IPv4 and IPv6) endpoints of the EC2 API and the IPv6 endpoint of the EC2 instance metadata service are used, e.g. in IPv6-only clusters. The AWS_USE_DUALSTACK_ENDPOINT environment variable is honored if false.")
+	fs.StringVar(&s.CloudProvider, "cloud", cloud.CloudProviderAWS, "The backend of the driver: aws for EC2, or fake for an in-memory fake of EC2 to run e.g. csi-sanity without AWS credentials. For tests only.")
+	fs.DurationVar(&s.FakeCloudLatency, "fake-cloud-latency", 0, "The latency added to every call of the fake cloud provider. For tests only.")
+	fs.DurationVar(&s.FakeCloudTransitionDelay, "fake-cloud-transition-delay", 0, "How long volumes of the fake cloud provider take to be created, attached, detached and modified, and its snapshots to complete. For tests only.")
+	fs.StringToStringVar(&s.FakeCloudErrors, "fake-cloud-errors", nil, "Comma separated list of method=message pairs of the errors returned by the calls of the methods of the fake cloud provider, e.g. AttachDisk=throttled. For tests only.")
+	for _, name := range []string{"cloud", "fake-cloud-latency", "fake-cloud-transition-delay", "fake-cloud-errors"} {
+		_ = fs.MarkHidden(name)
+	}
+	fs.IntVar(&s.RPCSummaryLogLevel, "rpc-summary-log-level", 4, "The log level (klog verbosity) at which a structured summary of every RPC, with its method, volume ID, duration and result code, is logged. Secrets in the logged requests are redacted.")
 }
@@ -33,6 +33,51 @@ func TestServerOptions(t *testing.T) {
 			flag:  "endpoint",
 			found: true,
 		},
+		{
+			name:  "lookup metadata-sources",
+			flag:  "metadata-sources",
+			found: true,
+		},
+		{
+			name:  "lookup imds-version",
+			flag:  "imds-version",
+			found: true,
+		},
+		{
+			name:  "lookup cloud",
+			flag:  "cloud",
+			found: true,
+		},
+		{
+			name:  "lookup fake-cloud-errors",
+			flag:  "fake-cloud-errors",
+			found: true,
+		},
+		{
+			name:  "lookup aws-ec2-endpoint",
+			flag:  "aws-ec2-endpoint",
+			found: true,
+		},
+		{
+			name:  "lookup aws-ec2-regional-endpoints",
+			flag:  "aws-ec2-regional-endpoints",
+			found: true,
+		},
+		{
+			name:  "lookup use-fips-endpoints",
+			flag:  "use-fips-endpoints",
+			found: true,
+		},
+		{
+			name:  "lookup use-dualstack-endpoints",
+			flag:  "use-dualstack-endpoints",
+			found: true,
+		},
+		{
+			name:  "lookup rpc-summary-log-level",
+			flag:  "rpc-summary-log-level",
+			found: true,
+		},
 		{
 			name:  "fail for non-desired flag",
 			flag:  "some-other-flag",
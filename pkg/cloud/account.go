@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// unknownAccountID labels metrics when the AWS account ID could not be determined.
+const unknownAccountID = "unknown"
+
+// accountIDBackoff is the backoff between attempts to look up the account ID.
+var accountIDBackoff = wait.Backoff{
+	Duration: 1 * time.Second,
+	Factor:   2,
+	Cap:      5 * time.Minute,
+	Steps:    1<<31 - 1,
+}
+
+// awsAccount is the AWS account of the driver's credentials, whose ID labels
+// the metrics of the AWS API requests. The ID is looked up in the background
+// when the driver starts, so that requests never wait for it, and is reported
+// as unknownAccountID until the lookup succeeds.
+type awsAccount struct {
+	mu sync.RWMutex
+	id string
+}
+
+// ID returns the ID of the account, or unknownAccountID if it has not been
+// looked up yet.
+func (a *awsAccount) ID() string {
+	if a == nil {
+		return unknownAccountID
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.id == "" {
+		return unknownAccountID
+	}
+	return a.id
+}
+
+// resolve looks up the ID of the account with STS GetCallerIdentity, retrying
+// with backoff until it succeeds or ctx is done.
+func (a *awsAccount) resolve(ctx context.Context, svc stsiface.STSAPI) {
+	logger := klog.FromContext(ctx)
+	_ = wait.ExponentialBackoffWithContext(ctx, accountIDBackoff, func(ctx context.Context) (bool, error) {
+		response, err := svc.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			logger.Error(err, "Failed to get AWS account ID for metrics, retrying")
+			return false, nil
+		}
+		a.mu.Lock()
+		a.id = aws.StringValue(response.Account)
+		a.mu.Unlock()
+		logger.V(4).Info("Resolved AWS account ID for metrics", "accountID", a.ID())
+		return true, nil
+	})
+}
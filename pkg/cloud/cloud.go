@@ -23,18 +23,26 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/batcher"
 	dm "github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/devicemanager"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
@@ -62,20 +70,9 @@ const (
 	VolumeTypeStandard = "standard"
 )
 
-// AWS provisioning limits.
-// Source: http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/EBSVolumeTypes.html
-const (
-	io1MinTotalIOPS             = 100
-	io1MaxTotalIOPS             = 64000
-	io1MaxIOPSPerGB             = 50
-	io2MinTotalIOPS             = 100
-	io2MaxTotalIOPS             = 64000
-	io2BlockExpressMaxTotalIOPS = 256000
-	io2MaxIOPSPerGB             = 500
-	gp3MaxTotalIOPS             = 16000
-	gp3MinTotalIOPS             = 3000
-	gp3MaxIOPSPerGB             = 500
-)
+// gp3MinIOPSPerMiBps is the inverse of the maximum gp3 throughput to IOPS ratio
+// of 0.25 MiB/s per provisioned IOPS.
+const gp3MinIOPSPerMiBps = 4
 
 var (
 	ValidVolumeTypes = []string{
@@ -136,6 +133,20 @@ const (
 	AWSTagKeyPrefix = "aws:"
 	//AwsEbsDriverTagKey is the tag to identify if a volume/snapshot is managed by ebs csi driver
 	AwsEbsDriverTagKey = "ebs.csi.aws.com/cluster"
+	// FSRWarmCacheTagKeyPrefix is the prefix of the tag, suffixed with the cluster ID, to identify snapshots that
+	// fast snapshot restores are kept enabled on by the cluster. Its value lists the availability zones the cluster enabled them in.
+	FSRWarmCacheTagKeyPrefix = "ebs.csi.aws.com/fsr-warm-cache/"
+	// GroupSnapshotIDTagKey is the tag to identify the snapshots of a volume group snapshot.
+	// Its value is the ID of the group snapshot.
+	GroupSnapshotIDTagKey = "ebs.csi.aws.com/group-snapshot-id"
+	// BaselineSnapshotTagKey is the tag to identify the baseline snapshot taken of a volume right after its creation.
+	// Its value is the ID of the volume.
+	BaselineSnapshotTagKey = "ebs.csi.aws.com/baseline-snapshot-of"
+	// CopiedSnapshotTagKey is the tag to identify the snapshots copied from another region to restore volumes from.
+	// Its value is the region and ID of the source snapshot, e.g. "us-east-1/snap-0123456789abcdef0".
+	CopiedSnapshotTagKey = "ebs.csi.aws.com/copied-from"
+	// GrowthHeadroomTagKey is the tag to record the growth headroom added to the requested size of a volume, e.g. "10GiB".
+	GrowthHeadroomTagKey = "ebs.csi.aws.com/growth-headroom"
 )
 
 // Batcher
@@ -144,6 +155,16 @@ const (
 	volumeTagBatcher
 )
 
+const (
+	// DefaultBatchingWindow is how long concurrent DescribeVolumes and
+	// DescribeInstances calls are collected into a single call by default.
+	DefaultBatchingWindow = 1 * time.Second
+
+	// maxBatchEntries is the maximum number of volumes or instances described
+	// by a single batched call.
+	maxBatchEntries = 500
+)
+
 var (
 	// ErrMultiDisks is an error that is returned when multiple
 	// disks are found with the same volume name.
@@ -172,10 +193,73 @@ var (
 	// ErrInvalidMaxResults is returned when a MaxResults pagination parameter is between 1 and 4
 	ErrInvalidMaxResults = errors.New("MaxResults parameter must be 0 or greater than or equal to 5")
 
+	// ErrInvalidNextToken is returned when a pagination token is not valid or expired
+	ErrInvalidNextToken = errors.New("Invalid pagination token")
+
+	// ErrKMSKeyAccessDenied is returned when the instance role of a node is not
+	// allowed to use the KMS key an encrypted volume is protected with.
+	ErrKMSKeyAccessDenied = errors.New("Instance role is not allowed to use the KMS key of the volume")
+
+	// ErrTooManyTags is returned when a volume has more tags than EC2 allows on a resource.
+	ErrTooManyTags = errors.New("Too many tags")
+
+	// ErrVolumeNotAvailable is returned when a volume created by CreateDisk did
+	// not become available in time. The volume is deleted, so CreateDisk fails
+	// the same way when retried with the same client token.
+	ErrVolumeNotAvailable = errors.New("failed to get an available volume in EC2")
+
+	// ErrVolumeLimitExceeded is returned when a volume cannot be created because
+	// the account reached its limit of volumes in the region.
+	ErrVolumeLimitExceeded = errors.New("Volume count limit of the account reached")
+
+	// ErrInvalidVolumeParameters is returned when the parameters of a volume,
+	// e.g. its IOPS or throughput, are not supported by its volume type.
+	ErrInvalidVolumeParameters = errors.New("Invalid volume parameters")
+
+	// ErrCapacityOutOfRange is returned when the size of a volume is not
+	// supported by its volume type.
+	ErrCapacityOutOfRange = errors.New("Volume size out of range")
+
+	// ErrNotAttachedToSameInstance is returned when a group snapshot is
+	// requested of volumes that are not all attached to the same instance.
+	ErrNotAttachedToSameInstance = errors.New("Volumes are not attached to the same instance")
+
 	// VolumeNotBeingModified is returned if volume being described is not being modified
 	VolumeNotBeingModified = fmt.Errorf("volume is not being modified")
 )
 
+// Error reasons returned by ErrorReason.
+const (
+	// ErrorReasonQuota is returned when an account quota (e.g. volume count,
+	// storage or IOPS limits) would be exceeded.
+	ErrorReasonQuota = "QUOTA_EXCEEDED"
+	// ErrorReasonCapacity is returned when EBS lacks the capacity to serve the request.
+	ErrorReasonCapacity = "INSUFFICIENT_CAPACITY"
+	// ErrorReasonThrottling is returned when the request was throttled by the AWS API.
+	ErrorReasonThrottling = "THROTTLED"
+	// ErrorReasonValidation is returned when the request was rejected as invalid.
+	ErrorReasonValidation = "INVALID_PARAMETER"
+	// ErrorReasonKMS is returned when the KMS key of the request cannot be used.
+	ErrorReasonKMS = "KMS_KEY_UNUSABLE"
+)
+
+// errorReasons maps AWS error codes to the reasons returned by ErrorReason.
+var errorReasons = map[string]string{
+	"VolumeLimitExceeded":         ErrorReasonQuota,
+	"MaxIOPSLimitExceeded":        ErrorReasonQuota,
+	"ResourceLimitExceeded":       ErrorReasonQuota,
+	"InsufficientVolumeCapacity":  ErrorReasonCapacity,
+	"RequestLimitExceeded":        ErrorReasonThrottling,
+	"Throttling":                  ErrorReasonThrottling,
+	"ThrottlingException":         ErrorReasonThrottling,
+	"InvalidParameter":            ErrorReasonValidation,
+	"InvalidParameterValue":       ErrorReasonValidation,
+	"InvalidParameterCombination": ErrorReasonValidation,
+	"MissingParameter":            ErrorReasonValidation,
+	"UnknownVolumeType":           ErrorReasonValidation,
+	"InvalidZone.NotFound":        ErrorReasonValidation,
+}
+
 // Set during build time via -ldflags
 var driverVersion string
 
@@ -187,6 +271,28 @@ type Disk struct {
 	SnapshotID       string
 	OutpostArn       string
 	Attachments      []string
+	Tags             map[string]string
+	CreateTime       time.Time
+	// MultiAttachEnabled is set if the volume can be attached to several instances at once.
+	MultiAttachEnabled bool
+	// State is the state of the volume, e.g. available, in-use or error.
+	State string
+}
+
+// ListDisksResponse is a page of the volumes of the driver, with the token
+// to pass to get the next page, if any.
+type ListDisksResponse struct {
+	Disks     []*Disk
+	NextToken string
+}
+
+// VolumeStatus is the result of the status checks EC2 runs on a volume.
+type VolumeStatus struct {
+	// Status is ok, warning, impaired or insufficient-data.
+	Status string
+	// Problems describe the failed checks and scheduled events of the volume,
+	// e.g. "io-enabled: failed".
+	Problems []string
 }
 
 // DiskOptions represents parameters to create an EBS volume
@@ -207,6 +313,19 @@ type DiskOptions struct {
 	// example: arn:aws:kms:us-east-1:012345678910:key/abcd1234-a123-456a-a12b-a123b4cd56ef
 	KmsKeyID   string
 	SnapshotID string
+	// AllowThroughputDecrease lowers a gp3 throughput that is too high for the
+	// volume's IOPS to the highest valid value instead of rejecting the request.
+	AllowThroughputDecrease bool
+	// DropExcessTags drops the lowest priority tags of a volume with more than
+	// MaxNumTagsPerResource tags instead of rejecting the request.
+	DropExcessTags bool
+	// DryRun only validates the options with EC2, and that the KMS key is
+	// enabled, without creating a volume. The returned Disk has no VolumeID.
+	DryRun bool
+	// RetryAttempt, if positive, derives another client token from the volume
+	// name, to retry creating a volume whose previous attempts created a
+	// volume that was deleted.
+	RetryAttempt int
 }
 
 // ModifyDiskOptions represents parameters to modify an EBS volume
@@ -220,9 +339,14 @@ type ModifyDiskOptions struct {
 type Snapshot struct {
 	SnapshotID     string
 	SourceVolumeID string
-	Size           int64
-	CreationTime   time.Time
-	ReadyToUse     bool
+	// Size is the size in bytes of the source volume at the time of the
+	// snapshot, i.e. the minimum size of a volume restored from it
+	Size         int64
+	CreationTime time.Time
+	ReadyToUse   bool
+	// Encrypted is set if the snapshot is encrypted, with the KMS key KmsKeyID.
+	Encrypted bool
+	KmsKeyID  string
 }
 
 // ListSnapshotsResponse is the container for our snapshots along with a pagination token to pass back to the caller
@@ -234,6 +358,17 @@ type ListSnapshotsResponse struct {
 // SnapshotOptions represents parameters to create an EBS volume
 type SnapshotOptions struct {
 	Tags map[string]string
+	// OutpostArn is the ARN of the Outpost to store a local snapshot of a
+	// volume on the Outpost on, if not empty.
+	OutpostArn string
+}
+
+// CopySnapshotOptions represents parameters to copy a snapshot from another region
+type CopySnapshotOptions struct {
+	Tags      map[string]string
+	Encrypted bool
+	// KmsKeyID represents a fully qualified resource name to the key to use for encryption.
+	KmsKeyID string
 }
 
 // ec2ListSnapshotsResponse is a helper struct returned from the AWS API calling function to the main ListSnapshots function
@@ -248,35 +383,86 @@ type batcherType int
 // batcherManager maintains a collection of batchers for different types of tasks.
 type batcherManager struct {
 	batchers map[batcherType]*batcher.Batcher[string, *ec2.Volume]
+	// instanceIDBatcher describes instances by ID
+	instanceIDBatcher *batcher.Batcher[string, *ec2.Instance]
 }
 
 type cloud struct {
 	region string
 	ec2    ec2iface.EC2API
+	iam    iamiface.IAMAPI
+	kms    kmsiface.KMSAPI
 	dm     dm.DeviceManager
 	bm     *batcherManager
+	// detaches tracks detachments to force them once they are stuck. It is
+	// nil unless a force detach timeout is set.
+	detaches *detachTracker
+	// describes caches described instances and volumes. It is nil unless a
+	// describe cache TTL is set.
+	describes *describeCache
+	// kmsAccess caches the roles allowed to use KMS keys
+	kmsAccess *kmsAccessCache
 }
 
 var _ Cloud = &cloud{}
 
-// NewCloud returns a new instance of AWS cloud
+// CloudOptions configure the AWS cloud returned by NewCloud.
+type CloudOptions struct {
+	// AWSSDKDebugLog logs the requests and responses of the AWS SDK.
+	AWSSDKDebugLog bool
+	// UserAgentExtra is appended to the user agent of AWS API requests.
+	UserAgentExtra string
+	// Batching collects concurrent DescribeVolumes and DescribeInstances calls
+	// for BatchingWindow, or DefaultBatchingWindow if 0, into single calls.
+	Batching       bool
+	BatchingWindow time.Duration
+	// DeviceNameLeaseTimeout is how long device names reserved for attaches
+	// that are neither released nor confirmed stay reserved, or forever if 0.
+	DeviceNameLeaseTimeout time.Duration
+	// ForceDetachTimeout is how long after they were requested detachments
+	// still not done are forced, or never if 0.
+	ForceDetachTimeout time.Duration
+	// OnForceDetach, if set, is called after a detachment was forced.
+	OnForceDetach func(volumeID, nodeID string)
+	// RateLimits are the client-side rate limits of EC2 API requests.
+	RateLimits RateLimits
+	// EndpointOptions resolve the endpoints of the EC2 API.
+	EndpointOptions EndpointOptions
+	// DescribeCacheTTL is how long described instances and volumes are
+	// cached, or not at all if 0.
+	DescribeCacheTTL time.Duration
+	// RoleOptions is the role whose credentials AWS is called with, if any.
+	RoleOptions RoleOptions
+}
+
+// NewCloud returns a new instance of AWS cloud in region, configured by opts.
 // It panics if session is invalid
-func NewCloud(region string, awsSdkDebugLog bool, userAgentExtra string, batching bool) (Cloud, error) {
-	c := newEC2Cloud(region, awsSdkDebugLog, userAgentExtra)
+func NewCloud(region string, opts CloudOptions) (Cloud, error) {
+	if err := opts.EndpointOptions.Validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.RoleOptions.Validate(); err != nil {
+		return nil, err
+	}
+	c := newEC2Cloud(region, opts)
 
-	if batching {
-		klog.V(4).InfoS("NewCloud: batching enabled")
+	if opts.Batching {
+		batchingWindow := opts.BatchingWindow
+		if batchingWindow <= 0 {
+			batchingWindow = DefaultBatchingWindow
+		}
+		klog.V(4).InfoS("NewCloud: batching enabled", "batchingWindow", batchingWindow)
 		cloudInstance, ok := c.(*cloud)
 		if !ok {
 			return nil, fmt.Errorf("expected *cloud type but got %T", c)
 		}
-		cloudInstance.bm = newBatcherManager(cloudInstance.ec2)
+		cloudInstance.bm = newBatcherManager(cloudInstance.ec2, batchingWindow)
 	}
 
 	return c, nil
 }
 
-func newEC2Cloud(region string, awsSdkDebugLog bool, userAgentExtra string) Cloud {
+func newEC2Cloud(region string, opts CloudOptions) Cloud {
 	awsConfig := &aws.Config{
 		Region:                        aws.String(region),
 		CredentialsChainVerboseErrors: aws.Bool(true),
@@ -284,59 +470,64 @@ func newEC2Cloud(region string, awsSdkDebugLog bool, userAgentExtra string) Clou
 		MaxRetries: aws.Int(8),
 	}
 
-	endpoint := os.Getenv("AWS_EC2_ENDPOINT")
-	if endpoint != "" {
-		customResolver := func(service, region string, optFns ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
-			if service == ec2.EndpointsID {
-				return endpoints.ResolvedEndpoint{
-					URL:           endpoint,
-					SigningRegion: region,
-				}, nil
-			}
-			return endpoints.DefaultResolver().EndpointFor(service, region, optFns...)
-		}
-		awsConfig.EndpointResolver = endpoints.ResolverFunc(customResolver)
-	}
+	opts.EndpointOptions.apply(awsConfig)
 
-	if awsSdkDebugLog {
+	if opts.AWSSDKDebugLog {
 		awsConfig.WithLogLevel(aws.LogDebugWithRequestErrors)
 	}
 
 	// Set the env var so that the session appends custom user agent string
-	if userAgentExtra != "" {
-		os.Setenv("AWS_EXECUTION_ENV", "aws-ebs-csi-driver-"+driverVersion+"-"+userAgentExtra)
+	if opts.UserAgentExtra != "" {
+		os.Setenv("AWS_EXECUTION_ENV", "aws-ebs-csi-driver-"+driverVersion+"-"+opts.UserAgentExtra)
 	} else {
 		os.Setenv("AWS_EXECUTION_ENV", "aws-ebs-csi-driver-"+driverVersion)
 	}
 
-	svc := ec2.New(session.Must(session.NewSession(awsConfig)))
+	sess := opts.RoleOptions.session(session.Must(session.NewSession(awsConfig)))
+	svc := ec2.New(sess)
 	svc.Handlers.AfterRetry.PushFrontNamed(request.NamedHandler{
 		Name: "recordThrottledRequestsHandler",
 		Fn:   RecordThrottledRequestsHandler,
 	})
+	account := &awsAccount{}
+	go account.resolve(context.Background(), sts.New(sess))
 	svc.Handlers.Complete.PushFrontNamed(request.NamedHandler{
 		Name: "recordRequestsHandler",
-		Fn:   RecordRequestsHandler,
+		Fn:   recordRequestsHandler(account),
+	})
+	svc.Handlers.Complete.PushFrontNamed(request.NamedHandler{
+		Name: "recordRetriesHandler",
+		Fn:   RecordRetriesHandler,
 	})
+	newRequestRateLimiter(opts.RateLimits).addHandlers(&svc.Handlers)
 
 	return &cloud{
-		region: region,
-		dm:     dm.NewDeviceManager(),
-		ec2:    svc,
+		region:    region,
+		dm:        dm.NewDeviceManager(opts.DeviceNameLeaseTimeout),
+		ec2:       svc,
+		iam:       iam.New(sess),
+		kms:       kms.New(sess),
+		detaches:  newDetachTracker(opts.ForceDetachTimeout, opts.OnForceDetach),
+		describes: newDescribeCache(opts.DescribeCacheTTL),
+		kmsAccess: newKMSAccessCache(),
 	}
 }
 
-// newBatcherManager initializes a new instance of batcherManager.
-func newBatcherManager(svc ec2iface.EC2API) *batcherManager {
+// newBatcherManager initializes a new instance of batcherManager whose
+// batchers execute at the latest after window.
+func newBatcherManager(svc ec2iface.EC2API, window time.Duration) *batcherManager {
 	return &batcherManager{
 		batchers: map[batcherType]*batcher.Batcher[string, *ec2.Volume]{
-			volumeIDBatcher: batcher.New(500, 1*time.Second, func(ids []string) (map[string]*ec2.Volume, error) {
+			volumeIDBatcher: batcher.New(maxBatchEntries, window, func(ids []string) (map[string]*ec2.Volume, error) {
 				return execBatchDescribeVolumes(svc, ids, volumeIDBatcher)
 			}),
-			volumeTagBatcher: batcher.New(500, 1*time.Second, func(names []string) (map[string]*ec2.Volume, error) {
+			volumeTagBatcher: batcher.New(maxBatchEntries, window, func(names []string) (map[string]*ec2.Volume, error) {
 				return execBatchDescribeVolumes(svc, names, volumeTagBatcher)
 			}),
 		},
+		instanceIDBatcher: batcher.New(maxBatchEntries, window, func(ids []string) (map[string]*ec2.Instance, error) {
+			return execBatchDescribeInstances(svc, ids)
+		}),
 	}
 }
 
@@ -430,6 +621,63 @@ func (c *cloud) batchDescribeVolumes(request *ec2.DescribeVolumesInput) (*ec2.Vo
 	return r.Result, nil
 }
 
+// execBatchDescribeInstances executes a batched DescribeInstances API call.
+// Instances are filtered rather than requested by ID, so that a single
+// terminated or unknown instance does not fail the whole batch.
+func execBatchDescribeInstances(svc ec2iface.EC2API, ids []string) (map[string]*ec2.Instance, error) {
+	klog.V(7).InfoS("execBatchDescribeInstances", "instanceIds", ids)
+	request := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice(ids),
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := make(map[string]*ec2.Instance)
+	for {
+		response, err := svc.DescribeInstancesWithContext(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error listing AWS instances: %w", err)
+		}
+		for _, reservation := range response.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.InstanceId == nil {
+					continue
+				}
+				result[*instance.InstanceId] = instance
+			}
+		}
+		if aws.StringValue(response.NextToken) == "" {
+			break
+		}
+		request.NextToken = response.NextToken
+	}
+
+	klog.V(7).InfoS("execBatchDescribeInstances: success", "result", result)
+	return result, nil
+}
+
+// batchDescribeInstances queues the description of the instance nodeID to
+// the instance batcher and waits for the result.
+func (c *cloud) batchDescribeInstances(nodeID string) (*ec2.Instance, error) {
+	ch := make(chan batcher.BatchResult[*ec2.Instance])
+	c.bm.instanceIDBatcher.AddTask(nodeID, ch)
+
+	r := <-ch
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if r.Result == nil {
+		return nil, ErrNotFound
+	}
+	return r.Result, nil
+}
+
 // extractVolumeKey retrieves the key associated with a given volume based on the batcher type.
 // For the volumeIDBatcher type, it returns the volume's ID.
 // For other types, it searches for the VolumeNameTagKey within the volume's tags.
@@ -454,71 +702,74 @@ func extractVolumeKey(v *ec2.Volume, batcher batcherType) (string, error) {
 	return "", errors.New("extractVolumeKey: missing VolumeNameTagKey in volume tags")
 }
 
-func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *DiskOptions) (*Disk, error) {
+func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *DiskOptions) (disk *Disk, err error) {
+	logger := klog.FromContext(ctx)
+	if !diskOptions.DryRun {
+		defer func() { recordOperationResult("CreateDisk", err) }()
+	}
 	var (
 		createType    string
 		iops          int64
 		throughput    int64
-		err           error
-		maxIops       int64
-		minIops       int64
-		maxIopsPerGb  int64
 		requestedIops int64
 	)
 
 	capacityGiB := util.BytesToGiB(diskOptions.CapacityBytes)
 
 	if diskOptions.IOPS > 0 && diskOptions.IOPSPerGB > 0 {
-		return nil, fmt.Errorf("invalid StorageClass parameters; specify either IOPS or IOPSPerGb, not both")
+		return nil, fmt.Errorf("%w: specify either IOPS or IOPSPerGb, not both", ErrInvalidVolumeParameters)
 	}
 
 	createType = diskOptions.VolumeType
-	// If no volume type is specified, GP3 is used as default for newly created volumes.
+	// If no volume type is specified, GP3 is used as default for newly created
+	// volumes, GP2 for volumes on Outposts, which support no other type.
 	if createType == "" {
 		createType = VolumeTypeGP3
+		if len(diskOptions.OutpostArn) > 0 {
+			createType = VolumeTypeGP2
+		}
+	}
+	if len(diskOptions.OutpostArn) > 0 && createType != VolumeTypeGP2 {
+		return nil, fmt.Errorf("%w: %s volumes are not supported on Outposts, only gp2 volumes are", ErrInvalidVolumeParameters, createType)
 	}
 
-	switch createType {
-	case VolumeTypeGP2, VolumeTypeSC1, VolumeTypeST1, VolumeTypeSBG1, VolumeTypeSBP1, VolumeTypeStandard:
-	case VolumeTypeIO1:
-		maxIops = io1MaxTotalIOPS
-		minIops = io1MinTotalIOPS
-		maxIopsPerGb = io1MaxIOPSPerGB
-	case VolumeTypeIO2:
-		if diskOptions.BlockExpress {
-			maxIops = io2BlockExpressMaxTotalIOPS
-		} else {
-			maxIops = io2MaxTotalIOPS
-		}
-		minIops = io2MinTotalIOPS
-		maxIopsPerGb = io2MaxIOPSPerGB
-	case VolumeTypeGP3:
-		maxIops = gp3MaxTotalIOPS
-		minIops = gp3MinTotalIOPS
-		maxIopsPerGb = gp3MaxIOPSPerGB
-		throughput = int64(diskOptions.Throughput)
-	default:
-		return nil, fmt.Errorf("invalid AWS VolumeType %q", diskOptions.VolumeType)
+	limits, err := limitsOf(createType, diskOptions.BlockExpress)
+	if err != nil {
+		return nil, err
+	}
+	if err = limits.validateSize(createType, capacityGiB); err != nil {
+		return nil, err
 	}
 
-	if diskOptions.MultiAttachEnabled && createType != VolumeTypeIO2 {
-		return nil, fmt.Errorf("CreateDisk: multi-attach is only supported for io2 volumes")
+	if diskOptions.MultiAttachEnabled && createType != VolumeTypeIO1 && createType != VolumeTypeIO2 {
+		return nil, fmt.Errorf("%w: multi-attach is only supported for io1 and io2 volumes", ErrInvalidVolumeParameters)
 	}
 
-	if maxIops > 0 {
+	if limits.maxIOPS > 0 {
 		if diskOptions.IOPS > 0 {
 			requestedIops = int64(diskOptions.IOPS)
 		} else if diskOptions.IOPSPerGB > 0 {
 			requestedIops = int64(diskOptions.IOPSPerGB) * capacityGiB
 		}
-		iops, err = capIOPS(createType, capacityGiB, requestedIops, minIops, maxIops, maxIopsPerGb, diskOptions.AllowIOPSPerGBIncrease)
+		iops, err = capIOPS(createType, capacityGiB, requestedIops, limits, diskOptions.AllowIOPSPerGBIncrease)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limits.maxThroughput > 0 && diskOptions.Throughput > 0 {
+		throughput, err = capThroughput(int64(diskOptions.Throughput), iops, limits, diskOptions.AllowThroughputDecrease)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	volumeTags, err := limitTags(volumeName, diskOptions.Tags, diskOptions.DropExcessTags)
+	if err != nil {
+		return nil, err
+	}
 	var tags []*ec2.Tag
-	for key, value := range diskOptions.Tags {
+	for key, value := range volumeTags {
 		copiedKey := key
 		copiedValue := value
 		tags = append(tags, &ec2.Tag{Key: &copiedKey, Value: &copiedValue})
@@ -531,7 +782,7 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 	zone := diskOptions.AvailabilityZone
 	if zone == "" {
 		zone, err = c.randomAvailabilityZone(ctx)
-		klog.V(5).InfoS("[Debug] AZ is not provided. Using node AZ", "zone", zone)
+		logger.V(5).Info("[Debug] AZ is not provided. Using node AZ", "zone", zone)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get availability zone %w", err)
 		}
@@ -539,6 +790,9 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 
 	// We hash the volume name to generate a unique token that is less than or equal to 64 characters
 	clientToken := sha256.Sum256([]byte(volumeName))
+	if diskOptions.RetryAttempt > 0 {
+		clientToken = sha256.Sum256([]byte(fmt.Sprintf("%s-%d", volumeName, diskOptions.RetryAttempt)))
+	}
 
 	requestInput := &ec2.CreateVolumeInput{
 		AvailabilityZone:   aws.String(zone),
@@ -573,6 +827,10 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 		requestInput.SnapshotId = aws.String(snapshotID)
 	}
 
+	if diskOptions.DryRun {
+		return c.createDiskDryRun(ctx, requestInput, diskOptions.KmsKeyID)
+	}
+
 	response, err := c.ec2.CreateVolumeWithContext(ctx, requestInput)
 	if err != nil {
 		if isAWSErrorSnapshotNotFound(err) {
@@ -581,6 +839,9 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 		if isAWSErrorIdempotentParameterMismatch(err) {
 			return nil, ErrIdempotentParameterMismatch
 		}
+		if isAWSError(err, "VolumeLimitExceeded") {
+			return nil, fmt.Errorf("could not create volume in EC2: %w: %w", ErrVolumeLimitExceeded, err)
+		}
 		return nil, fmt.Errorf("could not create volume in EC2: %w", err)
 	}
 
@@ -598,11 +859,11 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 		// To avoid leaking volume, we should delete the volume just created
 		// TODO: Need to figure out how to handle DeleteDisk failed scenario instead of just log the error
 		if _, error := c.DeleteDisk(ctx, volumeID); error != nil {
-			klog.ErrorS(error, "failed to be deleted, this may cause volume leak", "volumeID", volumeID)
+			logger.Error(error, "failed to be deleted, this may cause volume leak", "volumeID", volumeID)
 		} else {
-			klog.V(5).InfoS("[Debug] volume is deleted because it is not in desired state within retry limit", "volumeID", volumeID)
+			logger.V(5).Info("[Debug] volume is deleted because it is not in desired state within retry limit", "volumeID", volumeID)
 		}
-		return nil, fmt.Errorf("failed to get an available volume in EC2: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrVolumeNotAvailable, err)
 	}
 
 	outpostArn := aws.StringValue(response.OutpostArn)
@@ -617,25 +878,116 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 			// To avoid leaking volume, we should delete the volume just created
 			// TODO: Need to figure out how to handle DeleteDisk failed scenario instead of just log the error
 			if _, error := c.DeleteDisk(ctx, volumeID); err != nil {
-				klog.ErrorS(error, "failed to be deleted, this may cause volume leak", "volumeID", volumeID)
+				logger.Error(error, "failed to be deleted, this may cause volume leak", "volumeID", volumeID)
 			} else {
-				klog.V(5).InfoS("volume is deleted because there was an error while attaching the tags", "volumeID", volumeID)
+				logger.V(5).Info("volume is deleted because there was an error while attaching the tags", "volumeID", volumeID)
 			}
 			return nil, fmt.Errorf("could not attach tags to volume: %v. %w", volumeID, err)
 		}
 	}
-	return &Disk{CapacityGiB: size, VolumeID: volumeID, AvailabilityZone: zone, SnapshotID: snapshotID, OutpostArn: outpostArn}, nil
+	return &Disk{CapacityGiB: size, VolumeID: volumeID, AvailabilityZone: zone, SnapshotID: snapshotID, OutpostArn: outpostArn, MultiAttachEnabled: diskOptions.MultiAttachEnabled}, nil
+}
+
+// createDiskDryRun validates requestInput with an EC2 dry run. Because the dry
+// run does not check that a KMS key can actually be used, kmsKeyID is checked
+// to exist and be enabled separately.
+func (c *cloud) createDiskDryRun(ctx context.Context, requestInput *ec2.CreateVolumeInput, kmsKeyID string) (*Disk, error) {
+	if kmsKeyID != "" {
+		key, err := c.kms.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(kmsKeyID)})
+		if err != nil {
+			return nil, fmt.Errorf("could not describe KMS key %q: %w", kmsKeyID, err)
+		}
+		if key.KeyMetadata == nil {
+			return nil, fmt.Errorf("KMS key %q has no metadata", kmsKeyID)
+		}
+		if state := aws.StringValue(key.KeyMetadata.KeyState); state != kms.KeyStateEnabled {
+			return nil, fmt.Errorf("KMS key %q is in state %s, not %s", kmsKeyID, state, kms.KeyStateEnabled)
+		}
+	}
+
+	requestInput.DryRun = aws.Bool(true)
+	// A dry run that would have succeeded fails with DryRunOperation
+	if _, err := c.ec2.CreateVolumeWithContext(ctx, requestInput); !isAWSError(err, "DryRunOperation") {
+		if err == nil {
+			err = errors.New("CreateVolume dry run unexpectedly succeeded")
+		}
+		return nil, fmt.Errorf("could not create volume in EC2: %w", err)
+	}
+	return &Disk{
+		CapacityGiB:      aws.Int64Value(requestInput.Size),
+		AvailabilityZone: aws.StringValue(requestInput.AvailabilityZone),
+	}, nil
+}
+
+// Coarse categories of failed operations, used as the category label of
+// the cloudprovider_aws_operation_failures_total metric.
+const (
+	failureCategoryThrottle   = "throttle"
+	failureCategoryQuota      = "quota"
+	failureCategoryValidation = "validation"
+	failureCategoryOther      = "other"
+)
+
+// recordOperationResult counts the outcome of a Cloud operation, so that
+// error-rate SLOs can be computed per operation. operation must be one of
+// a fixed set of names and failures are labeled with one of a few coarse
+// categories to keep the cardinality of the metrics bounded.
+func recordOperationResult(operation string, err error) {
+	if metrics.Recorder() == nil {
+		return
+	}
+	if err == nil {
+		metrics.Recorder().IncreaseCount("cloudprovider_aws_operation_successes_total", map[string]string{
+			"operation": operation,
+		})
+		return
+	}
+	metrics.Recorder().IncreaseCount("cloudprovider_aws_operation_failures_total", map[string]string{
+		"operation": operation,
+		"category":  failureCategory(err),
+	})
+}
+
+// ignoreNotFound returns nil if err is ErrNotFound, for the results of
+// operations that remove a resource, as a resource already gone is what the
+// driver asked for rather than a failure.
+func ignoreNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// failureCategory returns the coarse category of the error of a failed operation.
+func failureCategory(err error) string {
+	if errors.Is(err, ErrTooManyTags) {
+		return failureCategoryValidation
+	}
+	switch reason, _ := ErrorReason(err); reason {
+	case ErrorReasonThrottling:
+		return failureCategoryThrottle
+	case ErrorReasonQuota:
+		return failureCategoryQuota
+	case ErrorReasonValidation:
+		return failureCategoryValidation
+	default:
+		return failureCategoryOther
+	}
 }
 
 // ResizeOrModifyDisk resizes an EBS volume in GiB increments, rouding up to the next possible allocatable unit, and/or modifies an EBS
 // volume with the parameters in ModifyDiskOptions.
 // The resizing operation is performed only when newSizeBytes != 0.
 // It returns the volume size after this call or an error if the size couldn't be determined or the volume couldn't be modified.
-func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSizeBytes int64, options *ModifyDiskOptions) (int64, error) {
+func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSizeBytes int64, options *ModifyDiskOptions) (size int64, err error) {
+	logger := klog.FromContext(ctx)
+	defer func() { recordOperationResult("ResizeOrModifyDisk", err) }()
+	// The volume may have been described while it was being modified
+	defer c.describes.invalidate(volumeID)
 	if newSizeBytes != 0 {
-		klog.V(4).InfoS("Received Resize and/or Modify Disk request", "volumeID", volumeID, "newSizeBytes", newSizeBytes, "options", options)
+		logger.V(4).Info("Received Resize and/or Modify Disk request", "volumeID", volumeID, "newSizeBytes", newSizeBytes, "options", options)
 	} else {
-		klog.V(4).InfoS("Received Modify Disk request", "volumeID", volumeID, "options", options)
+		logger.V(4).Info("Received Modify Disk request", "volumeID", volumeID, "options", options)
 	}
 
 	newSizeGiB := util.RoundUpGiB(newSizeBytes)
@@ -678,8 +1030,10 @@ func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSize
 	return c.checkDesiredState(ctx, volumeID, newSizeGiB, options)
 }
 
-func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
+func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (success bool, err error) {
+	defer func() { recordOperationResult("DeleteDisk", ignoreNotFound(err)) }()
 	request := &ec2.DeleteVolumeInput{VolumeId: &volumeID}
+	defer c.describes.invalidate(volumeID)
 	if _, err := c.ec2.DeleteVolumeWithContext(ctx, request); err != nil {
 		if isAWSErrorVolumeNotFound(err) {
 			return false, ErrNotFound
@@ -689,7 +1043,15 @@ func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
 	return true, nil
 }
 
-func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string, error) {
+func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (devicePath string, err error) {
+	logger := klog.FromContext(ctx)
+	defer func() { recordOperationResult("AttachDisk", err) }()
+	defer func() {
+		if err != nil {
+			// The attachment may have failed because of stale block device mappings
+			c.describes.invalidate(nodeID, volumeID)
+		}
+	}()
 	instance, err := c.getInstance(ctx, nodeID)
 	if err != nil {
 		return "", err
@@ -699,37 +1061,77 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 	if err != nil {
 		return "", err
 	}
-	defer device.Release(false)
+	defer func() {
+		if device != nil {
+			device.Release(false)
+		}
+	}()
 
 	if !device.IsAlreadyAssigned {
-		request := &ec2.AttachVolumeInput{
-			Device:     aws.String(device.Path),
-			InstanceId: aws.String(nodeID),
-			VolumeId:   aws.String(volumeID),
+		attachErr := c.attachVolume(ctx, volumeID, nodeID, device.Path)
+		if isAWSErrorAttachmentPointInUse(attachErr) {
+			// The block device mappings the device name was chosen from miss
+			// an attachment, e.g. of a volume attached outside of the driver
+			// since the instance was cached. Choose again from the live ones.
+			logger.Info("Device name is already in use, retrying with the live block device mappings", "volumeID", volumeID, "nodeID", nodeID, "device", device.Path)
+			device.Release(false)
+			c.describes.invalidate(nodeID)
+			instance, err = c.describeInstance(ctx, nodeID)
+			if err != nil {
+				device = nil
+				return "", err
+			}
+			c.describes.put(nodeID, instance)
+			device, err = c.dm.NewDevice(instance, volumeID)
+			if err != nil {
+				return "", err
+			}
+			attachErr = nil
+			if !device.IsAlreadyAssigned {
+				attachErr = c.attachVolume(ctx, volumeID, nodeID, device.Path)
+			}
 		}
-
-		resp, attachErr := c.ec2.AttachVolumeWithContext(ctx, request)
 		if attachErr != nil {
+			if isAttachOutcomeUnknown(attachErr) {
+				// The volume may be attaching with the device name, which
+				// must stay reserved until it is attached or the lease expires
+				device.Taint()
+			}
+			if isAWSError(attachErr, "VolumeInUse") {
+				// The volume is attached to another instance and not multi-attach enabled
+				return "", fmt.Errorf("could not attach volume %q to node %q: %w: %w", volumeID, nodeID, ErrVolumeInUse, attachErr)
+			}
 			return "", fmt.Errorf("could not attach volume %q to node %q: %w", volumeID, nodeID, attachErr)
 		}
-		klog.V(5).InfoS("[Debug] AttachVolume", "volumeID", volumeID, "nodeID", nodeID, "resp", resp)
 	}
 
 	_, err = c.WaitForAttachmentState(ctx, volumeID, volumeAttachedState, *instance.InstanceId, device.Path, device.IsAlreadyAssigned)
 
-	// This is the only situation where we taint the device
+	// The volume may still become attached with the device name
 	if err != nil {
 		device.Taint()
 		return "", err
 	}
 
+	c.describes.attached(nodeID, volumeID, device.Path)
+	// A detachment that was still tracked is over, and the next one must not
+	// be forced right away
+	c.detaches.end(volumeID, nodeID)
+
 	// TODO: Check volume capability matches for ALREADY_EXISTS
 	// This could happen when request volume already attached to request node,
 	// but is incompatible with the specified volume_capability or readonly flag
 	return device.Path, nil
 }
 
-func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
+func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) (err error) {
+	logger := klog.FromContext(ctx)
+	defer func() { recordOperationResult("DetachDisk", ignoreNotFound(err)) }()
+	defer func() {
+		if err != nil {
+			c.describes.invalidate(nodeID, volumeID)
+		}
+	}()
 	instance, err := c.getInstance(ctx, nodeID)
 	if err != nil {
 		return err
@@ -740,41 +1142,104 @@ func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
 	if err != nil {
 		return err
 	}
-	defer device.Release(true)
+	defer func() {
+		// The device name may still be in use until the volume is detached
+		if err == nil {
+			device.Release(true)
+		}
+	}()
 
 	if !device.IsAlreadyAssigned {
-		klog.InfoS("DetachDisk: called on non-attached volume", "volumeID", volumeID)
+		logger.Info("DetachDisk: called on non-attached volume", "volumeID", volumeID)
+	}
+
+	forceAfter, forceable := c.detaches.begin(volumeID, nodeID)
+	force := forceable && forceAfter <= 0
+	if err := c.detachVolume(ctx, volumeID, nodeID, force); err != nil {
+		return err
+	}
+
+	waitCtx := ctx
+	escalate := forceable && !force
+	if escalate {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, forceAfter)
+		defer cancel()
+	}
+	attachment, err := c.WaitForAttachmentState(waitCtx, volumeID, volumeDetachedState, *instance.InstanceId, "", false)
+	if err != nil && escalate && ctx.Err() == nil && errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+		// The detachment is still not done after the force detach timeout
+		if err = c.detachVolume(ctx, volumeID, nodeID, true); err != nil {
+			return err
+		}
+		attachment, err = c.WaitForAttachmentState(ctx, volumeID, volumeDetachedState, *instance.InstanceId, "", false)
+	}
+	if err != nil {
+		return err
+	}
+	c.detaches.end(volumeID, nodeID)
+	c.describes.detached(nodeID, volumeID)
+	if attachment != nil {
+		// We expect it to be nil, it is (maybe) interesting if it is not
+		logger.V(2).Info("waitForAttachmentState returned non-nil attachment with state=detached", "attachment", attachment)
+	}
+
+	return nil
+}
+
+// attachVolume requests the attachment of volumeID to nodeID with the device
+// name devicePath.
+func (c *cloud) attachVolume(ctx context.Context, volumeID, nodeID, devicePath string) error {
+	logger := klog.FromContext(ctx)
+	request := &ec2.AttachVolumeInput{
+		Device:     aws.String(devicePath),
+		InstanceId: aws.String(nodeID),
+		VolumeId:   aws.String(volumeID),
 	}
 
+	resp, err := c.ec2.AttachVolumeWithContext(ctx, request)
+	if err != nil {
+		return err
+	}
+	logger.V(5).Info("[Debug] AttachVolume", "volumeID", volumeID, "nodeID", nodeID, "resp", resp)
+	return nil
+}
+
+// detachVolume requests the detachment of volumeID from nodeID. A forced
+// detachment does not wait for the instance to release the volume, which may
+// lose data not yet written by the instance, so it is logged, counted and
+// reported to the OnForceDetach handler.
+func (c *cloud) detachVolume(ctx context.Context, volumeID, nodeID string, force bool) error {
+	logger := klog.FromContext(ctx)
 	request := &ec2.DetachVolumeInput{
 		InstanceId: aws.String(nodeID),
 		VolumeId:   aws.String(volumeID),
 	}
+	if force {
+		logger.Info("DetachDisk: forcing detachment of volume stuck detaching", "volumeID", volumeID, "nodeID", nodeID, "forceDetachTimeout", c.detaches.timeout)
+		request.Force = aws.Bool(true)
+		metrics.Recorder().IncreaseCount(forceDetachesMetric, map[string]string{})
+	}
 
-	_, err = c.ec2.DetachVolumeWithContext(ctx, request)
+	_, err := c.ec2.DetachVolumeWithContext(ctx, request)
 	if err != nil {
 		if isAWSErrorIncorrectState(err) ||
 			isAWSErrorInvalidAttachmentNotFound(err) ||
 			isAWSErrorVolumeNotFound(err) {
+			c.detaches.end(volumeID, nodeID)
 			return ErrNotFound
 		}
 		return fmt.Errorf("could not detach volume %q from node %q: %w", volumeID, nodeID, err)
 	}
-
-	attachment, err := c.WaitForAttachmentState(ctx, volumeID, volumeDetachedState, *instance.InstanceId, "", false)
-	if err != nil {
-		return err
-	}
-	if attachment != nil {
-		// We expect it to be nil, it is (maybe) interesting if it is not
-		klog.V(2).InfoS("waitForAttachmentState returned non-nil attachment with state=detached", "attachment", attachment)
+	if force {
+		c.detaches.forced(volumeID, nodeID)
 	}
-
 	return nil
 }
 
 // WaitForAttachmentState polls until the attachment status is the expected value.
 func (c *cloud) WaitForAttachmentState(ctx context.Context, volumeID, expectedState string, expectedInstance string, expectedDevice string, alreadyAssigned bool) (*ec2.VolumeAttachment, error) {
+	logger := klog.FromContext(ctx)
 	// Most attach/detach operations on AWS finish within 1-4 seconds.
 	// By using 1 second starting interval with a backoff of 1.8,
 	// we get [1, 1.8, 3.24, 5.832000000000001, 10.4976].
@@ -800,22 +1265,22 @@ func (c *cloud) WaitForAttachmentState(ctx context.Context, volumeID, expectedSt
 			if isAWSErrorVolumeNotFound(err) {
 				if expectedState == volumeDetachedState {
 					// The disk doesn't exist, assume it's detached, log warning and stop waiting
-					klog.InfoS("Waiting for volume to be detached but the volume does not exist", "volumeID", volumeID)
+					logger.Info("Waiting for volume to be detached but the volume does not exist", "volumeID", volumeID)
 					return true, nil
 				}
 				if expectedState == volumeAttachedState {
 					// The disk doesn't exist, complain, give up waiting and report error
-					klog.InfoS("Waiting for volume to be attached but the volume does not exist", "volumeID", volumeID)
+					logger.Info("Waiting for volume to be attached but the volume does not exist", "volumeID", volumeID)
 					return false, err
 				}
 			}
 
-			klog.InfoS("Ignoring error from describe volume, will retry", "volumeID", volumeID, "err", err)
+			logger.Info("Ignoring error from describe volume, will retry", "volumeID", volumeID, "err", err)
 			return false, nil
 		}
 
 		if volume.MultiAttachEnabled != nil && !*volume.MultiAttachEnabled && len(volume.Attachments) > 1 {
-			klog.InfoS("Found multiple attachments for volume", "volumeID", volumeID, "volume", volume)
+			logger.Info("Found multiple attachments for volume", "volumeID", volumeID, "volume", volume)
 			return false, fmt.Errorf("volume %q has multiple attachments", volumeID)
 		}
 
@@ -837,7 +1302,7 @@ func (c *cloud) WaitForAttachmentState(ctx context.Context, volumeID, expectedSt
 		if attachment != nil && attachment.Device != nil && expectedState == volumeAttachedState {
 			device := aws.StringValue(attachment.Device)
 			if device != expectedDevice {
-				klog.InfoS("WaitForAttachmentState: device mismatch", "device", device, "expectedDevice", expectedDevice, "attachment", attachment)
+				logger.Info("WaitForAttachmentState: device mismatch", "device", device, "expectedDevice", expectedDevice, "attachment", attachment)
 				return false, nil
 			}
 		}
@@ -859,7 +1324,7 @@ func (c *cloud) WaitForAttachmentState(ctx context.Context, volumeID, expectedSt
 			return true, nil
 		}
 		// continue waiting
-		klog.InfoS("Waiting for volume state", "volumeID", volumeID, "actual", attachmentState, "desired", expectedState)
+		logger.Info("Waiting for volume state", "volumeID", volumeID, "actual", attachmentState, "desired", expectedState)
 		return false, nil
 	}
 
@@ -887,93 +1352,389 @@ func (c *cloud) GetDiskByName(ctx context.Context, name string, capacityBytes in
 	}
 
 	return &Disk{
-		VolumeID:         aws.StringValue(volume.VolumeId),
-		CapacityGiB:      volSizeBytes,
-		AvailabilityZone: aws.StringValue(volume.AvailabilityZone),
-		SnapshotID:       aws.StringValue(volume.SnapshotId),
-		OutpostArn:       aws.StringValue(volume.OutpostArn),
+		VolumeID:           aws.StringValue(volume.VolumeId),
+		CapacityGiB:        volSizeBytes,
+		AvailabilityZone:   aws.StringValue(volume.AvailabilityZone),
+		SnapshotID:         aws.StringValue(volume.SnapshotId),
+		OutpostArn:         aws.StringValue(volume.OutpostArn),
+		MultiAttachEnabled: aws.BoolValue(volume.MultiAttachEnabled),
+		State:              aws.StringValue(volume.State),
 	}, nil
 }
 
 func (c *cloud) GetDiskByID(ctx context.Context, volumeID string) (*Disk, error) {
-	request := &ec2.DescribeVolumesInput{
-		VolumeIds: []*string{
-			aws.String(volumeID),
-		},
-	}
-
-	volume, err := c.getVolume(ctx, request)
-
+	volume, err := c.getVolumeByID(ctx, volumeID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Disk{
-		VolumeID:         aws.StringValue(volume.VolumeId),
-		CapacityGiB:      aws.Int64Value(volume.Size),
-		AvailabilityZone: aws.StringValue(volume.AvailabilityZone),
-		OutpostArn:       aws.StringValue(volume.OutpostArn),
-		Attachments:      getVolumeAttachmentsList(volume),
+		VolumeID:           aws.StringValue(volume.VolumeId),
+		CapacityGiB:        aws.Int64Value(volume.Size),
+		AvailabilityZone:   aws.StringValue(volume.AvailabilityZone),
+		OutpostArn:         aws.StringValue(volume.OutpostArn),
+		Attachments:        getVolumeAttachmentsList(volume),
+		Tags:               getVolumeTags(volume),
+		MultiAttachEnabled: aws.BoolValue(volume.MultiAttachEnabled),
+		State:              aws.StringValue(volume.State),
 	}, nil
 }
 
-func (c *cloud) IsExistInstance(ctx context.Context, nodeID string) bool {
-	instance, err := c.getInstance(ctx, nodeID)
-	if err != nil || instance == nil {
-		return false
+// ListDisks returns the volumes created by the driver. If maxResults is set,
+// it returns up to maxResults volumes and the token to get the next page with.
+func (c *cloud) ListDisks(ctx context.Context, maxResults int64, nextToken string) (*ListDisksResponse, error) {
+	if maxResults > 0 && maxResults < 5 {
+		return nil, ErrInvalidMaxResults
 	}
-	return true
-}
-
-func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (snapshot *Snapshot, err error) {
-	descriptions := "Created by AWS EBS CSI driver for volume " + volumeID
-
-	var tags []*ec2.Tag
-	for key, value := range snapshotOptions.Tags {
-		copiedKey := key
-		copiedValue := value
-		tags = append(tags, &ec2.Tag{Key: &copiedKey, Value: &copiedValue})
+	request := &ec2.DescribeVolumesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + AwsEbsDriverTagKey),
+				Values: []*string{aws.String("true")},
+			},
+		},
 	}
-	tagSpec := ec2.TagSpecification{
-		ResourceType: aws.String("snapshot"),
-		Tags:         tags,
+	if maxResults > 0 {
+		// DescribeVolumes returns at most 500 volumes per page
+		request.MaxResults = aws.Int64(min(maxResults, 500))
 	}
-	request := &ec2.CreateSnapshotInput{
-		VolumeId:          aws.String(volumeID),
-		DryRun:            aws.Bool(false),
-		TagSpecifications: []*ec2.TagSpecification{&tagSpec},
-		Description:       aws.String(descriptions),
+	if len(nextToken) > 0 {
+		request.NextToken = aws.String(nextToken)
 	}
 
-	res, err := c.ec2.CreateSnapshotWithContext(ctx, request)
-	if err != nil {
-		return nil, fmt.Errorf("error creating snapshot of volume %s: %w", volumeID, err)
-	}
-	if res == nil {
-		return nil, fmt.Errorf("nil CreateSnapshotResponse")
+	var disks []*Disk
+	for {
+		response, err := c.ec2.DescribeVolumesWithContext(ctx, request)
+		if err != nil {
+			// The token is the only parameter of the request that can be invalid
+			if request.NextToken != nil && (isAWSError(err, "InvalidPaginationToken") || isAWSError(err, "InvalidParameterValue")) {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidNextToken, err)
+			}
+			return nil, fmt.Errorf("could not list volumes: %w", err)
+		}
+		for _, volume := range response.Volumes {
+			disks = append(disks, &Disk{
+				VolumeID:           aws.StringValue(volume.VolumeId),
+				CapacityGiB:        aws.Int64Value(volume.Size),
+				AvailabilityZone:   aws.StringValue(volume.AvailabilityZone),
+				SnapshotID:         aws.StringValue(volume.SnapshotId),
+				OutpostArn:         aws.StringValue(volume.OutpostArn),
+				Attachments:        getVolumeAttachmentsList(volume),
+				MultiAttachEnabled: aws.BoolValue(volume.MultiAttachEnabled),
+				State:              aws.StringValue(volume.State),
+			})
+		}
+		nextToken = aws.StringValue(response.NextToken)
+		if maxResults > 0 || nextToken == "" {
+			break
+		}
+		request.NextToken = response.NextToken
 	}
+	return &ListDisksResponse{Disks: disks, NextToken: nextToken}, nil
+}
 
-	return c.ec2SnapshotResponseToStruct(res), nil
+// GetVolumeStatuses returns the statuses of the given volumes by volume ID.
+// Volumes EC2 reports no status for are left out.
+func (c *cloud) GetVolumeStatuses(ctx context.Context, volumeIDs []string) (map[string]*VolumeStatus, error) {
+	statuses := make(map[string]*VolumeStatus, len(volumeIDs))
+	if len(volumeIDs) == 0 {
+		return statuses, nil
+	}
+	request := &ec2.DescribeVolumeStatusInput{
+		VolumeIds: aws.StringSlice(volumeIDs),
+	}
+	for {
+		response, err := c.ec2.DescribeVolumeStatusWithContext(ctx, request)
+		if err != nil {
+			if isAWSErrorVolumeNotFound(err) {
+				return nil, ErrNotFound
+			}
+			return nil, fmt.Errorf("could not get status of volumes: %w", err)
+		}
+		for _, item := range response.VolumeStatuses {
+			statuses[aws.StringValue(item.VolumeId)] = newVolumeStatus(item)
+		}
+		if aws.StringValue(response.NextToken) == "" {
+			break
+		}
+		request.NextToken = response.NextToken
+	}
+	return statuses, nil
 }
 
-func (c *cloud) DeleteSnapshot(ctx context.Context, snapshotID string) (success bool, err error) {
-	request := &ec2.DeleteSnapshotInput{}
-	request.SnapshotId = aws.String(snapshotID)
-	request.DryRun = aws.Bool(false)
-	if _, err := c.ec2.DeleteSnapshotWithContext(ctx, request); err != nil {
-		if isAWSErrorSnapshotNotFound(err) {
-			return false, ErrNotFound
+// newVolumeStatus summarizes the status checks, attachment statuses and
+// scheduled events of a volume.
+func newVolumeStatus(item *ec2.VolumeStatusItem) *VolumeStatus {
+	status := &VolumeStatus{}
+	if item.VolumeStatus != nil {
+		status.Status = aws.StringValue(item.VolumeStatus.Status)
+		for _, detail := range item.VolumeStatus.Details {
+			// Passed checks are "passed", I/O performance is "normal" when fine
+			switch detailStatus := aws.StringValue(detail.Status); detailStatus {
+			case "passed", "normal", "not-applicable":
+			default:
+				status.Problems = append(status.Problems, fmt.Sprintf("%s: %s", aws.StringValue(detail.Name), detailStatus))
+			}
 		}
-		return false, fmt.Errorf("DeleteSnapshot could not delete volume: %w", err)
 	}
-	return true, nil
+	for _, attachment := range item.AttachmentStatuses {
+		if ioPerformance := aws.StringValue(attachment.IoPerformance); ioPerformance != "" && ioPerformance != "normal" {
+			status.Problems = append(status.Problems, fmt.Sprintf("io-performance on %s: %s", aws.StringValue(attachment.InstanceId), ioPerformance))
+		}
+	}
+	for _, event := range item.Events {
+		status.Problems = append(status.Problems, fmt.Sprintf("%s: %s", aws.StringValue(event.EventType), aws.StringValue(event.Description)))
+	}
+	return status
 }
 
-func (c *cloud) GetSnapshotByName(ctx context.Context, name string) (snapshot *Snapshot, err error) {
-	request := &ec2.DescribeSnapshotsInput{
+// GetAvailableDisksByTags returns the volumes that are not attached to any
+// instance and have all of the given tags.
+func (c *cloud) GetAvailableDisksByTags(ctx context.Context, tags map[string]string) ([]*Disk, error) {
+	request := &ec2.DescribeVolumesInput{
 		Filters: []*ec2.Filter{
 			{
-				Name:   aws.String("tag:" + SnapshotNameTagKey),
+				Name:   aws.String("status"),
+				Values: []*string{aws.String(ec2.VolumeStateAvailable)},
+			},
+		},
+	}
+	for key, value := range tags {
+		request.Filters = append(request.Filters, &ec2.Filter{
+			Name:   aws.String("tag:" + key),
+			Values: []*string{aws.String(value)},
+		})
+	}
+	volumes, err := describeVolumes(ctx, c.ec2, request)
+	if err != nil {
+		return nil, fmt.Errorf("could not list available volumes: %w", err)
+	}
+
+	disks := make([]*Disk, 0, len(volumes))
+	for _, volume := range volumes {
+		disks = append(disks, &Disk{
+			VolumeID:         aws.StringValue(volume.VolumeId),
+			CapacityGiB:      aws.Int64Value(volume.Size),
+			AvailabilityZone: aws.StringValue(volume.AvailabilityZone),
+			Tags:             getVolumeTags(volume),
+			CreateTime:       aws.TimeValue(volume.CreateTime),
+		})
+	}
+	return disks, nil
+}
+
+func (c *cloud) IsExistInstance(ctx context.Context, nodeID string) bool {
+	instance, err := c.getInstance(ctx, nodeID)
+	if err != nil || instance == nil {
+		return false
+	}
+	return true
+}
+
+// kmsKeyAccessActions are the KMS permissions an instance role needs in order
+// to use an encrypted volume once it is attached.
+var kmsKeyAccessActions = []string{"kms:CreateGrant", "kms:Decrypt", "kms:GenerateDataKeyWithoutPlaintext"}
+
+// kmsAccessCacheTTL is how long a role that was allowed to use a KMS key is
+// not checked again. Denials are not cached so that fixing the policy of a
+// role or key takes effect on the next attach.
+const kmsAccessCacheTTL = 10 * time.Minute
+
+type kmsAccess struct {
+	roleArn string
+	keyID   string
+}
+
+// kmsAccessCache caches the roles allowed to use KMS keys, so that attaching
+// many volumes encrypted with the same key to the nodes of the same role calls
+// IAM once. A nil cache caches nothing.
+type kmsAccessCache struct {
+	mu      sync.Mutex
+	allowed map[kmsAccess]time.Time
+	now     func() time.Time
+}
+
+func newKMSAccessCache() *kmsAccessCache {
+	return &kmsAccessCache{
+		allowed: map[kmsAccess]time.Time{},
+		now:     time.Now,
+	}
+}
+
+func (k *kmsAccessCache) isAllowed(access kmsAccess) bool {
+	if k == nil {
+		return false
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	expiry, ok := k.allowed[access]
+	if ok && k.now().After(expiry) {
+		delete(k.allowed, access)
+		return false
+	}
+	return ok
+}
+
+func (k *kmsAccessCache) allow(access kmsAccess) {
+	if k == nil {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.allowed[access] = k.now().Add(kmsAccessCacheTTL)
+}
+
+// CheckKMSKeyAccess verifies that the instance role of nodeID is allowed to
+// use the KMS key volumeID is encrypted with. Unencrypted volumes always pass.
+// The policy of the key is taken into account, since it may grant or deny the
+// role access regardless of the role's own policies.
+func (c *cloud) CheckKMSKeyAccess(ctx context.Context, volumeID, nodeID string) error {
+	logger := klog.FromContext(ctx)
+	volume, err := c.getVolumeByID(ctx, volumeID)
+	if err != nil {
+		return err
+	}
+	keyID := aws.StringValue(volume.KmsKeyId)
+	if !aws.BoolValue(volume.Encrypted) || keyID == "" {
+		return nil
+	}
+
+	instance, err := c.getInstance(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	if instance.IamInstanceProfile == nil || aws.StringValue(instance.IamInstanceProfile.Arn) == "" {
+		return fmt.Errorf("%w: instance %q has no instance profile", ErrKMSKeyAccessDenied, nodeID)
+	}
+
+	// The instance profile ARN has the form arn:aws:iam::<account>:instance-profile/<path>/<name>
+	profileArn := aws.StringValue(instance.IamInstanceProfile.Arn)
+	profileName := profileArn[strings.LastIndex(profileArn, "/")+1:]
+	profile, err := c.iam.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(profileName),
+	})
+	if err != nil {
+		return fmt.Errorf("could not get instance profile %q: %w", profileName, err)
+	}
+	if profile.InstanceProfile == nil || len(profile.InstanceProfile.Roles) == 0 {
+		return fmt.Errorf("%w: instance profile %q has no role", ErrKMSKeyAccessDenied, profileName)
+	}
+
+	var (
+		keyPolicy *string
+		denials   []error
+	)
+	for _, role := range profile.InstanceProfile.Roles {
+		roleArn := aws.StringValue(role.Arn)
+		access := kmsAccess{roleArn: roleArn, keyID: keyID}
+		if c.kmsAccess.isAllowed(access) {
+			logger.V(4).Info("CheckKMSKeyAccess: node has access to volume KMS key", "volumeID", volumeID, "nodeID", nodeID, "kmsKeyID", keyID, "roleArn", roleArn, "cached", true)
+			return nil
+		}
+
+		if keyPolicy == nil {
+			policy, err := c.kms.GetKeyPolicyWithContext(ctx, &kms.GetKeyPolicyInput{
+				KeyId:      aws.String(keyID),
+				PolicyName: aws.String("default"),
+			})
+			if err != nil {
+				return fmt.Errorf("could not get policy of key %q: %w", keyID, err)
+			}
+			keyPolicy = policy.Policy
+		}
+
+		denied, err := c.simulateKMSKeyAccess(ctx, roleArn, keyID, keyPolicy)
+		if err != nil {
+			return err
+		}
+		if len(denied) == 0 {
+			c.kmsAccess.allow(access)
+			logger.V(4).Info("CheckKMSKeyAccess: node has access to volume KMS key", "volumeID", volumeID, "nodeID", nodeID, "kmsKeyID", keyID, "roleArn", roleArn)
+			return nil
+		}
+		denials = append(denials, fmt.Errorf("role %q is not allowed %v", roleArn, denied))
+	}
+	return fmt.Errorf("%w: no role of instance profile %q may use key %q: %w", ErrKMSKeyAccessDenied, profileName, keyID, errors.Join(denials...))
+}
+
+// simulateKMSKeyAccess simulates the policies of roleArn together with the
+// policy of keyID and returns the kmsKeyAccessActions that are not allowed.
+func (c *cloud) simulateKMSKeyAccess(ctx context.Context, roleArn, keyID string, keyPolicy *string) ([]string, error) {
+	var denied []string
+	request := &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(roleArn),
+		ActionNames:     aws.StringSlice(kmsKeyAccessActions),
+		ResourceArns:    []*string{aws.String(keyID)},
+		ResourcePolicy:  keyPolicy,
+	}
+	err := c.iam.SimulatePrincipalPolicyPagesWithContext(ctx, request, func(page *iam.SimulatePolicyResponse, lastPage bool) bool {
+		for _, result := range page.EvaluationResults {
+			if aws.StringValue(result.EvalDecision) != iam.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, aws.StringValue(result.EvalActionName))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not simulate policy of role %q: %w", roleArn, err)
+	}
+	return denied, nil
+}
+
+func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (snapshot *Snapshot, err error) {
+	defer func() { recordOperationResult("CreateSnapshot", err) }()
+	descriptions := "Created by AWS EBS CSI driver for volume " + volumeID
+
+	var tags []*ec2.Tag
+	for key, value := range snapshotOptions.Tags {
+		copiedKey := key
+		copiedValue := value
+		tags = append(tags, &ec2.Tag{Key: &copiedKey, Value: &copiedValue})
+	}
+	tagSpec := ec2.TagSpecification{
+		ResourceType: aws.String("snapshot"),
+		Tags:         tags,
+	}
+	request := &ec2.CreateSnapshotInput{
+		VolumeId:          aws.String(volumeID),
+		DryRun:            aws.Bool(false),
+		TagSpecifications: []*ec2.TagSpecification{&tagSpec},
+		Description:       aws.String(descriptions),
+	}
+	if len(snapshotOptions.OutpostArn) > 0 {
+		request.OutpostArn = aws.String(snapshotOptions.OutpostArn)
+	}
+
+	res, err := c.ec2.CreateSnapshotWithContext(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot of volume %s: %w", volumeID, err)
+	}
+	if res == nil {
+		return nil, fmt.Errorf("nil CreateSnapshotResponse")
+	}
+
+	return c.ec2SnapshotResponseToStruct(res), nil
+}
+
+func (c *cloud) DeleteSnapshot(ctx context.Context, snapshotID string) (success bool, err error) {
+	defer func() { recordOperationResult("DeleteSnapshot", ignoreNotFound(err)) }()
+	request := &ec2.DeleteSnapshotInput{}
+	request.SnapshotId = aws.String(snapshotID)
+	request.DryRun = aws.Bool(false)
+	if _, err := c.ec2.DeleteSnapshotWithContext(ctx, request); err != nil {
+		if isAWSErrorSnapshotNotFound(err) {
+			return false, ErrNotFound
+		}
+		return false, fmt.Errorf("DeleteSnapshot could not delete volume: %w", err)
+	}
+	return true, nil
+}
+
+func (c *cloud) GetSnapshotByName(ctx context.Context, name string) (snapshot *Snapshot, err error) {
+	request := &ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + SnapshotNameTagKey),
 				Values: []*string{aws.String(name)},
 			},
 		},
@@ -996,12 +1757,118 @@ func (c *cloud) GetSnapshotByID(ctx context.Context, snapshotID string) (snapsho
 
 	ec2snapshot, err := c.getSnapshot(ctx, request)
 	if err != nil {
+		if isAWSErrorSnapshotNotFound(err) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
 	return c.ec2SnapshotResponseToStruct(ec2snapshot), nil
 }
 
+// copySnapshotPollInterval is the time between checks of the state of a
+// snapshot being copied from another region.
+var copySnapshotPollInterval = 15 * time.Second
+
+// CopySnapshotFromRegion copies the snapshot sourceSnapshotID of sourceRegion
+// into the region of the driver, tagged with CopiedSnapshotTagKey, and waits
+// for the copy to complete, so that volumes can be restored from it. A copy
+// made before, e.g. by a CreateVolume that timed out while the copy was in
+// progress, is reused rather than copied again.
+func (c *cloud) CopySnapshotFromRegion(ctx context.Context, sourceSnapshotID, sourceRegion string, copyOptions *CopySnapshotOptions) (snapshot *Snapshot, err error) {
+	logger := klog.FromContext(ctx)
+	defer func() { recordOperationResult("CopySnapshot", err) }()
+	copiedFrom := sourceRegion + "/" + sourceSnapshotID
+
+	snapshotID, err := c.getCopiedSnapshotID(ctx, copiedFrom)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotID != "" {
+		logger.V(4).Info("Snapshot already copied from region; waiting for copy", "sourceSnapshotID", sourceSnapshotID, "sourceRegion", sourceRegion, "snapshotID", snapshotID)
+	} else {
+		tags := []*ec2.Tag{{Key: aws.String(CopiedSnapshotTagKey), Value: aws.String(copiedFrom)}}
+		for key, value := range copyOptions.Tags {
+			tags = append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		request := &ec2.CopySnapshotInput{
+			SourceSnapshotId: aws.String(sourceSnapshotID),
+			SourceRegion:     aws.String(sourceRegion),
+			Description:      aws.String("Copied by AWS EBS CSI driver from snapshot " + copiedFrom),
+			TagSpecifications: []*ec2.TagSpecification{
+				{
+					ResourceType: aws.String("snapshot"),
+					Tags:         tags,
+				},
+			},
+		}
+		if copyOptions.Encrypted {
+			request.Encrypted = aws.Bool(true)
+			if len(copyOptions.KmsKeyID) > 0 {
+				request.KmsKeyId = aws.String(copyOptions.KmsKeyID)
+			}
+		}
+
+		response, err := c.ec2.CopySnapshotWithContext(ctx, request)
+		if err != nil {
+			if isAWSErrorSnapshotNotFound(err) {
+				return nil, ErrNotFound
+			}
+			return nil, fmt.Errorf("could not copy snapshot %s: %w", copiedFrom, err)
+		}
+		snapshotID = aws.StringValue(response.SnapshotId)
+		logger.Info("Copying snapshot from region", "sourceSnapshotID", sourceSnapshotID, "sourceRegion", sourceRegion, "snapshotID", snapshotID)
+	}
+
+	var ec2snapshot *ec2.Snapshot
+	err = wait.PollUntilContextCancel(ctx, copySnapshotPollInterval, true, func(ctx context.Context) (bool, error) {
+		ec2snapshot, err = c.getSnapshot(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: []*string{aws.String(snapshotID)}})
+		if err != nil {
+			return false, err
+		}
+		switch aws.StringValue(ec2snapshot.State) {
+		case ec2.SnapshotStateCompleted:
+			return true, nil
+		case ec2.SnapshotStateError:
+			return false, fmt.Errorf("copy %s of snapshot %s failed: %s", snapshotID, copiedFrom, aws.StringValue(ec2snapshot.StateMessage))
+		}
+		logger.V(4).Info("Waiting for snapshot copy to complete", "snapshotID", snapshotID, "progress", aws.StringValue(ec2snapshot.Progress))
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not wait for copy %s of snapshot %s: %w", snapshotID, copiedFrom, err)
+	}
+	return c.ec2SnapshotResponseToStruct(ec2snapshot), nil
+}
+
+// getCopiedSnapshotID returns the ID of a copy, pending or completed, of the
+// snapshot copiedFrom, if any. Failed copies are ignored.
+func (c *cloud) getCopiedSnapshotID(ctx context.Context, copiedFrom string) (string, error) {
+	request := &ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + CopiedSnapshotTagKey),
+				Values: []*string{aws.String(copiedFrom)},
+			},
+		},
+		OwnerIds: []*string{aws.String("self")},
+	}
+	var snapshotID string
+	err := c.ec2.DescribeSnapshotsPagesWithContext(ctx, request, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+		for _, snapshot := range page.Snapshots {
+			if aws.StringValue(snapshot.State) != ec2.SnapshotStateError {
+				snapshotID = aws.StringValue(snapshot.SnapshotId)
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not look up copies of snapshot %s: %w", copiedFrom, err)
+	}
+	return snapshotID, nil
+}
+
 // ListSnapshots retrieves AWS EBS snapshots for an optionally specified volume ID.  If maxResults is set, it will return up to maxResults snapshots.  If there are more snapshots than maxResults,
 // a next token value will be returned to the client as well.  They can use this token with subsequent calls to retrieve the next page of results.  If maxResults is not set (0),
 // there will be no restriction up to 1000 results (https://docs.aws.amazon.com/sdk-for-go/api/service/ec2/#DescribeSnapshotsInput).
@@ -1045,6 +1912,160 @@ func (c *cloud) ListSnapshots(ctx context.Context, volumeID string, maxResults i
 	}, nil
 }
 
+// CreateGroupSnapshot creates crash-consistent snapshots of volumeIDs at the
+// same point in time. The volumes must all be attached to the same instance,
+// whose other volumes are excluded from the snapshots.
+func (c *cloud) CreateGroupSnapshot(ctx context.Context, volumeIDs []string, snapshotOptions *SnapshotOptions) (snapshots []*Snapshot, err error) {
+	logger := klog.FromContext(ctx)
+	defer func() { recordOperationResult("CreateGroupSnapshot", err) }()
+	instanceID, err := c.attachedInstance(ctx, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The live block device mappings are needed, as a volume attached since
+	// the instance was cached would be snapshotted too
+	instance, err := c.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := map[string]bool{}
+	for _, volumeID := range volumeIDs {
+		requested[volumeID] = true
+	}
+	// EC2 only allows to exclude the root volume with ExcludeBootVolume
+	excludeBootVolume := true
+	var excludeDataVolumeIDs []*string
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		volumeID := aws.StringValue(mapping.Ebs.VolumeId)
+		isRoot := aws.StringValue(mapping.DeviceName) == aws.StringValue(instance.RootDeviceName)
+		switch {
+		case requested[volumeID] && isRoot:
+			excludeBootVolume = false
+		case !requested[volumeID] && !isRoot:
+			excludeDataVolumeIDs = append(excludeDataVolumeIDs, aws.String(volumeID))
+		}
+	}
+
+	var tags []*ec2.Tag
+	for key, value := range snapshotOptions.Tags {
+		tags = append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	request := &ec2.CreateSnapshotsInput{
+		InstanceSpecification: &ec2.InstanceSpecification{
+			InstanceId:           aws.String(instanceID),
+			ExcludeBootVolume:    aws.Bool(excludeBootVolume),
+			ExcludeDataVolumeIds: excludeDataVolumeIDs,
+		},
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String("snapshot"),
+				Tags:         tags,
+			},
+		},
+		Description: aws.String("Created by AWS EBS CSI driver for volumes " + strings.Join(volumeIDs, ", ")),
+	}
+	if len(snapshotOptions.OutpostArn) > 0 {
+		request.OutpostArn = aws.String(snapshotOptions.OutpostArn)
+	}
+
+	res, err := c.ec2.CreateSnapshotsWithContext(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshots of volumes %v: %w", volumeIDs, err)
+	}
+	for _, info := range res.Snapshots {
+		snapshots = append(snapshots, snapshotInfoToStruct(info))
+	}
+
+	// A volume may have been attached to or detached from the instance
+	// since it was described, so the snapshots are not of the volumes
+	if len(snapshots) != len(volumeIDs) || slices.ContainsFunc(snapshots, func(snapshot *Snapshot) bool { return !requested[snapshot.SourceVolumeID] }) {
+		for _, snapshot := range snapshots {
+			if _, deleteErr := c.DeleteSnapshot(ctx, snapshot.SnapshotID); deleteErr != nil {
+				logger.Error(deleteErr, "Could not delete snapshot of unexpected group snapshot", "snapshotID", snapshot.SnapshotID)
+			}
+		}
+		return nil, fmt.Errorf("the volumes attached to instance %s changed while creating snapshots of volumes %v, got %d snapshots", instanceID, volumeIDs, len(snapshots))
+	}
+	return snapshots, nil
+}
+
+// attachedInstance returns the ID of an instance all of volumeIDs are
+// attached to.
+func (c *cloud) attachedInstance(ctx context.Context, volumeIDs []string) (string, error) {
+	response, err := c.ec2.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: aws.StringSlice(volumeIDs),
+	})
+	if err != nil {
+		if isAWSErrorVolumeNotFound(err) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("could not describe volumes %v: %w", volumeIDs, err)
+	}
+
+	// Multi-attach volumes may be attached to several instances
+	attachedVolumes := map[string]int{}
+	for _, volume := range response.Volumes {
+		for _, attachment := range volume.Attachments {
+			if aws.StringValue(attachment.State) == volumeAttachedState {
+				attachedVolumes[aws.StringValue(attachment.InstanceId)]++
+			}
+		}
+	}
+	var instanceIDs []string
+	for instanceID, count := range attachedVolumes {
+		if count == len(volumeIDs) {
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+	}
+	if len(instanceIDs) == 0 {
+		return "", fmt.Errorf("%w: %v", ErrNotAttachedToSameInstance, volumeIDs)
+	}
+	slices.Sort(instanceIDs)
+	return instanceIDs[0], nil
+}
+
+// ListGroupSnapshots returns the snapshots of the volume group snapshot
+// groupSnapshotID, which are tagged with its ID.
+func (c *cloud) ListGroupSnapshots(ctx context.Context, groupSnapshotID string) ([]*Snapshot, error) {
+	request := &ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + GroupSnapshotIDTagKey),
+				Values: []*string{aws.String(groupSnapshotID)},
+			},
+		},
+		OwnerIds: []*string{aws.String("self")},
+	}
+	var snapshots []*Snapshot
+	err := c.ec2.DescribeSnapshotsPagesWithContext(ctx, request, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+		for _, snapshot := range page.Snapshots {
+			snapshots = append(snapshots, c.ec2SnapshotResponseToStruct(snapshot))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list snapshots of group snapshot %s: %w", groupSnapshotID, err)
+	}
+	return snapshots, nil
+}
+
+// snapshotInfoToStruct converts a snapshot created by CreateSnapshots to
+// the internal struct.
+func snapshotInfoToStruct(info *ec2.SnapshotInfo) *Snapshot {
+	return &Snapshot{
+		SnapshotID:     aws.StringValue(info.SnapshotId),
+		SourceVolumeID: aws.StringValue(info.VolumeId),
+		Size:           util.GiBToBytes(aws.Int64Value(info.VolumeSize)),
+		CreationTime:   aws.TimeValue(info.StartTime),
+		ReadyToUse:     aws.StringValue(info.State) == "completed",
+		Encrypted:      aws.BoolValue(info.Encrypted),
+	}
+}
+
 // Helper method converting EC2 snapshot type to the internal struct
 func (c *cloud) ec2SnapshotResponseToStruct(ec2Snapshot *ec2.Snapshot) *Snapshot {
 	if ec2Snapshot == nil {
@@ -1056,6 +2077,8 @@ func (c *cloud) ec2SnapshotResponseToStruct(ec2Snapshot *ec2.Snapshot) *Snapshot
 		SourceVolumeID: aws.StringValue(ec2Snapshot.VolumeId),
 		Size:           snapshotSize,
 		CreationTime:   aws.TimeValue(ec2Snapshot.StartTime),
+		Encrypted:      aws.BoolValue(ec2Snapshot.Encrypted),
+		KmsKeyID:       aws.StringValue(ec2Snapshot.KmsKeyId),
 	}
 	if aws.StringValue(ec2Snapshot.State) == "completed" {
 		snapshot.ReadyToUse = true
@@ -1067,13 +2090,14 @@ func (c *cloud) ec2SnapshotResponseToStruct(ec2Snapshot *ec2.Snapshot) *Snapshot
 }
 
 func (c *cloud) EnableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) (*ec2.EnableFastSnapshotRestoresOutput, error) {
+	logger := klog.FromContext(ctx)
 	request := &ec2.EnableFastSnapshotRestoresInput{
 		AvailabilityZones: aws.StringSlice(availabilityZones),
 		SourceSnapshotIds: []*string{
 			aws.String(snapshotID),
 		},
 	}
-	klog.V(4).InfoS("Creating Fast Snapshot Restores", "snapshotID", snapshotID, "availabilityZones", availabilityZones)
+	logger.V(4).Info("Creating Fast Snapshot Restores", "snapshotID", snapshotID, "availabilityZones", availabilityZones)
 	response, err := c.ec2.EnableFastSnapshotRestoresWithContext(ctx, request)
 	if err != nil {
 		return nil, err
@@ -1084,6 +2108,162 @@ func (c *cloud) EnableFastSnapshotRestores(ctx context.Context, availabilityZone
 	return response, nil
 }
 
+func (c *cloud) DisableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) error {
+	logger := klog.FromContext(ctx)
+	request := &ec2.DisableFastSnapshotRestoresInput{
+		AvailabilityZones: aws.StringSlice(availabilityZones),
+		SourceSnapshotIds: []*string{
+			aws.String(snapshotID),
+		},
+	}
+	logger.V(4).Info("Disabling Fast Snapshot Restores", "snapshotID", snapshotID, "availabilityZones", availabilityZones)
+	response, err := c.ec2.DisableFastSnapshotRestoresWithContext(ctx, request)
+	if err != nil {
+		return err
+	}
+	if len(response.Unsuccessful) > 0 {
+		return fmt.Errorf("failed to disable fast snapshot restores for snapshot %s: %v", snapshotID, response.Unsuccessful)
+	}
+	return nil
+}
+
+// GetFastSnapshotRestoreZones returns the availability zones fast snapshot
+// restores are enabled, or being enabled, in for the given snapshot.
+func (c *cloud) GetFastSnapshotRestoreZones(ctx context.Context, snapshotID string) ([]string, error) {
+	request := &ec2.DescribeFastSnapshotRestoresInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("snapshot-id"),
+				Values: []*string{aws.String(snapshotID)},
+			},
+			{
+				Name:   aws.String("state"),
+				Values: aws.StringSlice([]string{"enabling", "optimizing", "enabled"}),
+			},
+		},
+	}
+	var zones []string
+	err := c.ec2.DescribeFastSnapshotRestoresPagesWithContext(ctx, request, func(page *ec2.DescribeFastSnapshotRestoresOutput, lastPage bool) bool {
+		for _, fsr := range page.FastSnapshotRestores {
+			zones = append(zones, aws.StringValue(fsr.AvailabilityZone))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// GetFastSnapshotRestoreState returns the state of fast snapshot restores of
+// snapshotID in availabilityZone, e.g. "optimizing" or "enabled", or an empty
+// string if they were never enabled there.
+func (c *cloud) GetFastSnapshotRestoreState(ctx context.Context, snapshotID, availabilityZone string) (string, error) {
+	request := &ec2.DescribeFastSnapshotRestoresInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("snapshot-id"),
+				Values: []*string{aws.String(snapshotID)},
+			},
+			{
+				Name:   aws.String("availability-zone"),
+				Values: []*string{aws.String(availabilityZone)},
+			},
+		},
+	}
+	var state string
+	err := c.ec2.DescribeFastSnapshotRestoresPagesWithContext(ctx, request, func(page *ec2.DescribeFastSnapshotRestoresOutput, lastPage bool) bool {
+		for _, fsr := range page.FastSnapshotRestores {
+			state = aws.StringValue(fsr.State)
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not describe fast snapshot restores of snapshot %s: %w", snapshotID, err)
+	}
+	return state, nil
+}
+
+// GetSnapshotIDsByTag returns the IDs of the snapshots owned by the account
+// that are tagged with the given key and value.
+func (c *cloud) GetSnapshotIDsByTag(ctx context.Context, tagKey, tagValue string) ([]string, error) {
+	request := &ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + tagKey),
+				Values: []*string{aws.String(tagValue)},
+			},
+		},
+		OwnerIds: []*string{aws.String("self")},
+	}
+	var snapshotIDs []string
+	err := c.ec2.DescribeSnapshotsPagesWithContext(ctx, request, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+		for _, snapshot := range page.Snapshots {
+			snapshotIDs = append(snapshotIDs, aws.StringValue(snapshot.SnapshotId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshotIDs, nil
+}
+
+// GetSnapshotTagValues returns the values of the given tag key of the
+// snapshots owned by the account that are tagged with it, by snapshot ID.
+func (c *cloud) GetSnapshotTagValues(ctx context.Context, tagKey string) (map[string]string, error) {
+	request := &ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(tagKey)},
+			},
+		},
+		OwnerIds: []*string{aws.String("self")},
+	}
+	tagValues := map[string]string{}
+	err := c.ec2.DescribeSnapshotsPagesWithContext(ctx, request, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+		for _, snapshot := range page.Snapshots {
+			for _, tag := range snapshot.Tags {
+				if aws.StringValue(tag.Key) == tagKey {
+					tagValues[aws.StringValue(snapshot.SnapshotId)] = aws.StringValue(tag.Value)
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tagValues, nil
+}
+
+func (c *cloud) TagSnapshot(ctx context.Context, snapshotID string, tags map[string]string) error {
+	request := &ec2.CreateTagsInput{
+		Resources: []*string{aws.String(snapshotID)},
+	}
+	for key, value := range tags {
+		request.Tags = append(request.Tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	if _, err := c.ec2.CreateTagsWithContext(ctx, request); err != nil {
+		return fmt.Errorf("could not tag snapshot %s: %w", snapshotID, err)
+	}
+	return nil
+}
+
+func (c *cloud) UntagSnapshot(ctx context.Context, snapshotID string, tagKeys []string) error {
+	request := &ec2.DeleteTagsInput{
+		Resources: []*string{aws.String(snapshotID)},
+	}
+	for _, key := range tagKeys {
+		request.Tags = append(request.Tags, &ec2.Tag{Key: aws.String(key)})
+	}
+	if _, err := c.ec2.DeleteTagsWithContext(ctx, request); err != nil {
+		return fmt.Errorf("could not untag snapshot %s: %w", snapshotID, err)
+	}
+	return nil
+}
+
 func describeVolumes(ctx context.Context, svc ec2iface.EC2API, request *ec2.DescribeVolumesInput) ([]*ec2.Volume, error) {
 	var volumes []*ec2.Volume
 	var nextToken *string
@@ -1120,7 +2300,42 @@ func (c *cloud) getVolume(ctx context.Context, request *ec2.DescribeVolumesInput
 	}
 }
 
+// getVolumeByID returns the volume volumeID from the describe cache, if
+// cached, or describes it. Callers waiting for the volume to change state
+// must describe it with getVolume instead.
+func (c *cloud) getVolumeByID(ctx context.Context, volumeID string) (*ec2.Volume, error) {
+	if volume, ok := c.describes.volume(volumeID); ok {
+		return volume, nil
+	}
+	volume, err := c.getVolume(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: []*string{aws.String(volumeID)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.describes.put(volumeID, volume)
+	return volume, nil
+}
+
+// getInstance returns the instance nodeID from the describe cache, if cached,
+// or describes it.
 func (c *cloud) getInstance(ctx context.Context, nodeID string) (*ec2.Instance, error) {
+	if instance, ok := c.describes.instance(nodeID); ok {
+		return instance, nil
+	}
+	instance, err := c.describeInstance(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	c.describes.put(nodeID, instance)
+	return instance, nil
+}
+
+func (c *cloud) describeInstance(ctx context.Context, nodeID string) (*ec2.Instance, error) {
+	if c.bm != nil {
+		return c.batchDescribeInstances(nodeID)
+	}
+
 	instances := []*ec2.Instance{}
 	request := &ec2.DescribeInstancesInput{
 		InstanceIds: []*string{&nodeID},
@@ -1274,6 +2489,26 @@ func isAWSErrorInvalidAttachmentNotFound(err error) bool {
 	return isAWSError(err, "InvalidAttachment.NotFound")
 }
 
+// isAWSErrorAttachmentPointInUse returns a boolean indicating whether the
+// given error is an AWS InvalidParameterValue error reported when the device
+// name of an attachment is already in use on the instance.
+func isAWSErrorAttachmentPointInUse(err error) bool {
+	var awsErr awserr.Error
+	return isAWSError(err, "InvalidParameterValue") && errors.As(err, &awsErr) && strings.Contains(awsErr.Message(), "is already in use")
+}
+
+// isAttachOutcomeUnknown returns a boolean indicating whether the given
+// AttachVolume error leaves it unknown if EC2 started the attachment, e.g.
+// because the request timed out or EC2 failed internally. Only errors that
+// EC2 responded with a client error status to are known to be rejections.
+func isAttachOutcomeUnknown(err error) bool {
+	var requestFailure awserr.RequestFailure
+	if errors.As(err, &requestFailure) {
+		return requestFailure.StatusCode() >= 500
+	}
+	return true
+}
+
 // isAWSErrorModificationNotFound returns a boolean indicating whether the given
 // error is an AWS InvalidVolumeModification.NotFound error
 func isAWSErrorModificationNotFound(err error) bool {
@@ -1294,6 +2529,26 @@ func isAWSErrorIdempotentParameterMismatch(err error) bool {
 	return isAWSError(err, "IdempotentParameterMismatch")
 }
 
+// ErrorReason classifies an AWS error into one of the ErrorReason* constants
+// and returns it together with the AWS error code. The reason is empty if the
+// error is not an AWS error or its code is not classified.
+func ErrorReason(err error) (reason string, awsCode string) {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return "", ""
+	}
+	code := awsErr.Code()
+	if reason, ok := errorReasons[code]; ok {
+		return reason, code
+	}
+	// KMS failures are reported with several codes, e.g. KMS.DisabledException
+	// or InvalidKMSKey.InvalidState
+	if strings.Contains(strings.ToLower(code), "kms") {
+		return ErrorReasonKMS, code
+	}
+	return "", code
+}
+
 // Checks for desired size on volume by also verifying volume size by describing volume.
 // This is to get around potential eventual consistency problems with describing volume modifications
 // objects and ensuring that we read two different objects to verify volume state.
@@ -1398,6 +2653,20 @@ func (c *cloud) randomAvailabilityZone(ctx context.Context) (string, error) {
 	return zones[0], nil
 }
 
+// CheckConnectivity checks that EC2 can be reached and accepts the credentials
+// of the driver with a dry run of DescribeAvailabilityZones.
+func (c *cloud) CheckConnectivity(ctx context.Context) error {
+	// A dry run that would have succeeded fails with DryRunOperation
+	_, err := c.ec2.DescribeAvailabilityZonesWithContext(ctx, &ec2.DescribeAvailabilityZonesInput{DryRun: aws.Bool(true)})
+	if isAWSError(err, "DryRunOperation") {
+		return nil
+	}
+	if err == nil {
+		return errors.New("dry run of DescribeAvailabilityZones unexpectedly succeeded")
+	}
+	return fmt.Errorf("could not reach EC2: %w", err)
+}
+
 // AvailabilityZones returns availability zones from the given region
 func (c *cloud) AvailabilityZones(ctx context.Context) (map[string]struct{}, error) {
 	response, err := c.ec2.DescribeAvailabilityZonesWithContext(ctx, &ec2.DescribeAvailabilityZonesInput{})
@@ -1432,6 +2701,7 @@ func needsVolumeModification(volume *ec2.Volume, newSizeGiB int64, options *Modi
 }
 
 func (c *cloud) validateModifyVolume(ctx context.Context, volumeID string, newSizeGiB int64, options *ModifyDiskOptions) (bool, int64, error) {
+	logger := klog.FromContext(ctx)
 	request := &ec2.DescribeVolumesInput{
 		VolumeIds: []*string{
 			aws.String(volumeID),
@@ -1455,7 +2725,7 @@ func (c *cloud) validateModifyVolume(ctx context.Context, volumeID string, newSi
 		state = aws.StringValue(latestMod.ModificationState)
 		if state == ec2.VolumeModificationStateModifying {
 			// If volume is already modifying, detour to waiting for it to modify
-			klog.V(5).InfoS("[Debug] Watching ongoing modification", "volumeID", volumeID)
+			logger.V(5).Info("[Debug] Watching ongoing modification", "volumeID", volumeID)
 			err = c.waitForVolumeModification(ctx, volumeID)
 			if err != nil {
 				return true, oldSizeGiB, err
@@ -1468,7 +2738,7 @@ func (c *cloud) validateModifyVolume(ctx context.Context, volumeID string, newSi
 	// At this point, we know we are starting a new volume modification
 	// If we're asked to modify a volume to its current state, ignore the request and immediately return a success
 	if !needsVolumeModification(volume, newSizeGiB, options) {
-		klog.V(5).InfoS("[Debug] Skipping modification for volume due to matching stats", "volumeID", volumeID)
+		logger.V(5).Info("[Debug] Skipping modification for volume due to matching stats", "volumeID", volumeID)
 		// Wait for any existing modifications to prevent race conditions where DescribeVolume(s) returns the new
 		// state before the volume is actually finished modifying
 		err = c.waitForVolumeModification(ctx, volumeID)
@@ -1504,8 +2774,19 @@ func getVolumeAttachmentsList(volume *ec2.Volume) []string {
 	return volumeAttachmentList
 }
 
+func getVolumeTags(volume *ec2.Volume) map[string]string {
+	if len(volume.Tags) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(volume.Tags))
+	for _, tag := range volume.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags
+}
+
 // Calculate actual IOPS for a volume and cap it at supported AWS limits.
-func capIOPS(volumeType string, requestedCapacityGiB int64, requestedIops int64, minTotalIOPS, maxTotalIOPS, maxIOPSPerGB int64, allowIncrease bool) (int64, error) {
+func capIOPS(volumeType string, requestedCapacityGiB int64, requestedIops int64, limits volumeTypeLimits, allowIncrease bool) (int64, error) {
 	// If requestedIops is zero the user did not request a specific amount, and the default will be used instead
 	if requestedIops == 0 {
 		return 0, nil
@@ -1513,24 +2794,83 @@ func capIOPS(volumeType string, requestedCapacityGiB int64, requestedIops int64,
 
 	iops := requestedIops
 
-	if iops < minTotalIOPS {
+	if iops < limits.minIOPS {
 		if allowIncrease {
-			iops = minTotalIOPS
+			iops = limits.minIOPS
 			klog.V(5).InfoS("[Debug] Increased IOPS to the min supported limit", "volumeType", volumeType, "requestedCapacityGiB", requestedCapacityGiB, "limit", iops)
 		} else if volumeType == VolumeTypeGP3 {
 			klog.V(5).InfoS("[Debug] Did not increase IOPS", "volumeType", volumeType, "requestedCapacityGiB", requestedCapacityGiB)
 		} else {
-			return 0, fmt.Errorf("invalid IOPS: %d is too low, it must be at least %d", iops, minTotalIOPS)
+			return 0, fmt.Errorf("%w: IOPS %d is too low, %s volumes must have at least %d IOPS", ErrInvalidVolumeParameters, iops, volumeType, limits.minIOPS)
 		}
 	}
-	if iops > maxTotalIOPS {
-		iops = maxTotalIOPS
-		klog.V(5).InfoS("[Debug] Capped IOPS, volume at the max supported limit", "volumeType", volumeType, "requestedCapacityGiB", requestedCapacityGiB, "limit", iops)
-	}
-	maxIopsByCapacity := maxIOPSPerGB * requestedCapacityGiB
-	if iops > maxIopsByCapacity && maxIopsByCapacity >= minTotalIOPS {
-		iops = maxIopsByCapacity
-		klog.V(5).InfoS("[Debug] Capped IOPS for volume", "volumeType", volumeType, "requestedCapacityGiB", requestedCapacityGiB, "maxIOPSPerGB", maxIOPSPerGB, "limit", iops)
+	if maxIops := limits.maxIOPSOf(requestedCapacityGiB); iops > maxIops {
+		iops = maxIops
+		klog.V(5).InfoS("[Debug] Capped IOPS, volume at the max supported limit", "volumeType", volumeType, "requestedCapacityGiB", requestedCapacityGiB, "maxIOPSPerGB", limits.maxIOPSPerGB, "limit", iops)
 	}
 	return iops, nil
 }
+
+// tagPriority ranks tag keys for limitTags, lower is more important: the tags
+// the driver finds its volumes by, then the tags reserved for Kubernetes, then
+// all other tags, e.g. extra tags and tags from StorageClass parameters.
+func tagPriority(key string) int {
+	switch {
+	case key == VolumeNameTagKey, strings.HasPrefix(key, "ebs.csi.aws.com/"):
+		return 0
+	case strings.HasPrefix(key, KubernetesTagKeyPrefix):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// limitTags checks tags against the EC2 limit of tags per resource. If there
+// are too many, it either returns ErrTooManyTags or, if dropExcess is set, drops
+// the tags of the lowest priority, in reverse alphabetical order of their keys.
+func limitTags(volumeName string, tags map[string]string, dropExcess bool) (map[string]string, error) {
+	if len(tags) <= MaxNumTagsPerResource {
+		return tags, nil
+	}
+	if !dropExcess {
+		return nil, fmt.Errorf("%w: volume has %d tags, EC2 allows at most %d", ErrTooManyTags, len(tags), MaxNumTagsPerResource)
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if pi, pj := tagPriority(keys[i]), tagPriority(keys[j]); pi != pj {
+			return pi < pj
+		}
+		return keys[i] < keys[j]
+	})
+	limited := make(map[string]string, MaxNumTagsPerResource)
+	for _, key := range keys[:MaxNumTagsPerResource] {
+		limited[key] = tags[key]
+	}
+	klog.InfoS("Dropping tags of volume over the EC2 limit of tags per resource", "volumeName", volumeName, "limit", MaxNumTagsPerResource, "droppedTags", keys[MaxNumTagsPerResource:])
+	return limited, nil
+}
+
+// capThroughput validates the requested gp3 throughput against the throughput
+// limits of gp3 and the maximum throughput to IOPS ratio. Volumes created
+// without IOPS get the gp3 baseline.
+func capThroughput(requestedThroughput int64, iops int64, limits volumeTypeLimits, allowDecrease bool) (int64, error) {
+	if requestedThroughput < limits.minThroughput {
+		return 0, fmt.Errorf("%w: throughput %d MiB/s is too low, gp3 volumes must have at least %d MiB/s", ErrInvalidVolumeParameters, requestedThroughput, limits.minThroughput)
+	}
+	if iops == 0 {
+		iops = limits.minIOPS
+	}
+	maxThroughput := min(iops/gp3MinIOPSPerMiBps, limits.maxThroughput)
+	if requestedThroughput <= maxThroughput {
+		return requestedThroughput, nil
+	}
+	if !allowDecrease {
+		return 0, fmt.Errorf("%w: throughput %d MiB/s is too high for %d IOPS, gp3 volumes support at most %d MiB/s (0.25 MiB/s per IOPS, up to %d MiB/s)", ErrInvalidVolumeParameters, requestedThroughput, iops, maxThroughput, limits.maxThroughput)
+	}
+	klog.V(5).InfoS("[Debug] Decreased throughput to the max supported for IOPS", "requestedThroughput", requestedThroughput, "iops", iops, "limit", maxThroughput)
+	return maxThroughput, nil
+}
@@ -15,12 +15,27 @@ type Cloud interface {
 	WaitForAttachmentState(ctx context.Context, volumeID, expectedState string, expectedInstance string, expectedDevice string, alreadyAssigned bool) (*ec2.VolumeAttachment, error)
 	GetDiskByName(ctx context.Context, name string, capacityBytes int64) (disk *Disk, err error)
 	GetDiskByID(ctx context.Context, volumeID string) (disk *Disk, err error)
+	GetAvailableDisksByTags(ctx context.Context, tags map[string]string) (disks []*Disk, err error)
+	ListDisks(ctx context.Context, maxResults int64, nextToken string) (listDisksResponse *ListDisksResponse, err error)
+	GetVolumeStatuses(ctx context.Context, volumeIDs []string) (statuses map[string]*VolumeStatus, err error)
 	IsExistInstance(ctx context.Context, nodeID string) (success bool)
+	CheckKMSKeyAccess(ctx context.Context, volumeID, nodeID string) (err error)
 	CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (snapshot *Snapshot, err error)
 	DeleteSnapshot(ctx context.Context, snapshotID string) (success bool, err error)
 	GetSnapshotByName(ctx context.Context, name string) (snapshot *Snapshot, err error)
 	GetSnapshotByID(ctx context.Context, snapshotID string) (snapshot *Snapshot, err error)
+	CopySnapshotFromRegion(ctx context.Context, sourceSnapshotID, sourceRegion string, copyOptions *CopySnapshotOptions) (snapshot *Snapshot, err error)
 	ListSnapshots(ctx context.Context, volumeID string, maxResults int64, nextToken string) (listSnapshotsResponse *ListSnapshotsResponse, err error)
+	CreateGroupSnapshot(ctx context.Context, volumeIDs []string, snapshotOptions *SnapshotOptions) (snapshots []*Snapshot, err error)
+	ListGroupSnapshots(ctx context.Context, groupSnapshotID string) (snapshots []*Snapshot, err error)
 	EnableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) (*ec2.EnableFastSnapshotRestoresOutput, error)
+	DisableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) (err error)
+	GetFastSnapshotRestoreZones(ctx context.Context, snapshotID string) (availabilityZones []string, err error)
+	GetFastSnapshotRestoreState(ctx context.Context, snapshotID, availabilityZone string) (state string, err error)
+	GetSnapshotIDsByTag(ctx context.Context, tagKey, tagValue string) (snapshotIDs []string, err error)
+	GetSnapshotTagValues(ctx context.Context, tagKey string) (tagValues map[string]string, err error)
+	TagSnapshot(ctx context.Context, snapshotID string, tags map[string]string) (err error)
+	UntagSnapshot(ctx context.Context, snapshotID string, tagKeys []string) (err error)
 	AvailabilityZones(ctx context.Context) (map[string]struct{}, error)
+	CheckConnectivity(ctx context.Context) (err error)
 }
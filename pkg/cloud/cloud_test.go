@@ -18,20 +18,32 @@ package cloud
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"github.com/golang/mock/gomock"
 	dm "github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/devicemanager"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"github.com/stretchr/testify/assert"
 )
@@ -171,7 +183,7 @@ func TestBatchDescribeVolumes(t *testing.T) {
 			mockEC2 := NewMockEC2API(mockCtrl)
 			c := newCloud(mockEC2)
 			cloudInstance := c.(*cloud)
-			cloudInstance.bm = newBatcherManager(cloudInstance.ec2)
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, DefaultBatchingWindow)
 
 			tc.mockFunc(mockEC2, tc.expErr, tc.volumes)
 			volumeIDs, volumeNames := extractVolumeIdentifiers(tc.volumes)
@@ -247,6 +259,97 @@ func executeDescribeVolumesTest(t *testing.T, c *cloud, volumeIDs, volumeNames [
 	}
 }
 
+func TestBatchDescribeInstances(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instanceIDs  []string
+		instances    []*ec2.Instance
+		describeErr  error
+		expNotFound  []string
+		expErr       error
+		expCallCount int
+	}{
+		{
+			name:        "success: concurrent calls are batched",
+			instanceIDs: []string{"i-001", "i-002", "i-003"},
+			instances: []*ec2.Instance{
+				{InstanceId: aws.String("i-001")},
+				{InstanceId: aws.String("i-002")},
+				{InstanceId: aws.String("i-003")},
+			},
+		},
+		{
+			name:        "success: unknown instance is not found without failing the batch",
+			instanceIDs: []string{"i-001", "i-unknown"},
+			instances: []*ec2.Instance{
+				{InstanceId: aws.String("i-001")},
+			},
+			expNotFound: []string{"i-unknown"},
+		},
+		{
+			name:        "fail: DescribeInstances error is returned to all callers",
+			instanceIDs: []string{"i-001", "i-002"},
+			describeErr: errors.New("RequestLimitExceeded"),
+			expErr:      errors.New("error listing AWS instances: RequestLimitExceeded"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+			cloudInstance := c.(*cloud)
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, 200*time.Millisecond)
+
+			mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.DescribeInstancesInput, _ ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+				if len(input.InstanceIds) != 0 || len(input.Filters) != 1 || aws.StringValue(input.Filters[0].Name) != "instance-id" {
+					t.Errorf("Expected instances to be filtered by ID, got %v", input)
+				}
+				assert.ElementsMatch(t, tc.instanceIDs, aws.StringValueSlice(input.Filters[0].Values))
+				if tc.describeErr != nil {
+					return nil, tc.describeErr
+				}
+				return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: tc.instances}}}, nil
+			}).Times(1)
+
+			var wg sync.WaitGroup
+			instances := make([]*ec2.Instance, len(tc.instanceIDs))
+			errs := make([]error, len(tc.instanceIDs))
+			for i, instanceID := range tc.instanceIDs {
+				wg.Add(1)
+				go func(i int, instanceID string) {
+					defer wg.Done()
+					instances[i], errs[i] = cloudInstance.getInstance(context.Background(), instanceID)
+				}(i, instanceID)
+			}
+			wg.Wait()
+
+			for i, instanceID := range tc.instanceIDs {
+				switch {
+				case tc.expErr != nil:
+					if errs[i] == nil || errs[i].Error() != tc.expErr.Error() {
+						t.Errorf("Expected error %v for instance %s, got %v", tc.expErr, instanceID, errs[i])
+					}
+				case slices.Contains(tc.expNotFound, instanceID):
+					if !errors.Is(errs[i], ErrNotFound) {
+						t.Errorf("Expected instance %s not to be found, got %v", instanceID, errs[i])
+					}
+				default:
+					if errs[i] != nil {
+						t.Fatalf("Unexpected error for instance %s: %v", instanceID, errs[i])
+					}
+					assert.Equal(t, instanceID, aws.StringValue(instances[i].InstanceId))
+				}
+			}
+		})
+	}
+}
+
 func TestCreateDisk(t *testing.T) {
 	testCases := []struct {
 		name                 string
@@ -314,14 +417,14 @@ func TestCreateDisk(t *testing.T) {
 			name:       "success: normal with io2 options",
 			volumeName: "vol-test-name",
 			diskOptions: &DiskOptions{
-				CapacityBytes: util.GiBToBytes(1),
+				CapacityBytes: util.GiBToBytes(4),
 				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
 				VolumeType:    VolumeTypeIO2,
-				IOPSPerGB:     100,
+				IOPSPerGB:     25,
 			},
 			expDisk: &Disk{
 				VolumeID:         "vol-test",
-				CapacityGiB:      1,
+				CapacityGiB:      4,
 				AvailabilityZone: defaultZone,
 			},
 			expCreateVolumeInput: &ec2.CreateVolumeInput{
@@ -352,14 +455,14 @@ func TestCreateDisk(t *testing.T) {
 			name:       "success: io2 with IOPS parameter",
 			volumeName: "vol-test-name",
 			diskOptions: &DiskOptions{
-				CapacityBytes: util.GiBToBytes(1),
+				CapacityBytes: util.GiBToBytes(4),
 				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
 				VolumeType:    VolumeTypeIO2,
 				IOPS:          100,
 			},
 			expDisk: &Disk{
 				VolumeID:         "vol-test",
-				CapacityGiB:      1,
+				CapacityGiB:      4,
 				AvailabilityZone: defaultZone,
 			},
 			expCreateVolumeInput: &ec2.CreateVolumeInput{
@@ -388,6 +491,83 @@ func TestCreateDisk(t *testing.T) {
 			},
 			expErr: nil,
 		},
+		{
+			name:       "success: gp3 with throughput at the max ratio for IOPS",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(400),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeGP3,
+				IOPS:          4000,
+				Throughput:    1000,
+			},
+			expDisk: &Disk{
+				VolumeID:         "vol-test",
+				CapacityGiB:      400,
+				AvailabilityZone: defaultZone,
+			},
+			expCreateVolumeInput: &ec2.CreateVolumeInput{
+				Iops:       aws.Int64(4000),
+				Throughput: aws.Int64(1000),
+			},
+			expErr: nil,
+		},
+		{
+			name:       "fail: gp3 with throughput too high for IOPS",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(400),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeGP3,
+				IOPS:          3500,
+				Throughput:    1000,
+			},
+			expErr: fmt.Errorf("Invalid volume parameters: throughput 1000 MiB/s is too high for 3500 IOPS, gp3 volumes support at most 875 MiB/s (0.25 MiB/s per IOPS, up to 1000 MiB/s)"),
+		},
+		{
+			name:       "fail: gp3 with throughput too high for baseline IOPS",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(400),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				Throughput:    800,
+			},
+			expErr: fmt.Errorf("Invalid volume parameters: throughput 800 MiB/s is too high for 3000 IOPS, gp3 volumes support at most 750 MiB/s (0.25 MiB/s per IOPS, up to 1000 MiB/s)"),
+		},
+		{
+			name:       "fail: gp3 with throughput too high for IOPS capped by capacity",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(7),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeGP3,
+				IOPS:          16000,
+				Throughput:    1000,
+			},
+			expErr: fmt.Errorf("Invalid volume parameters: throughput 1000 MiB/s is too high for 3500 IOPS, gp3 volumes support at most 875 MiB/s (0.25 MiB/s per IOPS, up to 1000 MiB/s)"),
+		},
+		{
+			name:       "success: gp3 with throughput too high for IOPS decreased",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes:           util.GiBToBytes(400),
+				Tags:                    map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:              VolumeTypeGP3,
+				IOPS:                    3500,
+				Throughput:              1000,
+				AllowThroughputDecrease: true,
+			},
+			expDisk: &Disk{
+				VolumeID:         "vol-test",
+				CapacityGiB:      400,
+				AvailabilityZone: defaultZone,
+			},
+			expCreateVolumeInput: &ec2.CreateVolumeInput{
+				Iops:       aws.Int64(3500),
+				Throughput: aws.Int64(875),
+			},
+			expErr: nil,
+		},
 		{
 			name:       "success: normal with provided zone",
 			volumeName: "vol-test-name",
@@ -573,7 +753,7 @@ func TestCreateDisk(t *testing.T) {
 				AvailabilityZone: defaultZone,
 			},
 			expCreateVolumeInput: nil,
-			expErr:               fmt.Errorf("invalid StorageClass parameters; specify either IOPS or IOPSPerGb, not both"),
+			expErr:               fmt.Errorf("Invalid volume parameters: specify either IOPS or IOPSPerGb, not both"),
 		},
 		{
 			name:       "fail: io1 with too low iopsPerGB",
@@ -590,7 +770,7 @@ func TestCreateDisk(t *testing.T) {
 				AvailabilityZone: defaultZone,
 			},
 			expCreateVolumeInput: nil,
-			expErr:               fmt.Errorf("invalid IOPS: 4 is too low, it must be at least 100"),
+			expErr:               fmt.Errorf("Invalid volume parameters: IOPS 4 is too low, io1 volumes must have at least 100 IOPS"),
 		},
 		{
 			name:       "success: small io1 with too high iopsPerGB",
@@ -665,7 +845,7 @@ func TestCreateDisk(t *testing.T) {
 				AvailabilityZone: defaultZone,
 			},
 			expCreateVolumeInput: nil,
-			expErr:               fmt.Errorf("invalid IOPS: 4 is too low, it must be at least 100"),
+			expErr:               fmt.Errorf("Invalid volume parameters: IOPS 4 is too low, io2 volumes must have at least 100 IOPS"),
 		},
 		{
 			name:       "success: small io2 with too high iopsPerGB",
@@ -725,6 +905,84 @@ func TestCreateDisk(t *testing.T) {
 			},
 			expErr: nil,
 		},
+		{
+			name:       "success: io2 Block Express with iopsPerGB over the io2 limit",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(100),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeIO2,
+				IOPSPerGB:     2000,
+				BlockExpress:  true,
+			},
+			expDisk: &Disk{
+				VolumeID:         "vol-test",
+				CapacityGiB:      100,
+				AvailabilityZone: defaultZone,
+			},
+			expCreateVolumeInput: &ec2.CreateVolumeInput{
+				Iops: aws.Int64(100000),
+			},
+			expErr: nil,
+		},
+		{
+			name:       "success: io2 Block Express larger than the io2 limit",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(65536),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeIO2,
+				BlockExpress:  true,
+			},
+			expDisk: &Disk{
+				VolumeID:         "vol-test",
+				CapacityGiB:      65536,
+				AvailabilityZone: defaultZone,
+			},
+			expCreateVolumeInput: &ec2.CreateVolumeInput{},
+			expErr:               nil,
+		},
+		{
+			name:       "fail: io2 larger than the io2 limit",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(65536),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeIO2,
+			},
+			expErr: fmt.Errorf("Volume size out of range: 65536 GiB, io2 volumes must be between 4 GiB and 16384 GiB"),
+		},
+		{
+			name:       "fail: st1 smaller than the st1 limit",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(10),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeST1,
+			},
+			expErr: fmt.Errorf("Volume size out of range: 10 GiB, st1 volumes must be between 125 GiB and 16384 GiB"),
+		},
+		{
+			name:       "fail: gp3 with throughput below the gp3 limit",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(100),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeGP3,
+				Throughput:    100,
+			},
+			expErr: fmt.Errorf("Invalid volume parameters: throughput 100 MiB/s is too low, gp3 volumes must have at least 125 MiB/s"),
+		},
+		{
+			name:       "fail: unknown volume type",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(100),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    "io3",
+			},
+			expErr: fmt.Errorf("Invalid volume parameters: invalid AWS VolumeType \"io3\""),
+		},
 		{
 			name:       "success: create volume when zone is snow and add tags",
 			volumeName: "vol-test-name",
@@ -798,6 +1056,27 @@ func TestCreateDisk(t *testing.T) {
 			},
 			expErr: nil,
 		},
+		{
+			name:       "success: multi-attach with IO1",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes:      util.GiBToBytes(4),
+				Tags:               map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:         VolumeTypeIO1,
+				MultiAttachEnabled: true,
+				IOPSPerGB:          50,
+			},
+			expDisk: &Disk{
+				VolumeID:           "vol-test",
+				CapacityGiB:        4,
+				AvailabilityZone:   defaultZone,
+				MultiAttachEnabled: true,
+			},
+			expCreateVolumeInput: &ec2.CreateVolumeInput{
+				Iops: aws.Int64(200),
+			},
+			expErr: nil,
+		},
 		{
 			name:       "failure: multi-attach with GP3",
 			volumeName: "vol-test-name",
@@ -813,7 +1092,19 @@ func TestCreateDisk(t *testing.T) {
 				CapacityGiB:      4,
 				AvailabilityZone: defaultZone,
 			},
-			expErr: fmt.Errorf("CreateDisk: multi-attach is only supported for io2 volumes"),
+			expErr: fmt.Errorf("Invalid volume parameters: multi-attach is only supported for io1 and io2 volumes"),
+		},
+		{
+			name:       "fail: gp3 volume on outpost",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(1),
+				Tags:             map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:       VolumeTypeGP3,
+				AvailabilityZone: expZone,
+				OutpostArn:       "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0",
+			},
+			expErr: fmt.Errorf("Invalid volume parameters: gp3 volumes are not supported on Outposts, only gp2 volumes are"),
 		},
 	}
 
@@ -896,6 +1187,150 @@ func TestCreateDisk(t *testing.T) {
 	}
 }
 
+// fakeSTS implements the subset of stsiface.STSAPI used to look up the account ID.
+type fakeSTS struct {
+	stsiface.STSAPI
+	account string
+	// err is returned by the first failures calls
+	err      error
+	failures int
+	calls    int
+}
+
+func (f *fakeSTS) GetCallerIdentityWithContext(_ aws.Context, _ *sts.GetCallerIdentityInput, _ ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &sts.GetCallerIdentityOutput{Account: aws.String(f.account)}, nil
+}
+
+func TestAWSAccount(t *testing.T) {
+	defaultBackoff := accountIDBackoff
+	accountIDBackoff.Duration = time.Millisecond
+	defer func() { accountIDBackoff = defaultBackoff }()
+
+	testCases := []struct {
+		name         string
+		sts          *fakeSTS
+		expAccountID string
+		expCalls     int
+	}{
+		{
+			name:         "success: looked up once",
+			sts:          &fakeSTS{account: "111111111111"},
+			expAccountID: "111111111111",
+			expCalls:     1,
+		},
+		{
+			name:         "success: lookup is retried",
+			sts:          &fakeSTS{account: "111111111111", failures: 2, err: errors.New("RequestError")},
+			expAccountID: "111111111111",
+			expCalls:     3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			account := &awsAccount{}
+			assert.Equal(t, unknownAccountID, account.ID())
+			account.resolve(context.Background(), tc.sts)
+			assert.Equal(t, tc.expAccountID, account.ID())
+			assert.Equal(t, tc.expCalls, tc.sts.calls)
+		})
+	}
+}
+
+func TestAWSAccountResolveCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	account := &awsAccount{}
+	account.resolve(ctx, &fakeSTS{err: errors.New("AccessDenied"), failures: 1 << 30})
+	assert.Equal(t, unknownAccountID, account.ID())
+
+	var nilAccount *awsAccount
+	assert.Equal(t, unknownAccountID, nilAccount.ID())
+}
+
+func TestOperationResultMetrics(t *testing.T) {
+	metricsAddress := reserveMetricsAddress(t)
+	metrics.InitializeRecorder().InitializeMetricsHandler(metricsAddress, "/metrics")
+
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := &cloud{region: "test-region", ec2: mockEC2}
+
+	gomock.InOrder(
+		mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DeleteVolumeOutput{}, nil),
+		mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DeleteVolumeOutput{}, nil),
+		mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)),
+		mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("InvalidParameterValue", "Invalid volume ID.", nil)),
+		mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection reset")),
+		mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("InvalidVolume.NotFound", "Volume not found.", nil)),
+	)
+	for i := 0; i < 6; i++ {
+		_, _ = c.DeleteDisk(context.Background(), "vol-test")
+	}
+
+	mockEC2.EXPECT().CreateSnapshotWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("SnapshotLimitExceeded", "Snapshot limit exceeded.", nil))
+	mockEC2.EXPECT().CreateSnapshotWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("ResourceLimitExceeded", "Snapshot limit exceeded.", nil))
+	for i := 0; i < 2; i++ {
+		_, _ = c.CreateSnapshot(context.Background(), "vol-test", &SnapshotOptions{})
+	}
+
+	expectMetric(t, metricsAddress, `cloudprovider_aws_operation_successes_total{operation="DeleteDisk"} 3`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_operation_failures_total{category="throttle",operation="DeleteDisk"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_operation_failures_total{category="validation",operation="DeleteDisk"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_operation_failures_total{category="other",operation="DeleteDisk"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_operation_failures_total{category="other",operation="CreateSnapshot"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_operation_failures_total{category="quota",operation="CreateSnapshot"} 1`)
+}
+
+func TestFailureCategory(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		expCategory string
+	}{
+		{
+			name:        "throttled",
+			err:         fmt.Errorf("could not create volume in EC2: %w", awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)),
+			expCategory: failureCategoryThrottle,
+		},
+		{
+			name:        "quota exceeded",
+			err:         awserr.New("VolumeLimitExceeded", "Volume limit exceeded.", nil),
+			expCategory: failureCategoryQuota,
+		},
+		{
+			name:        "invalid parameter",
+			err:         awserr.New("InvalidParameterCombination", "Invalid combination.", nil),
+			expCategory: failureCategoryValidation,
+		},
+		{
+			name:        "too many tags",
+			err:         fmt.Errorf("volume has 51 tags: %w", ErrTooManyTags),
+			expCategory: failureCategoryValidation,
+		},
+		{
+			name:        "insufficient capacity",
+			err:         awserr.New("InsufficientVolumeCapacity", "Insufficient capacity.", nil),
+			expCategory: failureCategoryOther,
+		},
+		{
+			name:        "not an AWS error",
+			err:         ErrNotFound,
+			expCategory: failureCategoryOther,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expCategory, failureCategory(tc.err))
+		})
+	}
+}
+
 func TestDeleteDisk(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -997,6 +1432,42 @@ func TestAttachDisk(t *testing.T) {
 					mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), volumeRequest).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, path, "attached"), nil))
 			},
 		},
+		{
+			name:     "success: AttachVolume device name already in use",
+			volumeID: defaultVolumeID,
+			nodeID:   defaultNodeID,
+			path:     "/dev/xvdab",
+			expErr:   nil,
+			mockFunc: func(mockEC2 *MockEC2API, ctx context.Context, volumeID, nodeID, nodeID2, path string, dm dm.DeviceManager) {
+				volumeRequest := createVolumeRequest(volumeID)
+				instanceRequest := createInstanceRequest(nodeID)
+				inUseErr := awserr.New("InvalidParameterValue", "Invalid value '/dev/xvdaa' for unixDevice. Attachment point /dev/xvdaa is already in use", nil)
+
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), instanceRequest).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), createAttachRequest(volumeID, nodeID, defaultPath)).Return(nil, inUseErr),
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), instanceRequest).Return(newDescribeInstancesOutput(nodeID, "vol-other"), nil),
+					mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), createAttachRequest(volumeID, nodeID, path)).Return(createAttachVolumeOutput(volumeID, nodeID, path), nil),
+					mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), volumeRequest).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, path, "attached"), nil),
+				)
+			},
+		},
+		{
+			name:     "fail: AttachVolume volume attached to another instance",
+			volumeID: defaultVolumeID,
+			nodeID:   defaultNodeID,
+			path:     defaultPath,
+			expErr:   fmt.Errorf("could not attach volume %q to node %q: %w: %w", defaultVolumeID, defaultNodeID, ErrVolumeInUse, awserr.New("VolumeInUse", "vol-test-1234 is already attached to an instance", nil)),
+			mockFunc: func(mockEC2 *MockEC2API, ctx context.Context, volumeID, nodeID, nodeID2, path string, dm dm.DeviceManager) {
+				instanceRequest := createInstanceRequest(nodeID)
+				attachRequest := createAttachRequest(volumeID, nodeID, path)
+
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), instanceRequest).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), attachRequest).Return(nil, awserr.New("VolumeInUse", "vol-test-1234 is already attached to an instance", nil)),
+				)
+			},
+		},
 		{
 			name:     "fail: AttachVolume returned generic error",
 			volumeID: defaultVolumeID,
@@ -1109,7 +1580,51 @@ func TestAttachDisk(t *testing.T) {
 	}
 }
 
-func TestDetachDisk(t *testing.T) {
+func TestAttachDiskKeepsDeviceNameOfUnknownOutcome(t *testing.T) {
+	testCases := []struct {
+		name        string
+		attachErr   error
+		expReserved bool
+	}{
+		{
+			name:        "request timed out",
+			attachErr:   awserr.New(request.ErrCodeResponseTimeout, "read timeout", nil),
+			expReserved: true,
+		},
+		{
+			name:        "internal error",
+			attachErr:   awserr.NewRequestFailure(awserr.New("InternalError", "An internal error has occurred", nil), 500, "request-id"),
+			expReserved: true,
+		},
+		{
+			name:      "request rejected",
+			attachErr: awserr.NewRequestFailure(awserr.New("IncorrectState", "vol-test-1234 is not 'available'", nil), 400, "request-id"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+			ctx := context.Background()
+
+			mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(newDescribeInstancesOutput(defaultNodeID), nil)
+			mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, tc.attachErr)
+
+			_, err := c.AttachDisk(ctx, defaultVolumeID, defaultNodeID)
+			assert.Error(t, err)
+
+			// The device name stays reserved for the volume if it may be attaching
+			device, err := c.(*cloud).dm.NewDevice(&ec2.Instance{InstanceId: aws.String(defaultNodeID)}, defaultVolumeID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expReserved, device.IsAlreadyAssigned)
+			assert.Equal(t, defaultPath, device.Path)
+		})
+	}
+}
+
+func TestDetachDisk(t *testing.T) {
 	testCases := []struct {
 		name     string
 		volumeID string
@@ -1189,6 +1704,208 @@ func TestDetachDisk(t *testing.T) {
 	}
 }
 
+func TestAttachDetachDiskDescribeCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+	c.describes = newDescribeCache(time.Minute)
+	ctx := context.Background()
+	nodeID := defaultNodeID
+	volumeIDs := []string{"vol-test-1", "vol-test-2"}
+
+	// The instance is described once for both attachments
+	devices := map[string]string{}
+	mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), createInstanceRequest(nodeID)).Return(newDescribeInstancesOutput(nodeID), nil).Times(1)
+	mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.AttachVolumeInput, _ ...request.Option) (*ec2.VolumeAttachment, error) {
+		devices[aws.StringValue(input.VolumeId)] = aws.StringValue(input.Device)
+		return createAttachVolumeOutput(aws.StringValue(input.VolumeId), nodeID, aws.StringValue(input.Device)), nil
+	}).Times(2)
+	mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.DescribeVolumesInput, _ ...request.Option) (*ec2.DescribeVolumesOutput, error) {
+		volumeID := aws.StringValue(input.VolumeIds[0])
+		return createDescribeVolumesOutput([]*string{&volumeID}, nodeID, devices[volumeID], "attached"), nil
+	}).Times(2)
+	for _, volumeID := range volumeIDs {
+		if _, err := c.AttachDisk(ctx, volumeID, nodeID); err != nil {
+			t.Fatalf("AttachDisk of %s failed: %v", volumeID, err)
+		}
+	}
+	if devices[volumeIDs[0]] == devices[volumeIDs[1]] {
+		t.Fatalf("expected the volumes to be attached at different devices, got %v", devices)
+	}
+
+	// The detachment is recorded in the cached instance
+	gomock.InOrder(
+		mockEC2.EXPECT().DetachVolumeWithContext(gomock.Any(), createDetachRequest(volumeIDs[0], nodeID)).Return(nil, nil),
+		mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), createVolumeRequest(volumeIDs[0])).Return(createDescribeVolumesOutput([]*string{&volumeIDs[0]}, nodeID, "", "detached"), nil),
+	)
+	if err := c.DetachDisk(ctx, volumeIDs[0], nodeID); err != nil {
+		t.Fatalf("DetachDisk failed: %v", err)
+	}
+	instance, ok := c.describes.instance(nodeID)
+	if !ok {
+		t.Fatal("expected the instance to stay cached after the detachment")
+	}
+	if len(instance.BlockDeviceMappings) != 1 || aws.StringValue(instance.BlockDeviceMappings[0].Ebs.VolumeId) != volumeIDs[1] {
+		t.Fatalf("expected only %s in the block device mappings of the cached instance, got %v", volumeIDs[1], instance.BlockDeviceMappings)
+	}
+
+	// A failed attachment drops the cached instance
+	mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("AttachVolume error"))
+	if _, err := c.AttachDisk(ctx, "vol-test-3", nodeID); err == nil {
+		t.Fatal("expected AttachDisk to fail")
+	}
+	if _, ok := c.describes.instance(nodeID); ok {
+		t.Fatal("expected the instance to be dropped from the cache after a failed attachment")
+	}
+}
+
+func TestGetDiskByIDDescribeCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+	c.describes = newDescribeCache(time.Minute)
+	ctx := context.Background()
+	volumeID := defaultVolumeID
+
+	mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), createVolumeRequest(volumeID)).Return(&ec2.DescribeVolumesOutput{
+		Volumes: []*ec2.Volume{{VolumeId: aws.String(volumeID), Size: aws.Int64(1)}},
+	}, nil).Times(1)
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetDiskByID(ctx, volumeID); err != nil {
+			t.Fatalf("GetDiskByID failed: %v", err)
+		}
+	}
+
+	// The deletion of the volume drops it from the cache
+	mockEC2.EXPECT().DeleteVolumeWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DeleteVolumeOutput{}, nil)
+	if _, err := c.DeleteDisk(ctx, volumeID); err != nil {
+		t.Fatalf("DeleteDisk failed: %v", err)
+	}
+	if _, ok := c.describes.volume(volumeID); ok {
+		t.Fatal("expected the volume to be dropped from the cache after its deletion")
+	}
+}
+
+func TestDetachDiskForce(t *testing.T) {
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	forceDetachRequest := createDetachRequest(volumeID, nodeID)
+	forceDetachRequest.Force = aws.Bool(true)
+
+	testCases := []struct {
+		name               string
+		forceDetachTimeout time.Duration
+		startedAgo         time.Duration
+		expErr             error
+		expTracked         bool
+		expForced          []string
+		mockFunc           func(*MockEC2API)
+	}{
+		{
+			name:               "success: detachment within the timeout is not forced",
+			forceDetachTimeout: time.Hour,
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().DetachVolumeWithContext(gomock.Any(), createDetachRequest(volumeID, nodeID)).Return(nil, nil),
+					mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, "", "detached"), nil),
+				)
+			},
+		},
+		{
+			name:               "success: detachment requested before the timeout is forced",
+			forceDetachTimeout: time.Minute,
+			startedAgo:         2 * time.Minute,
+			expForced:          []string{volumeID + "/" + nodeID},
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().DetachVolumeWithContext(gomock.Any(), forceDetachRequest).Return(nil, nil),
+					mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, "", "detached"), nil),
+				)
+			},
+		},
+		{
+			name:               "success: detachment stuck detaching until the timeout is forced",
+			forceDetachTimeout: 100 * time.Millisecond,
+			expForced:          []string{volumeID + "/" + nodeID},
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().DetachVolumeWithContext(gomock.Any(), createDetachRequest(volumeID, nodeID)).Return(nil, nil),
+					mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, "", "detaching"), nil),
+					mockEC2.EXPECT().DetachVolumeWithContext(gomock.Any(), forceDetachRequest).Return(nil, nil),
+					mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, "", "detached"), nil),
+				)
+			},
+		},
+		{
+			name:               "fail: forced detachment returned error",
+			forceDetachTimeout: time.Minute,
+			startedAgo:         2 * time.Minute,
+			expErr:             fmt.Errorf("could not detach volume %q from node %q: %w", volumeID, nodeID, errors.New("DetachVolume error")),
+			expTracked:         true,
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().DetachVolumeWithContext(gomock.Any(), forceDetachRequest).Return(nil, errors.New("DetachVolume error")),
+				)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			tc.mockFunc(mockEC2)
+
+			c := newCloud(mockEC2).(*cloud)
+			var forced []string
+			c.detaches = newDetachTracker(tc.forceDetachTimeout, func(volumeID, nodeID string) {
+				forced = append(forced, volumeID+"/"+nodeID)
+			})
+			key := detachment{volumeID: volumeID, nodeID: nodeID}
+			if tc.startedAgo > 0 {
+				c.detaches.started[key] = time.Now().Add(-tc.startedAgo)
+			}
+
+			err := c.DetachDisk(context.Background(), volumeID, nodeID)
+			assert.Equal(t, tc.expErr, err)
+			_, tracked := c.detaches.started[key]
+			assert.Equal(t, tc.expTracked, tracked)
+			assert.Equal(t, tc.expForced, forced)
+		})
+	}
+}
+
+func TestAttachDiskEndsDetachment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockEC2 := NewMockEC2API(mockCtrl)
+
+	volumeID := defaultVolumeID
+	nodeID := defaultNodeID
+	gomock.InOrder(
+		mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), createInstanceRequest(nodeID)).Return(newDescribeInstancesOutput(nodeID), nil),
+		mockEC2.EXPECT().AttachVolumeWithContext(gomock.Any(), createAttachRequest(volumeID, nodeID, defaultPath)).Return(createAttachVolumeOutput(volumeID, nodeID, defaultPath), nil),
+		mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), createVolumeRequest(volumeID)).Return(createDescribeVolumesOutput([]*string{&volumeID}, nodeID, defaultPath, "attached"), nil),
+	)
+
+	c := newCloud(mockEC2).(*cloud)
+	c.detaches = newDetachTracker(time.Minute, nil)
+	key := detachment{volumeID: volumeID, nodeID: nodeID}
+	c.detaches.started[key] = time.Now().Add(-2 * time.Minute)
+
+	if _, err := c.AttachDisk(context.Background(), volumeID, nodeID); err != nil {
+		t.Fatalf("AttachDisk failed: %v", err)
+	}
+	if _, tracked := c.detaches.started[key]; tracked {
+		t.Fatal("expected the detachment to be forgotten once the volume is attached again")
+	}
+}
+
 func TestGetDiskByName(t *testing.T) {
 	testCases := []struct {
 		name             string
@@ -1268,6 +1985,294 @@ func TestGetDiskByName(t *testing.T) {
 	}
 }
 
+func TestCreateDiskVolumeLimitExceeded(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+
+	limitErr := awserr.New("VolumeLimitExceeded", "You have exceeded your maximum gp3 storage limit.", nil)
+	mockEC2.EXPECT().CreateVolumeWithContext(gomock.Any(), gomock.Any()).Return(nil, limitErr)
+
+	_, err := c.CreateDisk(context.Background(), "vol-test-name", &DiskOptions{
+		CapacityBytes:    util.GiBToBytes(1),
+		AvailabilityZone: defaultZone,
+		Tags:             map[string]string{VolumeNameTagKey: "vol-test"},
+	})
+	if !errors.Is(err, ErrVolumeLimitExceeded) {
+		t.Fatalf("CreateDisk() failed: expected error %v, got: %v", ErrVolumeLimitExceeded, err)
+	}
+	if reason, awsCode := ErrorReason(err); reason != ErrorReasonQuota || awsCode != "VolumeLimitExceeded" {
+		t.Fatalf("expected error reason %s with code VolumeLimitExceeded, got %s with code %s", ErrorReasonQuota, reason, awsCode)
+	}
+}
+
+func TestGetAvailableDisksByTags(t *testing.T) {
+	createTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		volumes  []*ec2.Volume
+		expDisks []*Disk
+		expErr   error
+	}{
+		{
+			name: "success: available volumes",
+			volumes: []*ec2.Volume{
+				{
+					VolumeId:         aws.String("vol-test-1"),
+					Size:             aws.Int64(10),
+					AvailabilityZone: aws.String(defaultZone),
+					CreateTime:       aws.Time(createTime),
+					Tags:             []*ec2.Tag{{Key: aws.String(AwsEbsDriverTagKey), Value: aws.String("true")}},
+				},
+				{
+					VolumeId:         aws.String("vol-test-2"),
+					Size:             aws.Int64(20),
+					AvailabilityZone: aws.String(defaultZone),
+					CreateTime:       aws.Time(createTime),
+				},
+			},
+			expDisks: []*Disk{
+				{VolumeID: "vol-test-1", CapacityGiB: 10, AvailabilityZone: defaultZone, CreateTime: createTime, Tags: map[string]string{AwsEbsDriverTagKey: "true"}},
+				{VolumeID: "vol-test-2", CapacityGiB: 20, AvailabilityZone: defaultZone, CreateTime: createTime},
+			},
+		},
+		{
+			name:     "success: no volumes",
+			expDisks: []*Disk{},
+		},
+		{
+			name:   "fail: DescribeVolumes returned generic error",
+			expErr: fmt.Errorf("DescribeVolumes generic error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.DescribeVolumesInput, _ ...request.Option) (*ec2.DescribeVolumesOutput, error) {
+				filters := map[string]string{}
+				for _, filter := range input.Filters {
+					filters[aws.StringValue(filter.Name)] = aws.StringValue(filter.Values[0])
+				}
+				assert.Equal(t, map[string]string{"status": "available", "tag:" + AwsEbsDriverTagKey: "true"}, filters)
+				if tc.expErr != nil {
+					return nil, tc.expErr
+				}
+				return &ec2.DescribeVolumesOutput{Volumes: tc.volumes}, nil
+			})
+
+			disks, err := c.GetAvailableDisksByTags(context.Background(), map[string]string{AwsEbsDriverTagKey: "true"})
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expDisks, disks)
+		})
+	}
+}
+
+func TestListDisks(t *testing.T) {
+	testCases := []struct {
+		name        string
+		maxResults  int64
+		nextToken   string
+		pages       []*ec2.DescribeVolumesOutput
+		describeErr error
+		expResponse *ListDisksResponse
+		expErr      error
+	}{
+		{
+			name: "success: all pages",
+			pages: []*ec2.DescribeVolumesOutput{
+				{
+					Volumes: []*ec2.Volume{
+						{VolumeId: aws.String("vol-test-1"), Size: aws.Int64(10), AvailabilityZone: aws.String(defaultZone), State: aws.String("available")},
+					},
+					NextToken: aws.String("page-2"),
+				},
+				{
+					Volumes: []*ec2.Volume{
+						{
+							VolumeId:         aws.String("vol-test-2"),
+							Size:             aws.Int64(20),
+							AvailabilityZone: aws.String(defaultZone),
+							State:            aws.String("in-use"),
+							Attachments:      []*ec2.VolumeAttachment{{InstanceId: aws.String("i-test"), State: aws.String("attached")}},
+						},
+					},
+				},
+			},
+			expResponse: &ListDisksResponse{
+				Disks: []*Disk{
+					{VolumeID: "vol-test-1", CapacityGiB: 10, AvailabilityZone: defaultZone, State: "available"},
+					{VolumeID: "vol-test-2", CapacityGiB: 20, AvailabilityZone: defaultZone, State: "in-use", Attachments: []string{"i-test"}},
+				},
+			},
+		},
+		{
+			name:       "success: one page",
+			maxResults: 5,
+			nextToken:  "page-1",
+			pages: []*ec2.DescribeVolumesOutput{
+				{
+					Volumes: []*ec2.Volume{
+						{VolumeId: aws.String("vol-test-1"), Size: aws.Int64(10), AvailabilityZone: aws.String(defaultZone), State: aws.String("available")},
+					},
+					NextToken: aws.String("page-2"),
+				},
+			},
+			expResponse: &ListDisksResponse{
+				Disks: []*Disk{
+					{VolumeID: "vol-test-1", CapacityGiB: 10, AvailabilityZone: defaultZone, State: "available"},
+				},
+				NextToken: "page-2",
+			},
+		},
+		{
+			name:       "fail: invalid max results",
+			maxResults: 4,
+			expErr:     ErrInvalidMaxResults,
+		},
+		{
+			name:        "fail: invalid next token",
+			maxResults:  5,
+			nextToken:   "invalid",
+			describeErr: awserr.New("InvalidPaginationToken", "", nil),
+			expErr:      ErrInvalidNextToken,
+		},
+		{
+			name:        "fail: DescribeVolumes returned generic error",
+			describeErr: fmt.Errorf("DescribeVolumes generic error"),
+			expErr:      fmt.Errorf("could not list volumes: DescribeVolumes generic error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			if tc.describeErr != nil {
+				mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).Return(nil, tc.describeErr)
+			}
+			nextToken := tc.nextToken
+			for _, page := range tc.pages {
+				page, expToken := page, nextToken
+				mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.DescribeVolumesInput, _ ...request.Option) (*ec2.DescribeVolumesOutput, error) {
+					assert.Equal(t, "tag:"+AwsEbsDriverTagKey, aws.StringValue(input.Filters[0].Name))
+					assert.Equal(t, expToken, aws.StringValue(input.NextToken))
+					if tc.maxResults > 0 {
+						assert.Equal(t, tc.maxResults, aws.Int64Value(input.MaxResults))
+					}
+					return page, nil
+				})
+				nextToken = aws.StringValue(page.NextToken)
+			}
+
+			response, err := c.ListDisks(context.Background(), tc.maxResults, tc.nextToken)
+			if tc.expErr != nil {
+				if errors.Is(tc.expErr, ErrInvalidMaxResults) || errors.Is(tc.expErr, ErrInvalidNextToken) {
+					assert.ErrorIs(t, err, tc.expErr)
+				} else {
+					assert.EqualError(t, err, tc.expErr.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expResponse, response)
+		})
+	}
+}
+
+func TestGetVolumeStatuses(t *testing.T) {
+	testCases := []struct {
+		name        string
+		volumeIDs   []string
+		statuses    []*ec2.VolumeStatusItem
+		describeErr error
+		expStatuses map[string]*VolumeStatus
+		expErr      error
+	}{
+		{
+			name:      "success: ok and impaired volumes",
+			volumeIDs: []string{"vol-test-1", "vol-test-2"},
+			statuses: []*ec2.VolumeStatusItem{
+				{
+					VolumeId: aws.String("vol-test-1"),
+					VolumeStatus: &ec2.VolumeStatusInfo{
+						Status:  aws.String(ec2.VolumeStatusInfoStatusOk),
+						Details: []*ec2.VolumeStatusDetails{{Name: aws.String("io-enabled"), Status: aws.String("passed")}},
+					},
+				},
+				{
+					VolumeId: aws.String("vol-test-2"),
+					VolumeStatus: &ec2.VolumeStatusInfo{
+						Status: aws.String(ec2.VolumeStatusInfoStatusImpaired),
+						Details: []*ec2.VolumeStatusDetails{
+							{Name: aws.String("io-enabled"), Status: aws.String("failed")},
+							{Name: aws.String("io-performance"), Status: aws.String("normal")},
+						},
+					},
+					AttachmentStatuses: []*ec2.VolumeStatusAttachmentStatus{{InstanceId: aws.String("i-test"), IoPerformance: aws.String("degraded")}},
+					Events:             []*ec2.VolumeStatusEvent{{EventType: aws.String("potential-data-inconsistency"), Description: aws.String("THIS IS AN AUTOMATED EVENT")}},
+				},
+			},
+			expStatuses: map[string]*VolumeStatus{
+				"vol-test-1": {Status: "ok"},
+				"vol-test-2": {
+					Status: "impaired",
+					Problems: []string{
+						"io-enabled: failed",
+						"io-performance on i-test: degraded",
+						"potential-data-inconsistency: THIS IS AN AUTOMATED EVENT",
+					},
+				},
+			},
+		},
+		{
+			name:        "success: no volumes",
+			expStatuses: map[string]*VolumeStatus{},
+		},
+		{
+			name:        "fail: volume not found",
+			volumeIDs:   []string{"vol-test-1"},
+			describeErr: awserr.New("InvalidVolume.NotFound", "", nil),
+			expErr:      ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			if len(tc.volumeIDs) > 0 {
+				mockEC2.EXPECT().DescribeVolumeStatusWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.DescribeVolumeStatusInput, _ ...request.Option) (*ec2.DescribeVolumeStatusOutput, error) {
+					assert.Equal(t, tc.volumeIDs, aws.StringValueSlice(input.VolumeIds))
+					if tc.describeErr != nil {
+						return nil, tc.describeErr
+					}
+					return &ec2.DescribeVolumeStatusOutput{VolumeStatuses: tc.statuses}, nil
+				})
+			}
+
+			statuses, err := c.GetVolumeStatuses(context.Background(), tc.volumeIDs)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expStatuses, statuses)
+		})
+	}
+}
+
 func TestGetDiskByID(t *testing.T) {
 	testCases := []struct {
 		name             string
@@ -1275,6 +2280,8 @@ func TestGetDiskByID(t *testing.T) {
 		availabilityZone string
 		outpostArn       string
 		attachments      *ec2.VolumeAttachment
+		tags             []*ec2.Tag
+		expTags          map[string]string
 		expErr           error
 	}{
 		{
@@ -1302,6 +2309,17 @@ func TestGetDiskByID(t *testing.T) {
 				State:      aws.String("attached")},
 			expErr: nil,
 		},
+		{
+			name:             "success: volume tags",
+			volumeID:         "vol-test-1234",
+			availabilityZone: expZone,
+			attachments:      &ec2.VolumeAttachment{},
+			tags: []*ec2.Tag{
+				{Key: aws.String("kubernetes.io/created-for/pvc/name"), Value: aws.String("data")},
+			},
+			expTags: map[string]string{"kubernetes.io/created-for/pvc/name": "data"},
+			expErr:  nil,
+		},
 		{
 			name:     "fail: DescribeVolumes returned generic error",
 			volumeID: "vol-test-1234",
@@ -1324,6 +2342,7 @@ func TestGetDiskByID(t *testing.T) {
 							AvailabilityZone: aws.String(tc.availabilityZone),
 							OutpostArn:       aws.String(tc.outpostArn),
 							Attachments:      []*ec2.VolumeAttachment{tc.attachments},
+							Tags:             tc.tags,
 						},
 					},
 				},
@@ -1351,6 +2370,203 @@ func TestGetDiskByID(t *testing.T) {
 				if len(disk.Attachments) > 0 && disk.Attachments[0] != aws.StringValue(tc.attachments.InstanceId) {
 					t.Fatalf("GetDisk() failed: expected attachment instance %q, got %q", aws.StringValue(tc.attachments.InstanceId), disk.Attachments[0])
 				}
+				if !reflect.DeepEqual(disk.Tags, tc.expTags) {
+					t.Fatalf("GetDisk() failed: expected tags %v, got %v", tc.expTags, disk.Tags)
+				}
+			}
+
+			mockCtrl.Finish()
+		})
+	}
+}
+
+// fakeIAM implements the subset of iamiface.IAMAPI used by CheckKMSKeyAccess.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	roleArns      []string
+	getProfileErr error
+	// denied are the denied actions by role ARN
+	denied map[string]map[string]bool
+	// simulations are the resource policies of the simulations by role ARN
+	simulations map[string][]string
+}
+
+func (f *fakeIAM) GetInstanceProfileWithContext(_ aws.Context, input *iam.GetInstanceProfileInput, _ ...request.Option) (*iam.GetInstanceProfileOutput, error) {
+	if f.getProfileErr != nil {
+		return nil, f.getProfileErr
+	}
+	profile := &iam.InstanceProfile{InstanceProfileName: input.InstanceProfileName}
+	for _, roleArn := range f.roleArns {
+		profile.Roles = append(profile.Roles, &iam.Role{Arn: aws.String(roleArn)})
+	}
+	return &iam.GetInstanceProfileOutput{InstanceProfile: profile}, nil
+}
+
+func (f *fakeIAM) SimulatePrincipalPolicyPagesWithContext(_ aws.Context, input *iam.SimulatePrincipalPolicyInput, fn func(*iam.SimulatePolicyResponse, bool) bool, _ ...request.Option) error {
+	roleArn := aws.StringValue(input.PolicySourceArn)
+	if f.simulations == nil {
+		f.simulations = map[string][]string{}
+	}
+	f.simulations[roleArn] = append(f.simulations[roleArn], aws.StringValue(input.ResourcePolicy))
+	page := &iam.SimulatePolicyResponse{}
+	for _, action := range input.ActionNames {
+		decision := iam.PolicyEvaluationDecisionTypeAllowed
+		if f.denied[roleArn][aws.StringValue(action)] {
+			decision = iam.PolicyEvaluationDecisionTypeImplicitDeny
+		}
+		page.EvaluationResults = append(page.EvaluationResults, &iam.EvaluationResult{
+			EvalActionName: action,
+			EvalDecision:   aws.String(decision),
+		})
+	}
+	fn(page, true)
+	return nil
+}
+
+func TestCheckKMSKeyAccess(t *testing.T) {
+	const (
+		keyArn     = "arn:aws:kms:us-west-2:111111111111:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+		keyPolicy  = `{"Version":"2012-10-17","Statement":[]}`
+		profileArn = "arn:aws:iam::111111111111:instance-profile/path/node-profile"
+		roleArn    = "arn:aws:iam::111111111111:role/node-role"
+		otherRole  = "arn:aws:iam::111111111111:role/other-role"
+	)
+	testCases := []struct {
+		name       string
+		encrypted  bool
+		profileArn string
+		iam        *fakeIAM
+		kms        *fakeKMS
+		attempts   int
+		expErr     error
+		// expSimulations is the number of simulations by role ARN
+		expSimulations map[string]int
+	}{
+		{
+			name:      "success: volume is not encrypted",
+			encrypted: false,
+		},
+		{
+			name:           "success: role is allowed to use the key",
+			encrypted:      true,
+			profileArn:     profileArn,
+			iam:            &fakeIAM{roleArns: []string{roleArn}},
+			expSimulations: map[string]int{roleArn: 1},
+		},
+		{
+			name:           "success: allowed role is cached",
+			encrypted:      true,
+			profileArn:     profileArn,
+			iam:            &fakeIAM{roleArns: []string{roleArn}},
+			attempts:       3,
+			expSimulations: map[string]int{roleArn: 1},
+		},
+		{
+			name:       "success: second role is allowed to use the key",
+			encrypted:  true,
+			profileArn: profileArn,
+			iam: &fakeIAM{
+				roleArns: []string{otherRole, roleArn},
+				denied:   map[string]map[string]bool{otherRole: {"kms:Decrypt": true}},
+			},
+			expSimulations: map[string]int{otherRole: 1, roleArn: 1},
+		},
+		{
+			name:      "fail: instance has no instance profile",
+			encrypted: true,
+			expErr:    ErrKMSKeyAccessDenied,
+		},
+		{
+			name:       "fail: instance profile has no role",
+			encrypted:  true,
+			profileArn: profileArn,
+			iam:        &fakeIAM{},
+			expErr:     ErrKMSKeyAccessDenied,
+		},
+		{
+			name:       "fail: no role is allowed to decrypt with the key",
+			encrypted:  true,
+			profileArn: profileArn,
+			iam: &fakeIAM{
+				roleArns: []string{otherRole, roleArn},
+				denied:   map[string]map[string]bool{otherRole: {"kms:Decrypt": true}, roleArn: {"kms:Decrypt": true}},
+			},
+			attempts:       2,
+			expErr:         ErrKMSKeyAccessDenied,
+			expSimulations: map[string]int{otherRole: 2, roleArn: 2},
+		},
+		{
+			name:       "fail: GetInstanceProfile returned generic error",
+			encrypted:  true,
+			profileArn: profileArn,
+			iam:        &fakeIAM{getProfileErr: errors.New("GetInstanceProfile generic error")},
+			expErr:     errors.New("GetInstanceProfile generic error"),
+		},
+		{
+			name:       "fail: GetKeyPolicy returned generic error",
+			encrypted:  true,
+			profileArn: profileArn,
+			iam:        &fakeIAM{roleArns: []string{roleArn}},
+			kms:        &fakeKMS{getKeyPolicyErr: errors.New("GetKeyPolicy generic error")},
+			expErr:     errors.New("GetKeyPolicy generic error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2).(*cloud)
+			c.kmsAccess = newKMSAccessCache()
+			if tc.iam != nil {
+				c.iam = tc.iam
+			}
+			c.kms = &fakeKMS{keyPolicy: keyPolicy}
+			if tc.kms != nil {
+				c.kms = tc.kms
+			}
+
+			attempts := tc.attempts
+			if attempts == 0 {
+				attempts = 1
+			}
+			ctx := context.Background()
+			mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Any()).Return(
+				&ec2.DescribeVolumesOutput{
+					Volumes: []*ec2.Volume{
+						{
+							VolumeId:  aws.String(defaultVolumeID),
+							Encrypted: aws.Bool(tc.encrypted),
+							KmsKeyId:  aws.String(keyArn),
+						},
+					},
+				}, nil).Times(attempts)
+			if tc.encrypted {
+				instance := newDescribeInstancesOutput(defaultNodeID)
+				if tc.profileArn != "" {
+					instance.Reservations[0].Instances[0].IamInstanceProfile = &ec2.IamInstanceProfile{Arn: aws.String(tc.profileArn)}
+				}
+				mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(instance, nil).Times(attempts)
+			}
+
+			for i := 0; i < attempts; i++ {
+				err := c.CheckKMSKeyAccess(ctx, defaultVolumeID, defaultNodeID)
+				if tc.expErr == nil {
+					assert.NoError(t, err)
+				} else if errors.Is(tc.expErr, ErrKMSKeyAccessDenied) {
+					assert.ErrorIs(t, err, ErrKMSKeyAccessDenied)
+				} else {
+					assert.ErrorContains(t, err, tc.expErr.Error())
+				}
+			}
+
+			if tc.iam != nil {
+				for role, expected := range tc.expSimulations {
+					assert.Len(t, tc.iam.simulations[role], expected, "simulations of role %s", role)
+					for _, resourcePolicy := range tc.iam.simulations[role] {
+						assert.Equal(t, keyPolicy, resourcePolicy)
+					}
+				}
 			}
 
 			mockCtrl.Finish()
@@ -1358,6 +2574,397 @@ func TestGetDiskByID(t *testing.T) {
 	}
 }
 
+func TestKMSAccessCache(t *testing.T) {
+	now := time.Now()
+	cache := newKMSAccessCache()
+	cache.now = func() time.Time { return now }
+	access := kmsAccess{roleArn: "arn:aws:iam::111111111111:role/node-role", keyID: "key"}
+
+	assert.False(t, cache.isAllowed(access))
+	cache.allow(access)
+	assert.True(t, cache.isAllowed(access))
+	assert.False(t, cache.isAllowed(kmsAccess{roleArn: access.roleArn, keyID: "other-key"}))
+
+	now = now.Add(kmsAccessCacheTTL + time.Second)
+	assert.False(t, cache.isAllowed(access))
+
+	var nilCache *kmsAccessCache
+	nilCache.allow(access)
+	assert.False(t, nilCache.isAllowed(access))
+}
+
+// fakeKMS implements the subset of kmsiface.KMSAPI used by CreateDisk dry runs
+// and CheckKMSKeyAccess.
+type fakeKMS struct {
+	kmsiface.KMSAPI
+	keyState        string
+	describeKeyErr  error
+	keyPolicy       string
+	getKeyPolicyErr error
+}
+
+func (f *fakeKMS) GetKeyPolicyWithContext(_ aws.Context, _ *kms.GetKeyPolicyInput, _ ...request.Option) (*kms.GetKeyPolicyOutput, error) {
+	if f.getKeyPolicyErr != nil {
+		return nil, f.getKeyPolicyErr
+	}
+	return &kms.GetKeyPolicyOutput{Policy: aws.String(f.keyPolicy)}, nil
+}
+
+func (f *fakeKMS) DescribeKeyWithContext(_ aws.Context, input *kms.DescribeKeyInput, _ ...request.Option) (*kms.DescribeKeyOutput, error) {
+	if f.describeKeyErr != nil {
+		return nil, f.describeKeyErr
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: &kms.KeyMetadata{KeyId: input.KeyId, KeyState: aws.String(f.keyState)}}, nil
+}
+
+func TestCreateDiskDryRun(t *testing.T) {
+	const keyArn = "arn:aws:kms:us-west-2:111111111111:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	testCases := []struct {
+		name        string
+		diskOptions *DiskOptions
+		kms         *fakeKMS
+		expDryRun   bool
+		dryRunErr   error
+		expErr      string
+		expDiskZone string
+		expDiskSize int64
+	}{
+		{
+			name: "success: valid options",
+			diskOptions: &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				VolumeType:       VolumeTypeGP3,
+				IOPS:             4000,
+				AvailabilityZone: defaultZone,
+				DryRun:           true,
+			},
+			expDryRun:   true,
+			dryRunErr:   awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil),
+			expDiskZone: defaultZone,
+			expDiskSize: 10,
+		},
+		{
+			name: "success: enabled KMS key",
+			diskOptions: &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				AvailabilityZone: defaultZone,
+				Encrypted:        true,
+				KmsKeyID:         keyArn,
+				DryRun:           true,
+			},
+			kms:         &fakeKMS{keyState: kms.KeyStateEnabled},
+			expDryRun:   true,
+			dryRunErr:   awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil),
+			expDiskZone: defaultZone,
+			expDiskSize: 10,
+		},
+		{
+			name: "fail: options rejected by EC2",
+			diskOptions: &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(125),
+				VolumeType:       "sc1",
+				AvailabilityZone: defaultZone,
+				DryRun:           true,
+			},
+			expDryRun: true,
+			dryRunErr: awserr.New("InvalidParameterValue", "The volume type sc1 is not supported in this Availability Zone.", nil),
+			expErr:    "InvalidParameterValue",
+		},
+		{
+			name: "fail: KMS key not found",
+			diskOptions: &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				AvailabilityZone: defaultZone,
+				KmsKeyID:         keyArn,
+				DryRun:           true,
+			},
+			kms:    &fakeKMS{describeKeyErr: awserr.New(kms.ErrCodeNotFoundException, "Key does not exist", nil)},
+			expErr: kms.ErrCodeNotFoundException,
+		},
+		{
+			name: "fail: KMS key disabled",
+			diskOptions: &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				AvailabilityZone: defaultZone,
+				KmsKeyID:         keyArn,
+				DryRun:           true,
+			},
+			kms:    &fakeKMS{keyState: kms.KeyStateDisabled},
+			expErr: "is in state Disabled",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2).(*cloud)
+			if tc.kms != nil {
+				c.kms = tc.kms
+			}
+
+			if tc.expDryRun {
+				mockEC2.EXPECT().CreateVolumeWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ aws.Context, input *ec2.CreateVolumeInput, _ ...request.Option) (*ec2.Volume, error) {
+						if !aws.BoolValue(input.DryRun) {
+							t.Errorf("expected CreateVolume dry run")
+						}
+						return nil, tc.dryRunErr
+					})
+			}
+
+			disk, err := c.CreateDisk(context.Background(), "vol-test-name", tc.diskOptions)
+			if tc.expErr != "" {
+				assert.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, disk.VolumeID)
+			assert.Equal(t, tc.expDiskZone, disk.AvailabilityZone)
+			assert.Equal(t, tc.expDiskSize, disk.CapacityGiB)
+		})
+	}
+}
+
+func TestCreateDiskClientToken(t *testing.T) {
+	testCases := []struct {
+		name         string
+		retryAttempt int
+		expSeed      string
+	}{
+		{
+			name:    "success: client token derived from the volume name",
+			expSeed: "vol-test-name",
+		},
+		{
+			name:         "success: client token derived from the volume name and the retry attempt",
+			retryAttempt: 2,
+			expSeed:      "vol-test-name-2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			expToken := sha256.Sum256([]byte(tc.expSeed))
+			mockEC2.EXPECT().CreateVolumeWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ aws.Context, input *ec2.CreateVolumeInput, _ ...request.Option) (*ec2.Volume, error) {
+					assert.Equal(t, hex.EncodeToString(expToken[:]), aws.StringValue(input.ClientToken))
+					return nil, awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+				})
+
+			_, err := c.CreateDisk(context.Background(), "vol-test-name", &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				AvailabilityZone: defaultZone,
+				RetryAttempt:     tc.retryAttempt,
+				DryRun:           true,
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCreateDiskOutpostVolumeType(t *testing.T) {
+	const outpostArn = "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
+	testCases := []struct {
+		name          string
+		volumeType    string
+		outpostArn    string
+		expVolumeType string
+	}{
+		{
+			name:          "default in region",
+			expVolumeType: VolumeTypeGP3,
+		},
+		{
+			name:          "default on outpost",
+			outpostArn:    outpostArn,
+			expVolumeType: VolumeTypeGP2,
+		},
+		{
+			name:          "gp2 on outpost",
+			volumeType:    VolumeTypeGP2,
+			outpostArn:    outpostArn,
+			expVolumeType: VolumeTypeGP2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			mockEC2.EXPECT().CreateVolumeWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ aws.Context, input *ec2.CreateVolumeInput, _ ...request.Option) (*ec2.Volume, error) {
+					assert.Equal(t, tc.expVolumeType, aws.StringValue(input.VolumeType))
+					return nil, awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+				})
+
+			_, err := c.CreateDisk(context.Background(), "vol-test-name", &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				VolumeType:       tc.volumeType,
+				AvailabilityZone: defaultZone,
+				OutpostArn:       tc.outpostArn,
+				DryRun:           true,
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCreateDiskFromSnapshotEncryption(t *testing.T) {
+	const kmsKeyID = "arn:aws:kms:us-west-2:111111111111:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	testCases := []struct {
+		name         string
+		encrypted    bool
+		kmsKeyID     string
+		expEncrypted bool
+		expKmsKeyID  *string
+	}{
+		{
+			name: "encryption of snapshot",
+		},
+		{
+			name:         "encrypted with default key",
+			encrypted:    true,
+			expEncrypted: true,
+		},
+		{
+			name:         "encrypted with KMS key",
+			encrypted:    true,
+			kmsKeyID:     kmsKeyID,
+			expEncrypted: true,
+			expKmsKeyID:  aws.String(kmsKeyID),
+		},
+		{
+			name:         "KMS key implies encryption",
+			kmsKeyID:     kmsKeyID,
+			expEncrypted: true,
+			expKmsKeyID:  aws.String(kmsKeyID),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			createErr := errors.New("CreateVolume generic error")
+			mockEC2.EXPECT().CreateVolumeWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ aws.Context, input *ec2.CreateVolumeInput, _ ...request.Option) (*ec2.Volume, error) {
+					assert.Equal(t, "snap-test", aws.StringValue(input.SnapshotId))
+					assert.Equal(t, tc.expEncrypted, aws.BoolValue(input.Encrypted))
+					assert.Equal(t, tc.expKmsKeyID, input.KmsKeyId)
+					return nil, createErr
+				})
+
+			_, err := c.CreateDisk(context.Background(), "vol-test-name", &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				AvailabilityZone: defaultZone,
+				SnapshotID:       "snap-test",
+				Encrypted:        tc.encrypted,
+				KmsKeyID:         tc.kmsKeyID,
+			})
+			assert.ErrorIs(t, err, createErr)
+		})
+	}
+}
+
+func TestCreateDiskTagLimit(t *testing.T) {
+	userTags := func(n int) map[string]string {
+		tags := map[string]string{}
+		for i := 0; i < n; i++ {
+			tags[fmt.Sprintf("tag-%02d", i)] = "value"
+		}
+		return tags
+	}
+	driverTags := map[string]string{
+		VolumeNameTagKey:                   "vol-test-name",
+		AwsEbsDriverTagKey:                 "true",
+		"kubernetes.io/cluster/my-cluster": "owned",
+	}
+	overLimitTags := userTags(MaxNumTagsPerResource)
+	for key, value := range driverTags {
+		overLimitTags[key] = value
+	}
+
+	testCases := []struct {
+		name           string
+		tags           map[string]string
+		dropExcessTags bool
+		expErr         error
+		expTags        []string
+		expDroppedTags []string
+	}{
+		{
+			name:    "success: tags at limit",
+			tags:    userTags(MaxNumTagsPerResource),
+			expTags: []string{"tag-00", "tag-49"},
+		},
+		{
+			name:   "fail: tags over limit",
+			tags:   overLimitTags,
+			expErr: ErrTooManyTags,
+		},
+		{
+			name:           "success: lowest priority tags over limit dropped",
+			tags:           overLimitTags,
+			dropExcessTags: true,
+			expTags:        []string{VolumeNameTagKey, AwsEbsDriverTagKey, "kubernetes.io/cluster/my-cluster", "tag-00", "tag-46"},
+			expDroppedTags: []string{"tag-47", "tag-48", "tag-49"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			if tc.expErr == nil {
+				mockEC2.EXPECT().CreateVolumeWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ aws.Context, input *ec2.CreateVolumeInput, _ ...request.Option) (*ec2.Volume, error) {
+						tags := map[string]string{}
+						for _, tag := range input.TagSpecifications[0].Tags {
+							tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+						}
+						assert.Len(t, tags, len(tc.tags)-len(tc.expDroppedTags))
+						for _, key := range tc.expTags {
+							assert.Contains(t, tags, key)
+						}
+						for _, key := range tc.expDroppedTags {
+							assert.NotContains(t, tags, key)
+						}
+						return nil, awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+					})
+			}
+
+			_, err := c.CreateDisk(context.Background(), "vol-test-name", &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				Tags:             tc.tags,
+				AvailabilityZone: defaultZone,
+				DropExcessTags:   tc.dropExcessTags,
+				DryRun:           true,
+			})
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestCreateSnapshot(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -1368,14 +2975,52 @@ func TestCreateSnapshot(t *testing.T) {
 		expErr          error
 	}{
 		{
-			name:         "success: normal",
+			name:         "success: normal",
+			snapshotName: "snap-test-name",
+			snapshotOptions: &SnapshotOptions{
+				Tags: map[string]string{
+					SnapshotNameTagKey: "snap-test-name",
+					AwsEbsDriverTagKey: "true",
+					"extra-tag-key":    "extra-tag-value",
+				},
+			},
+			expInput: &ec2.CreateSnapshotInput{
+				VolumeId: aws.String("snap-test-volume"),
+				DryRun:   aws.Bool(false),
+				TagSpecifications: []*ec2.TagSpecification{
+					{
+						ResourceType: aws.String("snapshot"),
+						Tags: []*ec2.Tag{
+							{
+								Key:   aws.String(SnapshotNameTagKey),
+								Value: aws.String("snap-test-name"),
+							},
+							{
+								Key:   aws.String(AwsEbsDriverTagKey),
+								Value: aws.String("true"),
+							},
+							{
+								Key:   aws.String("extra-tag-key"),
+								Value: aws.String("extra-tag-value"),
+							},
+						},
+					},
+				},
+				Description: aws.String("Created by AWS EBS CSI driver for volume snap-test-volume"),
+			},
+			expSnapshot: &Snapshot{
+				SourceVolumeID: "snap-test-volume",
+			},
+			expErr: nil,
+		},
+		{
+			name:         "success: outpost",
 			snapshotName: "snap-test-name",
 			snapshotOptions: &SnapshotOptions{
 				Tags: map[string]string{
 					SnapshotNameTagKey: "snap-test-name",
-					AwsEbsDriverTagKey: "true",
-					"extra-tag-key":    "extra-tag-value",
 				},
+				OutpostArn: "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0",
 			},
 			expInput: &ec2.CreateSnapshotInput{
 				VolumeId: aws.String("snap-test-volume"),
@@ -1388,18 +3033,11 @@ func TestCreateSnapshot(t *testing.T) {
 								Key:   aws.String(SnapshotNameTagKey),
 								Value: aws.String("snap-test-name"),
 							},
-							{
-								Key:   aws.String(AwsEbsDriverTagKey),
-								Value: aws.String("true"),
-							},
-							{
-								Key:   aws.String("extra-tag-key"),
-								Value: aws.String("extra-tag-value"),
-							},
 						},
 					},
 				},
 				Description: aws.String("Created by AWS EBS CSI driver for volume snap-test-volume"),
+				OutpostArn:  aws.String("arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"),
 			},
 			expSnapshot: &Snapshot{
 				SourceVolumeID: "snap-test-volume",
@@ -1444,6 +3082,259 @@ func TestCreateSnapshot(t *testing.T) {
 	}
 }
 
+func TestCreateGroupSnapshot(t *testing.T) {
+	attachedTo := func(instanceIDs ...string) []*ec2.VolumeAttachment {
+		var attachments []*ec2.VolumeAttachment
+		for _, instanceID := range instanceIDs {
+			attachments = append(attachments, &ec2.VolumeAttachment{
+				InstanceId: aws.String(instanceID),
+				State:      aws.String(volumeAttachedState),
+			})
+		}
+		return attachments
+	}
+	instance := &ec2.Instance{
+		InstanceId:     aws.String("i-1"),
+		RootDeviceName: aws.String("/dev/xvda"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+			{DeviceName: aws.String("/dev/xvdba"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")}},
+			{DeviceName: aws.String("/dev/xvdbb"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-2")}},
+			{DeviceName: aws.String("/dev/xvdbc"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-other")}},
+		},
+	}
+	snapshotInfo := func(snapshotID, volumeID string) *ec2.SnapshotInfo {
+		return &ec2.SnapshotInfo{
+			SnapshotId: aws.String(snapshotID),
+			VolumeId:   aws.String(volumeID),
+			VolumeSize: aws.Int64(10),
+			State:      aws.String("pending"),
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		volumeIDs    []string
+		volumes      []*ec2.Volume
+		describeErr  error
+		snapshots    []*ec2.SnapshotInfo
+		expInput     *ec2.CreateSnapshotsInput
+		expDeleted   []string
+		expSnapshots []string
+		expErr       error
+	}{
+		{
+			name:      "success: data volumes",
+			volumeIDs: []string{"vol-1", "vol-2"},
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-1"), Attachments: attachedTo("i-1")},
+				{VolumeId: aws.String("vol-2"), Attachments: attachedTo("i-1")},
+			},
+			snapshots: []*ec2.SnapshotInfo{snapshotInfo("snap-1", "vol-1"), snapshotInfo("snap-2", "vol-2")},
+			expInput: &ec2.CreateSnapshotsInput{
+				InstanceSpecification: &ec2.InstanceSpecification{
+					InstanceId:           aws.String("i-1"),
+					ExcludeBootVolume:    aws.Bool(true),
+					ExcludeDataVolumeIds: []*string{aws.String("vol-other")},
+				},
+			},
+			expSnapshots: []string{"snap-1", "snap-2"},
+		},
+		{
+			name:      "success: root volume",
+			volumeIDs: []string{"vol-root", "vol-1"},
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-root"), Attachments: attachedTo("i-1")},
+				{VolumeId: aws.String("vol-1"), Attachments: attachedTo("i-1")},
+			},
+			snapshots: []*ec2.SnapshotInfo{snapshotInfo("snap-root", "vol-root"), snapshotInfo("snap-1", "vol-1")},
+			expInput: &ec2.CreateSnapshotsInput{
+				InstanceSpecification: &ec2.InstanceSpecification{
+					InstanceId:           aws.String("i-1"),
+					ExcludeBootVolume:    aws.Bool(false),
+					ExcludeDataVolumeIds: []*string{aws.String("vol-2"), aws.String("vol-other")},
+				},
+			},
+			expSnapshots: []string{"snap-root", "snap-1"},
+		},
+		{
+			name:      "success: multi-attach volume",
+			volumeIDs: []string{"vol-1", "vol-2"},
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-1"), Attachments: attachedTo("i-2", "i-1")},
+				{VolumeId: aws.String("vol-2"), Attachments: attachedTo("i-1")},
+			},
+			snapshots: []*ec2.SnapshotInfo{snapshotInfo("snap-1", "vol-1"), snapshotInfo("snap-2", "vol-2")},
+			expInput: &ec2.CreateSnapshotsInput{
+				InstanceSpecification: &ec2.InstanceSpecification{
+					InstanceId:           aws.String("i-1"),
+					ExcludeBootVolume:    aws.Bool(true),
+					ExcludeDataVolumeIds: []*string{aws.String("vol-other")},
+				},
+			},
+			expSnapshots: []string{"snap-1", "snap-2"},
+		},
+		{
+			name:      "fail: volumes attached to different instances",
+			volumeIDs: []string{"vol-1", "vol-2"},
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-1"), Attachments: attachedTo("i-1")},
+				{VolumeId: aws.String("vol-2"), Attachments: attachedTo("i-2")},
+			},
+			expErr: ErrNotAttachedToSameInstance,
+		},
+		{
+			name:      "fail: volume not attached",
+			volumeIDs: []string{"vol-1", "vol-2"},
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-1"), Attachments: attachedTo("i-1")},
+				{VolumeId: aws.String("vol-2")},
+			},
+			expErr: ErrNotAttachedToSameInstance,
+		},
+		{
+			name:        "fail: volume not found",
+			volumeIDs:   []string{"vol-1", "vol-missing"},
+			describeErr: awserr.New("InvalidVolume.NotFound", "", nil),
+			expErr:      ErrNotFound,
+		},
+		{
+			name:      "fail: snapshots of other volumes are deleted",
+			volumeIDs: []string{"vol-1", "vol-2"},
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-1"), Attachments: attachedTo("i-1")},
+				{VolumeId: aws.String("vol-2"), Attachments: attachedTo("i-1")},
+			},
+			snapshots: []*ec2.SnapshotInfo{snapshotInfo("snap-1", "vol-1"), snapshotInfo("snap-2", "vol-2"), snapshotInfo("snap-3", "vol-3")},
+			expInput: &ec2.CreateSnapshotsInput{
+				InstanceSpecification: &ec2.InstanceSpecification{
+					InstanceId:           aws.String("i-1"),
+					ExcludeBootVolume:    aws.Bool(true),
+					ExcludeDataVolumeIds: []*string{aws.String("vol-other")},
+				},
+			},
+			expDeleted: []string{"snap-1", "snap-2", "snap-3"},
+			expErr:     errors.New("the volumes attached to instance i-1 changed"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+			ctx := context.Background()
+
+			mockEC2.EXPECT().DescribeVolumesWithContext(gomock.Any(), gomock.Eq(&ec2.DescribeVolumesInput{VolumeIds: aws.StringSlice(tc.volumeIDs)})).Return(&ec2.DescribeVolumesOutput{Volumes: tc.volumes}, tc.describeErr)
+			if tc.expInput != nil {
+				mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{instance}}}}, nil)
+				mockEC2.EXPECT().CreateSnapshotsWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.CreateSnapshotsInput, _ ...request.Option) (*ec2.CreateSnapshotsOutput, error) {
+					assert.Equal(t, tc.expInput.InstanceSpecification, input.InstanceSpecification)
+					assert.Equal(t, []*ec2.TagSpecification{{
+						ResourceType: aws.String("snapshot"),
+						Tags:         []*ec2.Tag{{Key: aws.String(GroupSnapshotIDTagKey), Value: aws.String("groupsnapshot-1")}},
+					}}, input.TagSpecifications)
+					return &ec2.CreateSnapshotsOutput{Snapshots: tc.snapshots}, nil
+				})
+			}
+			for _, snapshotID := range tc.expDeleted {
+				mockEC2.EXPECT().DeleteSnapshotWithContext(gomock.Any(), gomock.Eq(&ec2.DeleteSnapshotInput{SnapshotId: aws.String(snapshotID), DryRun: aws.Bool(false)})).Return(&ec2.DeleteSnapshotOutput{}, nil)
+			}
+
+			snapshots, err := c.CreateGroupSnapshot(ctx, tc.volumeIDs, &SnapshotOptions{Tags: map[string]string{GroupSnapshotIDTagKey: "groupsnapshot-1"}})
+			if tc.expErr != nil {
+				if errors.Is(tc.expErr, ErrNotFound) || errors.Is(tc.expErr, ErrNotAttachedToSameInstance) {
+					assert.ErrorIs(t, err, tc.expErr)
+				} else {
+					assert.ErrorContains(t, err, tc.expErr.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateGroupSnapshot() failed: expected no error, got: %v", err)
+			}
+			var snapshotIDs []string
+			for _, snapshot := range snapshots {
+				snapshotIDs = append(snapshotIDs, snapshot.SnapshotID)
+				assert.Equal(t, util.GiBToBytes(10), snapshot.Size)
+				assert.False(t, snapshot.ReadyToUse)
+			}
+			assert.Equal(t, tc.expSnapshots, snapshotIDs)
+		})
+	}
+}
+
+func TestListGroupSnapshots(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+	ctx := context.Background()
+
+	expInput := &ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + GroupSnapshotIDTagKey),
+				Values: []*string{aws.String("groupsnapshot-1")},
+			},
+		},
+		OwnerIds: []*string{aws.String("self")},
+	}
+	mockEC2.EXPECT().DescribeSnapshotsPagesWithContext(gomock.Any(), gomock.Eq(expInput), gomock.Any()).DoAndReturn(func(_ context.Context, _ *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, _ ...request.Option) error {
+		fn(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{
+			{SnapshotId: aws.String("snap-1"), VolumeId: aws.String("vol-1"), State: aws.String("completed")},
+		}}, false)
+		fn(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{
+			{SnapshotId: aws.String("snap-2"), VolumeId: aws.String("vol-2"), State: aws.String("pending")},
+		}}, true)
+		return nil
+	})
+
+	snapshots, err := c.ListGroupSnapshots(ctx, "groupsnapshot-1")
+	if err != nil {
+		t.Fatalf("ListGroupSnapshots() failed: expected no error, got: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("ListGroupSnapshots() failed: expected 2 snapshots, got %d", len(snapshots))
+	}
+	assert.Equal(t, "snap-1", snapshots[0].SnapshotID)
+	assert.True(t, snapshots[0].ReadyToUse)
+	assert.Equal(t, "vol-2", snapshots[1].SourceVolumeID)
+	assert.False(t, snapshots[1].ReadyToUse)
+}
+
+func TestSnapshotSourceVolumeSize(t *testing.T) {
+	ec2Snapshot := &ec2.Snapshot{
+		SnapshotId: aws.String("snap-test-name"),
+		VolumeId:   aws.String("snap-test-volume"),
+		VolumeSize: aws.Int64(20),
+		State:      aws.String("completed"),
+	}
+	expSize := util.GiBToBytes(20)
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+	ctx := context.Background()
+
+	mockEC2.EXPECT().CreateSnapshotWithContext(gomock.Any(), gomock.Any()).Return(ec2Snapshot, nil)
+	snapshot, err := c.CreateSnapshot(ctx, "snap-test-volume", &SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("CreateSnapshot() failed: expected no error, got: %v", err)
+	}
+	if snapshot.Size != expSize {
+		t.Fatalf("CreateSnapshot() failed: expected size %d, got %d", expSize, snapshot.Size)
+	}
+
+	mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{ec2Snapshot}}, nil)
+	resp, err := c.ListSnapshots(ctx, "", 0, "")
+	if err != nil {
+		t.Fatalf("ListSnapshots() failed: expected no error, got: %v", err)
+	}
+	if len(resp.Snapshots) != 1 || resp.Snapshots[0].Size != expSize {
+		t.Fatalf("ListSnapshots() failed: expected one snapshot of size %d, got %+v", expSize, resp.Snapshots)
+	}
+}
 func TestEnableFastSnapshotRestores(t *testing.T) {
 	testCases := []struct {
 		name              string
@@ -1528,6 +3419,148 @@ func TestEnableFastSnapshotRestores(t *testing.T) {
 	}
 }
 
+func TestDisableFastSnapshotRestores(t *testing.T) {
+	testCases := []struct {
+		name      string
+		expOutput *ec2.DisableFastSnapshotRestoresOutput
+		ec2Err    error
+		expErr    bool
+	}{
+		{
+			name: "success: normal",
+			expOutput: &ec2.DisableFastSnapshotRestoresOutput{
+				Successful: []*ec2.DisableFastSnapshotRestoreSuccessItem{{
+					AvailabilityZone: aws.String("us-west-2a"),
+					SnapshotId:       aws.String("snap-test-id")}},
+			},
+		},
+		{
+			name: "fail: unsuccessful response",
+			expOutput: &ec2.DisableFastSnapshotRestoresOutput{
+				Unsuccessful: []*ec2.DisableFastSnapshotRestoreErrorItem{{
+					SnapshotId: aws.String("snap-test-id"),
+				}},
+			},
+			expErr: true,
+		},
+		{
+			name:   "fail: error",
+			ec2Err: fmt.Errorf("DisableFastSnapshotRestores error"),
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			expRequest := &ec2.DisableFastSnapshotRestoresInput{
+				AvailabilityZones: aws.StringSlice([]string{"us-west-2a"}),
+				SourceSnapshotIds: aws.StringSlice([]string{"snap-test-id"}),
+			}
+			mockEC2.EXPECT().DisableFastSnapshotRestoresWithContext(gomock.Any(), gomock.Eq(expRequest)).Return(tc.expOutput, tc.ec2Err)
+
+			err := c.DisableFastSnapshotRestores(context.Background(), []string{"us-west-2a"}, "snap-test-id")
+			if tc.expErr && err == nil {
+				t.Fatalf("DisableFastSnapshotRestores() failed: expected error, got nothing")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatalf("DisableFastSnapshotRestores() failed: expected no error, got: %v", err)
+			}
+			mockCtrl.Finish()
+		})
+	}
+}
+
+func TestGetFastSnapshotRestoreZones(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+
+	mockEC2.EXPECT().DescribeFastSnapshotRestoresPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ aws.Context, input *ec2.DescribeFastSnapshotRestoresInput, fn func(*ec2.DescribeFastSnapshotRestoresOutput, bool) bool, _ ...request.Option) error {
+			if aws.StringValue(input.Filters[0].Values[0]) != "snap-test-id" {
+				t.Fatalf("Unexpected snapshot filter: %v", input.Filters[0])
+			}
+			fn(&ec2.DescribeFastSnapshotRestoresOutput{
+				FastSnapshotRestores: []*ec2.DescribeFastSnapshotRestoreSuccessItem{
+					{AvailabilityZone: aws.String("us-west-2a"), SnapshotId: aws.String("snap-test-id"), State: aws.String("enabled")},
+				},
+			}, false)
+			fn(&ec2.DescribeFastSnapshotRestoresOutput{
+				FastSnapshotRestores: []*ec2.DescribeFastSnapshotRestoreSuccessItem{
+					{AvailabilityZone: aws.String("us-west-2b"), SnapshotId: aws.String("snap-test-id"), State: aws.String("optimizing")},
+				},
+			}, true)
+			return nil
+		})
+
+	zones, err := c.GetFastSnapshotRestoreZones(context.Background(), "snap-test-id")
+	if err != nil {
+		t.Fatalf("GetFastSnapshotRestoreZones() failed: expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(zones, []string{"us-west-2a", "us-west-2b"}) {
+		t.Fatalf("GetFastSnapshotRestoreZones() failed: expected zones [us-west-2a us-west-2b], got %v", zones)
+	}
+}
+
+func TestGetFastSnapshotRestoreState(t *testing.T) {
+	testCases := []struct {
+		name     string
+		items    []*ec2.DescribeFastSnapshotRestoreSuccessItem
+		expState string
+		expErr   error
+	}{
+		{
+			name: "success: optimizing",
+			items: []*ec2.DescribeFastSnapshotRestoreSuccessItem{
+				{AvailabilityZone: aws.String("us-west-2a"), SnapshotId: aws.String("snap-test-id"), State: aws.String("optimizing")},
+			},
+			expState: "optimizing",
+		},
+		{
+			name:     "success: never enabled",
+			expState: "",
+		},
+		{
+			name:   "fail: DescribeFastSnapshotRestores returned generic error",
+			expErr: errors.New("DescribeFastSnapshotRestores generic error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			mockEC2.EXPECT().DescribeFastSnapshotRestoresPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ aws.Context, input *ec2.DescribeFastSnapshotRestoresInput, fn func(*ec2.DescribeFastSnapshotRestoresOutput, bool) bool, _ ...request.Option) error {
+					if aws.StringValue(input.Filters[0].Values[0]) != "snap-test-id" || aws.StringValue(input.Filters[1].Values[0]) != "us-west-2a" {
+						t.Fatalf("Unexpected filters: %v", input.Filters)
+					}
+					if tc.expErr != nil {
+						return tc.expErr
+					}
+					fn(&ec2.DescribeFastSnapshotRestoresOutput{FastSnapshotRestores: tc.items}, true)
+					return nil
+				})
+
+			state, err := c.GetFastSnapshotRestoreState(context.Background(), "snap-test-id", "us-west-2a")
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expState, state)
+		})
+	}
+}
+
 func TestAvailabilityZones(t *testing.T) {
 	testCases := []struct {
 		name             string
@@ -1990,6 +4023,22 @@ func TestGetSnapshotByID(t *testing.T) {
 			},
 			expErr: nil,
 		},
+		{
+			name:         "success: encrypted",
+			snapshotName: "snap-test-name",
+			snapshotOptions: &SnapshotOptions{
+				Tags: map[string]string{
+					SnapshotNameTagKey: "snap-test-name",
+					AwsEbsDriverTagKey: "true",
+				},
+			},
+			expSnapshot: &Snapshot{
+				SourceVolumeID: "snap-test-volume",
+				Encrypted:      true,
+				KmsKeyID:       "arn:aws:kms:us-west-2:111111111111:key/test",
+			},
+			expErr: nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2002,12 +4051,20 @@ func TestGetSnapshotByID(t *testing.T) {
 				SnapshotId: aws.String(tc.snapshotOptions.Tags[SnapshotNameTagKey]),
 				VolumeId:   aws.String("snap-test-volume"),
 				State:      aws.String("completed"),
+				Encrypted:  aws.Bool(tc.expSnapshot.Encrypted),
+			}
+			if tc.expSnapshot.KmsKeyID != "" {
+				ec2snapshot.KmsKeyId = aws.String(tc.expSnapshot.KmsKeyID)
 			}
 
 			ctx := context.Background()
 			mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{ec2snapshot}}, nil)
 
-			_, err := c.GetSnapshotByID(ctx, tc.snapshotOptions.Tags[SnapshotNameTagKey])
+			snapshot, err := c.GetSnapshotByID(ctx, tc.snapshotOptions.Tags[SnapshotNameTagKey])
+			if err == nil {
+				assert.Equal(t, tc.expSnapshot.Encrypted, snapshot.Encrypted)
+				assert.Equal(t, tc.expSnapshot.KmsKeyID, snapshot.KmsKeyID)
+			}
 			if err != nil {
 				if tc.expErr == nil {
 					t.Fatalf("GetSnapshotByName() failed: expected no error, got: %v", err)
@@ -2022,6 +4079,123 @@ func TestGetSnapshotByID(t *testing.T) {
 		})
 	}
 }
+func TestGetSnapshotByIDNotFound(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+
+	mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("InvalidSnapshot.NotFound", "Snapshot not found", nil))
+
+	_, err := c.GetSnapshotByID(context.Background(), "snap-test")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCopySnapshotFromRegion(t *testing.T) {
+	defer func(interval time.Duration) { copySnapshotPollInterval = interval }(copySnapshotPollInterval)
+	copySnapshotPollInterval = time.Millisecond
+
+	const (
+		sourceSnapshotID = "snap-source"
+		sourceRegion     = "us-east-1"
+	)
+	snapshot := func(snapshotID, state string) *ec2.Snapshot {
+		return &ec2.Snapshot{SnapshotId: aws.String(snapshotID), VolumeId: aws.String("vol-source"), State: aws.String(state)}
+	}
+	existingCopies := func(snapshots ...*ec2.Snapshot) func(context.Context, *ec2.DescribeSnapshotsInput, func(*ec2.DescribeSnapshotsOutput, bool) bool, ...request.Option) error {
+		return func(_ context.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, _ ...request.Option) error {
+			assert.Equal(t, "tag:"+CopiedSnapshotTagKey, aws.StringValue(input.Filters[0].Name))
+			assert.Equal(t, sourceRegion+"/"+sourceSnapshotID, aws.StringValue(input.Filters[0].Values[0]))
+			fn(&ec2.DescribeSnapshotsOutput{Snapshots: snapshots}, true)
+			return nil
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		copyOptions   *CopySnapshotOptions
+		expSnapshotID string
+		expErr        string
+		mockFunc      func(*MockEC2API)
+	}{
+		{
+			name:          "success: copies snapshot and waits for the copy",
+			copyOptions:   &CopySnapshotOptions{Tags: map[string]string{AwsEbsDriverTagKey: "true"}, Encrypted: true, KmsKeyID: "arn:aws:kms:us-west-2:111111111111:key/test"},
+			expSnapshotID: "snap-copy",
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeSnapshotsPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(existingCopies()),
+					mockEC2.EXPECT().CopySnapshotWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *ec2.CopySnapshotInput, _ ...request.Option) (*ec2.CopySnapshotOutput, error) {
+						assert.Equal(t, sourceSnapshotID, aws.StringValue(input.SourceSnapshotId))
+						assert.Equal(t, sourceRegion, aws.StringValue(input.SourceRegion))
+						assert.True(t, aws.BoolValue(input.Encrypted))
+						assert.Equal(t, "arn:aws:kms:us-west-2:111111111111:key/test", aws.StringValue(input.KmsKeyId))
+						assert.ElementsMatch(t, []*ec2.Tag{
+							{Key: aws.String(CopiedSnapshotTagKey), Value: aws.String(sourceRegion + "/" + sourceSnapshotID)},
+							{Key: aws.String(AwsEbsDriverTagKey), Value: aws.String("true")},
+						}, input.TagSpecifications[0].Tags)
+						return &ec2.CopySnapshotOutput{SnapshotId: aws.String("snap-copy")}, nil
+					}),
+					mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{snapshot("snap-copy", "pending")}}, nil),
+					mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{snapshot("snap-copy", "completed")}}, nil),
+				)
+			},
+		},
+		{
+			name:          "success: reuses existing copy and ignores failed copies",
+			copyOptions:   &CopySnapshotOptions{},
+			expSnapshotID: "snap-copy",
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeSnapshotsPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(existingCopies(snapshot("snap-failed", "error"), snapshot("snap-copy", "pending"))),
+					mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{snapshot("snap-copy", "completed")}}, nil),
+				)
+			},
+		},
+		{
+			name:        "fail: source snapshot not found",
+			copyOptions: &CopySnapshotOptions{},
+			expErr:      ErrNotFound.Error(),
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeSnapshotsPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(existingCopies()),
+					mockEC2.EXPECT().CopySnapshotWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("InvalidSnapshot.NotFound", "Snapshot not found", nil)),
+				)
+			},
+		},
+		{
+			name:        "fail: copy failed",
+			copyOptions: &CopySnapshotOptions{},
+			expErr:      "copy snap-copy of snapshot us-east-1/snap-source failed",
+			mockFunc: func(mockEC2 *MockEC2API) {
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeSnapshotsPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(existingCopies(snapshot("snap-copy", "pending"))),
+					mockEC2.EXPECT().DescribeSnapshotsWithContext(gomock.Any(), gomock.Any()).Return(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{snapshot("snap-copy", "error")}}, nil),
+				)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2 := NewMockEC2API(mockCtrl)
+			tc.mockFunc(mockEC2)
+			c := newCloud(mockEC2)
+
+			snapshot, err := c.CopySnapshotFromRegion(context.Background(), sourceSnapshotID, sourceRegion, tc.copyOptions)
+			if tc.expErr != "" {
+				assert.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expSnapshotID, snapshot.SnapshotID)
+			assert.True(t, snapshot.ReadyToUse)
+		})
+	}
+}
+
 func TestListSnapshots(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -2406,7 +4580,7 @@ func TestWaitForAttachmentState(t *testing.T) {
 func newCloud(mockEC2 ec2iface.EC2API) Cloud {
 	c := &cloud{
 		region: "test-region",
-		dm:     dm.NewDeviceManager(),
+		dm:     dm.NewDeviceManager(0),
 		ec2:    mockEC2,
 	}
 	return c
@@ -2568,3 +4742,70 @@ func (m *eqCreateVolumeMatcher) Matches(x interface{}) bool {
 func (m *eqCreateVolumeMatcher) String() string {
 	return m.expected.String()
 }
+
+func TestErrorReason(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedReason string
+		expectedCode   string
+	}{
+		{
+			name:           "quota",
+			err:            awserr.New("VolumeLimitExceeded", "", nil),
+			expectedReason: ErrorReasonQuota,
+			expectedCode:   "VolumeLimitExceeded",
+		},
+		{
+			name:           "capacity",
+			err:            awserr.New("InsufficientVolumeCapacity", "", nil),
+			expectedReason: ErrorReasonCapacity,
+			expectedCode:   "InsufficientVolumeCapacity",
+		},
+		{
+			name:           "throttling",
+			err:            awserr.New("RequestLimitExceeded", "", nil),
+			expectedReason: ErrorReasonThrottling,
+			expectedCode:   "RequestLimitExceeded",
+		},
+		{
+			name:           "validation",
+			err:            awserr.New("InvalidParameterCombination", "", nil),
+			expectedReason: ErrorReasonValidation,
+			expectedCode:   "InvalidParameterCombination",
+		},
+		{
+			name:           "kms",
+			err:            awserr.New("InvalidKMSKey.InvalidState", "", nil),
+			expectedReason: ErrorReasonKMS,
+			expectedCode:   "InvalidKMSKey.InvalidState",
+		},
+		{
+			name:           "wrapped",
+			err:            fmt.Errorf("could not create volume in EC2: %w", awserr.New("Throttling", "", nil)),
+			expectedReason: ErrorReasonThrottling,
+			expectedCode:   "Throttling",
+		},
+		{
+			name:         "unclassified",
+			err:          awserr.New("InternalError", "", nil),
+			expectedCode: "InternalError",
+		},
+		{
+			name: "not an AWS error",
+			err:  ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, code := ErrorReason(tc.err)
+			if reason != tc.expectedReason {
+				t.Fatalf("Expected reason %q, got %q", tc.expectedReason, reason)
+			}
+			if code != tc.expectedCode {
+				t.Fatalf("Expected code %q, got %q", tc.expectedCode, code)
+			}
+		})
+	}
+}
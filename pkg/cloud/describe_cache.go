@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
+)
+
+// describeCacheRequestsMetric counts the lookups of described instances and
+// volumes in the cache, labeled with whether they were found.
+const describeCacheRequestsMetric = "cloudprovider_aws_describe_cache_requests_total"
+
+const (
+	cachedInstance = "instance"
+	cachedVolume   = "volume"
+)
+
+// describeCache remembers the instances and volumes described by the driver
+// for a time to live, so that e.g. repeated attachments to the same node do
+// not describe the node again. The block device mappings of cached instances
+// are updated with the attachments and detachments of the driver, and the
+// cached volumes it attaches, detaches, modifies or deletes are dropped;
+// changes made outside the driver are only seen once the cached description
+// expired.
+//
+// It is nil, and nothing is cached, unless the time to live is positive.
+type describeCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]describeCacheEntry
+}
+
+type describeCacheEntry struct {
+	// object is the *ec2.Instance or *ec2.Volume described
+	object  interface{}
+	expires time.Time
+}
+
+func newDescribeCache(ttl time.Duration) *describeCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &describeCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]describeCacheEntry{},
+	}
+}
+
+// instance returns the cached description of the instance nodeID, if any.
+func (c *describeCache) instance(nodeID string) (*ec2.Instance, bool) {
+	instance, ok := c.get(cachedInstance, nodeID).(*ec2.Instance)
+	return instance, ok
+}
+
+// volume returns the cached description of the volume volumeID, if any.
+func (c *describeCache) volume(volumeID string) (*ec2.Volume, bool) {
+	volume, ok := c.get(cachedVolume, volumeID).(*ec2.Volume)
+	return volume, ok
+}
+
+func (c *describeCache) get(kind, id string) interface{} {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok || !c.now().Before(entry.expires) {
+		metrics.Recorder().IncreaseCount(describeCacheRequestsMetric, map[string]string{"resource": kind, "result": "miss"})
+		return nil
+	}
+	metrics.Recorder().IncreaseCount(describeCacheRequestsMetric, map[string]string{"resource": kind, "result": "hit"})
+	return entry.object
+}
+
+// put caches the description of the instance or volume id. Instance and
+// volume IDs do not overlap.
+func (c *describeCache) put(id string, object interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[id] = describeCacheEntry{
+		object:  object,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// attached records the attachment of volumeID to the cached instance nodeID
+// at devicePath, so that the device name is not reused.
+func (c *describeCache) attached(nodeID, volumeID, devicePath string) {
+	c.updateInstance(nodeID, volumeID, func(mappings []*ec2.InstanceBlockDeviceMapping) []*ec2.InstanceBlockDeviceMapping {
+		for _, mapping := range mappings {
+			if aws.StringValue(mapping.DeviceName) == devicePath {
+				return mappings
+			}
+		}
+		return append(mappings, &ec2.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(devicePath),
+			Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String(volumeID)},
+		})
+	})
+}
+
+// detached records the detachment of volumeID from the cached instance nodeID.
+func (c *describeCache) detached(nodeID, volumeID string) {
+	c.updateInstance(nodeID, volumeID, func(mappings []*ec2.InstanceBlockDeviceMapping) []*ec2.InstanceBlockDeviceMapping {
+		var kept []*ec2.InstanceBlockDeviceMapping
+		for _, mapping := range mappings {
+			if mapping.Ebs == nil || aws.StringValue(mapping.Ebs.VolumeId) != volumeID {
+				kept = append(kept, mapping)
+			}
+		}
+		return kept
+	})
+}
+
+// updateInstance replaces the cached instance nodeID, if any, with a copy
+// whose block device mappings are updated by update, and drops the cached
+// volume volumeID. The cached instance itself is not modified, as it may be
+// in use.
+func (c *describeCache) updateInstance(nodeID, volumeID string, update func([]*ec2.InstanceBlockDeviceMapping) []*ec2.InstanceBlockDeviceMapping) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, volumeID)
+	entry, ok := c.entries[nodeID]
+	if !ok {
+		return
+	}
+	instance := *entry.object.(*ec2.Instance)
+	mappings := make([]*ec2.InstanceBlockDeviceMapping, len(instance.BlockDeviceMappings))
+	copy(mappings, instance.BlockDeviceMappings)
+	instance.BlockDeviceMappings = update(mappings)
+	entry.object = &instance
+	c.entries[nodeID] = entry
+}
+
+// invalidate drops the cached descriptions of the instances and volumes ids,
+// e.g. after an attachment failed.
+func (c *describeCache) invalidate(ids ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+}
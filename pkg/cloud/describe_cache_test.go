@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestDescribeCache(t *testing.T) {
+	if c := newDescribeCache(0); c != nil {
+		t.Fatalf("expected no cache for ttl 0, got %v", c)
+	}
+	var disabled *describeCache
+	disabled.put("i-test", &ec2.Instance{})
+	if _, ok := disabled.instance("i-test"); ok {
+		t.Fatal("expected no instance in a disabled cache")
+	}
+
+	now := time.Now()
+	c := newDescribeCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.put("i-test", newFakeInstance("i-test", "vol-test-1", "/dev/xvdaa"))
+	c.put("vol-test-1", &ec2.Volume{VolumeId: aws.String("vol-test-1")})
+	if _, ok := c.instance("i-test"); !ok {
+		t.Fatal("expected a cached instance")
+	}
+	if _, ok := c.volume("vol-test-1"); !ok {
+		t.Fatal("expected a cached volume")
+	}
+	// Instances are not returned as volumes and vice versa
+	if _, ok := c.volume("i-test"); ok {
+		t.Fatal("expected no volume for an instance ID")
+	}
+
+	// Attachments update a copy of the cached instance and drop the volume
+	cached, _ := c.instance("i-test")
+	c.attached("i-test", "vol-test-2", "/dev/xvdab")
+	instance, _ := c.instance("i-test")
+	if len(instance.BlockDeviceMappings) != 2 || aws.StringValue(instance.BlockDeviceMappings[1].DeviceName) != "/dev/xvdab" {
+		t.Fatalf("expected the attachment in the block device mappings, got %v", instance.BlockDeviceMappings)
+	}
+	if len(cached.BlockDeviceMappings) != 1 {
+		t.Fatalf("expected the previously cached instance to be unchanged, got %v", cached.BlockDeviceMappings)
+	}
+	c.detached("i-test", "vol-test-1")
+	instance, _ = c.instance("i-test")
+	if len(instance.BlockDeviceMappings) != 1 || aws.StringValue(instance.BlockDeviceMappings[0].Ebs.VolumeId) != "vol-test-2" {
+		t.Fatalf("expected only vol-test-2 in the block device mappings, got %v", instance.BlockDeviceMappings)
+	}
+	if _, ok := c.volume("vol-test-1"); ok {
+		t.Fatal("expected the detached volume to be dropped")
+	}
+
+	// Invalidated and expired descriptions are not returned
+	c.put("vol-test-1", &ec2.Volume{VolumeId: aws.String("vol-test-1")})
+	c.invalidate("vol-test-1")
+	if _, ok := c.volume("vol-test-1"); ok {
+		t.Fatal("expected no volume after invalidation")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.instance("i-test"); ok {
+		t.Fatal("expected no instance after the ttl")
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"sync"
+	"time"
+)
+
+// forceDetachesMetric counts the detachments that were forced because they
+// were stuck in the detaching state.
+const forceDetachesMetric = "cloudprovider_aws_force_detaches_total"
+
+// detachTracker records when the detachment of a volume from an instance was
+// first requested, so that a detachment stuck in the detaching state, e.g.
+// because the instance is unreachable, can be forced once it took longer than
+// timeout. The detachment is tracked across DetachDisk calls, as the
+// external-attacher retries ControllerUnpublishVolume with a timeout shorter
+// than a stuck detachment lasts. A detachment is forgotten once it is done,
+// or once the volume is attached to the instance again.
+//
+// It is nil, and detachments are never forced, unless the timeout is positive.
+type detachTracker struct {
+	timeout time.Duration
+	now     func() time.Time
+	// onForce, if set, is called after a detachment was forced.
+	onForce func(volumeID, nodeID string)
+
+	mu      sync.Mutex
+	started map[detachment]time.Time
+}
+
+// detachment is the detachment of a volume from an instance.
+type detachment struct {
+	volumeID string
+	nodeID   string
+}
+
+func newDetachTracker(timeout time.Duration, onForce func(volumeID, nodeID string)) *detachTracker {
+	if timeout <= 0 {
+		return nil
+	}
+	return &detachTracker{
+		timeout: timeout,
+		now:     time.Now,
+		onForce: onForce,
+		started: map[detachment]time.Time{},
+	}
+}
+
+// begin records the start of the detachment of volumeID from nodeID, unless
+// it was already requested before. It returns how long the detachment may
+// still take before it is forced, which is not positive once it must be
+// forced, and false if detachments are never forced.
+func (t *detachTracker) begin(volumeID, nodeID string) (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := detachment{volumeID: volumeID, nodeID: nodeID}
+	started, ok := t.started[key]
+	if !ok {
+		started = t.now()
+		t.started[key] = started
+	}
+	return t.timeout - t.now().Sub(started), true
+}
+
+// end forgets the detachment of volumeID from nodeID once it completed or the
+// volume was attached to nodeID again.
+func (t *detachTracker) end(volumeID, nodeID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.started, detachment{volumeID: volumeID, nodeID: nodeID})
+}
+
+// forced reports that the detachment of volumeID from nodeID was forced.
+func (t *detachTracker) forced(volumeID, nodeID string) {
+	if t.onForce != nil {
+		t.onForce(volumeID, nodeID)
+	}
+}
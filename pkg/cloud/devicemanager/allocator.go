@@ -18,6 +18,7 @@ package devicemanager
 
 import (
 	"fmt"
+	"strings"
 )
 
 // ExistingNames is a map of assigned device names. Presence of a key with a device
@@ -45,10 +46,32 @@ var _ NameAllocator = &nameAllocator{}
 // It does this by using a list of legal EBS device names from device_names.go
 func (d *nameAllocator) GetNext(existingNames ExistingNames) (string, error) {
 	for _, name := range deviceNames {
-		if _, found := existingNames[name]; !found {
+		if !isNameInUse(existingNames, name) {
 			return name, nil
 		}
 	}
 
 	return "", fmt.Errorf("there are no names available")
 }
+
+// isNameInUse returns whether name or its alias is in existingNames. EC2
+// treats /dev/sdX and /dev/xvdX as the same attachment point, so a volume
+// attached outside of the driver as /dev/sdf makes /dev/xvdf unavailable.
+func isNameInUse(existingNames ExistingNames, name string) bool {
+	if _, found := existingNames[name]; found {
+		return true
+	}
+	_, found := existingNames[aliasName(name)]
+	return found
+}
+
+// aliasName returns the /dev/sdX name of a /dev/xvdX name and vice versa.
+func aliasName(name string) string {
+	if suffix, found := strings.CutPrefix(name, "/dev/xvd"); found {
+		return "/dev/sd" + suffix
+	}
+	if suffix, found := strings.CutPrefix(name, "/dev/sd"); found {
+		return "/dev/xvd" + suffix
+	}
+	return name
+}
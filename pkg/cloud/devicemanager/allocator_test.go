@@ -51,3 +51,59 @@ func TestNameAllocatorError(t *testing.T) {
 		t.Errorf("expected error, got device  %q", name)
 	}
 }
+
+func TestNameAllocatorAlias(t *testing.T) {
+	testCases := []struct {
+		name          string
+		existingNames ExistingNames
+		expected      string
+	}{
+		{
+			name:          "xvd name in use",
+			existingNames: ExistingNames{"/dev/xvdaa": ""},
+			expected:      "/dev/xvdab",
+		},
+		{
+			name:          "sd alias of xvd name in use",
+			existingNames: ExistingNames{"/dev/sdaa": ""},
+			expected:      "/dev/xvdab",
+		},
+		{
+			name:          "sd alias of several xvd names in use",
+			existingNames: ExistingNames{"/dev/sdaa": "", "/dev/xvdab": "", "/dev/sdac": ""},
+			expected:      "/dev/xvdad",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allocator := nameAllocator{}
+			actual, err := allocator.GetNext(tc.existingNames)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDeviceNamesNamespace(t *testing.T) {
+	names := map[string]bool{}
+	for _, name := range deviceNames {
+		if names[aliasName(name)] {
+			t.Errorf("device names %q and %q are the same attachment point", name, aliasName(name))
+		}
+		names[name] = true
+	}
+
+	for _, first := range "bc" {
+		for _, second := range "abcdefghijklmnopqrstuvwxyz" {
+			name := "/dev/xvd" + string(first) + string(second)
+			if !names[name] {
+				t.Errorf("expected device name %q in the list", name)
+			}
+		}
+	}
+}
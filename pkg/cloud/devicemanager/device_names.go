@@ -20,7 +20,10 @@ package devicemanager
 // Notable (undocumented) restrictions include:
 // /dev/xvda is broken on Windows (despite the API allowing it)
 // /dev/xvddx is the last allowed device name in the /dev/xvd{a-z}{a-z} series
-// /dev/xvdc{a-z} don't work on some Windows instance types
+// /dev/sd{b-z} are the same attachment points as /dev/xvd{b-z}, so they are
+// not in the list
+// /dev/xvdc{a-z} don't work on some Windows instance types, so they come last
+// and are only chosen on nodes that have used up all the other names
 //
 // These names are ordered such that /dev/xvda{a-z} and /dev/xvdb{a-z} are
 // the first 52 names in the list. This is intentional, so that those names
@@ -131,30 +134,31 @@ var deviceNames = []string{
 	"/dev/xvdx",
 	"/dev/xvdy",
 	"/dev/xvdz",
-	"/dev/sdb",
-	"/dev/sdc",
-	"/dev/sdd",
-	"/dev/sde",
-	"/dev/sdf",
-	"/dev/sdg",
-	"/dev/sdh",
-	"/dev/sdi",
-	"/dev/sdj",
-	"/dev/sdk",
-	"/dev/sdl",
-	"/dev/sdm",
-	"/dev/sdn",
-	"/dev/sdo",
-	"/dev/sdp",
-	"/dev/sdq",
-	"/dev/sdr",
-	"/dev/sds",
-	"/dev/sdt",
-	"/dev/sdu",
-	"/dev/sdv",
-	"/dev/sdw",
-	"/dev/sdx",
-	"/dev/sdy",
-	"/dev/sdz",
 	"/dev/sda2",
+	"/dev/xvdca",
+	"/dev/xvdcb",
+	"/dev/xvdcc",
+	"/dev/xvdcd",
+	"/dev/xvdce",
+	"/dev/xvdcf",
+	"/dev/xvdcg",
+	"/dev/xvdch",
+	"/dev/xvdci",
+	"/dev/xvdcj",
+	"/dev/xvdck",
+	"/dev/xvdcl",
+	"/dev/xvdcm",
+	"/dev/xvdcn",
+	"/dev/xvdco",
+	"/dev/xvdcp",
+	"/dev/xvdcq",
+	"/dev/xvdcr",
+	"/dev/xvdcs",
+	"/dev/xvdct",
+	"/dev/xvdcu",
+	"/dev/xvdcv",
+	"/dev/xvdcw",
+	"/dev/xvdcx",
+	"/dev/xvdcy",
+	"/dev/xvdcz",
 }
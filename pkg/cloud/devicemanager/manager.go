@@ -19,6 +19,7 @@ package devicemanager
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -67,39 +68,55 @@ type deviceManager struct {
 	// and then get a second request before we attach the volume.
 	mux      sync.Mutex
 	inFlight inFlightAttaching
+
+	// leaseTimeout is how long a device name stays reserved for an attach
+	// that was neither released nor confirmed, e.g. because the device of a
+	// failed attach was tainted. Reservations never expire if 0.
+	leaseTimeout time.Duration
+	now          func() time.Time
 }
 
 var _ DeviceManager = &deviceManager{}
 
+// reservation is a device name reserved for a volume being attached.
+type reservation struct {
+	volumeID   string
+	reservedAt time.Time
+}
+
 // inFlightAttaching represents the device names being currently attached to nodes.
-// A valid pseudo-representation of it would be {"nodeID": {"deviceName: "volumeID"}}.
-type inFlightAttaching map[string]map[string]string
+// A valid pseudo-representation of it would be {"nodeID": {"deviceName: {"volumeID", reservedAt}}}.
+type inFlightAttaching map[string]map[string]reservation
 
-func (i inFlightAttaching) Add(nodeID, volumeID, name string) {
+func (i inFlightAttaching) Add(nodeID, volumeID, name string, reservedAt time.Time) {
 	attaching := i[nodeID]
 	if attaching == nil {
-		attaching = make(map[string]string)
+		attaching = make(map[string]reservation)
 		i[nodeID] = attaching
 	}
-	attaching[name] = volumeID
+	attaching[name] = reservation{volumeID: volumeID, reservedAt: reservedAt}
 }
 
 func (i inFlightAttaching) Del(nodeID, name string) {
 	delete(i[nodeID], name)
 }
 
-func (i inFlightAttaching) GetNames(nodeID string) map[string]string {
+func (i inFlightAttaching) GetNames(nodeID string) map[string]reservation {
 	return i[nodeID]
 }
 
 func (i inFlightAttaching) GetVolume(nodeID, name string) string {
-	return i[nodeID][name]
+	return i[nodeID][name].volumeID
 }
 
-func NewDeviceManager() DeviceManager {
+// NewDeviceManager returns a DeviceManager whose device name reservations
+// expire after leaseTimeout, or never if 0.
+func NewDeviceManager(leaseTimeout time.Duration) DeviceManager {
 	return &deviceManager{
 		nameAllocator: &nameAllocator{},
 		inFlight:      make(inFlightAttaching),
+		leaseTimeout:  leaseTimeout,
+		now:           time.Now,
 	}
 }
 
@@ -130,7 +147,7 @@ func (d *deviceManager) NewDevice(instance *ec2.Instance, volumeID string) (*Dev
 	}
 
 	// Add the chosen device and volume to the "attachments in progress" map
-	d.inFlight.Add(nodeID, volumeID, name)
+	d.inFlight.Add(nodeID, volumeID, name, d.now())
 
 	return d.newBlockDevice(instance, volumeID, name, false), nil
 }
@@ -199,16 +216,63 @@ func (d *deviceManager) getDeviceNamesInUse(instance *ec2.Instance) map[string]s
 	inUse := map[string]string{}
 	for _, blockDevice := range instance.BlockDeviceMappings {
 		name := aws.StringValue(blockDevice.DeviceName)
-		inUse[name] = aws.StringValue(blockDevice.Ebs.VolumeId)
+		// The name is in use even if it is not an EBS volume of the driver,
+		// e.g. the root volume or a volume attached outside of the driver
+		volumeID := ""
+		if blockDevice.Ebs != nil {
+			volumeID = aws.StringValue(blockDevice.Ebs.VolumeId)
+		}
+		inUse[name] = volumeID
 	}
 
-	for name, volumeID := range d.inFlight.GetNames(nodeID) {
-		inUse[name] = volumeID
+	d.releaseExpired(nodeID, inUse)
+	d.releaseConflicting(nodeID, inUse)
+	for name, reservation := range d.inFlight.GetNames(nodeID) {
+		inUse[name] = reservation.volumeID
 	}
 
 	return inUse
 }
 
+// releaseExpired releases the reservations of nodeID older than the lease
+// timeout. attached are the device names of the volumes attached to the
+// instance, which keep the names of confirmed attaches in use.
+func (d *deviceManager) releaseExpired(nodeID string, attached map[string]string) {
+	if d.leaseTimeout == 0 {
+		return
+	}
+	for name, reservation := range d.inFlight.GetNames(nodeID) {
+		if d.now().Sub(reservation.reservedAt) < d.leaseTimeout {
+			continue
+		}
+		if attached[name] == reservation.volumeID {
+			klog.V(4).InfoS("Releasing expired device name reservation of attached volume", "nodeID", nodeID, "device", name, "volumeID", reservation.volumeID)
+		} else {
+			klog.InfoS("Releasing expired device name reservation of volume that was not attached", "nodeID", nodeID, "device", name, "volumeID", reservation.volumeID, "reservedAt", reservation.reservedAt)
+		}
+		d.inFlight.Del(nodeID, name)
+	}
+}
+
+// releaseConflicting releases the reservations of nodeID whose device name
+// the instance uses for another volume. attached are the device names of the
+// volumes attached to the instance. The attach of the reserved volume can no
+// longer succeed with that name, so keeping the reservation only hides the
+// actual user of the name.
+func (d *deviceManager) releaseConflicting(nodeID string, attached map[string]string) {
+	for name, reservation := range d.inFlight.GetNames(nodeID) {
+		volumeID, found := attached[name]
+		if !found {
+			volumeID, found = attached[aliasName(name)]
+		}
+		if !found || volumeID == reservation.volumeID {
+			continue
+		}
+		klog.InfoS("Releasing device name reservation of volume whose device name is used by another attachment", "nodeID", nodeID, "device", name, "volumeID", reservation.volumeID, "attachedVolumeID", volumeID)
+		d.inFlight.Del(nodeID, name)
+	}
+}
+
 func (d *deviceManager) getPath(inUse map[string]string, volumeID string) string {
 	for name, volID := range inUse {
 		if volumeID == volID {
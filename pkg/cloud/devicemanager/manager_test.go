@@ -18,6 +18,7 @@ package devicemanager
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -54,7 +55,7 @@ func TestNewDevice(t *testing.T) {
 		},
 	}
 	// Use a shared DeviceManager to make sure that there are no race conditions
-	dm := NewDeviceManager()
+	dm := NewDeviceManager(0)
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -130,7 +131,7 @@ func TestNewDeviceWithExistingDevice(t *testing.T) {
 		},
 	}
 	// Use a shared DeviceManager to make sure that there are no race conditions
-	dm := NewDeviceManager()
+	dm := NewDeviceManager(0)
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -165,7 +166,7 @@ func TestGetDevice(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			dm := NewDeviceManager()
+			dm := NewDeviceManager(0)
 			fakeInstance := newFakeInstance(tc.instanceID, tc.existingVolumeID, tc.existingDevicePath)
 
 			// Should create valid Device with valid path
@@ -199,7 +200,7 @@ func TestReleaseDevice(t *testing.T) {
 		},
 	}
 
-	dm := NewDeviceManager()
+	dm := NewDeviceManager(0)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			fakeInstance := newFakeInstance(tc.instanceID, tc.existingVolumeID, tc.existingDevicePath)
@@ -223,6 +224,145 @@ func TestReleaseDevice(t *testing.T) {
 	}
 }
 
+func TestReservationLeaseExpiry(t *testing.T) {
+	testCases := []struct {
+		name         string
+		leaseTimeout time.Duration
+		elapsed      time.Duration
+		attached     bool
+		expReserved  bool
+		expAssigned  bool
+	}{
+		{
+			name:         "unconfirmed reservation within lease is kept",
+			leaseTimeout: time.Minute,
+			elapsed:      30 * time.Second,
+			expReserved:  true,
+			expAssigned:  true,
+		},
+		{
+			name:         "unconfirmed reservation is released after lease",
+			leaseTimeout: time.Minute,
+			elapsed:      2 * time.Minute,
+		},
+		{
+			name:         "confirmed reservation is released after lease and name stays in use",
+			leaseTimeout: time.Minute,
+			elapsed:      2 * time.Minute,
+			attached:     true,
+			expAssigned:  true,
+		},
+		{
+			name:        "reservation never expires without lease timeout",
+			elapsed:     24 * time.Hour,
+			expReserved: true,
+			expAssigned: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			dm := NewDeviceManager(tc.leaseTimeout).(*deviceManager)
+			dm.now = func() time.Time { return now }
+			instance := newFakeInstance("instance-1", "vol-1", "/dev/xvdbc")
+
+			// A tainted device of a failed attach stays reserved after release
+			dev, err := dm.NewDevice(instance, "vol-2")
+			assertDevice(t, dev, false /*IsAlreadyAssigned*/, err)
+			dev.Taint()
+			dev.Release(false)
+
+			if tc.attached {
+				instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, &ec2.InstanceBlockDeviceMapping{
+					DeviceName: aws.String(dev.Path),
+					Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-2")},
+				})
+			}
+			now = now.Add(tc.elapsed)
+
+			dev2, err := dm.GetDevice(instance, "vol-2")
+			assertDevice(t, dev2, tc.expAssigned, err)
+			if tc.expAssigned && dev2.Path != dev.Path {
+				t.Fatalf("Expected path %v, got %v", dev.Path, dev2.Path)
+			}
+			reserved := dm.inFlight.GetVolume("instance-1", dev.Path) == "vol-2"
+			if reserved != tc.expReserved {
+				t.Fatalf("Expected device name reserved: %v, got: %v", tc.expReserved, reserved)
+			}
+		})
+	}
+}
+
+func TestNewDeviceWithNonEBSMappings(t *testing.T) {
+	dm := NewDeviceManager(0)
+	instance := &ec2.Instance{
+		InstanceId: aws.String("instance-1"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String(deviceNames[0])},
+			{
+				DeviceName: aws.String("/dev/sdab"),
+				Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")},
+			},
+		},
+	}
+
+	dev, err := dm.NewDevice(instance, "vol-2")
+	assertDevice(t, dev, false /*IsAlreadyAssigned*/, err)
+	if dev.Path != deviceNames[2] {
+		t.Fatalf("Expected path %v, got %v", deviceNames[2], dev.Path)
+	}
+}
+
+func TestReleaseConflictingReservation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mappedName  string
+		mappedID    string
+		expReserved bool
+	}{
+		{
+			name:        "reservation of attaching volume is kept",
+			mappedName:  deviceNames[0],
+			mappedID:    "vol-2",
+			expReserved: true,
+		},
+		{
+			name:       "reservation of name used by another volume is released",
+			mappedName: deviceNames[0],
+			mappedID:   "vol-3",
+		},
+		{
+			name:       "reservation of name whose alias is used by another volume is released",
+			mappedName: aliasName(deviceNames[0]),
+			mappedID:   "vol-3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dm := NewDeviceManager(0).(*deviceManager)
+			instance := &ec2.Instance{InstanceId: aws.String("instance-1")}
+
+			dev, err := dm.NewDevice(instance, "vol-2")
+			assertDevice(t, dev, false /*IsAlreadyAssigned*/, err)
+			dev.Taint()
+			dev.Release(false)
+
+			instance = newFakeInstance("instance-1", tc.mappedID, tc.mappedName)
+			dev2, err := dm.NewDevice(instance, "vol-4")
+			assertDevice(t, dev2, false /*IsAlreadyAssigned*/, err)
+			if dev2.Path == dev.Path {
+				t.Fatalf("Expected path other than %v", dev.Path)
+			}
+			reserved := dm.inFlight.GetVolume("instance-1", dev.Path) == "vol-2"
+			if reserved != tc.expReserved {
+				t.Fatalf("Expected device name reserved: %v, got: %v", tc.expReserved, reserved)
+			}
+		})
+	}
+}
+
 func newFakeInstance(instanceID, volumeID, devicePath string) *ec2.Instance {
 	return &ec2.Instance{
 		InstanceId: aws.String(instanceID),
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// EndpointOptions configure the endpoints of the EC2 API and of the EC2
+// instance metadata service, e.g. in GovCloud, China or air-gapped clusters.
+// Custom endpoints take precedence over FIPS and dual-stack endpoints.
+type EndpointOptions struct {
+	// EC2Endpoint is the URL of the EC2 API in all regions, if set, e.g. a VPC
+	// endpoint of EC2.
+	EC2Endpoint string
+	// RegionalEC2Endpoints are the URLs of the EC2 API by region. They take
+	// precedence over EC2Endpoint, e.g. for the source region of a snapshot copy.
+	RegionalEC2Endpoints map[string]string
+	// UseFIPSEndpoints resolves FIPS 140-2 endpoints of the EC2 API.
+	UseFIPSEndpoints bool
+	// UseDualStackEndpoints resolves dual-stack (IPv4 and IPv6) endpoints of
+	// the EC2 API and the IPv6 endpoint of the instance metadata service.
+	UseDualStackEndpoints bool
+}
+
+// Validate returns an error if a custom endpoint is not an HTTP(S) URL.
+func (o EndpointOptions) Validate() error {
+	if o.EC2Endpoint != "" {
+		if err := validateEndpointURL(o.EC2Endpoint); err != nil {
+			return fmt.Errorf("invalid EC2 endpoint: %w", err)
+		}
+	}
+	for region, endpoint := range o.RegionalEC2Endpoints {
+		if region == "" {
+			return fmt.Errorf("invalid EC2 endpoint %q: region is empty", endpoint)
+		}
+		if err := validateEndpointURL(endpoint); err != nil {
+			return fmt.Errorf("invalid EC2 endpoint of region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+func validateEndpointURL(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("%q is not an http or https URL", endpoint)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", endpoint)
+	}
+	return nil
+}
+
+// ec2Endpoint returns the custom endpoint of the EC2 API in region, or "" if
+// the endpoint is resolved by the SDK.
+func (o EndpointOptions) ec2Endpoint(region string) string {
+	if endpoint, ok := o.RegionalEC2Endpoints[region]; ok {
+		return endpoint
+	}
+	return o.EC2Endpoint
+}
+
+// apply configures the resolution of the endpoints of the EC2 API in config.
+// FIPS and dual-stack endpoints are left to the SDK configuration, e.g. the
+// AWS_USE_FIPS_ENDPOINT environment variable, unless enabled.
+func (o EndpointOptions) apply(config *aws.Config) {
+	if o.UseFIPSEndpoints {
+		config.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
+	}
+	if o.UseDualStackEndpoints {
+		config.UseDualStackEndpoint = endpoints.DualStackEndpointStateEnabled
+	}
+	if o.EC2Endpoint == "" && len(o.RegionalEC2Endpoints) == 0 {
+		return
+	}
+	config.EndpointResolver = endpoints.ResolverFunc(func(service, region string, optFns ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if service == ec2.EndpointsID {
+			if endpoint := o.ec2Endpoint(region); endpoint != "" {
+				return endpoints.ResolvedEndpoint{
+					URL:           endpoint,
+					SigningRegion: region,
+				}, nil
+			}
+		}
+		return endpoints.DefaultResolver().EndpointFor(service, region, optFns...)
+	})
+}
+
+// metadataSessionOptions returns the options of the session of the instance
+// metadata service client.
+func (o EndpointOptions) metadataSessionOptions(config *aws.Config) session.Options {
+	opts := session.Options{Config: *config}
+	if o.UseDualStackEndpoints {
+		opts.EC2IMDSEndpointMode = endpoints.EC2IMDSEndpointModeStateIPv6
+	}
+	return opts
+}
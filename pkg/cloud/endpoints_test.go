@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options EndpointOptions
+		expErr  string
+	}{
+		{
+			name: "valid: default endpoints",
+		},
+		{
+			name: "valid: custom endpoints",
+			options: EndpointOptions{
+				EC2Endpoint:          "https://vpce-0123.ec2.us-west-2.vpce.amazonaws.com",
+				RegionalEC2Endpoints: map[string]string{"us-east-1": "http://ec2.example.com:8080"},
+			},
+		},
+		{
+			name:    "invalid: endpoint without scheme",
+			options: EndpointOptions{EC2Endpoint: "ec2.us-west-2.amazonaws.com"},
+			expErr:  `invalid EC2 endpoint: "ec2.us-west-2.amazonaws.com" is not an http or https URL`,
+		},
+		{
+			name:    "invalid: endpoint without host",
+			options: EndpointOptions{EC2Endpoint: "https://"},
+			expErr:  `invalid EC2 endpoint: "https://" has no host`,
+		},
+		{
+			name:    "invalid: regional endpoint",
+			options: EndpointOptions{RegionalEC2Endpoints: map[string]string{"us-east-1": "ftp://ec2.example.com"}},
+			expErr:  `invalid EC2 endpoint of region us-east-1: "ftp://ec2.example.com" is not an http or https URL`,
+		},
+		{
+			name:    "invalid: regional endpoint without region",
+			options: EndpointOptions{RegionalEC2Endpoints: map[string]string{"": "https://ec2.example.com"}},
+			expErr:  `invalid EC2 endpoint "https://ec2.example.com": region is empty`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.options.Validate()
+			if tc.expErr != "" {
+				assert.EqualError(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestEndpointOptionsApply(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     EndpointOptions
+		region      string
+		expEndpoint string
+	}{
+		{
+			name:        "default endpoint",
+			region:      "us-west-2",
+			expEndpoint: "https://ec2.us-west-2.amazonaws.com",
+		},
+		{
+			name:        "FIPS endpoint",
+			options:     EndpointOptions{UseFIPSEndpoints: true},
+			region:      "us-gov-west-1",
+			expEndpoint: "https://ec2.us-gov-west-1.amazonaws.com",
+		},
+		{
+			name:        "FIPS endpoint in commercial region",
+			options:     EndpointOptions{UseFIPSEndpoints: true},
+			region:      "us-east-1",
+			expEndpoint: "https://ec2-fips.us-east-1.amazonaws.com",
+		},
+		{
+			name:        "dual-stack endpoint",
+			options:     EndpointOptions{UseDualStackEndpoints: true},
+			region:      "us-west-2",
+			expEndpoint: "https://ec2.us-west-2.api.aws",
+		},
+		{
+			name:        "China endpoint",
+			region:      "cn-north-1",
+			expEndpoint: "https://ec2.cn-north-1.amazonaws.com.cn",
+		},
+		{
+			name:        "custom endpoint",
+			options:     EndpointOptions{EC2Endpoint: "https://ec2.example.com", UseFIPSEndpoints: true},
+			region:      "us-west-2",
+			expEndpoint: "https://ec2.example.com",
+		},
+		{
+			name: "regional endpoint",
+			options: EndpointOptions{
+				EC2Endpoint:          "https://ec2.example.com",
+				RegionalEC2Endpoints: map[string]string{"us-east-1": "https://ec2.us-east-1.example.com"},
+			},
+			region:      "us-east-1",
+			expEndpoint: "https://ec2.us-east-1.example.com",
+		},
+		{
+			name:        "default endpoint of region without regional endpoint",
+			options:     EndpointOptions{RegionalEC2Endpoints: map[string]string{"us-east-1": "https://ec2.us-east-1.example.com"}},
+			region:      "us-west-2",
+			expEndpoint: "https://ec2.us-west-2.amazonaws.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &aws.Config{
+				Region:      aws.String(tc.region),
+				Credentials: credentials.AnonymousCredentials,
+			}
+			tc.options.apply(config)
+			svc := ec2.New(session.Must(session.NewSession(config)))
+			assert.Equal(t, tc.expEndpoint, svc.Endpoint)
+			assert.Equal(t, tc.region, svc.SigningRegion)
+		})
+	}
+}
+
+func TestEndpointOptionsMetadataSessionOptions(t *testing.T) {
+	config := &aws.Config{EC2MetadataEnableFallback: aws.Bool(false)}
+
+	opts := EndpointOptions{}.metadataSessionOptions(config)
+	assert.Equal(t, endpoints.EC2IMDSEndpointModeStateUnset, opts.EC2IMDSEndpointMode)
+	assert.Equal(t, *config, opts.Config)
+
+	opts = EndpointOptions{UseDualStackEndpoints: true}.metadataSessionOptions(config)
+	assert.Equal(t, endpoints.EC2IMDSEndpointModeStateIPv6, opts.EC2IMDSEndpointMode)
+}
@@ -0,0 +1,1063 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// CloudProviderAWS backs the driver with the EC2 API.
+	CloudProviderAWS = "aws"
+	// CloudProviderFake backs the driver with an in-memory fake of EC2, to
+	// test the driver without AWS credentials.
+	CloudProviderFake = "fake"
+)
+
+// CloudProviders are the supported cloud providers of the driver.
+var CloudProviders = []string{CloudProviderAWS, CloudProviderFake}
+
+const (
+	// FakeInstanceID is the ID of the instance of the fake cloud provider if
+	// none are configured.
+	FakeInstanceID = "i-0123456789abcdef0"
+	// fakeRegion is the region of the fake cloud provider if none is configured.
+	fakeRegion = "us-east-1"
+	// fakeInstanceType is the type of the instances of the fake cloud provider.
+	fakeInstanceType = "m5.large"
+	// fakeAttachmentPollInterval is the time between checks of the state of
+	// the attachments of the fake cloud provider.
+	fakeAttachmentPollInterval = 10 * time.Millisecond
+)
+
+// FakeCloudOptions configure the fake cloud provider.
+type FakeCloudOptions struct {
+	// Region is the region of the fake cloud, us-east-1 if empty.
+	Region string
+	// AvailabilityZones are the zones of the region, zones a, b and c of the
+	// region if empty. Volumes are created in the first zone by default.
+	AvailabilityZones []string
+	// InstanceIDs are the IDs of the instances volumes can be attached to,
+	// FakeInstanceID if empty. The first instance is the node of the driver.
+	InstanceIDs []string
+	// Latency is added to every call.
+	Latency time.Duration
+	// TransitionDelay is how long volumes take to be created, attached,
+	// detached and modified, and snapshots and fast snapshot restores to
+	// complete.
+	TransitionDelay time.Duration
+	// Errors are returned by the calls of the methods of Cloud, by method
+	// name, e.g. "AttachDisk", instead of calling them.
+	Errors map[string]error
+}
+
+func (o FakeCloudOptions) withDefaults() FakeCloudOptions {
+	if o.Region == "" {
+		o.Region = fakeRegion
+	}
+	if len(o.AvailabilityZones) == 0 {
+		o.AvailabilityZones = []string{o.Region + "a", o.Region + "b", o.Region + "c"}
+	}
+	if len(o.InstanceIDs) == 0 {
+		o.InstanceIDs = []string{FakeInstanceID}
+	}
+	return o
+}
+
+// Metadata returns the instance data of the node of the fake cloud provider,
+// i.e. its first instance in its first availability zone.
+func (o FakeCloudOptions) Metadata() *Metadata {
+	o = o.withDefaults()
+	return &Metadata{
+		InstanceID:       o.InstanceIDs[0],
+		InstanceType:     fakeInstanceType,
+		Region:           o.Region,
+		AvailabilityZone: o.AvailabilityZones[0],
+	}
+}
+
+// FakeCloudProvider is an in-memory implementation of Cloud, to run the
+// driver, e.g. csi-sanity, without AWS. Volumes, attachments, snapshots and
+// fast snapshot restores go through the same states as in EC2, each
+// transition taking the configured TransitionDelay.
+type FakeCloudProvider struct {
+	options FakeCloudOptions
+
+	mu        sync.Mutex
+	errors    map[string]error
+	instances map[string]struct{}
+	volumes   map[string]*fakeVolume
+	snapshots map[string]*fakeSnapshot
+	// fsrs are the times fast snapshot restores were enabled, by snapshot
+	// ID and availability zone.
+	fsrs   map[string]map[string]time.Time
+	nextID uint64
+}
+
+var _ Cloud = &FakeCloudProvider{}
+
+type fakeVolume struct {
+	disk       Disk
+	name       string
+	volumeType string
+	iops       int
+	throughput int
+	// attachments are the attachments of the volume by instance ID.
+	attachments map[string]*ec2.VolumeAttachment
+}
+
+type fakeSnapshot struct {
+	snapshot Snapshot
+	tags     map[string]string
+	// completes is when the snapshot becomes ready to use.
+	completes time.Time
+}
+
+// NewFakeCloudProvider returns a fake cloud provider without volumes or
+// snapshots.
+func NewFakeCloudProvider(options FakeCloudOptions) *FakeCloudProvider {
+	options = options.withDefaults()
+	c := &FakeCloudProvider{
+		options:   options,
+		errors:    map[string]error{},
+		instances: map[string]struct{}{},
+		volumes:   map[string]*fakeVolume{},
+		snapshots: map[string]*fakeSnapshot{},
+		fsrs:      map[string]map[string]time.Time{},
+	}
+	for method, err := range options.Errors {
+		c.errors[method] = err
+	}
+	for _, instanceID := range options.InstanceIDs {
+		c.instances[instanceID] = struct{}{}
+	}
+	return c
+}
+
+// SetError makes the calls of method return err, or be served again if err
+// is nil.
+func (c *FakeCloudProvider) SetError(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.errors, method)
+		return
+	}
+	c.errors[method] = err
+}
+
+// call simulates the latency of a call of method, and returns the error
+// configured for it, if any.
+func (c *FakeCloudProvider) call(ctx context.Context, method string) error {
+	if err := c.sleep(ctx, c.options.Latency); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err, ok := c.errors[method]; ok {
+		klog.FromContext(ctx).V(4).Info("Fake cloud provider returning configured error", "method", method, "err", err)
+		return err
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newID returns a new resource ID with the given prefix, e.g. "vol".
+func (c *FakeCloudProvider) newID(prefix string) string {
+	c.nextID++
+	return fmt.Sprintf("%s-%017x", prefix, c.nextID)
+}
+
+func (c *FakeCloudProvider) CreateDisk(ctx context.Context, volumeName string, diskOptions *DiskOptions) (*Disk, error) {
+	if err := c.call(ctx, "CreateDisk"); err != nil {
+		return nil, err
+	}
+	capacityGiB := util.BytesToGiB(diskOptions.CapacityBytes)
+	volumeType := diskOptions.VolumeType
+	if volumeType == "" {
+		volumeType = VolumeTypeGP3
+	}
+	limits, err := limitsOf(volumeType, diskOptions.BlockExpress)
+	if err != nil {
+		return nil, err
+	}
+	if err = limits.validateSize(volumeType, capacityGiB); err != nil {
+		return nil, err
+	}
+	zone := diskOptions.AvailabilityZone
+	if zone == "" {
+		zone = c.options.AvailabilityZones[0]
+	}
+
+	c.mu.Lock()
+	if !c.hasZone(zone) {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w: invalid availability zone %q", ErrInvalidVolumeParameters, zone)
+	}
+	if diskOptions.SnapshotID != "" {
+		snapshot, ok := c.snapshots[diskOptions.SnapshotID]
+		if !ok {
+			c.mu.Unlock()
+			return nil, ErrNotFound
+		}
+		if util.GiBToBytes(capacityGiB) < snapshot.snapshot.Size {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("%w: volume of %d GiB is smaller than snapshot %s", ErrInvalidVolumeParameters, capacityGiB, diskOptions.SnapshotID)
+		}
+	}
+	if diskOptions.DryRun {
+		c.mu.Unlock()
+		return &Disk{CapacityGiB: capacityGiB, AvailabilityZone: zone, SnapshotID: diskOptions.SnapshotID}, nil
+	}
+	// Like the client token of CreateVolume, the name makes creation idempotent
+	for _, volume := range c.volumes {
+		if volume.name == volumeName {
+			defer c.mu.Unlock()
+			if volume.disk.CapacityGiB != capacityGiB || volume.volumeType != volumeType {
+				return nil, ErrIdempotentParameterMismatch
+			}
+			return volume.toDisk(), nil
+		}
+	}
+	tags := make(map[string]string, len(diskOptions.Tags))
+	for key, value := range diskOptions.Tags {
+		tags[key] = value
+	}
+	volume := &fakeVolume{
+		disk: Disk{
+			VolumeID:           c.newID("vol"),
+			CapacityGiB:        capacityGiB,
+			AvailabilityZone:   zone,
+			SnapshotID:         diskOptions.SnapshotID,
+			OutpostArn:         diskOptions.OutpostArn,
+			Tags:               tags,
+			CreateTime:         time.Now(),
+			MultiAttachEnabled: diskOptions.MultiAttachEnabled,
+			State:              ec2.VolumeStateCreating,
+		},
+		name:        volumeName,
+		volumeType:  volumeType,
+		iops:        diskOptions.IOPS,
+		throughput:  diskOptions.Throughput,
+		attachments: map[string]*ec2.VolumeAttachment{},
+	}
+	c.volumes[volume.disk.VolumeID] = volume
+	c.mu.Unlock()
+
+	// Like the EC2 cloud provider, wait for the volume to be available
+	if err := c.sleep(ctx, c.options.TransitionDelay); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVolumeNotAvailable, err)
+	}
+	c.mu.Lock()
+	volume.disk.State = ec2.VolumeStateAvailable
+	disk := volume.disk
+	c.mu.Unlock()
+	return &disk, nil
+}
+
+func (c *FakeCloudProvider) hasZone(zone string) bool {
+	for _, z := range c.options.AvailabilityZones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *FakeCloudProvider) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
+	if err := c.call(ctx, "DeleteDisk"); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	volume, ok := c.volumes[volumeID]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if len(volume.attachments) > 0 {
+		return false, fmt.Errorf("DeleteDisk could not delete volume: %w", ErrVolumeInUse)
+	}
+	delete(c.volumes, volumeID)
+	return true, nil
+}
+
+func (c *FakeCloudProvider) AttachDisk(ctx context.Context, volumeID, nodeID string) (string, error) {
+	if err := c.call(ctx, "AttachDisk"); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	if _, ok := c.instances[nodeID]; !ok {
+		c.mu.Unlock()
+		return "", ErrNotFound
+	}
+	volume, ok := c.volumes[volumeID]
+	if !ok {
+		c.mu.Unlock()
+		return "", ErrNotFound
+	}
+	if attachment, ok := volume.attachments[nodeID]; ok {
+		c.mu.Unlock()
+		device := aws.StringValue(attachment.Device)
+		if _, err := c.WaitForAttachmentState(ctx, volumeID, volumeAttachedState, nodeID, device, true); err != nil {
+			return "", err
+		}
+		return device, nil
+	}
+	if len(volume.attachments) > 0 && !volume.disk.MultiAttachEnabled {
+		c.mu.Unlock()
+		return "", fmt.Errorf("could not attach volume %q to node %q: %w", volumeID, nodeID, ErrVolumeInUse)
+	}
+	if volume.disk.State != ec2.VolumeStateAvailable && volume.disk.State != ec2.VolumeStateInUse {
+		c.mu.Unlock()
+		return "", fmt.Errorf("could not attach volume %q to node %q: volume is %s", volumeID, nodeID, volume.disk.State)
+	}
+	device := c.newDevicePath(nodeID)
+	attachment := &ec2.VolumeAttachment{
+		AttachTime: aws.Time(time.Now()),
+		Device:     aws.String(device),
+		InstanceId: aws.String(nodeID),
+		State:      aws.String(ec2.VolumeAttachmentStateAttaching),
+		VolumeId:   aws.String(volumeID),
+	}
+	volume.attachments[nodeID] = attachment
+	volume.disk.State = ec2.VolumeStateInUse
+	c.mu.Unlock()
+
+	c.transition(func() {
+		attachment.State = aws.String(ec2.VolumeAttachmentStateAttached)
+	})
+	if _, err := c.WaitForAttachmentState(ctx, volumeID, volumeAttachedState, nodeID, device, false); err != nil {
+		return "", err
+	}
+	return device, nil
+}
+
+// newDevicePath returns the first device name not used on the instance
+// nodeID, e.g. /dev/xvdba.
+func (c *FakeCloudProvider) newDevicePath(nodeID string) string {
+	used := map[string]struct{}{}
+	for _, volume := range c.volumes {
+		if attachment, ok := volume.attachments[nodeID]; ok {
+			used[aws.StringValue(attachment.Device)] = struct{}{}
+		}
+	}
+	for first := 'b'; first <= 'z'; first++ {
+		for second := 'a'; second <= 'z'; second++ {
+			device := fmt.Sprintf("/dev/xvd%c%c", first, second)
+			if _, ok := used[device]; !ok {
+				return device
+			}
+		}
+	}
+	return ""
+}
+
+// transition applies change after the transition delay, in the background
+// like EC2 does.
+func (c *FakeCloudProvider) transition(change func()) {
+	time.AfterFunc(c.options.TransitionDelay, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		change()
+	})
+}
+
+func (c *FakeCloudProvider) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
+	if err := c.call(ctx, "DetachDisk"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if _, ok := c.instances[nodeID]; !ok {
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	volume, ok := c.volumes[volumeID]
+	if !ok {
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	attachment, ok := volume.attachments[nodeID]
+	if !ok {
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	attachment.State = aws.String(ec2.VolumeAttachmentStateDetaching)
+	c.mu.Unlock()
+
+	c.transition(func() {
+		if volume.attachments[nodeID] != attachment {
+			return
+		}
+		delete(volume.attachments, nodeID)
+		if len(volume.attachments) == 0 {
+			volume.disk.State = ec2.VolumeStateAvailable
+		}
+	})
+	_, err := c.WaitForAttachmentState(ctx, volumeID, volumeDetachedState, nodeID, "", false)
+	return err
+}
+
+func (c *FakeCloudProvider) WaitForAttachmentState(ctx context.Context, volumeID, expectedState string, expectedInstance string, expectedDevice string, alreadyAssigned bool) (*ec2.VolumeAttachment, error) {
+	for {
+		c.mu.Lock()
+		volume, ok := c.volumes[volumeID]
+		if !ok {
+			c.mu.Unlock()
+			return nil, ErrNotFound
+		}
+		var attachment *ec2.VolumeAttachment
+		if a, ok := volume.attachments[expectedInstance]; ok {
+			copied := *a
+			attachment = &copied
+		}
+		c.mu.Unlock()
+
+		state := volumeDetachedState
+		if attachment != nil {
+			state = aws.StringValue(attachment.State)
+		}
+		if state == expectedState {
+			if attachment != nil && expectedDevice != "" && aws.StringValue(attachment.Device) != expectedDevice {
+				return nil, fmt.Errorf("attachment of volume %q to node %q has device %q, expected %q", volumeID, expectedInstance, aws.StringValue(attachment.Device), expectedDevice)
+			}
+			return attachment, nil
+		}
+		if expectedState == volumeAttachedState && attachment == nil {
+			return nil, fmt.Errorf("attachment of volume %q to node %q disappeared", volumeID, expectedInstance)
+		}
+		if err := c.sleep(ctx, fakeAttachmentPollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *FakeCloudProvider) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSizeBytes int64, options *ModifyDiskOptions) (int64, error) {
+	if err := c.call(ctx, "ResizeOrModifyDisk"); err != nil {
+		return 0, err
+	}
+	newSizeGiB := util.RoundUpGiB(newSizeBytes)
+	c.mu.Lock()
+	volume, ok := c.volumes[volumeID]
+	if !ok {
+		c.mu.Unlock()
+		return 0, ErrNotFound
+	}
+	oldSizeGiB := volume.disk.CapacityGiB
+	needsModification := newSizeGiB > oldSizeGiB ||
+		(options.VolumeType != "" && options.VolumeType != volume.volumeType) ||
+		(options.IOPS != 0 && options.IOPS != volume.iops) ||
+		(options.Throughput != 0 && options.Throughput != volume.throughput)
+	if newSizeGiB < oldSizeGiB {
+		newSizeGiB = oldSizeGiB
+	}
+	volumeType := volume.volumeType
+	if options.VolumeType != "" {
+		volumeType = options.VolumeType
+	}
+	c.mu.Unlock()
+	if !needsModification {
+		return oldSizeGiB, nil
+	}
+	limits, err := limitsOf(volumeType, false)
+	if err != nil {
+		return 0, err
+	}
+	if err = limits.validateSize(volumeType, newSizeGiB); err != nil {
+		return 0, err
+	}
+
+	// Like the EC2 cloud provider, wait for the modification to complete
+	if err := c.sleep(ctx, c.options.TransitionDelay); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.volumes[volumeID]; !ok {
+		return 0, ErrNotFound
+	}
+	volume.disk.CapacityGiB = newSizeGiB
+	volume.volumeType = volumeType
+	if options.IOPS != 0 {
+		volume.iops = options.IOPS
+	}
+	if options.Throughput != 0 {
+		volume.throughput = options.Throughput
+	}
+	return newSizeGiB, nil
+}
+
+func (c *FakeCloudProvider) GetDiskByName(ctx context.Context, name string, capacityBytes int64) (*Disk, error) {
+	if err := c.call(ctx, "GetDiskByName"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var found *fakeVolume
+	for _, volume := range c.volumes {
+		if volume.disk.Tags[VolumeNameTagKey] != name {
+			continue
+		}
+		if found != nil {
+			return nil, ErrMultiDisks
+		}
+		found = volume
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	if found.disk.CapacityGiB != util.BytesToGiB(capacityBytes) {
+		return nil, ErrDiskExistsDiffSize
+	}
+	return found.toDisk(), nil
+}
+
+func (c *FakeCloudProvider) GetDiskByID(ctx context.Context, volumeID string) (*Disk, error) {
+	if err := c.call(ctx, "GetDiskByID"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	volume, ok := c.volumes[volumeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return volume.toDisk(), nil
+}
+
+// toDisk returns a copy of the volume, with the instances it is attached to.
+func (v *fakeVolume) toDisk() *Disk {
+	disk := v.disk
+	disk.Attachments = nil
+	for instanceID, attachment := range v.attachments {
+		if aws.StringValue(attachment.State) == volumeAttachedState {
+			disk.Attachments = append(disk.Attachments, instanceID)
+		}
+	}
+	sort.Strings(disk.Attachments)
+	disk.Tags = make(map[string]string, len(v.disk.Tags))
+	for key, value := range v.disk.Tags {
+		disk.Tags[key] = value
+	}
+	return &disk
+}
+
+// sortedVolumes returns the volumes ordered by ID, so that pages are stable.
+func (c *FakeCloudProvider) sortedVolumes() []*fakeVolume {
+	volumes := make([]*fakeVolume, 0, len(c.volumes))
+	for _, volume := range c.volumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].disk.VolumeID < volumes[j].disk.VolumeID
+	})
+	return volumes
+}
+
+func (c *FakeCloudProvider) GetAvailableDisksByTags(ctx context.Context, tags map[string]string) ([]*Disk, error) {
+	if err := c.call(ctx, "GetAvailableDisksByTags"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	disks := []*Disk{}
+	for _, volume := range c.sortedVolumes() {
+		if volume.disk.State == ec2.VolumeStateAvailable && hasTags(volume.disk.Tags, tags) {
+			disks = append(disks, volume.toDisk())
+		}
+	}
+	return disks, nil
+}
+
+func hasTags(tags, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *FakeCloudProvider) ListDisks(ctx context.Context, maxResults int64, nextToken string) (*ListDisksResponse, error) {
+	if err := c.call(ctx, "ListDisks"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var disks []*Disk
+	for _, volume := range c.sortedVolumes() {
+		if _, ok := volume.disk.Tags[AwsEbsDriverTagKey]; ok {
+			disks = append(disks, volume.toDisk())
+		}
+	}
+	page, nextToken, err := paginate(len(disks), maxResults, nextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ListDisksResponse{Disks: disks[page.start:page.end], NextToken: nextToken}, nil
+}
+
+type fakePage struct {
+	start, end int
+}
+
+// paginate returns the page of up to maxResults of count items starting at
+// the index nextToken, and the token of the next page, if any. Unlike EC2,
+// pages of less than 5 items can be requested, e.g. by csi-sanity.
+func paginate(count int, maxResults int64, nextToken string) (fakePage, string, error) {
+	page := fakePage{end: count}
+	if nextToken != "" {
+		start, err := strconv.Atoi(nextToken)
+		if err != nil || start < 0 || start > count {
+			return page, "", ErrInvalidNextToken
+		}
+		page.start = start
+	}
+	if maxResults > 0 && int64(count-page.start) > maxResults {
+		page.end = page.start + int(maxResults)
+		return page, strconv.Itoa(page.end), nil
+	}
+	return page, "", nil
+}
+
+func (c *FakeCloudProvider) GetVolumeStatuses(ctx context.Context, volumeIDs []string) (map[string]*VolumeStatus, error) {
+	if err := c.call(ctx, "GetVolumeStatuses"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make(map[string]*VolumeStatus, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		if _, ok := c.volumes[volumeID]; !ok {
+			return nil, ErrNotFound
+		}
+		statuses[volumeID] = &VolumeStatus{Status: ec2.VolumeStatusInfoStatusOk}
+	}
+	return statuses, nil
+}
+
+func (c *FakeCloudProvider) IsExistInstance(ctx context.Context, nodeID string) bool {
+	if err := c.call(ctx, "IsExistInstance"); err != nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.instances[nodeID]
+	return ok
+}
+
+func (c *FakeCloudProvider) CheckKMSKeyAccess(ctx context.Context, volumeID, nodeID string) error {
+	if err := c.call(ctx, "CheckKMSKeyAccess"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.volumes[volumeID]; !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (*Snapshot, error) {
+	if err := c.call(ctx, "CreateSnapshot"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	volume, ok := c.volumes[volumeID]
+	if !ok {
+		return nil, fmt.Errorf("error creating snapshot of volume %s: %w", volumeID, ErrNotFound)
+	}
+	return c.newSnapshot(volumeID, util.GiBToBytes(volume.disk.CapacityGiB), snapshotOptions.Tags), nil
+}
+
+// newSnapshot adds a pending snapshot that completes after the transition
+// delay, and returns a copy of it.
+func (c *FakeCloudProvider) newSnapshot(volumeID string, size int64, tags map[string]string) *Snapshot {
+	now := time.Now()
+	snapshot := &fakeSnapshot{
+		snapshot: Snapshot{
+			SnapshotID:     c.newID("snap"),
+			SourceVolumeID: volumeID,
+			Size:           size,
+			CreationTime:   now,
+		},
+		tags:      map[string]string{},
+		completes: now.Add(c.options.TransitionDelay),
+	}
+	for key, value := range tags {
+		snapshot.tags[key] = value
+	}
+	c.snapshots[snapshot.snapshot.SnapshotID] = snapshot
+	return snapshot.toSnapshot()
+}
+
+// toSnapshot returns a copy of the snapshot, ready to use once completed.
+func (s *fakeSnapshot) toSnapshot() *Snapshot {
+	snapshot := s.snapshot
+	snapshot.ReadyToUse = !time.Now().Before(s.completes)
+	return &snapshot
+}
+
+func (c *FakeCloudProvider) DeleteSnapshot(ctx context.Context, snapshotID string) (bool, error) {
+	if err := c.call(ctx, "DeleteSnapshot"); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.snapshots[snapshotID]; !ok {
+		return false, ErrNotFound
+	}
+	delete(c.snapshots, snapshotID)
+	delete(c.fsrs, snapshotID)
+	return true, nil
+}
+
+func (c *FakeCloudProvider) GetSnapshotByName(ctx context.Context, name string) (*Snapshot, error) {
+	if err := c.call(ctx, "GetSnapshotByName"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var found *fakeSnapshot
+	for _, snapshot := range c.snapshots {
+		if snapshot.tags[SnapshotNameTagKey] != name {
+			continue
+		}
+		if found != nil {
+			return nil, ErrMultiSnapshots
+		}
+		found = snapshot
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found.toSnapshot(), nil
+}
+
+func (c *FakeCloudProvider) GetSnapshotByID(ctx context.Context, snapshotID string) (*Snapshot, error) {
+	if err := c.call(ctx, "GetSnapshotByID"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot, ok := c.snapshots[snapshotID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return snapshot.toSnapshot(), nil
+}
+
+// CopySnapshotFromRegion copies a snapshot of the fake cloud provider, which
+// has a single region, and waits for the copy to complete. Like in EC2, a
+// copy made before is reused.
+func (c *FakeCloudProvider) CopySnapshotFromRegion(ctx context.Context, sourceSnapshotID, sourceRegion string, copyOptions *CopySnapshotOptions) (*Snapshot, error) {
+	if err := c.call(ctx, "CopySnapshotFromRegion"); err != nil {
+		return nil, err
+	}
+	copiedFrom := sourceRegion + "/" + sourceSnapshotID
+	c.mu.Lock()
+	var copied *Snapshot
+	for _, snapshot := range c.snapshots {
+		if snapshot.tags[CopiedSnapshotTagKey] == copiedFrom {
+			copied = snapshot.toSnapshot()
+			break
+		}
+	}
+	if copied == nil {
+		source, ok := c.snapshots[sourceSnapshotID]
+		if !ok {
+			c.mu.Unlock()
+			return nil, ErrNotFound
+		}
+		tags := map[string]string{CopiedSnapshotTagKey: copiedFrom}
+		for key, value := range copyOptions.Tags {
+			tags[key] = value
+		}
+		copied = c.newSnapshot(source.snapshot.SourceVolumeID, source.snapshot.Size, tags)
+	}
+	c.mu.Unlock()
+
+	for !copied.ReadyToUse {
+		if err := c.sleep(ctx, fakeAttachmentPollInterval); err != nil {
+			return nil, fmt.Errorf("could not wait for copy %s of snapshot %s: %w", copied.SnapshotID, copiedFrom, err)
+		}
+		var err error
+		if copied, err = c.GetSnapshotByID(ctx, copied.SnapshotID); err != nil {
+			return nil, fmt.Errorf("could not wait for copy of snapshot %s: %w", copiedFrom, err)
+		}
+	}
+	return copied, nil
+}
+
+func (c *FakeCloudProvider) ListSnapshots(ctx context.Context, volumeID string, maxResults int64, nextToken string) (*ListSnapshotsResponse, error) {
+	if err := c.call(ctx, "ListSnapshots"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var snapshots []*Snapshot
+	for _, snapshot := range c.snapshots {
+		if volumeID == "" || snapshot.snapshot.SourceVolumeID == volumeID {
+			snapshots = append(snapshots, snapshot.toSnapshot())
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].SnapshotID < snapshots[j].SnapshotID
+	})
+	page, nextToken, err := paginate(len(snapshots), maxResults, nextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ListSnapshotsResponse{Snapshots: snapshots[page.start:page.end], NextToken: nextToken}, nil
+}
+
+// CreateGroupSnapshot snapshots volumeIDs at the same time. Like in EC2, the
+// volumes must all be attached to the same instance.
+func (c *FakeCloudProvider) CreateGroupSnapshot(ctx context.Context, volumeIDs []string, snapshotOptions *SnapshotOptions) ([]*Snapshot, error) {
+	if err := c.call(ctx, "CreateGroupSnapshot"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	attachedVolumes := map[string]int{}
+	for _, volumeID := range volumeIDs {
+		volume, ok := c.volumes[volumeID]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		for instanceID, attachment := range volume.attachments {
+			if aws.StringValue(attachment.State) == volumeAttachedState {
+				attachedVolumes[instanceID]++
+			}
+		}
+	}
+	sameInstance := false
+	for _, count := range attachedVolumes {
+		sameInstance = sameInstance || count == len(volumeIDs)
+	}
+	if !sameInstance {
+		return nil, fmt.Errorf("%w: %v", ErrNotAttachedToSameInstance, volumeIDs)
+	}
+
+	var snapshots []*Snapshot
+	for _, volumeID := range volumeIDs {
+		size := util.GiBToBytes(c.volumes[volumeID].disk.CapacityGiB)
+		snapshots = append(snapshots, c.newSnapshot(volumeID, size, snapshotOptions.Tags))
+	}
+	return snapshots, nil
+}
+
+func (c *FakeCloudProvider) ListGroupSnapshots(ctx context.Context, groupSnapshotID string) ([]*Snapshot, error) {
+	if err := c.call(ctx, "ListGroupSnapshots"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var snapshots []*Snapshot
+	for _, snapshot := range c.snapshots {
+		if snapshot.tags[GroupSnapshotIDTagKey] == groupSnapshotID {
+			snapshots = append(snapshots, snapshot.toSnapshot())
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].SnapshotID < snapshots[j].SnapshotID
+	})
+	return snapshots, nil
+}
+
+func (c *FakeCloudProvider) EnableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) (*ec2.EnableFastSnapshotRestoresOutput, error) {
+	if err := c.call(ctx, "EnableFastSnapshotRestores"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.snapshots[snapshotID]; !ok {
+		return nil, ErrNotFound
+	}
+	for _, zone := range availabilityZones {
+		if !c.hasZone(zone) {
+			return nil, fmt.Errorf("failed to create fast snapshot restores for snapshot %s: invalid availability zone %q", snapshotID, zone)
+		}
+	}
+	output := &ec2.EnableFastSnapshotRestoresOutput{}
+	for _, zone := range availabilityZones {
+		if c.fsrs[snapshotID] == nil {
+			c.fsrs[snapshotID] = map[string]time.Time{}
+		}
+		if _, ok := c.fsrs[snapshotID][zone]; !ok {
+			c.fsrs[snapshotID][zone] = time.Now()
+		}
+		output.Successful = append(output.Successful, &ec2.EnableFastSnapshotRestoreSuccessItem{
+			AvailabilityZone: aws.String(zone),
+			SnapshotId:       aws.String(snapshotID),
+			State:            aws.String(c.fsrState(snapshotID, zone)),
+		})
+	}
+	return output, nil
+}
+
+// fsrState returns the state of the fast snapshot restores of snapshotID in
+// zone, which are optimizing for the transition delay once enabled.
+func (c *FakeCloudProvider) fsrState(snapshotID, zone string) string {
+	enabled, ok := c.fsrs[snapshotID][zone]
+	if !ok {
+		return ""
+	}
+	if time.Since(enabled) < c.options.TransitionDelay {
+		return ec2.FastSnapshotRestoreStateCodeOptimizing
+	}
+	return ec2.FastSnapshotRestoreStateCodeEnabled
+}
+
+func (c *FakeCloudProvider) DisableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) error {
+	if err := c.call(ctx, "DisableFastSnapshotRestores"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, zone := range availabilityZones {
+		delete(c.fsrs[snapshotID], zone)
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) GetFastSnapshotRestoreZones(ctx context.Context, snapshotID string) ([]string, error) {
+	if err := c.call(ctx, "GetFastSnapshotRestoreZones"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zones []string
+	for zone := range c.fsrs[snapshotID] {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+func (c *FakeCloudProvider) GetFastSnapshotRestoreState(ctx context.Context, snapshotID, availabilityZone string) (string, error) {
+	if err := c.call(ctx, "GetFastSnapshotRestoreState"); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fsrState(snapshotID, availabilityZone), nil
+}
+
+func (c *FakeCloudProvider) GetSnapshotIDsByTag(ctx context.Context, tagKey, tagValue string) ([]string, error) {
+	if err := c.call(ctx, "GetSnapshotIDsByTag"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var snapshotIDs []string
+	for snapshotID, snapshot := range c.snapshots {
+		if value, ok := snapshot.tags[tagKey]; ok && value == tagValue {
+			snapshotIDs = append(snapshotIDs, snapshotID)
+		}
+	}
+	sort.Strings(snapshotIDs)
+	return snapshotIDs, nil
+}
+
+func (c *FakeCloudProvider) GetSnapshotTagValues(ctx context.Context, tagKey string) (map[string]string, error) {
+	if err := c.call(ctx, "GetSnapshotTagValues"); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tagValues := map[string]string{}
+	for snapshotID, snapshot := range c.snapshots {
+		if value, ok := snapshot.tags[tagKey]; ok {
+			tagValues[snapshotID] = value
+		}
+	}
+	return tagValues, nil
+}
+
+func (c *FakeCloudProvider) TagSnapshot(ctx context.Context, snapshotID string, tags map[string]string) error {
+	if err := c.call(ctx, "TagSnapshot"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot, ok := c.snapshots[snapshotID]
+	if !ok {
+		return fmt.Errorf("could not tag snapshot %s: %w", snapshotID, ErrNotFound)
+	}
+	for key, value := range tags {
+		snapshot.tags[key] = value
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) UntagSnapshot(ctx context.Context, snapshotID string, tagKeys []string) error {
+	if err := c.call(ctx, "UntagSnapshot"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot, ok := c.snapshots[snapshotID]
+	if !ok {
+		return fmt.Errorf("could not untag snapshot %s: %w", snapshotID, ErrNotFound)
+	}
+	for _, key := range tagKeys {
+		delete(snapshot.tags, key)
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) CheckConnectivity(ctx context.Context) error {
+	return c.call(ctx, "CheckConnectivity")
+}
+
+func (c *FakeCloudProvider) AvailabilityZones(ctx context.Context) (map[string]struct{}, error) {
+	if err := c.call(ctx, "AvailabilityZones"); err != nil {
+		return nil, err
+	}
+	zones := make(map[string]struct{}, len(c.options.AvailabilityZones))
+	for _, zone := range c.options.AvailabilityZones {
+		zones[zone] = struct{}{}
+	}
+	return zones, nil
+}
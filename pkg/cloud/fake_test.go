@@ -0,0 +1,348 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeDiskOptions(sizeGiB int64) *DiskOptions {
+	return &DiskOptions{
+		CapacityBytes: util.GiBToBytes(sizeGiB),
+		Tags: map[string]string{
+			VolumeNameTagKey:   "pvc-1",
+			AwsEbsDriverTagKey: "true",
+		},
+	}
+}
+
+func TestFakeCloudProviderCreateDisk(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{})
+
+	disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), disk.CapacityGiB)
+	assert.Equal(t, "us-east-1a", disk.AvailabilityZone)
+	assert.Equal(t, ec2.VolumeStateAvailable, disk.State)
+
+	// Creation is idempotent by name, as with the client token of CreateVolume
+	again, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+	assert.Equal(t, disk.VolumeID, again.VolumeID)
+	_, err = c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(20))
+	assert.ErrorIs(t, err, ErrIdempotentParameterMismatch)
+
+	byName, err := c.GetDiskByName(ctx, "pvc-1", util.GiBToBytes(10))
+	require.NoError(t, err)
+	assert.Equal(t, disk.VolumeID, byName.VolumeID)
+	_, err = c.GetDiskByName(ctx, "pvc-1", util.GiBToBytes(20))
+	assert.ErrorIs(t, err, ErrDiskExistsDiffSize)
+
+	options := newFakeDiskOptions(10)
+	options.AvailabilityZone = "us-west-2a"
+	_, err = c.CreateDisk(ctx, "pvc-2", options)
+	assert.ErrorIs(t, err, ErrInvalidVolumeParameters)
+
+	options = newFakeDiskOptions(10)
+	options.SnapshotID = "snap-missing"
+	_, err = c.CreateDisk(ctx, "pvc-3", options)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	deleted, err := c.DeleteDisk(ctx, disk.VolumeID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = c.GetDiskByID(ctx, disk.VolumeID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = c.DeleteDisk(ctx, disk.VolumeID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFakeCloudProviderCreateDiskTransition(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{TransitionDelay: 100 * time.Millisecond})
+
+	created := make(chan *Disk)
+	go func() {
+		disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+		assert.NoError(t, err)
+		created <- disk
+	}()
+
+	assert.Eventually(t, func() bool {
+		disks, err := c.ListDisks(ctx, 0, "")
+		return err == nil && len(disks.Disks) == 1 && disks.Disks[0].State == ec2.VolumeStateCreating
+	}, time.Second, time.Millisecond)
+	disk := <-created
+	assert.Equal(t, ec2.VolumeStateAvailable, disk.State)
+}
+
+func TestFakeCloudProviderAttachDetachDisk(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{
+		InstanceIDs:     []string{"i-1", "i-2"},
+		TransitionDelay: 10 * time.Millisecond,
+	})
+	disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+	other, err := c.CreateDisk(ctx, "pvc-2", newFakeDiskOptions(10))
+	require.NoError(t, err)
+
+	device, err := c.AttachDisk(ctx, disk.VolumeID, "i-1")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/xvdba", device)
+	attached, err := c.GetDiskByID(ctx, disk.VolumeID)
+	require.NoError(t, err)
+	assert.Equal(t, ec2.VolumeStateInUse, attached.State)
+	assert.Equal(t, []string{"i-1"}, attached.Attachments)
+
+	// Attaching again returns the same device
+	device, err = c.AttachDisk(ctx, disk.VolumeID, "i-1")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/xvdba", device)
+
+	otherDevice, err := c.AttachDisk(ctx, other.VolumeID, "i-1")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/xvdbb", otherDevice)
+
+	_, err = c.AttachDisk(ctx, disk.VolumeID, "i-2")
+	assert.ErrorIs(t, err, ErrVolumeInUse)
+	_, err = c.AttachDisk(ctx, disk.VolumeID, "i-missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = c.DeleteDisk(ctx, disk.VolumeID)
+	assert.ErrorIs(t, err, ErrVolumeInUse)
+
+	require.NoError(t, c.DetachDisk(ctx, disk.VolumeID, "i-1"))
+	detached, err := c.GetDiskByID(ctx, disk.VolumeID)
+	require.NoError(t, err)
+	assert.Equal(t, ec2.VolumeStateAvailable, detached.State)
+	assert.Empty(t, detached.Attachments)
+	assert.ErrorIs(t, c.DetachDisk(ctx, disk.VolumeID, "i-1"), ErrNotFound)
+
+	// The device name of the detached volume is reused
+	device, err = c.AttachDisk(ctx, disk.VolumeID, "i-2")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/xvdba", device)
+}
+
+func TestFakeCloudProviderMultiAttach(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{InstanceIDs: []string{"i-1", "i-2"}})
+	options := newFakeDiskOptions(10)
+	options.VolumeType = VolumeTypeIO2
+	options.IOPS = 1000
+	options.MultiAttachEnabled = true
+	disk, err := c.CreateDisk(ctx, "pvc-1", options)
+	require.NoError(t, err)
+
+	_, err = c.AttachDisk(ctx, disk.VolumeID, "i-1")
+	require.NoError(t, err)
+	_, err = c.AttachDisk(ctx, disk.VolumeID, "i-2")
+	require.NoError(t, err)
+	attached, err := c.GetDiskByID(ctx, disk.VolumeID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-1", "i-2"}, attached.Attachments)
+}
+
+func TestFakeCloudProviderResizeOrModifyDisk(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{})
+	disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+
+	size, err := c.ResizeOrModifyDisk(ctx, disk.VolumeID, util.GiBToBytes(20), &ModifyDiskOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), size)
+
+	// Volumes are not shrunk
+	size, err = c.ResizeOrModifyDisk(ctx, disk.VolumeID, util.GiBToBytes(15), &ModifyDiskOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), size)
+
+	_, err = c.ResizeOrModifyDisk(ctx, disk.VolumeID, util.GiBToBytes(100000), &ModifyDiskOptions{})
+	assert.Error(t, err)
+	_, err = c.ResizeOrModifyDisk(ctx, "vol-missing", util.GiBToBytes(20), &ModifyDiskOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFakeCloudProviderSnapshots(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{TransitionDelay: 50 * time.Millisecond})
+	disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+
+	snapshot, err := c.CreateSnapshot(ctx, disk.VolumeID, &SnapshotOptions{Tags: map[string]string{SnapshotNameTagKey: "snapshot-1"}})
+	require.NoError(t, err)
+	assert.False(t, snapshot.ReadyToUse)
+	assert.Equal(t, util.GiBToBytes(10), snapshot.Size)
+	assert.Eventually(t, func() bool {
+		snapshot, err := c.GetSnapshotByName(ctx, "snapshot-1")
+		return err == nil && snapshot.ReadyToUse
+	}, time.Second, time.Millisecond)
+
+	copied, err := c.CopySnapshotFromRegion(ctx, snapshot.SnapshotID, "us-west-2", &CopySnapshotOptions{})
+	require.NoError(t, err)
+	assert.True(t, copied.ReadyToUse)
+	again, err := c.CopySnapshotFromRegion(ctx, snapshot.SnapshotID, "us-west-2", &CopySnapshotOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, copied.SnapshotID, again.SnapshotID)
+
+	options := newFakeDiskOptions(5)
+	options.SnapshotID = snapshot.SnapshotID
+	_, err = c.CreateDisk(ctx, "pvc-2", options)
+	assert.ErrorIs(t, err, ErrInvalidVolumeParameters)
+
+	snapshots, err := c.ListSnapshots(ctx, disk.VolumeID, 0, "")
+	require.NoError(t, err)
+	assert.Len(t, snapshots.Snapshots, 2)
+	_, err = c.ListSnapshots(ctx, "vol-missing", 0, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	deleted, err := c.DeleteSnapshot(ctx, snapshot.SnapshotID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = c.GetSnapshotByID(ctx, snapshot.SnapshotID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFakeCloudProviderGroupSnapshots(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{InstanceIDs: []string{"i-1", "i-2"}})
+	var volumeIDs []string
+	for _, name := range []string{"pvc-1", "pvc-2", "pvc-3"} {
+		disk, err := c.CreateDisk(ctx, name, newFakeDiskOptions(10))
+		require.NoError(t, err)
+		volumeIDs = append(volumeIDs, disk.VolumeID)
+	}
+	for _, volumeID := range volumeIDs[:2] {
+		_, err := c.AttachDisk(ctx, volumeID, "i-1")
+		require.NoError(t, err)
+	}
+	_, err := c.AttachDisk(ctx, volumeIDs[2], "i-2")
+	require.NoError(t, err)
+
+	options := &SnapshotOptions{Tags: map[string]string{GroupSnapshotIDTagKey: "groupsnapshot-1"}}
+	snapshots, err := c.CreateGroupSnapshot(ctx, volumeIDs[:2], options)
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	_, err = c.CreateGroupSnapshot(ctx, volumeIDs, options)
+	assert.ErrorIs(t, err, ErrNotAttachedToSameInstance)
+	_, err = c.CreateGroupSnapshot(ctx, []string{volumeIDs[0], "vol-missing"}, options)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	listed, err := c.ListGroupSnapshots(ctx, "groupsnapshot-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{snapshots[0].SnapshotID, snapshots[1].SnapshotID}, []string{listed[0].SnapshotID, listed[1].SnapshotID})
+	listed, err = c.ListGroupSnapshots(ctx, "groupsnapshot-2")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
+
+func TestFakeCloudProviderFastSnapshotRestores(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{TransitionDelay: 50 * time.Millisecond})
+	disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+	snapshot, err := c.CreateSnapshot(ctx, disk.VolumeID, &SnapshotOptions{})
+	require.NoError(t, err)
+
+	_, err = c.EnableFastSnapshotRestores(ctx, []string{"us-east-1a"}, snapshot.SnapshotID)
+	require.NoError(t, err)
+	state, err := c.GetFastSnapshotRestoreState(ctx, snapshot.SnapshotID, "us-east-1a")
+	require.NoError(t, err)
+	assert.Equal(t, ec2.FastSnapshotRestoreStateCodeOptimizing, state)
+	assert.Eventually(t, func() bool {
+		state, err := c.GetFastSnapshotRestoreState(ctx, snapshot.SnapshotID, "us-east-1a")
+		return err == nil && state == ec2.FastSnapshotRestoreStateCodeEnabled
+	}, time.Second, time.Millisecond)
+
+	zones, err := c.GetFastSnapshotRestoreZones(ctx, snapshot.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"us-east-1a"}, zones)
+
+	require.NoError(t, c.DisableFastSnapshotRestores(ctx, []string{"us-east-1a"}, snapshot.SnapshotID))
+	state, err = c.GetFastSnapshotRestoreState(ctx, snapshot.SnapshotID, "us-east-1a")
+	require.NoError(t, err)
+	assert.Empty(t, state)
+}
+
+func TestFakeCloudProviderListDisks(t *testing.T) {
+	ctx := context.Background()
+	c := NewFakeCloudProvider(FakeCloudOptions{})
+	for _, name := range []string{"pvc-1", "pvc-2", "pvc-3", "pvc-4", "pvc-5", "pvc-6"} {
+		_, err := c.CreateDisk(ctx, name, newFakeDiskOptions(10))
+		require.NoError(t, err)
+	}
+
+	page, err := c.ListDisks(ctx, 5, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Disks, 5)
+	assert.Equal(t, "5", page.NextToken)
+	page, err = c.ListDisks(ctx, 5, page.NextToken)
+	require.NoError(t, err)
+	assert.Len(t, page.Disks, 1)
+	assert.Empty(t, page.NextToken)
+
+	page, err = c.ListDisks(ctx, 1, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Disks, 1)
+	assert.Equal(t, "1", page.NextToken)
+
+	_, err = c.ListDisks(ctx, 5, "invalid")
+	assert.ErrorIs(t, err, ErrInvalidNextToken)
+}
+
+func TestFakeCloudProviderErrors(t *testing.T) {
+	ctx := context.Background()
+	errThrottled := errors.New("throttled")
+	c := NewFakeCloudProvider(FakeCloudOptions{Errors: map[string]error{"CreateDisk": errThrottled}})
+
+	_, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	assert.ErrorIs(t, err, errThrottled)
+
+	c.SetError("CreateDisk", nil)
+	disk, err := c.CreateDisk(ctx, "pvc-1", newFakeDiskOptions(10))
+	require.NoError(t, err)
+
+	c.SetError("AttachDisk", errThrottled)
+	_, err = c.AttachDisk(ctx, disk.VolumeID, FakeInstanceID)
+	assert.ErrorIs(t, err, errThrottled)
+}
+
+func TestFakeCloudProviderLatency(t *testing.T) {
+	c := NewFakeCloudProvider(FakeCloudOptions{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetDiskByID(ctx, "vol-1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFakeCloudOptionsMetadata(t *testing.T) {
+	metadata := FakeCloudOptions{Region: "eu-west-1"}.Metadata()
+	assert.Equal(t, FakeInstanceID, metadata.GetInstanceID())
+	assert.Equal(t, "eu-west-1", metadata.GetRegion())
+	assert.Equal(t, "eu-west-1a", metadata.GetAvailabilityZone())
+}
@@ -17,24 +17,34 @@ limitations under the License.
 package cloud
 
 import (
+	"errors"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"k8s.io/klog/v2"
 )
 
-// RecordRequestsHandler is added to the Complete chain; called after any request
-func RecordRequestsHandler(r *request.Request) {
-	labels := map[string]string{
-		"request": operationName(r),
-	}
-
-	if r.Error != nil {
-		metrics.Recorder().IncreaseCount("cloudprovider_aws_api_request_errors", labels)
-	} else {
-		duration := time.Since(r.Time).Seconds()
-		metrics.Recorder().ObserveHistogram("cloudprovider_aws_api_request_duration_seconds", duration, labels, nil)
+// recordRequestsHandler returns the handler added to the Complete chain;
+// called after any request. The metrics are labeled with the ID of the account
+// and the region of the request, so that the requests of fleets spanning
+// several accounts and regions can be told apart.
+func recordRequestsHandler(account *awsAccount) func(r *request.Request) {
+	return func(r *request.Request) {
+		labels := map[string]string{
+			"request":    operationName(r),
+			"account_id": account.ID(),
+			"region":     aws.StringValue(r.Config.Region),
+		}
+		if r.Error != nil {
+			labels["code"] = requestCode(r)
+			metrics.Recorder().IncreaseCount("cloudprovider_aws_api_request_errors", labels)
+		} else {
+			duration := time.Since(r.Time).Seconds()
+			metrics.Recorder().ObserveHistogram("cloudprovider_aws_api_request_duration_seconds", duration, labels, nil)
+		}
 	}
 }
 
@@ -46,10 +56,32 @@ func RecordThrottledRequestsHandler(r *request.Request) {
 
 	if r.IsErrorThrottle() {
 		metrics.Recorder().IncreaseCount("cloudprovider_aws_api_throttled_requests_total", labels)
-		klog.InfoS("Got RequestLimitExceeded error on AWS request", "request", describeRequest(r))
+		klog.FromContext(r.Context()).Info("Got RequestLimitExceeded error on AWS request", "request", describeRequest(r))
 	}
 }
 
+// RecordRetriesHandler is added to the Complete chain; called after any request.
+// It records how many times the request was retried before it completed,
+// whether it eventually succeeded or not. The request is logged with the
+// logger of its context, which identifies the CSI RPC that sent it, if any.
+func RecordRetriesHandler(r *request.Request) {
+	labels := map[string]string{
+		"request": operationName(r),
+	}
+	metrics.Recorder().ObserveHistogram("cloudprovider_aws_api_request_retries", float64(r.RetryCount), labels, retryCountBuckets)
+
+	logger := klog.FromContext(r.Context())
+	if r.RetryCount > 0 {
+		logger.Info("AWS request completed after retries", "request", describeRequest(r), "duration", time.Since(r.Time), "retries", r.RetryCount, "err", r.Error)
+	} else {
+		logger.V(4).Info("AWS request completed without retries", "request", describeRequest(r), "duration", time.Since(r.Time), "err", r.Error)
+	}
+}
+
+// retryCountBuckets covers every retry count up to the maximum number of
+// retries of the EC2 client
+var retryCountBuckets = []float64{0, 1, 2, 3, 4, 5, 6, 7, 8}
+
 // Return the operation name, for use in log messages and metrics
 func operationName(r *request.Request) string {
 	name := "N/A"
@@ -59,6 +91,15 @@ func operationName(r *request.Request) string {
 	return name
 }
 
+// Return the AWS error code of a failed request, for use in metrics
+func requestCode(r *request.Request) string {
+	var awsErr awserr.Error
+	if errors.As(r.Error, &awsErr) {
+		return awsErr.Code()
+	}
+	return "Unknown"
+}
+
 // Return a user-friendly string describing the request, for use in log messages
 func describeRequest(r *request.Request) string {
 	service := r.ClientInfo.ServiceName
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/go-logr/logr/funcr"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+func TestRecordRetries(t *testing.T) {
+	metricsAddress := reserveMetricsAddress(t)
+	metrics.InitializeRecorder().InitializeMetricsHandler(metricsAddress, "/metrics")
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<DescribeSnapshotsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><snapshotSet/></DescribeSnapshotsResponse>`)
+	}))
+	defer server.Close()
+
+	svc := ec2.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(2),
+	})))
+	svc.Handlers.Complete.PushFrontNamed(request.NamedHandler{
+		Name: "recordRetriesHandler",
+		Fn:   RecordRetriesHandler,
+	})
+
+	if _, err := svc.DescribeSnapshots(&ec2.DescribeSnapshotsInput{}); err != nil {
+		t.Fatalf("DescribeSnapshots failed: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected DescribeSnapshots to be sent twice, got %d", calls)
+	}
+
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_retries_bucket{request="DescribeSnapshots",le="0"} 0`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_retries_bucket{request="DescribeSnapshots",le="1"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_retries_sum{request="DescribeSnapshots"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_retries_count{request="DescribeSnapshots"} 1`)
+}
+
+func TestRecordRetriesLogsWithContextLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<DescribeSnapshotsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><snapshotSet/></DescribeSnapshotsResponse>`)
+	}))
+	defer server.Close()
+
+	svc := ec2.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+	svc.Handlers.Complete.PushFrontNamed(request.NamedHandler{
+		Name: "recordRetriesHandler",
+		Fn:   RecordRetriesHandler,
+	})
+
+	var lines []string
+	logger := funcr.NewJSON(func(obj string) { lines = append(lines, obj) }, funcr.Options{Verbosity: 4})
+	ctx := klog.NewContext(context.Background(), logger.WithValues("requestID", "req-test"))
+	if _, err := svc.DescribeSnapshotsWithContext(ctx, &ec2.DescribeSnapshotsInput{}); err != nil {
+		t.Fatalf("DescribeSnapshots failed: %v", err)
+	}
+
+	if len(lines) != 1 {
+		t.Fatalf("expected a single log line, got: %v", lines)
+	}
+	for _, expected := range []string{`"requestID":"req-test"`, `"request":"ec2::DescribeSnapshots"`, `"duration":`} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("expected log line to contain %s, got: %s", expected, lines[0])
+		}
+	}
+}
+
+// reserveMetricsAddress returns a free local address to serve metrics on.
+func reserveMetricsAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve metrics address: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func expectMetric(t *testing.T, address, expected string) {
+	t.Helper()
+	var body string
+	err := wait.PollUntilContextTimeout(context.Background(), 50*time.Millisecond, 5*time.Second, true, func(_ context.Context) (bool, error) {
+		resp, err := http.Get("http://" + address + "/metrics")
+		if err != nil {
+			return false, nil
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, nil
+		}
+		body = string(b)
+		return strings.Contains(body, expected), nil
+	})
+	if err != nil {
+		t.Fatalf("expected metrics to contain %q, got:\n%s", expected, body)
+	}
+}
+
+func TestRecordRequests(t *testing.T) {
+	metricsAddress := reserveMetricsAddress(t)
+	metrics.InitializeRecorder().InitializeMetricsHandler(metricsAddress, "/metrics")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse request: %v", err)
+		}
+		if r.Form.Get("Action") == "DeleteVolume" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidVolume.NotFound</Code><Message>not found</Message></Error></Errors><RequestID>1</RequestID></Response>`)
+			return
+		}
+		fmt.Fprint(w, `<DescribeVolumesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><volumeSet/></DescribeVolumesResponse>`)
+	}))
+	defer server.Close()
+
+	svc := ec2.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+	svc.Handlers.Complete.PushFrontNamed(request.NamedHandler{
+		Name: "recordRequestsHandler",
+		Fn:   recordRequestsHandler(&awsAccount{id: "111111111111"}),
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{}); err != nil {
+			t.Fatalf("DescribeVolumes failed: %v", err)
+		}
+	}
+	if _, err := svc.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: aws.String("vol-test")}); err == nil {
+		t.Fatalf("expected DeleteVolume to fail")
+	}
+
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_errors{account_id="111111111111",code="InvalidVolume.NotFound",region="us-west-2",request="DeleteVolume"} 1`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_duration_seconds_count{account_id="111111111111",region="us-west-2",request="DescribeVolumes"} 2`)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cloud
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws/arn"
@@ -48,6 +49,21 @@ const (
 
 var _ MetadataService = &Metadata{}
 
+const (
+	// MetadataSourceIMDS retrieves instance data from the EC2 instance metadata service.
+	MetadataSourceIMDS = "imds"
+	// MetadataSourceKubernetes retrieves instance data from the provider ID and
+	// labels of the instance's Node object in the Kubernetes API.
+	MetadataSourceKubernetes = "kubernetes"
+)
+
+// DefaultMetadataSources are the sources of instance data in order of preference.
+var DefaultMetadataSources = []string{MetadataSourceIMDS, MetadataSourceKubernetes}
+
+// ErrMetadataUnavailable is returned when the EC2 instance metadata service or
+// the Kubernetes API could not be reached to retrieve instance data.
+var ErrMetadataUnavailable = errors.New("error getting instance data from ec2 metadata or kubernetes api")
+
 // GetInstanceID returns the instance identification.
 func (m *Metadata) GetInstanceID() string {
 	return m.InstanceID
@@ -81,26 +97,55 @@ func (m *Metadata) GetOutpostArn() arn.ARN {
 	return m.OutpostArn
 }
 
-func NewMetadataService(ec2MetadataClient EC2MetadataClient, k8sAPIClient KubernetesAPIClient, region string) (MetadataService, error) {
+func NewMetadataService(ec2MetadataClient EC2MetadataClient, k8sAPIClient KubernetesAPIClient, region string, metadataSources []string) (MetadataService, error) {
+	if len(metadataSources) == 0 {
+		metadataSources = DefaultMetadataSources
+	}
+
+	// Each source is tried in order until one of them returns the instance
+	// data, so that e.g. nodes without access to IMDS fall back to their Node
+	var errs []error
+	for _, source := range metadataSources {
+		var (
+			metadata *Metadata
+			err      error
+		)
+		switch source {
+		case MetadataSourceIMDS:
+			metadata, err = ec2MetadataInstanceInfo(ec2MetadataClient, region)
+		case MetadataSourceKubernetes:
+			metadata, err = kubernetesAPIInstanceInfo(k8sAPIClient)
+		default:
+			err = fmt.Errorf("unknown metadata source %q", source)
+		}
+		if err == nil {
+			return metadata, nil
+		}
+		klog.InfoS("could not retrieve instance data", "source", source, "err", err)
+		errs = append(errs, fmt.Errorf("%s: %w", source, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func ec2MetadataInstanceInfo(ec2MetadataClient EC2MetadataClient, region string) (*Metadata, error) {
 	klog.InfoS("retrieving instance data from ec2 metadata")
 	svc, err := ec2MetadataClient()
+	if err != nil {
+		return nil, fmt.Errorf("%w: error creating ec2 metadata client: %w", ErrMetadataUnavailable, err)
+	}
 	if !svc.Available() {
-		klog.InfoS("ec2 metadata is not available")
-	} else if err != nil {
-		klog.InfoS("error creating ec2 metadata client", "err", err)
-	} else {
-		klog.InfoS("ec2 metadata is available")
-		return EC2MetadataInstanceInfo(svc, region)
+		return nil, fmt.Errorf("%w: ec2 metadata is not available", ErrMetadataUnavailable)
 	}
+	klog.InfoS("ec2 metadata is available")
+	return EC2MetadataInstanceInfo(svc, region)
+}
 
+func kubernetesAPIInstanceInfo(k8sAPIClient KubernetesAPIClient) (*Metadata, error) {
 	klog.InfoS("retrieving instance data from kubernetes api")
 	clientset, err := k8sAPIClient()
 	if err != nil {
-		klog.InfoS("error creating kubernetes api client", "err", err)
-	} else {
-		klog.InfoS("kubernetes api is available")
-		return KubernetesAPIInstanceInfo(clientset)
+		return nil, fmt.Errorf("%w: error creating kubernetes api client: %w", ErrMetadataUnavailable, err)
 	}
-
-	return nil, fmt.Errorf("error getting instance data from ec2 metadata or kubernetes api")
+	klog.InfoS("kubernetes api is available")
+	return KubernetesAPIInstanceInfo(clientset)
 }
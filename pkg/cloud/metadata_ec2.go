@@ -14,12 +14,36 @@ import (
 
 type EC2MetadataClient func() (EC2Metadata, error)
 
-var DefaultEC2MetadataClient = func() (EC2Metadata, error) {
-	sess := session.Must(session.NewSession(&aws.Config{}))
-	svc := ec2metadata.New(sess)
-	return svc, nil
+const (
+	// IMDSVersionAuto uses IMDSv2 session tokens, falling back to IMDSv1 if no
+	// session token can be retrieved.
+	IMDSVersionAuto = "auto"
+	// IMDSVersionV2 only uses IMDSv2, as required on instances configured with
+	// HttpTokens=required. Requests fail if no session token can be retrieved.
+	IMDSVersionV2 = "v2"
+)
+
+// IMDSVersions are the supported IMDS versions.
+var IMDSVersions = []string{IMDSVersionAuto, IMDSVersionV2}
+
+// NewEC2MetadataClient returns an EC2MetadataClient using imdsVersion. The SDK
+// caches the IMDSv2 session token, refreshes it before it expires and retries
+// requests rejected with 401 Unauthorized with a new token. The IPv6 endpoint
+// of the service is used if endpointOptions enable dual-stack endpoints.
+func NewEC2MetadataClient(imdsVersion string, endpointOptions EndpointOptions) EC2MetadataClient {
+	return func() (EC2Metadata, error) {
+		config := &aws.Config{}
+		if imdsVersion == IMDSVersionV2 {
+			config.EC2MetadataEnableFallback = aws.Bool(false)
+		}
+		sess := session.Must(session.NewSessionWithOptions(endpointOptions.metadataSessionOptions(config)))
+		svc := ec2metadata.New(sess)
+		return svc, nil
+	}
 }
 
+var DefaultEC2MetadataClient = NewEC2MetadataClient(IMDSVersionAuto, EndpointOptions{})
+
 func EC2MetadataInstanceInfo(svc EC2Metadata, regionFromSession string) (*Metadata, error) {
 	doc, err := svc.GetInstanceIdentityDocument()
 	klog.InfoS("Retrieving EC2 instance identity metadata", "regionFromSession", regionFromSession)
@@ -17,7 +17,10 @@ limitations under the License.
 package cloud
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -393,14 +396,15 @@ func TestNewMetadataService(t *testing.T) {
 			var m MetadataService
 			var err error
 			if tc.regionFromSession == snowRegion {
-				m, err = NewMetadataService(ec2MetadataClient, k8sAPIClient, snowRegion)
+				m, err = NewMetadataService(ec2MetadataClient, k8sAPIClient, snowRegion, nil)
 			} else {
-				m, err = NewMetadataService(ec2MetadataClient, k8sAPIClient, stdRegion)
+				m, err = NewMetadataService(ec2MetadataClient, k8sAPIClient, stdRegion, nil)
 			}
 			if err != nil {
 				if tc.expectedErr == nil {
 					t.Errorf("got error %q, expected no error", err)
-				} else if err.Error() != tc.expectedErr.Error() {
+				} else if !strings.Contains(err.Error(), tc.expectedErr.Error()) {
+					// The errors of all sources are returned if none succeeded
 					t.Errorf("got error %q, expected %q", err, tc.expectedErr)
 				}
 			} else {
@@ -433,3 +437,202 @@ func TestNewMetadataService(t *testing.T) {
 		})
 	}
 }
+
+func TestNewMetadataServiceSources(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"node.kubernetes.io/instance-type": stdInstanceType,
+				"topology.kubernetes.io/region":    stdRegion,
+				"topology.kubernetes.io/zone":      stdAvailabilityZone,
+			},
+			Name: nodeName,
+		},
+		Spec: v1.NodeSpec{
+			ProviderID: "aws:///" + stdAvailabilityZone + "/" + stdInstanceID,
+		},
+	}
+	expectIMDS := func(mockEC2Metadata *MockEC2Metadata) {
+		mockEC2Metadata.EXPECT().Available().Return(true)
+		mockEC2Metadata.EXPECT().GetInstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{
+			InstanceID:       stdInstanceID,
+			InstanceType:     stdInstanceType,
+			Region:           stdRegion,
+			AvailabilityZone: stdAvailabilityZone,
+		}, nil)
+		mockEC2Metadata.EXPECT().GetMetadata(enisEndpoint).Return("00:00:00:00:00:00\n00:00:00:00:00:01", nil)
+		mockEC2Metadata.EXPECT().GetMetadata(blockDevicesEndpoint).Return("ami\nroot", nil)
+		mockEC2Metadata.EXPECT().GetMetadata(outpostArnEndpoint).Return("", fmt.Errorf("404"))
+	}
+
+	testCases := []struct {
+		name            string
+		metadataSources []string
+		expectMock      func(mockEC2Metadata *MockEC2Metadata)
+		node            *v1.Node
+		expectedENIs    int
+		expectedErr     error
+	}{
+		{
+			name: "success: falls back to kubernetes when IMDS fails",
+			expectMock: func(mockEC2Metadata *MockEC2Metadata) {
+				mockEC2Metadata.EXPECT().Available().Return(true)
+				mockEC2Metadata.EXPECT().GetInstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{}, fmt.Errorf("foo"))
+			},
+			node:         node,
+			expectedENIs: 1,
+		},
+		{
+			name:            "success: kubernetes preferred over IMDS",
+			metadataSources: []string{MetadataSourceKubernetes, MetadataSourceIMDS},
+			node:            node,
+			expectedENIs:    1,
+		},
+		{
+			name:            "success: falls back to IMDS when kubernetes fails",
+			metadataSources: []string{MetadataSourceKubernetes, MetadataSourceIMDS},
+			expectMock:      expectIMDS,
+			expectedENIs:    2,
+		},
+		{
+			name:            "failure: only IMDS, which is not available",
+			metadataSources: []string{MetadataSourceIMDS},
+			expectMock: func(mockEC2Metadata *MockEC2Metadata) {
+				mockEC2Metadata.EXPECT().Available().Return(false)
+			},
+			node:        node,
+			expectedErr: ErrMetadataUnavailable,
+		},
+		{
+			name:            "failure: unknown source",
+			metadataSources: []string{"unknown"},
+			expectedErr:     fmt.Errorf("unknown: unknown metadata source \"unknown\""),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockEC2Metadata := NewMockEC2Metadata(mockCtrl)
+			if tc.expectMock != nil {
+				tc.expectMock(mockEC2Metadata)
+			}
+
+			clientset := fake.NewSimpleClientset()
+			if tc.node != nil {
+				clientset = fake.NewSimpleClientset(tc.node)
+			}
+			ec2MetadataClient := func() (EC2Metadata, error) { return mockEC2Metadata, nil }
+			k8sAPIClient := func() (kubernetes.Interface, error) { return clientset, nil }
+			t.Setenv("CSI_NODE_NAME", nodeName)
+
+			m, err := NewMetadataService(ec2MetadataClient, k8sAPIClient, stdRegion, tc.metadataSources)
+			if tc.expectedErr != nil {
+				if err == nil {
+					t.Fatalf("expected error %q, got none", tc.expectedErr)
+				}
+				if !errors.Is(err, tc.expectedErr) && err.Error() != tc.expectedErr.Error() {
+					t.Fatalf("got error %q, expected %q", err, tc.expectedErr)
+				}
+				if tc.node != nil && len(clientset.Actions()) > 0 {
+					t.Errorf("kubernetes client was unexpectedly called! %v", clientset.Actions())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q, expected no error", err)
+			}
+			if m.GetInstanceID() != stdInstanceID {
+				t.Errorf("got wrong instance ID %v, expected %v", m.GetInstanceID(), stdInstanceID)
+			}
+			if m.GetAvailabilityZone() != stdAvailabilityZone {
+				t.Errorf("got wrong AZ %v, expected %v", m.GetAvailabilityZone(), stdAvailabilityZone)
+			}
+			if m.GetNumAttachedENIs() != tc.expectedENIs {
+				t.Errorf("got %v attached ENIs, expected %v", m.GetNumAttachedENIs(), tc.expectedENIs)
+			}
+		})
+	}
+}
+
+func TestNewEC2MetadataClient(t *testing.T) {
+	testCases := []struct {
+		name           string
+		imdsVersion    string
+		tokenAvailable bool
+		tokenRequired  bool
+		expectedErr    bool
+	}{
+		{
+			name:           "auto: uses session token",
+			imdsVersion:    IMDSVersionAuto,
+			tokenAvailable: true,
+			tokenRequired:  true,
+		},
+		{
+			name:        "auto: falls back to IMDSv1",
+			imdsVersion: IMDSVersionAuto,
+		},
+		{
+			name:           "v2: uses session token",
+			imdsVersion:    IMDSVersionV2,
+			tokenAvailable: true,
+			tokenRequired:  true,
+		},
+		{
+			name:        "v2: fails without session token",
+			imdsVersion: IMDSVersionV2,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			const token = "test-token"
+			var tokenRequests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/latest/api/token" {
+					tokenRequests++
+					if !tc.tokenAvailable {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					w.Header().Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+					fmt.Fprint(w, token)
+					return
+				}
+				if tc.tokenRequired && r.Header.Get("X-Aws-Ec2-Metadata-Token") != token {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprint(w, stdInstanceID)
+			}))
+			defer server.Close()
+			t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+
+			svc, err := NewEC2MetadataClient(tc.imdsVersion, EndpointOptions{})()
+			if err != nil {
+				t.Fatalf("got error %q, expected no error", err)
+			}
+			for i := 0; i < 2; i++ {
+				instanceID, err := svc.GetMetadata("instance-id")
+				if tc.expectedErr {
+					if err == nil {
+						t.Fatalf("expected error, got instance ID %q", instanceID)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("got error %q, expected no error", err)
+				}
+				if instanceID != stdInstanceID {
+					t.Errorf("got wrong instance ID %v, expected %v", instanceID, stdInstanceID)
+				}
+			}
+			if tc.tokenAvailable && tokenRequests != 1 {
+				t.Errorf("expected the session token to be requested once and cached, got %d requests", tokenRequests)
+			}
+		})
+	}
+}
@@ -65,6 +65,49 @@ func (mr *MockCloudMockRecorder) AvailabilityZones(ctx interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AvailabilityZones", reflect.TypeOf((*MockCloud)(nil).AvailabilityZones), ctx)
 }
 
+// CheckConnectivity mocks base method.
+func (m *MockCloud) CheckConnectivity(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckConnectivity", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckConnectivity indicates an expected call of CheckConnectivity.
+func (mr *MockCloudMockRecorder) CheckConnectivity(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckConnectivity", reflect.TypeOf((*MockCloud)(nil).CheckConnectivity), ctx)
+}
+
+// CheckKMSKeyAccess mocks base method.
+func (m *MockCloud) CheckKMSKeyAccess(ctx context.Context, volumeID, nodeID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckKMSKeyAccess", ctx, volumeID, nodeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckKMSKeyAccess indicates an expected call of CheckKMSKeyAccess.
+func (mr *MockCloudMockRecorder) CheckKMSKeyAccess(ctx, volumeID, nodeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckKMSKeyAccess", reflect.TypeOf((*MockCloud)(nil).CheckKMSKeyAccess), ctx, volumeID, nodeID)
+}
+
+// CopySnapshotFromRegion mocks base method.
+func (m *MockCloud) CopySnapshotFromRegion(ctx context.Context, sourceSnapshotID, sourceRegion string, copyOptions *CopySnapshotOptions) (*Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CopySnapshotFromRegion", ctx, sourceSnapshotID, sourceRegion, copyOptions)
+	ret0, _ := ret[0].(*Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CopySnapshotFromRegion indicates an expected call of CopySnapshotFromRegion.
+func (mr *MockCloudMockRecorder) CopySnapshotFromRegion(ctx, sourceSnapshotID, sourceRegion, copyOptions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopySnapshotFromRegion", reflect.TypeOf((*MockCloud)(nil).CopySnapshotFromRegion), ctx, sourceSnapshotID, sourceRegion, copyOptions)
+}
+
 // CreateDisk mocks base method.
 func (m *MockCloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *DiskOptions) (*Disk, error) {
 	m.ctrl.T.Helper()
@@ -80,6 +123,21 @@ func (mr *MockCloudMockRecorder) CreateDisk(ctx, volumeName, diskOptions interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDisk", reflect.TypeOf((*MockCloud)(nil).CreateDisk), ctx, volumeName, diskOptions)
 }
 
+// CreateGroupSnapshot mocks base method.
+func (m *MockCloud) CreateGroupSnapshot(ctx context.Context, volumeIDs []string, snapshotOptions *SnapshotOptions) ([]*Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroupSnapshot", ctx, volumeIDs, snapshotOptions)
+	ret0, _ := ret[0].([]*Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroupSnapshot indicates an expected call of CreateGroupSnapshot.
+func (mr *MockCloudMockRecorder) CreateGroupSnapshot(ctx, volumeIDs, snapshotOptions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroupSnapshot", reflect.TypeOf((*MockCloud)(nil).CreateGroupSnapshot), ctx, volumeIDs, snapshotOptions)
+}
+
 // CreateSnapshot mocks base method.
 func (m *MockCloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (*Snapshot, error) {
 	m.ctrl.T.Helper()
@@ -139,6 +197,20 @@ func (mr *MockCloudMockRecorder) DetachDisk(ctx, volumeID, nodeID interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachDisk", reflect.TypeOf((*MockCloud)(nil).DetachDisk), ctx, volumeID, nodeID)
 }
 
+// DisableFastSnapshotRestores mocks base method.
+func (m *MockCloud) DisableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableFastSnapshotRestores", ctx, availabilityZones, snapshotID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableFastSnapshotRestores indicates an expected call of DisableFastSnapshotRestores.
+func (mr *MockCloudMockRecorder) DisableFastSnapshotRestores(ctx, availabilityZones, snapshotID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableFastSnapshotRestores", reflect.TypeOf((*MockCloud)(nil).DisableFastSnapshotRestores), ctx, availabilityZones, snapshotID)
+}
+
 // EnableFastSnapshotRestores mocks base method.
 func (m *MockCloud) EnableFastSnapshotRestores(ctx context.Context, availabilityZones []string, snapshotID string) (*ec2.EnableFastSnapshotRestoresOutput, error) {
 	m.ctrl.T.Helper()
@@ -154,6 +226,21 @@ func (mr *MockCloudMockRecorder) EnableFastSnapshotRestores(ctx, availabilityZon
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableFastSnapshotRestores", reflect.TypeOf((*MockCloud)(nil).EnableFastSnapshotRestores), ctx, availabilityZones, snapshotID)
 }
 
+// GetAvailableDisksByTags mocks base method.
+func (m *MockCloud) GetAvailableDisksByTags(ctx context.Context, tags map[string]string) ([]*Disk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailableDisksByTags", ctx, tags)
+	ret0, _ := ret[0].([]*Disk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAvailableDisksByTags indicates an expected call of GetAvailableDisksByTags.
+func (mr *MockCloudMockRecorder) GetAvailableDisksByTags(ctx, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailableDisksByTags", reflect.TypeOf((*MockCloud)(nil).GetAvailableDisksByTags), ctx, tags)
+}
+
 // GetDiskByID mocks base method.
 func (m *MockCloud) GetDiskByID(ctx context.Context, volumeID string) (*Disk, error) {
 	m.ctrl.T.Helper()
@@ -184,6 +271,36 @@ func (mr *MockCloudMockRecorder) GetDiskByName(ctx, name, capacityBytes interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskByName", reflect.TypeOf((*MockCloud)(nil).GetDiskByName), ctx, name, capacityBytes)
 }
 
+// GetFastSnapshotRestoreState mocks base method.
+func (m *MockCloud) GetFastSnapshotRestoreState(ctx context.Context, snapshotID, availabilityZone string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFastSnapshotRestoreState", ctx, snapshotID, availabilityZone)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFastSnapshotRestoreState indicates an expected call of GetFastSnapshotRestoreState.
+func (mr *MockCloudMockRecorder) GetFastSnapshotRestoreState(ctx, snapshotID, availabilityZone interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFastSnapshotRestoreState", reflect.TypeOf((*MockCloud)(nil).GetFastSnapshotRestoreState), ctx, snapshotID, availabilityZone)
+}
+
+// GetFastSnapshotRestoreZones mocks base method.
+func (m *MockCloud) GetFastSnapshotRestoreZones(ctx context.Context, snapshotID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFastSnapshotRestoreZones", ctx, snapshotID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFastSnapshotRestoreZones indicates an expected call of GetFastSnapshotRestoreZones.
+func (mr *MockCloudMockRecorder) GetFastSnapshotRestoreZones(ctx, snapshotID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFastSnapshotRestoreZones", reflect.TypeOf((*MockCloud)(nil).GetFastSnapshotRestoreZones), ctx, snapshotID)
+}
+
 // GetSnapshotByID mocks base method.
 func (m *MockCloud) GetSnapshotByID(ctx context.Context, snapshotID string) (*Snapshot, error) {
 	m.ctrl.T.Helper()
@@ -214,6 +331,51 @@ func (mr *MockCloudMockRecorder) GetSnapshotByName(ctx, name interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshotByName", reflect.TypeOf((*MockCloud)(nil).GetSnapshotByName), ctx, name)
 }
 
+// GetSnapshotIDsByTag mocks base method.
+func (m *MockCloud) GetSnapshotIDsByTag(ctx context.Context, tagKey, tagValue string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshotIDsByTag", ctx, tagKey, tagValue)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshotIDsByTag indicates an expected call of GetSnapshotIDsByTag.
+func (mr *MockCloudMockRecorder) GetSnapshotIDsByTag(ctx, tagKey, tagValue interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshotIDsByTag", reflect.TypeOf((*MockCloud)(nil).GetSnapshotIDsByTag), ctx, tagKey, tagValue)
+}
+
+// GetSnapshotTagValues mocks base method.
+func (m *MockCloud) GetSnapshotTagValues(ctx context.Context, tagKey string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshotTagValues", ctx, tagKey)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshotTagValues indicates an expected call of GetSnapshotTagValues.
+func (mr *MockCloudMockRecorder) GetSnapshotTagValues(ctx, tagKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshotTagValues", reflect.TypeOf((*MockCloud)(nil).GetSnapshotTagValues), ctx, tagKey)
+}
+
+// GetVolumeStatuses mocks base method.
+func (m *MockCloud) GetVolumeStatuses(ctx context.Context, volumeIDs []string) (map[string]*VolumeStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeStatuses", ctx, volumeIDs)
+	ret0, _ := ret[0].(map[string]*VolumeStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumeStatuses indicates an expected call of GetVolumeStatuses.
+func (mr *MockCloudMockRecorder) GetVolumeStatuses(ctx, volumeIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeStatuses", reflect.TypeOf((*MockCloud)(nil).GetVolumeStatuses), ctx, volumeIDs)
+}
+
 // IsExistInstance mocks base method.
 func (m *MockCloud) IsExistInstance(ctx context.Context, nodeID string) bool {
 	m.ctrl.T.Helper()
@@ -228,6 +390,36 @@ func (mr *MockCloudMockRecorder) IsExistInstance(ctx, nodeID interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsExistInstance", reflect.TypeOf((*MockCloud)(nil).IsExistInstance), ctx, nodeID)
 }
 
+// ListDisks mocks base method.
+func (m *MockCloud) ListDisks(ctx context.Context, maxResults int64, nextToken string) (*ListDisksResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDisks", ctx, maxResults, nextToken)
+	ret0, _ := ret[0].(*ListDisksResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDisks indicates an expected call of ListDisks.
+func (mr *MockCloudMockRecorder) ListDisks(ctx, maxResults, nextToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDisks", reflect.TypeOf((*MockCloud)(nil).ListDisks), ctx, maxResults, nextToken)
+}
+
+// ListGroupSnapshots mocks base method.
+func (m *MockCloud) ListGroupSnapshots(ctx context.Context, groupSnapshotID string) ([]*Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupSnapshots", ctx, groupSnapshotID)
+	ret0, _ := ret[0].([]*Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupSnapshots indicates an expected call of ListGroupSnapshots.
+func (mr *MockCloudMockRecorder) ListGroupSnapshots(ctx, groupSnapshotID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupSnapshots", reflect.TypeOf((*MockCloud)(nil).ListGroupSnapshots), ctx, groupSnapshotID)
+}
+
 // ListSnapshots mocks base method.
 func (m *MockCloud) ListSnapshots(ctx context.Context, volumeID string, maxResults int64, nextToken string) (*ListSnapshotsResponse, error) {
 	m.ctrl.T.Helper()
@@ -258,6 +450,34 @@ func (mr *MockCloudMockRecorder) ResizeOrModifyDisk(ctx, volumeID, newSizeBytes,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizeOrModifyDisk", reflect.TypeOf((*MockCloud)(nil).ResizeOrModifyDisk), ctx, volumeID, newSizeBytes, options)
 }
 
+// TagSnapshot mocks base method.
+func (m *MockCloud) TagSnapshot(ctx context.Context, snapshotID string, tags map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TagSnapshot", ctx, snapshotID, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TagSnapshot indicates an expected call of TagSnapshot.
+func (mr *MockCloudMockRecorder) TagSnapshot(ctx, snapshotID, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagSnapshot", reflect.TypeOf((*MockCloud)(nil).TagSnapshot), ctx, snapshotID, tags)
+}
+
+// UntagSnapshot mocks base method.
+func (m *MockCloud) UntagSnapshot(ctx context.Context, snapshotID string, tagKeys []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntagSnapshot", ctx, snapshotID, tagKeys)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UntagSnapshot indicates an expected call of UntagSnapshot.
+func (mr *MockCloudMockRecorder) UntagSnapshot(ctx, snapshotID, tagKeys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntagSnapshot", reflect.TypeOf((*MockCloud)(nil).UntagSnapshot), ctx, snapshotID, tagKeys)
+}
+
 // WaitForAttachmentState mocks base method.
 func (m *MockCloud) WaitForAttachmentState(ctx context.Context, volumeID, expectedState, expectedInstance, expectedDevice string, alreadyAssigned bool) (*ec2.VolumeAttachment, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+// requestQueueDepthMetric is the number of requests of an operation waiting
+// for the client-side rate limit of their category. It is only reported once
+// a request waited, so not at all unless a rate limit is configured.
+const requestQueueDepthMetric = "cloudprovider_aws_api_request_queue_depth"
+
+// RateLimits are the client-side rate limits of EC2 API requests, per
+// category of request. A category whose QPS is 0 is not rate limited. Its
+// burst defaults to the QPS, rounded up, if 0.
+type RateLimits struct {
+	MutatingQPS   float64
+	MutatingBurst int
+	ReadOnlyQPS   float64
+	ReadOnlyBurst int
+}
+
+// requestCategory is the category of an EC2 API request for rate limiting,
+// as EC2 throttles mutating and read-only requests separately.
+type requestCategory string
+
+const (
+	mutatingRequests requestCategory = "mutating"
+	readOnlyRequests requestCategory = "read-only"
+)
+
+const (
+	// rateLimitDecreaseFactor is the factor the rate of a category of requests
+	// is decreased by each time a request of the category is throttled.
+	rateLimitDecreaseFactor = 0.5
+	// rateLimitIncreaseFraction is the fraction of the configured rate of a
+	// category of requests its rate is increased by, up to the configured rate,
+	// each time a request of the category succeeds.
+	rateLimitIncreaseFraction = 0.05
+	// rateLimitMinFraction is the fraction of the configured rate of a category
+	// of requests its rate is never decreased below.
+	rateLimitMinFraction = 0.1
+)
+
+// requestRateLimiter limits the rate of EC2 API requests with a token bucket
+// per category of request. The rate of a category adapts to throttling: it is
+// halved each time a request is throttled by EC2, and recovers gradually to
+// the configured rate as requests succeed. It complements the retries of the
+// SDK, which back off exponentially with jitter, or as long as a Retry-After
+// header asks, after each throttled attempt.
+//
+// It is nil, and requests are not rate limited, unless a rate is configured.
+type requestRateLimiter struct {
+	limiters map[requestCategory]*adaptiveLimiter
+}
+
+type adaptiveLimiter struct {
+	mu      sync.Mutex
+	limiter *rate.Limiter
+	max     rate.Limit
+}
+
+func newRequestRateLimiter(limits RateLimits) *requestRateLimiter {
+	limiters := map[requestCategory]*adaptiveLimiter{}
+	if l := newAdaptiveLimiter(limits.MutatingQPS, limits.MutatingBurst); l != nil {
+		limiters[mutatingRequests] = l
+	}
+	if l := newAdaptiveLimiter(limits.ReadOnlyQPS, limits.ReadOnlyBurst); l != nil {
+		limiters[readOnlyRequests] = l
+	}
+	if len(limiters) == 0 {
+		return nil
+	}
+	return &requestRateLimiter{limiters: limiters}
+}
+
+func newAdaptiveLimiter(qps float64, burst int) *adaptiveLimiter {
+	if qps <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(qps))
+	}
+	return &adaptiveLimiter{
+		limiter: rate.NewLimiter(rate.Limit(qps), burst),
+		max:     rate.Limit(qps),
+	}
+}
+
+// addHandlers adds the handlers that limit the rate of the requests of
+// handlers. Every attempt of a request, including retries, waits for a token.
+func (l *requestRateLimiter) addHandlers(handlers *request.Handlers) {
+	if l == nil {
+		return
+	}
+	handlers.Sign.PushFrontNamed(request.NamedHandler{
+		Name: "rateLimitRequestsHandler",
+		Fn:   l.waitHandler,
+	})
+	handlers.AfterRetry.PushFrontNamed(request.NamedHandler{
+		Name: "rateLimitThrottledRequestsHandler",
+		Fn:   l.throttledHandler,
+	})
+	handlers.Complete.PushFrontNamed(request.NamedHandler{
+		Name: "rateLimitCompletedRequestsHandler",
+		Fn:   l.completedHandler,
+	})
+}
+
+func (l *requestRateLimiter) limiterOf(r *request.Request) (*adaptiveLimiter, requestCategory) {
+	category := categoryOf(operationName(r))
+	return l.limiters[category], category
+}
+
+// waitHandler waits until the rate limit of its category allows the request.
+func (l *requestRateLimiter) waitHandler(r *request.Request) {
+	limiter, category := l.limiterOf(r)
+	if limiter == nil {
+		return
+	}
+	labels := map[string]string{"limiter": string(category), "operation": operationName(r)}
+	metrics.Recorder().AddGauge(requestQueueDepthMetric, 1, labels)
+	err := limiter.limiter.Wait(r.Context())
+	metrics.Recorder().AddGauge(requestQueueDepthMetric, -1, labels)
+	if err != nil {
+		r.Error = awserr.New(request.CanceledErrorCode, "request canceled while waiting for client-side rate limit", err)
+	}
+}
+
+// throttledHandler decreases the rate of the category of throttled requests.
+func (l *requestRateLimiter) throttledHandler(r *request.Request) {
+	limiter, category := l.limiterOf(r)
+	if limiter == nil || !r.IsErrorThrottle() {
+		return
+	}
+	limit := limiter.adjust(rateLimitDecreaseFactor, 0)
+	klog.V(4).InfoS("Decreased client-side rate limit of throttled requests", "category", category, "request", describeRequest(r), "qps", float64(limit))
+}
+
+// completedHandler increases the rate of the category of successful requests.
+func (l *requestRateLimiter) completedHandler(r *request.Request) {
+	limiter, _ := l.limiterOf(r)
+	if limiter == nil || r.Error != nil {
+		return
+	}
+	limiter.adjust(1, limiter.max*rateLimitIncreaseFraction)
+}
+
+// adjust sets the rate of the limiter to its rate multiplied by factor, plus
+// increase, within rateLimitMinFraction of its configured rate and its
+// configured rate, and returns the new rate.
+func (a *adaptiveLimiter) adjust(factor float64, increase rate.Limit) rate.Limit {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	limit := a.limiter.Limit()*rate.Limit(factor) + increase
+	limit = min(max(limit, a.max*rateLimitMinFraction), a.max)
+	a.limiter.SetLimit(limit)
+	return limit
+}
+
+// categoryOf returns the category of an EC2 API operation, e.g. read-only for
+// DescribeVolumes and mutating for AttachVolume.
+func categoryOf(operation string) requestCategory {
+	for _, prefix := range []string{"Describe", "Get", "List"} {
+		if strings.HasPrefix(operation, prefix) {
+			return readOnlyRequests
+		}
+	}
+	return mutatingRequests
+}
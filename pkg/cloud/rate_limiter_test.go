@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestNewRequestRateLimiter(t *testing.T) {
+	testCases := []struct {
+		name      string
+		limits    RateLimits
+		expLimits map[requestCategory]rate.Limit
+		expBursts map[requestCategory]int
+	}{
+		{
+			name: "disabled without rates",
+		},
+		{
+			name:      "burst defaults to rate",
+			limits:    RateLimits{MutatingQPS: 2.5},
+			expLimits: map[requestCategory]rate.Limit{mutatingRequests: 2.5},
+			expBursts: map[requestCategory]int{mutatingRequests: 3},
+		},
+		{
+			name:      "both categories",
+			limits:    RateLimits{MutatingQPS: 5, MutatingBurst: 10, ReadOnlyQPS: 20, ReadOnlyBurst: 40},
+			expLimits: map[requestCategory]rate.Limit{mutatingRequests: 5, readOnlyRequests: 20},
+			expBursts: map[requestCategory]int{mutatingRequests: 10, readOnlyRequests: 40},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newRequestRateLimiter(tc.limits)
+			if tc.expLimits == nil {
+				assert.Nil(t, l)
+				// addHandlers must be safe on a disabled rate limiter
+				l.addHandlers(&request.Handlers{})
+				return
+			}
+			assert.Len(t, l.limiters, len(tc.expLimits))
+			for category, limit := range tc.expLimits {
+				assert.Equal(t, limit, l.limiters[category].limiter.Limit())
+				assert.Equal(t, tc.expBursts[category], l.limiters[category].limiter.Burst())
+			}
+		})
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	testCases := map[string]requestCategory{
+		"DescribeVolumes":            readOnlyRequests,
+		"DescribeInstances":          readOnlyRequests,
+		"GetEbsEncryptionByDefault":  readOnlyRequests,
+		"CreateVolume":               mutatingRequests,
+		"AttachVolume":               mutatingRequests,
+		"EnableFastSnapshotRestores": mutatingRequests,
+	}
+	for operation, expCategory := range testCases {
+		assert.Equal(t, expCategory, categoryOf(operation), operation)
+	}
+}
+
+func TestRequestRateLimiterAdaptsToThrottling(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>test</RequestID></Response>`)
+			return
+		}
+		fmt.Fprint(w, `<DescribeVolumesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><volumeSet/></DescribeVolumesResponse>`)
+	}))
+	defer server.Close()
+
+	svc := newRateLimitedEC2(t, server.URL, RateLimits{ReadOnlyQPS: 10})
+	limiter := svc.limiter.limiters[readOnlyRequests]
+
+	if _, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{}); err != nil {
+		t.Fatalf("DescribeVolumes failed: %v", err)
+	}
+	assert.Equal(t, int32(2), requests.Load())
+	// Halved after the throttled attempt, increased by 5% of 10 after the successful one
+	assert.InDelta(t, 5.5, float64(limiter.limiter.Limit()), 0.001)
+
+	for i := 0; i < 20; i++ {
+		if _, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{}); err != nil {
+			t.Fatalf("DescribeVolumes failed: %v", err)
+		}
+	}
+	assert.Equal(t, rate.Limit(10), limiter.limiter.Limit())
+}
+
+func TestRequestRateLimiterMinimumRate(t *testing.T) {
+	l := newAdaptiveLimiter(10, 0)
+	for i := 0; i < 10; i++ {
+		l.adjust(rateLimitDecreaseFactor, 0)
+	}
+	assert.Equal(t, rate.Limit(1), l.limiter.Limit())
+}
+
+func TestRequestRateLimiterWaitCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<DescribeVolumesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><volumeSet/></DescribeVolumesResponse>`)
+	}))
+	defer server.Close()
+
+	svc := newRateLimitedEC2(t, server.URL, RateLimits{ReadOnlyQPS: 0.001, ReadOnlyBurst: 1})
+
+	// The first request uses up the burst, the next one would wait for too long
+	if _, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{}); err != nil {
+		t.Fatalf("DescribeVolumes failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{})
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != request.CanceledErrorCode {
+		t.Fatalf("Expected %s error, got: %v", request.CanceledErrorCode, err)
+	}
+}
+
+func TestRequestRateLimiterQueueDepth(t *testing.T) {
+	metricsAddress := reserveMetricsAddress(t)
+	metrics.InitializeRecorder().InitializeMetricsHandler(metricsAddress, "/metrics")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<DescribeVolumesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><volumeSet/></DescribeVolumesResponse>`)
+	}))
+	defer server.Close()
+
+	svc := newRateLimitedEC2(t, server.URL, RateLimits{ReadOnlyQPS: 0.001, ReadOnlyBurst: 1})
+
+	// The first request uses up the burst and is not counted once it completes,
+	// the next ones wait for the rate limit until they are canceled
+	if _, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{}); err != nil {
+		t.Fatalf("DescribeVolumes failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	const volumeRequests, snapshotRequests = 3, 2
+	var wg sync.WaitGroup
+	for i := 0; i < volumeRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{})
+		}()
+	}
+	for i := 0; i < snapshotRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.DescribeSnapshotsWithContext(ctx, &ec2.DescribeSnapshotsInput{})
+		}()
+	}
+
+	expectMetric(t, metricsAddress, fmt.Sprintf(`cloudprovider_aws_api_request_queue_depth{limiter="read-only",operation="DescribeVolumes"} %d`, volumeRequests))
+	expectMetric(t, metricsAddress, fmt.Sprintf(`cloudprovider_aws_api_request_queue_depth{limiter="read-only",operation="DescribeSnapshots"} %d`, snapshotRequests))
+	cancel()
+	wg.Wait()
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_queue_depth{limiter="read-only",operation="DescribeVolumes"} 0`)
+	expectMetric(t, metricsAddress, `cloudprovider_aws_api_request_queue_depth{limiter="read-only",operation="DescribeSnapshots"} 0`)
+}
+
+type rateLimitedEC2 struct {
+	*ec2.EC2
+	limiter *requestRateLimiter
+}
+
+func newRateLimitedEC2(t *testing.T, endpoint string, limits RateLimits) rateLimitedEC2 {
+	t.Helper()
+	svc := ec2.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(endpoint),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		Retryer: client.DefaultRetryer{
+			NumMaxRetries:    1,
+			MinThrottleDelay: time.Millisecond,
+			MaxThrottleDelay: time.Millisecond,
+		},
+	})))
+	limiter := newRequestRateLimiter(limits)
+	limiter.addHandlers(&svc.Handlers)
+	return rateLimitedEC2{EC2: svc, limiter: limiter}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	// defaultRoleSessionName names the sessions of the assumed role of
+	// clusters without an ID.
+	defaultRoleSessionName = "ebs-csi-driver"
+	// maxRoleSessionNameLength is the maximum length of a role session name
+	// accepted by STS.
+	maxRoleSessionNameLength = 64
+	// roleCredentialsExpiryWindow is how long before they expire the
+	// credentials of the assumed role are refreshed, so that requests in
+	// flight are not signed with expired credentials.
+	roleCredentialsExpiryWindow = time.Minute
+)
+
+// invalidRoleSessionNameChars matches the characters STS does not accept in
+// role session names.
+var invalidRoleSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// RoleOptions configure the IAM role the driver assumes to call AWS, e.g. to
+// provision volumes in another account than the one of the cluster.
+type RoleOptions struct {
+	// RoleARN is the ARN of the role to assume. The credentials of the driver
+	// are used as is if empty.
+	RoleARN string
+	// ExternalID is passed to STS when assuming the role, if set, as required
+	// by the trust policies of roles assumed by third parties.
+	ExternalID string
+	// SessionName names the sessions of the assumed role, e.g. in CloudTrail.
+	SessionName string
+}
+
+// Validate returns an error if the role to assume is not an IAM role ARN.
+func (o RoleOptions) Validate() error {
+	if o.RoleARN == "" {
+		if o.ExternalID != "" {
+			return fmt.Errorf("external ID %q set without a role to assume", o.ExternalID)
+		}
+		return nil
+	}
+	parsed, err := arn.Parse(o.RoleARN)
+	if err != nil {
+		return fmt.Errorf("invalid role ARN %q: %w", o.RoleARN, err)
+	}
+	if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return fmt.Errorf("invalid role ARN %q: not an IAM role", o.RoleARN)
+	}
+	return nil
+}
+
+// RoleSessionName returns the name of the sessions of the assumed role of the
+// cluster clusterID, which identifies the cluster in CloudTrail.
+func RoleSessionName(clusterID string) string {
+	if clusterID == "" {
+		return defaultRoleSessionName
+	}
+	name := defaultRoleSessionName + "-" + invalidRoleSessionNameChars.ReplaceAllString(clusterID, "-")
+	if len(name) > maxRoleSessionNameLength {
+		name = name[:maxRoleSessionNameLength]
+	}
+	return name
+}
+
+// session returns a copy of sess whose clients assume the role, or sess if no
+// role is set. The credentials of the role are refreshed by STS before they
+// expire.
+func (o RoleOptions) session(sess *session.Session) *session.Session {
+	if o.RoleARN == "" {
+		return sess
+	}
+	creds := stscreds.NewCredentials(sess, o.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.ExpiryWindow = roleCredentialsExpiryWindow
+		p.RoleSessionName = o.SessionName
+		if p.RoleSessionName == "" {
+			p.RoleSessionName = defaultRoleSessionName
+		}
+		if o.ExternalID != "" {
+			p.ExternalID = aws.String(o.ExternalID)
+		}
+	})
+	return sess.Copy(&aws.Config{Credentials: creds})
+}
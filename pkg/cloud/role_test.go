@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options RoleOptions
+		expErr  string
+	}{
+		{
+			name: "valid: no role",
+		},
+		{
+			name:    "valid: role",
+			options: RoleOptions{RoleARN: "arn:aws:iam::123456789012:role/ebs-csi-driver"},
+		},
+		{
+			name:    "valid: role with path and external ID",
+			options: RoleOptions{RoleARN: "arn:aws-us-gov:iam::123456789012:role/csi/ebs-csi-driver", ExternalID: "external-id"},
+		},
+		{
+			name:    "invalid: external ID without role",
+			options: RoleOptions{ExternalID: "external-id"},
+			expErr:  `external ID "external-id" set without a role to assume`,
+		},
+		{
+			name:    "invalid: not an ARN",
+			options: RoleOptions{RoleARN: "ebs-csi-driver"},
+			expErr:  `invalid role ARN "ebs-csi-driver": arn: invalid prefix`,
+		},
+		{
+			name:    "invalid: not an IAM role",
+			options: RoleOptions{RoleARN: "arn:aws:iam::123456789012:user/ebs-csi-driver"},
+			expErr:  `invalid role ARN "arn:aws:iam::123456789012:user/ebs-csi-driver": not an IAM role`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.options.Validate()
+			if tc.expErr != "" {
+				assert.EqualError(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRoleSessionName(t *testing.T) {
+	testCases := []struct {
+		name      string
+		clusterID string
+		expected  string
+	}{
+		{
+			name:     "no cluster ID",
+			expected: "ebs-csi-driver",
+		},
+		{
+			name:      "cluster ID",
+			clusterID: "my-cluster",
+			expected:  "ebs-csi-driver-my-cluster",
+		},
+		{
+			name:      "invalid characters are replaced",
+			clusterID: "my cluster/prod:1",
+			expected:  "ebs-csi-driver-my-cluster-prod-1",
+		},
+		{
+			name:      "long cluster ID is truncated",
+			clusterID: strings.Repeat("a", 100),
+			expected:  "ebs-csi-driver-" + strings.Repeat("a", maxRoleSessionNameLength-len("ebs-csi-driver-")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, RoleSessionName(tc.clusterID))
+		})
+	}
+}
+
+func TestRoleOptionsSession(t *testing.T) {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+
+	assert.Same(t, sess, RoleOptions{}.session(sess))
+
+	assumed := RoleOptions{RoleARN: "arn:aws:iam::123456789012:role/ebs-csi-driver"}.session(sess)
+	assert.NotSame(t, sess, assumed)
+	assert.NotSame(t, sess.Config.Credentials, assumed.Config.Credentials)
+	assert.Equal(t, "us-west-2", aws.StringValue(assumed.Config.Region))
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import "fmt"
+
+// volumeTypeLimits are the size and performance limits of an EBS volume type.
+type volumeTypeLimits struct {
+	// minSizeGiB and maxSizeGiB bound the size of volumes.
+	minSizeGiB, maxSizeGiB int64
+	// minIOPS and maxIOPS bound the provisioned IOPS of volumes, 0 if IOPS
+	// cannot be provisioned.
+	minIOPS, maxIOPS int64
+	// maxIOPSPerGB bounds the provisioned IOPS of volumes relative to their size.
+	maxIOPSPerGB int64
+	// minThroughput and maxThroughput bound the provisioned throughput of
+	// volumes in MiB/s, 0 if throughput cannot be provisioned but follows from
+	// the IOPS and size of volumes, e.g. up to 4000 MiB/s for io2 Block Express.
+	minThroughput, maxThroughput int64
+}
+
+// AWS provisioning limits.
+// Source: https://docs.aws.amazon.com/ebs/latest/userguide/ebs-volume-types.html
+var (
+	volumeTypesLimits = map[string]volumeTypeLimits{
+		VolumeTypeGP2: {minSizeGiB: 1, maxSizeGiB: 16384},
+		VolumeTypeGP3: {
+			minSizeGiB: 1, maxSizeGiB: 16384,
+			minIOPS: 3000, maxIOPS: 16000, maxIOPSPerGB: 500,
+			minThroughput: 125, maxThroughput: 1000,
+		},
+		VolumeTypeIO1: {
+			minSizeGiB: 4, maxSizeGiB: 16384,
+			minIOPS: 100, maxIOPS: 64000, maxIOPSPerGB: 50,
+		},
+		VolumeTypeIO2: {
+			minSizeGiB: 4, maxSizeGiB: 16384,
+			minIOPS: 100, maxIOPS: 64000, maxIOPSPerGB: 500,
+		},
+		VolumeTypeST1:      {minSizeGiB: 125, maxSizeGiB: 16384},
+		VolumeTypeSC1:      {minSizeGiB: 125, maxSizeGiB: 16384},
+		VolumeTypeStandard: {minSizeGiB: 1, maxSizeGiB: 1024},
+	}
+
+	// io2BlockExpressLimits are the limits of io2 volumes on Block Express.
+	io2BlockExpressLimits = volumeTypeLimits{
+		minSizeGiB: 4, maxSizeGiB: 65536,
+		minIOPS: 100, maxIOPS: 256000, maxIOPSPerGB: 1000,
+	}
+)
+
+// limitsOf returns the limits of volumeType, of io2 Block Express if
+// blockExpress is set. Volume types of Snowball Edge devices, whose limits
+// depend on the device, have no limits.
+func limitsOf(volumeType string, blockExpress bool) (volumeTypeLimits, error) {
+	switch volumeType {
+	case VolumeTypeSBG1, VolumeTypeSBP1:
+		return volumeTypeLimits{}, nil
+	case VolumeTypeIO2:
+		if blockExpress {
+			return io2BlockExpressLimits, nil
+		}
+	}
+	limits, ok := volumeTypesLimits[volumeType]
+	if !ok {
+		return volumeTypeLimits{}, fmt.Errorf("%w: invalid AWS VolumeType %q", ErrInvalidVolumeParameters, volumeType)
+	}
+	return limits, nil
+}
+
+// MaxVolumeSizeGiB returns the maximum size of volumes of volumeType, of io2
+// Block Express if blockExpress is set, or 0 if it is unknown, e.g. for volume
+// types of Snowball Edge devices. gp3 volumes are created if volumeType is empty.
+func MaxVolumeSizeGiB(volumeType string, blockExpress bool) int64 {
+	if volumeType == "" {
+		volumeType = VolumeTypeGP3
+	}
+	limits, err := limitsOf(volumeType, blockExpress)
+	if err != nil {
+		return 0
+	}
+	return limits.maxSizeGiB
+}
+
+// validateSize returns an error if a volume of capacityGiB is smaller or larger
+// than the volume type supports.
+func (l volumeTypeLimits) validateSize(volumeType string, capacityGiB int64) error {
+	if l.maxSizeGiB == 0 {
+		return nil
+	}
+	if capacityGiB < l.minSizeGiB || capacityGiB > l.maxSizeGiB {
+		return fmt.Errorf("%w: %d GiB, %s volumes must be between %d GiB and %d GiB", ErrCapacityOutOfRange, capacityGiB, volumeType, l.minSizeGiB, l.maxSizeGiB)
+	}
+	return nil
+}
+
+// maxIOPSOf returns the maximum IOPS of a volume of capacityGiB, that is the
+// lower of the maximum IOPS of the volume type and its maximum IOPS per GiB
+// times capacityGiB, unless the latter is below the minimum IOPS.
+func (l volumeTypeLimits) maxIOPSOf(capacityGiB int64) int64 {
+	maxIOPSByCapacity := l.maxIOPSPerGB * capacityGiB
+	if maxIOPSByCapacity < l.maxIOPS && maxIOPSByCapacity >= l.minIOPS {
+		return maxIOPSByCapacity
+	}
+	return l.maxIOPS
+}
@@ -28,6 +28,17 @@ const (
 	// VolumeAttributePartition represents key for partition config in VolumeContext
 	// this represents the partition number on a device used to mount
 	VolumeAttributePartition = "partition"
+
+	// VolumeAttributeSizeBytes represents key for the size in bytes the volume was provisioned with
+	VolumeAttributeSizeBytes = "sizeBytes"
+
+	// VolumeAttributeFilesystemUUID represents key for the UUID of the filesystem of a volume,
+	// by which the node resolves the device of the volume if --resolve-devices-by-uuid is set
+	VolumeAttributeFilesystemUUID = "filesystemUUID"
+
+	// VolumeAttributeDefaultMountOptions represents key for the comma separated mount options
+	// that the node adds to those of the volume capability when staging the volume
+	VolumeAttributeDefaultMountOptions = "defaultMountOptions"
 )
 
 // constants of disk partition suffix
@@ -53,6 +64,9 @@ const (
 	// ThroughputKey represents key for throughput
 	ThroughputKey = "throughput"
 
+	// AllowAutoThroughputDecreaseKey represents key for allowing automatic decrease of throughput
+	AllowAutoThroughputDecreaseKey = "allowautothroughputdecrease"
+
 	// EncryptedKey represents key for whether filesystem is encrypted
 	EncryptedKey = "encrypted"
 
@@ -79,6 +93,16 @@ const (
 	// for the snapshot
 	VolumeSnapshotContentNameKey = "csi.storage.k8s.io/volumesnapshotcontent/name"
 
+	// VolumeGroupSnapshotNameKey contains name of the group snapshot
+	VolumeGroupSnapshotNameKey = "csi.storage.k8s.io/volumegroupsnapshot/name"
+
+	// VolumeGroupSnapshotNamespaceKey contains namespace of the group snapshot
+	VolumeGroupSnapshotNamespaceKey = "csi.storage.k8s.io/volumegroupsnapshot/namespace"
+
+	// VolumeGroupSnapshotContentNameKey contains name of the VolumeGroupSnapshotContent
+	// that is the source for the group snapshot
+	VolumeGroupSnapshotContentNameKey = "csi.storage.k8s.io/volumegroupsnapshotcontent/name"
+
 	// BlockExpressKey increases the iops limit for io2 volumes to the block express limit
 	BlockExpressKey = "blockexpress"
 
@@ -103,6 +127,24 @@ const (
 	// Ext4ClusterSizeKey configures the cluster size when formatting an ext4 volume with the bigalloc option enabled
 	Ext4ClusterSizeKey = "ext4clustersize"
 
+	// IsolateMountNamespaceKey stages and publishes a volume in the node's configured mount namespace
+	IsolateMountNamespaceKey = "isolatemountnamespace"
+
+	// ColocateWithVolumeIDKey creates the volume in the availability zone of an existing volume
+	ColocateWithVolumeIDKey = "colocatewithvolumeid"
+
+	// BaselineSnapshotKey takes a snapshot of the volume right after it is created
+	BaselineSnapshotKey = "baselinesnapshot"
+
+	// GrowthHeadroomKey adds a percentage of the requested size to the size of the volume, to account for future growth
+	GrowthHeadroomKey = "growthheadroom"
+
+	// OutpostArnKey creates the volume, or stores the snapshot of a volume on an Outpost as a local snapshot, on the Outpost of the given ARN
+	OutpostArnKey = "outpostarn"
+
+	// SourceRegionKey restores the volume from a copy of its source snapshot in the given region, if the snapshot is not found in the region of the driver
+	SourceRegionKey = "sourceregion"
+
 	// TagKeyPrefix contains the prefix of a volume parameter that designates it as
 	// a tag to be attached to the resource
 	TagKeyPrefix = "tagSpecification"
@@ -187,6 +229,9 @@ const (
 
 type fileSystemConfig struct {
 	NotSupportedParams map[string]struct{}
+	// NotSupportedMountOptions are the names of mount options, without their
+	// value, that mounting the filesystem fails with
+	NotSupportedMountOptions map[string]struct{}
 }
 
 func (fsConfig fileSystemConfig) isParameterSupported(paramName string) bool {
@@ -194,22 +239,39 @@ func (fsConfig fileSystemConfig) isParameterSupported(paramName string) bool {
 	return !notSupported
 }
 
+func (fsConfig fileSystemConfig) isMountOptionSupported(optionName string) bool {
+	_, notSupported := fsConfig.NotSupportedMountOptions[optionName]
+	return !notSupported
+}
+
 var (
+	// xfsMountOptions are mount options of xfs that ext filesystems do not support
+	xfsMountOptions = []string{"nouuid", "inode32", "inode64", "logbufs", "logbsize", "logdev", "rtdev", "allocsize", "largeio", "nolargeio", "swalloc", "sunit", "swidth", "noalign", "filestreams", "ikeep", "noikeep", "attr2", "noattr2", "wsync", "pquota", "gquota", "uquota"}
+	// ext4MountOptions are mount options of ext3 and ext4, most of them about
+	// their journal, that xfs does not support
+	ext4MountOptions = []string{"data", "commit", "journal_checksum", "nojournal_checksum", "journal_async_commit", "journal_ioprio", "noload", "barrier", "nobarrier", "errors", "delalloc", "nodelalloc", "auto_da_alloc", "noauto_da_alloc", "init_itable", "noinit_itable", "stripe"}
+	// ext2JournalMountOptions are mount options of ext3 and ext4 that ext2,
+	// which has no journal, does not support
+	ext2JournalMountOptions = []string{"data", "commit", "journal_checksum", "nojournal_checksum", "journal_async_commit", "journal_ioprio", "noload", "barrier", "nobarrier", "delalloc", "nodelalloc", "auto_da_alloc", "noauto_da_alloc", "init_itable", "noinit_itable"}
+
 	FileSystemConfigs = map[string]fileSystemConfig{
 		FSTypeExt2: {
 			NotSupportedParams: map[string]struct{}{
 				Ext4BigAllocKey:    {},
 				Ext4ClusterSizeKey: {},
 			},
+			NotSupportedMountOptions: newMountOptionSet(xfsMountOptions, ext2JournalMountOptions),
 		},
 		FSTypeExt3: {
 			NotSupportedParams: map[string]struct{}{
 				Ext4BigAllocKey:    {},
 				Ext4ClusterSizeKey: {},
 			},
+			NotSupportedMountOptions: newMountOptionSet(xfsMountOptions),
 		},
 		FSTypeExt4: {
-			NotSupportedParams: map[string]struct{}{},
+			NotSupportedParams:       map[string]struct{}{},
+			NotSupportedMountOptions: newMountOptionSet(xfsMountOptions),
 		},
 		FSTypeXfs: {
 			NotSupportedParams: map[string]struct{}{
@@ -218,6 +280,7 @@ var (
 				Ext4BigAllocKey:    {},
 				Ext4ClusterSizeKey: {},
 			},
+			NotSupportedMountOptions: newMountOptionSet(ext4MountOptions),
 		},
 		FSTypeNtfs: {
 			NotSupportedParams: map[string]struct{}{
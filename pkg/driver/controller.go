@@ -20,20 +20,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/awslabs/volume-modifier-for-k8s/pkg/rpc"
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util/template"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 )
 
@@ -51,6 +62,11 @@ var (
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
 	}
 )
 
@@ -58,10 +74,21 @@ const isManagedByDriver = "true"
 
 // controllerService represents the controller service of CSI driver
 type controllerService struct {
+	csi.UnimplementedControllerServer
+	csi.UnimplementedGroupControllerServer
 	cloud               cloud.Cloud
 	inFlight            *internal.InFlight
+	results             *internal.ResultCache
 	driverOptions       *DriverOptions
 	modifyVolumeManager *modifyVolumeManager
+	shutdown            *shutdownCoordinator
+	attachLimiter       *attachLimiter
+	nodeOperations      *nodeOperationQueue
+	attachBudget        *attachBudget
+	orphanedVolumes     *orphanedVolumeReporter
+	placer              *volumePlacer
+	// fsrWarmCache is nil unless the fast snapshot restore warm cache is enabled
+	fsrWarmCache *fsrWarmCacheManager
 
 	rpc.UnimplementedModifyServer
 }
@@ -78,10 +105,48 @@ var (
 // newControllerService creates a new controller service
 // it panics if failed to create the service
 func newControllerService(driverOptions *DriverOptions) controllerService {
+	cloudSrv := newCloudService(driverOptions)
+
+	var fsrWarmCache *fsrWarmCacheManager
+	if driverOptions.fsrWarmCacheInterval > 0 {
+		fsrWarmCache = newFSRWarmCacheManager(cloudSrv, cloud.DefaultKubernetesAPIClient, driverOptions)
+	} else if len(driverOptions.fsrWarmSnapshots) > 0 {
+		klog.InfoS("Ignoring fast snapshot restore warm cache snapshots because no warm cache interval is set", "snapshotIDs", driverOptions.fsrWarmSnapshots)
+	}
+
+	if driverOptions.validateStorageClasses {
+		go newStorageClassValidator(cloudSrv, cloud.DefaultKubernetesAPIClient, driverOptions).run(context.Background())
+	}
+
+	return controllerService{
+		cloud:               cloudSrv,
+		inFlight:            internal.NewInFlight(),
+		results:             internal.NewResultCache(driverOptions.requestCacheTTL),
+		driverOptions:       driverOptions,
+		modifyVolumeManager: newModifyVolumeManager(),
+		shutdown:            newShutdownCoordinator(),
+		attachLimiter:       newAttachLimiter(driverOptions.maxConcurrentAttaches),
+		nodeOperations:      newNodeOperationQueue(driverOptions.nodeOperationWorkers),
+		attachBudget:        newAttachBudget(driverOptions.attachRetryBudget, driverOptions.attachRetryDeadline),
+		orphanedVolumes:     newOrphanedVolumeReporter(cloudSrv, cloud.DefaultKubernetesAPIClient, driverOptions),
+		placer:              newVolumePlacer(driverOptions.volumePlacementStrategy),
+		fsrWarmCache:        fsrWarmCache,
+	}
+}
+
+// newCloudService creates the cloud provider of the controller service, an
+// in-memory fake one if configured.
+// it panics if failed to create the cloud provider
+func newCloudService(driverOptions *DriverOptions) cloud.Cloud {
+	if driverOptions.cloudProvider == cloud.CloudProviderFake {
+		klog.InfoS("Using fake cloud provider", "options", driverOptions.fakeCloudOptions)
+		return cloud.NewFakeCloudProvider(driverOptions.fakeCloudOptions)
+	}
+
 	region := os.Getenv("AWS_REGION")
 	if region == "" {
 		klog.V(5).InfoS("[Debug] Retrieving region from metadata service")
-		metadata, err := NewMetadataFunc(cloud.DefaultEC2MetadataClient, cloud.DefaultKubernetesAPIClient, region)
+		metadata, err := NewMetadataFunc(cloud.NewEC2MetadataClient(driverOptions.imdsVersion, driverOptions.endpointOptions), cloud.DefaultKubernetesAPIClient, region, driverOptions.metadataSources)
 		if err != nil {
 			klog.ErrorS(err, "Could not determine region from any metadata service. The region can be manually supplied via the AWS_REGION environment variable.")
 			panic(err)
@@ -90,25 +155,32 @@ func newControllerService(driverOptions *DriverOptions) controllerService {
 	}
 
 	klog.InfoS("batching", "status", driverOptions.batching)
-	cloudSrv, err := NewCloudFunc(region, driverOptions.awsSdkDebugLog, driverOptions.userAgentExtra, driverOptions.batching)
+	cloudSrv, err := NewCloudFunc(region, cloud.CloudOptions{
+		AWSSDKDebugLog:         driverOptions.awsSdkDebugLog,
+		UserAgentExtra:         driverOptions.userAgentExtra,
+		Batching:               driverOptions.batching,
+		BatchingWindow:         driverOptions.batchingWindow,
+		DeviceNameLeaseTimeout: driverOptions.deviceNameLeaseTimeout,
+		ForceDetachTimeout:     driverOptions.forceDetachTimeout,
+		OnForceDetach:          newForceDetachEventRecorder(cloud.DefaultKubernetesAPIClient).forceDetached,
+		RateLimits:             driverOptions.ec2RateLimits,
+		EndpointOptions:        driverOptions.endpointOptions,
+		DescribeCacheTTL:       driverOptions.describeCacheTTL,
+		RoleOptions:            driverOptions.roleOptions,
+	})
 	if err != nil {
 		panic(err)
 	}
-
-	return controllerService{
-		cloud:               cloudSrv,
-		inFlight:            internal.NewInFlight(),
-		driverOptions:       driverOptions,
-		modifyVolumeManager: newModifyVolumeManager(),
-	}
+	return cloudSrv
 }
 
 func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	klog.V(4).InfoS("CreateVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("CreateVolume: called", "args", req)
 	if err := validateCreateVolumeRequest(req); err != nil {
 		return nil, err
 	}
-	volSizeBytes, err := getVolSizeBytes(req)
+	volSizeBytes, err := getVolSizeBytes(req, d.driverOptions.volumeSizeGranularity)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +190,7 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 	multiAttach := false
 	for _, c := range volCap {
 		if c.GetAccessMode().GetMode() == MultiNodeMultiWriter && isBlock(c) {
-			klog.V(4).InfoS("CreateVolume: multi-attach is enabled", "volumeID", volName)
+			logger.V(4).Info("CreateVolume: multi-attach is enabled", "volumeID", volName)
 			multiAttach = true
 		}
 	}
@@ -130,26 +202,40 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 	}
 	defer d.inFlight.Delete(volName)
 
+	resultKey := "CreateVolume/" + volName
+	if result, ok := d.results.Get(resultKey, req); ok {
+		return result.(*csi.CreateVolumeResponse), nil
+	}
+
 	var (
-		volumeType             string
-		iopsPerGB              int
-		allowIOPSPerGBIncrease bool
-		iops                   int
-		throughput             int
-		isEncrypted            bool
-		blockExpress           bool
-		kmsKeyID               string
-		scTags                 []string
-		volumeTags             = map[string]string{
+		volumeType              string
+		iopsPerGB               int
+		allowIOPSPerGBIncrease  bool
+		iops                    int
+		throughput              int
+		allowThroughputDecrease bool
+		isEncrypted             bool
+		// isUnencrypted is set if encrypted is explicitly false
+		isUnencrypted bool
+		blockExpress  bool
+		kmsKeyID      string
+		scTags        []string
+		volumeTags    = map[string]string{
 			cloud.VolumeNameTagKey:   volName,
 			cloud.AwsEbsDriverTagKey: isManagedByDriver,
 		}
-		blockSize       string
-		inodeSize       string
-		bytesPerInode   string
-		numberOfInodes  string
-		ext4BigAlloc    bool
-		ext4ClusterSize string
+		blockSize             string
+		inodeSize             string
+		bytesPerInode         string
+		numberOfInodes        string
+		ext4BigAlloc          bool
+		ext4ClusterSize       string
+		isolateMountNamespace bool
+		colocateWithVolumeID  string
+		baselineSnapshot      bool
+		growthHeadroom        string
+		sourceRegion          string
+		outpostArn            string
 	)
 
 	tProps := new(template.PVProps)
@@ -157,7 +243,7 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 	for key, value := range req.GetParameters() {
 		switch strings.ToLower(key) {
 		case "fstype":
-			klog.InfoS("\"fstype\" is deprecated, please use \"csi.storage.k8s.io/fstype\" instead")
+			logger.Info("\"fstype\" is deprecated, please use \"csi.storage.k8s.io/fstype\" instead")
 		case VolumeTypeKey:
 			volumeType = value
 		case IopsPerGBKey:
@@ -177,10 +263,13 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 			if err != nil {
 				return nil, status.Errorf(codes.InvalidArgument, "Could not parse invalid throughput: %v", err)
 			}
+		case AllowAutoThroughputDecreaseKey:
+			allowThroughputDecrease = value == "true"
 		case EncryptedKey:
 			if value == "true" {
 				isEncrypted = true
 			}
+			isUnencrypted = value == "false"
 		case KmsKeyIDKey:
 			kmsKeyID = value
 		case PVCNameKey:
@@ -198,22 +287,22 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 			}
 		case BlockSizeKey:
 			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse blockSize (%s): %v", value, err)
+				return nil, status.Errorf(codes.InvalidArgument, "Could not parse blockSize (%s): not alphanumeric", value)
 			}
 			blockSize = value
 		case InodeSizeKey:
 			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse inodeSize (%s): %v", value, err)
+				return nil, status.Errorf(codes.InvalidArgument, "Could not parse inodeSize (%s): not alphanumeric", value)
 			}
 			inodeSize = value
 		case BytesPerInodeKey:
 			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse bytesPerInode (%s): %v", value, err)
+				return nil, status.Errorf(codes.InvalidArgument, "Could not parse bytesPerInode (%s): not alphanumeric", value)
 			}
 			bytesPerInode = value
 		case NumberOfInodesKey:
 			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse numberOfInodes (%s): %v", value, err)
+				return nil, status.Errorf(codes.InvalidArgument, "Could not parse numberOfInodes (%s): not alphanumeric", value)
 			}
 			numberOfInodes = value
 		case Ext4BigAllocKey:
@@ -222,9 +311,24 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 			}
 		case Ext4ClusterSizeKey:
 			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse ext4ClusterSize (%s): %v", value, err)
+				return nil, status.Errorf(codes.InvalidArgument, "Could not parse ext4ClusterSize (%s): not alphanumeric", value)
 			}
 			ext4ClusterSize = value
+		case IsolateMountNamespaceKey:
+			isolateMountNamespace = value == "true"
+		case ColocateWithVolumeIDKey:
+			colocateWithVolumeID = value
+		case BaselineSnapshotKey:
+			baselineSnapshot = value == "true"
+		case GrowthHeadroomKey:
+			growthHeadroom = value
+		case SourceRegionKey:
+			sourceRegion = value
+		case OutpostArnKey:
+			if !isOutpostArn(value) {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter value %s for %s: not an Outpost ARN", value, key)
+			}
+			outpostArn = value
 		default:
 			if strings.HasPrefix(key, TagKeyPrefix) {
 				scTags = append(scTags, value)
@@ -234,6 +338,40 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 	}
 
+	// The mutable parameters of the VolumeAttributesClass of the volume, if
+	// any, take precedence over the parameters of its StorageClass
+	if mutableParams := req.GetMutableParameters(); len(mutableParams) > 0 {
+		if err = validateMutableParameters("CreateVolume", mutableParams); err != nil {
+			return nil, err
+		}
+		modifyOptions, parseErr := parseModifyVolumeParameters(mutableParams)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if modifyOptions.VolumeType != "" {
+			volumeType = modifyOptions.VolumeType
+		}
+		if modifyOptions.IOPS != 0 {
+			iops = modifyOptions.IOPS
+			iopsPerGB = 0
+		}
+		if modifyOptions.Throughput != 0 {
+			throughput = modifyOptions.Throughput
+		}
+	}
+
+	if growthHeadroom != "" {
+		sizeWithHeadroom, headroomErr := applyGrowthHeadroom(volSizeBytes, growthHeadroom, req.GetCapacityRange().GetLimitBytes(), cloud.MaxVolumeSizeGiB(volumeType, blockExpress), d.driverOptions.volumeSizeGranularity)
+		if headroomErr != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Could not parse invalid growthHeadroom: %v", headroomErr)
+		}
+		if sizeWithHeadroom > volSizeBytes {
+			logger.V(4).Info("CreateVolume: adding growth headroom to volume size", "volumeName", volName, "requestedBytes", volSizeBytes, "volSizeBytes", sizeWithHeadroom)
+			volumeTags[cloud.GrowthHeadroomTagKey] = fmt.Sprintf("%dGiB", util.BytesToGiB(sizeWithHeadroom-volSizeBytes))
+			volSizeBytes = sizeWithHeadroom
+		}
+	}
+
 	responseCtx := map[string]string{}
 
 	if len(blockSize) > 0 {
@@ -273,6 +411,17 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 	}
 
+	if isolateMountNamespace {
+		responseCtx[IsolateMountNamespaceKey] = "true"
+	}
+
+	if err = validateVolumeCapabilityMountOptions(volCap); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid mount options: %v", err)
+	}
+	if defaultMountOptions := d.defaultMountOptions(volumeType, volCap); len(defaultMountOptions) > 0 {
+		responseCtx[VolumeAttributeDefaultMountOptions] = strings.Join(defaultMountOptions, ",")
+	}
+
 	if !ext4BigAlloc && len(ext4ClusterSize) > 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "Cannot set ext4BigAllocClusterSize when ext4BigAlloc is false")
 	}
@@ -293,10 +442,50 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 		snapshotID = sourceSnapshot.GetSnapshotId()
 	}
+	requestedSnapshotID := snapshotID
+	if snapshotID != "" && isUnencrypted && kmsKeyID != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Cannot set kmsKeyId when encrypted is false")
+	}
+	if snapshotID != "" && sourceRegion != "" {
+		snapshotID, err = d.restorableSnapshotID(ctx, snapshotID, sourceRegion, isEncrypted, kmsKeyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// EC2 restores encrypted snapshots only to encrypted volumes, so a volume
+	// that is explicitly not encrypted is refused rather than encrypted anyway.
+	// Unencrypted snapshots are restored to volumes encrypted as requested.
+	if snapshotID != "" && isUnencrypted {
+		snapshot, err := d.cloud.GetSnapshotByID(ctx, snapshotID)
+		if err != nil && !errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.Internal, "Could not get snapshot %q: %v", snapshotID, err)
+		}
+		if snapshot != nil && snapshot.Encrypted {
+			return nil, status.Errorf(codes.InvalidArgument, "Cannot restore encrypted snapshot %q to a volume with encrypted false", requestedSnapshotID)
+		}
+	}
 
 	// create a new volume
-	zone := pickAvailabilityZone(req.GetAccessibilityRequirements())
-	outpostArn := getOutpostArn(req.GetAccessibilityRequirements())
+	zones := d.placer.zones(req.GetAccessibilityRequirements())
+	if colocateWithVolumeID != "" {
+		zone, err := d.colocatedAvailabilityZone(ctx, colocateWithVolumeID, req.GetAccessibilityRequirements())
+		if err != nil {
+			return nil, err
+		}
+		zones = []string{zone}
+	}
+	if topologyOutpostArn := getOutpostArn(req.GetAccessibilityRequirements()); topologyOutpostArn != "" {
+		if outpostArn != "" && outpostArn != topologyOutpostArn {
+			return nil, status.Errorf(codes.InvalidArgument, "Outpost %s of parameter %s does not match Outpost %s of the accessibility requirements", outpostArn, OutpostArnKey, topologyOutpostArn)
+		}
+		outpostArn = topologyOutpostArn
+	}
+	if outpostArn != "" && colocateWithVolumeID == "" {
+		// A volume on an Outpost can only be created in the zone of the Outpost
+		zones = availabilityZones(req.GetAccessibilityRequirements())
+		zones = zones[:min(len(zones), 1)]
+	}
 
 	// fill volume tags
 	if d.driverOptions.kubernetesClusterID != "" {
@@ -305,7 +494,7 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		volumeTags[NameTag] = d.driverOptions.kubernetesClusterID + "-dynamic-" + volName
 		volumeTags[KubernetesClusterTag] = d.driverOptions.kubernetesClusterID
 	}
-	for k, v := range d.driverOptions.extraTags {
+	for k, v := range interpolateExtraTags(d.driverOptions.extraTags, tProps) {
 		volumeTags[k] = v
 	}
 
@@ -318,28 +507,28 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid tag value: %v", err)
 	}
 
-	for k, v := range addTags {
-		volumeTags[k] = v
-	}
+	// StorageClass tags take precedence over the driver's default tags
+	mergeTags(volumeTags, addTags)
 
 	opts := &cloud.DiskOptions{
-		CapacityBytes:          volSizeBytes,
-		Tags:                   volumeTags,
-		VolumeType:             volumeType,
-		IOPSPerGB:              iopsPerGB,
-		AllowIOPSPerGBIncrease: allowIOPSPerGBIncrease,
-		IOPS:                   iops,
-		Throughput:             throughput,
-		AvailabilityZone:       zone,
-		OutpostArn:             outpostArn,
-		Encrypted:              isEncrypted,
-		BlockExpress:           blockExpress,
-		KmsKeyID:               kmsKeyID,
-		SnapshotID:             snapshotID,
-		MultiAttachEnabled:     multiAttach,
-	}
-
-	disk, err := d.cloud.CreateDisk(ctx, volName, opts)
+		CapacityBytes:           volSizeBytes,
+		Tags:                    volumeTags,
+		VolumeType:              volumeType,
+		IOPSPerGB:               iopsPerGB,
+		AllowIOPSPerGBIncrease:  allowIOPSPerGBIncrease,
+		IOPS:                    iops,
+		Throughput:              throughput,
+		OutpostArn:              outpostArn,
+		Encrypted:               isEncrypted,
+		BlockExpress:            blockExpress,
+		KmsKeyID:                kmsKeyID,
+		SnapshotID:              snapshotID,
+		MultiAttachEnabled:      multiAttach,
+		AllowThroughputDecrease: allowThroughputDecrease,
+		DropExcessTags:          d.driverOptions.dropExcessTags,
+	}
+
+	disk, err := d.createDiskInZones(ctx, volName, opts, zones)
 	if err != nil {
 		var errCode codes.Code
 		switch {
@@ -347,12 +536,333 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 			errCode = codes.NotFound
 		case errors.Is(err, cloud.ErrIdempotentParameterMismatch), errors.Is(err, cloud.ErrAlreadyExists):
 			errCode = codes.AlreadyExists
+		case errors.Is(err, cloud.ErrTooManyTags), errors.Is(err, cloud.ErrInvalidVolumeParameters):
+			errCode = codes.InvalidArgument
+		case errors.Is(err, cloud.ErrCapacityOutOfRange):
+			errCode = codes.OutOfRange
+		case errors.Is(err, cloud.ErrVolumeLimitExceeded):
+			d.orphanedVolumes.trigger()
+			return nil, withErrorReason(status.Newf(codes.ResourceExhausted, "Could not create volume %q: the account reached its limit of volumes in the region, delete unused volumes or request a higher limit: %v", volName, err), err).Err()
 		default:
 			errCode = codes.Internal
 		}
-		return nil, status.Errorf(errCode, "Could not create volume %q: %v", volName, err)
+		return nil, withErrorReason(status.Newf(errCode, "Could not create volume %q: %v", volName, err), err).Err()
+	}
+	if baselineSnapshot {
+		go d.createBaselineSnapshot(disk.VolumeID)
+	}
+	// The volume is reported as restored from the requested snapshot rather than from its copy
+	if disk.SnapshotID != "" {
+		disk.SnapshotID = requestedSnapshotID
+	}
+	resp := newCreateVolumeResponse(disk, responseCtx)
+	d.results.Put(resultKey, req, disk.VolumeID, resp)
+	return resp, nil
+}
+
+// restorableSnapshotID returns the ID of the snapshot to restore a volume
+// from: snapshotID if it exists in the region of the driver, else the ID of
+// its copy from sourceRegion, which is made and waited for if needed. The
+// copy is encrypted like the volume.
+func (d *controllerService) restorableSnapshotID(ctx context.Context, snapshotID, sourceRegion string, encrypted bool, kmsKeyID string) (string, error) {
+	logger := klog.FromContext(ctx)
+	_, err := d.cloud.GetSnapshotByID(ctx, snapshotID)
+	if err == nil {
+		return snapshotID, nil
+	}
+	if !errors.Is(err, cloud.ErrNotFound) {
+		return "", status.Errorf(codes.Internal, "Could not get snapshot %q: %v", snapshotID, err)
+	}
+
+	snapshotTags := map[string]string{
+		cloud.AwsEbsDriverTagKey: isManagedByDriver,
+	}
+	if d.driverOptions.kubernetesClusterID != "" {
+		resourceLifecycleTag := ResourceLifecycleTagPrefix + d.driverOptions.kubernetesClusterID
+		snapshotTags[resourceLifecycleTag] = ResourceLifecycleOwned
+		snapshotTags[NameTag] = d.driverOptions.kubernetesClusterID + "-copy-" + snapshotID
+	}
+	for k, v := range interpolateExtraTags(d.driverOptions.extraTags, nil) {
+		snapshotTags[k] = v
+	}
+
+	logger.V(4).Info("CreateVolume: snapshot not found in region, restoring from its copy", "snapshotID", snapshotID, "sourceRegion", sourceRegion)
+	snapshot, err := d.cloud.CopySnapshotFromRegion(ctx, snapshotID, sourceRegion, &cloud.CopySnapshotOptions{
+		Tags:      snapshotTags,
+		Encrypted: encrypted,
+		KmsKeyID:  kmsKeyID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, cloud.ErrNotFound):
+			return "", status.Errorf(codes.NotFound, "Snapshot %q not found in region %s", snapshotID, sourceRegion)
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			return "", status.Errorf(codes.DeadlineExceeded, "Snapshot %q is still being copied from region %s: %v", snapshotID, sourceRegion, err)
+		}
+		return "", status.Errorf(codes.Internal, "Could not copy snapshot %q from region %s: %v", snapshotID, sourceRegion, err)
+	}
+	return snapshot.SnapshotID, nil
+}
+
+// createDiskRetryInterval is the time between CreateDisk attempts.
+var createDiskRetryInterval = 2 * time.Second
+
+// createDiskErrorClass tells whether and how CreateDisk may be retried after an error.
+type createDiskErrorClass int
+
+const (
+	// createDiskErrorPermanent errors fail again on retry, e.g. invalid parameters.
+	createDiskErrorPermanent createDiskErrorClass = iota
+	// createDiskErrorSafe errors are returned when EC2 rejected the request
+	// without creating a volume, so it can be retried right away.
+	createDiskErrorSafe
+	// createDiskErrorUnsafe errors leave unknown whether EC2 created a volume,
+	// or whether it is still usable, e.g. timeouts, server errors or a volume
+	// that did not become available and was deleted, so a retry must first look
+	// it up.
+	createDiskErrorUnsafe
+)
+
+// classifyCreateDiskError returns whether and how CreateDisk may be retried after err.
+func classifyCreateDiskError(err error) createDiskErrorClass {
+	switch {
+	case errors.Is(err, cloud.ErrNotFound), errors.Is(err, cloud.ErrIdempotentParameterMismatch),
+		errors.Is(err, cloud.ErrAlreadyExists), errors.Is(err, cloud.ErrTooManyTags),
+		errors.Is(err, cloud.ErrInvalidVolumeParameters), errors.Is(err, cloud.ErrCapacityOutOfRange),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return createDiskErrorPermanent
+	case errors.Is(err, cloud.ErrVolumeNotAvailable):
+		return createDiskErrorUnsafe
+	}
+	switch reason, _ := cloud.ErrorReason(err); reason {
+	case cloud.ErrorReasonThrottling, cloud.ErrorReasonCapacity:
+		return createDiskErrorSafe
+	case cloud.ErrorReasonQuota, cloud.ErrorReasonValidation, cloud.ErrorReasonKMS:
+		return createDiskErrorPermanent
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return createDiskErrorUnsafe
+	}
+	// Other client errors are returned when EC2 rejected the request, while
+	// server errors and errors without response leave it unknown
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) && requestErr.StatusCode() < http.StatusInternalServerError {
+		return createDiskErrorSafe
+	}
+	return createDiskErrorUnsafe
+}
+
+// createDisk creates volume volName, retrying failed attempts up to
+// createVolumeRetries times. Before retrying after an error that leaves
+// unknown whether a volume was created, it looks the volume up by name: an
+// available volume is returned rather than created again, and a volume that
+// was deleted, e.g. because it did not become available, is created again with
+// another client token, since EC2 returns the deleted volume for the same one.
+func (d *controllerService) createDisk(ctx context.Context, volName string, opts *cloud.DiskOptions) (*cloud.Disk, error) {
+	logger := klog.FromContext(ctx)
+	disk, err := d.cloud.CreateDisk(ctx, volName, opts)
+	for attempt := 1; err != nil && attempt <= d.driverOptions.createVolumeRetries; attempt++ {
+		switch classifyCreateDiskError(err) {
+		case createDiskErrorPermanent:
+			return nil, err
+		case createDiskErrorUnsafe:
+			existing, lookupErr := d.cloud.GetDiskByName(ctx, volName, opts.CapacityBytes)
+			switch {
+			case lookupErr == nil && (existing.State == ec2.VolumeStateAvailable || existing.State == ec2.VolumeStateInUse):
+				logger.Info("CreateVolume: found volume created by failed attempt", "volumeName", volName, "volumeID", existing.VolumeID, "err", err)
+				return existing, nil
+			case lookupErr == nil && existing.State == ec2.VolumeStateCreating:
+				// CreateDisk waits for the volume of the same client token
+			case lookupErr == nil, errors.Is(lookupErr, cloud.ErrNotFound) && errors.Is(err, cloud.ErrVolumeNotAvailable):
+				logger.Info("CreateVolume: volume of failed attempt was deleted, creating another one", "volumeName", volName, "err", err)
+				opts.RetryAttempt = attempt
+			case errors.Is(lookupErr, cloud.ErrNotFound):
+				// The same client token does not create a duplicate if
+				// the failed attempt created a volume after all
+			default:
+				logger.Error(lookupErr, "CreateVolume: could not look up volume of failed attempt, not retrying", "volumeName", volName)
+				return nil, err
+			}
+		}
+
+		logger.Info("CreateVolume: retrying failed attempt", "volumeName", volName, "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(createDiskRetryInterval):
+		}
+		disk, err = d.cloud.CreateDisk(ctx, volName, opts)
+	}
+	return disk, err
+}
+
+// createDiskInZones creates volume volName in the first of zones that EC2
+// has the capacity for, trying the next zone after an insufficient capacity
+// error. EC2 chooses the zone if zones is empty.
+func (d *controllerService) createDiskInZones(ctx context.Context, volName string, opts *cloud.DiskOptions, zones []string) (*cloud.Disk, error) {
+	logger := klog.FromContext(ctx)
+	if len(zones) == 0 {
+		zones = []string{""}
+	}
+
+	var disk *cloud.Disk
+	var err error
+	for i, zone := range zones {
+		if opts.SnapshotID != "" && zone != "" && d.driverOptions.fsrWaitTimeout > 0 {
+			d.waitForFastSnapshotRestore(ctx, opts.SnapshotID, zone)
+		}
+		opts.AvailabilityZone = zone
+		disk, err = d.createDisk(ctx, volName, opts)
+		if errors.Is(err, cloud.ErrIdempotentParameterMismatch) && len(zones) > 1 {
+			// A previous request for the volume may have created it in
+			// another of the zones, e.g. after trying this one
+			existing, lookupErr := d.cloud.GetDiskByName(ctx, volName, opts.CapacityBytes)
+			if lookupErr == nil && slices.Contains(zones, existing.AvailabilityZone) {
+				logger.V(4).Info("CreateVolume: found volume created by a previous request", "volumeName", volName, "volumeID", existing.VolumeID, "availabilityZone", existing.AvailabilityZone)
+				disk, err = existing, nil
+			}
+		}
+		if reason, _ := cloud.ErrorReason(err); reason != cloud.ErrorReasonCapacity || i == len(zones)-1 {
+			break
+		}
+		logger.Info("CreateVolume: insufficient capacity in availability zone, trying the next one", "volumeName", volName, "availabilityZone", zone, "nextAvailabilityZone", zones[i+1], "err", err)
+	}
+	if err == nil {
+		d.placer.placed(disk.AvailabilityZone)
 	}
-	return newCreateVolumeResponse(disk, responseCtx), nil
+	return disk, err
+}
+
+// fsrWaitPollInterval is the time between checks of the fast snapshot restore
+// state of a snapshot that CreateVolume waits for.
+var fsrWaitPollInterval = 5 * time.Second
+
+// waitForFastSnapshotRestore waits up to fsrWaitTimeout for fast snapshot
+// restores (FSR) of snapshotID to be enabled in zone, so that the volume
+// restored from it is fully initialized at creation. It returns right away if
+// FSR is not being enabled in zone, as waiting would not help. The volume is
+// restored either way, without FSR if it is not enabled in time. It returns
+// whether FSR is enabled.
+func (d *controllerService) waitForFastSnapshotRestore(ctx context.Context, snapshotID, zone string) bool {
+	logger := klog.FromContext(ctx)
+	var state string
+	err := wait.PollUntilContextTimeout(ctx, fsrWaitPollInterval, d.driverOptions.fsrWaitTimeout, true, func(ctx context.Context) (bool, error) {
+		var err error
+		state, err = d.cloud.GetFastSnapshotRestoreState(ctx, snapshotID, zone)
+		if err != nil {
+			return false, err
+		}
+		switch state {
+		case ec2.FastSnapshotRestoreStateCodeEnabling, ec2.FastSnapshotRestoreStateCodeOptimizing:
+			logger.V(4).Info("CreateVolume: waiting for fast snapshot restores to be enabled", "snapshotID", snapshotID, "availabilityZone", zone, "state", state)
+			return false, nil
+		default:
+			return true, nil
+		}
+	})
+	switch {
+	case err != nil:
+		logger.Info("CreateVolume: fast snapshot restores not enabled in time, restoring volume without them", "snapshotID", snapshotID, "availabilityZone", zone, "state", state, "err", err)
+		return false
+	case state == ec2.FastSnapshotRestoreStateCodeEnabled:
+		logger.V(4).Info("CreateVolume: restoring volume with fast snapshot restores", "snapshotID", snapshotID, "availabilityZone", zone)
+		return true
+	default:
+		logger.V(4).Info("CreateVolume: fast snapshot restores not enabled, restoring volume without them", "snapshotID", snapshotID, "availabilityZone", zone, "state", state)
+		return false
+	}
+}
+
+// baselineSnapshotTimeout bounds the creation of a baseline snapshot, which
+// runs after CreateVolume returned.
+const baselineSnapshotTimeout = 5 * time.Minute
+
+// createBaselineSnapshot takes a snapshot of the newly created volume volumeID,
+// tagged with cloud.BaselineSnapshotTagKey, unless it already has one, e.g.
+// because CreateVolume was retried. Failures are logged only, as the volume is
+// usable without a baseline snapshot.
+func (d *controllerService) createBaselineSnapshot(volumeID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), baselineSnapshotTimeout)
+	defer cancel()
+
+	existing, err := d.cloud.GetSnapshotIDsByTag(ctx, cloud.BaselineSnapshotTagKey, volumeID)
+	if err != nil {
+		klog.ErrorS(err, "Could not look up baseline snapshot of volume", "volumeID", volumeID)
+		return
+	}
+	if len(existing) > 0 {
+		klog.V(4).InfoS("Baseline snapshot of volume already exists; nothing to do", "volumeID", volumeID, "snapshotIDs", existing)
+		return
+	}
+
+	snapshotTags := map[string]string{
+		cloud.BaselineSnapshotTagKey: volumeID,
+		cloud.AwsEbsDriverTagKey:     isManagedByDriver,
+	}
+	if d.driverOptions.kubernetesClusterID != "" {
+		resourceLifecycleTag := ResourceLifecycleTagPrefix + d.driverOptions.kubernetesClusterID
+		snapshotTags[resourceLifecycleTag] = ResourceLifecycleOwned
+		snapshotTags[NameTag] = d.driverOptions.kubernetesClusterID + "-baseline-" + volumeID
+	}
+	for k, v := range interpolateExtraTags(d.driverOptions.extraTags, nil) {
+		snapshotTags[k] = v
+	}
+
+	snapshot, err := d.cloud.CreateSnapshot(ctx, volumeID, &cloud.SnapshotOptions{Tags: snapshotTags})
+	if err != nil {
+		klog.ErrorS(err, "Could not create baseline snapshot of volume", "volumeID", volumeID)
+		return
+	}
+	klog.InfoS("Created baseline snapshot of volume", "volumeID", volumeID, "snapshotID", snapshot.SnapshotID)
+}
+
+// withErrorReason attaches an ErrorInfo detail with the class of the AWS error
+// err to st, so that COs can tell e.g. quota and throttling failures apart.
+// st is returned unchanged if the error cannot be classified.
+func withErrorReason(st *status.Status, err error) *status.Status {
+	reason, awsCode := cloud.ErrorReason(err)
+	if reason == "" {
+		return st
+	}
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   DriverName,
+		Metadata: map[string]string{"awsErrorCode": awsCode},
+	})
+	if detailErr != nil {
+		klog.ErrorS(detailErr, "Could not attach error reason to status", "reason", reason)
+		return st
+	}
+	return detailed
+}
+
+// colocatedAvailabilityZone returns the availability zone of volumeID, in
+// which a volume co-located with it must be created. The zone must satisfy the
+// requisite topology of requirement, if any.
+func (d *controllerService) colocatedAvailabilityZone(ctx context.Context, volumeID string, requirement *csi.TopologyRequirement) (string, error) {
+	logger := klog.FromContext(ctx)
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
+		if errors.Is(err, cloud.ErrNotFound) {
+			return "", status.Errorf(codes.NotFound, "Volume %q to co-locate with not found", volumeID)
+		}
+		return "", status.Errorf(codes.Internal, "Could not get volume %q to co-locate with: %v", volumeID, err)
+	}
+
+	requisiteZones := map[string]struct{}{}
+	for _, topology := range requirement.GetRequisite() {
+		if zone, exists := topology.GetSegments()[WellKnownTopologyKey]; exists {
+			requisiteZones[zone] = struct{}{}
+		} else if zone, exists := topology.GetSegments()[TopologyKey]; exists {
+			requisiteZones[zone] = struct{}{}
+		}
+	}
+	if _, ok := requisiteZones[disk.AvailabilityZone]; len(requisiteZones) > 0 && !ok {
+		return "", status.Errorf(codes.ResourceExhausted, "Availability zone %s of volume %q to co-locate with does not satisfy the requisite topology", disk.AvailabilityZone, volumeID)
+	}
+	logger.V(4).Info("CreateVolume: co-locating with volume", "volumeID", volumeID, "availabilityZone", disk.AvailabilityZone)
+	return disk.AvailabilityZone, nil
 }
 
 func validateCreateVolumeRequest(req *csi.CreateVolumeRequest) error {
@@ -373,7 +883,8 @@ func validateCreateVolumeRequest(req *csi.CreateVolumeRequest) error {
 }
 
 func (d *controllerService) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
-	klog.V(4).InfoS("DeleteVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("DeleteVolume: called", "args", req)
 	if err := validateDeleteVolumeRequest(req); err != nil {
 		return nil, err
 	}
@@ -386,14 +897,21 @@ func (d *controllerService) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 	}
 	defer d.inFlight.Delete(volumeID)
 
+	resultKey := "DeleteVolume/" + volumeID
+	if _, ok := d.results.Get(resultKey, req); ok {
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
 	if _, err := d.cloud.DeleteDisk(ctx, volumeID); err != nil {
-		if errors.Is(err, cloud.ErrNotFound) {
-			klog.V(4).InfoS("DeleteVolume: volume not found, returning with success")
-			return &csi.DeleteVolumeResponse{}, nil
+		if !errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.Internal, "Could not delete volume ID %q: %v", volumeID, err)
 		}
-		return nil, status.Errorf(codes.Internal, "Could not delete volume ID %q: %v", volumeID, err)
+		logger.V(4).Info("DeleteVolume: volume not found, returning with success")
 	}
 
+	// The results of creating the volume must not outlive it
+	d.results.Forget(volumeID)
+	d.results.Put(resultKey, req, volumeID, struct{}{})
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
@@ -405,7 +923,8 @@ func validateDeleteVolumeRequest(req *csi.DeleteVolumeRequest) error {
 }
 
 func (d *controllerService) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
-	klog.V(4).InfoS("ControllerPublishVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ControllerPublishVolume: called", "args", req)
 	if err := validateControllerPublishVolumeRequest(req); err != nil {
 		return nil, err
 	}
@@ -413,21 +932,73 @@ func (d *controllerService) ControllerPublishVolume(ctx context.Context, req *cs
 	volumeID := req.GetVolumeId()
 	nodeID := req.GetNodeId()
 
+	ctx, done, ok := d.shutdown.begin(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unavailable, "Controller is shutting down, not accepting requests for volume %q", volumeID)
+	}
+	defer done()
+
 	if !d.inFlight.Insert(volumeID + nodeID) {
 		return nil, status.Error(codes.Aborted, fmt.Sprintf(internal.VolumeOperationAlreadyExistsErrorMsg, volumeID))
 	}
 	defer d.inFlight.Delete(volumeID + nodeID)
 
-	klog.V(2).InfoS("ControllerPublishVolume: attaching", "volumeID", volumeID, "nodeID", nodeID)
+	if d.driverOptions.kmsAccessCheck {
+		if err := d.cloud.CheckKMSKeyAccess(ctx, volumeID, nodeID); err != nil {
+			switch {
+			case errors.Is(err, cloud.ErrKMSKeyAccessDenied):
+				return nil, status.Errorf(codes.PermissionDenied, "Node %q cannot use the KMS key of volume %q: %v", nodeID, volumeID, err)
+			case errors.Is(err, cloud.ErrNotFound):
+				return nil, status.Errorf(codes.NotFound, "Volume %q or node %q not found", volumeID, nodeID)
+			case d.driverOptions.kmsAccessCheckFailOpen:
+				klog.FromContext(ctx).Error(err, "Could not verify KMS key access, attaching anyway", "volumeID", volumeID, "nodeID", nodeID)
+			default:
+				// The access is not settled, e.g. on throttling or timeouts, so
+				// the CO retries rather than attaching an unusable volume
+				return nil, status.Errorf(codes.Unavailable, "Could not verify that node %q can use the KMS key of volume %q: %v", nodeID, volumeID, err)
+			}
+		}
+	}
+
+	if err := d.attachBudget.check(volumeID); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Not attaching volume %q to node %q: %v", volumeID, nodeID, err)
+	}
+
+	op := trackVolumeOperation(volumeOperationAttach)
+	defer op.done()
+
+	releaseNode, err := d.nodeOperations.acquire(ctx, nodeID)
+	if err != nil {
+		return nil, status.Errorf(status.FromContextError(err).Code(), "Could not attach volume %q to node %q while waiting for other operations on the node: %v", volumeID, nodeID, err)
+	}
+	defer releaseNode()
+
+	release, err := d.attachLimiter.acquire(ctx)
+	if err != nil {
+		return nil, status.Errorf(status.FromContextError(err).Code(), "Could not attach volume %q to node %q while waiting for other attachments: %v", volumeID, nodeID, err)
+	}
+	defer release()
+
+	logger.V(2).Info("ControllerPublishVolume: attaching", "volumeID", volumeID, "nodeID", nodeID)
+	op.start()
 	devicePath, err := d.cloud.AttachDisk(ctx, volumeID, nodeID)
 	if err != nil {
+		if isAWSAttachFailure(err) {
+			d.attachBudget.failed(volumeID)
+		} else {
+			logger.V(4).Info("ControllerPublishVolume: attach failure not counted against attach budget", "volumeID", volumeID, "nodeID", nodeID, "err", err)
+		}
 		if errors.Is(err, cloud.ErrNotFound) {
-			klog.InfoS("ControllerPublishVolume: volume not found", "volumeID", volumeID, "nodeID", nodeID)
+			logger.Info("ControllerPublishVolume: volume not found", "volumeID", volumeID, "nodeID", nodeID)
 			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
 		}
+		if errors.Is(err, cloud.ErrVolumeInUse) {
+			return nil, status.Errorf(codes.FailedPrecondition, "Could not attach volume %q to node %q: volume is attached to another node and not multi-attach enabled: %v", volumeID, nodeID, err)
+		}
 		return nil, status.Errorf(codes.Internal, "Could not attach volume %q to node %q: %v", volumeID, nodeID, err)
 	}
-	klog.InfoS("ControllerPublishVolume: attached", "volumeID", volumeID, "nodeID", nodeID, "devicePath", devicePath)
+	d.attachBudget.succeeded(volumeID)
+	logger.Info("ControllerPublishVolume: attached", "volumeID", volumeID, "nodeID", nodeID, "devicePath", devicePath)
 
 	pvInfo := map[string]string{DevicePathKey: devicePath}
 	return &csi.ControllerPublishVolumeResponse{PublishContext: pvInfo}, nil
@@ -454,7 +1025,8 @@ func validateControllerPublishVolumeRequest(req *csi.ControllerPublishVolumeRequ
 }
 
 func (d *controllerService) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
-	klog.V(4).InfoS("ControllerUnpublishVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ControllerUnpublishVolume: called", "args", req)
 
 	if err := validateControllerUnpublishVolumeRequest(req); err != nil {
 		return nil, err
@@ -463,20 +1035,37 @@ func (d *controllerService) ControllerUnpublishVolume(ctx context.Context, req *
 	volumeID := req.GetVolumeId()
 	nodeID := req.GetNodeId()
 
+	ctx, done, ok := d.shutdown.begin(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unavailable, "Controller is shutting down, not accepting requests for volume %q", volumeID)
+	}
+	defer done()
+
 	if !d.inFlight.Insert(volumeID + nodeID) {
 		return nil, status.Error(codes.Aborted, fmt.Sprintf(internal.VolumeOperationAlreadyExistsErrorMsg, volumeID))
 	}
 	defer d.inFlight.Delete(volumeID + nodeID)
 
-	klog.V(2).InfoS("ControllerUnpublishVolume: detaching", "volumeID", volumeID, "nodeID", nodeID)
-	if err := d.cloud.DetachDisk(ctx, volumeID, nodeID); err != nil {
+	op := trackVolumeOperation(volumeOperationDetach)
+	defer op.done()
+
+	releaseNode, err := d.nodeOperations.acquire(ctx, nodeID)
+	if err != nil {
+		return nil, status.Errorf(status.FromContextError(err).Code(), "Could not detach volume %q from node %q while waiting for other operations on the node: %v", volumeID, nodeID, err)
+	}
+	defer releaseNode()
+
+	logger.V(2).Info("ControllerUnpublishVolume: detaching", "volumeID", volumeID, "nodeID", nodeID)
+	op.start()
+	err = d.cloud.DetachDisk(ctx, volumeID, nodeID)
+	if err != nil {
 		if errors.Is(err, cloud.ErrNotFound) {
-			klog.InfoS("ControllerUnpublishVolume: attachment not found", "volumeID", volumeID, "nodeID", nodeID)
+			logger.Info("ControllerUnpublishVolume: attachment not found", "volumeID", volumeID, "nodeID", nodeID)
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 		return nil, status.Errorf(codes.Internal, "Could not detach volume %q from node %q: %v", volumeID, nodeID, err)
 	}
-	klog.InfoS("ControllerUnpublishVolume: detached", "volumeID", volumeID, "nodeID", nodeID)
+	logger.Info("ControllerUnpublishVolume: detached", "volumeID", volumeID, "nodeID", nodeID)
 
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
@@ -494,7 +1083,8 @@ func validateControllerUnpublishVolumeRequest(req *csi.ControllerUnpublishVolume
 }
 
 func (d *controllerService) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
-	klog.V(4).InfoS("ControllerGetCapabilities: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ControllerGetCapabilities: called", "args", req)
 	var caps []*csi.ControllerServiceCapability
 	for _, cap := range controllerCaps {
 		c := &csi.ControllerServiceCapability{
@@ -510,17 +1100,81 @@ func (d *controllerService) ControllerGetCapabilities(ctx context.Context, req *
 }
 
 func (d *controllerService) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	klog.V(4).InfoS("GetCapacity: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("GetCapacity: called", "args", req)
 	return nil, status.Error(codes.Unimplemented, "")
 }
 
 func (d *controllerService) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	klog.V(4).InfoS("ListVolumes: called", "args", *req)
-	return nil, status.Error(codes.Unimplemented, "")
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ListVolumes: called", "args", req)
+	disks, err := d.cloud.ListDisks(ctx, int64(req.GetMaxEntries()), req.GetStartingToken())
+	if err != nil {
+		switch {
+		case errors.Is(err, cloud.ErrInvalidMaxResults):
+			return nil, status.Errorf(codes.InvalidArgument, "Error mapping MaxEntries to AWS MaxResults: %v", err)
+		case errors.Is(err, cloud.ErrInvalidNextToken):
+			return nil, status.Errorf(codes.Aborted, "Invalid starting token %q: %v", req.GetStartingToken(), err)
+		default:
+			return nil, status.Errorf(codes.Internal, "Could not list volumes: %v", err)
+		}
+	}
+
+	listed, statuses, err := d.getListedVolumeStatuses(ctx, disks.Disks)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get status of volumes: %v", err)
+	}
+
+	entries := make([]*csi.ListVolumesResponse_Entry, 0, len(listed))
+	for _, disk := range listed {
+		entries = append(entries, &csi.ListVolumesResponse_Entry{
+			Volume: newVolume(disk),
+			Status: &csi.ListVolumesResponse_VolumeStatus{
+				PublishedNodeIds: disk.Attachments,
+				VolumeCondition:  newVolumeCondition(disk, statuses[disk.VolumeID]),
+			},
+		})
+	}
+	return &csi.ListVolumesResponse{
+		Entries:   entries,
+		NextToken: disks.NextToken,
+	}, nil
+}
+
+// getListedVolumeStatuses returns the disks of disks that still exist and
+// their statuses. DescribeVolumeStatus fails as a whole if one of the volumes
+// was deleted since it was listed, so the statuses are then retrieved one
+// volume at a time and the deleted volumes are left out.
+func (d *controllerService) getListedVolumeStatuses(ctx context.Context, disks []*cloud.Disk) ([]*cloud.Disk, map[string]*cloud.VolumeStatus, error) {
+	volumeIDs := make([]string, 0, len(disks))
+	for _, disk := range disks {
+		volumeIDs = append(volumeIDs, disk.VolumeID)
+	}
+	statuses, err := d.cloud.GetVolumeStatuses(ctx, volumeIDs)
+	if !errors.Is(err, cloud.ErrNotFound) {
+		return disks, statuses, err
+	}
+
+	klog.FromContext(ctx).V(4).Info("ListVolumes: volumes were deleted since they were listed, getting their statuses one at a time")
+	existing := make([]*cloud.Disk, 0, len(disks))
+	statuses = make(map[string]*cloud.VolumeStatus, len(disks))
+	for _, disk := range disks {
+		volumeStatuses, err := d.cloud.GetVolumeStatuses(ctx, []string{disk.VolumeID})
+		if errors.Is(err, cloud.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		existing = append(existing, disk)
+		statuses[disk.VolumeID] = volumeStatuses[disk.VolumeID]
+	}
+	return existing, statuses, nil
 }
 
 func (d *controllerService) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
-	klog.V(4).InfoS("ValidateVolumeCapabilities: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ValidateVolumeCapabilities: called", "args", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -531,15 +1185,20 @@ func (d *controllerService) ValidateVolumeCapabilities(ctx context.Context, req
 		return nil, status.Error(codes.InvalidArgument, "Volume capabilities not provided")
 	}
 
-	if _, err := d.cloud.GetDiskByID(ctx, volumeID); err != nil {
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
 		if errors.Is(err, cloud.ErrNotFound) {
 			return nil, status.Error(codes.NotFound, "Volume not found")
 		}
 		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
 	}
 
+	if err := validateVolumeCapabilityMountOptions(volCaps); err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{Message: err.Error()}, nil
+	}
+
 	var confirmed *csi.ValidateVolumeCapabilitiesResponse_Confirmed
-	if isValidVolumeCapabilities(volCaps) {
+	if isValidVolumeCapabilities(volCaps) && (disk.MultiAttachEnabled || !hasMultiNodeAccessMode(volCaps)) {
 		confirmed = &csi.ValidateVolumeCapabilitiesResponse_Confirmed{VolumeCapabilities: volCaps}
 	}
 	return &csi.ValidateVolumeCapabilitiesResponse{
@@ -548,7 +1207,8 @@ func (d *controllerService) ValidateVolumeCapabilities(ctx context.Context, req
 }
 
 func (d *controllerService) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
-	klog.V(4).InfoS("ControllerExpandVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ControllerExpandVolume: called", "args", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -601,8 +1261,59 @@ func (d *controllerService) ControllerExpandVolume(ctx context.Context, req *csi
 }
 
 func (d *controllerService) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
-	klog.V(4).InfoS("ControllerGetVolume: called", "args", *req)
-	return nil, status.Error(codes.Unimplemented, "")
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ControllerGetVolume: called", "args", req)
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
+		if errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
+	}
+	statuses, err := d.cloud.GetVolumeStatuses(ctx, []string{volumeID})
+	if err != nil {
+		if errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get status of volume %q: %v", volumeID, err)
+	}
+
+	return &csi.ControllerGetVolumeResponse{
+		Volume: newVolume(disk),
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			PublishedNodeIds: disk.Attachments,
+			VolumeCondition:  newVolumeCondition(disk, statuses[volumeID]),
+		},
+	}, nil
+}
+
+// newVolumeCondition reports a volume as abnormal if it is in the error
+// state, or if EC2 reports its status checks as impaired or its I/O
+// performance as degraded, e.g. because I/O to it stalled.
+func newVolumeCondition(disk *cloud.Disk, volumeStatus *cloud.VolumeStatus) *csi.VolumeCondition {
+	if disk.State == ec2.VolumeStateError {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  "Volume is in the error state, its data may be lost",
+		}
+	}
+	if volumeStatus == nil {
+		return &csi.VolumeCondition{Message: "Volume status is not available"}
+	}
+	msg := "Volume status is " + volumeStatus.Status
+	if len(volumeStatus.Problems) > 0 {
+		msg += ": " + strings.Join(volumeStatus.Problems, ", ")
+	}
+	return &csi.VolumeCondition{
+		// EC2 reports degraded I/O performance as warning, which the SDK lacks a constant for
+		Abnormal: volumeStatus.Status == ec2.VolumeStatusInfoStatusImpaired || volumeStatus.Status == "warning",
+		Message:  msg,
+	}
 }
 
 func isValidVolumeCapabilities(v []*csi.VolumeCapability) bool {
@@ -636,6 +1347,17 @@ func isValidCapability(c *csi.VolumeCapability) bool {
 	}
 }
 
+// hasMultiNodeAccessMode returns true if any of caps allows the volume to be
+// attached to several nodes at once, which requires a multi-attach enabled volume.
+func hasMultiNodeAccessMode(caps []*csi.VolumeCapability) bool {
+	for _, c := range caps {
+		if c.GetAccessMode().GetMode() == MultiNodeMultiWriter {
+			return true
+		}
+	}
+	return false
+}
+
 func isBlock(cap *csi.VolumeCapability) bool {
 	_, isBlock := cap.GetAccessType().(*csi.VolumeCapability_Block)
 	return isBlock
@@ -655,11 +1377,23 @@ func isValidVolumeContext(volContext map[string]string) bool {
 			return false
 		}
 	}
+	if size, ok := volContext[VolumeAttributeSizeBytes]; ok {
+		sizeInt, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			klog.ErrorS(err, "failed to parse volume size as int", "sizeBytes", size)
+			return false
+		}
+		if sizeInt <= 0 {
+			klog.ErrorS(err, "invalid volume size", "sizeBytes", size)
+			return false
+		}
+	}
 	return true
 }
 
 func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
-	klog.V(4).InfoS("CreateSnapshot: called", "args", req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("CreateSnapshot: called", "args", req)
 	if err := validateCreateSnapshotRequest(req); err != nil {
 		return nil, err
 	}
@@ -674,17 +1408,9 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 	}
 	defer d.inFlight.Delete(snapshotName)
 
-	snapshot, err := d.cloud.GetSnapshotByName(ctx, snapshotName)
-	if err != nil && !errors.Is(err, cloud.ErrNotFound) {
-		klog.ErrorS(err, "Error looking for the snapshot", "snapshotName", snapshotName)
-		return nil, err
-	}
-	if snapshot != nil {
-		if snapshot.SourceVolumeID != volumeID {
-			return nil, status.Errorf(codes.AlreadyExists, "Snapshot %s already exists for different volume (%s)", snapshotName, snapshot.SourceVolumeID)
-		}
-		klog.V(4).InfoS("Snapshot of volume already exists; nothing to do", "snapshotName", snapshotName, "volumeId", volumeID)
-		return newCreateSnapshotResponse(snapshot)
+	resultKey := "CreateSnapshot/" + snapshotName
+	if result, ok := d.results.Get(resultKey, req); ok {
+		return result.(*csi.CreateSnapshotResponse), nil
 	}
 
 	snapshotTags := map[string]string{
@@ -694,6 +1420,8 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 
 	var vscTags []string
 	var fsrAvailabilityZones []string
+	var pvcName string
+	var outpostArn string
 	vsProps := new(template.VolumeSnapshotProps)
 	for key, value := range req.GetParameters() {
 		switch strings.ToLower(key) {
@@ -706,6 +1434,13 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		case FastSnapshotRestoreAvailabilityZones:
 			f := strings.ReplaceAll(value, " ", "")
 			fsrAvailabilityZones = strings.Split(f, ",")
+		case PVCNameKey:
+			pvcName = value
+		case OutpostArnKey:
+			if !isOutpostArn(value) {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter value %s for %s: not an Outpost ARN", value, key)
+			}
+			outpostArn = value
 		default:
 			if strings.HasPrefix(key, TagKeyPrefix) {
 				vscTags = append(vscTags, value)
@@ -715,6 +1450,23 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		}
 	}
 
+	snapshot, err := d.cloud.GetSnapshotByName(ctx, snapshotName)
+	if err != nil && !errors.Is(err, cloud.ErrNotFound) {
+		logger.Error(err, "Error looking for the snapshot", "snapshotName", snapshotName)
+		return nil, err
+	}
+	if snapshot != nil {
+		if snapshot.SourceVolumeID != volumeID {
+			return nil, status.Errorf(codes.AlreadyExists, "Snapshot %s already exists for different volume (%s)", snapshotName, snapshot.SourceVolumeID)
+		}
+		logger.V(4).Info("Snapshot of volume already exists; nothing to do", "snapshotName", snapshotName, "volumeId", volumeID)
+		resp, err := d.newCreateSnapshotResponseWithFSR(ctx, snapshot, fsrAvailabilityZones)
+		if err == nil {
+			d.putCreateSnapshotResult(resultKey, req, resp)
+		}
+		return resp, err
+	}
+
 	addTags, err := template.Evaluate(vscTags, vsProps, d.driverOptions.warnOnInvalidTag)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "Error interpolating the tag value: %v", err)
@@ -724,30 +1476,37 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid tag value: %v", err)
 	}
 
+	if pvcName == "" && d.driverOptions.snapshotPVCNameTag {
+		pvcName = d.getSourceVolumePVCName(ctx, volumeID)
+	}
+	if pvcName != "" {
+		snapshotTags[PVCNameTag] = pvcName
+	}
+
 	if d.driverOptions.kubernetesClusterID != "" {
 		resourceLifecycleTag := ResourceLifecycleTagPrefix + d.driverOptions.kubernetesClusterID
 		snapshotTags[resourceLifecycleTag] = ResourceLifecycleOwned
 		snapshotTags[NameTag] = d.driverOptions.kubernetesClusterID + "-dynamic-" + snapshotName
 	}
-	for k, v := range d.driverOptions.extraTags {
+	for k, v := range interpolateExtraTags(d.driverOptions.extraTags, vsProps) {
 		snapshotTags[k] = v
 	}
 
-	for k, v := range addTags {
-		snapshotTags[k] = v
-	}
+	// VolumeSnapshotClass tags take precedence over the driver's default tags
+	mergeTags(snapshotTags, addTags)
 
 	opts := &cloud.SnapshotOptions{
-		Tags: snapshotTags,
+		Tags:       snapshotTags,
+		OutpostArn: outpostArn,
 	}
 
 	// Check if the availability zone is supported for fast snapshot restore
 	if len(fsrAvailabilityZones) > 0 {
 		zones, error := d.cloud.AvailabilityZones(ctx)
 		if error != nil {
-			klog.ErrorS(error, "failed to get availability zones")
+			logger.Error(error, "failed to get availability zones")
 		} else {
-			klog.V(4).InfoS("Availability Zones", "zone", zones)
+			logger.V(4).Info("Availability Zones", "zone", zones)
 			for _, az := range fsrAvailabilityZones {
 				if _, ok := zones[az]; !ok {
 					return nil, status.Errorf(codes.InvalidArgument, "Availability zone %s is not supported for fast snapshot restore", az)
@@ -773,6 +1532,48 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 			return nil, status.Errorf(codes.Internal, "Failed to create Fast Snapshot Restores for snapshot ID %q: %v", snapshotName, err)
 		}
 	}
+	resp, err := d.newCreateSnapshotResponseWithFSR(ctx, snapshot, fsrAvailabilityZones)
+	if err == nil {
+		d.putCreateSnapshotResult(resultKey, req, resp)
+	}
+	return resp, err
+}
+
+// putCreateSnapshotResult caches the response of CreateSnapshot once the
+// snapshot is ready to use, as the snapshotter keeps calling CreateSnapshot
+// until then to learn when it is.
+func (d *controllerService) putCreateSnapshotResult(key string, req *csi.CreateSnapshotRequest, resp *csi.CreateSnapshotResponse) {
+	if resp.GetSnapshot().GetReadyToUse() {
+		d.results.Put(key, req, resp.GetSnapshot().GetSnapshotId(), resp)
+	}
+}
+
+// newCreateSnapshotResponseWithFSR returns the response of CreateSnapshot for
+// snapshot, which is only ready to use once fast snapshot restores (FSR)
+// requested in fsrAvailabilityZones are optimizing or enabled in each of them.
+// Volumes restored from the snapshot then avoid the first-read latency of
+// uninitialized blocks. EC2 only starts enabling FSR once the snapshot
+// completed, so the snapshotter keeps calling CreateSnapshot until then.
+func (d *controllerService) newCreateSnapshotResponseWithFSR(ctx context.Context, snapshot *cloud.Snapshot, fsrAvailabilityZones []string) (*csi.CreateSnapshotResponse, error) {
+	logger := klog.FromContext(ctx)
+	if !snapshot.ReadyToUse || len(fsrAvailabilityZones) == 0 {
+		return newCreateSnapshotResponse(snapshot)
+	}
+	for _, zone := range fsrAvailabilityZones {
+		state, err := d.cloud.GetFastSnapshotRestoreState(ctx, snapshot.SnapshotID, zone)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not get fast snapshot restore state of snapshot %q: %v", snapshot.SnapshotID, err)
+		}
+		switch state {
+		case ec2.FastSnapshotRestoreStateCodeOptimizing, ec2.FastSnapshotRestoreStateCodeEnabled:
+		case ec2.FastSnapshotRestoreStateCodeEnabling:
+			logger.V(4).Info("CreateSnapshot: waiting for fast snapshot restores to be optimizing", "snapshotID", snapshot.SnapshotID, "availabilityZone", zone)
+			snapshot.ReadyToUse = false
+		default:
+			// FSR was disabled meanwhile, waiting for it would never end
+			logger.Info("CreateSnapshot: fast snapshot restores are not being enabled", "snapshotID", snapshot.SnapshotID, "availabilityZone", zone, "state", state)
+		}
+	}
 	return newCreateSnapshotResponse(snapshot)
 }
 
@@ -788,7 +1589,8 @@ func validateCreateSnapshotRequest(req *csi.CreateSnapshotRequest) error {
 }
 
 func (d *controllerService) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
-	klog.V(4).InfoS("DeleteSnapshot: called", "args", req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("DeleteSnapshot: called", "args", req)
 	if err := validateDeleteSnapshotRequest(req); err != nil {
 		return nil, err
 	}
@@ -802,17 +1604,38 @@ func (d *controllerService) DeleteSnapshot(ctx context.Context, req *csi.DeleteS
 	}
 	defer d.inFlight.Delete(snapshotID)
 
+	d.disableFastSnapshotRestores(ctx, snapshotID)
+
 	if _, err := d.cloud.DeleteSnapshot(ctx, snapshotID); err != nil {
-		if errors.Is(err, cloud.ErrNotFound) {
-			klog.V(4).InfoS("DeleteSnapshot: snapshot not found, returning with success")
-			return &csi.DeleteSnapshotResponse{}, nil
+		if !errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.Internal, "Could not delete snapshot ID %q: %v", snapshotID, err)
 		}
-		return nil, status.Errorf(codes.Internal, "Could not delete snapshot ID %q: %v", snapshotID, err)
+		logger.V(4).Info("DeleteSnapshot: snapshot not found, returning with success")
 	}
+	d.results.Forget(snapshotID)
 
 	return &csi.DeleteSnapshotResponse{}, nil
 }
 
+// disableFastSnapshotRestores disables the fast snapshot restores of a
+// snapshot about to be deleted, so that their entries do not outlive it. It
+// is best effort and never prevents the deletion of the snapshot.
+func (d *controllerService) disableFastSnapshotRestores(ctx context.Context, snapshotID string) {
+	logger := klog.FromContext(ctx)
+	zones, err := d.cloud.GetFastSnapshotRestoreZones(ctx, snapshotID)
+	if err != nil {
+		logger.V(4).Info("DeleteSnapshot: could not get fast snapshot restores", "snapshotID", snapshotID, "err", err)
+		return
+	}
+	if len(zones) == 0 {
+		return
+	}
+	logger.V(4).Info("DeleteSnapshot: disabling fast snapshot restores", "snapshotID", snapshotID, "availabilityZones", zones)
+	if err := d.cloud.DisableFastSnapshotRestores(ctx, zones, snapshotID); err != nil {
+		logger.Info("DeleteSnapshot: could not disable fast snapshot restores", "snapshotID", snapshotID, "availabilityZones", zones, "err", err)
+	}
+}
+
 func validateDeleteSnapshotRequest(req *csi.DeleteSnapshotRequest) error {
 	if len(req.GetSnapshotId()) == 0 {
 		return status.Error(codes.InvalidArgument, "Snapshot ID not provided")
@@ -821,7 +1644,8 @@ func validateDeleteSnapshotRequest(req *csi.DeleteSnapshotRequest) error {
 }
 
 func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	klog.V(4).InfoS("ListSnapshots: called", "args", req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ListSnapshots: called", "args", req)
 	var snapshots []*cloud.Snapshot
 
 	snapshotID := req.GetSnapshotId()
@@ -829,7 +1653,7 @@ func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnap
 		snapshot, err := d.cloud.GetSnapshotByID(ctx, snapshotID)
 		if err != nil {
 			if errors.Is(err, cloud.ErrNotFound) {
-				klog.V(4).InfoS("ListSnapshots: snapshot not found, returning with success")
+				logger.V(4).Info("ListSnapshots: snapshot not found, returning with success")
 				return &csi.ListSnapshotsResponse{}, nil
 			}
 			return nil, status.Errorf(codes.Internal, "Could not get snapshot ID %q: %v", snapshotID, err)
@@ -848,7 +1672,7 @@ func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnap
 	cloudSnapshots, err := d.cloud.ListSnapshots(ctx, volumeID, maxEntries, nextToken)
 	if err != nil {
 		if errors.Is(err, cloud.ErrNotFound) {
-			klog.V(4).InfoS("ListSnapshots: snapshot not found, returning with success")
+			logger.V(4).Info("ListSnapshots: snapshot not found, returning with success")
 			return &csi.ListSnapshotsResponse{}, nil
 		}
 		if errors.Is(err, cloud.ErrInvalidMaxResults) {
@@ -861,36 +1685,6 @@ func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnap
 	return response, nil
 }
 
-// pickAvailabilityZone selects 1 zone given topology requirement.
-// if not found, empty string is returned.
-func pickAvailabilityZone(requirement *csi.TopologyRequirement) string {
-	if requirement == nil {
-		return ""
-	}
-	for _, topology := range requirement.GetPreferred() {
-		zone, exists := topology.GetSegments()[WellKnownTopologyKey]
-		if exists {
-			return zone
-		}
-
-		zone, exists = topology.GetSegments()[TopologyKey]
-		if exists {
-			return zone
-		}
-	}
-	for _, topology := range requirement.GetRequisite() {
-		zone, exists := topology.GetSegments()[WellKnownTopologyKey]
-		if exists {
-			return zone
-		}
-		zone, exists = topology.GetSegments()[TopologyKey]
-		if exists {
-			return zone
-		}
-	}
-	return ""
-}
-
 func getOutpostArn(requirement *csi.TopologyRequirement) string {
 	if requirement == nil {
 		return ""
@@ -912,6 +1706,17 @@ func getOutpostArn(requirement *csi.TopologyRequirement) string {
 }
 
 func newCreateVolumeResponse(disk *cloud.Disk, ctx map[string]string) *csi.CreateVolumeResponse {
+	ctx[VolumeAttributeSizeBytes] = strconv.FormatInt(util.GiBToBytes(disk.CapacityGiB), 10)
+
+	volume := newVolume(disk)
+	volume.VolumeContext = ctx
+	return &csi.CreateVolumeResponse{
+		Volume: volume,
+	}
+}
+
+// newVolume returns the volume of disk, without its volume context.
+func newVolume(disk *cloud.Disk) *csi.Volume {
 	var src *csi.VolumeContentSource
 	if disk.SnapshotID != "" {
 		src = &csi.VolumeContentSource{
@@ -934,19 +1739,33 @@ func newCreateVolumeResponse(disk *cloud.Disk, ctx map[string]string) *csi.Creat
 		segments[AwsOutpostIDKey] = strings.ReplaceAll(arn.Resource, "outpost/", "")
 	}
 
-	return &csi.CreateVolumeResponse{
-		Volume: &csi.Volume{
-			VolumeId:      disk.VolumeID,
-			CapacityBytes: util.GiBToBytes(disk.CapacityGiB),
-			VolumeContext: ctx,
-			AccessibleTopology: []*csi.Topology{
-				{
-					Segments: segments,
-				},
+	return &csi.Volume{
+		VolumeId:      disk.VolumeID,
+		CapacityBytes: util.GiBToBytes(disk.CapacityGiB),
+		AccessibleTopology: []*csi.Topology{
+			{
+				Segments: segments,
 			},
-			ContentSource: src,
 		},
+		ContentSource: src,
+	}
+}
+
+// getSourceVolumePVCName returns the PVC name the source volume of a snapshot
+// was tagged with. It returns an empty string if the volume has no such tag or
+// its tags cannot be read, in which case the snapshot is created without it.
+func (d *controllerService) getSourceVolumePVCName(ctx context.Context, volumeID string) string {
+	logger := klog.FromContext(ctx)
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
+		logger.Info("CreateSnapshot: could not read source volume tags, not tagging snapshot with PVC name", "volumeID", volumeID, "err", err)
+		return ""
+	}
+	pvcName, ok := disk.Tags[PVCNameTag]
+	if !ok {
+		logger.V(4).Info("CreateSnapshot: source volume has no PVC name tag", "volumeID", volumeID)
 	}
+	return pvcName
 }
 
 func newCreateSnapshotResponse(snapshot *cloud.Snapshot) (*csi.CreateSnapshotResponse, error) {
@@ -990,7 +1809,7 @@ func newListSnapshotsResponseEntry(snapshot *cloud.Snapshot) *csi.ListSnapshotsR
 	}
 }
 
-func getVolSizeBytes(req *csi.CreateVolumeRequest) (int64, error) {
+func getVolSizeBytes(req *csi.CreateVolumeRequest, granularityGiB int64) (int64, error) {
 	var volSizeBytes int64
 	capRange := req.GetCapacityRange()
 	if capRange == nil {
@@ -1002,7 +1821,53 @@ func getVolSizeBytes(req *csi.CreateVolumeRequest) (int64, error) {
 			return 0, status.Error(codes.InvalidArgument, "After round-up, volume size exceeds the limit specified")
 		}
 	}
-	return volSizeBytes, nil
+	return roundUpToGranularity(volSizeBytes, capRange.GetLimitBytes(), granularityGiB), nil
+}
+
+// roundUpToGranularity rounds a GiB aligned volume size up to a multiple of
+// granularityGiB. If that exceeds limitBytes, the volume is instead sized to
+// the limit rounded down to whole GiB.
+func roundUpToGranularity(volSizeBytes int64, limitBytes int64, granularityGiB int64) int64 {
+	if granularityGiB <= 1 {
+		return volSizeBytes
+	}
+	granularityBytes := util.GiBToBytes(granularityGiB)
+	roundedBytes := (volSizeBytes + granularityBytes - 1) / granularityBytes * granularityBytes
+	if limitBytes > 0 && roundedBytes > limitBytes {
+		roundedBytes = util.GiBToBytes(util.BytesToGiB(limitBytes))
+		klog.V(4).InfoS("Volume size rounded up to granularity exceeds limit, using limit instead", "volSizeBytes", volSizeBytes, "granularityGiB", granularityGiB, "limitBytes", limitBytes)
+	}
+	return roundedBytes
+}
+
+// applyGrowthHeadroom adds headroom, a percentage of volSizeBytes such as
+// "20%", to volSizeBytes. The result is rounded up to whole GiB and to
+// granularityGiB, and capped at limitBytes and at maxSizeGiB, the maximum size
+// of the volume type if known, so that the headroom never makes a volume
+// larger than allowed.
+func applyGrowthHeadroom(volSizeBytes int64, headroom string, limitBytes int64, maxSizeGiB int64, granularityGiB int64) (int64, error) {
+	percent, err := strconv.Atoi(strings.TrimSuffix(headroom, "%"))
+	if err != nil || !strings.HasSuffix(headroom, "%") || percent < 0 {
+		return 0, fmt.Errorf("%q is not a non-negative percentage such as \"20%%\"", headroom)
+	}
+	if maxSizeBytes := util.GiBToBytes(maxSizeGiB); maxSizeBytes > 0 && (limitBytes <= 0 || maxSizeBytes < limitBytes) {
+		limitBytes = maxSizeBytes
+	}
+	sizeBytes := util.RoundUpBytes(volSizeBytes + volSizeBytes*int64(percent)/100)
+	if limitBytes > 0 && sizeBytes > limitBytes {
+		sizeBytes = util.GiBToBytes(util.BytesToGiB(limitBytes))
+	}
+	if sizeBytes < volSizeBytes {
+		return volSizeBytes, nil
+	}
+	return roundUpToGranularity(sizeBytes, limitBytes, granularityGiB), nil
+}
+
+// isOutpostArn returns whether s is the ARN of an Outpost, e.g.
+// arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0.
+func isOutpostArn(s string) bool {
+	a, err := arn.Parse(s)
+	return err == nil && a.Service == "outposts" && strings.HasPrefix(a.Resource, "outpost/")
 }
 
 // BuildOutpostArn returns the string representation of the outpost ARN from the given csi.TopologyRequirement.segments
@@ -1050,3 +1915,380 @@ func validateFormattingOption(volumeCapabilities []*csi.VolumeCapability, paramN
 
 	return nil
 }
+
+// interpolateExtraTags evaluates the values of the driver's extra tags as
+// templates of props, e.g. to derive cost allocation tags from the PVC of a
+// volume. As extra tags apply to every resource, a tag that cannot be
+// evaluated for a resource, e.g. one referring to the PVC on a snapshot, is
+// left out instead of failing the request.
+func interpolateExtraTags(extraTags map[string]string, props interface{}) map[string]string {
+	if len(extraTags) == 0 {
+		return nil
+	}
+	if props == nil {
+		// Referring to any property fails, instead of evaluating to "<no value>"
+		props = struct{}{}
+	}
+	tags := make([]string, 0, len(extraTags))
+	for k, v := range extraTags {
+		tags = append(tags, k+"="+v)
+	}
+	// Evaluate never fails when only warning
+	interpolated, _ := template.Evaluate(tags, props, true)
+	return interpolated
+}
+
+// mergeTags copies the per-request tags into tags, overriding any default
+// value already set for the same key.
+func mergeTags(tags map[string]string, requestTags map[string]string) {
+	for k, v := range requestTags {
+		if old, ok := tags[k]; ok && old != v {
+			klog.V(4).InfoS("Overriding default tag with per-request value", "key", k, "defaultValue", old, "value", v)
+		}
+		tags[k] = v
+	}
+}
+
+// shutdownCoordinator tracks in-flight attach and detach operations so that
+// the controller can drain them before it stops.
+type shutdownCoordinator struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight sync.WaitGroup
+	// abortCtx is canceled once the grace period expires to abort the
+	// operations that are still in flight
+	abortCtx context.Context
+	abort    context.CancelFunc
+}
+
+func newShutdownCoordinator() *shutdownCoordinator {
+	abortCtx, abort := context.WithCancel(context.Background())
+	return &shutdownCoordinator{
+		abortCtx: abortCtx,
+		abort:    abort,
+	}
+}
+
+// begin registers a new operation. It returns false if the controller is
+// draining. Otherwise, it returns a context that is canceled if the operation
+// is aborted during shutdown and a function to call when the operation ends.
+func (s *shutdownCoordinator) begin(ctx context.Context) (context.Context, func(), bool) {
+	if s == nil {
+		return ctx, func() {}, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return ctx, nil, false
+	}
+	s.inFlight.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.abortCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		s.inFlight.Done()
+	}, true
+}
+
+// drain stops accepting new operations and waits up to gracePeriod for the
+// in-flight ones to finish, aborting them afterwards. It returns false if any
+// operation had to be aborted.
+func (s *shutdownCoordinator) drain(gracePeriod time.Duration) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		klog.InfoS("All in-flight attach and detach operations finished")
+		return true
+	case <-time.After(gracePeriod):
+		klog.InfoS("Shutdown grace period expired, aborting in-flight attach and detach operations", "gracePeriod", gracePeriod)
+		s.abort()
+		<-finished
+		return false
+	}
+}
+
+const (
+	volumeOperationsMetric = "ebs_csi_volume_operations"
+
+	volumeOperationAttach = "attach"
+	volumeOperationDetach = "detach"
+
+	volumeOperationQueued   = "queued"
+	volumeOperationInFlight = "in_flight"
+)
+
+// volumeOperation is an attachment or detachment counted by the
+// ebs_csi_volume_operations gauge, as queued while it waits for other
+// operations on its node or for an attach slot, and then as in flight while it
+// waits on AWS.
+type volumeOperation struct {
+	labels map[string]string
+}
+
+// trackVolumeOperation counts an attachment or detachment as queued until
+// start is called.
+func trackVolumeOperation(operation string) *volumeOperation {
+	op := &volumeOperation{labels: map[string]string{"operation": operation, "state": volumeOperationQueued}}
+	metrics.Recorder().AddGauge(volumeOperationsMetric, 1, op.labels)
+	return op
+}
+
+// start counts the operation as in flight instead of queued.
+func (op *volumeOperation) start() {
+	metrics.Recorder().AddGauge(volumeOperationsMetric, -1, op.labels)
+	op.labels = map[string]string{"operation": op.labels["operation"], "state": volumeOperationInFlight}
+	metrics.Recorder().AddGauge(volumeOperationsMetric, 1, op.labels)
+}
+
+// done stops counting the operation.
+func (op *volumeOperation) done() {
+	metrics.Recorder().AddGauge(volumeOperationsMetric, -1, op.labels)
+}
+
+// attachLimiter bounds the number of concurrent AttachDisk calls, separately
+// from the rate limiting of the AWS SDK. Attachments over the limit are queued
+// and admitted in the order they arrived.
+type attachLimiter struct {
+	sem *semaphore.Weighted
+}
+
+// newAttachLimiter returns nil, which does not limit attachments, if limit is
+// not positive.
+func newAttachLimiter(limit int) *attachLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &attachLimiter{sem: semaphore.NewWeighted(int64(limit))}
+}
+
+// acquire waits until an attachment may start or ctx is done. It returns a
+// function to call when the attachment ends.
+func (l *attachLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if err := l.sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() {
+		l.sem.Release(1)
+	}, nil
+}
+
+// nodeOperationQueue serializes the attachments and detachments of each node,
+// as EC2 fails concurrent attach and detach requests of an instance with
+// IncorrectState errors, while running those of up to workers nodes in
+// parallel. Operations waiting for their node or for a worker are admitted in
+// the order they arrived.
+type nodeOperationQueue struct {
+	workers *semaphore.Weighted
+
+	mu    sync.Mutex
+	nodes map[string]*nodeOperations
+}
+
+// nodeOperations are the operations of a node, queued or in flight.
+type nodeOperations struct {
+	sem     *semaphore.Weighted
+	pending int
+}
+
+// newNodeOperationQueue returns nil, which does not serialize operations, if
+// workers is not positive.
+func newNodeOperationQueue(workers int) *nodeOperationQueue {
+	if workers <= 0 {
+		return nil
+	}
+	return &nodeOperationQueue{
+		workers: semaphore.NewWeighted(int64(workers)),
+		nodes:   map[string]*nodeOperations{},
+	}
+}
+
+// acquire waits until an operation on nodeID may start or ctx is done. It
+// returns a function to call when the operation ends.
+func (q *nodeOperationQueue) acquire(ctx context.Context, nodeID string) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+	node := q.enqueue(nodeID)
+	err := node.sem.Acquire(ctx, 1)
+	if err == nil {
+		if err = q.workers.Acquire(ctx, 1); err != nil {
+			node.sem.Release(1)
+		}
+	}
+	if err != nil {
+		q.dequeue(nodeID, node)
+		return nil, err
+	}
+	return func() {
+		q.workers.Release(1)
+		node.sem.Release(1)
+		q.dequeue(nodeID, node)
+	}, nil
+}
+
+func (q *nodeOperationQueue) enqueue(nodeID string) *nodeOperations {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	node, ok := q.nodes[nodeID]
+	if !ok {
+		node = &nodeOperations{sem: semaphore.NewWeighted(1)}
+		q.nodes[nodeID] = node
+	}
+	node.pending++
+	return node
+}
+
+// dequeue forgets a node once it has no operations left.
+func (q *nodeOperationQueue) dequeue(nodeID string, node *nodeOperations) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	node.pending--
+	if node.pending == 0 {
+		delete(q.nodes, nodeID)
+	}
+}
+
+// attachBudgetResetPeriod is how long after its last failed attach attempt the
+// attach budget of a volume is restored.
+const attachBudgetResetPeriod = 10 * time.Minute
+
+// attachBudget bounds the failed attempts to attach a volume, counted across
+// all nodes, so that a volume that keeps failing to attach, e.g. while it
+// bounces between nodes during scheduling, is not retried indefinitely. Once
+// the budget of a volume is exhausted, its attachments fail without calling
+// AWS until it attaches or attachBudgetResetPeriod passes without a failed attempt.
+type attachBudget struct {
+	maxAttempts int
+	deadline    time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	volumes map[string]*attachAttempts
+}
+
+// attachAttempts are the failed attach attempts of a volume.
+type attachAttempts struct {
+	first    time.Time
+	last     time.Time
+	failures int
+}
+
+// newAttachBudget returns nil, which does not bound attach attempts, if
+// neither maxAttempts nor deadline is positive.
+func newAttachBudget(maxAttempts int, deadline time.Duration) *attachBudget {
+	if maxAttempts <= 0 && deadline <= 0 {
+		return nil
+	}
+	return &attachBudget{
+		maxAttempts: maxAttempts,
+		deadline:    deadline,
+		now:         time.Now,
+		volumes:     map[string]*attachAttempts{},
+	}
+}
+
+// check returns an error if the attach budget of volumeID is exhausted.
+func (b *attachBudget) check(volumeID string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	attempts, ok := b.volumes[volumeID]
+	if !ok {
+		return nil
+	}
+	now := b.now()
+	if now.Sub(attempts.last) >= attachBudgetResetPeriod {
+		delete(b.volumes, volumeID)
+		return nil
+	}
+	if b.maxAttempts > 0 && attempts.failures >= b.maxAttempts {
+		return fmt.Errorf("%d attach attempts failed since %s, exceeding the attach retry budget", attempts.failures, attempts.first.Format(time.RFC3339))
+	}
+	if b.deadline > 0 && now.Sub(attempts.first) >= b.deadline {
+		return fmt.Errorf("attach attempts have been failing since %s, exceeding the attach retry deadline of %s", attempts.first.Format(time.RFC3339), b.deadline)
+	}
+	return nil
+}
+
+// failed records a failed attempt to attach volumeID.
+func (b *attachBudget) failed(volumeID string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	// Forget volumes that are no longer being attached
+	for id, attempts := range b.volumes {
+		if now.Sub(attempts.last) >= attachBudgetResetPeriod {
+			delete(b.volumes, id)
+		}
+	}
+	attempts, ok := b.volumes[volumeID]
+	if !ok {
+		attempts = &attachAttempts{first: now}
+		b.volumes[volumeID] = attempts
+	}
+	attempts.last = now
+	attempts.failures++
+	klog.V(4).InfoS("Attach attempt failed", "volumeID", volumeID, "failures", attempts.failures, "since", attempts.first)
+}
+
+// isAWSAttachFailure returns whether an attach attempt failed in AWS, i.e. EC2
+// rejected the attachment or it did not complete in time, which is the only
+// kind of failure that counts against the attach budget of the volume. Requests
+// that were canceled, volumes or nodes that were not found, and transient
+// failures do not count.
+func isAWSAttachFailure(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, cloud.ErrNotFound), isTransientAttachError(err):
+		return false
+	case wait.Interrupted(err):
+		// The attachment did not complete in time
+		return true
+	}
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr)
+}
+
+// isTransientAttachError returns whether an attach attempt failed for a reason
+// unrelated to the volume or the node, such as throttling or an AWS server or
+// network error.
+func isTransientAttachError(err error) bool {
+	if reason, _ := cloud.ErrorReason(err); reason == cloud.ErrorReasonThrottling {
+		return true
+	}
+	return isTransientAWSError(err)
+}
+
+// succeeded restores the attach budget of volumeID.
+func (b *attachBudget) succeeded(volumeID string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.volumes, volumeID)
+}
@@ -2,12 +2,16 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/awslabs/volume-modifier-for-k8s/pkg/rpc"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -167,7 +171,7 @@ func (d *controllerService) executeModifyVolumeRequest(volumeID string, req *mod
 	defer cancel()
 	actualSizeGiB, err := d.cloud.ResizeOrModifyDisk(ctx, volumeID, req.newSize, &req.modifyDiskOptions)
 	if err != nil {
-		return 0, status.Errorf(codes.Internal, "Could not modify volume %q: %v", volumeID, err)
+		return 0, err
 	} else {
 		return actualSizeGiB, nil
 	}
@@ -184,51 +188,152 @@ func (d *controllerService) ModifyVolumeProperties(
 	ctx context.Context,
 	req *rpc.ModifyVolumePropertiesRequest,
 ) (*rpc.ModifyVolumePropertiesResponse, error) {
-	klog.V(4).InfoS("ModifyVolumeProperties called", "req", req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ModifyVolumeProperties called", "req", req)
 	if err := validateModifyVolumePropertiesRequest(req); err != nil {
 		return nil, err
 	}
 
 	name := req.GetName()
-	modifyOptions := cloud.ModifyDiskOptions{}
-	for key, value := range req.GetParameters() {
-		switch key {
-		case ModificationKeyIOPS:
-			iops, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse IOPS: %q", value)
-			}
-			modifyOptions.IOPS = iops
-		case ModificationKeyThroughput:
-			throughput, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse throughput: %q", value)
+	modifyOptions, err := parseModifyVolumeParameters(req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.modifyVolume(ctx, name, modifyOptions); err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not modify volume %q: %v", name, err)
+	}
+
+	return &rpc.ModifyVolumePropertiesResponse{}, nil
+}
+
+func (d *controllerService) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("ControllerModifyVolume: called", "volumeID", req.GetVolumeId(), "mutableParameters", req.GetMutableParameters())
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	// A missing volume is reported as such whatever its parameters
+	if _, err := d.cloud.GetDiskByID(ctx, volumeID); err != nil {
+		if errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
+	}
+
+	// There is nothing to modify without mutable parameters
+	if len(req.GetMutableParameters()) == 0 {
+		return &csi.ControllerModifyVolumeResponse{}, nil
+	}
+	if err := validateMutableParameters("ControllerModifyVolume", req.GetMutableParameters()); err != nil {
+		return nil, err
+	}
+
+	modifyOptions, err := parseModifyVolumeParameters(req.GetMutableParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.modifyVolume(ctx, volumeID, modifyOptions); err != nil {
+		var errCode codes.Code
+		switch {
+		case errors.Is(err, cloud.ErrNotFound):
+			errCode = codes.NotFound
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			errCode = codes.DeadlineExceeded
+		default:
+			errCode = codes.Internal
+			// EC2 rejected one of the requested values, e.g. IOPS out of range
+			if reason, _ := cloud.ErrorReason(err); reason == cloud.ErrorReasonValidation {
+				errCode = codes.InvalidArgument
 			}
-			modifyOptions.Throughput = throughput
-		case ModificationKeyVolumeType:
-			modifyOptions.VolumeType = value
 		}
+		return nil, withErrorReason(status.Newf(errCode, "Could not modify volume %q to %s: %v", volumeID, formatModifyDiskOptions(modifyOptions), err), err).Err()
 	}
+	logger.Info("ControllerModifyVolume: modified volume", "volumeID", volumeID, "mutableParameters", req.GetMutableParameters())
+
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
 
+// modifyVolume modifies volumeID with options and waits for the modification
+// to complete. It is coalesced with the other resize and modify requests for
+// the volume received meanwhile.
+func (d *controllerService) modifyVolume(ctx context.Context, volumeID string, options cloud.ModifyDiskOptions) error {
 	responseChan := make(chan modifyVolumeResponse)
 	request := modifyVolumeRequest{
-		modifyDiskOptions: modifyOptions,
+		modifyDiskOptions: options,
 		responseChan:      responseChan,
 	}
 
 	// Intentionally not pass in context as we deal with context locally in this method
-	d.addModifyVolumeRequest(name, &request) //nolint:contextcheck
+	d.addModifyVolumeRequest(volumeID, &request) //nolint:contextcheck
 
 	select {
 	case response := <-responseChan:
-		if response.err != nil {
-			return nil, status.Errorf(codes.Internal, "Could not modify volume %q: %v", name, response.err)
-		}
+		return response.err
 	case <-ctx.Done():
-		return nil, status.Errorf(codes.Internal, "Could not modify volume %q: context cancelled", name)
+		return fmt.Errorf("context cancelled: %w", ctx.Err())
 	}
+}
 
-	return &rpc.ModifyVolumePropertiesResponse{}, nil
+// parseModifyVolumeParameters parses the volume type, IOPS and throughput to
+// modify volumes to. Invalid values are reported along with their parameter,
+// other parameters are ignored.
+func parseModifyVolumeParameters(params map[string]string) (cloud.ModifyDiskOptions, error) {
+	options := cloud.ModifyDiskOptions{}
+	for key, value := range params {
+		switch key {
+		case ModificationKeyIOPS:
+			iops, err := strconv.Atoi(value)
+			if err != nil || iops <= 0 {
+				return options, status.Errorf(codes.InvalidArgument, "Could not parse invalid value %q of parameter %s: expected a positive integer", value, key)
+			}
+			options.IOPS = iops
+		case ModificationKeyThroughput:
+			throughput, err := strconv.Atoi(value)
+			if err != nil || throughput <= 0 {
+				return options, status.Errorf(codes.InvalidArgument, "Could not parse invalid value %q of parameter %s: expected a positive integer", value, key)
+			}
+			options.Throughput = throughput
+		case ModificationKeyVolumeType:
+			if !slices.Contains(cloud.ValidVolumeTypes, strings.ToLower(value)) {
+				return options, status.Errorf(codes.InvalidArgument, "Invalid value %q of parameter %s: expected one of %v", value, key, cloud.ValidVolumeTypes)
+			}
+			options.VolumeType = strings.ToLower(value)
+		}
+	}
+	return options, nil
+}
+
+// formatModifyDiskOptions returns the parameters set in options, for errors.
+func formatModifyDiskOptions(options cloud.ModifyDiskOptions) string {
+	var params []string
+	if options.VolumeType != "" {
+		params = append(params, fmt.Sprintf("%s=%s", ModificationKeyVolumeType, options.VolumeType))
+	}
+	if options.IOPS != 0 {
+		params = append(params, fmt.Sprintf("%s=%d", ModificationKeyIOPS, options.IOPS))
+	}
+	if options.Throughput != 0 {
+		params = append(params, fmt.Sprintf("%s=%d", ModificationKeyThroughput, options.Throughput))
+	}
+	return strings.Join(params, ", ")
+}
+
+// validateMutableParameters returns an error unless every key of the mutable
+// parameters of a request to rpc, e.g. of a VolumeAttributesClass, is a
+// parameter the driver can modify volumes by.
+func validateMutableParameters(rpc string, params map[string]string) error {
+	for key := range params {
+		switch key {
+		case ModificationKeyVolumeType, ModificationKeyIOPS, ModificationKeyThroughput:
+		default:
+			return status.Errorf(codes.InvalidArgument, "Invalid mutable parameter key %s for %s, expected %s, %s or %s", key, rpc, ModificationKeyVolumeType, ModificationKeyIOPS, ModificationKeyThroughput)
+		}
+	}
+	return nil
 }
 
 func validateModifyVolumePropertiesRequest(req *rpc.ModifyVolumePropertiesRequest) error {
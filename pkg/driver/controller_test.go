@@ -21,23 +21,33 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
+	"net/http"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util/template"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
@@ -53,8 +63,8 @@ func TestNewControllerService(t *testing.T) {
 		testErr    = errors.New("test error")
 		testRegion = "test-region"
 
-		getNewCloudFunc = func(expectedRegion string, _ bool) func(region string, awsSdkDebugLog bool, userAgentExtra string, batching bool) (cloud.Cloud, error) {
-			return func(region string, awsSdkDebugLog bool, userAgentExtra string, batching bool) (cloud.Cloud, error) {
+		getNewCloudFunc = func(expectedRegion string, _ bool) func(region string, opts cloud.CloudOptions) (cloud.Cloud, error) {
+			return func(region string, opts cloud.CloudOptions) (cloud.Cloud, error) {
 				if region != expectedRegion {
 					t.Fatalf("expected region %q but got %q", expectedRegion, region)
 				}
@@ -66,7 +76,7 @@ func TestNewControllerService(t *testing.T) {
 	testCases := []struct {
 		name                  string
 		region                string
-		newCloudFunc          func(string, bool, string, bool) (cloud.Cloud, error)
+		newCloudFunc          func(string, cloud.CloudOptions) (cloud.Cloud, error)
 		newMetadataFuncErrors bool
 		expectPanic           bool
 	}{
@@ -78,7 +88,7 @@ func TestNewControllerService(t *testing.T) {
 		{
 			name:   "AWS_REGION variable set, newCloud errors",
 			region: "foo",
-			newCloudFunc: func(region string, awsSdkDebugLog bool, userAgentExtra string, batching bool) (cloud.Cloud, error) {
+			newCloudFunc: func(region string, opts cloud.CloudOptions) (cloud.Cloud, error) {
 				return nil, testErr
 			},
 			expectPanic: true,
@@ -112,7 +122,7 @@ func TestNewControllerService(t *testing.T) {
 
 				oldNewMetadataFunc := NewMetadataFunc
 				defer func() { NewMetadataFunc = oldNewMetadataFunc }()
-				NewMetadataFunc = func(cloud.EC2MetadataClient, cloud.KubernetesAPIClient, string) (cloud.MetadataService, error) {
+				NewMetadataFunc = func(cloud.EC2MetadataClient, cloud.KubernetesAPIClient, string, []string) (cloud.MetadataService, error) {
 					if tc.newMetadataFuncErrors {
 						return nil, testErr
 					}
@@ -260,7 +270,7 @@ func TestCreateVolume(t *testing.T) {
 				expVol := &csi.Volume{
 					CapacityBytes: stdVolSize,
 					VolumeId:      "vol-test",
-					VolumeContext: map[string]string{},
+					VolumeContext: map[string]string{VolumeAttributeSizeBytes: strconv.FormatInt(stdVolSize, 10)},
 					AccessibleTopology: []*csi.Topology{
 						{
 							Segments: map[string]string{
@@ -616,7 +626,7 @@ func TestCreateVolume(t *testing.T) {
 					VolumeID:         req.Name,
 					AvailabilityZone: expZone,
 				}
-				volSizeBytes, err := getVolSizeBytes(req)
+				volSizeBytes, err := getVolSizeBytes(req, 0)
 				if err != nil {
 					t.Fatalf("Unable to get volume size bytes for req: %s", err)
 				}
@@ -818,6 +828,140 @@ func TestCreateVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success with volume type gp3 allowing throughput decrease",
+			testFunc: func(t *testing.T) {
+				volSize := int64(20 * 1024 * 1024 * 1024)
+				capRange := &csi.CapacityRange{RequiredBytes: volSize}
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      capRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						VolumeTypeKey:                  cloud.VolumeTypeGP3,
+						ThroughputKey:                  "1000",
+						AllowAutoThroughputDecreaseKey: "true",
+					},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := &cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(volSize),
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+					if !diskOptions.AllowThroughputDecrease {
+						t.Fatalf("Expected AllowThroughputDecrease to be set")
+					}
+					return mockDisk, nil
+				})
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := awsDriver.CreateVolume(ctx, req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+			},
+		},
+		{
+			name: "success with mount namespace isolation",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						IsolateMountNamespaceKey: "true",
+					},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := &cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := awsDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if resp.GetVolume().GetVolumeContext()[IsolateMountNamespaceKey] != "true" {
+					t.Fatalf("Expected volume context to contain %s, got %v", IsolateMountNamespaceKey, resp.GetVolume().GetVolumeContext())
+				}
+			},
+		},
+		{
+			name: "success with volume size granularity",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12)},
+					VolumeCapabilities: stdVolCap,
+				}
+
+				ctx := context.Background()
+
+				mockDisk := &cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      20,
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+					if diskOptions.CapacityBytes != util.GiBToBytes(20) {
+						t.Fatalf("Expected CreateDisk to be called with %d bytes, got %d", util.GiBToBytes(20), diskOptions.CapacityBytes)
+					}
+					return mockDisk, nil
+				})
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{volumeSizeGranularity: 10},
+				}
+
+				resp, err := awsDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if resp.GetVolume().GetCapacityBytes() != util.GiBToBytes(20) {
+					t.Fatalf("Expected volume capacity %d, got %d", util.GiBToBytes(20), resp.GetVolume().GetCapacityBytes())
+				}
+			},
+		},
 		{
 			name: "success with volume type io1 using iopsPerGB",
 			testFunc: func(t *testing.T) {
@@ -1427,6 +1571,71 @@ func TestCreateVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success with StorageClass tags overriding extra tags",
+			testFunc: func(t *testing.T) {
+				const (
+					volumeName          = "random-vol-name"
+					extraVolumeTagKey   = "extra-tag-key"
+					extraVolumeTagValue = "extra-tag-value"
+					otherTagKey         = "other-tag-key"
+					otherTagValue       = "other-tag-value"
+					scTagValue          = "storage-class-value"
+				)
+				req := &csi.CreateVolumeRequest{
+					Name:               volumeName,
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						"tagSpecification_1": extraVolumeTagKey + "=" + scTagValue,
+					},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := &cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey:   volumeName,
+						cloud.AwsEbsDriverTagKey: "true",
+						extraVolumeTagKey:        scTagValue,
+						otherTagKey:              otherTagValue,
+					},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				awsDriver := controllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					driverOptions: &DriverOptions{
+						extraTags: map[string]string{
+							extraVolumeTagKey: extraVolumeTagValue,
+							otherTagKey:       otherTagValue,
+						},
+					},
+				}
+
+				_, err := awsDriver.CreateVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+			},
+		},
 		{
 			name: "success with cluster-id",
 			testFunc: func(t *testing.T) {
@@ -1899,334 +2108,2312 @@ func TestCreateVolumeWithFormattingParameters(t *testing.T) {
 	}
 }
 
-func TestDeleteVolume(t *testing.T) {
+func TestCreateVolumeErrorReason(t *testing.T) {
+	req := &csi.CreateVolumeRequest{
+		Name:          "random-vol-name",
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{},
+				},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+	}
+
 	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
+		name           string
+		createDiskErr  error
+		expectedCode   codes.Code
+		expectedReason string
 	}{
 		{
-			name: "success normal",
-			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "vol-test",
-				}
-				expResp := &csi.DeleteVolumeResponse{}
-
-				ctx := context.Background()
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
-
-				mockCloud := cloud.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(true, nil)
-				awsDriver := controllerService{
-					cloud:         mockCloud,
-					inFlight:      internal.NewInFlight(),
-					driverOptions: &DriverOptions{},
-				}
-				resp, err := awsDriver.DeleteVolume(ctx, req)
-				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
-				}
-				if !reflect.DeepEqual(resp, expResp) {
-					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
-				}
-			},
+			name:           "quota",
+			createDiskErr:  fmt.Errorf("could not create volume in EC2: %w", awserr.New("VolumeLimitExceeded", "You have exceeded your maximum gp3 storage limit", nil)),
+			expectedCode:   codes.Internal,
+			expectedReason: cloud.ErrorReasonQuota,
 		},
 		{
-			name: "success invalid volume id",
-			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "invalid-volume-name",
-				}
-				expResp := &csi.DeleteVolumeResponse{}
-
-				ctx := context.Background()
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
-
-				mockCloud := cloud.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, cloud.ErrNotFound)
-				awsDriver := controllerService{
-					cloud:         mockCloud,
-					inFlight:      internal.NewInFlight(),
-					driverOptions: &DriverOptions{},
-				}
-				resp, err := awsDriver.DeleteVolume(ctx, req)
-				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
-				}
-				if !reflect.DeepEqual(resp, expResp) {
-					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
-				}
-			},
+			name:           "capacity",
+			createDiskErr:  fmt.Errorf("could not create volume in EC2: %w", awserr.New("InsufficientVolumeCapacity", "There is not enough capacity", nil)),
+			expectedCode:   codes.Internal,
+			expectedReason: cloud.ErrorReasonCapacity,
 		},
 		{
-			name: "fail delete disk",
-			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "test-vol",
-				}
+			name:           "throttling",
+			createDiskErr:  fmt.Errorf("could not create volume in EC2: %w", awserr.New("RequestLimitExceeded", "Request limit exceeded", nil)),
+			expectedCode:   codes.Internal,
+			expectedReason: cloud.ErrorReasonThrottling,
+		},
+		{
+			name:           "validation",
+			createDiskErr:  fmt.Errorf("could not create volume in EC2: %w", awserr.New("InvalidParameterValue", "Invalid iops", nil)),
+			expectedCode:   codes.Internal,
+			expectedReason: cloud.ErrorReasonValidation,
+		},
+		{
+			name:           "kms",
+			createDiskErr:  fmt.Errorf("could not create volume in EC2: %w", awserr.New("KMS.DisabledException", "The KMS key is disabled", nil)),
+			expectedCode:   codes.Internal,
+			expectedReason: cloud.ErrorReasonKMS,
+		},
+		{
+			name:          "unclassified AWS error",
+			createDiskErr: fmt.Errorf("could not create volume in EC2: %w", awserr.New("InternalError", "An internal error has occurred", nil)),
+			expectedCode:  codes.Internal,
+		},
+		{
+			name:          "driver error",
+			createDiskErr: cloud.ErrIdempotentParameterMismatch,
+			expectedCode:  codes.AlreadyExists,
+		},
+	}
 
-				ctx := context.Background()
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
 
-				mockCloud := cloud.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, fmt.Errorf("DeleteDisk could not delete volume"))
-				awsDriver := controllerService{
-					cloud:         mockCloud,
-					inFlight:      internal.NewInFlight(),
-					driverOptions: &DriverOptions{},
-				}
-				resp, err := awsDriver.DeleteVolume(ctx, req)
-				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.Internal {
-						t.Fatalf("Unexpected error: %v", srvErr.Code())
-					}
-				} else {
-					t.Fatalf("Expected error, got nil")
-				}
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).Return(nil, tc.createDiskErr)
 
-				if resp != nil {
-					t.Fatalf("Expected resp to be nil, got: %+v", resp)
-				}
-			},
-		},
-		{
-			name: "fail another request already in-flight",
-			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "vol-test",
-				}
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
 
-				ctx := context.Background()
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
+			_, err := awsDriver.CreateVolume(context.Background(), req)
+			st, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("Expected a gRPC status error, got: %v", err)
+			}
+			assert.Equal(t, tc.expectedCode, st.Code())
 
-				mockCloud := cloud.NewMockCloud(mockCtl)
-				inFlight := internal.NewInFlight()
-				inFlight.Insert(req.GetVolumeId())
-				defer inFlight.Delete(req.GetVolumeId())
-				awsDriver := controllerService{
-					cloud:         mockCloud,
-					inFlight:      inFlight,
-					driverOptions: &DriverOptions{},
-				}
-				_, err := awsDriver.DeleteVolume(ctx, req)
+			details := st.Details()
+			if tc.expectedReason == "" {
+				assert.Empty(t, details)
+				return
+			}
+			if len(details) != 1 {
+				t.Fatalf("Expected one status detail, got: %v", details)
+			}
+			info, ok := details[0].(*errdetails.ErrorInfo)
+			if !ok {
+				t.Fatalf("Expected ErrorInfo detail, got: %T", details[0])
+			}
+			assert.Equal(t, tc.expectedReason, info.GetReason())
+			assert.Equal(t, DriverName, info.GetDomain())
+			assert.NotEmpty(t, info.GetMetadata()["awsErrorCode"])
+		})
+	}
+}
 
-				checkExpectedErrorCode(t, err, codes.Aborted)
-			},
+func TestCreateVolumeVolumeLimitExceeded(t *testing.T) {
+	const volName = "random-vol-name"
+	limitErr := fmt.Errorf("could not create volume in EC2: %w: %w", cloud.ErrVolumeLimitExceeded, awserr.New("VolumeLimitExceeded", "You have exceeded your maximum gp3 storage limit.", nil))
+
+	testCases := []struct {
+		name                  string
+		reportOrphanedVolumes bool
+	}{
+		{
+			name: "fail: volume count limit",
+		},
+		{
+			name:                  "fail: volume count limit triggers report of orphaned volumes",
+			reportOrphanedVolumes: true,
 		},
 	}
 
 	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, limitErr)
+
+			driverOptions := &DriverOptions{reportOrphanedVolumes: tc.reportOrphanedVolumes, kubernetesClusterID: "test-cluster"}
+			awsDriver := controllerService{
+				cloud:           mockCloud,
+				inFlight:        internal.NewInFlight(),
+				driverOptions:   driverOptions,
+				orphanedVolumes: newOrphanedVolumeReporter(mockCloud, nil, driverOptions),
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:          volName,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+			})
+			checkExpectedErrorCode(t, err, codes.ResourceExhausted)
+			details := status.Convert(err).Details()
+			if len(details) != 1 {
+				t.Fatalf("Expected one status detail, got: %v", details)
+			}
+			info, ok := details[0].(*errdetails.ErrorInfo)
+			if !ok {
+				t.Fatalf("Expected ErrorInfo detail, got: %T", details[0])
+			}
+			assert.Equal(t, cloud.ErrorReasonQuota, info.GetReason())
+			assert.Equal(t, "VolumeLimitExceeded", info.GetMetadata()["awsErrorCode"])
+
+			if tc.reportOrphanedVolumes && len(awsDriver.orphanedVolumes.triggered) != 1 {
+				t.Fatalf("Expected report of orphaned volumes to be triggered")
+			}
+		})
 	}
 }
 
-func TestPickAvailabilityZone(t *testing.T) {
+func TestWaitForFastSnapshotRestore(t *testing.T) {
+	defer func(interval time.Duration) { fsrWaitPollInterval = interval }(fsrWaitPollInterval)
+	fsrWaitPollInterval = time.Millisecond
+
+	const snapshotID = "snap-test"
 	testCases := []struct {
-		name        string
-		requirement *csi.TopologyRequirement
-		expZone     string
+		name           string
+		fsrWaitTimeout time.Duration
+		states         []string
+		stateErr       error
+		// expLookups is the expected number of state lookups, at least 2 if -1
+		expLookups int
+		expEnabled bool
 	}{
 		{
-			name: "Return WellKnownTopologyKey if present from preferred",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: ""},
-					},
-				},
-				Preferred: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone, WellKnownTopologyKey: "foobar"},
-					},
-				},
-			},
-			expZone: "foobar",
+			name:           "FSR already enabled",
+			fsrWaitTimeout: time.Second,
+			states:         []string{"enabled"},
+			expLookups:     1,
+			expEnabled:     true,
 		},
 		{
-			name: "Return WellKnownTopologyKey if present from requisite",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone, WellKnownTopologyKey: "foobar"},
-					},
-				},
-			},
-			expZone: "foobar",
+			name:           "waits for FSR to be enabled",
+			fsrWaitTimeout: time.Second,
+			states:         []string{"enabling", "optimizing", "enabled"},
+			expLookups:     3,
+			expEnabled:     true,
 		},
 		{
-			name: "Pick from preferred",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: ""},
-					},
-				},
-				Preferred: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone},
-					},
-				},
-			},
-			expZone: expZone,
+			name:           "falls back when FSR is not enabled in time",
+			fsrWaitTimeout: 20 * time.Millisecond,
+			states:         []string{"optimizing"},
+			expLookups:     -1,
 		},
 		{
-			name: "Pick from requisite",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone},
-					},
-				},
-			},
-			expZone: expZone,
+			name:           "falls back right away when FSR was never enabled",
+			fsrWaitTimeout: time.Second,
+			states:         []string{""},
+			expLookups:     1,
 		},
 		{
-			name: "Pick from empty topology",
-			requirement: &csi.TopologyRequirement{
-				Preferred: []*csi.Topology{{}},
-				Requisite: []*csi.Topology{{}},
-			},
-			expZone: "",
+			name:           "falls back right away when FSR is being disabled",
+			fsrWaitTimeout: time.Second,
+			states:         []string{"disabling"},
+			expLookups:     1,
 		},
 		{
-			name:        "Topology Requirement is nil",
-			requirement: nil,
-			expZone:     "",
+			name:           "falls back when FSR state cannot be determined",
+			fsrWaitTimeout: time.Second,
+			stateErr:       errors.New("UnauthorizedOperation"),
+			expLookups:     1,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := pickAvailabilityZone(tc.requirement)
-			if actual != tc.expZone {
-				t.Fatalf("Expected zone %v, got zone: %v", tc.expZone, actual)
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			lookups := 0
+			call := mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Any(), snapshotID, expZone).DoAndReturn(func(context.Context, string, string) (string, error) {
+				lookups++
+				if tc.stateErr != nil {
+					return "", tc.stateErr
+				}
+				return tc.states[min(lookups, len(tc.states))-1], nil
+			})
+			if tc.expLookups < 0 {
+				call.MinTimes(2)
+			} else {
+				call.Times(tc.expLookups)
+			}
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{fsrWaitTimeout: tc.fsrWaitTimeout},
 			}
+			enabled := awsDriver.waitForFastSnapshotRestore(context.Background(), snapshotID, expZone)
+			assert.Equal(t, tc.expEnabled, enabled)
 		})
 	}
 }
 
-func TestGetOutpostArn(t *testing.T) {
-	expRawOutpostArn := "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
-	outpostArn, _ := arn.Parse(strings.ReplaceAll(expRawOutpostArn, "outpost/", ""))
+func TestCreateVolumeFastSnapshotRestoreWait(t *testing.T) {
+	defer func(interval time.Duration) { fsrWaitPollInterval = interval }(fsrWaitPollInterval)
+	fsrWaitPollInterval = time.Millisecond
+
+	const snapshotID = "snap-test"
+	zoneRequirement := &csi.TopologyRequirement{
+		Preferred: []*csi.Topology{{Segments: map[string]string{TopologyKey: expZone}}},
+	}
+
 	testCases := []struct {
-		name          string
-		requirement   *csi.TopologyRequirement
-		expZone       string
-		expOutpostArn string
+		name           string
+		fsrWaitTimeout time.Duration
+		requirement    *csi.TopologyRequirement
+		expectMock     func(mockCloud *cloud.MockCloud)
 	}{
 		{
-			name: "Get from preferred",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone},
-					},
-				},
-				Preferred: []*csi.Topology{
-					{
-						Segments: map[string]string{
-							TopologyKey:     expZone,
-							AwsAccountIDKey: outpostArn.AccountID,
-							AwsOutpostIDKey: outpostArn.Resource,
-							AwsRegionKey:    outpostArn.Region,
-							AwsPartitionKey: outpostArn.Partition,
-						},
-					},
-				},
+			name:           "success: restores after FSR is enabled",
+			fsrWaitTimeout: time.Second,
+			requirement:    zoneRequirement,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Any(), snapshotID, expZone).Return("optimizing", nil),
+					mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Any(), snapshotID, expZone).Return("enabled", nil),
+				)
 			},
-			expZone:       expZone,
-			expOutpostArn: expRawOutpostArn,
 		},
 		{
-			name: "Get from requisite",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{
-							TopologyKey:     expZone,
-							AwsAccountIDKey: outpostArn.AccountID,
-							AwsOutpostIDKey: outpostArn.Resource,
-							AwsRegionKey:    outpostArn.Region,
-							AwsPartitionKey: outpostArn.Partition,
-						},
-					},
-				},
+			name:           "success: restores without FSR when it is not enabled in time",
+			fsrWaitTimeout: 20 * time.Millisecond,
+			requirement:    zoneRequirement,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Any(), snapshotID, expZone).Return("optimizing", nil).MinTimes(2)
 			},
-			expZone:       expZone,
-			expOutpostArn: expRawOutpostArn,
 		},
 		{
-			name: "Get from empty topology",
-			requirement: &csi.TopologyRequirement{
-				Preferred: []*csi.Topology{{}},
-				Requisite: []*csi.Topology{{}},
-			},
-			expZone:       "",
-			expOutpostArn: "",
+			name:           "success: no wait without availability zone",
+			fsrWaitTimeout: time.Second,
 		},
 		{
-			name:          "Topology Requirement is nil",
-			requirement:   nil,
-			expZone:       "",
-			expOutpostArn: "",
+			name:        "success: no wait if disabled",
+			requirement: zoneRequirement,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := getOutpostArn(tc.requirement)
-			if actual != tc.expOutpostArn {
-				t.Fatalf("Expected %v, got outpostArn: %v", tc.expOutpostArn, actual)
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expectMock != nil {
+				tc.expectMock(mockCloud)
+			}
+			mockCloud.EXPECT().CreateDisk(gomock.Any(), "random-vol-name", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+				if diskOptions.SnapshotID != snapshotID {
+					t.Errorf("Expected volume to be restored from snapshot %s, got %q", snapshotID, diskOptions.SnapshotID)
+				}
+				return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 1, AvailabilityZone: expZone, SnapshotID: snapshotID}, nil
+			})
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{fsrWaitTimeout: tc.fsrWaitTimeout},
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				AccessibilityRequirements: tc.requirement,
+				VolumeContentSource: &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: snapshotID},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
 			}
 		})
 	}
 }
 
-func TestBuildOutpostArn(t *testing.T) {
-	expRawOutpostArn := "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
+func TestCreateVolumeColocation(t *testing.T) {
+	const refVolumeID = "vol-ref"
+	zoneRequirement := func(key string, requisite []string, preferred []string) *csi.TopologyRequirement {
+		requirement := &csi.TopologyRequirement{}
+		for _, zone := range requisite {
+			requirement.Requisite = append(requirement.Requisite, &csi.Topology{Segments: map[string]string{key: zone}})
+		}
+		for _, zone := range preferred {
+			requirement.Preferred = append(requirement.Preferred, &csi.Topology{Segments: map[string]string{key: zone}})
+		}
+		return requirement
+	}
+
 	testCases := []struct {
 		name         string
-		awsPartition string
-		awsRegion    string
-		awsAccountID string
-		awsOutpostID string
-		expectedArn  string
+		requirement  *csi.TopologyRequirement
+		refDisk      *cloud.Disk
+		getDiskErr   error
+		expectedZone string
+		expectedCode codes.Code
 	}{
 		{
-			name:         "all fields are present",
-			awsPartition: "aws",
-			awsRegion:    "us-west-2",
-			awsOutpostID: "op-0aaa000a0aaaa00a0",
-			awsAccountID: "111111111111",
-			expectedArn:  expRawOutpostArn,
+			name:         "success: no topology requirement",
+			refDisk:      &cloud.Disk{VolumeID: refVolumeID, AvailabilityZone: "us-east-1b"},
+			expectedZone: "us-east-1b",
 		},
 		{
-			name:         "partition is missing",
-			awsRegion:    "us-west-2",
-			awsOutpostID: "op-0aaa000a0aaaa00a0",
-			awsAccountID: "111111111111",
-			expectedArn:  "",
+			name:         "success: requisite contains zone of referenced volume",
+			requirement:  zoneRequirement(WellKnownTopologyKey, []string{"us-east-1a", "us-east-1b"}, []string{"us-east-1a"}),
+			refDisk:      &cloud.Disk{VolumeID: refVolumeID, AvailabilityZone: "us-east-1b"},
+			expectedZone: "us-east-1b",
 		},
 		{
-			name:         "region is missing",
-			awsPartition: "aws",
-			awsOutpostID: "op-0aaa000a0aaaa00a0",
+			name:         "success: requisite with legacy topology key contains zone of referenced volume",
+			requirement:  zoneRequirement(TopologyKey, []string{"us-east-1b"}, nil),
+			refDisk:      &cloud.Disk{VolumeID: refVolumeID, AvailabilityZone: "us-east-1b"},
+			expectedZone: "us-east-1b",
+		},
+		{
+			name:         "success: preferred zone is overridden",
+			requirement:  zoneRequirement(WellKnownTopologyKey, nil, []string{"us-east-1a"}),
+			refDisk:      &cloud.Disk{VolumeID: refVolumeID, AvailabilityZone: "us-east-1c"},
+			expectedZone: "us-east-1c",
+		},
+		{
+			name:         "fail: requisite does not contain zone of referenced volume",
+			requirement:  zoneRequirement(WellKnownTopologyKey, []string{"us-east-1a", "us-east-1c"}, []string{"us-east-1a"}),
+			refDisk:      &cloud.Disk{VolumeID: refVolumeID, AvailabilityZone: "us-east-1b"},
+			expectedCode: codes.ResourceExhausted,
+		},
+		{
+			name:         "fail: referenced volume not found",
+			getDiskErr:   cloud.ErrNotFound,
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "fail: referenced volume cannot be described",
+			getDiskErr:   errors.New("DescribeVolumes failed"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters:                map[string]string{ColocateWithVolumeIDKey: refVolumeID},
+				AccessibilityRequirements: tc.requirement,
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().GetDiskByID(gomock.Any(), gomock.Eq(refVolumeID)).Return(tc.refDisk, tc.getDiskErr)
+			if tc.expectedCode == codes.OK {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, volumeName string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+						if diskOptions.AvailabilityZone != tc.expectedZone {
+							t.Errorf("Expected volume to be created in zone %s, got %s", tc.expectedZone, diskOptions.AvailabilityZone)
+						}
+						return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 5, AvailabilityZone: diskOptions.AvailabilityZone}, nil
+					})
+			}
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			resp, err := awsDriver.CreateVolume(context.Background(), req)
+			if tc.expectedCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expectedCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if zone := resp.GetVolume().GetAccessibleTopology()[0].GetSegments()[TopologyKey]; zone != tc.expectedZone {
+				t.Fatalf("Expected accessible topology in zone %s, got %s", tc.expectedZone, zone)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeBaselineSnapshot(t *testing.T) {
+	const createdVolumeID = "vol-test"
+	testCases := []struct {
+		name              string
+		parameters        map[string]string
+		existingSnapshots []string
+		getSnapshotsErr   error
+		createSnapshotErr error
+		expSnapshot       bool
+	}{
+		{
+			name:        "success: baseline snapshot created",
+			parameters:  map[string]string{BaselineSnapshotKey: "true"},
+			expSnapshot: true,
+		},
+		{
+			name:              "success: baseline snapshot failure does not fail volume creation",
+			parameters:        map[string]string{BaselineSnapshotKey: "true"},
+			createSnapshotErr: errors.New("SnapshotCreationPerVolumeRateExceeded"),
+			expSnapshot:       true,
+		},
+		{
+			name:              "success: baseline snapshot already exists",
+			parameters:        map[string]string{BaselineSnapshotKey: "true"},
+			existingSnapshots: []string{"snap-test"},
+		},
+		{
+			name:            "success: baseline snapshot lookup failure does not fail volume creation",
+			parameters:      map[string]string{BaselineSnapshotKey: "true"},
+			getSnapshotsErr: errors.New("DescribeSnapshots failed"),
+		},
+		{
+			name:       "success: baseline snapshot disabled",
+			parameters: map[string]string{BaselineSnapshotKey: "false"},
+		},
+		{
+			name: "success: baseline snapshot not requested",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters: tc.parameters,
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).Return(&cloud.Disk{VolumeID: createdVolumeID, CapacityGiB: 5, AvailabilityZone: expZone}, nil)
+
+			done := make(chan struct{})
+			requested := tc.parameters[BaselineSnapshotKey] == "true"
+			if requested {
+				getSnapshots := mockCloud.EXPECT().GetSnapshotIDsByTag(gomock.Any(), gomock.Eq(cloud.BaselineSnapshotTagKey), gomock.Eq(createdVolumeID)).Return(tc.existingSnapshots, tc.getSnapshotsErr)
+				if tc.expSnapshot {
+					mockCloud.EXPECT().CreateSnapshot(gomock.Any(), gomock.Eq(createdVolumeID), gomock.Any()).DoAndReturn(
+						func(ctx context.Context, volumeID string, opts *cloud.SnapshotOptions) (*cloud.Snapshot, error) {
+							defer close(done)
+							expTags := map[string]string{
+								cloud.BaselineSnapshotTagKey:           createdVolumeID,
+								cloud.AwsEbsDriverTagKey:               isManagedByDriver,
+								ResourceLifecycleTagPrefix + "cluster": ResourceLifecycleOwned,
+								NameTag:                                "cluster-baseline-" + createdVolumeID,
+								"extra-tag-key":                        "extra-tag-value",
+							}
+							if !reflect.DeepEqual(opts.Tags, expTags) {
+								t.Errorf("Expected baseline snapshot tags %v, got %v", expTags, opts.Tags)
+							}
+							if tc.createSnapshotErr != nil {
+								return nil, tc.createSnapshotErr
+							}
+							return &cloud.Snapshot{SnapshotID: "snap-test", SourceVolumeID: volumeID}, nil
+						}).After(getSnapshots)
+				} else {
+					getSnapshots.Do(func(ctx context.Context, tagKey, tagValue string) { close(done) })
+				}
+			}
+
+			awsDriver := controllerService{
+				cloud:    mockCloud,
+				inFlight: internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					kubernetesClusterID: "cluster",
+					extraTags:           map[string]string{"extra-tag-key": "extra-tag-value"},
+				},
+			}
+
+			if _, err := awsDriver.CreateVolume(context.Background(), req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if requested {
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Baseline snapshot was not triggered")
+				}
+			}
+		})
+	}
+}
+
+func TestCreateVolumeTagLimit(t *testing.T) {
+	testCases := []struct {
+		name           string
+		dropExcessTags bool
+		createDiskErr  error
+		expErrCode     codes.Code
+	}{
+		{
+			name:           "success: excess tags dropped",
+			dropExcessTags: true,
+			expErrCode:     codes.OK,
+		},
+		{
+			name:          "fail: too many tags",
+			createDiskErr: fmt.Errorf("%w: volume has 60 tags, EC2 allows at most 50", cloud.ErrTooManyTags),
+			expErrCode:    codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, volumeName string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+					if diskOptions.DropExcessTags != tc.dropExcessTags {
+						t.Errorf("Expected DropExcessTags %v, got %v", tc.dropExcessTags, diskOptions.DropExcessTags)
+					}
+					if tc.createDiskErr != nil {
+						return nil, tc.createDiskErr
+					}
+					return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 5, AvailabilityZone: expZone}, nil
+				})
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{dropExcessTags: tc.dropExcessTags},
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), req)
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeMutableParameters(t *testing.T) {
+	testCases := []struct {
+		name          string
+		parameters    map[string]string
+		mutableParams map[string]string
+		expOptions    *cloud.DiskOptions
+		expErrCode    codes.Code
+	}{
+		{
+			name:          "success: mutable parameters override parameters",
+			parameters:    map[string]string{VolumeTypeKey: cloud.VolumeTypeGP3, IopsKey: "3000", ThroughputKey: "125"},
+			mutableParams: map[string]string{ModificationKeyVolumeType: "io2", ModificationKeyIOPS: "4000"},
+			expOptions:    &cloud.DiskOptions{VolumeType: cloud.VolumeTypeIO2, IOPS: 4000, Throughput: 125},
+			expErrCode:    codes.OK,
+		},
+		{
+			name:          "success: mutable IOPS replace IOPS per GB",
+			parameters:    map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1, IopsPerGBKey: "50"},
+			mutableParams: map[string]string{ModificationKeyIOPS: "200"},
+			expOptions:    &cloud.DiskOptions{VolumeType: cloud.VolumeTypeIO1, IOPS: 200},
+			expErrCode:    codes.OK,
+		},
+		{
+			name:          "fail: unknown mutable parameter",
+			mutableParams: map[string]string{"encrypted": "true"},
+			expErrCode:    codes.InvalidArgument,
+		},
+		{
+			name:          "fail: invalid mutable parameter value",
+			mutableParams: map[string]string{ModificationKeyThroughput: "fast"},
+			expErrCode:    codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters:        tc.parameters,
+				MutableParameters: tc.mutableParams,
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expOptions != nil {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, volumeName string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+						assert.Equal(t, tc.expOptions.VolumeType, diskOptions.VolumeType)
+						assert.Equal(t, tc.expOptions.IOPS, diskOptions.IOPS)
+						assert.Equal(t, tc.expOptions.IOPSPerGB, diskOptions.IOPSPerGB)
+						assert.Equal(t, tc.expOptions.Throughput, diskOptions.Throughput)
+						return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 5, AvailabilityZone: expZone}, nil
+					})
+			}
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), req)
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeOutOfVolumeTypeLimits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		createDiskErr error
+		expErrCode    codes.Code
+	}{
+		{
+			name:          "fail: size out of range",
+			createDiskErr: fmt.Errorf("%w: 10 GiB, st1 volumes must be between 125 GiB and 16384 GiB", cloud.ErrCapacityOutOfRange),
+			expErrCode:    codes.OutOfRange,
+		},
+		{
+			name:          "fail: invalid volume parameters",
+			createDiskErr: fmt.Errorf("%w: throughput 100 MiB/s is too low, gp3 volumes must have at least 125 MiB/s", cloud.ErrInvalidVolumeParameters),
+			expErrCode:    codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 10 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			// Errors of the parameters of a volume are not retried
+			mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).Return(nil, tc.createDiskErr).Times(1)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{createVolumeRetries: 2},
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), req)
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeOutpostArnParameter(t *testing.T) {
+	const (
+		outpostArn      = "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
+		otherOutpostArn = "arn:aws:outposts:us-west-2:111111111111:outpost/op-0bbb000b0bbbb00b0"
+	)
+	outpostTopology := func(outpostID string) *csi.TopologyRequirement {
+		return &csi.TopologyRequirement{
+			Requisite: []*csi.Topology{
+				{
+					Segments: map[string]string{
+						TopologyKey:     expZone,
+						AwsAccountIDKey: "111111111111",
+						AwsOutpostIDKey: outpostID,
+						AwsRegionKey:    "us-west-2",
+						AwsPartitionKey: "aws",
+					},
+				},
+			},
+		}
+	}
+	testCases := []struct {
+		name          string
+		outpostArn    string
+		topology      *csi.TopologyRequirement
+		expOutpostArn string
+		expErrCode    codes.Code
+	}{
+		{
+			name:          "success: parameter",
+			outpostArn:    outpostArn,
+			expOutpostArn: outpostArn,
+		},
+		{
+			name:          "success: parameter matches topology",
+			outpostArn:    outpostArn,
+			topology:      outpostTopology("op-0aaa000a0aaaa00a0"),
+			expOutpostArn: outpostArn,
+		},
+		{
+			name:       "fail: parameter does not match topology",
+			outpostArn: otherOutpostArn,
+			topology:   outpostTopology("op-0aaa000a0aaaa00a0"),
+			expErrCode: codes.InvalidArgument,
+		},
+		{
+			name:       "fail: parameter not an outpost arn",
+			outpostArn: "arn:aws:ec2:us-west-2:111111111111:instance/i-0aaa000a0aaaa00a0",
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 10 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters:                map[string]string{"outpostArn": tc.outpostArn},
+				AccessibilityRequirements: tc.topology,
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expErrCode == codes.OK {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+						if diskOptions.OutpostArn != tc.expOutpostArn {
+							t.Errorf("expected outpost arn %q, got %q", tc.expOutpostArn, diskOptions.OutpostArn)
+						}
+						return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 10, AvailabilityZone: expZone, OutpostArn: diskOptions.OutpostArn}, nil
+					})
+			}
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), req)
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeEncryptionFromSnapshot(t *testing.T) {
+	const kmsKeyID = "arn:aws:kms:us-west-2:111111111111:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	testCases := []struct {
+		name      string
+		params    map[string]string
+		snapshot  *cloud.Snapshot
+		getErr    error
+		expOpts   *cloud.DiskOptions
+		expErrMsg string
+	}{
+		{
+			name:    "success: encryption of snapshot",
+			expOpts: &cloud.DiskOptions{},
+		},
+		{
+			name:    "success: encrypt unencrypted snapshot",
+			params:  map[string]string{"encrypted": "true"},
+			expOpts: &cloud.DiskOptions{Encrypted: true},
+		},
+		{
+			name:    "success: encrypt with another KMS key",
+			params:  map[string]string{"encrypted": "true", "kmsKeyId": kmsKeyID},
+			expOpts: &cloud.DiskOptions{Encrypted: true, KmsKeyID: kmsKeyID},
+		},
+		{
+			name:     "success: unencrypted snapshot not encrypted",
+			params:   map[string]string{"encrypted": "false"},
+			snapshot: &cloud.Snapshot{SnapshotID: "snap-test"},
+			expOpts:  &cloud.DiskOptions{},
+		},
+		{
+			name:    "success: snapshot not found when checking encryption",
+			params:  map[string]string{"encrypted": "false"},
+			getErr:  cloud.ErrNotFound,
+			expOpts: &cloud.DiskOptions{},
+		},
+		{
+			name:      "fail: encrypted snapshot not encrypted",
+			params:    map[string]string{"encrypted": "false"},
+			snapshot:  &cloud.Snapshot{SnapshotID: "snap-test", Encrypted: true, KmsKeyID: kmsKeyID},
+			expErrMsg: `Cannot restore encrypted snapshot "snap-test" to a volume with encrypted false`,
+		},
+		{
+			name:      "fail: KMS key of unencrypted volume",
+			params:    map[string]string{"encrypted": "false", "kmsKeyId": kmsKeyID},
+			expErrMsg: "Cannot set kmsKeyId when encrypted is false",
+		},
+		{
+			name:    "success: encrypted other than true or false",
+			params:  map[string]string{"encrypted": "yes"},
+			expOpts: &cloud.DiskOptions{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &csi.CreateVolumeRequest{
+				Name:          "random-vol-name",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 10 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+						AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+					},
+				},
+				Parameters: tc.params,
+				VolumeContentSource: &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: "snap-test"},
+					},
+				},
+			}
+
+			awsDriver, mockCtl, mockCloud := createControllerService(t)
+			defer mockCtl.Finish()
+
+			if tc.snapshot != nil || tc.getErr != nil {
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), gomock.Eq("snap-test")).Return(tc.snapshot, tc.getErr)
+			}
+			if tc.expOpts != nil {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+						if diskOptions.SnapshotID != "snap-test" || diskOptions.Encrypted != tc.expOpts.Encrypted || diskOptions.KmsKeyID != tc.expOpts.KmsKeyID {
+							t.Errorf("expected snapshot %q, encrypted %t and KMS key %q, got %q, %t and %q", "snap-test", tc.expOpts.Encrypted, tc.expOpts.KmsKeyID, diskOptions.SnapshotID, diskOptions.Encrypted, diskOptions.KmsKeyID)
+						}
+						return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 10, AvailabilityZone: expZone, SnapshotID: diskOptions.SnapshotID}, nil
+					})
+			}
+
+			_, err := awsDriver.CreateVolume(context.Background(), req)
+			if tc.expErrMsg != "" {
+				checkExpectedErrorCode(t, err, codes.InvalidArgument)
+				if msg := status.Convert(err).Message(); msg != tc.expErrMsg {
+					t.Fatalf("expected error message %q, got %q", tc.expErrMsg, msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeResultCache(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	req := &csi.CreateVolumeRequest{
+		Name:          "random-vol-name",
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 10 * util.GiB},
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{},
+				},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+	}
+	disk := &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 10, AvailabilityZone: expZone}
+
+	mockCloud := cloud.NewMockCloud(mockCtl)
+	// Retries are answered from the cache until the volume is deleted
+	gomock.InOrder(
+		mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).Return(disk, nil),
+		mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).Return(nil, cloud.ErrIdempotentParameterMismatch),
+		mockCloud.EXPECT().DeleteDisk(gomock.Any(), gomock.Eq(disk.VolumeID)).Return(true, nil),
+		mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Eq(req.Name), gomock.Any()).Return(disk, nil),
+	)
+
+	awsDriver := controllerService{
+		cloud:         mockCloud,
+		inFlight:      internal.NewInFlight(),
+		results:       internal.NewResultCache(time.Minute),
+		driverOptions: &DriverOptions{},
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := awsDriver.CreateVolume(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resp.GetVolume().GetVolumeId() != disk.VolumeID {
+			t.Fatalf("expected volume %q, got %q", disk.VolumeID, resp.GetVolume().GetVolumeId())
+		}
+	}
+
+	// A request for a different volume with the same name is not answered from the cache
+	largerReq := &csi.CreateVolumeRequest{
+		Name:               req.Name,
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 20 * util.GiB},
+		VolumeCapabilities: req.VolumeCapabilities,
+	}
+	_, err := awsDriver.CreateVolume(context.Background(), largerReq)
+	checkExpectedErrorCode(t, err, codes.AlreadyExists)
+
+	for i := 0; i < 2; i++ {
+		if _, err = awsDriver.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: disk.VolumeID}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if _, err = awsDriver.CreateVolume(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error after deleting the volume: %v", err)
+	}
+}
+
+func TestCreateSnapshotResultCache(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	req := &csi.CreateSnapshotRequest{
+		Name:           "test-snapshot",
+		SourceVolumeId: "vol-test",
+	}
+	pending := &cloud.Snapshot{SnapshotID: "snap-test", SourceVolumeID: req.SourceVolumeId, Size: 1, CreationTime: time.Now()}
+	completed := *pending
+	completed.ReadyToUse = true
+
+	mockCloud := cloud.NewMockCloud(mockCtl)
+	// The snapshotter polls pending snapshots, which must not be answered from the cache
+	gomock.InOrder(
+		mockCloud.EXPECT().GetSnapshotByName(gomock.Any(), gomock.Eq(req.Name)).Return(pending, nil),
+		mockCloud.EXPECT().GetSnapshotByName(gomock.Any(), gomock.Eq(req.Name)).Return(&completed, nil),
+	)
+
+	awsDriver := controllerService{
+		cloud:         mockCloud,
+		inFlight:      internal.NewInFlight(),
+		results:       internal.NewResultCache(time.Minute),
+		driverOptions: &DriverOptions{},
+	}
+
+	for _, expReady := range []bool{false, true, true} {
+		resp, err := awsDriver.CreateSnapshot(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resp.GetSnapshot().GetReadyToUse() != expReady {
+			t.Fatalf("expected ready to use %v, got %v", expReady, resp.GetSnapshot().GetReadyToUse())
+		}
+	}
+}
+
+func TestClassifyCreateDiskError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expClass createDiskErrorClass
+	}{
+		{
+			name:     "throttling is safe",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)),
+			expClass: createDiskErrorSafe,
+		},
+		{
+			name:     "insufficient capacity is safe",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.New("InsufficientVolumeCapacity", "There is not enough capacity.", nil)),
+			expClass: createDiskErrorSafe,
+		},
+		{
+			name:     "other client error is safe",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.NewRequestFailure(awserr.New("UnknownClientError", "Unknown.", nil), http.StatusBadRequest, "")),
+			expClass: createDiskErrorSafe,
+		},
+		{
+			name:     "server error is unsafe",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.NewRequestFailure(awserr.New("InternalError", "An internal error has occurred.", nil), http.StatusInternalServerError, "")),
+			expClass: createDiskErrorUnsafe,
+		},
+		{
+			name:     "unknown error is unsafe",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.New("InternalError", "An internal error has occurred.", nil)),
+			expClass: createDiskErrorUnsafe,
+		},
+		{
+			name:     "timeout is unsafe",
+			err:      fmt.Errorf("could not create volume in EC2: %w", &net.DNSError{Err: "i/o timeout", IsTimeout: true}),
+			expClass: createDiskErrorUnsafe,
+		},
+		{
+			name:     "volume not available is unsafe",
+			err:      fmt.Errorf("%w: %w", cloud.ErrVolumeNotAvailable, wait.ErrWaitTimeout),
+			expClass: createDiskErrorUnsafe,
+		},
+		{
+			name:     "invalid parameter is permanent",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.New("InvalidParameterValue", "Invalid iops.", nil)),
+			expClass: createDiskErrorPermanent,
+		},
+		{
+			name:     "size out of range of volume type is permanent",
+			err:      fmt.Errorf("%w: 10 GiB, st1 volumes must be between 125 GiB and 16384 GiB", cloud.ErrCapacityOutOfRange),
+			expClass: createDiskErrorPermanent,
+		},
+		{
+			name:     "quota is permanent",
+			err:      fmt.Errorf("could not create volume in EC2: %w", awserr.New("VolumeLimitExceeded", "Volume limit exceeded.", nil)),
+			expClass: createDiskErrorPermanent,
+		},
+		{
+			name:     "idempotent parameter mismatch is permanent",
+			err:      cloud.ErrIdempotentParameterMismatch,
+			expClass: createDiskErrorPermanent,
+		},
+		{
+			name:     "deadline exceeded is permanent",
+			err:      fmt.Errorf("could not create volume in EC2: %w", context.DeadlineExceeded),
+			expClass: createDiskErrorPermanent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if class := classifyCreateDiskError(tc.err); class != tc.expClass {
+				t.Fatalf("Expected class %d, got %d", tc.expClass, class)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeRetries(t *testing.T) {
+	defer func(interval time.Duration) { createDiskRetryInterval = interval }(createDiskRetryInterval)
+	createDiskRetryInterval = time.Millisecond
+
+	const volName = "random-vol-name"
+	throttled := fmt.Errorf("could not create volume in EC2: %w", awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil))
+	serverErr := fmt.Errorf("could not create volume in EC2: %w", awserr.New("InternalError", "An internal error has occurred.", nil))
+	invalidErr := fmt.Errorf("could not create volume in EC2: %w", awserr.New("InvalidParameterValue", "Invalid iops.", nil))
+	notAvailableErr := fmt.Errorf("%w: %w", cloud.ErrVolumeNotAvailable, wait.ErrWaitTimeout)
+	disk := &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 5, AvailabilityZone: expZone}
+	diskIn := func(state string) *cloud.Disk {
+		return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 5, AvailabilityZone: expZone, State: state}
+	}
+	// createDiskAttempt expects CreateDisk to be called with retryAttempt and returns disk or err
+	createDiskAttempt := func(mockCloud *cloud.MockCloud, retryAttempt int, disk *cloud.Disk, err error) *gomock.Call {
+		return mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, opts *cloud.DiskOptions) (*cloud.Disk, error) {
+			if opts.RetryAttempt != retryAttempt {
+				t.Errorf("Expected retry attempt %d, got %d", retryAttempt, opts.RetryAttempt)
+			}
+			return disk, err
+		})
+	}
+
+	testCases := []struct {
+		name       string
+		retries    int
+		expectMock func(mockCloud *cloud.MockCloud)
+		expErrCode codes.Code
+	}{
+		{
+			name:    "success: safe error retried without lookup",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, throttled),
+					mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(disk, nil),
+				)
+			},
+			expErrCode: codes.OK,
+		},
+		{
+			name:    "success: unsafe error retried with the same client token after volume not found",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskAttempt(mockCloud, 0, nil, serverErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(nil, cloud.ErrNotFound),
+					createDiskAttempt(mockCloud, 0, disk, nil),
+				)
+			},
+			expErrCode: codes.OK,
+		},
+		{
+			name:    "success: unsafe error retried with the same client token while volume is creating",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskAttempt(mockCloud, 0, nil, serverErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(diskIn(ec2.VolumeStateCreating), nil),
+					createDiskAttempt(mockCloud, 0, disk, nil),
+				)
+			},
+			expErrCode: codes.OK,
+		},
+		{
+			name:    "success: unsafe error returns volume found by name",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, serverErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(diskIn(ec2.VolumeStateAvailable), nil),
+				)
+			},
+			expErrCode: codes.OK,
+		},
+		{
+			name:    "success: volume not available retried with another client token after volume deleted",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskAttempt(mockCloud, 0, nil, notAvailableErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(diskIn(ec2.VolumeStateDeleting), nil),
+					createDiskAttempt(mockCloud, 1, disk, nil),
+				)
+			},
+			expErrCode: codes.OK,
+		},
+		{
+			name:    "success: volume not available retried with another client token after volume not found",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskAttempt(mockCloud, 0, nil, notAvailableErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(nil, cloud.ErrNotFound),
+					createDiskAttempt(mockCloud, 1, nil, notAvailableErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(nil, cloud.ErrNotFound),
+					createDiskAttempt(mockCloud, 2, disk, nil),
+				)
+			},
+			expErrCode: codes.OK,
+		},
+		{
+			name:    "fail: unsafe error not retried when lookup fails",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, serverErr),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(nil, errors.New("DescribeVolumes failed")),
+				)
+			},
+			expErrCode: codes.Internal,
+		},
+		{
+			name:    "fail: permanent error not retried",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, invalidErr)
+			},
+			expErrCode: codes.Internal,
+		},
+		{
+			name:    "fail: retries exhausted",
+			retries: 2,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, throttled).Times(3)
+			},
+			expErrCode: codes.Internal,
+		},
+		{
+			name: "fail: retries disabled",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).Return(nil, throttled)
+			},
+			expErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			tc.expectMock(mockCloud)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{createVolumeRetries: tc.retries},
+			}
+
+			resp, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:          volName,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+			})
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if resp.GetVolume().GetVolumeId() != disk.VolumeID {
+					t.Fatalf("Expected volume %s, got %s", disk.VolumeID, resp.GetVolume().GetVolumeId())
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeAvailabilityZoneFallback(t *testing.T) {
+	const volName = "random-vol-name"
+	insufficientCapacity := fmt.Errorf("could not create volume in EC2: %w", awserr.New("InsufficientVolumeCapacity", "There is not enough capacity.", nil))
+	invalidErr := fmt.Errorf("could not create volume in EC2: %w", awserr.New("InvalidParameterValue", "Invalid iops.", nil))
+	diskIn := func(zone string) *cloud.Disk {
+		return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 5, AvailabilityZone: zone}
+	}
+	// createDiskIn expects CreateDisk to be called in zone and returns disk or err
+	createDiskIn := func(mockCloud *cloud.MockCloud, zone string, disk *cloud.Disk, err error) *gomock.Call {
+		return mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, opts *cloud.DiskOptions) (*cloud.Disk, error) {
+			if opts.AvailabilityZone != zone {
+				t.Errorf("Expected volume to be created in %s, got %s", zone, opts.AvailabilityZone)
+			}
+			return disk, err
+		})
+	}
+
+	testCases := []struct {
+		name       string
+		expectMock func(mockCloud *cloud.MockCloud)
+		expZone    string
+		expErrCode codes.Code
+	}{
+		{
+			name: "success: first zone",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				createDiskIn(mockCloud, "us-east-1b", diskIn("us-east-1b"), nil)
+			},
+			expZone:    "us-east-1b",
+			expErrCode: codes.OK,
+		},
+		{
+			name: "success: next zone after insufficient capacity",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskIn(mockCloud, "us-east-1b", nil, insufficientCapacity),
+					createDiskIn(mockCloud, "us-east-1a", nil, insufficientCapacity),
+					createDiskIn(mockCloud, "us-east-1c", diskIn("us-east-1c"), nil),
+				)
+			},
+			expZone:    "us-east-1c",
+			expErrCode: codes.OK,
+		},
+		{
+			name: "success: volume created in another zone by a previous request",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskIn(mockCloud, "us-east-1b", nil, cloud.ErrIdempotentParameterMismatch),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(diskIn("us-east-1a"), nil),
+				)
+			},
+			expZone:    "us-east-1a",
+			expErrCode: codes.OK,
+		},
+		{
+			name: "fail: volume created by a previous request in a zone that is not allowed",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskIn(mockCloud, "us-east-1b", nil, cloud.ErrIdempotentParameterMismatch),
+					mockCloud.EXPECT().GetDiskByName(gomock.Any(), volName, int64(5*util.GiB)).Return(diskIn("us-east-1d"), nil),
+				)
+			},
+			expErrCode: codes.AlreadyExists,
+		},
+		{
+			name: "fail: insufficient capacity in all zones",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				gomock.InOrder(
+					createDiskIn(mockCloud, "us-east-1b", nil, insufficientCapacity),
+					createDiskIn(mockCloud, "us-east-1a", nil, insufficientCapacity),
+					createDiskIn(mockCloud, "us-east-1c", nil, insufficientCapacity),
+				)
+			},
+			expErrCode: codes.Internal,
+		},
+		{
+			name: "fail: other errors are not retried in the next zone",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				createDiskIn(mockCloud, "us-east-1b", nil, invalidErr)
+			},
+			expErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			tc.expectMock(mockCloud)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			resp, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:          volName,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 5 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				AccessibilityRequirements: &csi.TopologyRequirement{
+					Requisite: []*csi.Topology{
+						{Segments: map[string]string{WellKnownTopologyKey: "us-east-1a"}},
+						{Segments: map[string]string{WellKnownTopologyKey: "us-east-1b"}},
+						{Segments: map[string]string{WellKnownTopologyKey: "us-east-1c"}},
+					},
+					Preferred: []*csi.Topology{
+						{Segments: map[string]string{WellKnownTopologyKey: "us-east-1b"}},
+					},
+				},
+			})
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if zone := resp.GetVolume().GetAccessibleTopology()[0].GetSegments()[TopologyKey]; zone != tc.expZone {
+					t.Fatalf("Expected volume in %s, got %s", tc.expZone, zone)
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeLeastUsedPlacement(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := cloud.NewMockCloud(mockCtl)
+	var zones []string
+	mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, volName string, opts *cloud.DiskOptions) (*cloud.Disk, error) {
+		zones = append(zones, opts.AvailabilityZone)
+		return &cloud.Disk{VolumeID: "vol-" + volName, CapacityGiB: 1, AvailabilityZone: opts.AvailabilityZone}, nil
+	}).Times(4)
+
+	awsDriver := controllerService{
+		cloud:         mockCloud,
+		inFlight:      internal.NewInFlight(),
+		driverOptions: &DriverOptions{},
+		placer:        newVolumePlacer(PlacementStrategyLeastUsed),
+	}
+	for i := 0; i < 4; i++ {
+		_, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+			Name: fmt.Sprintf("vol-%d", i),
+			VolumeCapabilities: []*csi.VolumeCapability{
+				{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+			},
+			AccessibilityRequirements: &csi.TopologyRequirement{
+				Preferred: []*csi.Topology{
+					{Segments: map[string]string{WellKnownTopologyKey: "us-east-1b"}},
+					{Segments: map[string]string{WellKnownTopologyKey: "us-east-1a"}},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expZones := []string{"us-east-1a", "us-east-1b", "us-east-1a", "us-east-1b"}
+	if !reflect.DeepEqual(zones, expZones) {
+		t.Fatalf("Expected volumes in %v, got %v", expZones, zones)
+	}
+}
+
+func TestCreateVolumeMountOptions(t *testing.T) {
+	testCases := []struct {
+		name                string
+		fsType              string
+		mountFlags          []string
+		block               bool
+		volumeType          string
+		defaultMountOptions []string
+		discardVolumeTypes  []string
+		expErrCode          codes.Code
+		expDefaults         string
+	}{
+		{
+			name:       "valid mount options without defaults",
+			mountFlags: []string{"noatime", "discard"},
+		},
+		{
+			name:       "fail conflicting mount options",
+			mountFlags: []string{"discard", "nodiscard"},
+			expErrCode: codes.InvalidArgument,
+		},
+		{
+			name:       "fail mount option not supported by fstype",
+			fsType:     FSTypeXfs,
+			mountFlags: []string{"data=ordered"},
+			expErrCode: codes.InvalidArgument,
+		},
+		{
+			name:                "default mount options supported by fstype",
+			fsType:              FSTypeXfs,
+			defaultMountOptions: []string{"noatime", "commit=30", "inode64"},
+			expDefaults:         "noatime,inode64",
+		},
+		{
+			name:               "discard for default volume type",
+			discardVolumeTypes: []string{cloud.VolumeTypeGP3},
+			expDefaults:        "discard",
+		},
+		{
+			name:               "no discard for other volume type",
+			volumeType:         cloud.VolumeTypeIO2,
+			discardVolumeTypes: []string{cloud.VolumeTypeGP3},
+		},
+		{
+			name:                "no default mount options for block volume",
+			block:               true,
+			defaultMountOptions: []string{"noatime"},
+			discardVolumeTypes:  []string{cloud.VolumeTypeGP3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expErrCode == codes.OK {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), "random-vol-name", gomock.Any()).Return(&cloud.Disk{VolumeID: "vol-test", CapacityGiB: 1, AvailabilityZone: expZone}, nil)
+			}
+
+			awsDriver := controllerService{
+				cloud:    mockCloud,
+				inFlight: internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					defaultMountOptions: tc.defaultMountOptions,
+					discardVolumeTypes:  tc.discardVolumeTypes,
+				},
+			}
+
+			volCap := &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{FsType: tc.fsType, MountFlags: tc.mountFlags},
+				},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			}
+			if tc.block {
+				volCap.AccessType = &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}
+			}
+			parameters := map[string]string{}
+			if tc.volumeType != "" {
+				parameters[VolumeTypeKey] = tc.volumeType
+			}
+
+			resp, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:               "random-vol-name",
+				VolumeCapabilities: []*csi.VolumeCapability{volCap},
+				Parameters:         parameters,
+			})
+			if tc.expErrCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expErrCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if defaults := resp.GetVolume().GetVolumeContext()[VolumeAttributeDefaultMountOptions]; defaults != tc.expDefaults {
+				t.Fatalf("Expected default mount options %q, got %q", tc.expDefaults, defaults)
+			}
+		})
+	}
+}
+
+func TestApplyGrowthHeadroom(t *testing.T) {
+	testCases := []struct {
+		name           string
+		volSizeBytes   int64
+		headroom       string
+		limitBytes     int64
+		maxSizeGiB     int64
+		granularityGiB int64
+		expSizeBytes   int64
+		expErr         bool
+	}{
+		{
+			name:         "percentage of requested size",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "20%",
+			expSizeBytes: 120 * util.GiB,
+		},
+		{
+			name:         "rounded up to GiB",
+			volSizeBytes: 5 * util.GiB,
+			headroom:     "10%",
+			expSizeBytes: 6 * util.GiB,
+		},
+		{
+			name:         "zero headroom",
+			volSizeBytes: 5 * util.GiB,
+			headroom:     "0%",
+			expSizeBytes: 5 * util.GiB,
+		},
+		{
+			name:         "capped at limit",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "50%",
+			limitBytes:   130*util.GiB + 1,
+			expSizeBytes: 130 * util.GiB,
+		},
+		{
+			name:         "limit below requested size",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "50%",
+			limitBytes:   100*util.GiB + 1,
+			expSizeBytes: 100 * util.GiB,
+		},
+		{
+			name:         "capped at maximum size of volume type",
+			volSizeBytes: 15000 * util.GiB,
+			headroom:     "20%",
+			maxSizeGiB:   16384,
+			expSizeBytes: 16384 * util.GiB,
+		},
+		{
+			name:         "capped at limit below maximum size of volume type",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "50%",
+			limitBytes:   130 * util.GiB,
+			maxSizeGiB:   16384,
+			expSizeBytes: 130 * util.GiB,
+		},
+		{
+			name:           "granularity not rounded past maximum size of volume type",
+			volSizeBytes:   16000 * util.GiB,
+			headroom:       "10%",
+			maxSizeGiB:     16384,
+			granularityGiB: 1000,
+			expSizeBytes:   16384 * util.GiB,
+		},
+		{
+			name:           "rounded up to granularity",
+			volSizeBytes:   100 * util.GiB,
+			headroom:       "10%",
+			granularityGiB: 16,
+			expSizeBytes:   112 * util.GiB,
+		},
+		{
+			name:         "missing percent sign",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "20",
+			expErr:       true,
+		},
+		{
+			name:         "negative percentage",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "-20%",
+			expErr:       true,
+		},
+		{
+			name:         "quantity",
+			volSizeBytes: 100 * util.GiB,
+			headroom:     "10Gi",
+			expErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sizeBytes, err := applyGrowthHeadroom(tc.volSizeBytes, tc.headroom, tc.limitBytes, tc.maxSizeGiB, tc.granularityGiB)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if sizeBytes != tc.expSizeBytes {
+				t.Fatalf("Expected size %d, got %d", tc.expSizeBytes, sizeBytes)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeGrowthHeadroom(t *testing.T) {
+	const volName = "random-vol-name"
+
+	testCases := []struct {
+		name           string
+		headroom       string
+		expSizeGiB     int64
+		expHeadroomTag string
+		expErrCode     codes.Code
+	}{
+		{
+			name:           "success: headroom added and tagged",
+			headroom:       "20%",
+			expSizeGiB:     12,
+			expHeadroomTag: "2GiB",
+			expErrCode:     codes.OK,
+		},
+		{
+			name:       "success: no headroom is not tagged",
+			headroom:   "0%",
+			expSizeGiB: 10,
+			expErrCode: codes.OK,
+		},
+		{
+			name:       "fail: invalid headroom",
+			headroom:   "twenty",
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expErrCode == codes.OK {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+					if diskOptions.CapacityBytes != tc.expSizeGiB*util.GiB {
+						t.Errorf("Expected size %d GiB, got %d bytes", tc.expSizeGiB, diskOptions.CapacityBytes)
+					}
+					if tag := diskOptions.Tags[cloud.GrowthHeadroomTagKey]; tag != tc.expHeadroomTag {
+						t.Errorf("Expected tag %s to be %q, got %q", cloud.GrowthHeadroomTagKey, tc.expHeadroomTag, tag)
+					}
+					return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: tc.expSizeGiB, AvailabilityZone: expZone}, nil
+				})
+			}
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			resp, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:          volName,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 10 * util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters: map[string]string{GrowthHeadroomKey: tc.headroom},
+			})
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if resp.GetVolume().GetCapacityBytes() != tc.expSizeGiB*util.GiB {
+					t.Fatalf("Expected capacity %d GiB, got %d bytes", tc.expSizeGiB, resp.GetVolume().GetCapacityBytes())
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestCreateVolumeFromSnapshotInSourceRegion(t *testing.T) {
+	const (
+		volName      = "random-vol-name"
+		snapshotID   = "snap-source"
+		sourceRegion = "us-east-1"
+	)
+
+	testCases := []struct {
+		name              string
+		expectMock        func(mockCloud *cloud.MockCloud)
+		expRestoredFromID string
+		expErrCode        codes.Code
+	}{
+		{
+			name: "success: snapshot found in region is not copied",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), snapshotID).Return(&cloud.Snapshot{SnapshotID: snapshotID, ReadyToUse: true}, nil)
+			},
+			expRestoredFromID: snapshotID,
+			expErrCode:        codes.OK,
+		},
+		{
+			name: "success: snapshot not found in region is restored from its copy",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), snapshotID).Return(nil, cloud.ErrNotFound)
+				mockCloud.EXPECT().CopySnapshotFromRegion(gomock.Any(), snapshotID, sourceRegion, gomock.Any()).DoAndReturn(func(_ context.Context, _, _ string, copyOptions *cloud.CopySnapshotOptions) (*cloud.Snapshot, error) {
+					assert.Equal(t, map[string]string{
+						cloud.AwsEbsDriverTagKey:                    isManagedByDriver,
+						ResourceLifecycleTagPrefix + "test-cluster": ResourceLifecycleOwned,
+						NameTag: "test-cluster-copy-" + snapshotID,
+					}, copyOptions.Tags)
+					assert.True(t, copyOptions.Encrypted)
+					return &cloud.Snapshot{SnapshotID: "snap-copy", ReadyToUse: true}, nil
+				})
+			},
+			expRestoredFromID: "snap-copy",
+			expErrCode:        codes.OK,
+		},
+		{
+			name: "fail: snapshot not found in source region",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), snapshotID).Return(nil, cloud.ErrNotFound)
+				mockCloud.EXPECT().CopySnapshotFromRegion(gomock.Any(), snapshotID, sourceRegion, gomock.Any()).Return(nil, cloud.ErrNotFound)
+			},
+			expErrCode: codes.NotFound,
+		},
+		{
+			name: "fail: snapshot copy still in progress",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), snapshotID).Return(nil, cloud.ErrNotFound)
+				mockCloud.EXPECT().CopySnapshotFromRegion(gomock.Any(), snapshotID, sourceRegion, gomock.Any()).Return(nil, fmt.Errorf("could not wait for copy: %w", context.DeadlineExceeded))
+			},
+			expErrCode: codes.DeadlineExceeded,
+		},
+		{
+			name: "fail: snapshot could not be looked up",
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), snapshotID).Return(nil, errors.New("DescribeSnapshots error"))
+			},
+			expErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			tc.expectMock(mockCloud)
+			if tc.expErrCode == codes.OK {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), volName, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+					if diskOptions.SnapshotID != tc.expRestoredFromID {
+						t.Errorf("Expected volume to be restored from snapshot %s, got %q", tc.expRestoredFromID, diskOptions.SnapshotID)
+					}
+					return &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 1, AvailabilityZone: expZone, SnapshotID: diskOptions.SnapshotID}, nil
+				})
+			}
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{kubernetesClusterID: "test-cluster"},
+			}
+
+			resp, err := awsDriver.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:          volName,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: util.GiB},
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters: map[string]string{
+					SourceRegionKey: sourceRegion,
+					EncryptedKey:    "true",
+				},
+				VolumeContentSource: &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: snapshotID},
+					},
+				},
+			})
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				// The volume is reported as restored from the requested snapshot, not from its copy
+				assert.Equal(t, snapshotID, resp.GetVolume().GetContentSource().GetSnapshot().GetSnapshotId())
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+		})
+	}
+}
+
+func TestDeleteVolume(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "vol-test",
+				}
+				expResp := &csi.DeleteVolumeResponse{}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(true, nil)
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := awsDriver.DeleteVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				}
+			},
+		},
+		{
+			name: "success invalid volume id",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "invalid-volume-name",
+				}
+				expResp := &csi.DeleteVolumeResponse{}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, cloud.ErrNotFound)
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := awsDriver.DeleteVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				}
+			},
+		},
+		{
+			name: "fail delete disk",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "test-vol",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, fmt.Errorf("DeleteDisk could not delete volume"))
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := awsDriver.DeleteVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.Internal {
+						t.Fatalf("Unexpected error: %v", srvErr.Code())
+					}
+				} else {
+					t.Fatalf("Expected error, got nil")
+				}
+
+				if resp != nil {
+					t.Fatalf("Expected resp to be nil, got: %+v", resp)
+				}
+			},
+		},
+		{
+			name: "fail another request already in-flight",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "vol-test",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				inFlight := internal.NewInFlight()
+				inFlight.Insert(req.GetVolumeId())
+				defer inFlight.Delete(req.GetVolumeId())
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      inFlight,
+					driverOptions: &DriverOptions{},
+				}
+				_, err := awsDriver.DeleteVolume(ctx, req)
+
+				checkExpectedErrorCode(t, err, codes.Aborted)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestAvailabilityZones(t *testing.T) {
+	testCases := []struct {
+		name        string
+		requirement *csi.TopologyRequirement
+		expZones    []string
+	}{
+		{
+			name: "Return WellKnownTopologyKey if present from preferred",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: ""},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone, WellKnownTopologyKey: "foobar"},
+					},
+				},
+			},
+			expZones: []string{"foobar"},
+		},
+		{
+			name: "Return WellKnownTopologyKey if present from requisite",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone, WellKnownTopologyKey: "foobar"},
+					},
+				},
+			},
+			expZones: []string{"foobar"},
+		},
+		{
+			name: "Pick from preferred",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: ""},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone},
+					},
+				},
+			},
+			expZones: []string{expZone},
+		},
+		{
+			name: "Pick from requisite",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone},
+					},
+				},
+			},
+			expZones: []string{expZone},
+		},
+		{
+			name: "Preferred zones come first without duplicates",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownTopologyKey: "us-east-1a"},
+					},
+					{
+						Segments: map[string]string{WellKnownTopologyKey: "us-east-1b"},
+					},
+					{
+						Segments: map[string]string{WellKnownTopologyKey: "us-east-1c"},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownTopologyKey: "us-east-1c"},
+					},
+					{
+						Segments: map[string]string{WellKnownTopologyKey: "us-east-1a"},
+					},
+				},
+			},
+			expZones: []string{"us-east-1c", "us-east-1a", "us-east-1b"},
+		},
+		{
+			name: "Pick from empty topology",
+			requirement: &csi.TopologyRequirement{
+				Preferred: []*csi.Topology{{}},
+				Requisite: []*csi.Topology{{}},
+			},
+			expZones: nil,
+		},
+		{
+			name:        "Topology Requirement is nil",
+			requirement: nil,
+			expZones:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := availabilityZones(tc.requirement)
+			if !reflect.DeepEqual(actual, tc.expZones) {
+				t.Fatalf("Expected zones %v, got zones: %v", tc.expZones, actual)
+			}
+		})
+	}
+}
+
+func TestGetOutpostArn(t *testing.T) {
+	expRawOutpostArn := "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
+	outpostArn, _ := arn.Parse(strings.ReplaceAll(expRawOutpostArn, "outpost/", ""))
+	testCases := []struct {
+		name          string
+		requirement   *csi.TopologyRequirement
+		expZone       string
+		expOutpostArn string
+	}{
+		{
+			name: "Get from preferred",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{
+							TopologyKey:     expZone,
+							AwsAccountIDKey: outpostArn.AccountID,
+							AwsOutpostIDKey: outpostArn.Resource,
+							AwsRegionKey:    outpostArn.Region,
+							AwsPartitionKey: outpostArn.Partition,
+						},
+					},
+				},
+			},
+			expZone:       expZone,
+			expOutpostArn: expRawOutpostArn,
+		},
+		{
+			name: "Get from requisite",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{
+							TopologyKey:     expZone,
+							AwsAccountIDKey: outpostArn.AccountID,
+							AwsOutpostIDKey: outpostArn.Resource,
+							AwsRegionKey:    outpostArn.Region,
+							AwsPartitionKey: outpostArn.Partition,
+						},
+					},
+				},
+			},
+			expZone:       expZone,
+			expOutpostArn: expRawOutpostArn,
+		},
+		{
+			name: "Get from empty topology",
+			requirement: &csi.TopologyRequirement{
+				Preferred: []*csi.Topology{{}},
+				Requisite: []*csi.Topology{{}},
+			},
+			expZone:       "",
+			expOutpostArn: "",
+		},
+		{
+			name:          "Topology Requirement is nil",
+			requirement:   nil,
+			expZone:       "",
+			expOutpostArn: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := getOutpostArn(tc.requirement)
+			if actual != tc.expOutpostArn {
+				t.Fatalf("Expected %v, got outpostArn: %v", tc.expOutpostArn, actual)
+			}
+		})
+	}
+}
+
+func TestBuildOutpostArn(t *testing.T) {
+	expRawOutpostArn := "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
+	testCases := []struct {
+		name         string
+		awsPartition string
+		awsRegion    string
+		awsAccountID string
+		awsOutpostID string
+		expectedArn  string
+	}{
+		{
+			name:         "all fields are present",
+			awsPartition: "aws",
+			awsRegion:    "us-west-2",
+			awsOutpostID: "op-0aaa000a0aaaa00a0",
+			awsAccountID: "111111111111",
+			expectedArn:  expRawOutpostArn,
+		},
+		{
+			name:         "partition is missing",
+			awsRegion:    "us-west-2",
+			awsOutpostID: "op-0aaa000a0aaaa00a0",
+			awsAccountID: "111111111111",
+			expectedArn:  "",
+		},
+		{
+			name:         "region is missing",
+			awsPartition: "aws",
+			awsOutpostID: "op-0aaa000a0aaaa00a0",
 			awsAccountID: "111111111111",
 			expectedArn:  "",
 		},
@@ -2262,6 +4449,186 @@ func TestBuildOutpostArn(t *testing.T) {
 	}
 }
 
+func TestMergeTags(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tags        map[string]string
+		requestTags map[string]string
+		expTags     map[string]string
+	}{
+		{
+			name:        "no per-request tags",
+			tags:        map[string]string{"key1": "default"},
+			requestTags: nil,
+			expTags:     map[string]string{"key1": "default"},
+		},
+		{
+			name:        "per-request tags are merged",
+			tags:        map[string]string{"key1": "default"},
+			requestTags: map[string]string{"key2": "request"},
+			expTags:     map[string]string{"key1": "default", "key2": "request"},
+		},
+		{
+			name:        "per-request tags override defaults",
+			tags:        map[string]string{"key1": "default", "key2": "default"},
+			requestTags: map[string]string{"key2": "request"},
+			expTags:     map[string]string{"key1": "default", "key2": "request"},
+		},
+		{
+			name:        "per-request empty value overrides default",
+			tags:        map[string]string{"key1": "default"},
+			requestTags: map[string]string{"key1": ""},
+			expTags:     map[string]string{"key1": ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mergeTags(tc.tags, tc.requestTags)
+			if !reflect.DeepEqual(tc.tags, tc.expTags) {
+				t.Fatalf("Expected tags %v, got %v", tc.expTags, tc.tags)
+			}
+		})
+	}
+}
+
+func TestInterpolateExtraTags(t *testing.T) {
+	extraTags := map[string]string{
+		"cluster":   "test-cluster",
+		"team":      "{{ .PVCNamespace }}",
+		"snapshot":  "{{ .VolumeSnapshotName }}",
+		"component": `{{ .PVCName | field "-" 0 }}`,
+	}
+	testCases := []struct {
+		name    string
+		props   interface{}
+		expTags map[string]string
+	}{
+		{
+			name: "volume",
+			props: &template.PVProps{
+				PVCName:      "data-0",
+				PVCNamespace: "analytics",
+				PVName:       "pvc-1234",
+			},
+			expTags: map[string]string{
+				"cluster":   "test-cluster",
+				"team":      "analytics",
+				"component": "data",
+			},
+		},
+		{
+			name: "snapshot",
+			props: &template.VolumeSnapshotProps{
+				VolumeSnapshotName: "backup",
+			},
+			expTags: map[string]string{
+				"cluster":  "test-cluster",
+				"snapshot": "backup",
+			},
+		},
+		{
+			name:    "no properties",
+			expTags: map[string]string{"cluster": "test-cluster"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags := interpolateExtraTags(extraTags, tc.props)
+			if !reflect.DeepEqual(tags, tc.expTags) {
+				t.Fatalf("Expected tags %v, got %v", tc.expTags, tags)
+			}
+		})
+	}
+}
+
+func TestGetVolSizeBytes(t *testing.T) {
+	testCases := []struct {
+		name           string
+		capRange       *csi.CapacityRange
+		granularityGiB int64
+		expSizeBytes   int64
+		expErr         bool
+	}{
+		{
+			name:         "no granularity rounds up to GiB",
+			capRange:     &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12) + 1},
+			expSizeBytes: util.GiBToBytes(13),
+		},
+		{
+			name:           "granularity of 1 GiB",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12) + 1},
+			granularityGiB: 1,
+			expSizeBytes:   util.GiBToBytes(13),
+		},
+		{
+			name:           "rounds up to granularity",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12)},
+			granularityGiB: 10,
+			expSizeBytes:   util.GiBToBytes(20),
+		},
+		{
+			name:           "rounds partial GiB up to granularity",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(20) + 1},
+			granularityGiB: 10,
+			expSizeBytes:   util.GiBToBytes(30),
+		},
+		{
+			name:           "size already a multiple of granularity",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(100)},
+			granularityGiB: 50,
+			expSizeBytes:   util.GiBToBytes(100),
+		},
+		{
+			name:           "rounded size within limit",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12), LimitBytes: util.GiBToBytes(20)},
+			granularityGiB: 10,
+			expSizeBytes:   util.GiBToBytes(20),
+		},
+		{
+			name:           "rounded size capped at limit",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12), LimitBytes: util.GiBToBytes(15)},
+			granularityGiB: 10,
+			expSizeBytes:   util.GiBToBytes(15),
+		},
+		{
+			name:           "rounded size capped at limit rounded down to GiB",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12), LimitBytes: util.GiBToBytes(15) + 1},
+			granularityGiB: 10,
+			expSizeBytes:   util.GiBToBytes(15),
+		},
+		{
+			name:           "required size exceeds limit after round-up",
+			capRange:       &csi.CapacityRange{RequiredBytes: util.GiBToBytes(12) + 1, LimitBytes: util.GiBToBytes(12) + 2},
+			granularityGiB: 10,
+			expErr:         true,
+		},
+		{
+			name:           "default size rounded up to granularity",
+			granularityGiB: 30,
+			expSizeBytes:   util.GiBToBytes(120),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &csi.CreateVolumeRequest{CapacityRange: tc.capRange}
+			sizeBytes, err := getVolSizeBytes(req, tc.granularityGiB)
+			if tc.expErr {
+				checkExpectedErrorCode(t, err, codes.InvalidArgument)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sizeBytes != tc.expSizeBytes {
+				t.Fatalf("Expected size %d, got %d", tc.expSizeBytes, sizeBytes)
+			}
+		})
+	}
+}
+
 func TestCreateSnapshot(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -2424,6 +4791,101 @@ func TestCreateSnapshot(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success with outpost arn",
+			testFunc: func(t *testing.T) {
+				const (
+					snapshotName = "test-snapshot"
+					outpostArn   = "arn:aws:outposts:us-west-2:111111111111:outpost/op-0aaa000a0aaaa00a0"
+				)
+				req := &csi.CreateSnapshotRequest{
+					Name: snapshotName,
+					Parameters: map[string]string{
+						"outpostArn": outpostArn,
+					},
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := context.Background()
+				mockSnapshot := &cloud.Snapshot{
+					SnapshotID:     "snap-test",
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				snapshotOptions := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						cloud.SnapshotNameTagKey: snapshotName,
+						cloud.AwsEbsDriverTagKey: "true",
+					},
+					OutpostArn: outpostArn,
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Eq(snapshotOptions)).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(nil, cloud.ErrNotFound)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+				if _, err := awsDriver.CreateSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail with invalid outpost arn",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name: "test-snapshot",
+					Parameters: map[string]string{
+						"outpostArn": "arn:aws:ec2:us-west-2:111111111111:instance/i-0aaa000a0aaaa00a0",
+					},
+					SourceVolumeId: "vol-test",
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				awsDriver := controllerService{
+					cloud:         cloud.NewMockCloud(mockCtl),
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+				_, err := awsDriver.CreateSnapshot(context.Background(), req)
+				checkExpectedErrorCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail with reserved tag key",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name: "test-snapshot",
+					Parameters: map[string]string{
+						"tagSpecification_1": cloud.SnapshotNameTagKey + "=clobbered",
+					},
+					SourceVolumeId: "vol-test",
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Any(), gomock.Eq(req.GetName())).Return(nil, cloud.ErrNotFound)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+				_, err := awsDriver.CreateSnapshot(context.Background(), req)
+				checkExpectedErrorCode(t, err, codes.InvalidArgument)
+			},
+		},
 		{
 			name: "fail no name",
 			testFunc: func(t *testing.T) {
@@ -2782,6 +5244,61 @@ func TestCreateSnapshot(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success with EnableFastSnapshotRestore - not ready until optimizing",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name: "test-snapshot",
+					Parameters: map[string]string{
+						"fastSnapshotRestoreAvailabilityZones": "us-east-1a, us-east-1f",
+					},
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).DoAndReturn(func(_ context.Context, _ string) (*cloud.Snapshot, error) {
+					return &cloud.Snapshot{
+						SnapshotID:     "snap-test",
+						SourceVolumeID: req.SourceVolumeId,
+						Size:           1,
+						CreationTime:   time.Now(),
+						ReadyToUse:     true,
+					}, nil
+				}).Times(2)
+				gomock.InOrder(
+					mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Eq(ctx), "snap-test", "us-east-1a").Return(ec2.FastSnapshotRestoreStateCodeOptimizing, nil),
+					mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Eq(ctx), "snap-test", "us-east-1f").Return(ec2.FastSnapshotRestoreStateCodeEnabling, nil),
+					mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Eq(ctx), "snap-test", "us-east-1a").Return(ec2.FastSnapshotRestoreStateCodeEnabled, nil),
+					mockCloud.EXPECT().GetFastSnapshotRestoreState(gomock.Eq(ctx), "snap-test", "us-east-1f").Return(ec2.FastSnapshotRestoreStateCodeOptimizing, nil),
+				)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := awsDriver.CreateSnapshot(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if resp.GetSnapshot().GetReadyToUse() {
+					t.Fatalf("Expected snapshot not to be ready while fast snapshot restores are enabling")
+				}
+
+				resp, err = awsDriver.CreateSnapshot(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !resp.GetSnapshot().GetReadyToUse() {
+					t.Fatalf("Expected snapshot to be ready once fast snapshot restores are optimizing")
+				}
+			},
+		},
 		{
 			name: "success with EnableFastSnapshotRestore - failed to get availability zones",
 			testFunc: func(t *testing.T) {
@@ -3007,6 +5524,99 @@ func TestCreateSnapshot(t *testing.T) {
 	}
 }
 
+func TestCreateSnapshotPVCNameTag(t *testing.T) {
+	const (
+		snapshotName = "test-snapshot"
+		volumeID     = "vol-test"
+	)
+	testCases := []struct {
+		name               string
+		parameters         map[string]string
+		snapshotPVCNameTag bool
+		disk               *cloud.Disk
+		getDiskErr         error
+		expectGetDisk      bool
+		expPVCNameTag      string
+	}{
+		{
+			name:               "success tag propagated from source volume",
+			snapshotPVCNameTag: true,
+			disk:               &cloud.Disk{VolumeID: volumeID, Tags: map[string]string{PVCNameTag: "data"}},
+			expectGetDisk:      true,
+			expPVCNameTag:      "data",
+		},
+		{
+			name:               "success parameter takes precedence over source volume",
+			parameters:         map[string]string{PVCNameKey: "from-parameter"},
+			snapshotPVCNameTag: true,
+			expPVCNameTag:      "from-parameter",
+		},
+		{
+			name:          "success parameter without lookup",
+			parameters:    map[string]string{PVCNameKey: "from-parameter"},
+			expPVCNameTag: "from-parameter",
+		},
+		{
+			name:               "success source volume without PVC name tag",
+			snapshotPVCNameTag: true,
+			disk:               &cloud.Disk{VolumeID: volumeID},
+			expectGetDisk:      true,
+		},
+		{
+			name:               "success source volume lookup failed",
+			snapshotPVCNameTag: true,
+			getDiskErr:         errors.New("DescribeVolumes failed"),
+			expectGetDisk:      true,
+		},
+		{
+			name: "success disabled",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			expTags := map[string]string{
+				cloud.SnapshotNameTagKey: snapshotName,
+				cloud.AwsEbsDriverTagKey: "true",
+			}
+			if tc.expPVCNameTag != "" {
+				expTags[PVCNameTag] = tc.expPVCNameTag
+			}
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().GetSnapshotByName(gomock.Any(), gomock.Eq(snapshotName)).Return(nil, cloud.ErrNotFound)
+			if tc.expectGetDisk {
+				mockCloud.EXPECT().GetDiskByID(gomock.Any(), gomock.Eq(volumeID)).Return(tc.disk, tc.getDiskErr)
+			}
+			mockCloud.EXPECT().CreateSnapshot(gomock.Any(), gomock.Eq(volumeID), gomock.Eq(&cloud.SnapshotOptions{Tags: expTags})).Return(&cloud.Snapshot{
+				SnapshotID:     "snap-test",
+				SourceVolumeID: volumeID,
+				Size:           1,
+				CreationTime:   time.Now(),
+			}, nil)
+
+			awsDriver := controllerService{
+				cloud:    mockCloud,
+				inFlight: internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					snapshotPVCNameTag: tc.snapshotPVCNameTag,
+				},
+			}
+			_, err := awsDriver.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{
+				Name:           snapshotName,
+				Parameters:     tc.parameters,
+				SourceVolumeId: volumeID,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestDeleteSnapshot(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -3031,6 +5641,62 @@ func TestDeleteSnapshot(t *testing.T) {
 					SnapshotId: "xxx",
 				}
 
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Eq(ctx), gomock.Eq("xxx")).Return(nil, nil)
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(true, nil)
+				if _, err := awsDriver.DeleteSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with fast snapshot restores disabled first",
+			testFunc: func(t *testing.T) {
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := cloud.NewMockCloud(mockCtl)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.DeleteSnapshotRequest{
+					SnapshotId: "xxx",
+				}
+
+				gomock.InOrder(
+					mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Eq(ctx), gomock.Eq("xxx")).Return([]string{"us-east-1a", "us-east-1b"}, nil),
+					mockCloud.EXPECT().DisableFastSnapshotRestores(gomock.Eq(ctx), gomock.Eq([]string{"us-east-1a", "us-east-1b"}), gomock.Eq("xxx")).Return(nil),
+					mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(true, nil),
+				)
+				if _, err := awsDriver.DeleteSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success when fast snapshot restores cannot be disabled",
+			testFunc: func(t *testing.T) {
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := cloud.NewMockCloud(mockCtl)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.DeleteSnapshotRequest{
+					SnapshotId: "xxx",
+				}
+
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Eq(ctx), gomock.Eq("xxx")).Return(nil, errors.New("UnauthorizedOperation"))
 				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(true, nil)
 				if _, err := awsDriver.DeleteSnapshot(ctx, req); err != nil {
 					t.Fatalf("Unexpected error: %v", err)
@@ -3056,6 +5722,7 @@ func TestDeleteSnapshot(t *testing.T) {
 					SnapshotId: "xxx",
 				}
 
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Eq(ctx), gomock.Eq("xxx")).Return(nil, nil)
 				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(false, cloud.ErrNotFound)
 				if _, err := awsDriver.DeleteSnapshot(ctx, req); err != nil {
 					t.Fatalf("Unexpected error: %v", err)
@@ -3230,85 +5897,448 @@ func TestListSnapshots(t *testing.T) {
 					driverOptions: &DriverOptions{},
 				}
 
-				resp, err := awsDriver.ListSnapshots(context.Background(), req)
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
-				}
+				resp, err := awsDriver.ListSnapshots(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(resp, &csi.ListSnapshotsResponse{}) {
+					t.Fatalf("Expected empty response, got %+v", resp)
+				}
+			},
+		},
+		{
+			name: "fail snapshot ID multiple found",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					SnapshotId: "snapshot-1",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(nil, cloud.ErrMultiSnapshots)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := awsDriver.ListSnapshots(context.Background(), req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.Internal {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.Internal, srvErr.Code(), srvErr.Message())
+					}
+				} else {
+					t.Fatalf("Expected error code %d, got no error", codes.Internal)
+				}
+			},
+		},
+		{
+			name: "fail 0 < MaxEntries < 5",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					MaxEntries: 4,
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(4)), gomock.Eq("")).Return(nil, cloud.ErrInvalidMaxResults)
+
+				awsDriver := controllerService{
+					cloud:         mockCloud,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := awsDriver.ListSnapshots(context.Background(), req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.InvalidArgument {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.InvalidArgument, srvErr.Code(), srvErr.Message())
+					}
+				} else {
+					t.Fatalf("Expected error code %d, got no error", codes.InvalidArgument)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestSnapshotSourceVolumeSize(t *testing.T) {
+	snapshot := &cloud.Snapshot{
+		SnapshotID:     "snap-test",
+		SourceVolumeID: "vol-test",
+		Size:           util.GiBToBytes(20),
+		CreationTime:   time.Now(),
+		ReadyToUse:     true,
+	}
+
+	awsDriver, mockCtl, mockCloud := createControllerService(t)
+	defer mockCtl.Finish()
+
+	mockCloud.EXPECT().GetSnapshotByName(gomock.Any(), gomock.Eq("test-snapshot")).Return(nil, cloud.ErrNotFound)
+	mockCloud.EXPECT().CreateSnapshot(gomock.Any(), gomock.Eq(snapshot.SourceVolumeID), gomock.Any()).Return(snapshot, nil)
+	createResp, err := awsDriver.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{
+		Name:           "test-snapshot",
+		SourceVolumeId: snapshot.SourceVolumeID,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if size := createResp.GetSnapshot().GetSizeBytes(); size != snapshot.Size {
+		t.Fatalf("Expected CreateSnapshot to report source volume size %d, got %d", snapshot.Size, size)
+	}
+
+	mockCloud.EXPECT().ListSnapshots(gomock.Any(), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(&cloud.ListSnapshotsResponse{Snapshots: []*cloud.Snapshot{snapshot}}, nil)
+	listResp, err := awsDriver.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(listResp.GetEntries()) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(listResp.GetEntries()))
+	}
+	if size := listResp.GetEntries()[0].GetSnapshot().GetSizeBytes(); size != snapshot.Size {
+		t.Fatalf("Expected ListSnapshots to report source volume size %d, got %d", snapshot.Size, size)
+	}
+}
+func TestListVolumes(t *testing.T) {
+	disks := []*cloud.Disk{
+		{VolumeID: "vol-test-1", CapacityGiB: 1, AvailabilityZone: expZone, State: "in-use", Attachments: []string{"i-test"}},
+		{VolumeID: "vol-test-2", CapacityGiB: 2, AvailabilityZone: expZone, State: "error"},
+	}
+
+	testCases := []struct {
+		name      string
+		req       *csi.ListVolumesRequest
+		listErr   error
+		statuses  map[string]*cloud.VolumeStatus
+		statusErr error
+		// volumeStatuses are retrieved per volume on cloud.ErrNotFound, volumes missing here were deleted
+		volumeStatuses map[string]map[string]*cloud.VolumeStatus
+		expEntries     []*csi.ListVolumesResponse_Entry
+		expNextToken   string
+		expectErrCode  codes.Code
+	}{
+		{
+			name: "success",
+			req:  &csi.ListVolumesRequest{MaxEntries: 5, StartingToken: "page-1"},
+			statuses: map[string]*cloud.VolumeStatus{
+				"vol-test-1": {Status: "impaired", Problems: []string{"io-enabled: failed"}},
+				"vol-test-2": {Status: "ok"},
+			},
+			expEntries: []*csi.ListVolumesResponse_Entry{
+				{
+					Volume: &csi.Volume{
+						VolumeId:           "vol-test-1",
+						CapacityBytes:      util.GiBToBytes(1),
+						AccessibleTopology: []*csi.Topology{{Segments: map[string]string{TopologyKey: expZone}}},
+					},
+					Status: &csi.ListVolumesResponse_VolumeStatus{
+						PublishedNodeIds: []string{"i-test"},
+						VolumeCondition:  &csi.VolumeCondition{Abnormal: true, Message: "Volume status is impaired: io-enabled: failed"},
+					},
+				},
+				{
+					Volume: &csi.Volume{
+						VolumeId:           "vol-test-2",
+						CapacityBytes:      util.GiBToBytes(2),
+						AccessibleTopology: []*csi.Topology{{Segments: map[string]string{TopologyKey: expZone}}},
+					},
+					Status: &csi.ListVolumesResponse_VolumeStatus{
+						VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: "Volume is in the error state, its data may be lost"},
+					},
+				},
+			},
+			expNextToken: "page-2",
+		},
+		{
+			name:          "fail: invalid max entries",
+			req:           &csi.ListVolumesRequest{MaxEntries: 4},
+			listErr:       cloud.ErrInvalidMaxResults,
+			expectErrCode: codes.InvalidArgument,
+		},
+		{
+			name:          "fail: invalid starting token",
+			req:           &csi.ListVolumesRequest{StartingToken: "invalid"},
+			listErr:       cloud.ErrInvalidNextToken,
+			expectErrCode: codes.Aborted,
+		},
+		{
+			name:      "success: volume deleted since it was listed",
+			req:       &csi.ListVolumesRequest{MaxEntries: 5, StartingToken: "page-1"},
+			statusErr: cloud.ErrNotFound,
+			volumeStatuses: map[string]map[string]*cloud.VolumeStatus{
+				"vol-test-1": {"vol-test-1": {Status: "ok"}},
+			},
+			expEntries: []*csi.ListVolumesResponse_Entry{
+				{
+					Volume: &csi.Volume{
+						VolumeId:           "vol-test-1",
+						CapacityBytes:      util.GiBToBytes(1),
+						AccessibleTopology: []*csi.Topology{{Segments: map[string]string{TopologyKey: expZone}}},
+					},
+					Status: &csi.ListVolumesResponse_VolumeStatus{
+						PublishedNodeIds: []string{"i-test"},
+						VolumeCondition:  &csi.VolumeCondition{Message: "Volume status is ok"},
+					},
+				},
+			},
+			expNextToken: "page-2",
+		},
+		{
+			name:          "fail: volume status error",
+			req:           &csi.ListVolumesRequest{MaxEntries: 5, StartingToken: "page-1"},
+			statusErr:     errors.New("DescribeVolumeStatus failed"),
+			expectErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsDriver, mockCtl, mockCloud := createControllerService(t)
+			defer mockCtl.Finish()
+
+			if tc.listErr != nil {
+				mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Eq(int64(tc.req.MaxEntries)), gomock.Eq(tc.req.StartingToken)).Return(nil, tc.listErr)
+			} else {
+				mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Eq(int64(tc.req.MaxEntries)), gomock.Eq(tc.req.StartingToken)).Return(&cloud.ListDisksResponse{Disks: disks, NextToken: "page-2"}, nil)
+				mockCloud.EXPECT().GetVolumeStatuses(gomock.Any(), gomock.Eq([]string{"vol-test-1", "vol-test-2"})).Return(tc.statuses, tc.statusErr)
+				if errors.Is(tc.statusErr, cloud.ErrNotFound) {
+					for _, disk := range disks {
+						if volumeStatuses, ok := tc.volumeStatuses[disk.VolumeID]; ok {
+							mockCloud.EXPECT().GetVolumeStatuses(gomock.Any(), gomock.Eq([]string{disk.VolumeID})).Return(volumeStatuses, nil)
+						} else {
+							mockCloud.EXPECT().GetVolumeStatuses(gomock.Any(), gomock.Eq([]string{disk.VolumeID})).Return(nil, cloud.ErrNotFound)
+						}
+					}
+				}
+			}
+
+			resp, err := awsDriver.ListVolumes(context.Background(), tc.req)
+			if tc.expectErrCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expectErrCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(resp.GetEntries(), tc.expEntries) {
+				t.Fatalf("Expected entries %+v, got %+v", tc.expEntries, resp.GetEntries())
+			}
+			if resp.GetNextToken() != tc.expNextToken {
+				t.Fatalf("Expected next token %q, got %q", tc.expNextToken, resp.GetNextToken())
+			}
+		})
+	}
+}
+
+func TestControllerGetVolume(t *testing.T) {
+	disk := &cloud.Disk{VolumeID: "vol-test", CapacityGiB: 1, AvailabilityZone: expZone, State: "in-use", Attachments: []string{"i-test"}}
 
-				if !reflect.DeepEqual(resp, &csi.ListSnapshotsResponse{}) {
-					t.Fatalf("Expected empty response, got %+v", resp)
-				}
-			},
+	testCases := []struct {
+		name          string
+		volumeID      string
+		diskErr       error
+		volumeStatus  *cloud.VolumeStatus
+		statusErr     error
+		expCondition  *csi.VolumeCondition
+		expectErrCode codes.Code
+	}{
+		{
+			name:         "success: ok volume",
+			volumeID:     "vol-test",
+			volumeStatus: &cloud.VolumeStatus{Status: "ok"},
+			expCondition: &csi.VolumeCondition{Message: "Volume status is ok"},
 		},
 		{
-			name: "fail snapshot ID multiple found",
-			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{
-					SnapshotId: "snapshot-1",
-				}
-
-				ctx := context.Background()
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
-
-				mockCloud := cloud.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(nil, cloud.ErrMultiSnapshots)
+			name:         "success: degraded volume",
+			volumeID:     "vol-test",
+			volumeStatus: &cloud.VolumeStatus{Status: "warning", Problems: []string{"io-performance on i-test: degraded"}},
+			expCondition: &csi.VolumeCondition{Abnormal: true, Message: "Volume status is warning: io-performance on i-test: degraded"},
+		},
+		{
+			name:         "success: no volume status",
+			volumeID:     "vol-test",
+			expCondition: &csi.VolumeCondition{Message: "Volume status is not available"},
+		},
+		{
+			name:          "fail: no volume ID",
+			expectErrCode: codes.InvalidArgument,
+		},
+		{
+			name:          "fail: volume not found",
+			volumeID:      "vol-test",
+			diskErr:       cloud.ErrNotFound,
+			expectErrCode: codes.NotFound,
+		},
+		{
+			name:          "fail: volume deleted before its status was got",
+			volumeID:      "vol-test",
+			statusErr:     cloud.ErrNotFound,
+			expectErrCode: codes.NotFound,
+		},
+		{
+			name:          "fail: volume status error",
+			volumeID:      "vol-test",
+			statusErr:     errors.New("DescribeVolumeStatus generic error"),
+			expectErrCode: codes.Internal,
+		},
+	}
 
-				awsDriver := controllerService{
-					cloud:         mockCloud,
-					inFlight:      internal.NewInFlight(),
-					driverOptions: &DriverOptions{},
-				}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsDriver, mockCtl, mockCloud := createControllerService(t)
+			defer mockCtl.Finish()
 
-				if _, err := awsDriver.ListSnapshots(context.Background(), req); err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.Internal {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.Internal, srvErr.Code(), srvErr.Message())
-					}
+			if tc.volumeID != "" {
+				if tc.diskErr != nil {
+					mockCloud.EXPECT().GetDiskByID(gomock.Any(), gomock.Eq(tc.volumeID)).Return(nil, tc.diskErr)
 				} else {
-					t.Fatalf("Expected error code %d, got no error", codes.Internal)
+					mockCloud.EXPECT().GetDiskByID(gomock.Any(), gomock.Eq(tc.volumeID)).Return(disk, nil)
+					statuses := map[string]*cloud.VolumeStatus{}
+					if tc.volumeStatus != nil {
+						statuses[tc.volumeID] = tc.volumeStatus
+					}
+					mockCloud.EXPECT().GetVolumeStatuses(gomock.Any(), gomock.Eq([]string{tc.volumeID})).Return(statuses, tc.statusErr)
 				}
+			}
+
+			resp, err := awsDriver.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: tc.volumeID})
+			if tc.expectErrCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expectErrCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.GetVolume().GetVolumeId() != tc.volumeID {
+				t.Fatalf("Expected volume %q, got %q", tc.volumeID, resp.GetVolume().GetVolumeId())
+			}
+			if !reflect.DeepEqual(resp.GetStatus().GetPublishedNodeIds(), disk.Attachments) {
+				t.Fatalf("Expected published nodes %v, got %v", disk.Attachments, resp.GetStatus().GetPublishedNodeIds())
+			}
+			if !reflect.DeepEqual(resp.GetStatus().GetVolumeCondition(), tc.expCondition) {
+				t.Fatalf("Expected volume condition %+v, got %+v", tc.expCondition, resp.GetStatus().GetVolumeCondition())
+			}
+		})
+	}
+}
+
+func TestValidateVolumeCapabilities(t *testing.T) {
+	stdVolCap := []*csi.VolumeCapability{
+		{
+			AccessType: &csi.VolumeCapability_Mount{
+				Mount: &csi.VolumeCapability_MountVolume{},
+			},
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
 			},
 		},
+	}
+	multiAttachVolCap := []*csi.VolumeCapability{
 		{
-			name: "fail 0 < MaxEntries < 5",
-			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{
-					MaxEntries: 4,
-				}
-
-				ctx := context.Background()
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
-				mockCloud := cloud.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(4)), gomock.Eq("")).Return(nil, cloud.ErrInvalidMaxResults)
-
-				awsDriver := controllerService{
-					cloud:         mockCloud,
-					inFlight:      internal.NewInFlight(),
-					driverOptions: &DriverOptions{},
-				}
+			AccessType: &csi.VolumeCapability_Block{
+				Block: &csi.VolumeCapability_BlockVolume{},
+			},
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+			},
+		},
+	}
 
-				if _, err := awsDriver.ListSnapshots(context.Background(), req); err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.InvalidArgument {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.InvalidArgument, srvErr.Code(), srvErr.Message())
-					}
-				} else {
-					t.Fatalf("Expected error code %d, got no error", codes.InvalidArgument)
-				}
+	testCases := []struct {
+		name         string
+		volCaps      []*csi.VolumeCapability
+		disk         *cloud.Disk
+		getDiskErr   error
+		expConfirmed bool
+		expErrCode   codes.Code
+	}{
+		{
+			name:         "single node writer confirmed",
+			volCaps:      stdVolCap,
+			disk:         &cloud.Disk{VolumeID: "vol-test"},
+			expConfirmed: true,
+		},
+		{
+			name:         "multi node multi writer confirmed for multi-attach volume",
+			volCaps:      multiAttachVolCap,
+			disk:         &cloud.Disk{VolumeID: "vol-test", MultiAttachEnabled: true},
+			expConfirmed: true,
+		},
+		{
+			name:         "multi node multi writer not confirmed for volume without multi-attach",
+			volCaps:      multiAttachVolCap,
+			disk:         &cloud.Disk{VolumeID: "vol-test"},
+			expConfirmed: false,
+		},
+		{
+			name: "conflicting mount options not confirmed",
+			volCaps: []*csi.VolumeCapability{
+				{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{MountFlags: []string{"discard", "nodiscard"}},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
 			},
+			disk:         &cloud.Disk{VolumeID: "vol-test"},
+			expConfirmed: false,
+		},
+		{
+			name:       "volume not found",
+			volCaps:    stdVolCap,
+			getDiskErr: cloud.ErrNotFound,
+			expErrCode: codes.NotFound,
 		},
 	}
 
 	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().GetDiskByID(gomock.Any(), "vol-test").Return(tc.disk, tc.getDiskErr)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			resp, err := awsDriver.ValidateVolumeCapabilities(context.Background(), &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId:           "vol-test",
+				VolumeCapabilities: tc.volCaps,
+			})
+			if tc.expErrCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expErrCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if confirmed := resp.GetConfirmed() != nil; confirmed != tc.expConfirmed {
+				t.Fatalf("Expected confirmed %v, got %v", tc.expConfirmed, confirmed)
+			}
+		})
 	}
 }
 
@@ -3343,93 +6373,211 @@ func TestControllerPublishVolume(t *testing.T) {
 			expResp: &csi.ControllerPublishVolumeResponse{
 				PublishContext: map[string]string{DevicePathKey: expDevicePath},
 			},
-			errorCode: codes.OK,
+			errorCode: codes.OK,
+		},
+		{
+			name:             "AttachDisk when volume is already attached to the node",
+			volumeId:         "vol-test",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return(expDevicePath, nil)
+			},
+			expResp: &csi.ControllerPublishVolumeResponse{
+				PublishContext: map[string]string{DevicePathKey: expDevicePath},
+			},
+			errorCode: codes.OK,
+		},
+
+		{
+			name:             "Invalid argument error when no VolumeId provided",
+			volumeId:         "",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			errorCode:        codes.InvalidArgument,
+		},
+		{
+			name:             "Invalid argument error when no NodeId provided",
+			volumeId:         "vol-test",
+			nodeId:           "",
+			volumeCapability: stdVolCap,
+			errorCode:        codes.InvalidArgument,
+		},
+		{
+			name:      "Invalid argument error when no VolumeCapability provided",
+			volumeId:  "vol-test",
+			nodeId:    expInstanceID,
+			errorCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Invalid argument error when invalid VolumeCapability provided",
+			volumeId: "vol-test",
+			nodeId:   expInstanceID,
+			volumeCapability: &csi.VolumeCapability{
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_UNKNOWN,
+				},
+			},
+			errorCode: codes.InvalidArgument,
+		},
+		{
+			name:             "Internal error when AttachDisk fails",
+			volumeId:         "vol-test",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return("", status.Error(codes.Internal, "test error"))
+			},
+			errorCode: codes.Internal,
+		},
+		{
+			name:     "AttachDisk multi-attach volume to a second node",
+			volumeId: "vol-test",
+			nodeId:   expInstanceID,
+			volumeCapability: &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Block{
+					Block: &csi.VolumeCapability_BlockVolume{},
+				},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+				},
+			},
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return(expDevicePath, nil)
+			},
+			expResp: &csi.ControllerPublishVolumeResponse{
+				PublishContext: map[string]string{DevicePathKey: expDevicePath},
+			},
+			errorCode: codes.OK,
+		},
+		{
+			name:             "Fail when volume is attached to another node and not multi-attach enabled",
+			volumeId:         "vol-test",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return("", fmt.Errorf("could not attach volume: %w", cloud.ErrVolumeInUse))
+			},
+			errorCode: codes.FailedPrecondition,
+		},
+		{
+			name:             "Fail when node does not exist",
+			volumeId:         "vol-test",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return("", status.Error(codes.Internal, "test error"))
+			},
+			errorCode: codes.Internal,
+		},
+		{
+			name:             "Fail when volume does not exist",
+			volumeId:         "vol-test",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return("", status.Error(codes.Internal, "volume not found"))
+			},
+			errorCode: codes.Internal,
+		},
+		{
+			name:             "Aborted error when AttachDisk operation already in-flight",
+			volumeId:         "vol-test",
+			nodeId:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+			},
+			errorCode: codes.Aborted,
+			setupFunc: func(controllerService *controllerService) {
+				controllerService.inFlight.Insert("vol-test" + expInstanceID)
+			},
 		},
 		{
-			name:             "AttachDisk when volume is already attached to the node",
+			name:             "AttachDisk successfully when KMS access check passes",
 			volumeId:         "vol-test",
 			nodeId:           expInstanceID,
 			volumeCapability: stdVolCap,
 			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return(expDevicePath, nil)
+				mockCloud.EXPECT().CheckKMSKeyAccess(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(expDevicePath, nil)
 			},
 			expResp: &csi.ControllerPublishVolumeResponse{
 				PublishContext: map[string]string{DevicePathKey: expDevicePath},
 			},
 			errorCode: codes.OK,
-		},
-
-		{
-			name:             "Invalid argument error when no VolumeId provided",
-			volumeId:         "",
-			nodeId:           expInstanceID,
-			volumeCapability: stdVolCap,
-			errorCode:        codes.InvalidArgument,
+			setupFunc: func(controllerService *controllerService) {
+				controllerService.driverOptions.kmsAccessCheck = true
+			},
 		},
 		{
-			name:             "Invalid argument error when no NodeId provided",
+			name:             "PermissionDenied error when node cannot use the volume KMS key",
 			volumeId:         "vol-test",
-			nodeId:           "",
+			nodeId:           expInstanceID,
 			volumeCapability: stdVolCap,
-			errorCode:        codes.InvalidArgument,
-		},
-		{
-			name:      "Invalid argument error when no VolumeCapability provided",
-			volumeId:  "vol-test",
-			nodeId:    expInstanceID,
-			errorCode: codes.InvalidArgument,
-		},
-		{
-			name:     "Invalid argument error when invalid VolumeCapability provided",
-			volumeId: "vol-test",
-			nodeId:   expInstanceID,
-			volumeCapability: &csi.VolumeCapability{
-				AccessMode: &csi.VolumeCapability_AccessMode{
-					Mode: csi.VolumeCapability_AccessMode_UNKNOWN,
-				},
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().CheckKMSKeyAccess(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(fmt.Errorf("%w: test", cloud.ErrKMSKeyAccessDenied))
+			},
+			errorCode: codes.PermissionDenied,
+			setupFunc: func(controllerService *controllerService) {
+				controllerService.driverOptions.kmsAccessCheck = true
 			},
-			errorCode: codes.InvalidArgument,
 		},
 		{
-			name:             "Internal error when AttachDisk fails",
+			name:             "Unavailable error when KMS access check is throttled",
 			volumeId:         "vol-test",
 			nodeId:           expInstanceID,
 			volumeCapability: stdVolCap,
 			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return("", status.Error(codes.Internal, "test error"))
+				mockCloud.EXPECT().CheckKMSKeyAccess(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(fmt.Errorf("could not get policy of key %q: %w", "key", awserr.New("ThrottlingException", "Rate exceeded", nil)))
+			},
+			errorCode: codes.Unavailable,
+			setupFunc: func(controllerService *controllerService) {
+				controllerService.driverOptions.kmsAccessCheck = true
 			},
-			errorCode: codes.Internal,
 		},
 		{
-			name:             "Fail when node does not exist",
+			name:             "Unavailable error when KMS access check times out",
 			volumeId:         "vol-test",
 			nodeId:           expInstanceID,
 			volumeCapability: stdVolCap,
 			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return("", status.Error(codes.Internal, "test error"))
+				mockCloud.EXPECT().CheckKMSKeyAccess(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(context.DeadlineExceeded)
+			},
+			errorCode: codes.Unavailable,
+			setupFunc: func(controllerService *controllerService) {
+				controllerService.driverOptions.kmsAccessCheck = true
 			},
-			errorCode: codes.Internal,
 		},
 		{
-			name:             "Fail when volume does not exist",
+			name:             "success when KMS access check cannot be completed with fail open",
 			volumeId:         "vol-test",
 			nodeId:           expInstanceID,
 			volumeCapability: stdVolCap,
 			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(expInstanceID)).Return("", status.Error(codes.Internal, "volume not found"))
+				mockCloud.EXPECT().CheckKMSKeyAccess(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(errors.New("test error"))
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(expDevicePath, nil)
+			},
+			expResp: &csi.ControllerPublishVolumeResponse{
+				PublishContext: map[string]string{DevicePathKey: expDevicePath},
+			},
+			errorCode: codes.OK,
+			setupFunc: func(controllerService *controllerService) {
+				controllerService.driverOptions.kmsAccessCheck = true
+				controllerService.driverOptions.kmsAccessCheckFailOpen = true
 			},
-			errorCode: codes.Internal,
 		},
 		{
-			name:             "Aborted error when AttachDisk operation already in-flight",
+			name:             "PermissionDenied error when node cannot use the volume KMS key with fail open",
 			volumeId:         "vol-test",
 			nodeId:           expInstanceID,
 			volumeCapability: stdVolCap,
 			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeId string, nodeId string) {
+				mockCloud.EXPECT().CheckKMSKeyAccess(gomock.Eq(ctx), gomock.Eq(volumeId), gomock.Eq(nodeId)).Return(fmt.Errorf("%w: test", cloud.ErrKMSKeyAccessDenied))
 			},
-			errorCode: codes.Aborted,
+			errorCode: codes.PermissionDenied,
 			setupFunc: func(controllerService *controllerService) {
-				controllerService.inFlight.Insert("vol-test" + expInstanceID)
+				controllerService.driverOptions.kmsAccessCheck = true
+				controllerService.driverOptions.kmsAccessCheckFailOpen = true
 			},
 		},
 	}
@@ -3467,6 +6615,433 @@ func TestControllerPublishVolume(t *testing.T) {
 	}
 }
 
+func TestControllerPublishVolumeDuringShutdown(t *testing.T) {
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+	newRequest := func(volumeID string) *csi.ControllerPublishVolumeRequest {
+		return &csi.ControllerPublishVolumeRequest{
+			NodeId:           expInstanceID,
+			VolumeCapability: stdVolCap,
+			VolumeId:         volumeID,
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		gracePeriod    time.Duration
+		attachFinishes bool
+		expDrained     bool
+		expErrorCode   codes.Code
+	}{
+		{
+			name:           "in-flight attach finishes within grace period",
+			gracePeriod:    time.Minute,
+			attachFinishes: true,
+			expDrained:     true,
+			expErrorCode:   codes.OK,
+		},
+		{
+			name:         "in-flight attach is aborted after grace period",
+			gracePeriod:  10 * time.Millisecond,
+			expDrained:   false,
+			expErrorCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsDriver, mockCtl, mockCloud := createControllerService(t)
+			defer mockCtl.Finish()
+			awsDriver.shutdown = newShutdownCoordinator()
+
+			attaching := make(chan struct{})
+			release := make(chan struct{})
+			mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Eq("vol-in-flight"), gomock.Eq(expInstanceID)).DoAndReturn(
+				func(ctx context.Context, volumeID, nodeID string) (string, error) {
+					close(attaching)
+					select {
+					case <-release:
+						return expDevicePath, nil
+					case <-ctx.Done():
+						return "", ctx.Err()
+					}
+				})
+
+			var (
+				wg         sync.WaitGroup
+				publishErr error
+			)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, publishErr = awsDriver.ControllerPublishVolume(context.Background(), newRequest("vol-in-flight"))
+			}()
+			<-attaching
+
+			drained := make(chan bool)
+			go func(gracePeriod time.Duration) {
+				drained <- awsDriver.shutdown.drain(gracePeriod)
+			}(tc.gracePeriod)
+
+			// New requests are rejected as soon as the controller is draining
+			err := wait.PollUntilContextTimeout(context.Background(), time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+				awsDriver.shutdown.mu.Lock()
+				defer awsDriver.shutdown.mu.Unlock()
+				return awsDriver.shutdown.draining, nil
+			})
+			if err != nil {
+				t.Fatalf("controller did not start draining: %v", err)
+			}
+			_, err = awsDriver.ControllerPublishVolume(context.Background(), newRequest("vol-new"))
+			if status.Code(err) != codes.Unavailable {
+				t.Fatalf("expected new request to be rejected with %v, got %v", codes.Unavailable, err)
+			}
+
+			if tc.attachFinishes {
+				close(release)
+			}
+			if got := <-drained; got != tc.expDrained {
+				t.Fatalf("expected drain to return %v, got %v", tc.expDrained, got)
+			}
+			wg.Wait()
+			if status.Code(publishErr) != tc.expErrorCode {
+				t.Fatalf("expected in-flight request to return %v, got %v", tc.expErrorCode, publishErr)
+			}
+		})
+	}
+}
+
+func TestControllerPublishVolumeConcurrencyLimit(t *testing.T) {
+	const (
+		maxConcurrentAttaches = 2
+		volumes               = 8
+	)
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+
+	awsDriver, mockCtl, mockCloud := createControllerService(t)
+	defer mockCtl.Finish()
+	awsDriver.attachLimiter = newAttachLimiter(maxConcurrentAttaches)
+
+	var attaching, maxAttaching atomic.Int32
+	started := make(chan struct{}, volumes)
+	release := make(chan struct{})
+	mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Any(), gomock.Eq(expInstanceID)).DoAndReturn(
+		func(ctx context.Context, volumeID, nodeID string) (string, error) {
+			n := attaching.Add(1)
+			defer attaching.Add(-1)
+			for {
+				m := maxAttaching.Load()
+				if n <= m || maxAttaching.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			started <- struct{}{}
+			<-release
+			return expDevicePath, nil
+		}).Times(volumes)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, volumes)
+	for i := 0; i < volumes; i++ {
+		wg.Add(1)
+		go func(volumeID string) {
+			defer wg.Done()
+			_, err := awsDriver.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+				NodeId:           expInstanceID,
+				VolumeCapability: stdVolCap,
+				VolumeId:         volumeID,
+			})
+			errs <- err
+		}(fmt.Sprintf("vol-test-%d", i))
+	}
+
+	for i := 0; i < maxConcurrentAttaches; i++ {
+		<-started
+	}
+	select {
+	case <-started:
+		t.Fatalf("expected at most %d concurrent attachments", maxConcurrentAttaches)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if got := maxAttaching.Load(); got != maxConcurrentAttaches {
+		t.Fatalf("expected %d concurrent attachments, got %d", maxConcurrentAttaches, got)
+	}
+}
+
+func TestAttachLimiter(t *testing.T) {
+	if l := newAttachLimiter(0); l != nil {
+		t.Fatalf("expected no limiter for limit 0, got %v", l)
+	}
+
+	l := newAttachLimiter(1)
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A queued attachment gives up when its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	release()
+	release, err = l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error after release: %v", err)
+	}
+	release()
+}
+
+func TestNodeOperationQueue(t *testing.T) {
+	const (
+		nodeA = "i-0000000000000000a"
+		nodeB = "i-0000000000000000b"
+	)
+	if q := newNodeOperationQueue(0); q != nil {
+		t.Fatalf("expected no queue for 0 workers, got %v", q)
+	}
+
+	q := newNodeOperationQueue(2)
+	releaseA, err := q.acquire(context.Background(), nodeA)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Operations on other nodes run in parallel
+	releaseB, err := q.acquire(context.Background(), nodeB)
+	if err != nil {
+		t.Fatalf("Unexpected error on another node: %v", err)
+	}
+	releaseB()
+
+	// Operations on the same node are serialized
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = q.acquire(ctx, nodeA); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		release, err := q.acquire(context.Background(), nodeA)
+		if err != nil {
+			t.Errorf("Unexpected error after release: %v", err)
+		}
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the operation to wait for the previous operation on the node")
+	case <-time.After(10 * time.Millisecond):
+	}
+	releaseA()
+	(<-acquired)()
+
+	if len(q.nodes) != 0 {
+		t.Fatalf("expected no nodes left, got %v", q.nodes)
+	}
+}
+
+func TestNodeOperationQueueWorkers(t *testing.T) {
+	q := newNodeOperationQueue(1)
+	release, err := q.acquire(context.Background(), "i-0000000000000000a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Operations on other nodes wait for a worker
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = q.acquire(ctx, "i-0000000000000000b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	release()
+	release, err = q.acquire(context.Background(), "i-0000000000000000b")
+	if err != nil {
+		t.Fatalf("Unexpected error after release: %v", err)
+	}
+	release()
+}
+
+func TestControllerPublishVolumeAttachBudget(t *testing.T) {
+	const (
+		volumeID = "vol-test"
+		nodeA    = "i-0000000000000000a"
+		nodeB    = "i-0000000000000000b"
+	)
+	attachErr := fmt.Errorf("could not attach volume: %w", awserr.New("IncorrectState", "vol-test is attached to another instance", nil))
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+
+	type attempt struct {
+		node      string
+		after     time.Duration
+		attachErr error
+		// attached is false if the attempt must be rejected without calling AWS
+		attached bool
+		expCode  codes.Code
+	}
+	testCases := []struct {
+		name        string
+		maxAttempts int
+		deadline    time.Duration
+		attempts    []attempt
+	}{
+		{
+			name:        "budget exhausted across nodes",
+			maxAttempts: 2,
+			attempts: []attempt{
+				{node: nodeA, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeB, after: time.Second, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, expCode: codes.FailedPrecondition},
+				{node: nodeB, after: time.Minute, expCode: codes.FailedPrecondition},
+			},
+		},
+		{
+			name:        "budget restored after reset period",
+			maxAttempts: 1,
+			attempts: []attempt{
+				{node: nodeA, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeB, after: attachBudgetResetPeriod - time.Second, expCode: codes.FailedPrecondition},
+				{node: nodeB, after: time.Second, attached: true, expCode: codes.OK},
+			},
+		},
+		{
+			name:        "budget restored after successful attach",
+			maxAttempts: 2,
+			attempts: []attempt{
+				{node: nodeA, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attached: true, expCode: codes.OK},
+				{node: nodeB, after: time.Second, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeB, after: time.Second, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, expCode: codes.FailedPrecondition},
+			},
+		},
+		{
+			name:     "deadline exceeded",
+			deadline: 5 * time.Minute,
+			attempts: []attempt{
+				{node: nodeA, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeB, after: 2 * time.Minute, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: 2 * time.Minute, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeB, after: time.Minute, expCode: codes.FailedPrecondition},
+			},
+		},
+		{
+			name:        "transient failures do not count against budget",
+			maxAttempts: 1,
+			attempts: []attempt{
+				{node: nodeA, attachErr: awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil), attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: awserr.NewRequestFailure(awserr.New("InternalError", "An internal error has occurred", nil), http.StatusInternalServerError, "request-id"), attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: fmt.Errorf("could not attach volume: %w", awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("connection reset"))), attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, expCode: codes.FailedPrecondition},
+			},
+		},
+		{
+			name:        "attachment not completed in time counts against budget",
+			maxAttempts: 1,
+			attempts: []attempt{
+				{node: nodeA, attachErr: wait.ErrWaitTimeout, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, expCode: codes.FailedPrecondition},
+			},
+		},
+		{
+			name:        "failures other than AWS attach failures do not count against budget",
+			maxAttempts: 1,
+			attempts: []attempt{
+				{node: nodeA, attachErr: cloud.ErrNotFound, attached: true, expCode: codes.NotFound},
+				{node: nodeA, after: time.Second, attachErr: fmt.Errorf("could not attach volume: %w", context.Canceled), attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: context.DeadlineExceeded, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: status.Error(codes.Unavailable, "Controller is in maintenance mode"), attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: errors.New("there are no device names available"), attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, attachErr: attachErr, attached: true, expCode: codes.Internal},
+				{node: nodeA, after: time.Second, expCode: codes.FailedPrecondition},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsDriver, mockCtl, mockCloud := createControllerService(t)
+			defer mockCtl.Finish()
+
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			awsDriver.attachBudget = newAttachBudget(tc.maxAttempts, tc.deadline)
+			awsDriver.attachBudget.now = func() time.Time { return now }
+
+			for i, a := range tc.attempts {
+				now = now.Add(a.after)
+				if a.attached {
+					mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Eq(volumeID), gomock.Eq(a.node)).Return(expDevicePath, a.attachErr)
+				}
+				_, err := awsDriver.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+					NodeId:           a.node,
+					VolumeCapability: stdVolCap,
+					VolumeId:         volumeID,
+				})
+				if status.Code(err) != a.expCode {
+					t.Fatalf("attempt %d: expected code %v, got: %v", i, a.expCode, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachBudget(t *testing.T) {
+	if b := newAttachBudget(0, 0); b != nil {
+		t.Fatalf("expected no budget if neither attempts nor deadline are set, got %v", b)
+	}
+
+	b := newAttachBudget(1, 0)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	b.failed("vol-stale")
+	now = now.Add(attachBudgetResetPeriod)
+	b.failed("vol-test")
+	if _, ok := b.volumes["vol-stale"]; ok {
+		t.Fatalf("expected volume without failed attempts in the reset period to be forgotten")
+	}
+	if err := b.check("vol-test"); err == nil {
+		t.Fatalf("expected budget of vol-test to be exhausted")
+	}
+	b.succeeded("vol-test")
+	if err := b.check("vol-test"); err != nil {
+		t.Fatalf("expected budget of vol-test to be restored, got: %v", err)
+	}
+}
+
 func TestControllerUnpublishVolume(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -3648,6 +7223,138 @@ func TestControllerExpandVolume(t *testing.T) {
 	}
 }
 
+func TestControllerModifyVolume(t *testing.T) {
+	testCases := []struct {
+		name              string
+		volumeID          string
+		mutableParameters map[string]string
+		expModifyOptions  *cloud.ModifyDiskOptions
+		getDiskErr        error
+		modifyErr         error
+		expErrCode        codes.Code
+		expErrMsg         string
+	}{
+		{
+			name:     "success normal",
+			volumeID: "vol-test",
+			mutableParameters: map[string]string{
+				ModificationKeyVolumeType: "GP3",
+				ModificationKeyIOPS:       "4000",
+				ModificationKeyThroughput: "250",
+			},
+			expModifyOptions: &cloud.ModifyDiskOptions{VolumeType: cloud.VolumeTypeGP3, IOPS: 4000, Throughput: 250},
+		},
+		{
+			name:              "fail no volume ID",
+			mutableParameters: map[string]string{ModificationKeyIOPS: "4000"},
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:     "success no mutable parameters",
+			volumeID: "vol-test",
+		},
+		{
+			name:              "fail unknown parameter",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{"encrypted": "true"},
+			expErrCode:        codes.InvalidArgument,
+			expErrMsg:         "encrypted",
+		},
+		{
+			name:              "fail invalid iops",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{ModificationKeyVolumeType: cloud.VolumeTypeIO2, ModificationKeyIOPS: "lots"},
+			expErrCode:        codes.InvalidArgument,
+			expErrMsg:         "parameter iops",
+		},
+		{
+			name:              "fail invalid volume type",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{ModificationKeyVolumeType: "gp9"},
+			expErrCode:        codes.InvalidArgument,
+			expErrMsg:         "parameter volumeType",
+		},
+		{
+			name:              "fail value rejected by EC2",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{ModificationKeyIOPS: "100000"},
+			expModifyOptions:  &cloud.ModifyDiskOptions{IOPS: 100000},
+			modifyErr:         awserr.New("InvalidParameterValue", "IOPS must be at most 16000 for gp3 volumes", nil),
+			expErrCode:        codes.InvalidArgument,
+			expErrMsg:         "iops=100000",
+		},
+		{
+			name:              "fail volume not found",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{ModificationKeyIOPS: "4000"},
+			expModifyOptions:  &cloud.ModifyDiskOptions{IOPS: 4000},
+			modifyErr:         cloud.ErrNotFound,
+			expErrCode:        codes.NotFound,
+		},
+		{
+			name:              "fail volume not found before validating parameters",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{"XXX_FakeKey": "XXX_FakeValue"},
+			getDiskErr:        cloud.ErrNotFound,
+			expErrCode:        codes.NotFound,
+		},
+		{
+			name:       "fail volume not found without parameters",
+			volumeID:   "vol-test",
+			getDiskErr: cloud.ErrNotFound,
+			expErrCode: codes.NotFound,
+		},
+		{
+			name:              "fail modification error",
+			volumeID:          "vol-test",
+			mutableParameters: map[string]string{ModificationKeyThroughput: "500"},
+			expModifyOptions:  &cloud.ModifyDiskOptions{Throughput: 500},
+			modifyErr:         errors.New("volume \"vol-test\" in OPTIMIZING state, cannot currently modify"),
+			expErrCode:        codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Modifications are coalesced for a while before they are executed
+			t.Parallel()
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.volumeID != "" {
+				mockCloud.EXPECT().GetDiskByID(gomock.Any(), tc.volumeID).Return(&cloud.Disk{VolumeID: tc.volumeID}, tc.getDiskErr)
+			}
+			if tc.expModifyOptions != nil {
+				mockCloud.EXPECT().ResizeOrModifyDisk(gomock.Any(), tc.volumeID, int64(0), gomock.Eq(tc.expModifyOptions)).Return(int64(100), tc.modifyErr)
+			}
+
+			awsDriver := controllerService{
+				cloud:               mockCloud,
+				inFlight:            internal.NewInFlight(),
+				driverOptions:       &DriverOptions{},
+				modifyVolumeManager: newModifyVolumeManager(),
+			}
+
+			_, err := awsDriver.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          tc.volumeID,
+				MutableParameters: tc.mutableParameters,
+			})
+			if tc.expErrCode == codes.OK {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			checkExpectedErrorCode(t, err, tc.expErrCode)
+			if !strings.Contains(err.Error(), tc.expErrMsg) {
+				t.Fatalf("Expected error to contain %q, got: %v", tc.expErrMsg, err)
+			}
+		})
+	}
+}
+
 func checkExpectedErrorCode(t *testing.T, err error, expectedCode codes.Code) {
 	if err == nil {
 		t.Fatalf("Expected operation to fail but got no error")
@@ -20,14 +20,20 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/awslabs/volume-modifier-for-k8s/pkg/rpc"
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"k8s.io/klog/v2"
 )
 
@@ -58,23 +64,85 @@ const (
 type Driver struct {
 	controllerService
 	nodeService
+	csi.UnimplementedIdentityServer
 
 	srv     *grpc.Server
 	options *DriverOptions
+
+	// health serves the gRPC health checking protocol, mirroring the readiness reported by Probe
+	health *health.Server
+	ready  atomic.Bool
+	// ec2Gate checks the connectivity of the controller to EC2 for the ec2 health service. It is nil in node mode.
+	ec2Gate *ec2ReadinessGate
+
+	maintenance *maintenanceMode
+	// leader elects the replica of the controller that serves mutating RPCs. It is nil unless leader election is enabled.
+	leader *leaderElector
+	// adminSrv serves the admin endpoint. It is nil unless an admin endpoint is configured.
+	adminSrv *http.Server
 }
 
 type DriverOptions struct {
-	endpoint                  string
-	extraTags                 map[string]string
-	mode                      Mode
-	volumeAttachLimit         int64
-	reservedVolumeAttachments int
-	kubernetesClusterID       string
-	awsSdkDebugLog            bool
-	batching                  bool
-	warnOnInvalidTag          bool
-	userAgentExtra            string
-	otelTracing               bool
+	endpoint                   string
+	extraTags                  map[string]string
+	mode                       Mode
+	volumeAttachLimit          int64
+	reservedVolumeAttachments  int
+	kubernetesClusterID        string
+	awsSdkDebugLog             bool
+	batching                   bool
+	warnOnInvalidTag           bool
+	userAgentExtra             string
+	otelTracing                bool
+	metadataRetryAttempts      int
+	kmsAccessCheck             bool
+	kmsAccessCheckFailOpen     bool
+	shutdownGracePeriod        time.Duration
+	availabilityZoneOverride   string
+	mountNamespace             string
+	volumeSizeGranularity      int64
+	deviceSizeCheckTimeout     time.Duration
+	snapshotPVCNameTag         bool
+	maxConcurrentAttaches      int
+	deviceAttachTimeout        time.Duration
+	deviceReattachTimeout      time.Duration
+	maintenanceMode            bool
+	adminEndpoint              string
+	fsrWarmSnapshots           []string
+	fsrWarmCacheInterval       time.Duration
+	attachRetryBudget          int
+	attachRetryDeadline        time.Duration
+	scsiFallbackWait           time.Duration
+	validateStorageClasses     bool
+	unmountDetachedVolumes     bool
+	dropExcessTags             bool
+	reportIOUtilization        bool
+	createVolumeRetries        int
+	kubernetesAPIRetryAttempts int
+	deviceNameLeaseTimeout     time.Duration
+	resolveDevicesByUUID       bool
+	reportOrphanedVolumes      bool
+	fsrWaitTimeout             time.Duration
+	rpcSummaryLogLevel         int
+	metadataSources            []string
+	batchingWindow             time.Duration
+	imdsVersion                string
+	forceDetachTimeout         time.Duration
+	ec2RateLimits              cloud.RateLimits
+	nodeOperationWorkers       int
+	requestCacheTTL            time.Duration
+	endpointOptions            cloud.EndpointOptions
+	describeCacheTTL           time.Duration
+	roleOptions                cloud.RoleOptions
+	cloudProvider              string
+	fakeCloudOptions           cloud.FakeCloudOptions
+	volumePlacementStrategy    string
+	defaultMountOptions        []string
+	discardVolumeTypes         []string
+	leaderElection             LeaderElectionOptions
+	// skipFilesystemResizeOnStage is unset by default, so that filesystems are
+	// resized on stage unless disabled
+	skipFilesystemResizeOnStage bool
 }
 
 func NewDriver(options ...func(*DriverOptions)) (*Driver, error) {
@@ -93,7 +161,9 @@ func NewDriver(options ...func(*DriverOptions)) (*Driver, error) {
 	}
 
 	driver := Driver{
-		options: &driverOptions,
+		options:     &driverOptions,
+		health:      newHealthServer(),
+		maintenance: newMaintenanceMode(driverOptions.maintenanceMode),
 	}
 
 	switch driverOptions.mode {
@@ -108,6 +178,14 @@ func NewDriver(options ...func(*DriverOptions)) (*Driver, error) {
 		return nil, fmt.Errorf("unknown mode: %s", driverOptions.mode)
 	}
 
+	if driverOptions.mode != NodeMode {
+		driver.ec2Gate = newEC2ReadinessGate(driver.controllerService.cloud, driver.updateEC2Health)
+	}
+
+	if driverOptions.leaderElection.Enabled && driverOptions.mode != NodeMode {
+		driver.leader = newLeaderElector(driverOptions.leaderElection, cloud.DefaultKubernetesAPIClient, driver.health)
+	}
+
 	return &driver, nil
 }
 
@@ -117,7 +195,9 @@ func NewFakeDriver(e string, c cloud.Cloud, md *cloud.Metadata, m Mounter) (*Dri
 		mode:     AllMode,
 	}
 	driver := Driver{
-		options: driverOptions,
+		options:     driverOptions,
+		health:      newHealthServer(),
+		maintenance: newMaintenanceMode(false),
 		controllerService: controllerService{
 			cloud:               c,
 			inFlight:            internal.NewInFlight(),
@@ -130,6 +210,7 @@ func NewFakeDriver(e string, c cloud.Cloud, md *cloud.Metadata, m Mounter) (*Dri
 			inFlight:         internal.NewInFlight(),
 			mounter:          m,
 			driverOptions:    driverOptions,
+			unstagedVolumes:  newUnstagedVolumes(),
 		},
 	}
 	return &driver, nil
@@ -149,13 +230,13 @@ func (d *Driver) Run() error {
 	logErr := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		resp, err := handler(ctx, req)
 		if err != nil {
-			klog.ErrorS(err, "GRPC error")
+			klog.FromContext(ctx).Error(err, "GRPC error")
 		}
 		return resp, err
 	}
 
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(logErr),
+		grpc.ChainUnaryInterceptor(requestIDInterceptor, newRPCSummaryLogger(d.options.rpcSummaryLogLevel).unaryInterceptor, metrics.UnaryServerInterceptor, logErr, d.maintenance.unaryInterceptor, d.leader.unaryInterceptor),
 	}
 	if d.options.otelTracing {
 		opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
@@ -163,26 +244,65 @@ func (d *Driver) Run() error {
 	d.srv = grpc.NewServer(opts...)
 
 	csi.RegisterIdentityServer(d.srv, d)
+	healthpb.RegisterHealthServer(d.srv, d.health)
 
 	switch d.options.mode {
 	case ControllerMode:
 		csi.RegisterControllerServer(d.srv, d)
+		csi.RegisterGroupControllerServer(d.srv, d)
 		rpc.RegisterModifyServer(d.srv, d)
 	case NodeMode:
 		csi.RegisterNodeServer(d.srv, d)
 	case AllMode:
 		csi.RegisterControllerServer(d.srv, d)
+		csi.RegisterGroupControllerServer(d.srv, d)
 		csi.RegisterNodeServer(d.srv, d)
 		rpc.RegisterModifyServer(d.srv, d)
 	default:
 		return fmt.Errorf("unknown mode: %s", d.options.mode)
 	}
 
+	if d.options.adminEndpoint != "" && d.options.mode != NodeMode {
+		adminListener, err := net.Listen("tcp", d.options.adminEndpoint)
+		if err != nil {
+			return err
+		}
+		d.adminSrv = newAdminServer(d.options.adminEndpoint, d.maintenance, d.leader)
+		go func() {
+			klog.InfoS("Admin server listening", "address", adminListener.Addr())
+			if err := d.adminSrv.Serve(adminListener); err != nil && err != http.ErrServerClosed {
+				klog.ErrorS(err, "Admin server failed", "address", adminListener.Addr())
+			}
+		}()
+	}
+
+	if d.controllerService.fsrWarmCache != nil {
+		d.leader.runWhileLeading(d.controllerService.fsrWarmCache.run)
+	}
+	if d.controllerService.orphanedVolumes != nil {
+		d.leader.runWhileLeading(d.controllerService.orphanedVolumes.run)
+	}
+	d.leader.start()
+	d.ec2Gate.start()
+
 	klog.V(4).InfoS("Listening for connections", "address", listener.Addr())
+	d.setReady(true)
 	return d.srv.Serve(listener)
 }
 
+// Stop stops the driver. If a shutdown grace period is set, in-flight attach
+// and detach operations are given that long to finish before the server stops.
+// If the controller is the leader, it hands off the leadership first.
 func (d *Driver) Stop() {
+	d.setReady(false)
+	d.ec2Gate.stop()
+	d.leader.stop()
+	if d.options.shutdownGracePeriod > 0 {
+		d.controllerService.shutdown.drain(d.options.shutdownGracePeriod)
+	}
+	if d.adminSrv != nil {
+		d.adminSrv.Close()
+	}
 	d.srv.Stop()
 }
 
@@ -260,3 +380,285 @@ func WithOtelTracing(enableOtelTracing bool) func(*DriverOptions) {
 		o.otelTracing = enableOtelTracing
 	}
 }
+
+func WithMetadataRetryAttempts(metadataRetryAttempts int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.metadataRetryAttempts = metadataRetryAttempts
+	}
+}
+
+func WithKMSAccessCheck(enableKMSAccessCheck bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.kmsAccessCheck = enableKMSAccessCheck
+	}
+}
+
+func WithKMSAccessCheckFailOpen(kmsAccessCheckFailOpen bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.kmsAccessCheckFailOpen = kmsAccessCheckFailOpen
+	}
+}
+
+func WithShutdownGracePeriod(shutdownGracePeriod time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.shutdownGracePeriod = shutdownGracePeriod
+	}
+}
+
+func WithAvailabilityZoneOverride(availabilityZoneOverride string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.availabilityZoneOverride = availabilityZoneOverride
+	}
+}
+
+func WithMountNamespace(mountNamespace string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.mountNamespace = mountNamespace
+	}
+}
+
+func WithVolumeSizeGranularity(volumeSizeGranularity int64) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.volumeSizeGranularity = volumeSizeGranularity
+	}
+}
+
+func WithSnapshotPVCNameTag(snapshotPVCNameTag bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.snapshotPVCNameTag = snapshotPVCNameTag
+	}
+}
+
+func WithMaxConcurrentAttaches(maxConcurrentAttaches int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.maxConcurrentAttaches = maxConcurrentAttaches
+	}
+}
+
+func WithFSRWarmSnapshots(fsrWarmSnapshots []string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.fsrWarmSnapshots = fsrWarmSnapshots
+	}
+}
+
+func WithFSRWarmCacheInterval(fsrWarmCacheInterval time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.fsrWarmCacheInterval = fsrWarmCacheInterval
+	}
+}
+
+func WithDeviceAttachTimeout(deviceAttachTimeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.deviceAttachTimeout = deviceAttachTimeout
+	}
+}
+
+func WithDeviceReattachTimeout(deviceReattachTimeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.deviceReattachTimeout = deviceReattachTimeout
+	}
+}
+
+func WithSCSIFallbackWait(scsiFallbackWait time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.scsiFallbackWait = scsiFallbackWait
+	}
+}
+
+func WithUnmountDetachedVolumes(unmountDetachedVolumes bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.unmountDetachedVolumes = unmountDetachedVolumes
+	}
+}
+
+func WithDropExcessTags(dropExcessTags bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.dropExcessTags = dropExcessTags
+	}
+}
+
+func WithReportIOUtilization(reportIOUtilization bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.reportIOUtilization = reportIOUtilization
+	}
+}
+
+func WithCreateVolumeRetries(createVolumeRetries int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.createVolumeRetries = createVolumeRetries
+	}
+}
+
+func WithKubernetesAPIRetryAttempts(kubernetesAPIRetryAttempts int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.kubernetesAPIRetryAttempts = kubernetesAPIRetryAttempts
+	}
+}
+
+func WithDeviceNameLeaseTimeout(deviceNameLeaseTimeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.deviceNameLeaseTimeout = deviceNameLeaseTimeout
+	}
+}
+
+func WithResolveDevicesByUUID(resolveDevicesByUUID bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.resolveDevicesByUUID = resolveDevicesByUUID
+	}
+}
+
+func WithDescribeCacheTTL(describeCacheTTL time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.describeCacheTTL = describeCacheTTL
+	}
+}
+
+func WithRoleOptions(roleOptions cloud.RoleOptions) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.roleOptions = roleOptions
+	}
+}
+
+func WithCloudProvider(cloudProvider string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.cloudProvider = cloudProvider
+	}
+}
+
+func WithFakeCloudOptions(fakeCloudOptions cloud.FakeCloudOptions) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.fakeCloudOptions = fakeCloudOptions
+	}
+}
+
+func WithVolumePlacementStrategy(volumePlacementStrategy string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.volumePlacementStrategy = volumePlacementStrategy
+	}
+}
+
+func WithDefaultMountOptions(defaultMountOptions []string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.defaultMountOptions = defaultMountOptions
+	}
+}
+
+func WithDiscardVolumeTypes(discardVolumeTypes []string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.discardVolumeTypes = discardVolumeTypes
+	}
+}
+
+func WithResizeFilesystemOnStage(resizeFilesystemOnStage bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.skipFilesystemResizeOnStage = !resizeFilesystemOnStage
+	}
+}
+
+func WithReportOrphanedVolumes(reportOrphanedVolumes bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.reportOrphanedVolumes = reportOrphanedVolumes
+	}
+}
+
+func WithFSRWaitTimeout(fsrWaitTimeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.fsrWaitTimeout = fsrWaitTimeout
+	}
+}
+
+func WithRPCSummaryLogLevel(rpcSummaryLogLevel int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.rpcSummaryLogLevel = rpcSummaryLogLevel
+	}
+}
+
+func WithMetadataSources(metadataSources []string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.metadataSources = metadataSources
+	}
+}
+
+func WithBatchingWindow(batchingWindow time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.batchingWindow = batchingWindow
+	}
+}
+
+func WithIMDSVersion(imdsVersion string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.imdsVersion = imdsVersion
+	}
+}
+
+func WithForceDetachTimeout(forceDetachTimeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.forceDetachTimeout = forceDetachTimeout
+	}
+}
+
+func WithEC2RateLimits(ec2RateLimits cloud.RateLimits) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.ec2RateLimits = ec2RateLimits
+	}
+}
+
+func WithNodeOperationWorkers(nodeOperationWorkers int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.nodeOperationWorkers = nodeOperationWorkers
+	}
+}
+
+func WithRequestCacheTTL(requestCacheTTL time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.requestCacheTTL = requestCacheTTL
+	}
+}
+
+func WithEndpointOptions(endpointOptions cloud.EndpointOptions) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.endpointOptions = endpointOptions
+	}
+}
+
+func WithMaintenanceMode(maintenanceMode bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.maintenanceMode = maintenanceMode
+	}
+}
+
+func WithAdminEndpoint(adminEndpoint string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.adminEndpoint = adminEndpoint
+	}
+}
+
+func WithAttachRetryBudget(attachRetryBudget int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.attachRetryBudget = attachRetryBudget
+	}
+}
+
+func WithAttachRetryDeadline(attachRetryDeadline time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.attachRetryDeadline = attachRetryDeadline
+	}
+}
+
+func WithValidateStorageClasses(validateStorageClasses bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.validateStorageClasses = validateStorageClasses
+	}
+}
+
+func WithDeviceSizeCheckTimeout(deviceSizeCheckTimeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.deviceSizeCheckTimeout = deviceSizeCheckTimeout
+	}
+}
+
+func WithLeaderElection(leaderElection LeaderElectionOptions) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.leaderElection = leaderElection
+	}
+}
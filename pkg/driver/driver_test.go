@@ -17,8 +17,12 @@ limitations under the License.
 package driver
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 )
 
 func TestWithEndpoint(t *testing.T) {
@@ -130,3 +134,445 @@ func TestWithBatching(t *testing.T) {
 		t.Fatalf("expected batching option got set to %v but is set to %v", batching, options.batching)
 	}
 }
+
+func TestWithMetadataRetryAttempts(t *testing.T) {
+	var metadataRetryAttempts int = 3
+	options := &DriverOptions{}
+	WithMetadataRetryAttempts(metadataRetryAttempts)(options)
+	if options.metadataRetryAttempts != metadataRetryAttempts {
+		t.Fatalf("expected metadataRetryAttempts option got set to %v but is set to %v", metadataRetryAttempts, options.metadataRetryAttempts)
+	}
+}
+
+func TestWithKMSAccessCheck(t *testing.T) {
+	options := &DriverOptions{}
+	WithKMSAccessCheck(true)(options)
+	if !options.kmsAccessCheck {
+		t.Fatalf("expected kmsAccessCheck option got set to %v but is set to %v", true, options.kmsAccessCheck)
+	}
+}
+
+func TestWithKMSAccessCheckFailOpen(t *testing.T) {
+	options := &DriverOptions{}
+	WithKMSAccessCheckFailOpen(true)(options)
+	if !options.kmsAccessCheckFailOpen {
+		t.Fatalf("expected kmsAccessCheckFailOpen option got set to %v but is set to %v", true, options.kmsAccessCheckFailOpen)
+	}
+}
+
+func TestWithShutdownGracePeriod(t *testing.T) {
+	var shutdownGracePeriod = 30 * time.Second
+	options := &DriverOptions{}
+	WithShutdownGracePeriod(shutdownGracePeriod)(options)
+	if options.shutdownGracePeriod != shutdownGracePeriod {
+		t.Fatalf("expected shutdownGracePeriod option got set to %v but is set to %v", shutdownGracePeriod, options.shutdownGracePeriod)
+	}
+}
+
+func TestWithAvailabilityZoneOverride(t *testing.T) {
+	value := "us-west-2a"
+	options := &DriverOptions{}
+	WithAvailabilityZoneOverride(value)(options)
+	if options.availabilityZoneOverride != value {
+		t.Fatalf("expected availabilityZoneOverride option got set to %q but is set to %q", value, options.availabilityZoneOverride)
+	}
+}
+
+func TestWithMountNamespace(t *testing.T) {
+	value := "/proc/1/ns/mnt"
+	options := &DriverOptions{}
+	WithMountNamespace(value)(options)
+	if options.mountNamespace != value {
+		t.Fatalf("expected mountNamespace option got set to %q but is set to %q", value, options.mountNamespace)
+	}
+}
+
+func TestWithVolumeSizeGranularity(t *testing.T) {
+	var value int64 = 10
+	options := &DriverOptions{}
+	WithVolumeSizeGranularity(value)(options)
+	if options.volumeSizeGranularity != value {
+		t.Fatalf("expected volumeSizeGranularity option got set to %d but is set to %d", value, options.volumeSizeGranularity)
+	}
+}
+
+func TestWithDeviceSizeCheckTimeout(t *testing.T) {
+	value := 30 * time.Second
+	options := &DriverOptions{}
+	WithDeviceSizeCheckTimeout(value)(options)
+	if options.deviceSizeCheckTimeout != value {
+		t.Fatalf("expected deviceSizeCheckTimeout option got set to %v but is set to %v", value, options.deviceSizeCheckTimeout)
+	}
+}
+
+func TestWithSnapshotPVCNameTag(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithSnapshotPVCNameTag(value)(options)
+	if options.snapshotPVCNameTag != value {
+		t.Fatalf("expected snapshotPVCNameTag option got set to %v but is set to %v", value, options.snapshotPVCNameTag)
+	}
+}
+
+func TestWithMaxConcurrentAttaches(t *testing.T) {
+	value := 10
+	options := &DriverOptions{}
+	WithMaxConcurrentAttaches(value)(options)
+	if options.maxConcurrentAttaches != value {
+		t.Fatalf("expected maxConcurrentAttaches option got set to %d but is set to %d", value, options.maxConcurrentAttaches)
+	}
+}
+
+func TestWithFSRWarmSnapshots(t *testing.T) {
+	value := []string{"snap-1", "snap-2"}
+	options := &DriverOptions{}
+	WithFSRWarmSnapshots(value)(options)
+	if !reflect.DeepEqual(options.fsrWarmSnapshots, value) {
+		t.Fatalf("expected fsrWarmSnapshots option got set to %v but is set to %v", value, options.fsrWarmSnapshots)
+	}
+}
+
+func TestWithFSRWarmCacheInterval(t *testing.T) {
+	value := 5 * time.Minute
+	options := &DriverOptions{}
+	WithFSRWarmCacheInterval(value)(options)
+	if options.fsrWarmCacheInterval != value {
+		t.Fatalf("expected fsrWarmCacheInterval option got set to %v but is set to %v", value, options.fsrWarmCacheInterval)
+	}
+}
+
+func TestWithDeviceAttachTimeout(t *testing.T) {
+	value := 30 * time.Second
+	options := &DriverOptions{}
+	WithDeviceAttachTimeout(value)(options)
+	if options.deviceAttachTimeout != value {
+		t.Fatalf("expected deviceAttachTimeout option got set to %v but is set to %v", value, options.deviceAttachTimeout)
+	}
+}
+
+func TestWithDeviceReattachTimeout(t *testing.T) {
+	value := 5 * time.Second
+	options := &DriverOptions{}
+	WithDeviceReattachTimeout(value)(options)
+	if options.deviceReattachTimeout != value {
+		t.Fatalf("expected deviceReattachTimeout option got set to %v but is set to %v", value, options.deviceReattachTimeout)
+	}
+}
+
+func TestWithMaintenanceMode(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithMaintenanceMode(value)(options)
+	if options.maintenanceMode != value {
+		t.Fatalf("expected maintenanceMode option got set to %v but is set to %v", value, options.maintenanceMode)
+	}
+}
+
+func TestWithAdminEndpoint(t *testing.T) {
+	value := "127.0.0.1:8082"
+	options := &DriverOptions{}
+	WithAdminEndpoint(value)(options)
+	if options.adminEndpoint != value {
+		t.Fatalf("expected adminEndpoint option got set to %v but is set to %v", value, options.adminEndpoint)
+	}
+}
+
+func TestWithAttachRetryBudget(t *testing.T) {
+	value := 5
+	options := &DriverOptions{}
+	WithAttachRetryBudget(value)(options)
+	if options.attachRetryBudget != value {
+		t.Fatalf("expected attachRetryBudget option got set to %v but is set to %v", value, options.attachRetryBudget)
+	}
+}
+
+func TestWithAttachRetryDeadline(t *testing.T) {
+	value := 15 * time.Minute
+	options := &DriverOptions{}
+	WithAttachRetryDeadline(value)(options)
+	if options.attachRetryDeadline != value {
+		t.Fatalf("expected attachRetryDeadline option got set to %v but is set to %v", value, options.attachRetryDeadline)
+	}
+}
+
+func TestWithSCSIFallbackWait(t *testing.T) {
+	value := 10 * time.Second
+	options := &DriverOptions{}
+	WithSCSIFallbackWait(value)(options)
+	if options.scsiFallbackWait != value {
+		t.Fatalf("expected scsiFallbackWait option got set to %v but is set to %v", value, options.scsiFallbackWait)
+	}
+}
+
+func TestWithValidateStorageClasses(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithValidateStorageClasses(value)(options)
+	if options.validateStorageClasses != value {
+		t.Fatalf("expected validateStorageClasses option got set to %v but is set to %v", value, options.validateStorageClasses)
+	}
+}
+
+func TestWithUnmountDetachedVolumes(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithUnmountDetachedVolumes(value)(options)
+	if options.unmountDetachedVolumes != value {
+		t.Fatalf("expected unmountDetachedVolumes option got set to %v but is set to %v", value, options.unmountDetachedVolumes)
+	}
+}
+
+func TestWithDropExcessTags(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithDropExcessTags(value)(options)
+	if options.dropExcessTags != value {
+		t.Fatalf("expected dropExcessTags option got set to %v but is set to %v", value, options.dropExcessTags)
+	}
+}
+
+func TestWithReportIOUtilization(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithReportIOUtilization(value)(options)
+	if options.reportIOUtilization != value {
+		t.Fatalf("expected reportIOUtilization option got set to %v but is set to %v", value, options.reportIOUtilization)
+	}
+}
+
+func TestWithCreateVolumeRetries(t *testing.T) {
+	value := 3
+	options := &DriverOptions{}
+	WithCreateVolumeRetries(value)(options)
+	if options.createVolumeRetries != value {
+		t.Fatalf("expected createVolumeRetries option got set to %d but is set to %d", value, options.createVolumeRetries)
+	}
+}
+
+func TestWithKubernetesAPIRetryAttempts(t *testing.T) {
+	value := 3
+	options := &DriverOptions{}
+	WithKubernetesAPIRetryAttempts(value)(options)
+	if options.kubernetesAPIRetryAttempts != value {
+		t.Fatalf("expected kubernetesAPIRetryAttempts option got set to %d but is set to %d", value, options.kubernetesAPIRetryAttempts)
+	}
+}
+
+func TestWithDeviceNameLeaseTimeout(t *testing.T) {
+	value := 10 * time.Minute
+	options := &DriverOptions{}
+	WithDeviceNameLeaseTimeout(value)(options)
+	if options.deviceNameLeaseTimeout != value {
+		t.Fatalf("expected deviceNameLeaseTimeout option got set to %s but is set to %s", value, options.deviceNameLeaseTimeout)
+	}
+}
+
+func TestWithResolveDevicesByUUID(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithResolveDevicesByUUID(value)(options)
+	if options.resolveDevicesByUUID != value {
+		t.Fatalf("expected resolveDevicesByUUID option got set to %v but is set to %v", value, options.resolveDevicesByUUID)
+	}
+}
+
+func TestWithReportOrphanedVolumes(t *testing.T) {
+	value := true
+	options := &DriverOptions{}
+	WithReportOrphanedVolumes(value)(options)
+	if options.reportOrphanedVolumes != value {
+		t.Fatalf("expected reportOrphanedVolumes option got set to %v but is set to %v", value, options.reportOrphanedVolumes)
+	}
+}
+
+func TestWithFSRWaitTimeout(t *testing.T) {
+	value := 30 * time.Second
+	options := &DriverOptions{}
+	WithFSRWaitTimeout(value)(options)
+	if options.fsrWaitTimeout != value {
+		t.Fatalf("expected fsrWaitTimeout option got set to %v but is set to %v", value, options.fsrWaitTimeout)
+	}
+}
+
+func TestWithRPCSummaryLogLevel(t *testing.T) {
+	value := 2
+	options := &DriverOptions{}
+	WithRPCSummaryLogLevel(value)(options)
+	if options.rpcSummaryLogLevel != value {
+		t.Fatalf("expected rpcSummaryLogLevel option got set to %d but is set to %d", value, options.rpcSummaryLogLevel)
+	}
+}
+
+func TestWithMetadataSources(t *testing.T) {
+	value := []string{"kubernetes", "imds"}
+	options := &DriverOptions{}
+	WithMetadataSources(value)(options)
+	if !reflect.DeepEqual(options.metadataSources, value) {
+		t.Fatalf("expected metadataSources option got set to %v but is set to %v", value, options.metadataSources)
+	}
+}
+
+func TestWithBatchingWindow(t *testing.T) {
+	value := 500 * time.Millisecond
+	options := &DriverOptions{}
+	WithBatchingWindow(value)(options)
+	if options.batchingWindow != value {
+		t.Fatalf("expected batchingWindow option got set to %v but is set to %v", value, options.batchingWindow)
+	}
+}
+
+func TestWithIMDSVersion(t *testing.T) {
+	value := "v2"
+	options := &DriverOptions{}
+	WithIMDSVersion(value)(options)
+	if options.imdsVersion != value {
+		t.Fatalf("expected imdsVersion option got set to %v but is set to %v", value, options.imdsVersion)
+	}
+}
+
+func TestWithForceDetachTimeout(t *testing.T) {
+	value := 10 * time.Minute
+	options := &DriverOptions{}
+	WithForceDetachTimeout(value)(options)
+	if options.forceDetachTimeout != value {
+		t.Fatalf("expected forceDetachTimeout option got set to %v but is set to %v", value, options.forceDetachTimeout)
+	}
+}
+
+func TestWithEC2RateLimits(t *testing.T) {
+	value := cloud.RateLimits{MutatingQPS: 5, MutatingBurst: 10, ReadOnlyQPS: 20}
+	options := &DriverOptions{}
+	WithEC2RateLimits(value)(options)
+	if options.ec2RateLimits != value {
+		t.Fatalf("expected ec2RateLimits option got set to %v but is set to %v", value, options.ec2RateLimits)
+	}
+}
+
+func TestWithNodeOperationWorkers(t *testing.T) {
+	value := 8
+	options := &DriverOptions{}
+	WithNodeOperationWorkers(value)(options)
+	if options.nodeOperationWorkers != value {
+		t.Fatalf("expected nodeOperationWorkers option got set to %d but is set to %d", value, options.nodeOperationWorkers)
+	}
+}
+
+func TestWithRequestCacheTTL(t *testing.T) {
+	value := time.Minute
+	options := &DriverOptions{}
+	WithRequestCacheTTL(value)(options)
+	if options.requestCacheTTL != value {
+		t.Fatalf("expected requestCacheTTL option got set to %v but is set to %v", value, options.requestCacheTTL)
+	}
+}
+
+func TestWithEndpointOptions(t *testing.T) {
+	value := cloud.EndpointOptions{
+		EC2Endpoint:          "https://ec2.us-gov-west-1.amazonaws.com",
+		RegionalEC2Endpoints: map[string]string{"us-gov-east-1": "https://ec2.us-gov-east-1.amazonaws.com"},
+		UseFIPSEndpoints:     true,
+	}
+	options := &DriverOptions{}
+	WithEndpointOptions(value)(options)
+	if !reflect.DeepEqual(options.endpointOptions, value) {
+		t.Fatalf("expected endpointOptions option got set to %v but is set to %v", value, options.endpointOptions)
+	}
+}
+
+func TestWithResizeFilesystemOnStage(t *testing.T) {
+	options := &DriverOptions{}
+	WithResizeFilesystemOnStage(false)(options)
+	if !options.skipFilesystemResizeOnStage {
+		t.Fatalf("expected skipFilesystemResizeOnStage option got set to true but is set to false")
+	}
+	WithResizeFilesystemOnStage(true)(options)
+	if options.skipFilesystemResizeOnStage {
+		t.Fatalf("expected skipFilesystemResizeOnStage option got set to false but is set to true")
+	}
+}
+
+func TestWithDescribeCacheTTL(t *testing.T) {
+	value := 10 * time.Second
+	options := &DriverOptions{}
+	WithDescribeCacheTTL(value)(options)
+	if options.describeCacheTTL != value {
+		t.Fatalf("expected describeCacheTTL option got set to %v but is set to %v", value, options.describeCacheTTL)
+	}
+}
+
+func TestWithRoleOptions(t *testing.T) {
+	value := cloud.RoleOptions{
+		RoleARN:     "arn:aws:iam::123456789012:role/ebs-csi-driver",
+		ExternalID:  "external-id",
+		SessionName: "ebs-csi-driver-cluster",
+	}
+	options := &DriverOptions{}
+	WithRoleOptions(value)(options)
+	if !reflect.DeepEqual(options.roleOptions, value) {
+		t.Fatalf("expected roleOptions option got set to %v but is set to %v", value, options.roleOptions)
+	}
+}
+
+func TestWithCloudProvider(t *testing.T) {
+	value := cloud.CloudProviderFake
+	options := &DriverOptions{}
+	WithCloudProvider(value)(options)
+	if options.cloudProvider != value {
+		t.Fatalf("expected cloudProvider option got set to %v but is set to %v", value, options.cloudProvider)
+	}
+}
+
+func TestWithFakeCloudOptions(t *testing.T) {
+	value := cloud.FakeCloudOptions{
+		Latency:         10 * time.Millisecond,
+		TransitionDelay: time.Second,
+		Errors:          map[string]error{"AttachDisk": errors.New("throttled")},
+	}
+	options := &DriverOptions{}
+	WithFakeCloudOptions(value)(options)
+	if !reflect.DeepEqual(options.fakeCloudOptions, value) {
+		t.Fatalf("expected fakeCloudOptions option got set to %v but is set to %v", value, options.fakeCloudOptions)
+	}
+}
+
+func TestWithVolumePlacementStrategy(t *testing.T) {
+	options := &DriverOptions{}
+	WithVolumePlacementStrategy(PlacementStrategyRoundRobin)(options)
+	if options.volumePlacementStrategy != PlacementStrategyRoundRobin {
+		t.Fatalf("expected volumePlacementStrategy option got set to %s but is set to %s", PlacementStrategyRoundRobin, options.volumePlacementStrategy)
+	}
+}
+
+func TestWithDefaultMountOptions(t *testing.T) {
+	value := []string{"noatime", "lazytime"}
+	options := &DriverOptions{}
+	WithDefaultMountOptions(value)(options)
+	if !reflect.DeepEqual(options.defaultMountOptions, value) {
+		t.Fatalf("expected defaultMountOptions option got set to %v but is set to %v", value, options.defaultMountOptions)
+	}
+}
+
+func TestWithDiscardVolumeTypes(t *testing.T) {
+	value := []string{"gp3", "io2"}
+	options := &DriverOptions{}
+	WithDiscardVolumeTypes(value)(options)
+	if !reflect.DeepEqual(options.discardVolumeTypes, value) {
+		t.Fatalf("expected discardVolumeTypes option got set to %v but is set to %v", value, options.discardVolumeTypes)
+	}
+}
+
+func TestWithLeaderElection(t *testing.T) {
+	value := LeaderElectionOptions{
+		Enabled:       true,
+		Namespace:     "kube-system",
+		LeaseName:     "ebs-csi-controller",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   5 * time.Second,
+	}
+	options := &DriverOptions{}
+	WithLeaderElection(value)(options)
+	if options.leaderElection != value {
+		t.Fatalf("expected leaderElection option got set to %v but is set to %v", value, options.leaderElection)
+	}
+}
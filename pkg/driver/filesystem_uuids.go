@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// filesystemUUIDsFile is the name of the file the filesystem UUIDs of volumes
+// are persisted in, next to the socket of the CSI endpoint.
+const filesystemUUIDsFile = "filesystem-uuids.json"
+
+// filesystemUUIDs records the filesystem UUIDs of volumes by volume ID. The
+// records are persisted at path, if set, so that they survive a restart of the
+// node plugin, e.g. when it is upgraded while a volume is detached.
+type filesystemUUIDs struct {
+	mu    sync.Mutex
+	uuids map[string]string
+	path  string
+}
+
+// newFilesystemUUIDs returns the records of filesystem UUIDs persisted at
+// path, or nil if resolving devices by UUID is not enabled.
+func newFilesystemUUIDs(enabled bool, path string) *filesystemUUIDs {
+	if !enabled {
+		return nil
+	}
+	f := &filesystemUUIDs{uuids: map[string]string{}, path: path}
+	if path == "" {
+		return f
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			klog.ErrorS(err, "Could not read recorded filesystem UUIDs", "path", path)
+		}
+		return f
+	}
+	if err := json.Unmarshal(data, &f.uuids); err != nil {
+		klog.ErrorS(err, "Could not parse recorded filesystem UUIDs", "path", path)
+		f.uuids = map[string]string{}
+	}
+	return f
+}
+
+// filesystemUUIDsPath returns the path the filesystem UUIDs are persisted at
+// for the CSI endpoint, next to its socket, which is a directory of the host
+// such as /var/lib/kubelet/plugins/ebs.csi.aws.com. It returns "" if the
+// endpoint is not a unix domain socket.
+func filesystemUUIDsPath(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || strings.ToLower(u.Scheme) != "unix" {
+		return ""
+	}
+	return filepath.Join("/", u.Host, filepath.Dir(filepath.FromSlash(u.Path)), filesystemUUIDsFile)
+}
+
+// load returns the recorded filesystem UUID of volumeID.
+func (f *filesystemUUIDs) load(volumeID string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	uuid, ok := f.uuids[volumeID]
+	return uuid, ok
+}
+
+// store records uuid as the filesystem UUID of volumeID and persists the
+// records if it changed.
+func (f *filesystemUUIDs) store(volumeID, uuid string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.uuids[volumeID] == uuid {
+		return
+	}
+	f.uuids[volumeID] = uuid
+	if f.path == "" {
+		return
+	}
+	if err := f.persist(); err != nil {
+		klog.ErrorS(err, "Could not persist recorded filesystem UUIDs, they are lost when the node plugin restarts", "path", f.path)
+	}
+}
+
+// persist writes the records to a temporary file that then replaces the file
+// at path, so that a crash never leaves a partially written file behind.
+func (f *filesystemUUIDs) persist() error {
+	data, err := json.Marshal(f.uuids)
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesystemUUIDsPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), filesystemUUIDsFile)
+
+	f := newFilesystemUUIDs(true, path)
+	f.store("vol-1", "f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10")
+	f.store("vol-2", "0b6e4f3c-2a1d-4c5e-8f7a-9d0c1b2a3e4f")
+
+	restarted := newFilesystemUUIDs(true, path)
+	for volumeID, expUUID := range map[string]string{"vol-1": "f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10", "vol-2": "0b6e4f3c-2a1d-4c5e-8f7a-9d0c1b2a3e4f"} {
+		if uuid, ok := restarted.load(volumeID); !ok || uuid != expUUID {
+			t.Fatalf("Expected filesystem UUID %q of volume %s after restart, got %q", expUUID, volumeID, uuid)
+		}
+	}
+}
+
+func TestFilesystemUUIDsUnreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), filesystemUUIDsFile)
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFilesystemUUIDs(true, path)
+	if _, ok := f.load("vol-1"); ok {
+		t.Fatalf("Expected no filesystem UUID recorded")
+	}
+	f.store("vol-1", "f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10")
+	if uuid, ok := newFilesystemUUIDs(true, path).load("vol-1"); !ok || uuid != "f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10" {
+		t.Fatalf("Expected unreadable records to be replaced, got %q", uuid)
+	}
+}
+
+func TestFilesystemUUIDsPath(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		expPath  string
+	}{
+		{
+			endpoint: "unix:/csi/csi.sock",
+			expPath:  "/csi/" + filesystemUUIDsFile,
+		},
+		{
+			endpoint: "unix:///var/lib/kubelet/plugins/ebs.csi.aws.com/csi.sock",
+			expPath:  "/var/lib/kubelet/plugins/ebs.csi.aws.com/" + filesystemUUIDsFile,
+		},
+		{
+			endpoint: "tcp://127.0.0.1:10000",
+			expPath:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			if path := filesystemUUIDsPath(tc.endpoint); path != filepath.FromSlash(tc.expPath) {
+				t.Fatalf("Expected path %q, got %q", tc.expPath, path)
+			}
+		})
+	}
+}
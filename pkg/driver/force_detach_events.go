@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// forceDetachedEventReason is the reason of the events of forced
+	// detachments.
+	forceDetachedEventReason = "ForceDetached"
+
+	// forceDetachEventTimeout bounds the recording of an event.
+	forceDetachEventTimeout = time.Minute
+)
+
+// forceDetachEventRecorder records a Warning event of the PersistentVolume of
+// each volume whose detachment was forced, so that its users learn that data
+// not yet written by the instance may have been lost. The Kubernetes client is
+// only created once a detachment is forced.
+type forceDetachEventRecorder struct {
+	k8sClient cloud.KubernetesAPIClient
+
+	mu        sync.Mutex
+	clientset kubernetes.Interface
+	recorder  record.EventRecorder
+}
+
+func newForceDetachEventRecorder(k8sClient cloud.KubernetesAPIClient) *forceDetachEventRecorder {
+	return &forceDetachEventRecorder{k8sClient: k8sClient}
+}
+
+// forceDetached records the event of the forced detachment of volumeID from
+// nodeID in the background, so that the detachment does not wait for the
+// Kubernetes API.
+func (r *forceDetachEventRecorder) forceDetached(volumeID, nodeID string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), forceDetachEventTimeout)
+		defer cancel()
+		if err := r.record(ctx, volumeID, nodeID); err != nil {
+			klog.ErrorS(err, "Could not record the event of a forced detachment", "volumeID", volumeID, "nodeID", nodeID)
+		}
+	}()
+}
+
+func (r *forceDetachEventRecorder) record(ctx context.Context, volumeID, nodeID string) error {
+	clientset, recorder, err := r.client()
+	if err != nil {
+		return err
+	}
+	pvs, err := clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("could not list PersistentVolumes: %w", err)
+	}
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		if referencesVolume(pv, volumeID) {
+			recorder.Eventf(pv, corev1.EventTypeWarning, forceDetachedEventReason,
+				"Volume %s was stuck detaching from node %s and was forcibly detached, data not yet written by the node may have been lost", volumeID, nodeID)
+			return nil
+		}
+	}
+	return fmt.Errorf("no PersistentVolume references volume %q", volumeID)
+}
+
+func (r *forceDetachEventRecorder) client() (kubernetes.Interface, record.EventRecorder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recorder == nil {
+		clientset, err := r.k8sClient()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create Kubernetes client: %w", err)
+		}
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+		r.clientset = clientset
+		r.recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: DriverName})
+	}
+	return r.clientset, r.recorder, nil
+}
+
+// referencesVolume returns whether pv is the PersistentVolume of volumeID,
+// including in-tree ones that may be migrated to the driver.
+func referencesVolume(pv *corev1.PersistentVolume, volumeID string) bool {
+	if csi := pv.Spec.CSI; csi != nil && csi.Driver == DriverName {
+		return csi.VolumeHandle == volumeID
+	}
+	// In-tree volume IDs may be of the form aws://<zone>/<volumeID>
+	if ebs := pv.Spec.AWSElasticBlockStore; ebs != nil {
+		return path.Base(ebs.VolumeID) == volumeID
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestForceDetachEventRecorder(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pv       *v1.PersistentVolume
+		volumeID string
+		expEvent bool
+	}{
+		{
+			name: "CSI PersistentVolume",
+			pv: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-csi"},
+				Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: DriverName, VolumeHandle: "vol-test"},
+				}},
+			},
+			volumeID: "vol-test",
+			expEvent: true,
+		},
+		{
+			name: "in-tree PersistentVolume",
+			pv: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-in-tree"},
+				Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+					AWSElasticBlockStore: &v1.AWSElasticBlockStoreVolumeSource{VolumeID: "aws://us-west-2a/vol-test"},
+				}},
+			},
+			volumeID: "vol-test",
+			expEvent: true,
+		},
+		{
+			name: "no PersistentVolume of the volume",
+			pv: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-other"},
+				Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: DriverName, VolumeHandle: "vol-other"},
+				}},
+			},
+			volumeID: "vol-test",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeRecorder := record.NewFakeRecorder(1)
+			r := &forceDetachEventRecorder{
+				clientset: fake.NewSimpleClientset(tc.pv),
+				recorder:  fakeRecorder,
+			}
+
+			err := r.record(context.Background(), tc.volumeID, "i-test")
+			if tc.expEvent {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				expected := "Warning ForceDetached Volume vol-test was stuck detaching from node i-test and was forcibly detached, data not yet written by the node may have been lost"
+				if event := <-fakeRecorder.Events; event != expected {
+					t.Fatalf("Expected event %q, got %q", expected, event)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error without PersistentVolume of the volume")
+			}
+			if len(fakeRecorder.Events) != 0 {
+				t.Fatal("Expected no event")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// fsrWarmCacheManager keeps fast snapshot restores (FSR) enabled on a set of
+// snapshots in the availability zones of the cluster's nodes, so that volumes
+// restored from them are fully initialized at creation.
+//
+// Snapshots are tagged with the cluster's warm cache tag while they are
+// managed, with the zones the manager enabled FSR in as its value. This lets
+// the manager disable FSR on snapshots that were removed from the set,
+// including across controller restarts, without disabling it in zones that
+// other clusters or users enabled it in.
+type fsrWarmCacheManager struct {
+	cloud       cloud.Cloud
+	k8sClient   cloud.KubernetesAPIClient
+	snapshotIDs sets.Set[string]
+	interval    time.Duration
+	// tagKey is the warm cache tag of the cluster
+	tagKey string
+	// retryAttempts is the number of retries of transiently failing Node lookups
+	retryAttempts int
+}
+
+func newFSRWarmCacheManager(c cloud.Cloud, k8sClient cloud.KubernetesAPIClient, driverOptions *DriverOptions) *fsrWarmCacheManager {
+	return &fsrWarmCacheManager{
+		cloud:         c,
+		k8sClient:     k8sClient,
+		snapshotIDs:   sets.New(driverOptions.fsrWarmSnapshots...),
+		interval:      driverOptions.fsrWarmCacheInterval,
+		tagKey:        cloud.FSRWarmCacheTagKeyPrefix + driverOptions.kubernetesClusterID,
+		retryAttempts: driverOptions.kubernetesAPIRetryAttempts,
+	}
+}
+
+// run reconciles the warm cache every interval until ctx is done.
+func (m *fsrWarmCacheManager) run(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	logger.Info("Starting fast snapshot restore warm cache", "snapshotIDs", sets.List(m.snapshotIDs), "interval", m.interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := m.reconcile(ctx); err != nil {
+			logger.Error(err, "Could not reconcile fast snapshot restore warm cache")
+		}
+	}, m.interval)
+}
+
+// reconcile enables FSR on the snapshots of the set in the zones in use and
+// disables it in the other zones it enabled it in, and disables FSR on managed
+// snapshots that left the set.
+func (m *fsrWarmCacheManager) reconcile(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+	zones, err := m.zonesInUse(ctx)
+	if err != nil {
+		return fmt.Errorf("could not determine availability zones in use: %w", err)
+	}
+	tagValues, err := m.cloud.GetSnapshotTagValues(ctx, m.tagKey)
+	if err != nil {
+		return fmt.Errorf("could not list snapshots in warm cache: %w", err)
+	}
+
+	var errs []error
+	// An empty zone list is more likely a transient API problem than a
+	// cluster without nodes, so FSR is left as is rather than disabled
+	if zones.Len() == 0 {
+		logger.Info("No availability zones in use, not updating fast snapshot restores of warm cache")
+	} else {
+		for _, snapshotID := range sets.List(m.snapshotIDs) {
+			tagValue, tagged := tagValues[snapshotID]
+			if err := m.warm(ctx, snapshotID, zones, parseWarmCacheZones(tagValue), tagged); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, snapshotID := range sets.List(sets.KeySet(tagValues).Difference(m.snapshotIDs)) {
+		if err := m.evict(ctx, snapshotID, parseWarmCacheZones(tagValues[snapshotID])); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// warm enables FSR on snapshotID in zones, and disables it in the zones that
+// the manager recorded it enabled it in that are no longer in use.
+func (m *fsrWarmCacheManager) warm(ctx context.Context, snapshotID string, zones, recorded sets.Set[string], tagged bool) error {
+	logger := klog.FromContext(ctx)
+	currentZones, err := m.cloud.GetFastSnapshotRestoreZones(ctx, snapshotID)
+	if err != nil {
+		return fmt.Errorf("could not get fast snapshot restores of snapshot %s: %w", snapshotID, err)
+	}
+	current := sets.New(currentZones...)
+	// Zones the manager enabled FSR in but that were disabled since are no longer owned
+	owned := recorded.Intersection(current)
+	enable := zones.Difference(current)
+	disable := owned.Difference(zones)
+
+	// Tag first so that FSR is never enabled in a zone the manager does not know it owns
+	if claimed := owned.Union(enable); !tagged || !claimed.Equal(recorded) {
+		if err := m.tag(ctx, snapshotID, claimed); err != nil {
+			return err
+		}
+	}
+	if enable.Len() > 0 {
+		logger.Info("Enabling fast snapshot restores of warm cache snapshot", "snapshotID", snapshotID, "availabilityZones", sets.List(enable))
+		if _, err := m.cloud.EnableFastSnapshotRestores(ctx, sets.List(enable), snapshotID); err != nil {
+			return err
+		}
+	}
+	if disable.Len() > 0 {
+		logger.Info("Disabling fast snapshot restores of warm cache snapshot in zones no longer in use", "snapshotID", snapshotID, "availabilityZones", sets.List(disable))
+		if err := m.cloud.DisableFastSnapshotRestores(ctx, sets.List(disable), snapshotID); err != nil {
+			return err
+		}
+		return m.tag(ctx, snapshotID, owned.Union(enable).Difference(disable))
+	}
+	return nil
+}
+
+// evict disables FSR on a snapshot that left the set in the zones the manager
+// enabled it in, i.e. owned, and stops managing it.
+func (m *fsrWarmCacheManager) evict(ctx context.Context, snapshotID string, owned sets.Set[string]) error {
+	logger := klog.FromContext(ctx)
+	if owned.Len() > 0 {
+		currentZones, err := m.cloud.GetFastSnapshotRestoreZones(ctx, snapshotID)
+		if err != nil {
+			return fmt.Errorf("could not get fast snapshot restores of snapshot %s: %w", snapshotID, err)
+		}
+		if disable := sets.List(owned.Intersection(sets.New(currentZones...))); len(disable) > 0 {
+			logger.Info("Disabling fast snapshot restores of snapshot removed from warm cache", "snapshotID", snapshotID, "availabilityZones", disable)
+			if err := m.cloud.DisableFastSnapshotRestores(ctx, disable, snapshotID); err != nil {
+				return err
+			}
+		}
+	}
+	return m.cloud.UntagSnapshot(ctx, snapshotID, []string{m.tagKey})
+}
+
+// tag records the zones the manager enabled FSR in on snapshotID.
+func (m *fsrWarmCacheManager) tag(ctx context.Context, snapshotID string, owned sets.Set[string]) error {
+	return m.cloud.TagSnapshot(ctx, snapshotID, map[string]string{m.tagKey: strings.Join(sets.List(owned), " ")})
+}
+
+// parseWarmCacheZones parses the zones of the value of a warm cache tag.
+func parseWarmCacheZones(tagValue string) sets.Set[string] {
+	return sets.New(strings.Fields(tagValue)...)
+}
+
+// zonesInUse returns the availability zones of the cluster's nodes.
+func (m *fsrWarmCacheManager) zonesInUse(ctx context.Context) (sets.Set[string], error) {
+	clientset, err := m.k8sClient()
+	if err != nil {
+		return nil, err
+	}
+	var nodes *corev1.NodeList
+	err = retryTransient(ctx, "Nodes", m.retryAttempts, func() error {
+		nodes, err = clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	zones := sets.New[string]()
+	for _, node := range nodes.Items {
+		zone := node.Labels[WellKnownTopologyKey]
+		if zone == "" {
+			zone = node.Labels[TopologyKey]
+		}
+		if zone != "" {
+			zones.Insert(zone)
+		}
+	}
+	return zones, nil
+}
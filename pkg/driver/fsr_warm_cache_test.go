@@ -0,0 +1,227 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newZoneNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func TestFSRWarmCacheReconcile(t *testing.T) {
+	tagKey := cloud.FSRWarmCacheTagKeyPrefix + "cluster-1"
+	warmTag := func(zones string) map[string]string { return map[string]string{tagKey: zones} }
+	twoZoneNodes := []runtime.Object{
+		newZoneNode("node-1", map[string]string{WellKnownTopologyKey: "us-west-2a"}),
+		newZoneNode("node-2", map[string]string{TopologyKey: "us-west-2b"}),
+		newZoneNode("node-3", map[string]string{WellKnownTopologyKey: "us-west-2a"}),
+		newZoneNode("node-4", nil),
+	}
+
+	testCases := []struct {
+		name        string
+		snapshotIDs []string
+		nodes       []runtime.Object
+		listErr     error
+		expectMock  func(mockCloud *cloud.MockCloud)
+		expectErr   bool
+	}{
+		{
+			name:        "new snapshot is tagged with and enabled in zones in use",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(nil, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return(nil, nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-1", warmTag("us-west-2a us-west-2b")).Return(nil)
+				mockCloud.EXPECT().EnableFastSnapshotRestores(gomock.Any(), []string{"us-west-2a", "us-west-2b"}, "snap-1").Return(nil, nil)
+			},
+		},
+		{
+			name:        "new snapshot does not claim zones enabled by others",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(nil, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return([]string{"us-west-2a"}, nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-1", warmTag("us-west-2b")).Return(nil)
+				mockCloud.EXPECT().EnableFastSnapshotRestores(gomock.Any(), []string{"us-west-2b"}, "snap-1").Return(nil, nil)
+			},
+		},
+		{
+			name:        "snapshot in sync is left as is",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "us-west-2a us-west-2b"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return([]string{"us-west-2b", "us-west-2a"}, nil)
+			},
+		},
+		{
+			name:        "snapshot follows changed zones",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "us-west-2a us-west-2c"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return([]string{"us-west-2a", "us-west-2c"}, nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-1", warmTag("us-west-2a us-west-2b us-west-2c")).Return(nil)
+				mockCloud.EXPECT().EnableFastSnapshotRestores(gomock.Any(), []string{"us-west-2b"}, "snap-1").Return(nil, nil)
+				mockCloud.EXPECT().DisableFastSnapshotRestores(gomock.Any(), []string{"us-west-2c"}, "snap-1").Return(nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-1", warmTag("us-west-2a us-west-2b")).Return(nil)
+			},
+		},
+		{
+			name:        "zones enabled by others are not disabled",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "us-west-2a us-west-2b"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return([]string{"us-west-2a", "us-west-2b", "us-west-2c"}, nil)
+			},
+		},
+		{
+			name:        "zones disabled by others are no longer claimed",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       []runtime.Object{newZoneNode("node-1", map[string]string{WellKnownTopologyKey: "us-west-2a"})},
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "us-west-2a us-west-2c"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return([]string{"us-west-2a"}, nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-1", warmTag("us-west-2a")).Return(nil)
+			},
+		},
+		{
+			name:        "snapshot removed from set is disabled in claimed zones and untagged",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "us-west-2a us-west-2b", "snap-2": "us-west-2a us-west-2b"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return([]string{"us-west-2a", "us-west-2b"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-2").Return([]string{"us-west-2a", "us-west-2c"}, nil)
+				mockCloud.EXPECT().DisableFastSnapshotRestores(gomock.Any(), []string{"us-west-2a"}, "snap-2").Return(nil)
+				mockCloud.EXPECT().UntagSnapshot(gomock.Any(), "snap-2", []string{tagKey}).Return(nil)
+			},
+		},
+		{
+			name:        "snapshot removed from set without claimed zones is untagged",
+			snapshotIDs: nil,
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-2": ""}, nil)
+				mockCloud.EXPECT().UntagSnapshot(gomock.Any(), "snap-2", []string{tagKey}).Return(nil)
+			},
+		},
+		{
+			name:        "snapshot stays tagged if disabling fails",
+			snapshotIDs: nil,
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-2": "us-west-2a"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-2").Return([]string{"us-west-2a"}, nil)
+				mockCloud.EXPECT().DisableFastSnapshotRestores(gomock.Any(), []string{"us-west-2a"}, "snap-2").Return(errors.New("DisableFastSnapshotRestores failed"))
+			},
+			expectErr: true,
+		},
+		{
+			name:        "snapshots in set are left as is without zones in use",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       []runtime.Object{newZoneNode("node-1", nil)},
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "us-west-2a", "snap-2": "us-west-2a"}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-2").Return([]string{"us-west-2a"}, nil)
+				mockCloud.EXPECT().DisableFastSnapshotRestores(gomock.Any(), []string{"us-west-2a"}, "snap-2").Return(nil)
+				mockCloud.EXPECT().UntagSnapshot(gomock.Any(), "snap-2", []string{tagKey}).Return(nil)
+			},
+		},
+		{
+			name:        "failure on one snapshot does not stop the others",
+			snapshotIDs: []string{"snap-1", "snap-2"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(map[string]string{"snap-1": "", "snap-2": ""}, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return(nil, errors.New("DescribeFastSnapshotRestores failed"))
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-2").Return(nil, nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-2", warmTag("us-west-2a us-west-2b")).Return(nil)
+				mockCloud.EXPECT().EnableFastSnapshotRestores(gomock.Any(), []string{"us-west-2a", "us-west-2b"}, "snap-2").Return(nil, nil)
+			},
+			expectErr: true,
+		},
+		{
+			name:        "snapshot is not enabled if tagging fails",
+			snapshotIDs: []string{"snap-1"},
+			nodes:       twoZoneNodes,
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetSnapshotTagValues(gomock.Any(), tagKey).Return(nil, nil)
+				mockCloud.EXPECT().GetFastSnapshotRestoreZones(gomock.Any(), "snap-1").Return(nil, nil)
+				mockCloud.EXPECT().TagSnapshot(gomock.Any(), "snap-1", warmTag("us-west-2a us-west-2b")).Return(errors.New("CreateTags failed"))
+			},
+			expectErr: true,
+		},
+		{
+			name:        "nothing is changed if nodes cannot be listed",
+			snapshotIDs: []string{"snap-1"},
+			listErr:     errors.New("nodes is forbidden"),
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expectMock != nil {
+				tc.expectMock(mockCloud)
+			}
+
+			clientset := fake.NewSimpleClientset(tc.nodes...)
+			if tc.listErr != nil {
+				clientset.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tc.listErr
+				})
+			}
+			k8sClient := func() (kubernetes.Interface, error) { return clientset, nil }
+
+			m := newFSRWarmCacheManager(mockCloud, k8sClient, &DriverOptions{fsrWarmSnapshots: tc.snapshotIDs, kubernetesClusterID: "cluster-1"})
+			err := m.reconcile(context.Background())
+			if tc.expectErr && err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util/template"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// groupControllerCaps represents the capability of group controller service
+	groupControllerCaps = []csi.GroupControllerServiceCapability_RPC_Type{
+		csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT,
+	}
+)
+
+func (d *controllerService) GroupControllerGetCapabilities(ctx context.Context, req *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) {
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("GroupControllerGetCapabilities: called", "args", req)
+	var caps []*csi.GroupControllerServiceCapability
+	for _, cap := range groupControllerCaps {
+		c := &csi.GroupControllerServiceCapability{
+			Type: &csi.GroupControllerServiceCapability_Rpc{
+				Rpc: &csi.GroupControllerServiceCapability_RPC{
+					Type: cap,
+				},
+			},
+		}
+		caps = append(caps, c)
+	}
+	return &csi.GroupControllerGetCapabilitiesResponse{Capabilities: caps}, nil
+}
+
+// CreateVolumeGroupSnapshot creates crash-consistent snapshots of the source
+// volumes with a single EC2 CreateSnapshots call, which requires them to be
+// attached to the same instance. The name of the request is the ID of the
+// group snapshot, which its snapshots are tagged with.
+func (d *controllerService) CreateVolumeGroupSnapshot(ctx context.Context, req *csi.CreateVolumeGroupSnapshotRequest) (*csi.CreateVolumeGroupSnapshotResponse, error) {
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("CreateVolumeGroupSnapshot: called", "args", req)
+	if err := validateCreateVolumeGroupSnapshotRequest(req); err != nil {
+		return nil, err
+	}
+
+	groupSnapshotID := req.GetName()
+	volumeIDs := slices.Clone(req.GetSourceVolumeIds())
+	slices.Sort(volumeIDs)
+	volumeIDs = slices.Compact(volumeIDs)
+
+	// check if a request is already in-flight
+	if ok := d.inFlight.Insert(groupSnapshotID); !ok {
+		msg := fmt.Sprintf(internal.VolumeOperationAlreadyExistsErrorMsg, groupSnapshotID)
+		return nil, status.Error(codes.Aborted, msg)
+	}
+	defer d.inFlight.Delete(groupSnapshotID)
+
+	var vscTags []string
+	var outpostArn string
+	vsProps := new(template.VolumeSnapshotProps)
+	for key, value := range req.GetParameters() {
+		switch strings.ToLower(key) {
+		// The group snapshot takes the place of the snapshot in tag templates
+		case VolumeGroupSnapshotNameKey:
+			vsProps.VolumeSnapshotName = value
+		case VolumeGroupSnapshotNamespaceKey:
+			vsProps.VolumeSnapshotNamespace = value
+		case VolumeGroupSnapshotContentNameKey:
+			vsProps.VolumeSnapshotContentName = value
+		case OutpostArnKey:
+			if !isOutpostArn(value) {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter value %s for %s: not an Outpost ARN", value, key)
+			}
+			outpostArn = value
+		default:
+			if strings.HasPrefix(key, TagKeyPrefix) {
+				vscTags = append(vscTags, value)
+			} else {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter key %s for CreateVolumeGroupSnapshot", key)
+			}
+		}
+	}
+
+	snapshots, err := d.cloud.ListGroupSnapshots(ctx, groupSnapshotID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get group snapshot %q: %v", groupSnapshotID, err)
+	}
+	if len(snapshots) > 0 {
+		if sourceVolumeIDs := groupSnapshotSourceVolumeIDs(snapshots); !slices.Equal(sourceVolumeIDs, volumeIDs) {
+			return nil, status.Errorf(codes.AlreadyExists, "Group snapshot %s already exists for different volumes (%v)", groupSnapshotID, sourceVolumeIDs)
+		}
+		logger.V(4).Info("Group snapshot of volumes already exists; nothing to do", "groupSnapshotID", groupSnapshotID, "volumeIDs", volumeIDs)
+		return &csi.CreateVolumeGroupSnapshotResponse{GroupSnapshot: newVolumeGroupSnapshot(groupSnapshotID, snapshots)}, nil
+	}
+
+	addTags, err := template.Evaluate(vscTags, vsProps, d.driverOptions.warnOnInvalidTag)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Error interpolating the tag value: %v", err)
+	}
+
+	if err = validateExtraTags(addTags, d.driverOptions.warnOnInvalidTag); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid tag value: %v", err)
+	}
+
+	snapshotTags := map[string]string{
+		cloud.GroupSnapshotIDTagKey: groupSnapshotID,
+		cloud.AwsEbsDriverTagKey:    isManagedByDriver,
+	}
+	if d.driverOptions.kubernetesClusterID != "" {
+		resourceLifecycleTag := ResourceLifecycleTagPrefix + d.driverOptions.kubernetesClusterID
+		snapshotTags[resourceLifecycleTag] = ResourceLifecycleOwned
+		snapshotTags[NameTag] = d.driverOptions.kubernetesClusterID + "-dynamic-" + groupSnapshotID
+	}
+	for k, v := range interpolateExtraTags(d.driverOptions.extraTags, vsProps) {
+		snapshotTags[k] = v
+	}
+
+	// VolumeGroupSnapshotClass tags take precedence over the driver's default tags
+	mergeTags(snapshotTags, addTags)
+
+	opts := &cloud.SnapshotOptions{
+		Tags:       snapshotTags,
+		OutpostArn: outpostArn,
+	}
+	snapshots, err = d.cloud.CreateGroupSnapshot(ctx, volumeIDs, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, cloud.ErrNotFound):
+			return nil, status.Errorf(codes.NotFound, "Source volume of group snapshot %q not found: %v", groupSnapshotID, err)
+		case errors.Is(err, cloud.ErrNotAttachedToSameInstance):
+			return nil, status.Errorf(codes.FailedPrecondition, "Could not create group snapshot %q: %v", groupSnapshotID, err)
+		default:
+			return nil, status.Errorf(codes.Internal, "Could not create group snapshot %q: %v", groupSnapshotID, err)
+		}
+	}
+	return &csi.CreateVolumeGroupSnapshotResponse{GroupSnapshot: newVolumeGroupSnapshot(groupSnapshotID, snapshots)}, nil
+}
+
+func validateCreateVolumeGroupSnapshotRequest(req *csi.CreateVolumeGroupSnapshotRequest) error {
+	if len(req.GetName()) == 0 {
+		return status.Error(codes.InvalidArgument, "Group snapshot name not provided")
+	}
+
+	if len(req.GetSourceVolumeIds()) == 0 {
+		return status.Error(codes.InvalidArgument, "Group snapshot source volume IDs not provided")
+	}
+	if slices.Contains(req.GetSourceVolumeIds(), "") {
+		return status.Error(codes.InvalidArgument, "Group snapshot source volume ID is empty")
+	}
+	return nil
+}
+
+func (d *controllerService) GetVolumeGroupSnapshot(ctx context.Context, req *csi.GetVolumeGroupSnapshotRequest) (*csi.GetVolumeGroupSnapshotResponse, error) {
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("GetVolumeGroupSnapshot: called", "args", req)
+	groupSnapshotID := req.GetGroupSnapshotId()
+	if len(groupSnapshotID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Group snapshot ID not provided")
+	}
+
+	snapshots, err := d.cloud.ListGroupSnapshots(ctx, groupSnapshotID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get group snapshot %q: %v", groupSnapshotID, err)
+	}
+	if len(snapshots) == 0 {
+		return nil, status.Errorf(codes.NotFound, "Group snapshot %q not found", groupSnapshotID)
+	}
+	if err := validateGroupSnapshotIDs(groupSnapshotID, req.GetSnapshotIds(), snapshots); err != nil {
+		return nil, err
+	}
+	return &csi.GetVolumeGroupSnapshotResponse{GroupSnapshot: newVolumeGroupSnapshot(groupSnapshotID, snapshots)}, nil
+}
+
+// DeleteVolumeGroupSnapshot deletes the snapshots of the group snapshot. It
+// succeeds once none are left, so that it can be retried after a partial
+// deletion.
+func (d *controllerService) DeleteVolumeGroupSnapshot(ctx context.Context, req *csi.DeleteVolumeGroupSnapshotRequest) (*csi.DeleteVolumeGroupSnapshotResponse, error) {
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("DeleteVolumeGroupSnapshot: called", "args", req)
+	groupSnapshotID := req.GetGroupSnapshotId()
+	if len(groupSnapshotID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Group snapshot ID not provided")
+	}
+
+	// check if a request is already in-flight
+	if ok := d.inFlight.Insert(groupSnapshotID); !ok {
+		msg := fmt.Sprintf("DeleteVolumeGroupSnapshot for group snapshot %s is already in progress", groupSnapshotID)
+		return nil, status.Error(codes.Aborted, msg)
+	}
+	defer d.inFlight.Delete(groupSnapshotID)
+
+	snapshots, err := d.cloud.ListGroupSnapshots(ctx, groupSnapshotID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get group snapshot %q: %v", groupSnapshotID, err)
+	}
+	if len(snapshots) == 0 {
+		logger.V(4).Info("DeleteVolumeGroupSnapshot: group snapshot not found, returning with success")
+		return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+	}
+	if err := validateGroupSnapshotIDs(groupSnapshotID, req.GetSnapshotIds(), snapshots); err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range snapshots {
+		if _, err := d.cloud.DeleteSnapshot(ctx, snapshot.SnapshotID); err != nil && !errors.Is(err, cloud.ErrNotFound) {
+			return nil, status.Errorf(codes.Internal, "Could not delete snapshot ID %q of group snapshot %q: %v", snapshot.SnapshotID, groupSnapshotID, err)
+		}
+	}
+	return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+}
+
+// validateGroupSnapshotIDs checks that the snapshot IDs of a request, if
+// any, are those of the snapshots of the group snapshot.
+func validateGroupSnapshotIDs(groupSnapshotID string, snapshotIDs []string, snapshots []*cloud.Snapshot) error {
+	for _, snapshotID := range snapshotIDs {
+		if !slices.ContainsFunc(snapshots, func(snapshot *cloud.Snapshot) bool { return snapshot.SnapshotID == snapshotID }) {
+			return status.Errorf(codes.InvalidArgument, "Snapshot %q is not part of group snapshot %q", snapshotID, groupSnapshotID)
+		}
+	}
+	return nil
+}
+
+// groupSnapshotSourceVolumeIDs returns the sorted IDs of the source volumes
+// of snapshots.
+func groupSnapshotSourceVolumeIDs(snapshots []*cloud.Snapshot) []string {
+	var volumeIDs []string
+	for _, snapshot := range snapshots {
+		volumeIDs = append(volumeIDs, snapshot.SourceVolumeID)
+	}
+	slices.Sort(volumeIDs)
+	return volumeIDs
+}
+
+// newVolumeGroupSnapshot returns the group snapshot made of snapshots. It is
+// created when its first snapshot was and is ready to use once all of them
+// are.
+func newVolumeGroupSnapshot(groupSnapshotID string, snapshots []*cloud.Snapshot) *csi.VolumeGroupSnapshot {
+	groupSnapshot := &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: groupSnapshotID,
+		ReadyToUse:      true,
+	}
+	for _, snapshot := range snapshots {
+		ts := timestamppb.New(snapshot.CreationTime)
+		groupSnapshot.Snapshots = append(groupSnapshot.Snapshots, &csi.Snapshot{
+			SnapshotId:      snapshot.SnapshotID,
+			SourceVolumeId:  snapshot.SourceVolumeID,
+			SizeBytes:       snapshot.Size,
+			CreationTime:    ts,
+			ReadyToUse:      snapshot.ReadyToUse,
+			GroupSnapshotId: groupSnapshotID,
+		})
+		if groupSnapshot.CreationTime == nil || snapshot.CreationTime.Before(groupSnapshot.CreationTime.AsTime()) {
+			groupSnapshot.CreationTime = ts
+		}
+		groupSnapshot.ReadyToUse = groupSnapshot.ReadyToUse && snapshot.ReadyToUse
+	}
+	return groupSnapshot
+}
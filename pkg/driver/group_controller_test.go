@@ -0,0 +1,341 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func newGroupSnapshotMembers(volumeIDs ...string) []*cloud.Snapshot {
+	var snapshots []*cloud.Snapshot
+	for i, volumeID := range volumeIDs {
+		snapshots = append(snapshots, &cloud.Snapshot{
+			SnapshotID:     fmt.Sprintf("snap-%d", i+1),
+			SourceVolumeID: volumeID,
+			Size:           1,
+			CreationTime:   time.Unix(int64(100-i), 0),
+			ReadyToUse:     true,
+		})
+	}
+	return snapshots
+}
+
+func TestGroupControllerGetCapabilities(t *testing.T) {
+	d := &controllerService{}
+	resp, err := d.GroupControllerGetCapabilities(context.Background(), &csi.GroupControllerGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.GetCapabilities()) != 1 || resp.GetCapabilities()[0].GetRpc().GetType() != csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT {
+		t.Fatalf("Expected group snapshot capability, got %v", resp.GetCapabilities())
+	}
+}
+
+func TestCreateVolumeGroupSnapshot(t *testing.T) {
+	testCases := []struct {
+		name         string
+		req          *csi.CreateVolumeGroupSnapshotRequest
+		existing     []*cloud.Snapshot
+		createErr    error
+		expVolumeIDs []string
+		expTags      map[string]string
+		expSnapshots int
+		expCode      codes.Code
+	}{
+		{
+			name: "success",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-2", "vol-1", "vol-2"},
+				Parameters: map[string]string{
+					VolumeGroupSnapshotNameKey:      "vgs",
+					VolumeGroupSnapshotNamespaceKey: "default",
+					TagKeyPrefix + "1":              "app={{ .VolumeSnapshotNamespace }}",
+				},
+			},
+			expVolumeIDs: []string{"vol-1", "vol-2"},
+			expTags: map[string]string{
+				cloud.GroupSnapshotIDTagKey: "groupsnapshot-1",
+				cloud.AwsEbsDriverTagKey:    isManagedByDriver,
+				"app":                       "default",
+			},
+			expSnapshots: 2,
+		},
+		{
+			name: "success: group snapshot already exists",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-2", "vol-1"},
+			},
+			existing:     newGroupSnapshotMembers("vol-1", "vol-2"),
+			expSnapshots: 2,
+		},
+		{
+			name: "fail: group snapshot exists for other volumes",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-1", "vol-3"},
+			},
+			existing: newGroupSnapshotMembers("vol-1", "vol-2"),
+			expCode:  codes.AlreadyExists,
+		},
+		{
+			name: "fail: volumes not attached to the same instance",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			},
+			createErr:    fmt.Errorf("%w: [vol-1 vol-2]", cloud.ErrNotAttachedToSameInstance),
+			expVolumeIDs: []string{"vol-1", "vol-2"},
+			expCode:      codes.FailedPrecondition,
+		},
+		{
+			name: "fail: volume not found",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			},
+			createErr:    cloud.ErrNotFound,
+			expVolumeIDs: []string{"vol-1", "vol-2"},
+			expCode:      codes.NotFound,
+		},
+		{
+			name: "fail: no name",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				SourceVolumeIds: []string{"vol-1"},
+			},
+			expCode: codes.InvalidArgument,
+		},
+		{
+			name: "fail: no source volumes",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name: "groupsnapshot-1",
+			},
+			expCode: codes.InvalidArgument,
+		},
+		{
+			name: "fail: invalid parameter",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-1"},
+				Parameters:      map[string]string{"fastSnapshotRestoreAvailabilityZones": "us-east-1a"},
+			},
+			expCode: codes.InvalidArgument,
+		},
+		{
+			name: "fail: reserved tag",
+			req: &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: []string{"vol-1"},
+				Parameters:      map[string]string{TagKeyPrefix + "1": cloud.GroupSnapshotIDTagKey + "=other"},
+			},
+			expCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := cloud.NewMockCloud(mockCtl)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			mockCloud.EXPECT().ListGroupSnapshots(gomock.Eq(ctx), gomock.Eq(tc.req.GetName())).Return(tc.existing, nil).MaxTimes(1)
+			if tc.expVolumeIDs != nil {
+				mockCloud.EXPECT().CreateGroupSnapshot(gomock.Eq(ctx), gomock.Eq(tc.expVolumeIDs), gomock.Any()).DoAndReturn(func(_ context.Context, volumeIDs []string, opts *cloud.SnapshotOptions) ([]*cloud.Snapshot, error) {
+					if tc.expTags != nil {
+						assert.Equal(t, tc.expTags, opts.Tags)
+					}
+					if tc.createErr != nil {
+						return nil, tc.createErr
+					}
+					return newGroupSnapshotMembers(volumeIDs...), nil
+				})
+			}
+
+			resp, err := awsDriver.CreateVolumeGroupSnapshot(ctx, tc.req)
+			if tc.expCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			groupSnapshot := resp.GetGroupSnapshot()
+			assert.Equal(t, tc.req.GetName(), groupSnapshot.GetGroupSnapshotId())
+			assert.Len(t, groupSnapshot.GetSnapshots(), tc.expSnapshots)
+			for _, snapshot := range groupSnapshot.GetSnapshots() {
+				assert.Equal(t, tc.req.GetName(), snapshot.GetGroupSnapshotId())
+			}
+		})
+	}
+}
+
+func TestGetVolumeGroupSnapshot(t *testing.T) {
+	testCases := []struct {
+		name        string
+		snapshotIDs []string
+		snapshots   []*cloud.Snapshot
+		expCode     codes.Code
+	}{
+		{
+			name:      "success",
+			snapshots: newGroupSnapshotMembers("vol-1", "vol-2"),
+		},
+		{
+			name:        "success with snapshot IDs",
+			snapshotIDs: []string{"snap-2", "snap-1"},
+			snapshots:   newGroupSnapshotMembers("vol-1", "vol-2"),
+		},
+		{
+			name:    "fail: not found",
+			expCode: codes.NotFound,
+		},
+		{
+			name:        "fail: snapshot not part of the group snapshot",
+			snapshotIDs: []string{"snap-1", "snap-3"},
+			snapshots:   newGroupSnapshotMembers("vol-1", "vol-2"),
+			expCode:     codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := cloud.NewMockCloud(mockCtl)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			mockCloud.EXPECT().ListGroupSnapshots(gomock.Eq(ctx), gomock.Eq("groupsnapshot-1")).Return(tc.snapshots, nil)
+			resp, err := awsDriver.GetVolumeGroupSnapshot(ctx, &csi.GetVolumeGroupSnapshotRequest{
+				GroupSnapshotId: "groupsnapshot-1",
+				SnapshotIds:     tc.snapshotIDs,
+			})
+			if tc.expCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assert.Len(t, resp.GetGroupSnapshot().GetSnapshots(), len(tc.snapshots))
+		})
+	}
+}
+
+func TestDeleteVolumeGroupSnapshot(t *testing.T) {
+	testCases := []struct {
+		name        string
+		snapshotIDs []string
+		snapshots   []*cloud.Snapshot
+		deleteErrs  map[string]error
+		expDeleted  []string
+		expCode     codes.Code
+	}{
+		{
+			name:       "success",
+			snapshots:  newGroupSnapshotMembers("vol-1", "vol-2"),
+			expDeleted: []string{"snap-1", "snap-2"},
+		},
+		{
+			name:       "success: snapshot already deleted",
+			snapshots:  newGroupSnapshotMembers("vol-1", "vol-2"),
+			deleteErrs: map[string]error{"snap-1": cloud.ErrNotFound},
+			expDeleted: []string{"snap-1", "snap-2"},
+		},
+		{
+			name: "success: group snapshot not found",
+		},
+		{
+			name:       "fail: snapshot not deleted",
+			snapshots:  newGroupSnapshotMembers("vol-1", "vol-2"),
+			deleteErrs: map[string]error{"snap-1": fmt.Errorf("throttled")},
+			expDeleted: []string{"snap-1"},
+			expCode:    codes.Internal,
+		},
+		{
+			name:        "fail: snapshot not part of the group snapshot",
+			snapshotIDs: []string{"snap-3"},
+			snapshots:   newGroupSnapshotMembers("vol-1", "vol-2"),
+			expCode:     codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := cloud.NewMockCloud(mockCtl)
+
+			awsDriver := controllerService{
+				cloud:         mockCloud,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{},
+			}
+
+			mockCloud.EXPECT().ListGroupSnapshots(gomock.Eq(ctx), gomock.Eq("groupsnapshot-1")).Return(tc.snapshots, nil)
+			for _, snapshotID := range tc.expDeleted {
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq(snapshotID)).Return(tc.deleteErrs[snapshotID] == nil, tc.deleteErrs[snapshotID])
+			}
+			_, err := awsDriver.DeleteVolumeGroupSnapshot(ctx, &csi.DeleteVolumeGroupSnapshotRequest{
+				GroupSnapshotId: "groupsnapshot-1",
+				SnapshotIds:     tc.snapshotIDs,
+			})
+			if tc.expCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewVolumeGroupSnapshot(t *testing.T) {
+	snapshots := newGroupSnapshotMembers("vol-1", "vol-2")
+	groupSnapshot := newVolumeGroupSnapshot("groupsnapshot-1", snapshots)
+	assert.True(t, groupSnapshot.GetReadyToUse())
+	assert.True(t, snapshots[1].CreationTime.Equal(groupSnapshot.GetCreationTime().AsTime()))
+
+	snapshots[0].ReadyToUse = false
+	groupSnapshot = newVolumeGroupSnapshot("groupsnapshot-1", snapshots)
+	assert.False(t, groupSnapshot.GetReadyToUse())
+}
@@ -20,11 +20,15 @@ import (
 	"context"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	"k8s.io/klog/v2"
 )
 
 func (d *Driver) GetPluginInfo(ctx context.Context, req *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
-	klog.V(6).InfoS("GetPluginInfo: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(6).Info("GetPluginInfo: called", "args", req)
 	resp := &csi.GetPluginInfoResponse{
 		Name:          DriverName,
 		VendorVersion: driverVersion,
@@ -34,7 +38,8 @@ func (d *Driver) GetPluginInfo(ctx context.Context, req *csi.GetPluginInfoReques
 }
 
 func (d *Driver) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
-	klog.V(6).InfoS("GetPluginCapabilities: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(6).Info("GetPluginCapabilities: called", "args", req)
 	resp := &csi.GetPluginCapabilitiesResponse{
 		Capabilities: []*csi.PluginCapability{
 			{
@@ -51,6 +56,20 @@ func (d *Driver) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCa
 					},
 				},
 			},
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE,
+					},
+				},
+			},
+			{
+				Type: &csi.PluginCapability_VolumeExpansion_{
+					VolumeExpansion: &csi.PluginCapability_VolumeExpansion{
+						Type: csi.PluginCapability_VolumeExpansion_ONLINE,
+					},
+				},
+			},
 		},
 	}
 
@@ -58,6 +77,40 @@ func (d *Driver) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCa
 }
 
 func (d *Driver) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
-	klog.V(6).InfoS("Probe: called", "args", *req)
-	return &csi.ProbeResponse{}, nil
+	logger := klog.FromContext(ctx)
+	logger.V(6).Info("Probe: called", "args", req)
+	return &csi.ProbeResponse{Ready: wrapperspb.Bool(d.ready.Load())}, nil
+}
+
+// newHealthServer returns a gRPC health server that reports NOT_SERVING until
+// the driver becomes ready.
+func newHealthServer() *health.Server {
+	s := health.NewServer()
+	s.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	s.SetServingStatus(ec2HealthService, healthpb.HealthCheckResponse_NOT_SERVING)
+	return s
+}
+
+// setReady updates the readiness reported by Probe and the gRPC health service.
+func (d *Driver) setReady(ready bool) {
+	d.ready.Store(ready)
+	servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		servingStatus = healthpb.HealthCheckResponse_SERVING
+	}
+	klog.V(4).InfoS("Updating driver readiness", "ready", ready)
+	d.health.SetServingStatus("", servingStatus)
+	d.updateEC2Health()
+}
+
+// updateEC2Health updates the ec2 service of the gRPC health checking
+// protocol, which is SERVING while the driver is ready and, in controller
+// mode, EC2 was reachable at the last check. Probe does not depend on EC2, so
+// that the liveness probe does not restart controllers while EC2 is unreachable.
+func (d *Driver) updateEC2Health() {
+	servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+	if d.ready.Load() && d.ec2Gate.isReachable() {
+		servingStatus = healthpb.HealthCheckResponse_SERVING
+	}
+	d.health.SetServingStatus(ec2HealthService, servingStatus)
 }
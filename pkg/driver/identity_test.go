@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestHealthCheck(t *testing.T) {
+	testCases := []struct {
+		name          string
+		ready         []bool
+		expReady      bool
+		expHealthResp healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:          "not serving before the driver is ready",
+			expReady:      false,
+			expHealthResp: healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:          "serving when the driver is ready",
+			ready:         []bool{true},
+			expReady:      true,
+			expHealthResp: healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name:          "not serving after the driver stops being ready",
+			ready:         []bool{true, false},
+			expReady:      false,
+			expHealthResp: healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewFakeDriver("", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, ready := range tc.ready {
+				d.setReady(ready)
+			}
+
+			probeResp, err := d.Probe(context.Background(), &csi.ProbeRequest{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if probeResp.GetReady().GetValue() != tc.expReady {
+				t.Fatalf("Expected Probe ready %v, got %v", tc.expReady, probeResp.GetReady().GetValue())
+			}
+
+			healthResp, err := d.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if healthResp.GetStatus() != tc.expHealthResp {
+				t.Fatalf("Expected health status %v, got %v", tc.expHealthResp, healthResp.GetStatus())
+			}
+		})
+	}
+}
+
+func TestHealthServiceOnCSISocket(t *testing.T) {
+	socket := fmt.Sprintf("%s/csi.sock", t.TempDir())
+	d, err := NewFakeDriver("unix:"+socket, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	go func() {
+		if err := d.Run(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}()
+	err = wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(_ context.Context) (bool, error) {
+		return d.ready.Load(), nil
+	})
+	if err != nil {
+		t.Fatalf("Driver did not become ready: %v", err)
+	}
+	defer d.Stop()
+
+	conn, err := grpc.Dial("unix:"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected health status %v, got %v", healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	}
+}
+
+func TestGetPluginCapabilities(t *testing.T) {
+	d, err := NewFakeDriver("", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resp, err := d.GetPluginCapabilities(context.Background(), &csi.GetPluginCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var onlineExpansion, groupController bool
+	for _, capability := range resp.GetCapabilities() {
+		if capability.GetVolumeExpansion().GetType() == csi.PluginCapability_VolumeExpansion_ONLINE {
+			onlineExpansion = true
+		}
+		if capability.GetService().GetType() == csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE {
+			groupController = true
+		}
+	}
+	if !onlineExpansion {
+		t.Fatalf("Expected online volume expansion capability, got %v", resp.GetCapabilities())
+	}
+	if !groupController {
+		t.Fatalf("Expected group controller service capability, got %v", resp.GetCapabilities())
+	}
+}
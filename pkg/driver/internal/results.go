@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ResultCache remembers the results of recently completed requests for a time
+// to live, so that retries of a request are answered without calling AWS again.
+// A result is only returned for a request equal to the one that produced it.
+type ResultCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mux     sync.Mutex
+	results map[string]cachedResult
+}
+
+type cachedResult struct {
+	// request is a digest of the request, which may carry secrets.
+	request    [sha256.Size]byte
+	resourceID string
+	result     interface{}
+	expires    time.Time
+}
+
+// NewResultCache returns nil, which caches no results, if ttl is not positive.
+func NewResultCache(ttl time.Duration) *ResultCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ResultCache{
+		ttl:     ttl,
+		now:     time.Now,
+		results: make(map[string]cachedResult),
+	}
+}
+
+// Get returns the result cached for key if it was produced by a request equal
+// to req and has not expired.
+func (c *ResultCache) Get(key string, req Idempotent) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	cached, ok := c.results[key]
+	if !ok || !c.now().Before(cached.expires) || cached.request != sha256.Sum256([]byte(req.String())) {
+		return nil, false
+	}
+	klog.V(4).InfoS("Returning cached result of request", "key", key)
+	return cached.result, true
+}
+
+// Put caches the result of req for key. resourceID is the ID of the volume or
+// snapshot the result refers to, whose results Forget drops.
+func (c *ResultCache) Put(key string, req Idempotent, resourceID string, result interface{}) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	now := c.now()
+	for k, cached := range c.results {
+		if !now.Before(cached.expires) {
+			delete(c.results, k)
+		}
+	}
+	c.results[key] = cachedResult{
+		request:    sha256.Sum256([]byte(req.String())),
+		resourceID: resourceID,
+		result:     result,
+		expires:    now.Add(c.ttl),
+	}
+}
+
+// Forget drops the cached results that refer to resourceID, e.g. once it was
+// deleted.
+func (c *ResultCache) Forget(resourceID string) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for k, cached := range c.results {
+		if cached.resourceID == resourceID {
+			delete(c.results, k)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+type stringRequest string
+
+func (r stringRequest) String() string {
+	return string(r)
+}
+
+func TestResultCache(t *testing.T) {
+	if c := NewResultCache(0); c != nil {
+		t.Fatalf("expected no cache for ttl 0, got %v", c)
+	}
+
+	now := time.Now()
+	c := NewResultCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Put("CreateVolume/vol-name", stringRequest("size: 1"), "vol-test", "result")
+	if result, ok := c.Get("CreateVolume/vol-name", stringRequest("size: 1")); !ok || result != "result" {
+		t.Fatalf("expected cached result, got %v, %v", result, ok)
+	}
+
+	// A different request with the same key is not answered from the cache
+	if _, ok := c.Get("CreateVolume/vol-name", stringRequest("size: 2")); ok {
+		t.Fatal("expected no result for a different request")
+	}
+
+	// Results expire after the ttl
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("CreateVolume/vol-name", stringRequest("size: 1")); ok {
+		t.Fatal("expected no result after the ttl")
+	}
+
+	// Results of a deleted resource are forgotten
+	c.Put("CreateVolume/vol-name", stringRequest("size: 1"), "vol-test", "result")
+	c.Put("CreateVolume/other-vol-name", stringRequest("size: 1"), "vol-other", "other result")
+	c.Forget("vol-test")
+	if _, ok := c.Get("CreateVolume/vol-name", stringRequest("size: 1")); ok {
+		t.Fatal("expected no result after forgetting the volume")
+	}
+	if _, ok := c.Get("CreateVolume/other-vol-name", stringRequest("size: 1")); !ok {
+		t.Fatal("expected the result of another volume to be kept")
+	}
+}
+
+func TestResultCacheNil(t *testing.T) {
+	var c *ResultCache
+	c.Put("CreateVolume/vol-name", stringRequest("size: 1"), "vol-test", "result")
+	if _, ok := c.Get("CreateVolume/vol-name", stringRequest("size: 1")); ok {
+		t.Fatal("expected no result from a nil cache")
+	}
+	c.Forget("vol-test")
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+)
+
+// blockStats are the cumulative I/O counters of a block device.
+type blockStats struct {
+	// ios is the number of completed reads and writes
+	ios uint64
+	// bytes is the number of bytes read and written
+	bytes uint64
+}
+
+type ioSample struct {
+	stats blockStats
+	time  time.Time
+}
+
+// ioUtilization is the I/O observed on a volume between two samples of its
+// block device statistics.
+type ioUtilization struct {
+	iops           float64
+	throughputMiBs float64
+	interval       time.Duration
+}
+
+func (u ioUtilization) String() string {
+	return fmt.Sprintf("Observed I/O: %.0f IOPS, %.1f MiB/s over %s", u.iops, u.throughputMiBs, u.interval.Round(time.Second))
+}
+
+// ioStatsTracker computes the IOPS and throughput of volumes from the block
+// device statistics sampled by consecutive NodeGetVolumeStats calls, so that
+// an external autoscaler can raise the performance of volumes under I/O
+// pressure. It is nil if reporting I/O utilization is disabled.
+type ioStatsTracker struct {
+	now     func() time.Time
+	mu      sync.Mutex
+	samples map[string]ioSample
+}
+
+func newIOStatsTracker(enabled bool) *ioStatsTracker {
+	if !enabled {
+		return nil
+	}
+	return &ioStatsTracker{
+		now:     time.Now,
+		samples: map[string]ioSample{},
+	}
+}
+
+// observe records stats of volumeID and returns the utilization since the
+// previous sample. It returns false on the first sample of the volume and if
+// the counters went backwards, e.g. because the volume was reattached.
+func (t *ioStatsTracker) observe(volumeID string, stats blockStats) (ioUtilization, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	previous, found := t.samples[volumeID]
+	t.samples[volumeID] = ioSample{stats: stats, time: now}
+	if !found || stats.ios < previous.stats.ios || stats.bytes < previous.stats.bytes {
+		return ioUtilization{}, false
+	}
+	interval := now.Sub(previous.time)
+	if interval <= 0 {
+		return ioUtilization{}, false
+	}
+	seconds := interval.Seconds()
+	return ioUtilization{
+		iops:           float64(stats.ios-previous.stats.ios) / seconds,
+		throughputMiBs: float64(stats.bytes-previous.stats.bytes) / util.MiB / seconds,
+		interval:       interval,
+	}, true
+}
+
+// forget drops the samples of volumeID, e.g. after it was unstaged.
+func (t *ioStatsTracker) forget(volumeID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.samples, volumeID)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+)
+
+func TestIOStatsTracker(t *testing.T) {
+	if newIOStatsTracker(false) != nil {
+		t.Fatalf("Expected no tracker when disabled")
+	}
+
+	now := time.Unix(1700000000, 0)
+	tracker := newIOStatsTracker(true)
+	tracker.now = func() time.Time { return now }
+
+	if _, ok := tracker.observe("vol-1", blockStats{ios: 1000, bytes: 10 * util.MiB}); ok {
+		t.Fatalf("Expected no utilization on the first sample")
+	}
+
+	now = now.Add(10 * time.Second)
+	utilization, ok := tracker.observe("vol-1", blockStats{ios: 31000, bytes: 1210 * util.MiB})
+	if !ok {
+		t.Fatalf("Expected utilization on the second sample")
+	}
+	if utilization.iops != 3000 || utilization.throughputMiBs != 120 || utilization.interval != 10*time.Second {
+		t.Fatalf("Expected 3000 IOPS and 120 MiB/s over 10s, got %+v", utilization)
+	}
+	if expected := "Observed I/O: 3000 IOPS, 120.0 MiB/s over 10s"; utilization.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, utilization.String())
+	}
+
+	// Counters of a reattached volume start over
+	now = now.Add(10 * time.Second)
+	if _, ok := tracker.observe("vol-1", blockStats{ios: 10, bytes: util.MiB}); ok {
+		t.Fatalf("Expected no utilization after the counters went backwards")
+	}
+
+	tracker.forget("vol-1")
+	now = now.Add(10 * time.Second)
+	if _, ok := tracker.observe("vol-1", blockStats{ios: 20, bytes: 2 * util.MiB}); ok {
+		t.Fatalf("Expected no utilization on the first sample after forget")
+	}
+
+	// forget is a no-op on a disabled tracker
+	var disabled *ioStatsTracker
+	disabled.forget("vol-1")
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// leaderPath is the path of the admin endpoint that reports the leadership of the controller.
+	leaderPath = "/leader"
+	// leaderHealthService is the service of the gRPC health checking protocol
+	// that is SERVING while the controller is the leader.
+	leaderHealthService = "leader"
+)
+
+var (
+	// sidecarLeaseSuffix is the driver name as the sidecars put it in the names
+	// of the Leases of their leader elections.
+	sidecarLeaseSuffix = strings.NewReplacer(".", "-", "/", "-").Replace(DriverName)
+
+	provisionerLease = sidecarLeaseSuffix
+	attacherLease    = "external-attacher-leader-" + sidecarLeaseSuffix
+	resizerLease     = "external-resizer-" + sidecarLeaseSuffix
+	snapshotterLease = "external-snapshotter-leader-" + sidecarLeaseSuffix
+
+	// sidecarLeases are the Leases of the sidecars that send the mutating
+	// controller RPCs. volume-modifier-for-k8s follows the Lease of the
+	// external-resizer rather than electing its own leader.
+	sidecarLeases = map[string]string{
+		"/csi.v1.Controller/CreateVolume":                   provisionerLease,
+		"/csi.v1.Controller/DeleteVolume":                   provisionerLease,
+		"/csi.v1.Controller/ControllerPublishVolume":        attacherLease,
+		"/csi.v1.Controller/ControllerUnpublishVolume":      attacherLease,
+		"/csi.v1.Controller/CreateSnapshot":                 snapshotterLease,
+		"/csi.v1.Controller/DeleteSnapshot":                 snapshotterLease,
+		"/csi.v1.Controller/ControllerExpandVolume":         resizerLease,
+		"/csi.v1.Controller/ControllerModifyVolume":         resizerLease,
+		"/modify.v1.Modify/ModifyVolumeProperties":          resizerLease,
+		"/csi.v1.GroupController/CreateVolumeGroupSnapshot": snapshotterLease,
+		"/csi.v1.GroupController/DeleteVolumeGroupSnapshot": snapshotterLease,
+	}
+)
+
+// LeaderElectionOptions configures the election of the controller replica
+// that serves mutating controller RPCs.
+type LeaderElectionOptions struct {
+	Enabled       bool
+	Namespace     string
+	LeaseName     string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// leaderElector elects the leader among the replicas of the controller with a
+// Lease, so that background loops that change volumes or snapshots run on one
+// replica only.
+//
+// The sidecars only call the driver of their own pod, so mutating controller
+// RPCs are not gated on that Lease: a replica serves a mutating RPC while the
+// sidecar of its pod that sends it holds its own Lease, and rejects it with
+// Unavailable otherwise, e.g. when it is sent by a sidecar that just lost its
+// leadership. Each kind of operation is thus served by one replica at a time,
+// that of the leader of its sidecar, whichever replica leads the driver.
+//
+// When a replica stops, it first stops accepting mutating RPCs and gives those
+// in flight up to the renew deadline to finish before it releases the Lease,
+// so that the next leader does not start operations on volumes that are still
+// being attached or detached.
+type leaderElector struct {
+	options   LeaderElectionOptions
+	identity  string
+	k8sClient cloud.KubernetesAPIClient
+	health    *health.Server
+	// podName is the identity the sidecars of the pod hold their Leases with
+	podName string
+
+	mu      sync.Mutex
+	leading bool
+	holder  string
+	// leases lists the Leases of the sidecars once they are known
+	leases coordinationlisters.LeaseNamespaceLister
+	// stopping is set once the controller stopped accepting mutating RPCs
+	stopping bool
+	// inFlight counts the mutating RPCs being served
+	inFlight int
+	// idle is closed once no mutating RPC is in flight during a handoff
+	idle chan struct{}
+
+	// tasks run in the background while the controller is the leader
+	tasks []func(context.Context)
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func newLeaderElector(options LeaderElectionOptions, k8sClient cloud.KubernetesAPIClient, healthServer *health.Server) *leaderElector {
+	hostname, err := os.Hostname()
+	if err != nil {
+		klog.ErrorS(err, "Could not get hostname for leader election identity")
+	}
+	healthServer.SetServingStatus(leaderHealthService, healthpb.HealthCheckResponse_NOT_SERVING)
+	return &leaderElector{
+		options:   options,
+		identity:  hostname + "_" + string(uuid.NewUUID()),
+		k8sClient: k8sClient,
+		health:    healthServer,
+		podName:   hostname,
+	}
+}
+
+// runWhileLeading runs task in the background while the controller is the
+// leader, with a context that is canceled when the leadership is lost, so that
+// background loops that change volumes or snapshots run on one replica only.
+// Without leader election, task runs right away until the driver exits. It
+// must be called before start.
+func (l *leaderElector) runWhileLeading(task func(context.Context)) {
+	if l == nil {
+		go task(context.Background())
+		return
+	}
+	l.tasks = append(l.tasks, task)
+}
+
+// start runs for the leadership in the background until stop is called.
+func (l *leaderElector) start() {
+	if l == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.stopped = make(chan struct{})
+	go func() {
+		defer close(l.stopped)
+		// A replica that lost the leadership runs for it again
+		wait.UntilWithContext(ctx, l.elect, l.options.RetryPeriod)
+	}()
+	go wait.UntilWithContext(ctx, l.watchSidecarLeases, l.options.RetryPeriod)
+}
+
+// watchSidecarLeases watches the Leases of the sidecars until ctx is canceled.
+// Mutating RPCs are rejected until the Leases are known.
+func (l *leaderElector) watchSidecarLeases(ctx context.Context) {
+	clientset, err := l.k8sClient()
+	if err != nil {
+		klog.ErrorS(err, "Could not watch the Leases of the sidecars")
+		return
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(l.options.Namespace))
+	leases := factory.Coordination().V1().Leases()
+	informer := leases.Informer()
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return
+	}
+	l.mu.Lock()
+	l.leases = leases.Lister().Leases(l.options.Namespace)
+	l.mu.Unlock()
+	klog.InfoS("Watching the Leases of the sidecars", "namespace", l.options.Namespace, "podName", l.podName)
+	<-ctx.Done()
+	factory.Shutdown()
+}
+
+// holdsSidecarLease returns whether the sidecar of the pod that sends method
+// holds its Lease. A sidecar without Lease, i.e. without leader election, is
+// the only one that sends method.
+func (l *leaderElector) holdsSidecarLease(method string) bool {
+	name, ok := sidecarLeases[method]
+	if !ok {
+		return l.leading
+	}
+	if l.leases == nil {
+		return false
+	}
+	lease, err := l.leases.Get(name)
+	if apierrors.IsNotFound(err) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	return lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == l.podName
+}
+
+// elect runs for the leadership and returns once the leadership is lost or ctx
+// is canceled.
+func (l *leaderElector) elect(ctx context.Context) {
+	clientset, err := l.k8sClient()
+	if err != nil {
+		klog.ErrorS(err, "Could not run for leader")
+		return
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      l.options.LeaseName,
+				Namespace: l.options.Namespace,
+			},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: l.identity},
+		},
+		LeaseDuration:   l.options.LeaseDuration,
+		RenewDeadline:   l.options.RenewDeadline,
+		RetryPeriod:     l.options.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            l.options.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				l.setLeading(true)
+				// ctx is canceled when the leadership is lost
+				for _, task := range l.tasks {
+					go task(ctx)
+				}
+			},
+			OnStoppedLeading: func() {
+				l.setLeading(false)
+			},
+			OnNewLeader: func(identity string) {
+				l.mu.Lock()
+				l.holder = identity
+				l.mu.Unlock()
+				klog.InfoS("New leader elected", "lease", klog.KRef(l.options.Namespace, l.options.LeaseName), "leader", identity)
+			},
+		},
+	})
+	if err != nil {
+		klog.ErrorS(err, "Could not run for leader")
+		return
+	}
+	klog.InfoS("Running for leader", "lease", klog.KRef(l.options.Namespace, l.options.LeaseName), "identity", l.identity)
+	elector.Run(ctx)
+}
+
+// setLeading reports whether the controller is the leader.
+func (l *leaderElector) setLeading(leading bool) {
+	l.mu.Lock()
+	changed := l.leading != leading
+	l.leading = leading
+	l.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+	if leading {
+		servingStatus = healthpb.HealthCheckResponse_SERVING
+		klog.InfoS("Became leader", "identity", l.identity)
+	} else {
+		klog.InfoS("Lost leadership", "identity", l.identity)
+	}
+	l.health.SetServingStatus(leaderHealthService, servingStatus)
+}
+
+// stop hands off the leadership: it stops accepting mutating RPCs, waits up to
+// the renew deadline for the in-flight ones to finish and releases the Lease.
+func (l *leaderElector) stop() {
+	if l == nil {
+		return
+	}
+	if !l.handoff(l.options.RenewDeadline) {
+		klog.InfoS("Releasing leadership with mutating requests still in flight", "timeout", l.options.RenewDeadline)
+	}
+	if l.cancel != nil {
+		l.cancel()
+		<-l.stopped
+	}
+}
+
+// handoff stops accepting mutating RPCs and waits up to timeout for the
+// in-flight ones to finish. It returns false if any is still in flight.
+// Mutating RPCs in flight when a sidecar loses its leadership rather run to
+// completion: EC2 operations are idempotent, so the replica of the next leader
+// of the sidecar waits for them when it retries them.
+func (l *leaderElector) handoff(timeout time.Duration) bool {
+	l.setLeading(false)
+
+	l.mu.Lock()
+	l.stopping = true
+	if l.inFlight == 0 {
+		l.mu.Unlock()
+		return true
+	}
+	if l.idle == nil {
+		l.idle = make(chan struct{})
+	}
+	idle := l.idle
+	l.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// begin registers the mutating RPC method. It returns false if the controller
+// is stopping or the sidecar that sends method does not hold its Lease.
+func (l *leaderElector) begin(method string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopping || !l.holdsSidecarLease(method) {
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+// end unregisters a mutating RPC registered by begin.
+func (l *leaderElector) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if l.inFlight == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
+// unaryInterceptor rejects mutating controller RPCs unless the sidecar of the
+// pod that sends them is the leader.
+func (l *leaderElector) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if _, ok := mutatingMethods[info.FullMethod]; l == nil || !ok {
+		return handler(ctx, req)
+	}
+	if !l.begin(info.FullMethod) {
+		return nil, status.Errorf(codes.Unavailable, "Sidecar of the controller is not the leader, not accepting %s requests", info.FullMethod)
+	}
+	defer l.end()
+	return handler(ctx, req)
+}
+
+type leaderStatus struct {
+	Leader   bool   `json:"leader"`
+	Identity string `json:"identity"`
+	Holder   string `json:"holder"`
+}
+
+// ServeHTTP reports the leadership of the controller. It responds with
+// 503 Service Unavailable unless the controller is the leader.
+func (l *leaderElector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	l.mu.Lock()
+	s := leaderStatus{Leader: l.leading, Identity: l.identity, Holder: l.holder}
+	l.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !s.Leader {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		klog.ErrorS(err, "Could not write leader response")
+	}
+}
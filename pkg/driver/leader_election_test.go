@@ -0,0 +1,364 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var testLeaderElectionOptions = LeaderElectionOptions{
+	Enabled:       true,
+	Namespace:     "kube-system",
+	LeaseName:     "ebs-csi-controller",
+	LeaseDuration: 2 * time.Second,
+	RenewDeadline: time.Second,
+	RetryPeriod:   100 * time.Millisecond,
+}
+
+func newTestLeaderElector(clientset kubernetes.Interface) *leaderElector {
+	return newLeaderElector(testLeaderElectionOptions, func() (kubernetes.Interface, error) { return clientset, nil }, health.NewServer())
+}
+
+// withSidecarLeases makes l know the Leases of the sidecars, held by the pods
+// in holders by Lease name.
+func withSidecarLeases(l *leaderElector, holders map[string]string) *leaderElector {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for name, holder := range holders {
+		holder := holder
+		lease := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: l.options.Namespace},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+		}
+		if err := indexer.Add(lease); err != nil {
+			panic(err)
+		}
+	}
+	l.leases = coordinationlisters.NewLeaseLister(indexer).Leases(l.options.Namespace)
+	return l
+}
+
+func leaderHealth(t *testing.T, l *leaderElector) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := l.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: leaderHealthService})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return resp.GetStatus()
+}
+
+func TestLeaderElectorUnaryInterceptor(t *testing.T) {
+	testCases := []struct {
+		name       string
+		elector    func() *leaderElector
+		method     string
+		expErrCode codes.Code
+	}{
+		{
+			name:       "mutating request served without leader election",
+			elector:    func() *leaderElector { return nil },
+			method:     "/csi.v1.Controller/ControllerPublishVolume",
+			expErrCode: codes.OK,
+		},
+		{
+			name:       "mutating request rejected until the sidecar Leases are known",
+			elector:    func() *leaderElector { return newTestLeaderElector(fake.NewSimpleClientset()) },
+			method:     "/csi.v1.Controller/ControllerPublishVolume",
+			expErrCode: codes.Unavailable,
+		},
+		{
+			name: "mutating request served if the sidecar leads on a replica whose driver is not the leader",
+			elector: func() *leaderElector {
+				l := newTestLeaderElector(fake.NewSimpleClientset())
+				return withSidecarLeases(l, map[string]string{attacherLease: l.podName, provisionerLease: "other-pod"})
+			},
+			method:     "/csi.v1.Controller/ControllerPublishVolume",
+			expErrCode: codes.OK,
+		},
+		{
+			name: "mutating request rejected if the sidecar leads on another replica whose driver is not the leader",
+			elector: func() *leaderElector {
+				l := newTestLeaderElector(fake.NewSimpleClientset())
+				l.setLeading(true)
+				return withSidecarLeases(l, map[string]string{attacherLease: l.podName, provisionerLease: "other-pod"})
+			},
+			method:     "/csi.v1.Controller/CreateVolume",
+			expErrCode: codes.Unavailable,
+		},
+		{
+			name: "modify request served if the external-resizer leads",
+			elector: func() *leaderElector {
+				l := newTestLeaderElector(fake.NewSimpleClientset())
+				return withSidecarLeases(l, map[string]string{resizerLease: l.podName})
+			},
+			method:     "/modify.v1.Modify/ModifyVolumeProperties",
+			expErrCode: codes.OK,
+		},
+		{
+			name: "group snapshot request rejected if the snapshotter leads on another replica",
+			elector: func() *leaderElector {
+				return withSidecarLeases(newTestLeaderElector(fake.NewSimpleClientset()), map[string]string{snapshotterLease: "other-pod"})
+			},
+			method:     "/csi.v1.GroupController/CreateVolumeGroupSnapshot",
+			expErrCode: codes.Unavailable,
+		},
+		{
+			name: "mutating request served if the sidecar runs without leader election",
+			elector: func() *leaderElector {
+				return withSidecarLeases(newTestLeaderElector(fake.NewSimpleClientset()), nil)
+			},
+			method:     "/csi.v1.Controller/DeleteSnapshot",
+			expErrCode: codes.OK,
+		},
+		{
+			name:       "read-only request served if not leader",
+			elector:    func() *leaderElector { return newTestLeaderElector(fake.NewSimpleClientset()) },
+			method:     "/csi.v1.Controller/ListVolumes",
+			expErrCode: codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "served", nil
+			}
+			resp, err := tc.elector().unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			if tc.expErrCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expErrCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp != "served" {
+				t.Fatalf("Expected request to be served, got %v", resp)
+			}
+		})
+	}
+}
+
+func TestLeaderElectorHandoff(t *testing.T) {
+	l := newTestLeaderElector(fake.NewSimpleClientset())
+	l.setLeading(true)
+	withSidecarLeases(l, map[string]string{attacherLease: l.podName})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	served := make(chan error)
+	go func() {
+		_, err := l.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/ControllerUnpublishVolume"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		served <- err
+	}()
+	<-started
+
+	if l.handoff(50 * time.Millisecond) {
+		t.Fatalf("Expected handoff to time out with a request in flight")
+	}
+	if leaderHealth(t, l) != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Expected leader health service to be NOT_SERVING after handoff")
+	}
+	_, err := l.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Fatalf("Request served during handoff")
+		return nil, nil
+	})
+	checkExpectedErrorCode(t, err, codes.Unavailable)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if !l.handoff(5 * time.Second) {
+		t.Fatalf("Expected handoff to finish once the request in flight finished")
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("Unexpected error of request in flight: %v", err)
+	}
+}
+
+func TestLeaderElectorServeHTTP(t *testing.T) {
+	testCases := []struct {
+		name      string
+		method    string
+		leading   bool
+		expStatus int
+	}{
+		{
+			name:      "get as leader",
+			method:    http.MethodGet,
+			leading:   true,
+			expStatus: http.StatusOK,
+		},
+		{
+			name:      "get as non-leader",
+			method:    http.MethodGet,
+			expStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:      "method not allowed",
+			method:    http.MethodPut,
+			expStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newTestLeaderElector(fake.NewSimpleClientset())
+			l.setLeading(tc.leading)
+
+			w := httptest.NewRecorder()
+			l.ServeHTTP(w, httptest.NewRequest(tc.method, leaderPath, nil))
+			if w.Code != tc.expStatus {
+				t.Fatalf("Expected status %d, got %d", tc.expStatus, w.Code)
+			}
+			if tc.method != http.MethodGet {
+				return
+			}
+			var s leaderStatus
+			if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+				t.Fatalf("Could not decode response: %v", err)
+			}
+			if s.Leader != tc.leading || s.Identity != l.identity {
+				t.Fatalf("Unexpected response %+v", s)
+			}
+		})
+	}
+}
+
+func TestLeaderElectorElection(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	first := newTestLeaderElector(clientset)
+	second := newTestLeaderElector(clientset)
+
+	// running counts the tasks of each elector that run while it is the leader
+	var running [2]atomic.Int32
+	for i, l := range []*leaderElector{first, second} {
+		i := i
+		l.runWhileLeading(func(ctx context.Context) {
+			running[i].Add(1)
+			<-ctx.Done()
+			running[i].Add(-1)
+		})
+	}
+	isRunning := func(i int, expRunning int32) wait.ConditionWithContextFunc {
+		return func(context.Context) (bool, error) {
+			return running[i].Load() == expRunning, nil
+		}
+	}
+
+	isLeader := func(l *leaderElector) wait.ConditionWithContextFunc {
+		return func(context.Context) (bool, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			return l.leading, nil
+		}
+	}
+
+	first.start()
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, isLeader(first)); err != nil {
+		t.Fatalf("First elector did not become leader: %v", err)
+	}
+	if leaderHealth(t, first) != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected leader health service of the leader to be SERVING")
+	}
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, isRunning(0, 1)); err != nil {
+		t.Fatalf("Task of the leader is not running: %v", err)
+	}
+
+	second.start()
+	defer second.stop()
+	time.Sleep(5 * testLeaderElectionOptions.RetryPeriod)
+	if leading, _ := isLeader(second)(context.Background()); leading {
+		t.Fatalf("Second elector became leader while the first one is")
+	}
+	if running[1].Load() != 0 {
+		t.Fatalf("Task of the second elector runs while it is not the leader")
+	}
+
+	// The first elector releases the Lease, so the second one takes over
+	// before the lease duration expires
+	first.stop()
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, testLeaderElectionOptions.LeaseDuration, true, isLeader(second)); err != nil {
+		t.Fatalf("Second elector did not become leader after the first one stopped: %v", err)
+	}
+	if leading, _ := isLeader(first)(context.Background()); leading {
+		t.Fatalf("First elector still leader after it stopped")
+	}
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, isRunning(1, 1)); err != nil {
+		t.Fatalf("Task of the new leader is not running: %v", err)
+	}
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, isRunning(0, 0)); err != nil {
+		t.Fatalf("Task of the first elector still runs after it lost the leadership: %v", err)
+	}
+}
+
+func TestSidecarLeases(t *testing.T) {
+	for method := range mutatingMethods {
+		if _, ok := sidecarLeases[method]; !ok {
+			t.Errorf("No sidecar Lease for mutating method %s", method)
+		}
+	}
+	if attacherLease != "external-attacher-leader-ebs-csi-aws-com" {
+		t.Errorf("Unexpected Lease of the external-attacher %q", attacherLease)
+	}
+}
+
+func TestLeaderElectorWatchSidecarLeases(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	l := newTestLeaderElector(clientset)
+	holder := l.podName
+	_, err := clientset.CoordinationV1().Leases(testLeaderElectionOptions.Namespace).Create(context.Background(), &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: attacherLease, Namespace: testLeaderElectionOptions.Namespace},
+		Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	l.start()
+	defer l.stop()
+	served := func(context.Context) (bool, error) {
+		_, err := l.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err == nil, nil
+	}
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, served); err != nil {
+		t.Fatalf("Request of the sidecar holding its Lease not served: %v", err)
+	}
+}
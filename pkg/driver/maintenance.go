@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// maintenancePath is the path of the admin endpoint that reports and toggles maintenance mode.
+const maintenancePath = "/maintenance"
+
+// mutatingMethods are the controller RPCs rejected while in maintenance mode.
+// All other RPCs, including the read-only controller RPCs, are served as usual.
+var mutatingMethods = map[string]struct{}{
+	"/csi.v1.Controller/CreateVolume":                   {},
+	"/csi.v1.Controller/DeleteVolume":                   {},
+	"/csi.v1.Controller/ControllerPublishVolume":        {},
+	"/csi.v1.Controller/ControllerUnpublishVolume":      {},
+	"/csi.v1.Controller/CreateSnapshot":                 {},
+	"/csi.v1.Controller/DeleteSnapshot":                 {},
+	"/csi.v1.Controller/ControllerExpandVolume":         {},
+	"/csi.v1.Controller/ControllerModifyVolume":         {},
+	"/modify.v1.Modify/ModifyVolumeProperties":          {},
+	"/csi.v1.GroupController/CreateVolumeGroupSnapshot": {},
+	"/csi.v1.GroupController/DeleteVolumeGroupSnapshot": {},
+}
+
+// maintenanceMode pauses mutating controller RPCs, e.g. during planned
+// maintenance of the cluster or the AWS account. Rejected RPCs fail with
+// Unavailable so that the CO backs off and retries them later. Operations that
+// were already running when maintenance mode was enabled are not affected.
+type maintenanceMode struct {
+	enabled atomic.Bool
+}
+
+func newMaintenanceMode(enabled bool) *maintenanceMode {
+	m := &maintenanceMode{}
+	m.enabled.Store(enabled)
+	return m
+}
+
+func (m *maintenanceMode) set(enabled bool) {
+	if m.enabled.Swap(enabled) != enabled {
+		klog.InfoS("Maintenance mode changed", "enabled", enabled)
+	}
+}
+
+// unaryInterceptor rejects mutating controller RPCs while in maintenance mode.
+func (m *maintenanceMode) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if _, ok := mutatingMethods[info.FullMethod]; ok && m.enabled.Load() {
+		return nil, status.Errorf(codes.Unavailable, "Controller is in maintenance mode, not accepting %s requests", info.FullMethod)
+	}
+	return handler(ctx, req)
+}
+
+type maintenanceModeStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ServeHTTP reports maintenance mode on GET and sets it on PUT, according to
+// the boolean "enabled" query parameter.
+func (m *maintenanceMode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			http.Error(w, "invalid value of query parameter \"enabled\", expected true or false", http.StatusBadRequest)
+			return
+		}
+		m.set(enabled)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(maintenanceModeStatus{Enabled: m.enabled.Load()}); err != nil {
+		klog.ErrorS(err, "Could not write maintenance mode response")
+	}
+}
+
+// newAdminServer returns an HTTP server for the admin endpoint at address.
+func newAdminServer(address string, maintenance *maintenanceMode, leader *leaderElector) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(maintenancePath, maintenance)
+	if leader != nil {
+		mux.Handle(leaderPath, leader)
+	}
+	return &http.Server{
+		Addr:        address,
+		Handler:     mux,
+		ReadTimeout: 3 * time.Second,
+	}
+}
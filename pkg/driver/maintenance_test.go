@@ -0,0 +1,297 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestMaintenanceModeInterceptor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		method      string
+		maintenance bool
+		expCode     codes.Code
+	}{
+		{
+			name:        "mutating RPC is served outside maintenance mode",
+			method:      "/csi.v1.Controller/CreateVolume",
+			maintenance: false,
+			expCode:     codes.OK,
+		},
+		{
+			name:        "CreateVolume is rejected in maintenance mode",
+			method:      "/csi.v1.Controller/CreateVolume",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "ControllerPublishVolume is rejected in maintenance mode",
+			method:      "/csi.v1.Controller/ControllerPublishVolume",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "DeleteSnapshot is rejected in maintenance mode",
+			method:      "/csi.v1.Controller/DeleteSnapshot",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "ControllerModifyVolume is rejected in maintenance mode",
+			method:      "/csi.v1.Controller/ControllerModifyVolume",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "ModifyVolumeProperties is rejected in maintenance mode",
+			method:      "/modify.v1.Modify/ModifyVolumeProperties",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "CreateVolumeGroupSnapshot is rejected in maintenance mode",
+			method:      "/csi.v1.GroupController/CreateVolumeGroupSnapshot",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "DeleteVolumeGroupSnapshot is rejected in maintenance mode",
+			method:      "/csi.v1.GroupController/DeleteVolumeGroupSnapshot",
+			maintenance: true,
+			expCode:     codes.Unavailable,
+		},
+		{
+			name:        "GetVolumeGroupSnapshot is served in maintenance mode",
+			method:      "/csi.v1.GroupController/GetVolumeGroupSnapshot",
+			maintenance: true,
+			expCode:     codes.OK,
+		},
+		{
+			name:        "ListVolumes is served in maintenance mode",
+			method:      "/csi.v1.Controller/ListVolumes",
+			maintenance: true,
+			expCode:     codes.OK,
+		},
+		{
+			name:        "ValidateVolumeCapabilities is served in maintenance mode",
+			method:      "/csi.v1.Controller/ValidateVolumeCapabilities",
+			maintenance: true,
+			expCode:     codes.OK,
+		},
+		{
+			name:        "node RPC is served in maintenance mode",
+			method:      "/csi.v1.Node/NodeStageVolume",
+			maintenance: true,
+			expCode:     codes.OK,
+		},
+		{
+			name:        "Probe is served in maintenance mode",
+			method:      "/csi.v1.Identity/Probe",
+			maintenance: true,
+			expCode:     codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newMaintenanceMode(tc.maintenance)
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return "response", nil
+			}
+
+			_, err := m.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			if status.Code(err) != tc.expCode {
+				t.Fatalf("Expected code %v, got: %v", tc.expCode, err)
+			}
+			if called != (tc.expCode == codes.OK) {
+				t.Fatalf("Expected handler to be called: %v, was called: %v", tc.expCode == codes.OK, called)
+			}
+		})
+	}
+}
+
+func TestMaintenanceModeInFlightOperationCompletes(t *testing.T) {
+	m := newMaintenanceMode(false)
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/CreateVolume"}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		_, err := m.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return "response", nil
+		})
+		result <- err
+	}()
+
+	<-started
+	m.set(true)
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatalf("Expected in-flight operation to complete, got: %v", err)
+	}
+
+	_, err := m.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "response", nil
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected new operation to be rejected with %v, got: %v", codes.Unavailable, err)
+	}
+}
+
+func TestMaintenanceModeHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		initial    bool
+		method     string
+		query      string
+		expStatus  int
+		expEnabled bool
+	}{
+		{
+			name:       "get disabled",
+			method:     http.MethodGet,
+			expStatus:  http.StatusOK,
+			expEnabled: false,
+		},
+		{
+			name:       "get enabled",
+			initial:    true,
+			method:     http.MethodGet,
+			expStatus:  http.StatusOK,
+			expEnabled: true,
+		},
+		{
+			name:       "enable",
+			method:     http.MethodPut,
+			query:      "?enabled=true",
+			expStatus:  http.StatusOK,
+			expEnabled: true,
+		},
+		{
+			name:       "disable",
+			initial:    true,
+			method:     http.MethodPut,
+			query:      "?enabled=false",
+			expStatus:  http.StatusOK,
+			expEnabled: false,
+		},
+		{
+			name:       "invalid value",
+			initial:    true,
+			method:     http.MethodPut,
+			query:      "?enabled=maybe",
+			expStatus:  http.StatusBadRequest,
+			expEnabled: true,
+		},
+		{
+			name:       "unsupported method",
+			method:     http.MethodPost,
+			query:      "?enabled=true",
+			expStatus:  http.StatusMethodNotAllowed,
+			expEnabled: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newMaintenanceMode(tc.initial)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, maintenancePath+tc.query, nil))
+
+			if rec.Code != tc.expStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expStatus, rec.Code, rec.Body.String())
+			}
+			if tc.expStatus == http.StatusOK {
+				expBody := fmt.Sprintf("{\"enabled\":%v}", tc.expEnabled)
+				if strings.TrimSpace(rec.Body.String()) != expBody {
+					t.Fatalf("Expected body %s, got %s", expBody, rec.Body.String())
+				}
+			}
+			if m.enabled.Load() != tc.expEnabled {
+				t.Fatalf("Expected maintenance mode %v, got %v", tc.expEnabled, m.enabled.Load())
+			}
+		})
+	}
+}
+
+func TestMaintenanceModeOnCSISocket(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+	mockCloud := cloud.NewMockCloud(mockCtl)
+
+	socket := fmt.Sprintf("%s/csi.sock", t.TempDir())
+	d, err := NewFakeDriver("unix:"+socket, mockCloud, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	d.options.adminEndpoint = "127.0.0.1:0"
+	go func() {
+		if err := d.Run(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}()
+	err = wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(_ context.Context) (bool, error) {
+		return d.ready.Load(), nil
+	})
+	if err != nil {
+		t.Fatalf("Driver did not become ready: %v", err)
+	}
+	defer d.Stop()
+
+	conn, err := grpc.Dial("unix:"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+	client := csi.NewControllerClient(conn)
+
+	d.maintenance.set(true)
+	_, err = client.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "vol-test"})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected DeleteVolume to fail with %v, got: %v", codes.Unavailable, err)
+	}
+	if _, err = client.ControllerGetCapabilities(context.Background(), &csi.ControllerGetCapabilitiesRequest{}); err != nil {
+		t.Fatalf("Expected ControllerGetCapabilities to succeed, got: %v", err)
+	}
+
+	d.maintenance.set(false)
+	mockCloud.EXPECT().DeleteDisk(gomock.Any(), gomock.Eq("vol-test")).Return(true, nil)
+	if _, err = client.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "vol-test"}); err != nil {
+		t.Fatalf("Expected DeleteVolume to succeed, got: %v", err)
+	}
+}
@@ -51,11 +51,12 @@ func (mr *MockMounterMockRecorder) CanSafelySkipMountPointCheck() *gomock.Call {
 }
 
 // FormatAndMountSensitiveWithFormatOptions mocks base method.
-func (m *MockMounter) FormatAndMountSensitiveWithFormatOptions(source, target, fstype string, options, sensitiveOptions, formatOptions []string) error {
+func (m *MockMounter) FormatAndMountSensitiveWithFormatOptions(source, target, fstype string, options, sensitiveOptions, formatOptions []string) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "FormatAndMountSensitiveWithFormatOptions", source, target, fstype, options, sensitiveOptions, formatOptions)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // FormatAndMountSensitiveWithFormatOptions indicates an expected call of FormatAndMountSensitiveWithFormatOptions.
@@ -64,6 +65,21 @@ func (mr *MockMounterMockRecorder) FormatAndMountSensitiveWithFormatOptions(sour
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FormatAndMountSensitiveWithFormatOptions", reflect.TypeOf((*MockMounter)(nil).FormatAndMountSensitiveWithFormatOptions), source, target, fstype, options, sensitiveOptions, formatOptions)
 }
 
+// GetBlockSizeBytes mocks base method.
+func (m *MockMounter) GetBlockSizeBytes(devicePath string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockSizeBytes", devicePath)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockSizeBytes indicates an expected call of GetBlockSizeBytes.
+func (mr *MockMounterMockRecorder) GetBlockSizeBytes(devicePath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockSizeBytes", reflect.TypeOf((*MockMounter)(nil).GetBlockSizeBytes), devicePath)
+}
+
 // GetDeviceNameFromMount mocks base method.
 func (m *MockMounter) GetDeviceNameFromMount(mountPath string) (string, int, error) {
 	m.ctrl.T.Helper()
@@ -80,6 +96,36 @@ func (mr *MockMounterMockRecorder) GetDeviceNameFromMount(mountPath interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceNameFromMount", reflect.TypeOf((*MockMounter)(nil).GetDeviceNameFromMount), mountPath)
 }
 
+// GetDiskFormat mocks base method.
+func (m *MockMounter) GetDiskFormat(disk string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDiskFormat", disk)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDiskFormat indicates an expected call of GetDiskFormat.
+func (mr *MockMounterMockRecorder) GetDiskFormat(disk interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskFormat", reflect.TypeOf((*MockMounter)(nil).GetDiskFormat), disk)
+}
+
+// GetFilesystemUUID mocks base method.
+func (m *MockMounter) GetFilesystemUUID(devicePath string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemUUID", devicePath)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystemUUID indicates an expected call of GetFilesystemUUID.
+func (mr *MockMounterMockRecorder) GetFilesystemUUID(devicePath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemUUID", reflect.TypeOf((*MockMounter)(nil).GetFilesystemUUID), devicePath)
+}
+
 // GetMountRefs mocks base method.
 func (m *MockMounter) GetMountRefs(pathname string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -297,6 +343,20 @@ func (mr *MockMounterMockRecorder) Unmount(target interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmount", reflect.TypeOf((*MockMounter)(nil).Unmount), target)
 }
 
+// UnmountLazy mocks base method.
+func (m *MockMounter) UnmountLazy(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmountLazy", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnmountLazy indicates an expected call of UnmountLazy.
+func (mr *MockMounterMockRecorder) UnmountLazy(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmountLazy", reflect.TypeOf((*MockMounter)(nil).UnmountLazy), path)
+}
+
 // Unpublish mocks base method.
 func (m *MockMounter) Unpublish(path string) error {
 	m.ctrl.T.Helper()
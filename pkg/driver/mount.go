@@ -32,16 +32,23 @@ import (
 type Mounter interface {
 	mountutils.Interface
 
-	FormatAndMountSensitiveWithFormatOptions(source string, target string, fstype string, options []string, sensitiveOptions []string, formatOptions []string) error
+	// FormatAndMountSensitiveWithFormatOptions formats source unless it already
+	// has a filesystem, mounts it at target and reports whether formatting was
+	// skipped. Mounters that cannot tell report false.
+	FormatAndMountSensitiveWithFormatOptions(source string, target string, fstype string, options []string, sensitiveOptions []string, formatOptions []string) (bool, error)
 	IsCorruptedMnt(err error) bool
 	GetDeviceNameFromMount(mountPath string) (string, int, error)
+	GetDiskFormat(disk string) (string, error)
 	MakeFile(path string) error
 	MakeDir(path string) error
 	PathExists(path string) (bool, error)
 	NeedResize(devicePath string, deviceMountPath string) (bool, error)
 	Unpublish(path string) error
 	Unstage(path string) error
+	UnmountLazy(path string) error
 	NewResizeFs() (Resizefs, error)
+	GetBlockSizeBytes(devicePath string) (int64, error)
+	GetFilesystemUUID(devicePath string) (string, error)
 }
 
 type Resizefs interface {
@@ -63,6 +70,16 @@ func newNodeMounter() (Mounter, error) {
 	return &NodeMounter{safeMounter}, nil
 }
 
+// newNodeMounterInNamespace returns a NodeMounter that performs mounts in the
+// mount namespace referenced by namespacePath.
+func newNodeMounterInNamespace(namespacePath string) (Mounter, error) {
+	safeMounter, err := mounter.NewSafeMounterInNamespace(namespacePath)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeMounter{safeMounter}, nil
+}
+
 // DeviceIdentifier is for mocking os io functions used for the driver to
 // identify an EBS volume's corresponding device (in Linux, the path under
 // /dev; in Windows, the volume number) so that it can mount it. For volumes
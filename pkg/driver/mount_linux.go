@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	mountutils "k8s.io/mount-utils"
+	utilexec "k8s.io/utils/exec"
 )
 
 // GetDeviceNameFromMount returns the volume ID for a mount path.
@@ -33,6 +34,31 @@ func (m NodeMounter) GetDeviceNameFromMount(mountPath string) (string, int, erro
 	return mountutils.GetDeviceNameFromMount(m, mountPath)
 }
 
+// FormatAndMountSensitiveWithFormatOptions formats source unless it already
+// has a filesystem, mounts it at target and reports whether formatting was
+// skipped, as decided by the disk format probe of SafeFormatAndMount itself.
+func (m *NodeMounter) FormatAndMountSensitiveWithFormatOptions(source string, target string, fstype string, options []string, sensitiveOptions []string, formatOptions []string) (bool, error) {
+	recorder := &formatRecorder{Interface: m.Exec}
+	safeMounter := *m.SafeFormatAndMount
+	safeMounter.Exec = recorder
+	err := safeMounter.FormatAndMountSensitiveWithFormatOptions(source, target, fstype, options, sensitiveOptions, formatOptions)
+	return !recorder.formatted, err
+}
+
+// formatRecorder records whether a filesystem was created with the commands
+// run through it.
+type formatRecorder struct {
+	utilexec.Interface
+	formatted bool
+}
+
+func (r *formatRecorder) Command(cmd string, args ...string) utilexec.Cmd {
+	if strings.HasPrefix(cmd, "mkfs") {
+		r.formatted = true
+	}
+	return r.Interface.Command(cmd, args...)
+}
+
 // IsCorruptedMnt return true if err is about corrupted mount point
 func (m NodeMounter) IsCorruptedMnt(err error) bool {
 	return mountutils.IsCorruptedMnt(err)
@@ -155,6 +181,46 @@ func (m *NodeMounter) Unstage(path string) error {
 	}
 }
 
+// UnmountLazy detaches the mount at path from the file system hierarchy right
+// away and cleans it up once it is no longer busy, which is the only way to
+// unmount a file system whose device is gone while files on it are still open.
+func (m *NodeMounter) UnmountLazy(path string) error {
+	output, err := m.Exec.Command("umount", "-l", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to lazily unmount %s: %w: %s", path, err, string(output))
+	}
+	return nil
+}
+
 func (m *NodeMounter) NewResizeFs() (Resizefs, error) {
 	return mountutils.NewResizeFs(m.Exec), nil
 }
+
+// GetFilesystemUUID returns the UUID of the filesystem on the device, or an
+// empty string if the device has no filesystem
+func (m *NodeMounter) GetFilesystemUUID(devicePath string) (string, error) {
+	output, err := m.Exec.Command("blkid", "-s", "UUID", "-o", "value", devicePath).Output()
+	if err != nil {
+		// blkid exits with 2 if the device has no filesystem
+		if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.ExitStatus() == 2 {
+			return "", nil
+		}
+		return "", fmt.Errorf("error when getting filesystem UUID of %s: output: %s, err: %w", devicePath, string(output), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetBlockSizeBytes returns the size of the block device in bytes
+func (m *NodeMounter) GetBlockSizeBytes(devicePath string) (int64, error) {
+	cmd := m.Exec.Command("blockdev", "--getsize64", devicePath)
+	output, err := cmd.Output()
+	if err != nil {
+		return -1, fmt.Errorf("error when getting size of block volume at path %s: output: %s, err: %w", devicePath, string(output), err)
+	}
+	strOut := strings.TrimSpace(string(output))
+	gotSizeBytes, err := strconv.ParseInt(strOut, 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("failed to parse size %s as int", strOut)
+	}
+	return gotSizeBytes, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+)
+
+// conflictingMountOptions are groups of mount options of which a volume can
+// only be mounted with one, as they set the same behavior differently.
+var conflictingMountOptions = [][]string{
+	{"ro", "rw"},
+	{"discard", "nodiscard"},
+	{"atime", "noatime", "relatime", "strictatime"},
+	{"diratime", "nodiratime"},
+	{"sync", "async"},
+	{"exec", "noexec"},
+	{"suid", "nosuid"},
+	{"dev", "nodev"},
+}
+
+func newMountOptionSet(optionLists ...[]string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, options := range optionLists {
+		for _, option := range options {
+			set[option] = struct{}{}
+		}
+	}
+	return set
+}
+
+// mountOptionName returns the name of a mount option without its value,
+// e.g. commit for commit=30.
+func mountOptionName(option string) string {
+	name, _, _ := strings.Cut(option, "=")
+	return name
+}
+
+// mountOptionsConflict returns true if a volume cannot be mounted with both
+// mount options a and b, e.g. discard and nodiscard or commit=5 and commit=30.
+func mountOptionsConflict(a, b string) bool {
+	nameA, nameB := mountOptionName(a), mountOptionName(b)
+	if nameA == nameB {
+		return a != b
+	}
+	for _, group := range conflictingMountOptions {
+		if slices.Contains(group, nameA) && slices.Contains(group, nameB) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMountOptions checks that options do not conflict with each other
+// and that the filesystem fsType supports them. Options of any filesystem are
+// valid if fsType is empty.
+func validateMountOptions(fsType string, options []string) error {
+	fsConfig := FileSystemConfigs[strings.ToLower(fsType)]
+	for i, option := range options {
+		if mountOptionName(option) == "" {
+			return fmt.Errorf("Mount option cannot be empty")
+		}
+		if !fsConfig.isMountOptionSupported(mountOptionName(option)) {
+			return fmt.Errorf("Mount option %s is not supported by fstype %s", option, fsType)
+		}
+		for _, other := range options[:i] {
+			if mountOptionsConflict(other, option) {
+				return fmt.Errorf("Mount options %s and %s conflict", other, option)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVolumeCapabilityMountOptions checks the mount options of the mount
+// volume capabilities against their fstype, so that volumes that could not
+// be mounted are refused before they are created.
+func validateVolumeCapabilityMountOptions(volCaps []*csi.VolumeCapability) error {
+	for _, volCap := range volCaps {
+		mountVolume := volCap.GetMount()
+		if mountVolume == nil {
+			continue
+		}
+		fsType := mountVolume.GetFsType()
+		if fsType == "" {
+			fsType = defaultFsType
+		}
+		if err := validateMountOptions(fsType, mountVolume.GetMountFlags()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeMountOptions returns options followed by the options of defaults that
+// neither are in options nor conflict with one of them, so that the options
+// a volume is mounted with take precedence over the driver's defaults.
+func mergeMountOptions(options, defaults []string) []string {
+	merged := slices.Clone(options)
+	for _, option := range defaults {
+		if !slices.ContainsFunc(merged, func(other string) bool { return other == option || mountOptionsConflict(other, option) }) {
+			merged = append(merged, option)
+		}
+	}
+	return merged
+}
+
+// splitMountOptions returns the mount options of a comma separated list.
+func splitMountOptions(options string) []string {
+	var split []string
+	for _, option := range strings.Split(options, ",") {
+		if option != "" {
+			split = append(split, option)
+		}
+	}
+	return split
+}
+
+// defaultMountOptions returns the driver's default mount options of a volume
+// of volumeType with the volume capabilities volCaps: those of
+// --default-mount-options that the fstype of the volume supports, followed by
+// discard if volumeType is one of --discard-volume-types. Block volumes have
+// no mount options.
+func (d *controllerService) defaultMountOptions(volumeType string, volCaps []*csi.VolumeCapability) []string {
+	fsType := ""
+	for _, volCap := range volCaps {
+		if volCap.GetMount() == nil {
+			return nil
+		}
+		fsType = volCap.GetMount().GetFsType()
+	}
+	if fsType == "" {
+		fsType = defaultFsType
+	}
+	fsConfig := FileSystemConfigs[strings.ToLower(fsType)]
+
+	var options []string
+	for _, option := range d.driverOptions.defaultMountOptions {
+		if fsConfig.isMountOptionSupported(mountOptionName(option)) {
+			options = append(options, option)
+		}
+	}
+	// EC2 creates gp3 volumes if no volume type is given
+	if volumeType == "" {
+		volumeType = cloud.VolumeTypeGP3
+	}
+	if slices.Contains(d.driverOptions.discardVolumeTypes, volumeType) {
+		options = mergeMountOptions(options, []string{"discard"})
+	}
+	return options
+}
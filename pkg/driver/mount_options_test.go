@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMountOptionsConflict(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{a: "discard", b: "nodiscard", expected: true},
+		{a: "noatime", b: "relatime", expected: true},
+		{a: "ro", b: "rw", expected: true},
+		{a: "commit=5", b: "commit=30", expected: true},
+		{a: "commit=5", b: "commit=5", expected: false},
+		{a: "discard", b: "discard", expected: false},
+		{a: "noatime", b: "nodiratime", expected: false},
+		{a: "discard", b: "lazytime", expected: false},
+	}
+
+	for _, tc := range testCases {
+		if conflict := mountOptionsConflict(tc.a, tc.b); conflict != tc.expected {
+			t.Errorf("mountOptionsConflict(%s, %s): expected %v, got %v", tc.a, tc.b, tc.expected, conflict)
+		}
+	}
+}
+
+func TestValidateMountOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fsType  string
+		options []string
+		expErr  error
+	}{
+		{
+			name:    "valid: ext4 options",
+			fsType:  FSTypeExt4,
+			options: []string{"noatime", "discard", "data=ordered", "commit=30"},
+		},
+		{
+			name:    "valid: xfs options",
+			fsType:  FSTypeXfs,
+			options: []string{"noatime", "inode64", "logbsize=256k"},
+		},
+		{
+			name:    "valid: options of any filesystem without fstype",
+			options: []string{"data=ordered", "inode64"},
+		},
+		{
+			name:    "valid: ntfs options",
+			fsType:  FSTypeNtfs,
+			options: []string{"noatime"},
+		},
+		{
+			name:    "invalid: empty option",
+			fsType:  FSTypeExt4,
+			options: []string{"noatime", ""},
+			expErr:  errors.New("Mount option cannot be empty"),
+		},
+		{
+			name:    "invalid: conflicting options",
+			fsType:  FSTypeExt4,
+			options: []string{"discard", "noatime", "nodiscard"},
+			expErr:  errors.New("Mount options discard and nodiscard conflict"),
+		},
+		{
+			name:    "invalid: conflicting values",
+			fsType:  FSTypeExt4,
+			options: []string{"commit=5", "commit=30"},
+			expErr:  errors.New("Mount options commit=5 and commit=30 conflict"),
+		},
+		{
+			name:    "invalid: journal option on ext2",
+			fsType:  FSTypeExt2,
+			options: []string{"data=journal"},
+			expErr:  errors.New("Mount option data=journal is not supported by fstype ext2"),
+		},
+		{
+			name:    "invalid: xfs option on ext4",
+			fsType:  FSTypeExt4,
+			options: []string{"inode64"},
+			expErr:  errors.New("Mount option inode64 is not supported by fstype ext4"),
+		},
+		{
+			name:    "invalid: ext4 option on xfs",
+			fsType:  FSTypeXfs,
+			options: []string{"data=ordered"},
+			expErr:  errors.New("Mount option data=ordered is not supported by fstype xfs"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMountOptions(tc.fsType, tc.options)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestMergeMountOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  []string
+		defaults []string
+		expected []string
+	}{
+		{
+			name:     "no defaults",
+			options:  []string{"noatime"},
+			expected: []string{"noatime"},
+		},
+		{
+			name:     "no options",
+			defaults: []string{"noatime", "discard"},
+			expected: []string{"noatime", "discard"},
+		},
+		{
+			name:     "defaults appended in order",
+			options:  []string{"nodev"},
+			defaults: []string{"noatime", "discard"},
+			expected: []string{"nodev", "noatime", "discard"},
+		},
+		{
+			name:     "duplicate defaults dropped",
+			options:  []string{"discard", "noatime"},
+			defaults: []string{"noatime", "lazytime"},
+			expected: []string{"discard", "noatime", "lazytime"},
+		},
+		{
+			name:     "conflicting defaults dropped",
+			options:  []string{"relatime", "nodiscard", "commit=5"},
+			defaults: []string{"noatime", "discard", "commit=30", "lazytime"},
+			expected: []string{"relatime", "nodiscard", "commit=5", "lazytime"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeMountOptions(tc.options, tc.defaults)
+			if !reflect.DeepEqual(merged, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, merged)
+			}
+		})
+	}
+}
+
+func TestSplitMountOptions(t *testing.T) {
+	testCases := []struct {
+		options  string
+		expected []string
+	}{
+		{options: "", expected: nil},
+		{options: "discard", expected: []string{"discard"}},
+		{options: "noatime,,discard,", expected: []string{"noatime", "discard"}},
+	}
+
+	for _, tc := range testCases {
+		if split := splitMountOptions(tc.options); !reflect.DeepEqual(split, tc.expected) {
+			t.Errorf("splitMountOptions(%q): expected %v, got %v", tc.options, tc.expected, split)
+		}
+	}
+}
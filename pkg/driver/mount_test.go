@@ -314,6 +314,75 @@ func TestNeedResize(t *testing.T) {
 	}
 }
 
+func TestFormatAndMountSensitiveWithFormatOptions(t *testing.T) {
+	testcases := []struct {
+		name                string
+		mountOptions        []string
+		cmdOutputs          []fakeexec.FakeAction
+		expectError         bool
+		expectFormatSkipped bool
+	}{
+		{
+			name: "unformatted device is formatted",
+			cmdOutputs: []fakeexec.FakeAction{
+				func() ([]byte, []byte, error) { return nil, nil, &fakeexec.FakeExitError{Status: 2} },
+				func() ([]byte, []byte, error) { return nil, nil, nil },
+			},
+			expectFormatSkipped: false,
+		},
+		{
+			name: "already formatted device is not formatted",
+			cmdOutputs: []fakeexec.FakeAction{
+				func() ([]byte, []byte, error) { return []byte("DEVNAME=/dev/test1\nTYPE=ext4\n"), nil, nil },
+				func() ([]byte, []byte, error) { return nil, nil, nil },
+			},
+			expectFormatSkipped: true,
+		},
+		{
+			name:         "unformatted read-only device is not formatted",
+			mountOptions: []string{"ro"},
+			cmdOutputs: []fakeexec.FakeAction{
+				func() ([]byte, []byte, error) { return nil, nil, &fakeexec.FakeExitError{Status: 2} },
+			},
+			expectError:         true,
+			expectFormatSkipped: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			fcmd := fakeexec.FakeCmd{
+				CombinedOutputScript: test.cmdOutputs,
+			}
+			fexec := fakeexec.FakeExec{}
+			for range test.cmdOutputs {
+				fexec.CommandScript = append(fexec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+					return fakeexec.InitFakeCmd(&fcmd, cmd, args...)
+				})
+			}
+			safe := mount.SafeFormatAndMount{
+				Interface: mount.NewFakeMounter(nil),
+				Exec:      &fexec,
+			}
+			fakeMounter := NodeMounter{&safe}
+
+			formatSkipped, err := fakeMounter.FormatAndMountSensitiveWithFormatOptions("/dev/test1", "/mnt/test1", FSTypeExt4, test.mountOptions, nil, nil)
+			if test.expectError && err == nil {
+				t.Fatalf("Expect error but got nil")
+			}
+			if !test.expectError && err != nil {
+				t.Fatalf("Expect no error but got %v", err)
+			}
+			if formatSkipped != test.expectFormatSkipped {
+				t.Fatalf("Expect formatSkipped %v but got %v", test.expectFormatSkipped, formatSkipped)
+			}
+			if fexec.CommandCalls != len(test.cmdOutputs) {
+				t.Fatalf("Expect %d commands but got %d", len(test.cmdOutputs), fexec.CommandCalls)
+			}
+		})
+	}
+}
+
 func TestMakeDir(t *testing.T) {
 	// Setup the full driver and its environment
 	dir, err := os.MkdirTemp("", "mount-ebs-csi")
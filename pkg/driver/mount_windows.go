@@ -27,12 +27,14 @@ import (
 	"regexp"
 )
 
-func (m NodeMounter) FormatAndMountSensitiveWithFormatOptions(source string, target string, fstype string, options []string, sensitiveOptions []string, formatOptions []string) error {
+// FormatAndMountSensitiveWithFormatOptions never reports formatting as
+// skipped because csi-proxy doesn't expose whether it formatted the disk.
+func (m NodeMounter) FormatAndMountSensitiveWithFormatOptions(source string, target string, fstype string, options []string, sensitiveOptions []string, formatOptions []string) (bool, error) {
 	proxyMounter, ok := m.SafeFormatAndMount.Interface.(*mounter.CSIProxyMounter)
 	if !ok {
-		return fmt.Errorf("failed to cast mounter to csi proxy mounter")
+		return false, fmt.Errorf("failed to cast mounter to csi proxy mounter")
 	}
-	return proxyMounter.FormatAndMountSensitiveWithFormatOptions(source, target, fstype, options, sensitiveOptions, formatOptions)
+	return false, proxyMounter.FormatAndMountSensitiveWithFormatOptions(source, target, fstype, options, sensitiveOptions, formatOptions)
 }
 
 // GetDeviceNameFromMount returns the volume ID for a mount path.
@@ -70,6 +72,12 @@ func (m NodeMounter) GetDeviceNameFromMount(mountPath string) (string, int, erro
 	return deviceName, 1, nil
 }
 
+// GetDiskFormat always returns an error because csi-proxy doesn't expose the
+// filesystem of a disk; format detection happens inside FormatAndMount.
+func (m NodeMounter) GetDiskFormat(disk string) (string, error) {
+	return "", fmt.Errorf("GetDiskFormat is not supported on Windows")
+}
+
 // IsCorruptedMnt return true if err is about corrupted mount point
 func (m NodeMounter) IsCorruptedMnt(err error) bool {
 	return mountutils.IsCorruptedMnt(err)
@@ -151,6 +159,11 @@ func (m *NodeMounter) Unstage(target string) error {
 	return nil
 }
 
+// UnmountLazy always returns an error because Windows has no lazy unmount.
+func (m *NodeMounter) UnmountLazy(path string) error {
+	return fmt.Errorf("UnmountLazy is not supported on Windows")
+}
+
 func (m *NodeMounter) NewResizeFs() (Resizefs, error) {
 	proxyMounter, ok := m.SafeFormatAndMount.Interface.(*mounter.CSIProxyMounter)
 	if !ok {
@@ -158,3 +171,24 @@ func (m *NodeMounter) NewResizeFs() (Resizefs, error) {
 	}
 	return resizefs.NewResizeFs(proxyMounter), nil
 }
+
+// GetFilesystemUUID always returns an error because devices are not resolved by
+// filesystem UUID on Windows.
+func (m *NodeMounter) GetFilesystemUUID(devicePath string) (string, error) {
+	return "", fmt.Errorf("GetFilesystemUUID is not supported on Windows")
+}
+
+// GetBlockSizeBytes gets the size of the disk in bytes
+func (m *NodeMounter) GetBlockSizeBytes(devicePath string) (int64, error) {
+	proxyMounter, ok := m.SafeFormatAndMount.Interface.(*mounter.CSIProxyMounter)
+	if !ok {
+		return -1, fmt.Errorf("failed to cast mounter to csi proxy mounter")
+	}
+
+	sizeInBytes, err := proxyMounter.GetDeviceSize(devicePath)
+	if err != nil {
+		return -1, err
+	}
+
+	return sizeInBytes, nil
+}
@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
@@ -48,6 +50,11 @@ const (
 
 	// sbeDeviceVolumeAttachmentLimit refers to the maximum number of volumes that can be attached to an instance on snow.
 	sbeDeviceVolumeAttachmentLimit = 10
+
+	// Reasons logged by NodeStageVolume when a volume is not formatted
+	formatSkippedBlockMode        = "BlockMode"
+	formatSkippedAlreadyFormatted = "AlreadyFormatted"
+	formatSkippedReadOnly         = "ReadOnly"
 )
 
 var (
@@ -66,6 +73,7 @@ var (
 		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
 		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
 		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
 	}
 
 	// taintRemovalBackoff is the exponential backoff configuration for node taint removal
@@ -74,26 +82,65 @@ var (
 		Factor:   2,
 		Steps:    10, // Max delay = 0.5 * 2^9 = ~4 minutes
 	}
+
+	// deviceSizeCheckInterval is the interval between checks of an attached device's size
+	deviceSizeCheckInterval = 1 * time.Second
+
+	// devicePathPollInterval is the interval between lookups of the device of an attached volume
+	devicePathPollInterval = 1 * time.Second
 )
 
 // nodeService represents the node service of CSI driver
 type nodeService struct {
+	csi.UnimplementedNodeServer
 	metadata         cloud.MetadataService
 	mounter          Mounter
 	deviceIdentifier DeviceIdentifier
 	inFlight         *internal.InFlight
 	driverOptions    *DriverOptions
+	// namespaceMounter stages and publishes volumes flagged for mount namespace
+	// isolation. It is nil unless a mount namespace is configured.
+	namespaceMounter Mounter
+	// unstagedVolumes records the volumes unstaged from the node, whose device
+	// is looked up again after a reattach rather than a first attach.
+	unstagedVolumes *unstagedVolumes
+	// ioStats computes the I/O utilization of volumes reported by
+	// NodeGetVolumeStats. It is nil unless reporting it is enabled.
+	ioStats *ioStatsTracker
+	// filesystemUUIDs records the filesystem UUIDs of volumes by volume ID, to
+	// find their device by UUID when it is not found by device path or volume
+	// ID. It is nil unless resolving devices by UUID is enabled.
+	filesystemUUIDs *filesystemUUIDs
+	// newMetadata retrieves the instance metadata if it was still transiently
+	// unavailable when the node service was created, see getMetadata.
+	newMetadata func() (cloud.MetadataService, error)
+	// metadataMutex guards metadata while it is retrieved by getMetadata. It
+	// is nil if metadata was retrieved when the node service was created.
+	metadataMutex *sync.Mutex
 }
 
 // newNodeService creates a new node service
 // it panics if failed to create the service
+// Instance metadata that is still transiently unavailable after the retries is
+// retrieved again by the requests that need it.
 func newNodeService(driverOptions *DriverOptions) nodeService {
 	klog.V(5).InfoS("[Debug] Retrieving node info from metadata service")
 	region := os.Getenv("AWS_REGION")
 	klog.InfoS("regionFromSession Node service", "region", region)
-	metadata, err := cloud.NewMetadataService(cloud.DefaultEC2MetadataClient, cloud.DefaultKubernetesAPIClient, region)
+	newMetadata := func() (cloud.MetadataService, error) {
+		if driverOptions.cloudProvider == cloud.CloudProviderFake {
+			return driverOptions.fakeCloudOptions.Metadata(), nil
+		}
+		return cloud.NewMetadataService(cloud.NewEC2MetadataClient(driverOptions.imdsVersion, driverOptions.endpointOptions), cloud.DefaultKubernetesAPIClient, region, driverOptions.metadataSources)
+	}
+	metadata, err := retrieveMetadata(context.Background(), newMetadata, driverOptions.metadataRetryAttempts)
+	var metadataMutex *sync.Mutex
 	if err != nil {
-		panic(err)
+		if !isTransientError(err) {
+			panic(err)
+		}
+		klog.ErrorS(err, "Instance metadata is unavailable, retrieving it again on requests that need it")
+		metadataMutex = &sync.Mutex{}
 	}
 
 	nodeMounter, err := newNodeMounter()
@@ -101,6 +148,14 @@ func newNodeService(driverOptions *DriverOptions) nodeService {
 		panic(err)
 	}
 
+	var namespaceMounter Mounter
+	if driverOptions.mountNamespace != "" {
+		namespaceMounter, err = newNodeMounterInNamespace(driverOptions.mountNamespace)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	// Remove taint from node to indicate driver startup success
 	// This is done at the last possible moment to prevent race conditions or false positive removals
 	go removeTaintInBackground(cloud.DefaultKubernetesAPIClient)
@@ -111,11 +166,37 @@ func newNodeService(driverOptions *DriverOptions) nodeService {
 		deviceIdentifier: newNodeDeviceIdentifier(),
 		inFlight:         internal.NewInFlight(),
 		driverOptions:    driverOptions,
+		namespaceMounter: namespaceMounter,
+		unstagedVolumes:  newUnstagedVolumes(),
+		ioStats:          newIOStatsTracker(driverOptions.reportIOUtilization),
+		filesystemUUIDs:  newFilesystemUUIDs(driverOptions.resolveDevicesByUUID, filesystemUUIDsPath(driverOptions.endpoint)),
+		newMetadata:      newMetadata,
+		metadataMutex:    metadataMutex,
 	}
 }
 
+// getMetadata returns the instance metadata. If it was unavailable when the
+// node service was created, it is retrieved first, retrying transient failures
+// up to metadataRetryAttempts times, and kept once retrieved.
+func (d *nodeService) getMetadata(ctx context.Context) (cloud.MetadataService, error) {
+	if d.metadataMutex == nil {
+		return d.metadata, nil
+	}
+	d.metadataMutex.Lock()
+	defer d.metadataMutex.Unlock()
+	if d.metadata == nil {
+		metadata, err := retrieveMetadata(ctx, d.newMetadata, d.driverOptions.metadataRetryAttempts)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "Could not retrieve instance metadata: %v", err)
+		}
+		d.metadata = metadata
+	}
+	return d.metadata, nil
+}
+
 func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	klog.V(4).InfoS("NodeStageVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeStageVolume: called", "args", req)
 
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
@@ -143,6 +224,7 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	// If the access type is block, do nothing for stage
 	switch volCap.GetAccessType().(type) {
 	case *csi.VolumeCapability_Block:
+		logger.Info("NodeStageVolume: formatting skipped", "volumeID", volumeID, "reason", formatSkippedBlockMode)
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
@@ -188,13 +270,16 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, err
 	}
 
-	mountOptions := collectMountOptions(fsType, mountVolume.MountFlags)
+	// The controller's default mount options apply unless the volume is
+	// mounted with the same or conflicting options
+	defaultMountOptions := splitMountOptions(volumeContext[VolumeAttributeDefaultMountOptions])
+	mountOptions := collectMountOptions(fsType, mergeMountOptions(mountVolume.MountFlags, defaultMountOptions))
 
 	if ok = d.inFlight.Insert(volumeID); !ok {
 		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
 	}
 	defer func() {
-		klog.V(4).InfoS("NodeStageVolume: volume operation finished", "volumeID", volumeID)
+		logger.V(4).Info("NodeStageVolume: volume operation finished", "volumeID", volumeID)
 		d.inFlight.Delete(volumeID)
 	}()
 
@@ -208,17 +293,27 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		if part != "0" {
 			partition = part
 		} else {
-			klog.InfoS("NodeStageVolume: invalid partition config, will ignore.", "partition", part)
+			logger.Info("NodeStageVolume: invalid partition config, will ignore.", "partition", part)
 		}
 	}
 
-	source, err := d.findDevicePath(devicePath, volumeID, partition)
+	mounter, err := d.mounterForVolume(volumeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	if uuid := volumeContext[VolumeAttributeFilesystemUUID]; uuid != "" && d.filesystemUUIDs != nil {
+		d.filesystemUUIDs.store(volumeID, uuid)
+	}
+
+	source, err := d.waitForDevicePath(ctx, devicePath, volumeID, partition)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to find device path %s. %v", devicePath, err)
 	}
+	d.unstagedVolumes.remove(volumeID)
 
-	klog.V(4).InfoS("NodeStageVolume: find device path", "devicePath", devicePath, "source", source)
-	exists, err := d.mounter.PathExists(target)
+	logger.V(4).Info("NodeStageVolume: find device path", "devicePath", devicePath, "source", source)
+	exists, err := mounter.PathExists(target)
 	if err != nil {
 		msg := fmt.Sprintf("failed to check if target %q exists: %v", target, err)
 		return nil, status.Error(codes.Internal, msg)
@@ -228,15 +323,15 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	// Otherwise we need to create the target directory.
 	if !exists {
 		// If target path does not exist we need to create the directory where volume will be staged
-		klog.V(4).InfoS("NodeStageVolume: creating target dir", "target", target)
-		if err = d.mounter.MakeDir(target); err != nil {
+		logger.V(4).Info("NodeStageVolume: creating target dir", "target", target)
+		if err = mounter.MakeDir(target); err != nil {
 			msg := fmt.Sprintf("could not create target dir %q: %v", target, err)
 			return nil, status.Error(codes.Internal, msg)
 		}
 	}
 
 	// Check if a device is mounted in target directory
-	device, _, err := d.mounter.GetDeviceNameFromMount(target)
+	device, _, err := mounter.GetDeviceNameFromMount(target)
 	if err != nil {
 		msg := fmt.Sprintf("failed to check if volume is already mounted: %v", err)
 		return nil, status.Error(codes.Internal, msg)
@@ -245,14 +340,20 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	// This operation (NodeStageVolume) MUST be idempotent.
 	// If the volume corresponding to the volume_id is already staged to the staging_target_path,
 	// and is identical to the specified volume_capability the Plugin MUST reply 0 OK.
-	klog.V(4).InfoS("NodeStageVolume: checking if volume is already staged", "device", device, "source", source, "target", target)
+	logger.V(4).Info("NodeStageVolume: checking if volume is already staged", "device", device, "source", source, "target", target)
 	if device == source {
-		klog.V(4).InfoS("NodeStageVolume: volume already staged", "volumeID", volumeID)
+		logger.V(4).Info("NodeStageVolume: volume already staged", "volumeID", volumeID)
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
+	if partition == "" {
+		if err = d.waitForDeviceSize(ctx, mounter, source, volumeContext); err != nil {
+			return nil, err
+		}
+	}
+
 	// FormatAndMount will format only if needed
-	klog.V(4).InfoS("NodeStageVolume: staging volume", "source", source, "volumeID", volumeID, "target", target, "fstype", fsType)
+	logger.V(4).Info("NodeStageVolume: staging volume", "source", source, "volumeID", volumeID, "target", target, "fstype", fsType)
 	formatOptions := []string{}
 	if len(blockSize) > 0 {
 		if fsType == FSTypeXfs {
@@ -279,33 +380,193 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	if len(ext4ClusterSize) > 0 {
 		formatOptions = append(formatOptions, "-C", ext4ClusterSize)
 	}
-	err = d.mounter.FormatAndMountSensitiveWithFormatOptions(source, target, fsType, mountOptions, nil, formatOptions)
+	formatSkipped, err := mounter.FormatAndMountSensitiveWithFormatOptions(source, target, fsType, mountOptions, nil, formatOptions)
 	if err != nil {
 		msg := fmt.Sprintf("could not format %q and mount it at %q: %v", source, target, err)
 		return nil, status.Error(codes.Internal, msg)
 	}
-
-	needResize, err := d.mounter.NeedResize(source, target)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Could not determine if volume %q (%q) need to be resized:  %v", req.GetVolumeId(), source, err)
+	if formatSkipped {
+		// Only probe the filesystem to explain why formatting was skipped
+		existingFormat, err := mounter.GetDiskFormat(source)
+		if err != nil {
+			logger.V(4).Info("NodeStageVolume: could not detect existing filesystem", "source", source, "err", err)
+		}
+		logger.Info("NodeStageVolume: formatting skipped", "volumeID", volumeID, "source", source, "reason", formatSkipReason(existingFormat, mountOptions), "detectedFsType", existingFormat, "requestedFsType", fsType)
 	}
+	d.recordFilesystemUUID(mounter, volumeID, source)
 
-	if needResize {
-		r, err := d.mounter.NewResizeFs()
+	// The volume may have been expanded while detached from the node, in which
+	// case NodeExpandVolume is not necessarily called
+	if d.driverOptions.skipFilesystemResizeOnStage {
+		logger.V(4).Info("NodeStageVolume: skipping filesystem resize check", "volumeID", volumeID, "source", source)
+	} else {
+		needResize, err := mounter.NeedResize(source, target)
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "Error attempting to create new ResizeFs:  %v", err)
+			return nil, status.Errorf(codes.Internal, "Could not determine if volume %q (%q) need to be resized:  %v", req.GetVolumeId(), source, err)
 		}
-		klog.V(2).InfoS("Volume needs resizing", "source", source)
-		if _, err := r.Resize(source, target); err != nil {
-			return nil, status.Errorf(codes.Internal, "Could not resize volume %q (%q):  %v", volumeID, source, err)
+
+		if needResize {
+			r, err := mounter.NewResizeFs()
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "Error attempting to create new ResizeFs:  %v", err)
+			}
+			logger.V(2).Info("Volume needs resizing", "source", source)
+			if _, err := r.Resize(source, target); err != nil {
+				return nil, status.Errorf(codes.Internal, "Could not resize volume %q (%q):  %v", volumeID, source, err)
+			}
 		}
 	}
-	klog.V(4).InfoS("NodeStageVolume: successfully staged volume", "source", source, "volumeID", volumeID, "target", target, "fstype", fsType)
+	logger.V(4).Info("NodeStageVolume: successfully staged volume", "source", source, "volumeID", volumeID, "target", target, "fstype", fsType)
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
+// waitForDevicePath looks up the device of an attached volume until it appears
+// or the device attach timeout expires. A volume that was unstaged from the
+// node before is being reattached, and its device may take a different time to
+// reappear than a fresh device to appear, so it waits for the reattach timeout
+// instead. The device is looked up only once if the timeout is 0.
+//
+// If the SCSI fallback wait is set and the device is still not found once it
+// elapses, the legacy SCSI device paths of the volume are looked up as well.
+func (d *nodeService) waitForDevicePath(ctx context.Context, devicePath, volumeID, partition string) (string, error) {
+	logger := klog.FromContext(ctx)
+	// findDevicePath looks up the devices of some regions differently
+	if _, err := d.getMetadata(ctx); err != nil {
+		return "", err
+	}
+	timeout := d.driverOptions.deviceAttachTimeout
+	if d.isReattach(volumeID) && d.driverOptions.deviceReattachTimeout > 0 {
+		timeout = d.driverOptions.deviceReattachTimeout
+	}
+	fallbackWait := d.driverOptions.scsiFallbackWait
+	if fallbackWait > 0 && timeout < fallbackWait+devicePathPollInterval {
+		// Leave time for at least one lookup including the SCSI device paths
+		timeout = fallbackWait + devicePathPollInterval
+	}
+	if timeout <= 0 {
+		return d.findDevice(devicePath, volumeID, partition)
+	}
+
+	start := time.Now()
+	var source string
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, devicePathPollInterval, timeout, true, func(_ context.Context) (bool, error) {
+		source, lastErr = d.findDevice(devicePath, volumeID, partition)
+		if lastErr != nil && fallbackWait > 0 && time.Since(start) >= fallbackWait {
+			scsiSource, scsiErr := d.findSCSIDevicePath(devicePath, partition)
+			if scsiErr == nil {
+				logger.Info("NodeStageVolume: NVMe device not found, falling back to SCSI device path", "devicePath", devicePath, "volumeID", volumeID, "source", scsiSource, "err", lastErr)
+				source, lastErr = scsiSource, nil
+			} else {
+				logger.V(4).Info("NodeStageVolume: SCSI device path not found", "devicePath", devicePath, "volumeID", volumeID, "err", scsiErr)
+			}
+		}
+		if lastErr != nil {
+			logger.V(4).Info("NodeStageVolume: device not found, retrying", "devicePath", devicePath, "volumeID", volumeID, "timeout", timeout, "err", lastErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			return "", lastErr
+		}
+		return "", err
+	}
+	return source, nil
+}
+
+// findDevice finds the device of a volume with findDevicePath and, if that
+// fails and the filesystem UUID of the volume is known, by the UUID. The UUID
+// identifies the partition of the filesystem, so partition is not appended.
+// A device found by UUID is only used if it reports the volume ID, as another
+// volume may carry a filesystem with the same UUID, e.g. a volume restored
+// from a snapshot of it. The device of a partition is not found until it exists.
+func (d *nodeService) findDevice(devicePath, volumeID, partition string) (string, error) {
+	source, err := d.findDevicePath(devicePath, volumeID, partition)
+	if err == nil && partition != "" {
+		if err = d.checkPartitionExists(source); err != nil {
+			source = ""
+		}
+	}
+	if err == nil || d.filesystemUUIDs == nil {
+		return source, err
+	}
+	uuid, ok := d.filesystemUUIDs.load(volumeID)
+	if !ok {
+		return "", err
+	}
+	uuidSource, uuidErr := d.findDeviceByUUID(uuid, volumeID)
+	if uuidErr != nil {
+		klog.V(4).InfoS("NodeStageVolume: device not found by filesystem UUID", "volumeID", volumeID, "uuid", uuid, "err", uuidErr)
+		return "", err
+	}
+	klog.InfoS("NodeStageVolume: device found by filesystem UUID", "devicePath", devicePath, "volumeID", volumeID, "uuid", uuid, "source", uuidSource)
+	return uuidSource, nil
+}
+
+// recordFilesystemUUID records the UUID of the filesystem on source, e.g. after
+// it was first formatted, if resolving devices by UUID is enabled.
+func (d *nodeService) recordFilesystemUUID(mounter Mounter, volumeID, source string) {
+	if d.filesystemUUIDs == nil {
+		return
+	}
+	uuid, err := mounter.GetFilesystemUUID(source)
+	if err != nil || uuid == "" {
+		klog.InfoS("NodeStageVolume: could not record filesystem UUID", "volumeID", volumeID, "source", source, "err", err)
+		return
+	}
+	klog.V(4).InfoS("NodeStageVolume: recorded filesystem UUID", "volumeID", volumeID, "source", source, "uuid", uuid)
+	d.filesystemUUIDs.store(volumeID, uuid)
+}
+
+// isReattach reports whether the volume was recently unstaged from the node.
+func (d *nodeService) isReattach(volumeID string) bool {
+	return d.unstagedVolumes.has(volumeID)
+}
+
+// waitForDeviceSize waits until the device reports at least the size the volume
+// was provisioned with. A smaller device is likely stale, e.g. a device the
+// kernel has not finished updating after attach. The device may be larger when
+// the volume was expanded, because the volume context is never updated.
+func (d *nodeService) waitForDeviceSize(ctx context.Context, mounter Mounter, source string, volumeContext map[string]string) error {
+	logger := klog.FromContext(ctx)
+	timeout := d.driverOptions.deviceSizeCheckTimeout
+	size, ok := volumeContext[VolumeAttributeSizeBytes]
+	if timeout <= 0 || !ok {
+		return nil
+	}
+	expectedBytes, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid volume size %q: %v", size, err)
+	}
+
+	var deviceBytes int64
+	var lastErr error
+	err = wait.PollUntilContextTimeout(ctx, deviceSizeCheckInterval, timeout, true, func(_ context.Context) (bool, error) {
+		deviceBytes, lastErr = mounter.GetBlockSizeBytes(source)
+		if lastErr != nil {
+			logger.V(4).Info("NodeStageVolume: failed to get device size, retrying", "source", source, "err", lastErr)
+			return false, nil
+		}
+		if deviceBytes < expectedBytes {
+			logger.Info("NodeStageVolume: device is smaller than the volume, retrying", "source", source, "deviceBytes", deviceBytes, "expectedBytes", expectedBytes)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			return status.Errorf(codes.Internal, "Failed to get size of device %s: %v", source, lastErr)
+		}
+		return status.Errorf(codes.Internal, "Device %s has size %d bytes, expected at least %d bytes", source, deviceBytes, expectedBytes)
+	}
+	return nil
+}
+
 func (d *nodeService) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	klog.V(4).InfoS("NodeUnstageVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeUnstageVolume: called", "args", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -320,14 +581,15 @@ func (d *nodeService) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
 	}
 	defer func() {
-		klog.V(4).InfoS("NodeUnStageVolume: volume operation finished", "volumeID", volumeID)
+		logger.V(4).Info("NodeUnStageVolume: volume operation finished", "volumeID", volumeID)
 		d.inFlight.Delete(volumeID)
 	}()
 
 	// Check if target directory is a mount point. GetDeviceNameFromMount
 	// given a mnt point, finds the device from /proc/mounts
 	// returns the device name, reference count, and error code
-	dev, refCount, err := d.mounter.GetDeviceNameFromMount(target)
+	mounter := d.mounterForTarget(target)
+	dev, refCount, err := mounter.GetDeviceNameFromMount(target)
 	if err != nil {
 		msg := fmt.Sprintf("failed to check if target %q is a mount point: %v", target, err)
 		return nil, status.Error(codes.Internal, msg)
@@ -336,26 +598,31 @@ func (d *nodeService) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 	// From the spec: If the volume corresponding to the volume_id
 	// is not staged to the staging_target_path, the Plugin MUST
 	// reply 0 OK.
+	// Block volumes are not staged, so their target is never mounted
 	if refCount == 0 {
-		klog.V(5).InfoS("[Debug] NodeUnstageVolume: target not mounted", "target", target)
+		logger.V(5).Info("[Debug] NodeUnstageVolume: target not mounted", "target", target)
+		d.unstagedVolumes.add(volumeID)
 		return &csi.NodeUnstageVolumeResponse{}, nil
 	}
 
 	if refCount > 1 {
-		klog.InfoS("NodeUnstageVolume: found references to device mounted at target path", "refCount", refCount, "device", dev, "target", target)
+		logger.Info("NodeUnstageVolume: found references to device mounted at target path", "refCount", refCount, "device", dev, "target", target)
 	}
 
-	klog.V(4).InfoS("NodeUnstageVolume: unmounting", "target", target)
-	err = d.mounter.Unstage(target)
+	logger.V(4).Info("NodeUnstageVolume: unmounting", "target", target)
+	err = mounter.Unstage(target)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not unmount target %q: %v", target, err)
 	}
-	klog.V(4).InfoS("NodeUnStageVolume: successfully unstaged volume", "volumeID", volumeID, "target", target)
+	d.unstagedVolumes.add(volumeID)
+	d.ioStats.forget(volumeID)
+	logger.V(4).Info("NodeUnStageVolume: successfully unstaged volume", "volumeID", volumeID, "target", target)
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
 func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
-	klog.V(4).InfoS("NodeExpandVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeExpandVolume: called", "args", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -375,7 +642,7 @@ func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 
 		if blk := volumeCapability.GetBlock(); blk != nil {
 			// Noop for Block NodeExpandVolume
-			klog.V(4).InfoS("NodeExpandVolume: called. Since it is a block device, ignoring...", "volumeID", volumeID, "volumePath", volumePath)
+			logger.V(4).Info("NodeExpandVolume: called. Since it is a block device, ignoring...", "volumeID", volumeID, "volumePath", volumePath)
 			return &csi.NodeExpandVolumeResponse{}, nil
 		}
 	} else {
@@ -387,26 +654,30 @@ func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 		}
 		if isBlock {
 			// Skip resizing for Block NodeExpandVolume
-			bcap, err := d.getBlockSizeBytes(volumePath)
+			bcap, err := d.mounter.GetBlockSizeBytes(volumePath)
 			if err != nil {
 				return nil, status.Errorf(codes.Internal, "failed to get block capacity on path %s: %v", req.VolumePath, err)
 			}
-			klog.V(4).InfoS("NodeExpandVolume: called, since given volumePath is a block device, ignoring...", "volumeID", volumeID, "volumePath", volumePath)
+			logger.V(4).Info("NodeExpandVolume: called, since given volumePath is a block device, ignoring...", "volumeID", volumeID, "volumePath", volumePath)
 			return &csi.NodeExpandVolumeResponse{CapacityBytes: bcap}, nil
 		}
 	}
 
-	deviceName, _, err := d.mounter.GetDeviceNameFromMount(volumePath)
+	mounter := d.mounterForTarget(volumePath)
+	deviceName, _, err := mounter.GetDeviceNameFromMount(volumePath)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get device name from mount %s: %v", volumePath, err)
 	}
 
+	if _, err := d.getMetadata(ctx); err != nil {
+		return nil, err
+	}
 	devicePath, err := d.findDevicePath(deviceName, volumeID, "")
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to find device path for device name %s for mount %s: %v", deviceName, req.GetVolumePath(), err)
 	}
 
-	r, err := d.mounter.NewResizeFs()
+	r, err := mounter.NewResizeFs()
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Error attempting to create new ResizeFs:  %v", err)
 	}
@@ -416,7 +687,7 @@ func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 		return nil, status.Errorf(codes.Internal, "Could not resize volume %q (%q):  %v", volumeID, devicePath, err)
 	}
 
-	bcap, err := d.getBlockSizeBytes(devicePath)
+	bcap, err := d.mounter.GetBlockSizeBytes(devicePath)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get block capacity on path %s: %v", req.VolumePath, err)
 	}
@@ -424,7 +695,8 @@ func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 }
 
 func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	klog.V(4).InfoS("NodePublishVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodePublishVolume: called", "args", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -453,10 +725,15 @@ func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
 	}
 	defer func() {
-		klog.V(4).InfoS("NodePublishVolume: volume operation finished", "volumeId", volumeID)
+		logger.V(4).Info("NodePublishVolume: volume operation finished", "volumeId", volumeID)
 		d.inFlight.Delete(volumeID)
 	}()
 
+	mounter, err := d.mounterForVolume(req.GetVolumeContext())
+	if err != nil {
+		return nil, err
+	}
+
 	mountOptions := []string{"bind"}
 	if req.GetReadonly() {
 		mountOptions = append(mountOptions, "ro")
@@ -464,11 +741,11 @@ func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 
 	switch mode := volCap.GetAccessType().(type) {
 	case *csi.VolumeCapability_Block:
-		if err := d.nodePublishVolumeForBlock(req, mountOptions); err != nil {
+		if err := d.nodePublishVolumeForBlock(ctx, req, mountOptions, mounter); err != nil {
 			return nil, err
 		}
 	case *csi.VolumeCapability_Mount:
-		if err := d.nodePublishVolumeForFileSystem(req, mountOptions, mode); err != nil {
+		if err := d.nodePublishVolumeForFileSystem(req, mountOptions, mode, mounter); err != nil {
 			return nil, err
 		}
 	}
@@ -477,7 +754,8 @@ func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 }
 
 func (d *nodeService) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
-	klog.V(4).InfoS("NodeUnpublishVolume: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeUnpublishVolume: called", "args", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -491,12 +769,38 @@ func (d *nodeService) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpu
 		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
 	}
 	defer func() {
-		klog.V(4).InfoS("NodeUnPublishVolume: volume operation finished", "volumeId", volumeID)
+		logger.V(4).Info("NodeUnPublishVolume: volume operation finished", "volumeId", volumeID)
 		d.inFlight.Delete(volumeID)
 	}()
 
-	klog.V(4).InfoS("NodeUnpublishVolume: unmounting", "target", target)
-	err := d.mounter.Unpublish(target)
+	mounter := d.mounterForTarget(target)
+	exists, err := mounter.PathExists(target)
+	if err != nil && !mounter.IsCorruptedMnt(err) {
+		return nil, status.Errorf(codes.Internal, "Could not check if target %q exists: %v", target, err)
+	}
+	// From the spec: If the volume corresponding to the volume_id is not
+	// published to the target_path, the Plugin MUST reply 0 OK.
+	if !exists {
+		logger.Info("NodeUnpublishVolume: target does not exist, volume is already unpublished", "volumeID", volumeID, "target", target)
+		return &csi.NodeUnpublishVolumeResponse{}, nil
+	}
+
+	// A corrupted mount is cleaned up by Unpublish without checking its device
+	if err == nil {
+		device, refCount, err := mounter.GetDeviceNameFromMount(target)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not check if target %q is a mount point: %v", target, err)
+		}
+		if refCount == 0 {
+			logger.Info("NodeUnpublishVolume: target is not mounted, removing it", "volumeID", volumeID, "target", target)
+		} else if d.isForeignMount(device, volumeID) {
+			logger.Info("NodeUnpublishVolume: target is mounted from the device of another volume, not unmounting it", "volumeID", volumeID, "target", target, "device", device)
+			return nil, status.Errorf(codes.FailedPrecondition, "Target %q is mounted from device %q, which does not belong to volume %q", target, device, volumeID)
+		}
+	}
+
+	logger.V(4).Info("NodeUnpublishVolume: unmounting", "target", target)
+	err = mounter.Unpublish(target)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not unmount %q: %v", target, err)
 	}
@@ -505,7 +809,8 @@ func (d *nodeService) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpu
 }
 
 func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	klog.V(4).InfoS("NodeGetVolumeStats: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeGetVolumeStats: called", "args", req)
 	if len(req.VolumeId) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "NodeGetVolumeStats volume ID was empty")
 	}
@@ -527,7 +832,7 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 		return nil, status.Errorf(codes.Internal, "failed to determine whether %s is block device: %v", req.VolumePath, err)
 	}
 	if isBlock {
-		bcap, blockErr := d.getBlockSizeBytes(req.VolumePath)
+		bcap, blockErr := d.mounter.GetBlockSizeBytes(req.VolumePath)
 		if blockErr != nil {
 			return nil, status.Errorf(codes.Internal, "failed to get block capacity on path %s: %v", req.VolumePath, err)
 		}
@@ -541,6 +846,38 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 		}, nil
 	}
 
+	mountPath := req.GetStagingTargetPath()
+	if mountPath == "" {
+		mountPath = req.VolumePath
+	}
+
+	// A volume detached while mounted fails all I/O, including the stat of
+	// its file system, so it is reported as abnormal instead of as an error.
+	// Kubelet expects usage entries whenever the call succeeds, so they are
+	// reported zeroed
+	if device := d.detachedDevice(mountPath); device != "" {
+		msg := fmt.Sprintf("Device %s of the volume was detached from the node while mounted", device)
+		logger.Info("NodeGetVolumeStats: volume detached while mounted", "volumeID", req.VolumeId, "device", device, "path", mountPath)
+		if d.driverOptions.unmountDetachedVolumes {
+			if err := d.unmountDetachedVolume(req.VolumePath, req.GetStagingTargetPath()); err != nil {
+				logger.Error(err, "NodeGetVolumeStats: could not unmount volume detached while mounted", "volumeID", req.VolumeId)
+				msg += fmt.Sprintf(", could not unmount it: %v", err)
+			} else {
+				msg += ", unmounted it"
+			}
+		}
+		return &csi.NodeGetVolumeStatsResponse{
+			Usage: []*csi.VolumeUsage{
+				{Unit: csi.VolumeUsage_BYTES},
+				{Unit: csi.VolumeUsage_INODES},
+			},
+			VolumeCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  msg,
+			},
+		}, nil
+	}
+
 	metricsProvider := volume.NewMetricsStatFS(req.VolumePath)
 
 	metrics, err := metricsProvider.GetMetrics()
@@ -548,7 +885,7 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 		return nil, status.Errorf(codes.Internal, "failed to get fs info on path %s: %v", req.VolumePath, err)
 	}
 
-	return &csi.NodeGetVolumeStatsResponse{
+	resp := &csi.NodeGetVolumeStatsResponse{
 		Usage: []*csi.VolumeUsage{
 			{
 				Unit:      csi.VolumeUsage_BYTES,
@@ -563,12 +900,48 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 				Used:      metrics.InodesUsed.AsDec().UnscaledBig().Int64(),
 			},
 		},
-	}, nil
+	}
+
+	// Report the mount options in effect on the staged volume, so that drift
+	// from the requested options is visible. The file systems remount a staged
+	// volume read-only after I/O errors, which is reported as abnormal
+	var messages []string
+	abnormal := false
+	opts, err := d.mountOptions(mountPath)
+	if err != nil {
+		logger.V(4).Info("NodeGetVolumeStats: could not get mount options", "path", mountPath, "err", err)
+	} else {
+		if req.GetStagingTargetPath() != "" && hasMountOption(opts, "ro") {
+			logger.Info("NodeGetVolumeStats: staged volume is mounted read-only", "volumeID", req.VolumeId, "path", mountPath)
+			abnormal = true
+			messages = append(messages, "File system of the volume is mounted read-only, e.g. after I/O errors")
+		}
+		messages = append(messages, "Mount options in effect: "+strings.Join(opts, ","))
+	}
+
+	// Report the IOPS and throughput since the previous call, so that an
+	// external autoscaler can raise the performance of the volume
+	if d.ioStats != nil {
+		stats, err := d.blockStats(mountPath)
+		if err != nil {
+			logger.V(4).Info("NodeGetVolumeStats: could not get block device statistics", "path", mountPath, "err", err)
+		} else if utilization, ok := d.ioStats.observe(req.VolumeId, stats); ok {
+			messages = append(messages, utilization.String())
+		}
+	}
 
+	if len(messages) > 0 {
+		resp.VolumeCondition = &csi.VolumeCondition{
+			Abnormal: abnormal,
+			Message:  strings.Join(messages, "; "),
+		}
+	}
+	return resp, nil
 }
 
 func (d *nodeService) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
-	klog.V(4).InfoS("NodeGetCapabilities: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeGetCapabilities: called", "args", req)
 	var caps []*csi.NodeServiceCapability
 	for _, cap := range nodeCaps {
 		c := &csi.NodeServiceCapability{
@@ -584,15 +957,24 @@ func (d *nodeService) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetC
 }
 
 func (d *nodeService) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
-	klog.V(4).InfoS("NodeGetInfo: called", "args", *req)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("NodeGetInfo: called", "args", req)
 
-	zone := d.metadata.GetAvailabilityZone()
+	metadata, err := d.getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := d.resolveAvailabilityZone()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
 
 	segments := map[string]string{
 		TopologyKey: zone,
 	}
 
-	outpostArn := d.metadata.GetOutpostArn()
+	outpostArn := metadata.GetOutpostArn()
 
 	// to my surprise ARN's string representation is not empty for empty ARN
 	if len(outpostArn.Resource) > 0 {
@@ -605,13 +987,102 @@ func (d *nodeService) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 	topology := &csi.Topology{Segments: segments}
 
 	return &csi.NodeGetInfoResponse{
-		NodeId:             d.metadata.GetInstanceID(),
+		NodeId:             metadata.GetInstanceID(),
 		MaxVolumesPerNode:  d.getVolumesLimit(),
 		AccessibleTopology: topology,
 	}, nil
 }
 
-func (d *nodeService) nodePublishVolumeForBlock(req *csi.NodePublishVolumeRequest, mountOptions []string) error {
+// mounterForVolume returns the mounter to stage and publish a volume with.
+// Volumes flagged for mount namespace isolation are mounted in the configured
+// mount namespace.
+func (d *nodeService) mounterForVolume(volumeContext map[string]string) (Mounter, error) {
+	if volumeContext[IsolateMountNamespaceKey] != "true" {
+		return d.mounter, nil
+	}
+	if d.namespaceMounter == nil {
+		return nil, status.Error(codes.FailedPrecondition, "volume requires mount namespace isolation but no mount namespace is configured on the node")
+	}
+	return d.namespaceMounter, nil
+}
+
+// mounterForTarget returns the mounter a volume was mounted at target with.
+// Unstage and unpublish requests carry no volume context, so the mount table
+// of the configured mount namespace is checked instead.
+func (d *nodeService) mounterForTarget(target string) Mounter {
+	if d.namespaceMounter == nil {
+		return d.mounter
+	}
+	_, refCount, err := d.namespaceMounter.GetDeviceNameFromMount(target)
+	if err != nil {
+		klog.InfoS("Could not check mount namespace for target, assuming it is not mounted there", "target", target, "err", err)
+		return d.mounter
+	}
+	if refCount > 0 {
+		klog.V(4).InfoS("Target is mounted in mount namespace", "target", target, "namespace", d.driverOptions.mountNamespace)
+		return d.namespaceMounter
+	}
+	return d.mounter
+}
+
+// unmountDetachedVolume lazily unmounts a volume that was detached while
+// mounted at its target and, if staged, at its staging target. The mounts are
+// gone right away, and the kernel releases the file system once the files still
+// open on it are closed.
+func (d *nodeService) unmountDetachedVolume(targetPath, stagingTargetPath string) error {
+	for _, path := range []string{targetPath, stagingTargetPath} {
+		if path == "" {
+			continue
+		}
+		klog.InfoS("Lazily unmounting volume detached while mounted", "path", path)
+		if err := d.mounterForTarget(path).UnmountLazy(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAvailabilityZone returns the availability zone of the node after
+// checking that it belongs to the node's region, which guards against
+// misconfigured metadata corrupting the topology with a zone of another region.
+func (d *nodeService) resolveAvailabilityZone() (string, error) {
+	zone := d.metadata.GetAvailabilityZone()
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = d.metadata.GetRegion()
+	}
+	if zoneInRegion(zone, region) {
+		return zone, nil
+	}
+
+	override := d.driverOptions.availabilityZoneOverride
+	if override == "" {
+		return "", fmt.Errorf("availability zone %q does not belong to region %q", zone, region)
+	}
+	if !zoneInRegion(override, region) {
+		return "", fmt.Errorf("availability zone %q and override %q do not belong to region %q", zone, override, region)
+	}
+	klog.InfoS("NodeGetInfo: availability zone does not belong to region, using override", "zone", zone, "region", region, "override", override)
+	return override, nil
+}
+
+// zoneInRegion returns true if zone is an availability, local or wavelength
+// zone of region, e.g. us-west-2a or us-west-2-lax-1a for us-west-2. Snow
+// devices report the same value for both. Unknown zones or regions are not
+// validated.
+func zoneInRegion(zone, region string) bool {
+	if zone == "" || region == "" || zone == region {
+		return true
+	}
+	if !strings.HasPrefix(zone, region) || len(zone) == len(region) {
+		return false
+	}
+	next := zone[len(region)]
+	return next == '-' || (next >= 'a' && next <= 'z')
+}
+
+func (d *nodeService) nodePublishVolumeForBlock(ctx context.Context, req *csi.NodePublishVolumeRequest, mountOptions []string, mounter Mounter) error {
+	logger := klog.FromContext(ctx)
 	target := req.GetTargetPath()
 	volumeID := req.GetVolumeId()
 	volumeContext := req.GetVolumeContext()
@@ -629,35 +1100,38 @@ func (d *nodeService) nodePublishVolumeForBlock(req *csi.NodePublishVolumeReques
 		if part != "0" {
 			partition = part
 		} else {
-			klog.InfoS("NodePublishVolume: invalid partition config, will ignore.", "partition", part)
+			logger.Info("NodePublishVolume: invalid partition config, will ignore.", "partition", part)
 		}
 	}
 
-	source, err := d.findDevicePath(devicePath, volumeID, partition)
+	// Block volumes are not staged, so the device of the volume or of its
+	// partition may not have appeared yet
+	source, err := d.waitForDevicePath(ctx, devicePath, volumeID, partition)
 	if err != nil {
 		return status.Errorf(codes.Internal, "Failed to find device path %s. %v", devicePath, err)
 	}
+	d.unstagedVolumes.remove(volumeID)
 
-	klog.V(4).InfoS("NodePublishVolume [block]: find device path", "devicePath", devicePath, "source", source)
+	logger.V(4).Info("NodePublishVolume [block]: find device path", "devicePath", devicePath, "source", source)
 
 	globalMountPath := filepath.Dir(target)
 
 	// create the global mount path if it is missing
 	// Path in the form of /var/lib/kubelet/plugins/kubernetes.io/csi/volumeDevices/publish/{volumeName}
-	exists, err = d.mounter.PathExists(globalMountPath)
+	exists, err = mounter.PathExists(globalMountPath)
 	if err != nil {
 		return status.Errorf(codes.Internal, "Could not check if path exists %q: %v", globalMountPath, err)
 	}
 
 	if !exists {
-		if err = d.mounter.MakeDir(globalMountPath); err != nil {
+		if err = mounter.MakeDir(globalMountPath); err != nil {
 			return status.Errorf(codes.Internal, "Could not create dir %q: %v", globalMountPath, err)
 		}
 	}
 
 	// Create the mount point as a file since bind mount device node requires it to be a file
-	klog.V(4).InfoS("NodePublishVolume [block]: making target file", "target", target)
-	if err = d.mounter.MakeFile(target); err != nil {
+	logger.V(4).Info("NodePublishVolume [block]: making target file", "target", target)
+	if err = mounter.MakeFile(target); err != nil {
 		if removeErr := os.Remove(target); removeErr != nil {
 			return status.Errorf(codes.Internal, "Could not remove mount target %q: %v", target, removeErr)
 		}
@@ -665,21 +1139,21 @@ func (d *nodeService) nodePublishVolumeForBlock(req *csi.NodePublishVolumeReques
 	}
 
 	//Checking if the target file is already mounted with a device.
-	mounted, err := d.isMounted(source, target)
+	mounted, err := d.isMounted(mounter, target)
 	if err != nil {
 		return status.Errorf(codes.Internal, "Could not check if %q is mounted: %v", target, err)
 	}
 
 	if !mounted {
-		klog.V(4).InfoS("NodePublishVolume [block]: mounting", "source", source, "target", target)
-		if err := d.mounter.Mount(source, target, "", mountOptions); err != nil {
+		logger.V(4).Info("NodePublishVolume [block]: mounting", "source", source, "target", target)
+		if err := mounter.Mount(source, target, "", mountOptions); err != nil {
 			if removeErr := os.Remove(target); removeErr != nil {
 				return status.Errorf(codes.Internal, "Could not remove mount target %q: %v", target, removeErr)
 			}
 			return status.Errorf(codes.Internal, "Could not mount %q at %q: %v", source, target, err)
 		}
 	} else {
-		klog.V(4).InfoS("NodePublishVolume [block]: Target path is already mounted", "target", target)
+		logger.V(4).Info("NodePublishVolume [block]: Target path is already mounted", "target", target)
 	}
 
 	return nil
@@ -687,20 +1161,20 @@ func (d *nodeService) nodePublishVolumeForBlock(req *csi.NodePublishVolumeReques
 
 // isMounted checks if target is mounted. It does NOT return an error if target
 // doesn't exist.
-func (d *nodeService) isMounted(_ string, target string) (bool, error) {
+func (d *nodeService) isMounted(mounter Mounter, target string) (bool, error) {
 	/*
 		Checking if it's a mount point using IsLikelyNotMountPoint. There are three different return values,
 		1. true, err when the directory does not exist or corrupted.
 		2. false, nil when the path is already mounted with a device.
 		3. true, nil when the path is not mounted with any device.
 	*/
-	notMnt, err := d.mounter.IsLikelyNotMountPoint(target)
+	notMnt, err := mounter.IsLikelyNotMountPoint(target)
 	if err != nil && !os.IsNotExist(err) {
 		//Checking if the path exists and error is related to Corrupted Mount, in that case, the system could unmount and mount.
-		_, pathErr := d.mounter.PathExists(target)
-		if pathErr != nil && d.mounter.IsCorruptedMnt(pathErr) {
+		_, pathErr := mounter.PathExists(target)
+		if pathErr != nil && mounter.IsCorruptedMnt(pathErr) {
 			klog.V(4).InfoS("NodePublishVolume: Target path is a corrupted mount. Trying to unmount.", "target", target)
-			if mntErr := d.mounter.Unpublish(target); mntErr != nil {
+			if mntErr := mounter.Unpublish(target); mntErr != nil {
 				return false, status.Errorf(codes.Internal, "Unable to unmount the target %q : %v", target, mntErr)
 			}
 			//After successful unmount, the device is ready to be mounted.
@@ -727,7 +1201,7 @@ func (d *nodeService) isMounted(_ string, target string) (bool, error) {
 	return !notMnt, nil
 }
 
-func (d *nodeService) nodePublishVolumeForFileSystem(req *csi.NodePublishVolumeRequest, mountOptions []string, mode *csi.VolumeCapability_Mount) error {
+func (d *nodeService) nodePublishVolumeForFileSystem(req *csi.NodePublishVolumeRequest, mountOptions []string, mode *csi.VolumeCapability_Mount, mounter Mounter) error {
 	target := req.GetTargetPath()
 	source := req.GetStagingTargetPath()
 	if m := mode.Mount; m != nil {
@@ -743,7 +1217,7 @@ func (d *nodeService) nodePublishVolumeForFileSystem(req *csi.NodePublishVolumeR
 	}
 
 	//Checking if the target directory is already mounted with a device.
-	mounted, err := d.isMounted(source, target)
+	mounted, err := d.isMounted(mounter, target)
 	if err != nil {
 		return status.Errorf(codes.Internal, "Could not check if %q is mounted: %v", target, err)
 	}
@@ -761,7 +1235,7 @@ func (d *nodeService) nodePublishVolumeForFileSystem(req *csi.NodePublishVolumeR
 
 		mountOptions = collectMountOptions(fsType, mountOptions)
 		klog.V(4).InfoS("NodePublishVolume: mounting", "source", source, "target", target, "mountOptions", mountOptions, "fsType", fsType)
-		if err := d.mounter.Mount(source, target, fsType, mountOptions); err != nil {
+		if err := mounter.Mount(source, target, fsType, mountOptions); err != nil {
 			return status.Errorf(codes.Internal, "Could not mount %q at %q: %v", source, target, err)
 		}
 	}
@@ -796,21 +1270,40 @@ func (d *nodeService) getVolumesLimit() int64 {
 	}
 	// For special dedicated limit instance types, the limit is only for EBS volumes
 	// For (all other) Nitro instances, attachments are shared between EBS volumes, ENIs and NVMe instance stores
+	var enis, nvmeInstanceStoreVolumes int
 	if dedicatedLimit != 0 {
 		availableAttachments = dedicatedLimit
 	} else if isNitro {
-		enis := d.metadata.GetNumAttachedENIs()
-		nvmeInstanceStoreVolumes := cloud.GetNVMeInstanceStoreVolumesForInstanceType(instanceType)
+		enis = d.metadata.GetNumAttachedENIs()
+		nvmeInstanceStoreVolumes = cloud.GetNVMeInstanceStoreVolumesForInstanceType(instanceType)
 		availableAttachments = availableAttachments - enis - nvmeInstanceStoreVolumes
 	}
 	availableAttachments = availableAttachments - reservedVolumeAttachments
 	if availableAttachments <= 0 {
 		availableAttachments = 1
 	}
+	klog.InfoS("Computed volume attachment limit", "limit", availableAttachments, "instanceType", instanceType, "isNitro", isNitro,
+		"dedicatedLimit", dedicatedLimit, "attachedENIs", enis, "nvmeInstanceStoreVolumes", nvmeInstanceStoreVolumes, "reservedVolumeAttachments", reservedVolumeAttachments)
 
 	return int64(availableAttachments)
 }
 
+// retrieveMetadata calls newMetadata, retrying up to retryAttempts times while
+// the failure looks transient (see isTransientError). Permanent failures are
+// returned immediately.
+func retrieveMetadata(ctx context.Context, newMetadata func() (cloud.MetadataService, error), retryAttempts int) (cloud.MetadataService, error) {
+	var metadata cloud.MetadataService
+	err := retryTransient(ctx, "instance metadata", retryAttempts, func() error {
+		var err error
+		metadata, err = newMetadata()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 func min(x, y int) int {
 	if x <= y {
 		return x
@@ -851,6 +1344,18 @@ func collectMountOptions(fsType string, mntFlags []string) []string {
 	return options
 }
 
+// formatSkipReason returns why FormatAndMount will not format a device with the
+// given existing filesystem and mount options, or "" if it will be formatted.
+func formatSkipReason(existingFormat string, mountOptions []string) string {
+	if existingFormat != "" {
+		return formatSkippedAlreadyFormatted
+	}
+	if hasMountOption(mountOptions, "ro") {
+		return formatSkippedReadOnly
+	}
+	return ""
+}
+
 // Struct for JSON patch operations
 type JSONPatch struct {
 	OP    string      `json:"op,omitempty"`
@@ -940,8 +1445,8 @@ func recheckFormattingOptionParameter(context map[string]string, key string, fsC
 	if ok {
 		// This check is already performed on the controller side
 		// However, because it is potentially security-sensitive, we redo it here to be safe
-		if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-			return "", status.Errorf(codes.InvalidArgument, "Invalid %s (aborting!): %v", key, err)
+		if isAlphanumeric := util.StringIsAlphanumeric(v); !isAlphanumeric {
+			return "", status.Errorf(codes.InvalidArgument, "Invalid %s (aborting!): %q is not alphanumeric", key, v)
 		}
 
 		// In the case that the default fstype does not support custom sizes we could
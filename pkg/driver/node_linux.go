@@ -23,14 +23,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"golang.org/x/sys/unix"
 	"k8s.io/klog/v2"
+	"k8s.io/mount-utils"
 )
 
+// procMountsPath is the file the mount options in effect are read from
+var procMountsPath = "/proc/mounts"
+
+// sysBlockPath is the directory the I/O statistics of block devices are read from
+var sysBlockPath = "/sys/class/block"
+
+// sectorSize is the unit of the sector counters of block device statistics,
+// regardless of the sector size of the device.
+const sectorSize = 512
+
 func (d *nodeService) appendPartition(devicePath, partition string) string {
 	if partition == "" {
 		return devicePath
@@ -75,8 +87,16 @@ func (d *nodeService) findDevicePath(devicePath, volumeID, partition string) (st
 			canonicalDevicePath = devicePath
 		}
 
-		klog.V(5).InfoS("[Debug] The canonical device path was resolved", "devicePath", devicePath, "cacanonicalDevicePath", canonicalDevicePath)
-		return d.appendPartition(canonicalDevicePath, partition), nil
+		// The device path is only a guess on Nitro instances, e.g. a link left
+		// behind by a volume that was attached under the same device name
+		// before, so NVMe devices that report another volume are not trusted
+		if deviceVolumeID, err := nvmeDeviceVolumeID(canonicalDevicePath); err == nil && deviceVolumeID != volumeID {
+			klog.InfoS("Device path belongs to another volume, looking up device by volume ID", "devicePath", devicePath, "canonicalDevicePath", canonicalDevicePath, "volumeID", volumeID, "deviceVolumeID", deviceVolumeID)
+			canonicalDevicePath = ""
+		} else {
+			klog.V(5).InfoS("[Debug] The canonical device path was resolved", "devicePath", devicePath, "cacanonicalDevicePath", canonicalDevicePath)
+			return d.appendPartition(canonicalDevicePath, partition), nil
+		}
 	}
 
 	klog.V(5).InfoS("[Debug] Falling back to nvme volume ID lookup", "devicePath", devicePath)
@@ -87,16 +107,13 @@ func (d *nodeService) findDevicePath(devicePath, volumeID, partition string) (st
 	// which AWS presents NVME devices under /dev/disk/by-id/. For example,
 	// vol-0fab1d5e3f72a5e23 creates a symlink at
 	// /dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_vol0fab1d5e3f72a5e23
-	nvmeName := "nvme-Amazon_Elastic_Block_Store_" + strings.Replace(volumeID, "-", "", -1)
-
-	nvmeDevicePath, err := findNvmeVolume(d.deviceIdentifier, nvmeName)
-
+	// If there is no such symlink, the volume ID is looked up in the identify
+	// controller data of the NVMe devices.
+	nvmeDevicePath, err := d.findNvmeDevice(volumeID)
 	if err == nil {
-		klog.V(5).InfoS("[Debug] successfully resolved", "nvmeName", nvmeName, "nvmeDevicePath", nvmeDevicePath)
+		klog.V(5).InfoS("[Debug] successfully resolved", "volumeID", volumeID, "nvmeDevicePath", nvmeDevicePath)
 		canonicalDevicePath = nvmeDevicePath
 		return d.appendPartition(canonicalDevicePath, partition), nil
-	} else {
-		klog.V(5).InfoS("[Debug] error searching for nvme path", "nvmeName", nvmeName, "err", err)
 	}
 
 	if util.IsSBE(d.metadata.GetRegion()) {
@@ -116,6 +133,20 @@ func (d *nodeService) findDevicePath(devicePath, volumeID, partition string) (st
 	return canonicalDevicePath, nil
 }
 
+// checkPartitionExists returns an error if the device of a partition does not
+// exist yet. The kernel creates the devices of the partitions of a volume only
+// after the device of the volume, once it has read its partition table.
+func (d *nodeService) checkPartitionExists(source string) error {
+	exists, err := d.mounter.PathExists(source)
+	if err != nil {
+		return fmt.Errorf("failed to check if partition %q exists: %w", source, err)
+	}
+	if !exists {
+		return fmt.Errorf("partition %q not found", source)
+	}
+	return nil
+}
+
 func errNoDevicePathFound(devicePath, volumeID string) error {
 	return fmt.Errorf("no device path for device %q volume %q found", devicePath, volumeID)
 }
@@ -151,6 +182,209 @@ func findNvmeVolume(deviceIdentifier DeviceIdentifier, findName string) (device
 	return resolved, nil
 }
 
+// findDeviceByUUID finds the device of the filesystem with the given UUID,
+// which udev links at /dev/disk/by-uuid, e.g.
+// /dev/disk/by-uuid/f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10 -> ../../nvme1n1
+// It fails unless the NVMe device, or the device whose partition it is,
+// reports volumeID, so devices are only found by UUID on Nitro instances.
+func (d *nodeService) findDeviceByUUID(uuid, volumeID string) (string, error) {
+	p := filepath.Join("/dev/disk/by-uuid/", uuid)
+	if _, err := d.deviceIdentifier.Lstat(p); err != nil {
+		return "", fmt.Errorf("error getting stat of %q: %w", p, err)
+	}
+	resolved, err := d.deviceIdentifier.EvalSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("error reading target of symlink %q: %w", p, err)
+	}
+	if !strings.HasPrefix(resolved, "/dev") {
+		return "", fmt.Errorf("resolved symlink for %q was unexpected: %q", p, resolved)
+	}
+	deviceVolumeID, err := nvmeDeviceVolumeID(nvmePartitionDisk(resolved))
+	if err != nil {
+		return "", fmt.Errorf("could not verify the volume of device %q: %w", resolved, err)
+	}
+	if deviceVolumeID != volumeID {
+		return "", fmt.Errorf("device %q belongs to volume %s, not %s", resolved, deviceVolumeID, volumeID)
+	}
+	return resolved, nil
+}
+
+// nvmePartitionRegexp matches NVMe partitions, e.g. /dev/nvme1n1p1, and
+// captures the device they are a partition of.
+var nvmePartitionRegexp = regexp.MustCompile(`^(/dev/nvme\d+n\d+)p\d+$`)
+
+// nvmePartitionDisk returns the NVMe device device is a partition of, e.g.
+// /dev/nvme1n1 for /dev/nvme1n1p1, or device itself if it is no partition.
+func nvmePartitionDisk(device string) string {
+	if m := nvmePartitionRegexp.FindStringSubmatch(device); m != nil {
+		return m[1]
+	}
+	return device
+}
+
+// legacyDevicePaths returns the SCSI device paths under which the device
+// requested at devicePath may be presented by an instance that does not use
+// NVMe, e.g. /dev/xvdf and /dev/sdf for /dev/xvdf or /dev/sdf.
+func legacyDevicePaths(devicePath string) []string {
+	name := strings.TrimPrefix(devicePath, "/dev/")
+	var suffix string
+	switch {
+	case strings.HasPrefix(name, "xvd"):
+		suffix = strings.TrimPrefix(name, "xvd")
+	case strings.HasPrefix(name, "sd"):
+		suffix = strings.TrimPrefix(name, "sd")
+	}
+	if suffix == "" {
+		return nil
+	}
+	return []string{"/dev/xvd" + suffix, "/dev/sd" + suffix}
+}
+
+// findSCSIDevicePath finds the device of devicePath at one of its legacy SCSI
+// device paths, without relying on the volume ID.
+func (d *nodeService) findSCSIDevicePath(devicePath, partition string) (string, error) {
+	for _, path := range legacyDevicePaths(devicePath) {
+		exists, err := d.mounter.PathExists(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to check if path %q exists: %w", path, err)
+		}
+		if !exists {
+			continue
+		}
+		canonicalDevicePath, err := d.deviceIdentifier.EvalSymlinks(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to evaluate symlink %q: %w", path, err)
+		}
+		source := d.appendPartition(canonicalDevicePath, partition)
+		if partition != "" {
+			if err := d.checkPartitionExists(source); err != nil {
+				return "", err
+			}
+		}
+		return source, nil
+	}
+	return "", fmt.Errorf("no SCSI device path for device %q found", devicePath)
+}
+
+// isForeignMount reports whether device, which is mounted at a target of the
+// volume, positively belongs to another volume. Mount sources that cannot be
+// related to a volume, such as the devtmpfs source of block volume bind mounts
+// or devices of volumes without an nvme link, are not considered foreign.
+func (d *nodeService) isForeignMount(device, volumeID string) bool {
+	if !strings.HasPrefix(device, "/dev/") {
+		return false
+	}
+	volumeDevice, err := d.findNvmeDevice(volumeID)
+	if err != nil {
+		klog.V(5).InfoS("[Debug] Could not find volume device, assuming mount is not foreign", "volumeID", volumeID, "device", device, "err", err)
+		return false
+	}
+	return device != volumeDevice && !strings.HasPrefix(device, volumeDevice+nvmeDiskPartitionSuffix)
+}
+
+// procMount returns the mount at target as listed in /proc/mounts or, if the
+// volume was mounted in the mount namespace of isolated volumes, in the mounts
+// of that namespace.
+func (d *nodeService) procMount(target string) (mount.MountPoint, error) {
+	target = filepath.Clean(target)
+	if d.namespaceMounter != nil {
+		mountPoints, err := d.namespaceMounter.List()
+		if err != nil {
+			klog.V(4).InfoS("Could not list mounts of mount namespace, looking up target in /proc/mounts", "target", target, "namespace", d.driverOptions.mountNamespace, "err", err)
+		} else if mountPoint, ok := lastMount(mountPoints, target); ok {
+			return mountPoint, nil
+		}
+	}
+	mountPoints, err := mount.ListProcMounts(procMountsPath)
+	if err != nil {
+		return mount.MountPoint{}, err
+	}
+	mountPoint, ok := lastMount(mountPoints, target)
+	if !ok {
+		return mount.MountPoint{}, fmt.Errorf("%s is not a mount point", target)
+	}
+	return mountPoint, nil
+}
+
+// lastMount returns the mount at target in mountPoints. If several mounts are
+// stacked at target, the last one is in effect.
+func lastMount(mountPoints []mount.MountPoint, target string) (mount.MountPoint, bool) {
+	found := false
+	var mountPoint mount.MountPoint
+	for _, mp := range mountPoints {
+		if mp.Path == target {
+			found = true
+			mountPoint = mp
+		}
+	}
+	return mountPoint, found
+}
+
+// mountOptions returns the options of the mount at target as listed in
+// /proc/mounts, i.e. the options in effect rather than the requested ones.
+func (d *nodeService) mountOptions(target string) ([]string, error) {
+	mountPoint, err := d.procMount(target)
+	if err != nil {
+		return nil, err
+	}
+	return mountPoint.Opts, nil
+}
+
+// detachedDevice returns the device mounted at target if the device no longer
+// exists, i.e. the volume was detached from the instance while still mounted,
+// e.g. by a force detach. It returns an empty string if target is not a mount
+// point or its device exists.
+func (d *nodeService) detachedDevice(target string) string {
+	mountPoint, err := d.procMount(target)
+	if err != nil {
+		klog.V(5).InfoS("[Debug] Could not get mount, not checking whether its device was detached", "target", target, "err", err)
+		return ""
+	}
+	if !strings.HasPrefix(mountPoint.Device, "/dev/") {
+		return ""
+	}
+	exists, err := d.mounter.PathExists(mountPoint.Device)
+	if err != nil || exists {
+		return ""
+	}
+	return mountPoint.Device
+}
+
+// blockStats returns the I/O statistics of the device mounted at target, read
+// from /sys/class/block/<device>/stat.
+func (d *nodeService) blockStats(target string) (blockStats, error) {
+	mountPoint, err := d.procMount(target)
+	if err != nil {
+		return blockStats{}, err
+	}
+	device, err := d.deviceIdentifier.EvalSymlinks(mountPoint.Device)
+	if err != nil {
+		return blockStats{}, fmt.Errorf("could not resolve device %s: %w", mountPoint.Device, err)
+	}
+	statPath := filepath.Join(sysBlockPath, filepath.Base(device), "stat")
+	data, err := os.ReadFile(statPath)
+	if err != nil {
+		return blockStats{}, err
+	}
+	// The fields are documented in https://www.kernel.org/doc/Documentation/block/stat.txt
+	fields := strings.Fields(string(data))
+	if len(fields) < 7 {
+		return blockStats{}, fmt.Errorf("unexpected format of %s: %q", statPath, string(data))
+	}
+	var counters [4]uint64
+	for i, field := range []int{0, 2, 4, 6} {
+		counters[i], err = strconv.ParseUint(fields[field], 10, 64)
+		if err != nil {
+			return blockStats{}, fmt.Errorf("unexpected format of %s: %w", statPath, err)
+		}
+	}
+	readIOs, readSectors, writeIOs, writeSectors := counters[0], counters[1], counters[2], counters[3]
+	return blockStats{
+		ios:   readIOs + writeIOs,
+		bytes: (readSectors + writeSectors) * sectorSize,
+	}, nil
+}
+
 func (d *nodeService) preparePublishTarget(target string) error {
 	klog.V(4).InfoS("NodePublishVolume: creating dir", "target", target)
 	if err := d.mounter.MakeDir(target); err != nil {
@@ -169,17 +403,3 @@ func (d *nodeService) IsBlockDevice(fullPath string) (bool, error) {
 
 	return (st.Mode & unix.S_IFMT) == unix.S_IFBLK, nil
 }
-
-func (d *nodeService) getBlockSizeBytes(devicePath string) (int64, error) {
-	cmd := d.mounter.(*NodeMounter).Exec.Command("blockdev", "--getsize64", devicePath)
-	output, err := cmd.Output()
-	if err != nil {
-		return -1, fmt.Errorf("error when getting size of block volume at path %s: output: %s, err: %w", devicePath, string(output), err)
-	}
-	strOut := strings.TrimSpace(string(output))
-	gotSizeBytes, err := strconv.ParseInt(strOut, 10, 64)
-	if err != nil {
-		return -1, fmt.Errorf("failed to parse size %s as int", strOut)
-	}
-	return gotSizeBytes, nil
-}
@@ -20,8 +20,11 @@ limitations under the License.
 package driver
 
 import (
+	"context"
+	"errors"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -40,11 +43,13 @@ func TestFindDevicePath(t *testing.T) {
 	deviceFileInfo := fs.FileInfo(&fakeFileInfo{devicePath, os.ModeDevice})
 	symlinkFileInfo := fs.FileInfo(&fakeFileInfo{nvmeName, os.ModeSymlink})
 	nvmeDevicePathSymlinkFileInfo := fs.FileInfo(&fakeFileInfo{nvmeDevicePath, os.ModeSymlink})
+	otherNvmeDevicePath := "/dev/nvme5n1"
 	type testCase struct {
 		name             string
 		devicePath       string
 		volumeID         string
 		partition        string
+		sysfs            map[string]string
 		expectMock       func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier)
 		expectDevicePath string
 		expectError      string
@@ -64,6 +69,44 @@ func TestFindDevicePath(t *testing.T) {
 			},
 			expectDevicePath: nvmeDevicePath,
 		},
+		{
+			name:       "success: device path is the nvme device of another volume",
+			devicePath: devicePath,
+			volumeID:   volumeID,
+			partition:  "",
+			sysfs: map[string]string{
+				"block/nvme5n1/device/model":  ebsNVMeModel,
+				"block/nvme5n1/device/serial": "volother",
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				gomock.InOrder(
+					mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil),
+					mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(nvmeDevicePathSymlinkFileInfo, nil),
+					mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(devicePath)).Return(otherNvmeDevicePath, nil),
+					mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(symlinkFileInfo, nil),
+					mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(symlinkFileInfo.Name())).Return(nvmeDevicePath, nil),
+				)
+			},
+			expectDevicePath: nvmeDevicePath,
+		},
+		{
+			name:       "success: device path is the nvme device of the volume",
+			devicePath: devicePath,
+			volumeID:   volumeID,
+			partition:  "",
+			sysfs: map[string]string{
+				"block/nvme1n1/device/model":  ebsNVMeModel,
+				"block/nvme1n1/device/serial": "voltest",
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				gomock.InOrder(
+					mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil),
+					mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(nvmeDevicePathSymlinkFileInfo, nil),
+					mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(devicePath)).Return(nvmeDevicePath, nil),
+				)
+			},
+			expectDevicePath: nvmeDevicePath,
+		},
 		{
 			name:       "10: device path exists and nvme device path doesn't exist",
 			devicePath: devicePath,
@@ -106,6 +149,31 @@ func TestFindDevicePath(t *testing.T) {
 			},
 			expectError: errNoDevicePathFound(devicePath, volumeID).Error(),
 		},
+		{
+			name:       "success: nvme controller of volume found without nvme link",
+			devicePath: devicePath,
+			volumeID:   volumeID,
+			partition:  "",
+			sysfs: map[string]string{
+				"nvme/nvme0/model":        "Amazon EC2 NVMe Instance Storage",
+				"nvme/nvme0/serial":       "AWS1234",
+				"nvme/nvme0/nvme0n1/size": "0",
+				"nvme/nvme1/model":        ebsNVMeModel + "                      ",
+				"nvme/nvme1/serial":       "voltest             ",
+				"nvme/nvme1/nvme1n1/size": "0",
+				"nvme/nvme2/model":        ebsNVMeModel,
+				"nvme/nvme2/serial":       "volother",
+				"nvme/nvme2/nvme2n1/size": "0",
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				gomock.InOrder(
+					mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(false, nil),
+
+					mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(nil, os.ErrNotExist),
+				)
+			},
+			expectDevicePath: nvmeDevicePath,
+		},
 		{
 			name:       "success: device path doesn't exist and snow path exists",
 			devicePath: devicePath,
@@ -157,6 +225,21 @@ func TestFindDevicePath(t *testing.T) {
 			mockMounter := NewMockMounter(mockCtl)
 			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
 
+			sysfs := t.TempDir()
+			for path, content := range tc.sysfs {
+				path = filepath.Join(sysfs, path)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			defer func(path string) { sysBlockPath = path }(sysBlockPath)
+			sysBlockPath = filepath.Join(sysfs, "block")
+			defer func(path string) { sysClassNVMePath = path }(sysClassNVMePath)
+			sysClassNVMePath = filepath.Join(sysfs, "nvme")
+
 			nodeDriver := nodeService{
 				metadata:         &cloud.Metadata{},
 				mounter:          mockMounter,
@@ -218,3 +301,527 @@ func (fi *fakeFileInfo) IsDir() bool {
 func (fi *fakeFileInfo) Sys() interface{} {
 	return nil
 }
+
+func TestWaitForDevicePath(t *testing.T) {
+	defaultDevicePathPollInterval := devicePathPollInterval
+	devicePathPollInterval = 10 * time.Millisecond
+	defer func() { devicePathPollInterval = defaultDevicePathPollInterval }()
+
+	devicePath := "/dev/xvdaa"
+	nvmeDevicePath := "/dev/nvme1n1"
+	volumeID := "vol-test"
+	nvmeName := "/dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_voltest"
+	symlinkFileInfo := fs.FileInfo(&fakeFileInfo{nvmeName, os.ModeSymlink})
+
+	testCases := []struct {
+		name                  string
+		reattach              bool
+		deviceAttachTimeout   time.Duration
+		deviceReattachTimeout time.Duration
+		// lookupsUntilFound is the number of lookups after which the device appears
+		lookupsUntilFound int
+		expectError       bool
+	}{
+		{
+			name:              "first attach without timeout looks device up once",
+			lookupsUntilFound: 1,
+		},
+		{
+			name:              "first attach without timeout fails if device appears late",
+			lookupsUntilFound: 3,
+			expectError:       true,
+		},
+		{
+			name:                  "first attach waits for attach timeout",
+			deviceAttachTimeout:   time.Minute,
+			deviceReattachTimeout: 15 * time.Millisecond,
+			lookupsUntilFound:     5,
+		},
+		{
+			name:                  "first attach fails after attach timeout",
+			deviceAttachTimeout:   15 * time.Millisecond,
+			deviceReattachTimeout: time.Minute,
+			lookupsUntilFound:     5,
+			expectError:           true,
+		},
+		{
+			name:                  "reattach waits for reattach timeout",
+			reattach:              true,
+			deviceAttachTimeout:   15 * time.Millisecond,
+			deviceReattachTimeout: time.Minute,
+			lookupsUntilFound:     5,
+		},
+		{
+			name:                  "reattach fails after reattach timeout",
+			reattach:              true,
+			deviceAttachTimeout:   time.Minute,
+			deviceReattachTimeout: 15 * time.Millisecond,
+			lookupsUntilFound:     5,
+			expectError:           true,
+		},
+		{
+			name:                "reattach without reattach timeout waits for attach timeout",
+			reattach:            true,
+			deviceAttachTimeout: time.Minute,
+			lookupsUntilFound:   3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+			lookups := 0
+			mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(false, nil).AnyTimes()
+			mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).DoAndReturn(func(string) (os.FileInfo, error) {
+				lookups++
+				if lookups < tc.lookupsUntilFound {
+					return nil, os.ErrNotExist
+				}
+				return symlinkFileInfo, nil
+			}).AnyTimes()
+			mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(nvmeName)).Return(nvmeDevicePath, nil).AnyTimes()
+
+			mockMetadata := cloud.NewMockMetadataService(mockCtl)
+			mockMetadata.EXPECT().GetRegion().Return("us-west-2").AnyTimes()
+
+			nodeDriver := nodeService{
+				metadata:         mockMetadata,
+				mounter:          mockMounter,
+				deviceIdentifier: mockDeviceIdentifier,
+				inFlight:         internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					deviceAttachTimeout:   tc.deviceAttachTimeout,
+					deviceReattachTimeout: tc.deviceReattachTimeout,
+				},
+				unstagedVolumes: newUnstagedVolumes(),
+			}
+			if tc.reattach {
+				nodeDriver.unstagedVolumes.add(volumeID)
+			}
+
+			source, err := nodeDriver.waitForDevicePath(context.Background(), devicePath, volumeID, "")
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, nvmeDevicePath, source)
+		})
+	}
+}
+
+func TestWaitForDevicePathSCSIFallback(t *testing.T) {
+	defaultDevicePathPollInterval := devicePathPollInterval
+	devicePathPollInterval = 10 * time.Millisecond
+	defer func() { devicePathPollInterval = defaultDevicePathPollInterval }()
+
+	devicePath := "/dev/xvdaa"
+	scsiDevicePath := "/dev/sdaa"
+	nvmeDevicePath := "/dev/nvme1n1"
+	volumeID := "vol-test"
+	nvmeName := "/dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_voltest"
+	symlinkFileInfo := fs.FileInfo(&fakeFileInfo{nvmeName, os.ModeSymlink})
+
+	testCases := []struct {
+		name                string
+		deviceAttachTimeout time.Duration
+		scsiFallbackWait    time.Duration
+		partition           string
+		// nvmeLookupsUntilFound is the number of lookups after which the NVMe device appears, never if 0
+		nvmeLookupsUntilFound int
+		scsiExists            bool
+		expectedSource        string
+		expectError           bool
+	}{
+		{
+			name:             "falls back to SCSI device path after NVMe lookup fails",
+			scsiFallbackWait: 30 * time.Millisecond,
+			scsiExists:       true,
+			expectedSource:   scsiDevicePath,
+		},
+		{
+			name:             "falls back to SCSI device path with partition",
+			scsiFallbackWait: 30 * time.Millisecond,
+			partition:        "1",
+			scsiExists:       true,
+			expectedSource:   scsiDevicePath + "1",
+		},
+		{
+			name:                "falls back to SCSI device path within longer attach timeout",
+			deviceAttachTimeout: time.Minute,
+			scsiFallbackWait:    30 * time.Millisecond,
+			scsiExists:          true,
+			expectedSource:      scsiDevicePath,
+		},
+		{
+			name:                  "NVMe device found before fallback wait",
+			deviceAttachTimeout:   time.Minute,
+			scsiFallbackWait:      time.Minute,
+			nvmeLookupsUntilFound: 3,
+			scsiExists:            true,
+			expectedSource:        nvmeDevicePath,
+		},
+		{
+			name:             "fails if neither NVMe nor SCSI device is found",
+			scsiFallbackWait: 30 * time.Millisecond,
+			expectError:      true,
+		},
+		{
+			name:        "no fallback without fallback wait",
+			scsiExists:  true,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+			lookups := 0
+			mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(false, nil).AnyTimes()
+			mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).DoAndReturn(func(string) (os.FileInfo, error) {
+				lookups++
+				if tc.nvmeLookupsUntilFound == 0 || lookups < tc.nvmeLookupsUntilFound {
+					return nil, os.ErrNotExist
+				}
+				return symlinkFileInfo, nil
+			}).AnyTimes()
+			mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(nvmeName)).Return(nvmeDevicePath, nil).AnyTimes()
+			if tc.scsiFallbackWait > 0 && tc.nvmeLookupsUntilFound == 0 {
+				mockMounter.EXPECT().PathExists(gomock.Eq(scsiDevicePath)).Return(tc.scsiExists, nil).MinTimes(1)
+				if tc.scsiExists {
+					mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(scsiDevicePath)).Return(scsiDevicePath, nil)
+				}
+				if tc.partition != "" {
+					mockMounter.EXPECT().PathExists(gomock.Eq(scsiDevicePath+tc.partition)).Return(true, nil)
+				}
+			}
+
+			mockMetadata := cloud.NewMockMetadataService(mockCtl)
+			mockMetadata.EXPECT().GetRegion().Return("us-west-2").AnyTimes()
+
+			nodeDriver := nodeService{
+				metadata:         mockMetadata,
+				mounter:          mockMounter,
+				deviceIdentifier: mockDeviceIdentifier,
+				inFlight:         internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					deviceAttachTimeout: tc.deviceAttachTimeout,
+					scsiFallbackWait:    tc.scsiFallbackWait,
+				},
+				unstagedVolumes: newUnstagedVolumes(),
+			}
+
+			start := time.Now()
+			source, err := nodeDriver.waitForDevicePath(context.Background(), devicePath, volumeID, tc.partition)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSource, source)
+			if source != nvmeDevicePath && time.Since(start) < tc.scsiFallbackWait {
+				t.Fatalf("expected SCSI fallback only after %v, got it after %v", tc.scsiFallbackWait, time.Since(start))
+			}
+		})
+	}
+}
+
+func TestWaitForDevicePathPartition(t *testing.T) {
+	defaultDevicePathPollInterval := devicePathPollInterval
+	devicePathPollInterval = 10 * time.Millisecond
+	defer func() { devicePathPollInterval = defaultDevicePathPollInterval }()
+
+	devicePath := "/dev/xvdaa"
+	volumeID := "vol-test"
+	nvmeName := "/dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_voltest"
+	nvmeDevicePath := "/dev/nvme1n1"
+	partitionPath := "/dev/nvme1n1p2"
+	symlinkFileInfo := fs.FileInfo(&fakeFileInfo{nvmeName, os.ModeSymlink})
+
+	testCases := []struct {
+		name                string
+		deviceAttachTimeout time.Duration
+		// partitionLookupsUntilFound is the number of lookups after which the partition appears, never if 0
+		partitionLookupsUntilFound int
+		expectError                bool
+	}{
+		{
+			name:                       "waits for partition to appear",
+			deviceAttachTimeout:        time.Minute,
+			partitionLookupsUntilFound: 3,
+		},
+		{
+			name:                "fails if partition does not appear",
+			deviceAttachTimeout: 30 * time.Millisecond,
+			expectError:         true,
+		},
+		{
+			name:        "fails without attach timeout if partition does not exist",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+			lookups := 0
+			mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(false, nil).AnyTimes()
+			mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(symlinkFileInfo, nil).AnyTimes()
+			mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(nvmeName)).Return(nvmeDevicePath, nil).AnyTimes()
+			mockMounter.EXPECT().PathExists(gomock.Eq(partitionPath)).DoAndReturn(func(string) (bool, error) {
+				lookups++
+				return tc.partitionLookupsUntilFound > 0 && lookups >= tc.partitionLookupsUntilFound, nil
+			}).MinTimes(1)
+
+			nodeDriver := nodeService{
+				mounter:          mockMounter,
+				deviceIdentifier: mockDeviceIdentifier,
+				inFlight:         internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					deviceAttachTimeout: tc.deviceAttachTimeout,
+				},
+			}
+
+			source, err := nodeDriver.waitForDevicePath(context.Background(), devicePath, volumeID, "2")
+			if tc.expectError {
+				assert.ErrorContains(t, err, "partition \"/dev/nvme1n1p2\" not found")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, partitionPath, source)
+			assert.Equal(t, tc.partitionLookupsUntilFound, lookups)
+		})
+	}
+}
+
+func TestWaitForDevicePathByFilesystemUUID(t *testing.T) {
+	devicePath := "/dev/xvdaa"
+	volumeID := "vol-test"
+	uuid := "f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10"
+	nvmeName := "/dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_voltest"
+	uuidPath := "/dev/disk/by-uuid/" + uuid
+	nvmeDevicePath := "/dev/nvme1n1"
+	uuidDevicePath := "/dev/nvme2n1p1"
+	symlinkFileInfo := fs.FileInfo(&fakeFileInfo{nvmeName, os.ModeSymlink})
+
+	testCases := []struct {
+		name         string
+		enabled      bool
+		recordedUUID string
+		nvmeExists   bool
+		uuidExists   bool
+		// uuidDeviceSerial is the serial number the NVMe controller of the
+		// device found by UUID reports, none if empty
+		uuidDeviceSerial string
+		expectedSource   string
+		expectError      bool
+	}{
+		{
+			name:             "finds device by recorded filesystem UUID",
+			enabled:          true,
+			recordedUUID:     uuid,
+			uuidExists:       true,
+			uuidDeviceSerial: "voltest",
+			expectedSource:   uuidDevicePath,
+		},
+		{
+			name:             "fails if device found by filesystem UUID belongs to another volume",
+			enabled:          true,
+			recordedUUID:     uuid,
+			uuidExists:       true,
+			uuidDeviceSerial: "volother",
+			expectError:      true,
+		},
+		{
+			name:         "fails if volume of device found by filesystem UUID is unknown",
+			enabled:      true,
+			recordedUUID: uuid,
+			uuidExists:   true,
+			expectError:  true,
+		},
+		{
+			name:           "prefers device found by volume ID",
+			enabled:        true,
+			recordedUUID:   uuid,
+			nvmeExists:     true,
+			uuidExists:     true,
+			expectedSource: nvmeDevicePath,
+		},
+		{
+			name:         "fails if filesystem UUID is not found",
+			enabled:      true,
+			recordedUUID: uuid,
+			expectError:  true,
+		},
+		{
+			name:        "fails if no filesystem UUID is recorded",
+			enabled:     true,
+			uuidExists:  true,
+			expectError: true,
+		},
+		{
+			name:         "not found by filesystem UUID if disabled",
+			recordedUUID: uuid,
+			uuidExists:   true,
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+			mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(false, nil)
+			if tc.nvmeExists {
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(symlinkFileInfo, nil)
+				mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(nvmeName)).Return(nvmeDevicePath, nil)
+			} else {
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(nil, os.ErrNotExist)
+			}
+			if tc.uuidExists {
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(uuidPath)).Return(symlinkFileInfo, nil).AnyTimes()
+				mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(uuidPath)).Return(uuidDevicePath, nil).AnyTimes()
+			} else {
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(uuidPath)).Return(nil, os.ErrNotExist).AnyTimes()
+			}
+
+			defer func(path string) { sysBlockPath = path }(sysBlockPath)
+			sysBlockPath = t.TempDir()
+			defer func(path string) { sysClassNVMePath = path }(sysClassNVMePath)
+			sysClassNVMePath = t.TempDir()
+			if tc.uuidDeviceSerial != "" {
+				controllerPath := filepath.Join(sysBlockPath, "nvme2n1", "device")
+				if err := os.MkdirAll(controllerPath, 0755); err != nil {
+					t.Fatal(err)
+				}
+				for name, content := range map[string]string{"model": ebsNVMeModel, "serial": tc.uuidDeviceSerial} {
+					if err := os.WriteFile(filepath.Join(controllerPath, name), []byte(content+"\n"), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			mockMetadata := cloud.NewMockMetadataService(mockCtl)
+			mockMetadata.EXPECT().GetRegion().Return("us-west-2").AnyTimes()
+
+			nodeDriver := nodeService{
+				metadata:         mockMetadata,
+				mounter:          mockMounter,
+				deviceIdentifier: mockDeviceIdentifier,
+				inFlight:         internal.NewInFlight(),
+				driverOptions:    &DriverOptions{},
+				filesystemUUIDs:  newFilesystemUUIDs(tc.enabled, ""),
+			}
+			if tc.enabled && tc.recordedUUID != "" {
+				nodeDriver.filesystemUUIDs.store(volumeID, tc.recordedUUID)
+			}
+
+			source, err := nodeDriver.waitForDevicePath(context.Background(), devicePath, volumeID, "")
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSource, source)
+		})
+	}
+}
+
+func TestRecordFilesystemUUID(t *testing.T) {
+	source := "/dev/nvme1n1"
+	volumeID := "vol-test"
+	uuid := "f1c5a3a2-6f4e-4d6b-9f2a-3c1b2e8d7a10"
+
+	testCases := []struct {
+		name         string
+		enabled      bool
+		uuid         string
+		err          error
+		expectedUUID string
+	}{
+		{
+			name:         "records filesystem UUID",
+			enabled:      true,
+			uuid:         uuid,
+			expectedUUID: uuid,
+		},
+		{
+			name:    "records nothing for a device without filesystem",
+			enabled: true,
+		},
+		{
+			name:    "records nothing if blkid fails",
+			enabled: true,
+			err:     errors.New("blkid failed"),
+		},
+		{
+			name: "records nothing if disabled",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMounter := NewMockMounter(mockCtl)
+			if tc.enabled {
+				mockMounter.EXPECT().GetFilesystemUUID(gomock.Eq(source)).Return(tc.uuid, tc.err)
+			}
+
+			nodeDriver := nodeService{
+				mounter:         mockMounter,
+				driverOptions:   &DriverOptions{},
+				filesystemUUIDs: newFilesystemUUIDs(tc.enabled, ""),
+			}
+			nodeDriver.recordFilesystemUUID(mockMounter, volumeID, source)
+
+			if !tc.enabled {
+				assert.Nil(t, nodeDriver.filesystemUUIDs)
+				return
+			}
+			recorded, ok := nodeDriver.filesystemUUIDs.load(volumeID)
+			if tc.expectedUUID == "" {
+				assert.False(t, ok, "expected no filesystem UUID to be recorded, got %v", recorded)
+				return
+			}
+			assert.Equal(t, tc.expectedUUID, recorded)
+		})
+	}
+}
+
+func TestLegacyDevicePaths(t *testing.T) {
+	testCases := []struct {
+		devicePath string
+		expected   []string
+	}{
+		{devicePath: "/dev/xvdf", expected: []string{"/dev/xvdf", "/dev/sdf"}},
+		{devicePath: "/dev/sdba", expected: []string{"/dev/xvdba", "/dev/sdba"}},
+		{devicePath: "/dev/nvme1n1"},
+		{devicePath: "/dev/xvd"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.devicePath, func(t *testing.T) {
+			assert.Equal(t, tc.expected, legacyDevicePaths(tc.devicePath))
+		})
+	}
+}
@@ -25,11 +25,15 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
@@ -38,6 +42,7 @@ import (
 	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/mount-utils"
 )
 
 var (
@@ -47,6 +52,9 @@ var (
 )
 
 func TestNodeStageVolume(t *testing.T) {
+	defaultDeviceSizeCheckInterval := deviceSizeCheckInterval
+	deviceSizeCheckInterval = time.Millisecond
+	defer func() { deviceSizeCheckInterval = defaultDeviceSizeCheckInterval }()
 
 	var (
 		targetPath     = "/test/path"
@@ -80,11 +88,13 @@ func TestNodeStageVolume(t *testing.T) {
 		}
 	)
 	testCases := []struct {
-		name         string
-		request      *csi.NodeStageVolumeRequest
-		inFlightFunc func(*internal.InFlight) *internal.InFlight
-		expectMock   func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier)
-		expectedCode codes.Code
+		name                   string
+		request                *csi.NodeStageVolumeRequest
+		deviceSizeCheckTimeout time.Duration
+		skipResize             bool
+		inFlightFunc           func(*internal.InFlight) *internal.InFlight
+		expectMock             func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier)
+		expectedCode           codes.Code
 	}{
 		{
 			name: "success normal",
@@ -140,6 +150,29 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Eq([]string{"dirsync", "noexec"}), gomock.Nil(), gomock.Len(0))
 			},
 		},
+		{
+			name: "success with default mount options",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"relatime", "nodiscard"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+				VolumeContext: map[string]string{VolumeAttributeDefaultMountOptions: "noatime,lazytime,discard"},
+				VolumeId:      volumeID,
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				successExpectMock(mockMounter, mockDeviceIdentifier)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Eq([]string{"relatime", "nodiscard", "lazytime"}), gomock.Nil(), gomock.Len(0))
+			},
+		},
 		{
 			name: "success fsType ext3",
 			request: &csi.NodeStageVolumeRequest{
@@ -242,6 +275,7 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
 				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
 				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePathWithPartition)).Return(true, nil)
 
 				// The device path argument should be canonicalized to contain the
 				// partition
@@ -370,6 +404,120 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Eq([]string{"-O", "bigalloc", "-C", "16384"}))
 			},
 		},
+		{
+			name: "success device already formatted",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeId:          volumeID,
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().NeedResize(gomock.Eq(devicePath), gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeXfs, nil)
+			},
+		},
+		{
+			name: "success when existing filesystem cannot be detected",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeId:          volumeID,
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().NeedResize(gomock.Eq(devicePath), gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return("", errors.New("blkid failed"))
+			},
+		},
+		{
+			name: "success resize filesystem of volume expanded while detached",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeId:          volumeID,
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().NeedResize(gomock.Eq(devicePath), gomock.Eq(targetPath)).Return(true, nil)
+				mockResizefs := NewMockResizefs(mockMounter.ctrl)
+				mockMounter.EXPECT().NewResizeFs().Return(mockResizefs, nil)
+				mockResizefs.EXPECT().Resize(gomock.Eq(devicePath), gomock.Eq(targetPath)).Return(true, nil)
+			},
+		},
+		{
+			name: "success resize on stage disabled",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeId:          volumeID,
+			},
+			skipResize: true,
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().NeedResize(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "fail resize filesystem",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeId:          volumeID,
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().NeedResize(gomock.Eq(devicePath), gomock.Eq(targetPath)).Return(true, nil)
+				mockResizefs := NewMockResizefs(mockMounter.ctrl)
+				mockMounter.EXPECT().NewResizeFs().Return(mockResizefs, nil)
+				mockResizefs.EXPECT().Resize(gomock.Eq(devicePath), gomock.Eq(targetPath)).Return(false, errors.New("resize2fs failed"))
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name: "fail invalid block size",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeId:          volumeID,
+				VolumeContext:     map[string]string{BlockSizeKey: "1024 -E nodiscard"},
+			},
+			expectedCode: codes.InvalidArgument,
+		},
 		{
 			name: "fail no VolumeId",
 			request: &csi.NodeStageVolumeRequest{
@@ -458,6 +606,105 @@ func TestNodeStageVolume(t *testing.T) {
 			},
 			expectedCode: codes.Aborted,
 		},
+		{
+			name: "success device size matches volume size",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeContext:     map[string]string{VolumeAttributeSizeBytes: "1073741824"},
+				VolumeId:          volumeID,
+			},
+			deviceSizeCheckTimeout: time.Second,
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				successExpectMock(mockMounter, mockDeviceIdentifier)
+				mockMounter.EXPECT().GetBlockSizeBytes(gomock.Eq(devicePath)).Return(int64(1073741824), nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0))
+			},
+		},
+		{
+			name: "success device larger than volume size after expansion",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeContext:     map[string]string{VolumeAttributeSizeBytes: "1073741824"},
+				VolumeId:          volumeID,
+			},
+			deviceSizeCheckTimeout: time.Second,
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				successExpectMock(mockMounter, mockDeviceIdentifier)
+				mockMounter.EXPECT().GetBlockSizeBytes(gomock.Eq(devicePath)).Return(int64(2147483648), nil)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0))
+			},
+		},
+		{
+			name: "success device size recovers after mismatch",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeContext:     map[string]string{VolumeAttributeSizeBytes: "1073741824"},
+				VolumeId:          volumeID,
+			},
+			deviceSizeCheckTimeout: time.Second,
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				successExpectMock(mockMounter, mockDeviceIdentifier)
+				gomock.InOrder(
+					mockMounter.EXPECT().GetBlockSizeBytes(gomock.Eq(devicePath)).Return(int64(0), nil),
+					mockMounter.EXPECT().GetBlockSizeBytes(gomock.Eq(devicePath)).Return(int64(-1), errors.New("blockdev failed")),
+					mockMounter.EXPECT().GetBlockSizeBytes(gomock.Eq(devicePath)).Return(int64(1073741824), nil),
+				)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0))
+			},
+		},
+		{
+			name: "success device size not checked when disabled",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeContext:     map[string]string{VolumeAttributeSizeBytes: "1073741824"},
+				VolumeId:          volumeID,
+			},
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				successExpectMock(mockMounter, mockDeviceIdentifier)
+				mockMounter.EXPECT().GetBlockSizeBytes(gomock.Any()).Times(0)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0))
+			},
+		},
+		{
+			name: "fail device size mismatch persists",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeContext:     map[string]string{VolumeAttributeSizeBytes: "1073741824"},
+				VolumeId:          volumeID,
+			},
+			deviceSizeCheckTimeout: 50 * time.Millisecond,
+			expectMock: func(mockMounter MockMounter, mockDeviceIdentifier MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				mockMounter.EXPECT().GetBlockSizeBytes(gomock.Eq(devicePath)).Return(int64(536870912), nil).MinTimes(2)
+				mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name: "fail invalid volume size in volumeContext",
+			request: &csi.NodeStageVolumeRequest{
+				PublishContext:    map[string]string{DevicePathKey: devicePath},
+				StagingTargetPath: targetPath,
+				VolumeCapability:  stdVolCap,
+				VolumeContext:     map[string]string{VolumeAttributeSizeBytes: "1Gi"},
+				VolumeId:          volumeID,
+			},
+			expectedCode: codes.InvalidArgument,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -479,6 +726,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mounter:          mockMounter,
 				deviceIdentifier: mockDeviceIdentifier,
 				inFlight:         inFlight,
+				driverOptions: &DriverOptions{
+					deviceSizeCheckTimeout:      tc.deviceSizeCheckTimeout,
+					skipFilesystemResizeOnStage: tc.skipResize,
+				},
 			}
 
 			if tc.expectMock != nil {
@@ -518,6 +769,7 @@ func TestNodeUnstageVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					unstagedVolumes:  newUnstagedVolumes(),
 				}
 
 				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return(devicePath, 1, nil)
@@ -532,6 +784,9 @@ func TestNodeUnstageVolume(t *testing.T) {
 				if err != nil {
 					t.Fatalf("Expect no error but got: %v", err)
 				}
+				if !awsDriver.isReattach(volumeID) {
+					t.Fatalf("Expect volume %q to be recorded as unstaged", volumeID)
+				}
 			},
 		},
 		{
@@ -549,6 +804,7 @@ func TestNodeUnstageVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					unstagedVolumes:  newUnstagedVolumes(),
 				}
 
 				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return(devicePath, 0, nil)
@@ -561,6 +817,10 @@ func TestNodeUnstageVolume(t *testing.T) {
 				if err != nil {
 					t.Fatalf("Expect no error but got: %v", err)
 				}
+				// Block volumes are unstaged without a mount
+				if !awsDriver.isReattach(volumeID) {
+					t.Fatalf("Expect volume %q to be recorded as unstaged", volumeID)
+				}
 			},
 		},
 		{
@@ -1010,6 +1270,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				gomock.InOrder(
@@ -1058,6 +1319,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				gomock.InOrder(
@@ -1105,6 +1367,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				gomock.InOrder(
@@ -1152,6 +1415,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				gomock.InOrder(
@@ -1205,10 +1469,12 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				gomock.InOrder(
 					mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().PathExists(gomock.Eq(devicePathWithPartition)).Return(true, nil),
 					mockMounter.EXPECT().PathExists(gomock.Eq("/test")).Return(false, nil),
 				)
 				mockMounter.EXPECT().MakeDir(gomock.Eq("/test")).Return(nil)
@@ -1254,6 +1520,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				gomock.InOrder(
@@ -1303,6 +1570,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1340,6 +1608,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1376,6 +1645,7 @@ func TestNodePublishVolume(t *testing.T) {
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
+					driverOptions:    &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1610,17 +1880,17 @@ func TestNodeExpandVolume(t *testing.T) {
 
 	tests := []struct {
 		name               string
-		request            csi.NodeExpandVolumeRequest
+		request            *csi.NodeExpandVolumeRequest
 		expectResponseCode codes.Code
 	}{
 		{
 			name:               "fail missing volumeId",
-			request:            csi.NodeExpandVolumeRequest{},
+			request:            &csi.NodeExpandVolumeRequest{},
 			expectResponseCode: codes.InvalidArgument,
 		},
 		{
 			name: "fail missing volumePath",
-			request: csi.NodeExpandVolumeRequest{
+			request: &csi.NodeExpandVolumeRequest{
 				StagingTargetPath: "/testDevice/Path",
 				VolumeId:          "test-volume-id",
 			},
@@ -1628,7 +1898,7 @@ func TestNodeExpandVolume(t *testing.T) {
 		},
 		{
 			name: "fail volume path not exist",
-			request: csi.NodeExpandVolumeRequest{
+			request: &csi.NodeExpandVolumeRequest{
 				VolumePath: "./test",
 				VolumeId:   "test-volume-id",
 			},
@@ -1636,7 +1906,7 @@ func TestNodeExpandVolume(t *testing.T) {
 		},
 		{
 			name: "Fail validate VolumeCapability",
-			request: csi.NodeExpandVolumeRequest{
+			request: &csi.NodeExpandVolumeRequest{
 				VolumePath: "./test",
 				VolumeId:   "test-volume-id",
 				VolumeCapability: &csi.VolumeCapability{
@@ -1652,7 +1922,7 @@ func TestNodeExpandVolume(t *testing.T) {
 		},
 		{
 			name: "Success [VolumeCapability is block]",
-			request: csi.NodeExpandVolumeRequest{
+			request: &csi.NodeExpandVolumeRequest{
 				VolumePath: "./test",
 				VolumeId:   "test-volume-id",
 				VolumeCapability: &csi.VolumeCapability{
@@ -1670,7 +1940,7 @@ func TestNodeExpandVolume(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := awsDriver.NodeExpandVolume(context.Background(), &test.request)
+			_, err := awsDriver.NodeExpandVolume(context.Background(), test.request)
 			if err != nil {
 				if test.expectResponseCode != codes.OK {
 					expectErr(t, err, test.expectResponseCode)
@@ -1683,7 +1953,11 @@ func TestNodeExpandVolume(t *testing.T) {
 }
 
 func TestNodeUnpublishVolume(t *testing.T) {
-	targetPath := "/test/path"
+	var (
+		targetPath     = "/test/path"
+		devicePath     = "/dev/nvme1n1"
+		nvmeDevicePath = "/dev/nvme2n1"
+	)
 
 	testCases := []struct {
 		name     string
@@ -1711,6 +1985,10 @@ func TestNodeUnpublishVolume(t *testing.T) {
 					VolumeId:   volumeID,
 				}
 
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return(nvmeDevicePath, 1, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(symlinkFileInfo, nil)
+				mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(symlinkFileInfo.Name())).Return(nvmeDevicePath, nil)
 				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(nil)
 				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
 				if err != nil {
@@ -1719,7 +1997,7 @@ func TestNodeUnpublishVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "fail no VolumeId",
+			name: "success target does not exist",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1737,14 +2015,19 @@ func TestNodeUnpublishVolume(t *testing.T) {
 
 				req := &csi.NodeUnpublishVolumeRequest{
 					TargetPath: targetPath,
+					VolumeId:   volumeID,
 				}
 
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, nil)
+				mockMounter.EXPECT().Unpublish(gomock.Any()).Times(0)
 				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
-				expectErr(t, err, codes.InvalidArgument)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
 			},
 		},
 		{
-			name: "fail no TargetPath",
+			name: "success target is not mounted",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1761,15 +2044,21 @@ func TestNodeUnpublishVolume(t *testing.T) {
 				}
 
 				req := &csi.NodeUnpublishVolumeRequest{
-					VolumeId: volumeID,
+					TargetPath: targetPath,
+					VolumeId:   volumeID,
 				}
 
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return("", 0, nil)
+				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(nil)
 				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
-				expectErr(t, err, codes.InvalidArgument)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
 			},
 		},
 		{
-			name: "fail error on unpublish",
+			name: "success corrupted mount",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1790,13 +2079,18 @@ func TestNodeUnpublishVolume(t *testing.T) {
 					VolumeId:   volumeID,
 				}
 
-				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(errors.New("test Unpublish error"))
+				corruptedErr := errors.New("transport endpoint is not connected")
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, corruptedErr)
+				mockMounter.EXPECT().IsCorruptedMnt(gomock.Eq(corruptedErr)).Return(true)
+				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(nil)
 				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
-				expectErr(t, err, codes.Internal)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
 			},
 		},
 		{
-			name: "fail another operation in-flight on given volumeId",
+			name: "success device of volume is unknown",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1817,25 +2111,18 @@ func TestNodeUnpublishVolume(t *testing.T) {
 					VolumeId:   volumeID,
 				}
 
-				awsDriver.inFlight.Insert(volumeID)
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return(devicePath, 1, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(nil, os.ErrNotExist)
+				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(nil)
 				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
-				expectErr(t, err, codes.Aborted)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
-
-func TestNodeGetVolumeStats(t *testing.T) {
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
 		{
-			name: "success normal",
+			name: "fail target is mounted from another volume",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1843,34 +2130,432 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				mockMetadata := cloud.NewMockMetadataService(mockCtl)
 				mockMounter := NewMockMounter(mockCtl)
 				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
-				VolumePath := "./test"
-				err := os.MkdirAll(VolumePath, 0644)
-				if err != nil {
-					t.Fatalf("fail to create dir: %v", err)
-				}
-				defer os.RemoveAll(VolumePath)
 
-				mockMounter.EXPECT().PathExists(VolumePath).Return(true, nil)
-
-				awsDriver := nodeService{
+				awsDriver := &nodeService{
 					metadata:         mockMetadata,
 					mounter:          mockMounter,
 					deviceIdentifier: mockDeviceIdentifier,
 					inFlight:         internal.NewInFlight(),
 				}
 
-				req := &csi.NodeGetVolumeStatsRequest{
+				req := &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
 					VolumeId:   volumeID,
-					VolumePath: VolumePath,
 				}
-				_, err = awsDriver.NodeGetVolumeStats(context.TODO(), req)
-				if err != nil {
-					t.Fatalf("Expect no error but got: %v", err)
-				}
-			},
-		},
-		{
-			name: "fail path not exist",
+
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return(devicePath, 1, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(symlinkFileInfo, nil)
+				mockDeviceIdentifier.EXPECT().EvalSymlinks(gomock.Eq(symlinkFileInfo.Name())).Return(nvmeDevicePath, nil)
+				mockMounter.EXPECT().Unpublish(gomock.Any()).Times(0)
+				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "fail error checking target",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+				awsDriver := &nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
+					VolumeId:   volumeID,
+				}
+
+				pathErr := errors.New("permission denied")
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(false, pathErr)
+				mockMounter.EXPECT().IsCorruptedMnt(gomock.Eq(pathErr)).Return(false)
+				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "fail no VolumeId",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+				awsDriver := &nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
+				}
+
+				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail no TargetPath",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+				awsDriver := &nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeUnpublishVolumeRequest{
+					VolumeId: volumeID,
+				}
+
+				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail error on unpublish",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+				awsDriver := &nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
+					VolumeId:   volumeID,
+				}
+
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return("", 0, nil)
+				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(errors.New("test Unpublish error"))
+				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "fail another operation in-flight on given volumeId",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+				awsDriver := &nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
+					VolumeId:   volumeID,
+				}
+
+				awsDriver.inFlight.Insert(volumeID)
+				_, err := awsDriver.NodeUnpublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.Aborted)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestNodeMountNamespaceIsolation(t *testing.T) {
+	var (
+		targetPath        = "/test/path"
+		stagingTargetPath = "/test/staging/path"
+		devicePath        = "/dev/fake"
+		deviceFileInfo    = fs.FileInfo(&fakeFileInfo{devicePath, os.ModeDevice})
+		isolatedContext   = map[string]string{IsolateMountNamespaceKey: "true"}
+		stdVolCap         = &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{
+				Mount: &csi.VolumeCapability_MountVolume{
+					FsType: FSTypeExt4,
+				},
+			},
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			},
+		}
+		stageExpectMock = func(mockMounter *MockMounter) {
+			mockMounter.EXPECT().PathExists(gomock.Eq(stagingTargetPath)).Return(false, nil)
+			mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
+			mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(stagingTargetPath)).Return("", 0, nil)
+			mockMounter.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq(devicePath), gomock.Eq(stagingTargetPath), gomock.Eq(defaultFsType), gomock.Any(), gomock.Nil(), gomock.Len(0)).Return(false, nil)
+			mockMounter.EXPECT().NeedResize(gomock.Eq(devicePath), gomock.Eq(stagingTargetPath)).Return(false, nil)
+		}
+	)
+
+	testCases := []struct {
+		name string
+		// noNamespace leaves the node without a configured mount namespace
+		noNamespace  bool
+		expectMock   func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier)
+		call         func(d *nodeService) error
+		expectedCode codes.Code
+	}{
+		{
+			name: "stage flagged volume in mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				stageExpectMock(mockNamespaceMounter)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodeStageVolume(context.TODO(), &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: stagingTargetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeContext:     isolatedContext,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name: "stage normal volume outside mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockMounter.EXPECT().PathExists(gomock.Eq(devicePath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(devicePath)).Return(deviceFileInfo, nil)
+				stageExpectMock(mockMounter)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodeStageVolume(context.TODO(), &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: stagingTargetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name:        "fail stage flagged volume without mount namespace",
+			noNamespace: true,
+			call: func(d *nodeService) error {
+				_, err := d.NodeStageVolume(context.TODO(), &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: stagingTargetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeContext:     isolatedContext,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name: "publish flagged volume in mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
+				mockNamespaceMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
+				mockNamespaceMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Eq([]string{"bind"})).Return(nil)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodePublishVolume(context.TODO(), &csi.NodePublishVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: stagingTargetPath,
+					TargetPath:        targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeContext:     isolatedContext,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name: "publish normal volume outside mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Eq([]string{"bind"})).Return(nil)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodePublishVolume(context.TODO(), &csi.NodePublishVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: stagingTargetPath,
+					TargetPath:        targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name: "unstage volume mounted in mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockNamespaceMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(stagingTargetPath)).Return(devicePath, 1, nil).Times(2)
+				mockNamespaceMounter.EXPECT().Unstage(gomock.Eq(stagingTargetPath)).Return(nil)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodeUnstageVolume(context.TODO(), &csi.NodeUnstageVolumeRequest{
+					StagingTargetPath: stagingTargetPath,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name: "unstage volume mounted outside mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockNamespaceMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(stagingTargetPath)).Return("", 0, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(stagingTargetPath)).Return(devicePath, 1, nil)
+				mockMounter.EXPECT().Unstage(gomock.Eq(stagingTargetPath)).Return(nil)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodeUnstageVolume(context.TODO(), &csi.NodeUnstageVolumeRequest{
+					StagingTargetPath: stagingTargetPath,
+					VolumeId:          volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name: "unpublish volume mounted in mount namespace",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockNamespaceMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return(devicePath, 2, nil).Times(2)
+				mockNamespaceMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockDeviceIdentifier.EXPECT().Lstat(gomock.Eq(nvmeName)).Return(nil, os.ErrNotExist)
+				mockNamespaceMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(nil)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
+					VolumeId:   volumeID,
+				})
+				return err
+			},
+		},
+		{
+			name: "unpublish volume when mount namespace cannot be checked",
+			expectMock: func(mockMounter, mockNamespaceMounter *MockMounter, mockDeviceIdentifier *MockDeviceIdentifier) {
+				mockNamespaceMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return("", 0, errors.New("nsenter failed"))
+				mockMounter.EXPECT().PathExists(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDeviceNameFromMount(gomock.Eq(targetPath)).Return("", 0, nil)
+				mockMounter.EXPECT().Unpublish(gomock.Eq(targetPath)).Return(nil)
+			},
+			call: func(d *nodeService) error {
+				_, err := d.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{
+					TargetPath: targetPath,
+					VolumeId:   volumeID,
+				})
+				return err
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockNamespaceMounter := NewMockMounter(mockCtl)
+			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+
+			awsDriver := &nodeService{
+				metadata:         cloud.NewMockMetadataService(mockCtl),
+				mounter:          mockMounter,
+				deviceIdentifier: mockDeviceIdentifier,
+				inFlight:         internal.NewInFlight(),
+				driverOptions:    &DriverOptions{mountNamespace: "/proc/1/ns/mnt"},
+				namespaceMounter: mockNamespaceMounter,
+			}
+			if tc.noNamespace {
+				awsDriver.driverOptions = &DriverOptions{}
+				awsDriver.namespaceMounter = nil
+			}
+
+			if tc.expectMock != nil {
+				tc.expectMock(mockMounter, mockNamespaceMounter, mockDeviceIdentifier)
+			}
+
+			err := tc.call(awsDriver)
+			if tc.expectedCode != codes.OK {
+				expectErr(t, err, tc.expectedCode)
+			} else if err != nil {
+				t.Fatalf("Expect no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNodeGetVolumeStats(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+				VolumePath := "./test"
+				err := os.MkdirAll(VolumePath, 0644)
+				if err != nil {
+					t.Fatalf("fail to create dir: %v", err)
+				}
+				defer os.RemoveAll(VolumePath)
+
+				mockMounter.EXPECT().PathExists(VolumePath).Return(true, nil)
+
+				awsDriver := nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:   volumeID,
+					VolumePath: VolumePath,
+				}
+				_, err = awsDriver.NodeGetVolumeStats(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail path not exist",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1882,83 +2567,423 @@ func TestNodeGetVolumeStats(t *testing.T) {
 
 				mockMounter.EXPECT().PathExists(VolumePath).Return(false, nil)
 
-				awsDriver := nodeService{
-					metadata:         mockMetadata,
-					mounter:          mockMounter,
-					deviceIdentifier: mockDeviceIdentifier,
-					inFlight:         internal.NewInFlight(),
-				}
+				awsDriver := nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:   volumeID,
+					VolumePath: VolumePath,
+				}
+				_, err := awsDriver.NodeGetVolumeStats(context.TODO(), req)
+				expectErr(t, err, codes.NotFound)
+			},
+		},
+		{
+			name: "fail can't determine block device",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+				VolumePath := "/test"
+
+				mockMounter.EXPECT().PathExists(VolumePath).Return(true, nil)
+
+				awsDriver := nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:   volumeID,
+					VolumePath: VolumePath,
+				}
+				_, err := awsDriver.NodeGetVolumeStats(context.TODO(), req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "fail error calling existsPath",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := cloud.NewMockMetadataService(mockCtl)
+				mockMounter := NewMockMounter(mockCtl)
+				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+				VolumePath := "/test"
+
+				mockMounter.EXPECT().PathExists(VolumePath).Return(false, errors.New("get existsPath call fail"))
+
+				awsDriver := nodeService{
+					metadata:         mockMetadata,
+					mounter:          mockMounter,
+					deviceIdentifier: mockDeviceIdentifier,
+					inFlight:         internal.NewInFlight(),
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:   volumeID,
+					VolumePath: VolumePath,
+				}
+				_, err := awsDriver.NodeGetVolumeStats(context.TODO(), req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+
+}
+
+func TestNodeGetVolumeStatsMountOptions(t *testing.T) {
+	volumePath := t.TempDir()
+	stagingPath := "/var/lib/kubelet/plugins/kubernetes.io/csi/ebs.csi.aws.com/abc/globalmount"
+
+	testCases := []struct {
+		name              string
+		stagingTargetPath string
+		procMounts        string
+		// namespaceMounts are the mounts of the mount namespace of isolated volumes, if one is configured
+		namespaceMounts   []mount.MountPoint
+		namespaceErr      error
+		expectedCondition *csi.VolumeCondition
+	}{
+		{
+			name:              "staged volume",
+			stagingTargetPath: stagingPath,
+			procMounts: "/dev/nvme1n1 " + stagingPath + " ext4 rw,noatime,data=ordered 0 0\n" +
+				"/dev/nvme1n1 " + volumePath + " ext4 ro,relatime 0 0\n",
+			expectedCondition: &csi.VolumeCondition{Message: "Mount options in effect: rw,noatime,data=ordered"},
+		},
+		{
+			name:              "last of stacked mounts",
+			stagingTargetPath: stagingPath + "/",
+			procMounts: "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n" +
+				"/dev/nvme2n1 " + stagingPath + " xfs rw,noatime 0 0\n",
+			expectedCondition: &csi.VolumeCondition{Message: "Mount options in effect: rw,noatime"},
+		},
+		{
+			name:              "staged volume remounted read-only",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme1n1 " + stagingPath + " ext4 ro,relatime 0 0\n",
+			expectedCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  "File system of the volume is mounted read-only, e.g. after I/O errors; Mount options in effect: ro,relatime",
+			},
+		},
+		{
+			name:              "volume path without staging target path",
+			procMounts:        "/dev/nvme1n1 " + volumePath + " ext4 ro,relatime 0 0\n",
+			expectedCondition: &csi.VolumeCondition{Message: "Mount options in effect: ro,relatime"},
+		},
+		{
+			name:              "staged volume not mounted",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme1n1 " + volumePath + " ext4 ro,relatime 0 0\n",
+		},
+		{
+			name:              "volume staged in mount namespace",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme2n1 /var/lib/kubelet ext4 rw,relatime 0 0\n",
+			namespaceMounts: []mount.MountPoint{
+				{Device: "/dev/nvme1n1", Path: stagingPath, Type: "ext4", Opts: []string{"ro", "relatime"}},
+			},
+			expectedCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  "File system of the volume is mounted read-only, e.g. after I/O errors; Mount options in effect: ro,relatime",
+			},
+		},
+		{
+			name:              "volume staged outside of mount namespace",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n",
+			namespaceMounts: []mount.MountPoint{
+				{Device: "/dev/nvme2n1", Path: "/var/lib/kubelet", Type: "ext4", Opts: []string{"ro", "relatime"}},
+			},
+			expectedCondition: &csi.VolumeCondition{Message: "Mount options in effect: rw,relatime"},
+		},
+		{
+			name:              "mounts of mount namespace cannot be listed",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n",
+			namespaceErr:      errors.New("nsenter failed"),
+			expectedCondition: &csi.VolumeCondition{Message: "Mount options in effect: rw,relatime"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			procMounts := filepath.Join(t.TempDir(), "mounts")
+			if err := os.WriteFile(procMounts, []byte(tc.procMounts), 0600); err != nil {
+				t.Fatalf("failed to write fake /proc/mounts: %v", err)
+			}
+			defer func(path string) { procMountsPath = path }(procMountsPath)
+			procMountsPath = procMounts
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockMounter.EXPECT().PathExists(volumePath).Return(true, nil)
+			mockMounter.EXPECT().PathExists(gomock.Any()).Return(true, nil).AnyTimes()
+
+			awsDriver := nodeService{
+				metadata:         cloud.NewMockMetadataService(mockCtl),
+				mounter:          mockMounter,
+				deviceIdentifier: NewMockDeviceIdentifier(mockCtl),
+				inFlight:         internal.NewInFlight(),
+				driverOptions:    &DriverOptions{},
+			}
+			if tc.namespaceMounts != nil || tc.namespaceErr != nil {
+				mockNamespaceMounter := NewMockMounter(mockCtl)
+				mockNamespaceMounter.EXPECT().List().Return(tc.namespaceMounts, tc.namespaceErr).AnyTimes()
+				awsDriver.namespaceMounter = mockNamespaceMounter
+			}
+
+			resp, err := awsDriver.NodeGetVolumeStats(context.TODO(), &csi.NodeGetVolumeStatsRequest{
+				VolumeId:          volumeID,
+				VolumePath:        volumePath,
+				StagingTargetPath: tc.stagingTargetPath,
+			})
+			if err != nil {
+				t.Fatalf("Expect no error but got: %v", err)
+			}
+			if (resp.GetVolumeCondition() == nil) != (tc.expectedCondition == nil) ||
+				resp.GetVolumeCondition().GetMessage() != tc.expectedCondition.GetMessage() {
+				t.Fatalf("Expected volume condition %v, got %v", tc.expectedCondition, resp.GetVolumeCondition())
+			}
+		})
+	}
+}
+
+func TestNodeGetVolumeStatsDetachedWhileMounted(t *testing.T) {
+	volumePath := t.TempDir()
+	stagingPath := "/var/lib/kubelet/plugins/kubernetes.io/csi/ebs.csi.aws.com/abc/globalmount"
 
-				req := &csi.NodeGetVolumeStatsRequest{
-					VolumeId:   volumeID,
-					VolumePath: VolumePath,
-				}
-				_, err := awsDriver.NodeGetVolumeStats(context.TODO(), req)
-				expectErr(t, err, codes.NotFound)
+	testCases := []struct {
+		name                   string
+		stagingTargetPath      string
+		procMounts             string
+		deviceExists           bool
+		unmountDetachedVolumes bool
+		unmountErr             error
+		expectUnmount          []string
+		expectedCondition      *csi.VolumeCondition
+	}{
+		{
+			name:              "attached volume",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n",
+			deviceExists:      true,
+			expectedCondition: &csi.VolumeCondition{Message: "Mount options in effect: rw,relatime"},
+		},
+		{
+			name:              "staged volume detached while mounted",
+			stagingTargetPath: stagingPath,
+			procMounts:        "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n",
+			expectedCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  "Device /dev/nvme1n1 of the volume was detached from the node while mounted",
 			},
 		},
 		{
-			name: "fail can't determine block device",
-			testFunc: func(t *testing.T) {
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
-
-				mockMetadata := cloud.NewMockMetadataService(mockCtl)
-				mockMounter := NewMockMounter(mockCtl)
-				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
-				VolumePath := "/test"
-
-				mockMounter.EXPECT().PathExists(VolumePath).Return(true, nil)
-
-				awsDriver := nodeService{
-					metadata:         mockMetadata,
-					mounter:          mockMounter,
-					deviceIdentifier: mockDeviceIdentifier,
-					inFlight:         internal.NewInFlight(),
-				}
-
-				req := &csi.NodeGetVolumeStatsRequest{
-					VolumeId:   volumeID,
-					VolumePath: VolumePath,
-				}
-				_, err := awsDriver.NodeGetVolumeStats(context.TODO(), req)
-				expectErr(t, err, codes.Internal)
+			name:       "published volume detached while mounted",
+			procMounts: "/dev/xvdba " + volumePath + " xfs rw,relatime 0 0\n",
+			expectedCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  "Device /dev/xvdba of the volume was detached from the node while mounted",
 			},
 		},
 		{
-			name: "fail error calling existsPath",
-			testFunc: func(t *testing.T) {
-				mockCtl := gomock.NewController(t)
-				defer mockCtl.Finish()
+			name:                   "detached volume is unmounted",
+			stagingTargetPath:      stagingPath,
+			procMounts:             "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n",
+			unmountDetachedVolumes: true,
+			expectUnmount:          []string{volumePath, stagingPath},
+			expectedCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  "Device /dev/nvme1n1 of the volume was detached from the node while mounted, unmounted it",
+			},
+		},
+		{
+			name:                   "detached volume fails to unmount",
+			stagingTargetPath:      stagingPath,
+			procMounts:             "/dev/nvme1n1 " + stagingPath + " ext4 rw,relatime 0 0\n",
+			unmountDetachedVolumes: true,
+			unmountErr:             errors.New("umount failed"),
+			expectUnmount:          []string{volumePath},
+			expectedCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  "Device /dev/nvme1n1 of the volume was detached from the node while mounted, could not unmount it: umount failed",
+			},
+		},
+		{
+			name:                   "volume not mounted from a device",
+			procMounts:             "tmpfs " + volumePath + " tmpfs rw 0 0\n",
+			unmountDetachedVolumes: true,
+			expectedCondition:      &csi.VolumeCondition{Message: "Mount options in effect: rw"},
+		},
+	}
 
-				mockMetadata := cloud.NewMockMetadataService(mockCtl)
-				mockMounter := NewMockMounter(mockCtl)
-				mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
-				VolumePath := "/test"
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
 
-				mockMounter.EXPECT().PathExists(VolumePath).Return(false, errors.New("get existsPath call fail"))
+			procMounts := filepath.Join(t.TempDir(), "mounts")
+			if err := os.WriteFile(procMounts, []byte(tc.procMounts), 0600); err != nil {
+				t.Fatalf("failed to write fake /proc/mounts: %v", err)
+			}
+			defer func(path string) { procMountsPath = path }(procMountsPath)
+			procMountsPath = procMounts
 
-				awsDriver := nodeService{
-					metadata:         mockMetadata,
-					mounter:          mockMounter,
-					deviceIdentifier: mockDeviceIdentifier,
-					inFlight:         internal.NewInFlight(),
-				}
+			mockMounter := NewMockMounter(mockCtl)
+			mockMounter.EXPECT().PathExists(volumePath).Return(true, nil)
+			mockMounter.EXPECT().PathExists(gomock.Any()).Return(tc.deviceExists, nil).AnyTimes()
+			for _, path := range tc.expectUnmount {
+				mockMounter.EXPECT().UnmountLazy(path).Return(tc.unmountErr)
+			}
 
-				req := &csi.NodeGetVolumeStatsRequest{
-					VolumeId:   volumeID,
-					VolumePath: VolumePath,
+			awsDriver := nodeService{
+				metadata:         cloud.NewMockMetadataService(mockCtl),
+				mounter:          mockMounter,
+				deviceIdentifier: NewMockDeviceIdentifier(mockCtl),
+				inFlight:         internal.NewInFlight(),
+				driverOptions:    &DriverOptions{unmountDetachedVolumes: tc.unmountDetachedVolumes},
+			}
+
+			resp, err := awsDriver.NodeGetVolumeStats(context.TODO(), &csi.NodeGetVolumeStatsRequest{
+				VolumeId:          volumeID,
+				VolumePath:        volumePath,
+				StagingTargetPath: tc.stagingTargetPath,
+			})
+			if err != nil {
+				t.Fatalf("Expect no error but got: %v", err)
+			}
+			if resp.GetVolumeCondition().GetAbnormal() != tc.expectedCondition.GetAbnormal() ||
+				resp.GetVolumeCondition().GetMessage() != tc.expectedCondition.GetMessage() {
+				t.Fatalf("Expected volume condition %v, got %v", tc.expectedCondition, resp.GetVolumeCondition())
+			}
+			if tc.expectedCondition.GetAbnormal() {
+				expUsage := []*csi.VolumeUsage{
+					{Unit: csi.VolumeUsage_BYTES},
+					{Unit: csi.VolumeUsage_INODES},
 				}
-				_, err := awsDriver.NodeGetVolumeStats(context.TODO(), req)
-				expectErr(t, err, codes.Internal)
+				if !reflect.DeepEqual(resp.GetUsage(), expUsage) {
+					t.Fatalf("Expected zeroed usage for detached volume, got %v", resp.GetUsage())
+				}
+			}
+		})
+	}
+}
+
+func TestNodeGetVolumeStatsIOUtilization(t *testing.T) {
+	volumePath := t.TempDir()
+	stagingPath := "/var/lib/kubelet/plugins/kubernetes.io/csi/ebs.csi.aws.com/abc/globalmount"
+	const mountOptions = "Mount options in effect: rw,relatime"
+
+	testCases := []struct {
+		name                string
+		reportIOUtilization bool
+		stats               []string
+		evalSymlinksErr     error
+		expectedMessages    []string
+	}{
+		{
+			name:                "utilization between calls",
+			reportIOUtilization: true,
+			stats: []string{
+				"    1000        0    20480      100     2000        0    40960      200        0      300      300\n",
+				"   16000        0  1249280     1600    17000        0  1269760     1700        0     3300     3300\n",
+			},
+			expectedMessages: []string{
+				mountOptions,
+				mountOptions + "; Observed I/O: 3000 IOPS, 120.0 MiB/s over 10s",
 			},
 		},
+		{
+			name:                "device not resolved",
+			reportIOUtilization: true,
+			stats:               []string{"0 0 0 0 0 0 0 0 0 0 0\n", "0 0 0 0 0 0 0 0 0 0 0\n"},
+			evalSymlinksErr:     errors.New("no such file or directory"),
+			expectedMessages:    []string{mountOptions, mountOptions},
+		},
+		{
+			name:             "disabled",
+			stats:            []string{"0 0 0 0 0 0 0 0 0 0 0\n", "1 0 2048 0 0 0 0 0 0 0 0\n"},
+			expectedMessages: []string{mountOptions, mountOptions},
+		},
 	}
 
 	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			procMounts := filepath.Join(t.TempDir(), "mounts")
+			if err := os.WriteFile(procMounts, []byte("/dev/xvdba "+stagingPath+" ext4 rw,relatime 0 0\n"), 0600); err != nil {
+				t.Fatalf("failed to write fake /proc/mounts: %v", err)
+			}
+			defer func(path string) { procMountsPath = path }(procMountsPath)
+			procMountsPath = procMounts
+
+			defer func(path string) { sysBlockPath = path }(sysBlockPath)
+			sysBlockPath = t.TempDir()
+			statPath := filepath.Join(sysBlockPath, "nvme1n1", "stat")
+			if err := os.MkdirAll(filepath.Dir(statPath), 0755); err != nil {
+				t.Fatalf("failed to create fake block device statistics: %v", err)
+			}
+
+			mockMounter := NewMockMounter(mockCtl)
+			mockMounter.EXPECT().PathExists(volumePath).Return(true, nil).Times(len(tc.stats))
+			mockMounter.EXPECT().PathExists(gomock.Any()).Return(true, nil).AnyTimes()
+			mockDeviceIdentifier := NewMockDeviceIdentifier(mockCtl)
+			mockDeviceIdentifier.EXPECT().EvalSymlinks("/dev/xvdba").Return("/dev/nvme1n1", tc.evalSymlinksErr).AnyTimes()
+
+			now := time.Unix(1700000000, 0)
+			ioStats := newIOStatsTracker(tc.reportIOUtilization)
+			if ioStats != nil {
+				ioStats.now = func() time.Time { return now }
+			}
+			awsDriver := nodeService{
+				metadata:         cloud.NewMockMetadataService(mockCtl),
+				mounter:          mockMounter,
+				deviceIdentifier: mockDeviceIdentifier,
+				inFlight:         internal.NewInFlight(),
+				driverOptions:    &DriverOptions{},
+				ioStats:          ioStats,
+			}
 
+			for i, stats := range tc.stats {
+				if err := os.WriteFile(statPath, []byte(stats), 0600); err != nil {
+					t.Fatalf("failed to write fake block device statistics: %v", err)
+				}
+				resp, err := awsDriver.NodeGetVolumeStats(context.TODO(), &csi.NodeGetVolumeStatsRequest{
+					VolumeId:          volumeID,
+					VolumePath:        volumePath,
+					StagingTargetPath: stagingPath,
+				})
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				if resp.GetVolumeCondition().GetMessage() != tc.expectedMessages[i] {
+					t.Fatalf("Expected volume condition message %q on call %d, got %q", tc.expectedMessages[i], i, resp.GetVolumeCondition().GetMessage())
+				}
+				now = now.Add(10 * time.Second)
+			}
+		})
+	}
 }
 
 func TestNodeGetCapabilities(t *testing.T) {
@@ -1998,6 +3023,13 @@ func TestNodeGetCapabilities(t *testing.T) {
 				},
 			},
 		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+				},
+			},
+		},
 	}
 	expResp := &csi.NodeGetCapabilitiesResponse{Capabilities: caps}
 
@@ -2354,6 +3386,282 @@ func TestNodeGetInfo(t *testing.T) {
 	}
 }
 
+func TestNodeGetInfoAvailabilityZoneValidation(t *testing.T) {
+	testCases := []struct {
+		name             string
+		availabilityZone string
+		region           string
+		envRegion        string
+		override         string
+		expZone          string
+		expErr           bool
+	}{
+		{
+			name:             "success zone in region",
+			availabilityZone: "us-west-2b",
+			region:           "us-west-2",
+			expZone:          "us-west-2b",
+		},
+		{
+			name:             "success zone in region from AWS_REGION",
+			availabilityZone: "eu-central-1a",
+			region:           "us-west-2",
+			envRegion:        "eu-central-1",
+			expZone:          "eu-central-1a",
+		},
+		{
+			name:             "fail zone not in region",
+			availabilityZone: "us-east-1a",
+			region:           "us-west-2",
+			expErr:           true,
+		},
+		{
+			name:             "success zone not in region with override",
+			availabilityZone: "us-east-1a",
+			region:           "us-west-2",
+			override:         "us-west-2c",
+			expZone:          "us-west-2c",
+		},
+		{
+			name:             "fail zone and override not in region",
+			availabilityZone: "us-east-1a",
+			region:           "us-west-2",
+			override:         "us-east-1b",
+			expErr:           true,
+		},
+		{
+			name:             "success override ignored when zone in region",
+			availabilityZone: "us-west-2b",
+			region:           "us-west-2",
+			override:         "us-west-2c",
+			expZone:          "us-west-2b",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", tc.envRegion)
+
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMetadata := cloud.NewMockMetadataService(mockCtl)
+			mockMetadata.EXPECT().GetInstanceID().Return("i-123456789abcdef01").AnyTimes()
+			mockMetadata.EXPECT().GetAvailabilityZone().Return(tc.availabilityZone)
+			mockMetadata.EXPECT().GetOutpostArn().Return(arn.ARN{}).AnyTimes()
+			mockMetadata.EXPECT().GetRegion().Return(tc.region).AnyTimes()
+
+			awsDriver := &nodeService{
+				metadata:         mockMetadata,
+				mounter:          NewMockMounter(mockCtl),
+				deviceIdentifier: NewMockDeviceIdentifier(mockCtl),
+				inFlight:         internal.NewInFlight(),
+				driverOptions: &DriverOptions{
+					volumeAttachLimit:        42,
+					availabilityZoneOverride: tc.override,
+				},
+			}
+
+			resp, err := awsDriver.NodeGetInfo(context.TODO(), &csi.NodeGetInfoRequest{})
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("Expected error, got response %v", resp)
+				}
+				expectErr(t, err, codes.FailedPrecondition)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected nil error, got %v", err)
+			}
+
+			if zone := resp.GetAccessibleTopology().Segments[TopologyKey]; zone != tc.expZone {
+				t.Fatalf("Expected topology %q, got %q", tc.expZone, zone)
+			}
+		})
+	}
+}
+
+func TestZoneInRegion(t *testing.T) {
+	testCases := []struct {
+		zone     string
+		region   string
+		expected bool
+	}{
+		{zone: "us-west-2a", region: "us-west-2", expected: true},
+		{zone: "us-west-2-lax-1a", region: "us-west-2", expected: true},
+		{zone: "snow", region: "snow", expected: true},
+		{zone: "us-west-2a", region: "", expected: true},
+		{zone: "us-east-1a", region: "us-west-2", expected: false},
+		{zone: "us-west-2", region: "us-west-2", expected: true},
+		{zone: "us-west-21a", region: "us-west-2", expected: false},
+		{zone: "us-west-2a", region: "us-west-1", expected: false},
+		{zone: "", region: "us-west-2", expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.zone+"/"+tc.region, func(t *testing.T) {
+			if got := zoneInRegion(tc.zone, tc.region); got != tc.expected {
+				t.Fatalf("zoneInRegion(%q, %q) = %v, expected %v", tc.zone, tc.region, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFormatSkipReason(t *testing.T) {
+	testCases := []struct {
+		name           string
+		existingFormat string
+		mountOptions   []string
+		expReason      string
+	}{
+		{
+			name:      "unformatted device is formatted",
+			expReason: "",
+		},
+		{
+			name:           "already formatted",
+			existingFormat: FSTypeExt4,
+			expReason:      formatSkippedAlreadyFormatted,
+		},
+		{
+			name:           "already formatted and read-only",
+			existingFormat: FSTypeXfs,
+			mountOptions:   []string{"ro"},
+			expReason:      formatSkippedAlreadyFormatted,
+		},
+		{
+			name:         "read-only",
+			mountOptions: []string{"noatime", "ro"},
+			expReason:    formatSkippedReadOnly,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := formatSkipReason(tc.existingFormat, tc.mountOptions)
+			if reason != tc.expReason {
+				t.Fatalf("expected reason %q, got %q", tc.expReason, reason)
+			}
+		})
+	}
+}
+
+func TestRetrieveMetadata(t *testing.T) {
+	transientErr := awserr.New("EC2MetadataRequestError", "failed to get EC2 instance identity document",
+		awserr.NewRequestFailure(awserr.New("EC2MetadataError", "failed to make EC2Metadata request", nil), 503, ""))
+	permanentErr := fmt.Errorf("could not get valid EC2 instance ID")
+
+	testCases := []struct {
+		name          string
+		retryAttempts int
+		errs          []error
+		expectedCalls int
+		expectErr     bool
+	}{
+		{
+			name:          "success on first attempt",
+			retryAttempts: 3,
+			expectedCalls: 1,
+		},
+		{
+			name:          "success after transient failures",
+			retryAttempts: 3,
+			errs:          []error{transientErr, cloud.ErrMetadataUnavailable},
+			expectedCalls: 3,
+		},
+		{
+			name:          "fail when retries are exhausted",
+			retryAttempts: 2,
+			errs:          []error{transientErr, transientErr, transientErr, transientErr},
+			expectedCalls: 3,
+			expectErr:     true,
+		},
+		{
+			name:          "fail without retrying when retries are disabled",
+			retryAttempts: 0,
+			errs:          []error{transientErr},
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+		{
+			name:          "fail without retrying a permanent failure",
+			retryAttempts: 3,
+			errs:          []error{permanentErr},
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+	}
+
+	defaultBackoff := transientRetryBackoff
+	transientRetryBackoff.Duration = time.Millisecond
+	defer func() { transientRetryBackoff = defaultBackoff }()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			newMetadata := func() (cloud.MetadataService, error) {
+				calls++
+				if calls <= len(tc.errs) {
+					return nil, tc.errs[calls-1]
+				}
+				return &cloud.Metadata{InstanceID: "i-abcdefgh123456789"}, nil
+			}
+
+			metadata, err := retrieveMetadata(context.Background(), newMetadata, tc.retryAttempts)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("Expected error, got nil")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if metadata.GetInstanceID() != "i-abcdefgh123456789" {
+					t.Fatalf("Unexpected instance ID: %s", metadata.GetInstanceID())
+				}
+			}
+			if calls != tc.expectedCalls {
+				t.Fatalf("Expected %d attempts, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestGetMetadataAfterTransientFailure(t *testing.T) {
+	defaultBackoff := transientRetryBackoff
+	transientRetryBackoff.Duration = time.Millisecond
+	defer func() { transientRetryBackoff = defaultBackoff }()
+
+	calls := 0
+	awsDriver := &nodeService{
+		driverOptions: &DriverOptions{},
+		newMetadata: func() (cloud.MetadataService, error) {
+			calls++
+			if calls == 1 {
+				return nil, cloud.ErrMetadataUnavailable
+			}
+			return &cloud.Metadata{InstanceID: "i-abcdefgh123456789", AvailabilityZone: "us-west-2b", Region: "us-west-2", InstanceType: "t2.medium"}, nil
+		},
+		metadataMutex: &sync.Mutex{},
+	}
+
+	_, err := awsDriver.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	expectErr(t, err, codes.Unavailable)
+
+	resp, err := awsDriver.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.GetNodeId() != "i-abcdefgh123456789" {
+		t.Fatalf("Unexpected node ID: %s", resp.GetNodeId())
+	}
+
+	if _, err := awsDriver.getMetadata(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected metadata to be retrieved 2 times, got %d", calls)
+	}
+}
+
 func TestRemoveNotReadyTaint(t *testing.T) {
 	nodeName := "test-node-123"
 	testCases := []struct {
@@ -88,22 +88,43 @@ func (d *nodeService) preparePublishTarget(target string) error {
 	return nil
 }
 
-// IsBlockDevice checks if the given path is a block device
-func (d *nodeService) IsBlockDevice(fullPath string) (bool, error) {
-	return false, nil
+// checkPartitionExists always succeeds because findDevicePath ignores partitions on Windows.
+func (d *nodeService) checkPartitionExists(_ string) error {
+	return nil
 }
 
-// getBlockSizeBytes gets the size of the disk in bytes
-func (d *nodeService) getBlockSizeBytes(devicePath string) (int64, error) {
-	proxyMounter, ok := (d.mounter.(*NodeMounter)).SafeFormatAndMount.Interface.(*mounter.CSIProxyMounter)
-	if !ok {
-		return -1, fmt.Errorf("failed to cast mounter to csi proxy mounter")
-	}
+// findSCSIDevicePath always fails because Windows identifies disks by serial number only.
+func (d *nodeService) findSCSIDevicePath(devicePath, _ string) (string, error) {
+	return "", fmt.Errorf("SCSI device path lookup of %q is not supported on Windows", devicePath)
+}
 
-	sizeInBytes, err := proxyMounter.GetDeviceSize(devicePath)
-	if err != nil {
-		return -1, err
-	}
+// findDeviceByUUID always fails because csi-proxy does not expose filesystem UUIDs.
+func (d *nodeService) findDeviceByUUID(_, _ string) (string, error) {
+	return "", fmt.Errorf("resolving devices by filesystem UUID is not supported on Windows")
+}
 
-	return sizeInBytes, nil
+// isForeignMount always returns false because csi-proxy does not expose which
+// volume a mounted disk belongs to.
+func (d *nodeService) isForeignMount(_, _ string) bool {
+	return false
+}
+
+// mountOptions is not supported on Windows, which has no /proc/mounts.
+func (d *nodeService) mountOptions(_ string) ([]string, error) {
+	return nil, fmt.Errorf("mount options are not available on Windows")
+}
+
+// detachedDevice is not supported on Windows, which has no /proc/mounts.
+func (d *nodeService) detachedDevice(_ string) string {
+	return ""
+}
+
+// blockStats is not supported on Windows, which has no /sys/class/block.
+func (d *nodeService) blockStats(_ string) (blockStats, error) {
+	return blockStats{}, fmt.Errorf("block device statistics are not available on Windows")
+}
+
+// IsBlockDevice checks if the given path is a block device
+func (d *nodeService) IsBlockDevice(fullPath string) (bool, error) {
+	return false, nil
 }
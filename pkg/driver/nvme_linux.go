@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// sysClassNVMePath is the directory the NVMe controllers of the instance are listed in
+var sysClassNVMePath = "/sys/class/nvme"
+
+// ebsNVMeModel is the model number EBS volumes report in the identify
+// controller data of their NVMe controller.
+const ebsNVMeModel = "Amazon Elastic Block Store"
+
+// findNvmeDevice finds the NVMe device of the volume volumeID, first by the
+// link udev creates for it in /dev/disk/by-id, then by the identify controller
+// data of the NVMe controllers of the instance, e.g. on hosts without the udev
+// rules for EBS.
+func (d *nodeService) findNvmeDevice(volumeID string) (string, error) {
+	nvmeName := "nvme-Amazon_Elastic_Block_Store_" + strings.Replace(volumeID, "-", "", -1)
+	device, err := findNvmeVolume(d.deviceIdentifier, nvmeName)
+	if err == nil {
+		return device, nil
+	}
+	klog.V(5).InfoS("[Debug] error searching for nvme path, falling back to NVMe controller lookup", "nvmeName", nvmeName, "err", err)
+
+	device, controllerErr := findNvmeDeviceByController(volumeID)
+	if controllerErr != nil {
+		klog.V(5).InfoS("[Debug] error searching for NVMe controller", "volumeID", volumeID, "err", controllerErr)
+		return "", err
+	}
+	return device, nil
+}
+
+// findNvmeDeviceByController finds the NVMe device of the volume volumeID
+// among the NVMe controllers listed in sysfs, e.g. /dev/nvme1n1 for the
+// controller /sys/class/nvme/nvme1 whose serial number is the volume ID.
+func findNvmeDeviceByController(volumeID string) (string, error) {
+	controllers, err := os.ReadDir(sysClassNVMePath)
+	if err != nil {
+		return "", fmt.Errorf("could not list NVMe controllers: %w", err)
+	}
+	for _, controller := range controllers {
+		controllerPath := filepath.Join(sysClassNVMePath, controller.Name())
+		if id, err := nvmeControllerVolumeID(controllerPath); err != nil || id != volumeID {
+			continue
+		}
+		// The namespaces of controller nvme1 are nvme1n1, nvme1n2, etc.
+		namespaces, err := filepath.Glob(filepath.Join(controllerPath, controller.Name()+"n*"))
+		if err != nil || len(namespaces) == 0 {
+			return "", fmt.Errorf("no namespace found for NVMe controller %s of volume %s", controller.Name(), volumeID)
+		}
+		sort.Strings(namespaces)
+		return "/dev/" + filepath.Base(namespaces[0]), nil
+	}
+	return "", fmt.Errorf("no NVMe controller found for volume %s in %s", volumeID, sysClassNVMePath)
+}
+
+// nvmeDeviceVolumeID returns the ID of the volume of an NVMe device, e.g.
+// /dev/nvme1n1, read from the identify controller data of its controller. It
+// returns an error if the device is not the device of an EBS volume.
+func nvmeDeviceVolumeID(device string) (string, error) {
+	// The device link of a namespace points to its controller
+	return nvmeControllerVolumeID(filepath.Join(sysBlockPath, filepath.Base(device), "device"))
+}
+
+// nvmeControllerVolumeID returns the ID of the volume of the NVMe controller
+// at controllerPath in sysfs. EBS reports the volume ID without its dash, e.g.
+// vol0fab1d5e3f72a5e23, as the serial number of the controller.
+func nvmeControllerVolumeID(controllerPath string) (string, error) {
+	model, err := os.ReadFile(filepath.Join(controllerPath, "model"))
+	if err != nil {
+		return "", err
+	}
+	if m := strings.TrimSpace(string(model)); m != ebsNVMeModel {
+		return "", fmt.Errorf("NVMe controller %s is not an EBS volume but %q", controllerPath, m)
+	}
+	serial, err := os.ReadFile(filepath.Join(controllerPath, "serial"))
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSpace(string(serial))
+	if !strings.HasPrefix(s, "vol") {
+		return "", fmt.Errorf("unexpected serial number %q of NVMe controller %s", s, controllerPath)
+	}
+	return "vol-" + strings.TrimPrefix(s, "vol"), nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// orphanedVolumeMinAge is the age below which a volume is never reported,
+	// as its PersistentVolume may not have been created yet.
+	orphanedVolumeMinAge = time.Hour
+
+	// orphanedVolumeReportTimeout bounds a report.
+	orphanedVolumeReportTimeout = 10 * time.Minute
+
+	// orphanedVolumesMetric is the number of orphaned volumes found by the
+	// last report.
+	orphanedVolumesMetric = "ebs_csi_orphaned_volumes"
+)
+
+// orphanedVolumeReporter reports the volumes the driver provisioned for the
+// cluster that are no longer referenced by any PersistentVolume, e.g. because
+// the PersistentVolume was deleted while the driver was unavailable. It runs
+// when CreateVolume fails because the account reached its volume count limit,
+// so that an administrator can free room for new volumes. Volumes are never
+// deleted, as a volume without PersistentVolume may still hold data that is
+// needed, e.g. of a PersistentVolume with the Retain policy that was deleted.
+//
+// Only available volumes tagged as owned by the cluster are considered, so it
+// is nil unless enabled and the cluster ID is known.
+type orphanedVolumeReporter struct {
+	cloud     cloud.Cloud
+	k8sClient cloud.KubernetesAPIClient
+	clusterID string
+	now       func() time.Time
+	// retryAttempts is the number of retries of transiently failing PersistentVolume lookups
+	retryAttempts int
+	// triggered wakes up run, at most one report is pending
+	triggered chan struct{}
+
+	mu sync.Mutex
+	// reported is the number of orphaned volumes of the last report, the
+	// value of orphanedVolumesMetric
+	reported int
+}
+
+func newOrphanedVolumeReporter(c cloud.Cloud, k8sClient cloud.KubernetesAPIClient, driverOptions *DriverOptions) *orphanedVolumeReporter {
+	if !driverOptions.reportOrphanedVolumes {
+		return nil
+	}
+	if driverOptions.kubernetesClusterID == "" {
+		klog.InfoS("Not reporting orphaned volumes on volume count limit because no cluster ID is set")
+		return nil
+	}
+	return &orphanedVolumeReporter{
+		cloud:         c,
+		k8sClient:     k8sClient,
+		clusterID:     driverOptions.kubernetesClusterID,
+		now:           time.Now,
+		retryAttempts: driverOptions.kubernetesAPIRetryAttempts,
+		triggered:     make(chan struct{}, 1),
+	}
+}
+
+// trigger requests a report from run unless one is pending. It is handled
+// once this replica leads, so that the replicas do not all list the volumes
+// and PersistentVolumes of the cluster.
+func (r *orphanedVolumeReporter) trigger() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.triggered <- struct{}{}:
+	default:
+	}
+}
+
+// run reports the orphaned volumes when triggered, until ctx is done, e.g.
+// when this replica loses leadership.
+func (r *orphanedVolumeReporter) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.triggered:
+		}
+		reportCtx, cancel := context.WithTimeout(ctx, orphanedVolumeReportTimeout)
+		orphaned, err := r.report(reportCtx)
+		cancel()
+		if err != nil {
+			klog.ErrorS(err, "Could not report orphaned volumes")
+			continue
+		}
+		klog.InfoS("Found orphaned volumes not referenced by any PersistentVolume, delete them if they are not needed", "volumeIDs", orphaned)
+	}
+}
+
+// report finds the orphaned volumes of the cluster, logs and counts them in
+// orphanedVolumesMetric, and returns their IDs.
+func (r *orphanedVolumeReporter) report(ctx context.Context) ([]string, error) {
+	logger := klog.FromContext(ctx)
+	disks, err := r.cloud.GetAvailableDisksByTags(ctx, map[string]string{
+		cloud.AwsEbsDriverTagKey:                 isManagedByDriver,
+		ResourceLifecycleTagPrefix + r.clusterID: ResourceLifecycleOwned,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var orphaned []string
+	if len(disks) > 0 {
+		// Volumes are only reported if every PersistentVolume could be listed
+		referenced, err := r.referencedVolumeIDs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not list PersistentVolumes: %w", err)
+		}
+		for _, disk := range disks {
+			if referenced.Has(disk.VolumeID) {
+				continue
+			}
+			if age := r.now().Sub(disk.CreateTime); age < orphanedVolumeMinAge {
+				logger.V(4).Info("Not reporting unreferenced volume created recently", "volumeID", disk.VolumeID, "age", age)
+				continue
+			}
+			logger.Info("Orphaned volume not referenced by any PersistentVolume", "volumeID", disk.VolumeID, "volumeName", disk.Tags[cloud.VolumeNameTagKey], "createTime", disk.CreateTime)
+			orphaned = append(orphaned, disk.VolumeID)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	metrics.Recorder().AddGauge(orphanedVolumesMetric, float64(len(orphaned)-r.reported), map[string]string{})
+	r.reported = len(orphaned)
+	return orphaned, nil
+}
+
+// referencedVolumeIDs returns the IDs of the volumes referenced by the
+// PersistentVolumes of the cluster, including in-tree ones that may be
+// migrated to the driver.
+func (r *orphanedVolumeReporter) referencedVolumeIDs(ctx context.Context) (sets.Set[string], error) {
+	clientset, err := r.k8sClient()
+	if err != nil {
+		return nil, err
+	}
+	var pvs *corev1.PersistentVolumeList
+	err = retryTransient(ctx, "PersistentVolumes", r.retryAttempts, func() error {
+		pvs, err = clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	referenced := sets.New[string]()
+	for _, pv := range pvs.Items {
+		if csi := pv.Spec.CSI; csi != nil && csi.Driver == DriverName {
+			referenced.Insert(csi.VolumeHandle)
+		}
+		// In-tree volume IDs may be of the form aws://<zone>/<volumeID>
+		if ebs := pv.Spec.AWSElasticBlockStore; ebs != nil {
+			referenced.Insert(path.Base(ebs.VolumeID))
+		}
+	}
+	return referenced, nil
+}
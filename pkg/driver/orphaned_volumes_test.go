@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewOrphanedVolumeReporter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		driverOptions *DriverOptions
+		expEnabled    bool
+	}{
+		{
+			name:          "enabled with cluster ID",
+			driverOptions: &DriverOptions{reportOrphanedVolumes: true, kubernetesClusterID: "test-cluster"},
+			expEnabled:    true,
+		},
+		{
+			name:          "disabled without cluster ID",
+			driverOptions: &DriverOptions{reportOrphanedVolumes: true},
+		},
+		{
+			name:          "disabled",
+			driverOptions: &DriverOptions{kubernetesClusterID: "test-cluster"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newOrphanedVolumeReporter(nil, nil, tc.driverOptions)
+			assert.Equal(t, tc.expEnabled, r != nil)
+			if !tc.expEnabled {
+				// trigger must be safe on a disabled reporter
+				r.trigger()
+			}
+		})
+	}
+}
+
+func TestOrphanedVolumeReporterReport(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * orphanedVolumeMinAge)
+	csiPV := func(name, volumeHandle string) runtime.Object {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: DriverName, VolumeHandle: volumeHandle},
+				},
+			},
+		}
+	}
+	inTreePV := func(name, volumeID string) runtime.Object {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					AWSElasticBlockStore: &v1.AWSElasticBlockStoreVolumeSource{VolumeID: volumeID},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		disks       []*cloud.Disk
+		pvs         []runtime.Object
+		k8sErr      error
+		expOrphaned []string
+		expErr      bool
+	}{
+		{
+			name: "reports unreferenced volumes",
+			disks: []*cloud.Disk{
+				{VolumeID: "vol-csi", CreateTime: old},
+				{VolumeID: "vol-intree", CreateTime: old},
+				{VolumeID: "vol-orphan", CreateTime: old},
+			},
+			pvs: []runtime.Object{
+				csiPV("pv-csi", "vol-csi"),
+				inTreePV("pv-intree", "aws://us-west-2b/vol-intree"),
+			},
+			expOrphaned: []string{"vol-orphan"},
+		},
+		{
+			name: "ignores recently created volumes",
+			disks: []*cloud.Disk{
+				{VolumeID: "vol-new", CreateTime: now.Add(-time.Minute)},
+			},
+		},
+		{
+			name: "reports nothing if PersistentVolumes cannot be listed",
+			disks: []*cloud.Disk{
+				{VolumeID: "vol-orphan", CreateTime: old},
+			},
+			k8sErr: errors.New("forbidden"),
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			// Volumes are never deleted, so DeleteDisk is not expected
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().GetAvailableDisksByTags(gomock.Any(), map[string]string{
+				cloud.AwsEbsDriverTagKey:                    isManagedByDriver,
+				ResourceLifecycleTagPrefix + "test-cluster": ResourceLifecycleOwned,
+			}).Return(tc.disks, nil)
+
+			clientset := fake.NewSimpleClientset(tc.pvs...)
+			k8sClient := func() (kubernetes.Interface, error) {
+				if tc.k8sErr != nil {
+					return nil, tc.k8sErr
+				}
+				return clientset, nil
+			}
+
+			r := newOrphanedVolumeReporter(mockCloud, k8sClient, &DriverOptions{reportOrphanedVolumes: true, kubernetesClusterID: "test-cluster"})
+			r.now = func() time.Time { return now }
+
+			orphaned, err := r.report(context.Background())
+			if (err != nil) != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			assert.Equal(t, tc.expOrphaned, orphaned)
+			if !tc.expErr {
+				assert.Equal(t, len(tc.expOrphaned), r.reported)
+			}
+		})
+	}
+}
+
+func TestOrphanedVolumeReporterRun(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	reported := make(chan struct{})
+	mockCloud := cloud.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().GetAvailableDisksByTags(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, map[string]string) ([]*cloud.Disk, error) {
+		close(reported)
+		return nil, nil
+	})
+
+	r := newOrphanedVolumeReporter(mockCloud, nil, &DriverOptions{reportOrphanedVolumes: true, kubernetesClusterID: "test-cluster"})
+	// Triggers before this replica leads are handled once it does, at most one
+	r.trigger()
+	r.trigger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.run(ctx)
+		close(done)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected orphaned volumes to be reported")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected reporter to stop when leadership is lost")
+	}
+}
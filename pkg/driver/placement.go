@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// Volume placement strategies, which choose the availability zone of a new
+// volume among the zones allowed by its accessibility requirements.
+const (
+	// PlacementStrategyFirst places volumes in the first preferred zone, or
+	// the first requisite zone if none is preferred.
+	PlacementStrategyFirst = "first"
+	// PlacementStrategyRoundRobin places volumes in the allowed zones in turn.
+	PlacementStrategyRoundRobin = "round-robin"
+	// PlacementStrategyLeastUsed places volumes in the allowed zone that the
+	// controller placed the fewest volumes in since it started.
+	PlacementStrategyLeastUsed = "least-used"
+)
+
+// PlacementStrategies are the supported volume placement strategies.
+var PlacementStrategies = []string{PlacementStrategyFirst, PlacementStrategyRoundRobin, PlacementStrategyLeastUsed}
+
+// volumePlacer orders the availability zones a volume may be created in
+// according to a placement strategy. A nil volumePlacer uses the first
+// strategy.
+type volumePlacer struct {
+	strategy string
+
+	mu sync.Mutex
+	// next is the turn of the next volume placed round-robin
+	next int
+	// counts are the numbers of volumes placed in each zone
+	counts map[string]int
+}
+
+func newVolumePlacer(strategy string) *volumePlacer {
+	return &volumePlacer{
+		strategy: strategy,
+		counts:   make(map[string]int),
+	}
+}
+
+// zones returns the availability zones allowed by requirement in the order
+// the volume should be tried to be created in. It returns no zones if
+// requirement allows any zone.
+func (p *volumePlacer) zones(requirement *csi.TopologyRequirement) []string {
+	zones := availabilityZones(requirement)
+	if p == nil || len(zones) < 2 {
+		return zones
+	}
+
+	// The zones are sorted so that the preferred ones, which the CO
+	// usually lists first, do not get more volumes than the others
+	switch p.strategy {
+	case PlacementStrategyRoundRobin:
+		slices.Sort(zones)
+		p.mu.Lock()
+		first := p.next % len(zones)
+		p.next++
+		p.mu.Unlock()
+		return append(zones[first:], zones[:first]...)
+	case PlacementStrategyLeastUsed:
+		slices.Sort(zones)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		slices.SortStableFunc(zones, func(a, b string) int {
+			return cmp.Compare(p.counts[a], p.counts[b])
+		})
+		return zones
+	default:
+		return zones
+	}
+}
+
+// placed records that a volume was created in zone.
+func (p *volumePlacer) placed(zone string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts[zone]++
+}
+
+// availabilityZones returns the zones of the preferred topologies of
+// requirement, followed by the other zones of its requisite topologies.
+func availabilityZones(requirement *csi.TopologyRequirement) []string {
+	if requirement == nil {
+		return nil
+	}
+	var zones []string
+	for _, topologies := range [][]*csi.Topology{requirement.GetPreferred(), requirement.GetRequisite()} {
+		for _, topology := range topologies {
+			zone, exists := topology.GetSegments()[WellKnownTopologyKey]
+			if !exists {
+				zone = topology.GetSegments()[TopologyKey]
+			}
+			if zone != "" && !slices.Contains(zones, zone) {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	return zones
+}
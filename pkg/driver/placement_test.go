@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestVolumePlacerZones(t *testing.T) {
+	requirement := &csi.TopologyRequirement{
+		Requisite: []*csi.Topology{
+			{Segments: map[string]string{WellKnownTopologyKey: "us-east-1a"}},
+			{Segments: map[string]string{WellKnownTopologyKey: "us-east-1b"}},
+			{Segments: map[string]string{WellKnownTopologyKey: "us-east-1c"}},
+		},
+		Preferred: []*csi.Topology{
+			{Segments: map[string]string{WellKnownTopologyKey: "us-east-1b"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		placer   *volumePlacer
+		placed   []string
+		expZones [][]string
+	}{
+		{
+			name:   "nil placer uses the first strategy",
+			placer: nil,
+			expZones: [][]string{
+				{"us-east-1b", "us-east-1a", "us-east-1c"},
+				{"us-east-1b", "us-east-1a", "us-east-1c"},
+			},
+		},
+		{
+			name:   "first",
+			placer: newVolumePlacer(PlacementStrategyFirst),
+			expZones: [][]string{
+				{"us-east-1b", "us-east-1a", "us-east-1c"},
+				{"us-east-1b", "us-east-1a", "us-east-1c"},
+			},
+		},
+		{
+			name:   "round-robin",
+			placer: newVolumePlacer(PlacementStrategyRoundRobin),
+			expZones: [][]string{
+				{"us-east-1a", "us-east-1b", "us-east-1c"},
+				{"us-east-1b", "us-east-1c", "us-east-1a"},
+				{"us-east-1c", "us-east-1a", "us-east-1b"},
+				{"us-east-1a", "us-east-1b", "us-east-1c"},
+			},
+		},
+		{
+			name:   "least-used",
+			placer: newVolumePlacer(PlacementStrategyLeastUsed),
+			placed: []string{"us-east-1a", "us-east-1a", "us-east-1c"},
+			expZones: [][]string{
+				{"us-east-1b", "us-east-1c", "us-east-1a"},
+			},
+		},
+		{
+			name:   "least-used without volumes",
+			placer: newVolumePlacer(PlacementStrategyLeastUsed),
+			expZones: [][]string{
+				{"us-east-1a", "us-east-1b", "us-east-1c"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, zone := range tc.placed {
+				tc.placer.placed(zone)
+			}
+			for _, expZones := range tc.expZones {
+				zones := tc.placer.zones(requirement)
+				if !reflect.DeepEqual(zones, expZones) {
+					t.Fatalf("Expected zones %v, got %v", expZones, zones)
+				}
+			}
+		})
+	}
+}
+
+func TestVolumePlacerSingleZone(t *testing.T) {
+	requirement := &csi.TopologyRequirement{
+		Requisite: []*csi.Topology{
+			{Segments: map[string]string{TopologyKey: "us-east-1a"}},
+		},
+	}
+
+	for _, strategy := range PlacementStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			placer := newVolumePlacer(strategy)
+			if zones := placer.zones(requirement); !reflect.DeepEqual(zones, []string{"us-east-1a"}) {
+				t.Fatalf("Expected zones [us-east-1a], got %v", zones)
+			}
+			if zones := placer.zones(nil); zones != nil {
+				t.Fatalf("Expected no zones, got %v", zones)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ec2HealthService is the service of the gRPC health checking protocol
+	// that is SERVING while the controller can reach EC2.
+	ec2HealthService = "ec2"
+	// ec2ReadinessCheckInterval is the interval between the checks of the
+	// connectivity to EC2 that the ec2 health service reports.
+	ec2ReadinessCheckInterval = 30 * time.Second
+	// ec2ReadinessCheckTimeout is how long a check of the connectivity to EC2 may take.
+	ec2ReadinessCheckTimeout = 10 * time.Second
+)
+
+// ec2ReadinessGate reports the connectivity of the controller to EC2 by the
+// ec2 service of the gRPC health checking protocol. The connectivity is
+// checked in the background every interval and the result of the last check
+// is cached, so that the gRPC health service, which is called often, never
+// calls EC2 itself. Probe does not depend on it.
+//
+// It is nil, and EC2 is considered reachable, in node mode.
+type ec2ReadinessGate struct {
+	cloud    cloud.Cloud
+	interval time.Duration
+	// onChange is called when the connectivity changes
+	onChange func()
+
+	reachable atomic.Bool
+	cancel    context.CancelFunc
+}
+
+func newEC2ReadinessGate(c cloud.Cloud, onChange func()) *ec2ReadinessGate {
+	return &ec2ReadinessGate{
+		cloud:    c,
+		interval: ec2ReadinessCheckInterval,
+		onChange: onChange,
+	}
+}
+
+// start checks the connectivity to EC2 every interval until stop is called.
+func (g *ec2ReadinessGate) start() {
+	if g == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	go wait.UntilWithContext(ctx, g.check, g.interval)
+}
+
+func (g *ec2ReadinessGate) stop() {
+	if g == nil || g.cancel == nil {
+		return
+	}
+	g.cancel()
+}
+
+// check checks the connectivity to EC2 and caches the result.
+func (g *ec2ReadinessGate) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, ec2ReadinessCheckTimeout)
+	defer cancel()
+	err := g.cloud.CheckConnectivity(ctx)
+	if err != nil {
+		klog.ErrorS(err, "EC2 is not reachable, reporting the ec2 health service not serving")
+	}
+	if g.reachable.Swap(err == nil) != (err == nil) {
+		klog.InfoS("EC2 connectivity changed", "reachable", err == nil)
+		g.onChange()
+	}
+}
+
+// isReachable returns whether EC2 was reachable at the last check.
+func (g *ec2ReadinessGate) isReachable() bool {
+	return g == nil || g.reachable.Load()
+}
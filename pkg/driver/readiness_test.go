@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestEC2ReadinessGate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		serving       bool
+		checks        []error
+		expEC2Serving bool
+	}{
+		{
+			name:          "ec2 not serving before EC2 is checked",
+			serving:       true,
+			expEC2Serving: false,
+		},
+		{
+			name:          "ec2 serving when EC2 is reachable",
+			serving:       true,
+			checks:        []error{nil},
+			expEC2Serving: true,
+		},
+		{
+			name:          "ec2 not serving when EC2 becomes unreachable",
+			serving:       true,
+			checks:        []error{nil, errors.New("could not reach EC2")},
+			expEC2Serving: false,
+		},
+		{
+			name:          "ec2 serving when EC2 becomes reachable again",
+			serving:       true,
+			checks:        []error{errors.New("could not reach EC2"), nil},
+			expEC2Serving: true,
+		},
+		{
+			name:          "ec2 not serving when the driver is not ready although EC2 is reachable",
+			serving:       false,
+			checks:        []error{nil},
+			expEC2Serving: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			for _, err := range tc.checks {
+				mockCloud.EXPECT().CheckConnectivity(gomock.Any()).Return(err)
+			}
+
+			d, err := NewFakeDriver("", mockCloud, nil, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			d.ec2Gate = newEC2ReadinessGate(mockCloud, d.updateEC2Health)
+			d.setReady(tc.serving)
+			for range tc.checks {
+				d.ec2Gate.check(context.Background())
+			}
+
+			// Probe and the default health service do not depend on EC2.
+			probeResp, err := d.Probe(context.Background(), &csi.ProbeRequest{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if probeResp.GetReady().GetValue() != tc.serving {
+				t.Fatalf("Expected Probe ready %v, got %v", tc.serving, probeResp.GetReady().GetValue())
+			}
+			checkHealth(t, d, "", tc.serving)
+			checkHealth(t, d, ec2HealthService, tc.expEC2Serving)
+		})
+	}
+}
+
+func checkHealth(t *testing.T, d *Driver, service string, expServing bool) {
+	t.Helper()
+	expHealthResp := healthpb.HealthCheckResponse_NOT_SERVING
+	if expServing {
+		expHealthResp = healthpb.HealthCheckResponse_SERVING
+	}
+	healthResp, err := d.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if healthResp.GetStatus() != expHealthResp {
+		t.Fatalf("Expected health status %v of service %q, got %v", expHealthResp, service, healthResp.GetStatus())
+	}
+}
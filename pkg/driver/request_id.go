@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+)
+
+type requestIDKey struct{}
+
+// requestIDInterceptor assigns a unique ID to each RPC and adds a logger to
+// its context that logs the ID, method and volume ID of the RPC with every
+// line. The logger is passed down to the cloud provider, so that the log lines
+// of the EC2 calls of an RPC can be correlated with it.
+func requestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := uuid.NewString()
+	logger := klog.FromContext(ctx).WithValues("requestID", requestID, "method", info.FullMethod)
+	if volumeID := rpcVolumeID(req, nil); volumeID != "" {
+		logger = logger.WithValues("volumeID", volumeID)
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	return handler(klog.NewContext(ctx, logger), req)
+}
+
+// requestIDFromContext returns the ID assigned to the RPC of ctx, or "" if
+// none was assigned.
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
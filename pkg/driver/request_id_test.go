@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+)
+
+func TestRequestIDInterceptor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		method      string
+		req         interface{}
+		expVolumeID interface{}
+	}{
+		{
+			name:        "RPC of volume",
+			method:      "/csi.v1.Controller/ControllerPublishVolume",
+			req:         &csi.ControllerPublishVolumeRequest{VolumeId: "vol-test"},
+			expVolumeID: "vol-test",
+		},
+		{
+			name:   "RPC without volume",
+			method: "/csi.v1.Controller/GetCapacity",
+			req:    &csi.GetCapacityRequest{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lines []string
+			logger := funcr.NewJSON(func(obj string) { lines = append(lines, obj) }, funcr.Options{})
+			ctx := klog.NewContext(context.Background(), logger)
+
+			var requestIDs []string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				requestIDs = append(requestIDs, requestIDFromContext(ctx))
+				klog.FromContext(ctx).Info("handling RPC")
+				return nil, nil
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: tc.method}
+			for i := 0; i < 2; i++ {
+				if _, err := requestIDInterceptor(ctx, tc.req, info, handler); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			if len(requestIDs) != 2 || requestIDs[0] == "" || requestIDs[0] == requestIDs[1] {
+				t.Fatalf("Expected two distinct request IDs, got %v", requestIDs)
+			}
+			for i, line := range lines {
+				var logged map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &logged); err != nil {
+					t.Fatalf("Could not parse log line %q: %v", line, err)
+				}
+				assert.Equal(t, requestIDs[i], logged["requestID"])
+				assert.Equal(t, tc.method, logged["method"])
+				assert.Equal(t, tc.expVolumeID, logged["volumeID"])
+			}
+		})
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	assert.Equal(t, "", requestIDFromContext(context.Background()))
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-test")
+	assert.Equal(t, "req-test", requestIDFromContext(ctx))
+}
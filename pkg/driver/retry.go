@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// transientRetryBackoff is the backoff between attempts of retryTransient.
+	// Steps is overridden by the configured number of retry attempts.
+	transientRetryBackoff = wait.Backoff{
+		Duration: 1 * time.Second,
+		Factor:   2,
+		Cap:      30 * time.Second,
+	}
+)
+
+// retryTransient calls lookup, retrying up to retryAttempts times while the
+// failure looks transient (see isTransientError). Permanent failures
+// are returned immediately. what names the looked up data in logs and errors.
+func retryTransient(ctx context.Context, what string, retryAttempts int, lookup func() error) error {
+	var lastErr error
+	backoff := transientRetryBackoff
+	backoff.Steps = retryAttempts + 1
+
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(context.Context) (bool, error) {
+		err := lookup()
+		if err == nil {
+			return true, nil
+		}
+		if !isTransientError(err) {
+			return false, err
+		}
+		lastErr = err
+		klog.FromContext(ctx).Info("Lookup failed transiently", "what", what, "err", err)
+		return false, nil
+	})
+	if wait.Interrupted(err) && lastErr != nil {
+		return fmt.Errorf("%s still unavailable after %d attempt(s): %w", what, backoff.Steps, lastErr)
+	}
+	return err
+}
+
+// isTransientError returns a boolean indicating whether a failure to retrieve
+// instance metadata or to look up Kubernetes objects may go away on its own,
+// such as IMDS or the API server being briefly unreachable or throttled, as
+// opposed to e.g. an incomplete identity document.
+func isTransientError(err error) bool {
+	return errors.Is(err, cloud.ErrMetadataUnavailable) || isTransientKubernetesError(err) || isTransientAWSError(err)
+}
+
+// isTransientKubernetesError returns a boolean indicating whether a Kubernetes
+// API request failed because the API server was throttling, overloaded or
+// unavailable.
+func isTransientKubernetesError(err error) bool {
+	return apierrors.IsTooManyRequests(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err)
+}
+
+// isTransientAWSError returns a boolean indicating whether a request to IMDS
+// or an AWS API failed because it was throttled, the server failed or the
+// network timed out.
+func isTransientAWSError(err error) bool {
+	var awsErr awserr.Error
+	for errors.As(err, &awsErr) {
+		if reqErr, ok := awsErr.(awserr.RequestFailure); ok {
+			return reqErr.StatusCode() == http.StatusTooManyRequests || reqErr.StatusCode() >= http.StatusInternalServerError
+		}
+		switch awsErr.Code() {
+		case request.ErrCodeRequestError, request.ErrCodeResponseTimeout:
+			return true
+		}
+		// awserr errors do not implement Unwrap, so walk the chain by hand
+		err = awsErr.OrigErr()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
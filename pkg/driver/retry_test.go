@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestRetryTransient(t *testing.T) {
+	testCases := []struct {
+		name          string
+		retryAttempts int
+		errs          []error
+		expectedCalls int
+		expectErr     bool
+	}{
+		{
+			name:          "success after throttling and unavailable API server",
+			retryAttempts: 3,
+			errs:          []error{apierrors.NewTooManyRequests("throttled", 1), apierrors.NewServiceUnavailable("unavailable")},
+			expectedCalls: 3,
+		},
+		{
+			name:          "success after API server timeout",
+			retryAttempts: 3,
+			errs:          []error{apierrors.NewTimeoutError("timeout", 1)},
+			expectedCalls: 2,
+		},
+		{
+			name:          "fail when retries are exhausted",
+			retryAttempts: 1,
+			errs:          []error{apierrors.NewInternalError(fmt.Errorf("etcd")), apierrors.NewInternalError(fmt.Errorf("etcd"))},
+			expectedCalls: 2,
+			expectErr:     true,
+		},
+		{
+			name:          "fail without retrying a forbidden lookup",
+			retryAttempts: 3,
+			errs:          []error{apierrors.NewForbidden(corev1.Resource("nodes"), "", fmt.Errorf("no RBAC"))},
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+	}
+
+	defaultBackoff := transientRetryBackoff
+	transientRetryBackoff.Duration = time.Millisecond
+	defer func() { transientRetryBackoff = defaultBackoff }()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			err := retryTransient(context.Background(), "Nodes", tc.retryAttempts, func() error {
+				calls++
+				if calls <= len(tc.errs) {
+					return tc.errs[calls-1]
+				}
+				return nil
+			})
+			if tc.expectErr && err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if calls != tc.expectedCalls {
+				t.Fatalf("Expected %d attempts, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
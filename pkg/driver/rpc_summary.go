@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"time"
+
+	"github.com/awslabs/volume-modifier-for-k8s/pkg/rpc"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"k8s.io/klog/v2"
+)
+
+// redactedValue replaces the values of secrets in logged requests.
+const redactedValue = "***redacted***"
+
+// rpcSummaryLogger logs a single structured line per RPC with its request ID,
+// method, volume ID, duration and result code, so that the handling of a
+// volume can be traced end to end across the controller and node services. It
+// complements the logs of the individual steps of the RPCs.
+type rpcSummaryLogger struct {
+	logger klog.Logger
+	level  int
+}
+
+func newRPCSummaryLogger(level int) *rpcSummaryLogger {
+	return &rpcSummaryLogger{
+		logger: klog.Background(),
+		level:  level,
+	}
+}
+
+// unaryInterceptor logs the summary of each RPC once it completed.
+func (l *rpcSummaryLogger) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	logger := l.logger.V(l.level)
+	if !logger.Enabled() {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logger.Info("RPC completed",
+		"requestID", requestIDFromContext(ctx),
+		"method", info.FullMethod,
+		"volumeID", rpcVolumeID(req, resp),
+		"duration", time.Since(start),
+		"code", status.Code(err).String(),
+		"request", redactSecrets(req))
+	return resp, err
+}
+
+// rpcVolumeID returns the ID of the volume an RPC handled, if any.
+func rpcVolumeID(req, resp interface{}) string {
+	switch r := req.(type) {
+	case interface{ GetVolumeId() string }:
+		return r.GetVolumeId()
+	case *csi.CreateSnapshotRequest:
+		return r.GetSourceVolumeId()
+	case *rpc.ModifyVolumePropertiesRequest:
+		return r.GetName()
+	}
+	// The ID of a created volume is only known from the response
+	if r, ok := resp.(*csi.CreateVolumeResponse); ok {
+		return r.GetVolume().GetVolumeId()
+	}
+	return ""
+}
+
+// redactSecrets returns a copy of req in which the values of the fields
+// marked as secrets in the CSI spec, e.g. the secrets of CreateVolume, are
+// redacted. Requests that are not protobuf messages are returned as is.
+func redactSecrets(req interface{}) interface{} {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return req
+	}
+	redacted := proto.Clone(msg)
+	redactMessage(redacted.ProtoReflect())
+	return redacted
+}
+
+func redactMessage(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case isSecretField(fd):
+			redactValue(fd, v)
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					redactMessage(mv.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Message() != nil {
+				for i := 0; i < v.List().Len(); i++ {
+					redactMessage(v.List().Get(i).Message())
+				}
+			}
+		case fd.Message() != nil:
+			redactMessage(v.Message())
+		}
+		return true
+	})
+}
+
+// redactValue redacts the secret field fd of value v in place. Secrets are
+// maps of strings in the CSI spec.
+func redactValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	if !fd.IsMap() || fd.MapValue().Kind() != protoreflect.StringKind {
+		return
+	}
+	v.Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		v.Map().Set(k, protoreflect.ValueOfString(redactedValue))
+		return true
+	})
+}
+
+func isSecretField(fd protoreflect.FieldDescriptor) bool {
+	secret, ok := proto.GetExtension(fd.Options(), csi.E_CsiSecret).(bool)
+	return ok && secret
+}
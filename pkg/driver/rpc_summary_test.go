@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRPCSummaryLoggerInterceptor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		method      string
+		req         interface{}
+		resp        interface{}
+		err         error
+		verbosity   int
+		expLogged   bool
+		expVolumeID string
+		expCode     string
+	}{
+		{
+			name:   "successful RPC",
+			method: "/csi.v1.Controller/CreateVolume",
+			req: &csi.CreateVolumeRequest{
+				Name:       "pvc-test",
+				Parameters: map[string]string{VolumeTypeKey: "gp3"},
+				Secrets:    map[string]string{"password": "hunter2"},
+			},
+			resp:        &csi.CreateVolumeResponse{Volume: &csi.Volume{VolumeId: "vol-test"}},
+			verbosity:   4,
+			expLogged:   true,
+			expVolumeID: "vol-test",
+			expCode:     "OK",
+		},
+		{
+			name:   "failed RPC",
+			method: "/csi.v1.Node/NodeStageVolume",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId: "vol-test",
+				Secrets:  map[string]string{"password": "hunter2"},
+			},
+			err:         status.Error(codes.NotFound, "device not found"),
+			verbosity:   4,
+			expLogged:   true,
+			expVolumeID: "vol-test",
+			expCode:     "NotFound",
+		},
+		{
+			name:      "not logged below the log level",
+			method:    "/csi.v1.Node/NodeStageVolume",
+			req:       &csi.NodeStageVolumeRequest{VolumeId: "vol-test"},
+			verbosity: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lines []string
+			l := newRPCSummaryLogger(4)
+			l.logger = funcr.NewJSON(func(obj string) { lines = append(lines, obj) }, funcr.Options{Verbosity: tc.verbosity})
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return tc.resp, tc.err
+			}
+			ctx := context.WithValue(context.Background(), requestIDKey{}, "req-test")
+			resp, err := l.unaryInterceptor(ctx, tc.req, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			assert.Equal(t, tc.resp, resp)
+			assert.Equal(t, tc.err, err)
+
+			if !tc.expLogged {
+				assert.Empty(t, lines)
+				return
+			}
+			if len(lines) != 1 {
+				t.Fatalf("Expected a single summary line, got: %v", lines)
+			}
+			var summary map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &summary); err != nil {
+				t.Fatalf("Could not parse summary line %q: %v", lines[0], err)
+			}
+			assert.Equal(t, "RPC completed", summary["msg"])
+			assert.Equal(t, "req-test", summary["requestID"])
+			assert.Equal(t, tc.method, summary["method"])
+			assert.Equal(t, tc.expVolumeID, summary["volumeID"])
+			assert.Equal(t, tc.expCode, summary["code"])
+			assert.Contains(t, summary, "duration")
+			if strings.Contains(lines[0], "hunter2") {
+				t.Errorf("Expected secrets to be redacted, got: %s", lines[0])
+			}
+			assert.Contains(t, lines[0], redactedValue)
+		})
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:      "vol-test",
+		Secrets:       map[string]string{"password": "hunter2"},
+		VolumeContext: map[string]string{"partition": "1"},
+	}
+
+	redacted, ok := redactSecrets(req).(*csi.NodePublishVolumeRequest)
+	if !ok {
+		t.Fatalf("Expected a redacted *csi.NodePublishVolumeRequest, got %T", redactSecrets(req))
+	}
+	assert.Equal(t, map[string]string{"password": redactedValue}, redacted.GetSecrets())
+	assert.Equal(t, req.GetVolumeContext(), redacted.GetVolumeContext())
+	assert.Equal(t, "vol-test", redacted.GetVolumeId())
+	// The request itself must not be modified
+	assert.Equal(t, "hunter2", req.GetSecrets()["password"])
+}
+
+func TestRPCVolumeID(t *testing.T) {
+	testCases := []struct {
+		name        string
+		req         interface{}
+		resp        interface{}
+		expVolumeID string
+	}{
+		{
+			name:        "volume ID of request",
+			req:         &csi.ControllerPublishVolumeRequest{VolumeId: "vol-test"},
+			expVolumeID: "vol-test",
+		},
+		{
+			name:        "created volume",
+			req:         &csi.CreateVolumeRequest{Name: "pvc-test"},
+			resp:        &csi.CreateVolumeResponse{Volume: &csi.Volume{VolumeId: "vol-test"}},
+			expVolumeID: "vol-test",
+		},
+		{
+			name:        "failed CreateVolume",
+			req:         &csi.CreateVolumeRequest{Name: "pvc-test"},
+			resp:        (*csi.CreateVolumeResponse)(nil),
+			expVolumeID: "",
+		},
+		{
+			name:        "source volume of snapshot",
+			req:         &csi.CreateSnapshotRequest{SourceVolumeId: "vol-test"},
+			expVolumeID: "vol-test",
+		},
+		{
+			name:        "no volume",
+			req:         &csi.GetCapacityRequest{},
+			expVolumeID: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expVolumeID, rpcVolumeID(tc.req, tc.resp))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// storageClassParameterPrefix is the prefix of StorageClass parameters that
+// the external-provisioner consumes instead of passing them to CreateVolume.
+const storageClassParameterPrefix = "csi.storage.k8s.io/"
+
+// dryRunCloud validates volumes with an EC2 dry run instead of creating them.
+type dryRunCloud struct {
+	cloud.Cloud
+}
+
+func (c dryRunCloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+	diskOptions.DryRun = true
+	return c.Cloud.CreateDisk(ctx, volumeName, diskOptions)
+}
+
+// storageClassValidator checks the parameters of StorageClasses by running
+// CreateVolume with them against EC2 in dry-run mode, so that misconfigured
+// StorageClasses are reported at startup rather than when a volume is
+// provisioned.
+type storageClassValidator struct {
+	controller controllerService
+	k8sClient  cloud.KubernetesAPIClient
+}
+
+func newStorageClassValidator(c cloud.Cloud, k8sClient cloud.KubernetesAPIClient, driverOptions *DriverOptions) *storageClassValidator {
+	return &storageClassValidator{
+		controller: controllerService{
+			cloud:         dryRunCloud{Cloud: c},
+			inFlight:      internal.NewInFlight(),
+			driverOptions: driverOptions,
+		},
+		k8sClient: k8sClient,
+	}
+}
+
+// run validates the StorageClasses of the driver and logs the result. It
+// returns the errors of the invalid StorageClasses by name.
+func (v *storageClassValidator) run(ctx context.Context) map[string]error {
+	logger := klog.FromContext(ctx)
+	clientset, err := v.k8sClient()
+	if err != nil {
+		logger.Error(err, "Could not validate StorageClasses")
+		return nil
+	}
+	var storageClasses *storagev1.StorageClassList
+	err = retryTransient(ctx, "StorageClasses", v.controller.driverOptions.kubernetesAPIRetryAttempts, func() error {
+		storageClasses, err = clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		logger.Error(err, "Could not list StorageClasses to validate")
+		return nil
+	}
+
+	invalid := map[string]error{}
+	for _, sc := range storageClasses.Items {
+		if sc.Provisioner != DriverName {
+			continue
+		}
+		if err := v.validate(ctx, sc.Name, sc.Parameters, sc.MountOptions); err != nil {
+			logger.Error(err, "StorageClass has invalid parameters, volumes provisioned with it will fail", "storageClass", sc.Name)
+			invalid[sc.Name] = err
+			continue
+		}
+		logger.Info("StorageClass parameters are valid", "storageClass", sc.Name)
+	}
+	return invalid
+}
+
+// validate checks parameters and mount options of the StorageClass name as
+// CreateVolume would, without creating a volume.
+func (v *storageClassValidator) validate(ctx context.Context, name string, parameters map[string]string, mountOptions []string) error {
+	volumeParameters := map[string]string{}
+	for key, value := range parameters {
+		lowerKey := strings.ToLower(key)
+		// BaselineSnapshotKey would snapshot the volume that is never created
+		if lowerKey == BaselineSnapshotKey {
+			continue
+		}
+		if strings.HasPrefix(lowerKey, storageClassParameterPrefix) {
+			continue
+		}
+		volumeParameters[key] = value
+	}
+
+	_, err := v.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: "storageclass-validation-" + name,
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{
+						FsType:     parameters[FSTypeKey],
+						MountFlags: mountOptions,
+					},
+				},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+		Parameters: volumeParameters,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid parameters of StorageClass %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStorageClassValidatorValidate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		parameters   map[string]string
+		mountOptions []string
+		expectMock   func(mockCloud *cloud.MockCloud)
+		expectErr    bool
+	}{
+		{
+			name: "valid parameters",
+			parameters: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeIO2,
+				IopsKey:       "5000",
+				EncryptedKey:  "true",
+				KmsKeyIDKey:   "arn:aws:kms:us-east-1:012345678910:key/abcd1234",
+				FSTypeKey:     FSTypeXfs,
+				"csi.storage.k8s.io/provisioner-secret-name": "secret",
+				BaselineSnapshotKey:                          "true",
+			},
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, diskOptions *cloud.DiskOptions) (*cloud.Disk, error) {
+					if !diskOptions.DryRun {
+						t.Errorf("Expected CreateDisk to be a dry run")
+					}
+					if diskOptions.KmsKeyID != "arn:aws:kms:us-east-1:012345678910:key/abcd1234" {
+						t.Errorf("Expected KMS key to be validated, got %q", diskOptions.KmsKeyID)
+					}
+					return &cloud.Disk{CapacityGiB: 100, AvailabilityZone: expZone}, nil
+				})
+			},
+		},
+		{
+			name:       "unknown parameter",
+			parameters: map[string]string{"unknownKey": "value"},
+			expectErr:  true,
+		},
+		{
+			name: "invalid iops",
+			parameters: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeIO2,
+				IopsKey:       "lots",
+			},
+			expectErr: true,
+		},
+		{
+			name: "mount option not supported by fstype",
+			parameters: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeGP3,
+				FSTypeKey:     FSTypeXfs,
+			},
+			mountOptions: []string{"noatime", "data=writeback"},
+			expectErr:    true,
+		},
+		{
+			name: "rejected by EC2 dry run",
+			parameters: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeIO2,
+				IopsKey:       "1000000",
+			},
+			expectMock: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("InvalidParameterValue"))
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			if tc.expectMock != nil {
+				tc.expectMock(mockCloud)
+			}
+
+			v := newStorageClassValidator(mockCloud, nil, &DriverOptions{})
+			err := v.validate(context.Background(), "test-sc", tc.parameters, tc.mountOptions)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestStorageClassValidatorRun(t *testing.T) {
+	storageClasses := []runtime.Object{
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "valid"},
+			Provisioner: DriverName,
+			Parameters:  map[string]string{VolumeTypeKey: cloud.VolumeTypeGP3},
+		},
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "invalid"},
+			Provisioner: DriverName,
+			Parameters:  map[string]string{VolumeTypeKey: cloud.VolumeTypeGP3, ThroughputKey: "fast"},
+		},
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "other-provisioner"},
+			Provisioner: "kubernetes.io/aws-ebs",
+			Parameters:  map[string]string{"unknownKey": "value"},
+		},
+	}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+	mockCloud := cloud.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Any(), gomock.Any()).Return(&cloud.Disk{CapacityGiB: 100, AvailabilityZone: expZone}, nil)
+
+	clientset := fake.NewSimpleClientset(storageClasses...)
+	k8sClient := func() (kubernetes.Interface, error) { return clientset, nil }
+
+	invalid := newStorageClassValidator(mockCloud, k8sClient, &DriverOptions{}).run(context.Background())
+	if len(invalid) != 1 || invalid["invalid"] == nil {
+		t.Fatalf("Expected only StorageClass \"invalid\" to be invalid, got: %v", invalid)
+	}
+}
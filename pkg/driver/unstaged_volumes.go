@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sync"
+	"time"
+)
+
+// unstagedVolumeTTL is how long after it was unstaged from the node a volume
+// whose device is looked up again is considered reattached.
+const unstagedVolumeTTL = 1 * time.Hour
+
+// unstagedVolumes records the volumes unstaged from the node, whose device is
+// looked up again after a reattach rather than a first attach. A volume is
+// forgotten once its device was found again or after unstagedVolumeTTL, e.g.
+// when it was attached to another node instead, so that the record does not
+// grow with every volume the node ever had.
+type unstagedVolumes struct {
+	mu sync.Mutex
+	// unstaged is when each volume was unstaged, by volume ID
+	unstaged map[string]time.Time
+	ttl      time.Duration
+}
+
+func newUnstagedVolumes() *unstagedVolumes {
+	return &unstagedVolumes{
+		unstaged: map[string]time.Time{},
+		ttl:      unstagedVolumeTTL,
+	}
+}
+
+// add records that volumeID was unstaged and forgets the expired volumes.
+func (u *unstagedVolumes) add(volumeID string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	for id, unstaged := range u.unstaged {
+		if now.Sub(unstaged) > u.ttl {
+			delete(u.unstaged, id)
+		}
+	}
+	u.unstaged[volumeID] = now
+}
+
+// remove forgets volumeID, e.g. once its device was found again.
+func (u *unstagedVolumes) remove(volumeID string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.unstaged, volumeID)
+}
+
+// has reports whether volumeID was unstaged less than the TTL ago.
+func (u *unstagedVolumes) has(volumeID string) bool {
+	if u == nil {
+		return false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	unstaged, ok := u.unstaged[volumeID]
+	if ok && time.Since(unstaged) > u.ttl {
+		delete(u.unstaged, volumeID)
+		return false
+	}
+	return ok
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnstagedVolumes(t *testing.T) {
+	testCases := []struct {
+		name string
+		// unstagedAgo is how long ago each volume was unstaged
+		unstagedAgo map[string]time.Duration
+		add         []string
+		remove      []string
+		expHas      map[string]bool
+		expLen      int
+	}{
+		{
+			name:   "unstaged volume is recorded",
+			add:    []string{"vol-1"},
+			expHas: map[string]bool{"vol-1": true, "vol-2": false},
+			expLen: 1,
+		},
+		{
+			name:        "volume whose device was found again is forgotten",
+			unstagedAgo: map[string]time.Duration{"vol-1": time.Minute},
+			remove:      []string{"vol-1"},
+			expHas:      map[string]bool{"vol-1": false},
+			expLen:      0,
+		},
+		{
+			name:        "volume unstaged longer than the TTL ago is forgotten",
+			unstagedAgo: map[string]time.Duration{"vol-1": 2 * unstagedVolumeTTL, "vol-2": time.Minute},
+			expHas:      map[string]bool{"vol-1": false, "vol-2": true},
+			expLen:      1,
+		},
+		{
+			name:        "expired volumes are forgotten when another one is unstaged",
+			unstagedAgo: map[string]time.Duration{"vol-1": 2 * unstagedVolumeTTL, "vol-2": 2 * unstagedVolumeTTL},
+			add:         []string{"vol-3"},
+			expLen:      1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := newUnstagedVolumes()
+			for volumeID, ago := range tc.unstagedAgo {
+				u.unstaged[volumeID] = time.Now().Add(-ago)
+			}
+			for _, volumeID := range tc.add {
+				u.add(volumeID)
+			}
+			for _, volumeID := range tc.remove {
+				u.remove(volumeID)
+			}
+			for volumeID, expHas := range tc.expHas {
+				if has := u.has(volumeID); has != expHas {
+					t.Fatalf("Expected volume %s unstaged %v, got %v", volumeID, expHas, has)
+				}
+			}
+			if len(u.unstaged) != tc.expLen {
+				t.Fatalf("Expected %d recorded volumes, got %d: %v", tc.expLen, len(u.unstaged), u.unstaged)
+			}
+		})
+	}
+}
+
+func TestUnstagedVolumesNil(t *testing.T) {
+	var u *unstagedVolumes
+	u.add("vol-1")
+	u.remove("vol-1")
+	if u.has("vol-1") {
+		t.Fatalf("Expected no volume recorded without tracking")
+	}
+}
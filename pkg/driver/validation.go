@@ -18,10 +18,14 @@ package driver
 
 import (
 	"fmt"
+	"net"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/klog/v2"
 )
 
@@ -34,6 +38,58 @@ func ValidateDriverOptions(options *DriverOptions) error {
 		return fmt.Errorf("Invalid mode: %w", err)
 	}
 
+	if err := validateMetadataSources(options.metadataSources); err != nil {
+		return fmt.Errorf("Invalid metadata sources: %w", err)
+	}
+
+	if options.metadataRetryAttempts < 0 {
+		return fmt.Errorf("Invalid metadata retry attempts: must not be negative")
+	}
+
+	if options.kubernetesAPIRetryAttempts < 0 {
+		return fmt.Errorf("Invalid Kubernetes API retry attempts: must not be negative")
+	}
+
+	if options.fsrWarmCacheInterval > 0 && options.kubernetesClusterID == "" {
+		return fmt.Errorf("Invalid fast snapshot restore warm cache: k8s-tag-cluster-id must be set to tell the snapshots of this cluster apart")
+	}
+
+	if err := validateIMDSVersion(options.imdsVersion); err != nil {
+		return fmt.Errorf("Invalid IMDS version: %w", err)
+	}
+
+	if err := validateCloudProvider(options.cloudProvider); err != nil {
+		return fmt.Errorf("Invalid cloud provider: %w", err)
+	}
+
+	if err := options.endpointOptions.Validate(); err != nil {
+		return fmt.Errorf("Invalid endpoints: %w", err)
+	}
+
+	if err := options.roleOptions.Validate(); err != nil {
+		return fmt.Errorf("Invalid role: %w", err)
+	}
+
+	if err := validateVolumePlacementStrategy(options.volumePlacementStrategy); err != nil {
+		return fmt.Errorf("Invalid volume placement strategy: %w", err)
+	}
+
+	if err := validateMountOptions("", options.defaultMountOptions); err != nil {
+		return fmt.Errorf("Invalid default mount options: %w", err)
+	}
+
+	if err := validateDiscardVolumeTypes(options.discardVolumeTypes); err != nil {
+		return fmt.Errorf("Invalid discard volume types: %w", err)
+	}
+
+	if err := validateLeaderElectionOptions(options.leaderElection); err != nil {
+		return fmt.Errorf("Invalid leader election options: %w", err)
+	}
+
+	if err := validateAdminEndpoint(options.adminEndpoint); err != nil {
+		return fmt.Errorf("Invalid admin endpoint: %w", err)
+	}
+
 	return nil
 }
 
@@ -65,6 +121,9 @@ func validateExtraTags(tags map[string]string, warnOnly bool) error {
 		if k == cloud.SnapshotNameTagKey {
 			return fmt.Errorf("Tag key '%s' is reserved", cloud.SnapshotNameTagKey)
 		}
+		if k == cloud.GroupSnapshotIDTagKey {
+			return fmt.Errorf("Tag key '%s' is reserved", cloud.GroupSnapshotIDTagKey)
+		}
 		if strings.HasPrefix(k, cloud.KubernetesTagKeyPrefix) {
 			return fmt.Errorf("Tag key prefix '%s' is reserved", cloud.KubernetesTagKeyPrefix)
 		}
@@ -85,6 +144,7 @@ func validateExtraTags(tags map[string]string, warnOnly bool) error {
 		if err != nil {
 			if warnOnly {
 				klog.InfoS("Skipping tag: the following key-value pair is not valid", "key", k, "value", v, "err", err)
+				delete(tags, k)
 			} else {
 				return err
 			}
@@ -100,3 +160,89 @@ func validateMode(mode Mode) error {
 
 	return nil
 }
+
+func validateMetadataSources(metadataSources []string) error {
+	seen := map[string]bool{}
+	for _, source := range metadataSources {
+		if source != cloud.MetadataSourceIMDS && source != cloud.MetadataSourceKubernetes {
+			return fmt.Errorf("Metadata source is not supported (actual: %s, supported: %v)", source, cloud.DefaultMetadataSources)
+		}
+		if seen[source] {
+			return fmt.Errorf("Metadata source %s is listed more than once", source)
+		}
+		seen[source] = true
+	}
+
+	return nil
+}
+
+func validateIMDSVersion(imdsVersion string) error {
+	if imdsVersion != "" && !slices.Contains(cloud.IMDSVersions, imdsVersion) {
+		return fmt.Errorf("IMDS version is not supported (actual: %s, supported: %v)", imdsVersion, cloud.IMDSVersions)
+	}
+	return nil
+}
+
+func validateCloudProvider(cloudProvider string) error {
+	if cloudProvider != "" && !slices.Contains(cloud.CloudProviders, cloudProvider) {
+		return fmt.Errorf("Cloud provider is not supported (actual: %s, supported: %v)", cloudProvider, cloud.CloudProviders)
+	}
+	return nil
+}
+
+func validateVolumePlacementStrategy(strategy string) error {
+	if strategy != "" && !slices.Contains(PlacementStrategies, strategy) {
+		return fmt.Errorf("Volume placement strategy is not supported (actual: %s, supported: %v)", strategy, PlacementStrategies)
+	}
+	return nil
+}
+
+func validateDiscardVolumeTypes(volumeTypes []string) error {
+	for _, volumeType := range volumeTypes {
+		if !slices.Contains(cloud.ValidVolumeTypes, volumeType) {
+			return fmt.Errorf("Volume type is not supported (actual: %s, supported: %v)", volumeType, cloud.ValidVolumeTypes)
+		}
+	}
+	return nil
+}
+
+// validateAdminEndpoint checks that the unauthenticated admin server, which
+// can toggle maintenance mode, only listens on a loopback address.
+func validateAdminEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return fmt.Errorf("%q is not a host:port address", endpoint)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%q does not listen on a loopback address, e.g. 127.0.0.1", endpoint)
+	}
+	return nil
+}
+
+func validateLeaderElectionOptions(options LeaderElectionOptions) error {
+	if !options.Enabled {
+		return nil
+	}
+	if options.Namespace == "" {
+		return fmt.Errorf("Lease namespace cannot be empty")
+	}
+	if options.LeaseName == "" {
+		return fmt.Errorf("Lease name cannot be empty")
+	}
+	if options.RetryPeriod <= 0 {
+		return fmt.Errorf("Retry period must be positive (actual: %v)", options.RetryPeriod)
+	}
+	if options.RenewDeadline <= time.Duration(leaderelection.JitterFactor*float64(options.RetryPeriod)) {
+		return fmt.Errorf("Renew deadline must be greater than %v times the retry period (actual: %v, retry period: %v)", leaderelection.JitterFactor, options.RenewDeadline, options.RetryPeriod)
+	}
+	if options.LeaseDuration <= options.RenewDeadline {
+		return fmt.Errorf("Lease duration must be greater than the renew deadline (actual: %v, renew deadline: %v)", options.LeaseDuration, options.RenewDeadline)
+	}
+	return nil
+}
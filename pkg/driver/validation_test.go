@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 )
@@ -85,6 +86,20 @@ func TestValidateExtraVolumeTags(t *testing.T) {
 			},
 			expErr: fmt.Errorf("Tag key '%s' is reserved", cloud.AwsEbsDriverTagKey),
 		},
+		{
+			name: "invalid tag: reserved snapshot name key",
+			tags: map[string]string{
+				cloud.SnapshotNameTagKey: "extra-tag-value",
+			},
+			expErr: fmt.Errorf("Tag key '%s' is reserved", cloud.SnapshotNameTagKey),
+		},
+		{
+			name: "invalid tag: reserved group snapshot ID key",
+			tags: map[string]string{
+				cloud.GroupSnapshotIDTagKey: "extra-tag-value",
+			},
+			expErr: fmt.Errorf("Tag key '%s' is reserved", cloud.GroupSnapshotIDTagKey),
+		},
 		{
 			name: "invalid tag: reserved Kubernetes key prefix",
 			tags: map[string]string{
@@ -116,6 +131,21 @@ func TestValidateExtraVolumeTags(t *testing.T) {
 	}
 }
 
+func TestValidateExtraTagsWarnOnly(t *testing.T) {
+	tags := map[string]string{
+		cloud.SnapshotNameTagKey: "clobbered",
+		"extra-tag-key":          "extra-tag-value",
+	}
+	if err := validateExtraTags(tags, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Invalid tags are skipped so that they do not override the tags of the driver
+	expTags := map[string]string{"extra-tag-key": "extra-tag-value"}
+	if !reflect.DeepEqual(tags, expTags) {
+		t.Fatalf("expected tags %v, got %v", expTags, tags)
+	}
+}
+
 func TestValidateMode(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -154,11 +184,300 @@ func TestValidateMode(t *testing.T) {
 	}
 }
 
+func TestValidateMetadataSources(t *testing.T) {
+	testCases := []struct {
+		name            string
+		metadataSources []string
+		expErr          error
+	}{
+		{
+			name:   "valid: default sources",
+			expErr: nil,
+		},
+		{
+			name:            "valid: kubernetes preferred",
+			metadataSources: []string{cloud.MetadataSourceKubernetes, cloud.MetadataSourceIMDS},
+			expErr:          nil,
+		},
+		{
+			name:            "invalid: unknown source",
+			metadataSources: []string{cloud.MetadataSourceIMDS, "unknown"},
+			expErr:          fmt.Errorf("Metadata source is not supported (actual: unknown, supported: %v)", cloud.DefaultMetadataSources),
+		},
+		{
+			name:            "invalid: duplicate source",
+			metadataSources: []string{cloud.MetadataSourceIMDS, cloud.MetadataSourceIMDS},
+			expErr:          fmt.Errorf("Metadata source imds is listed more than once"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMetadataSources(tc.metadataSources)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestValidateIMDSVersion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		imdsVersion string
+		expErr      error
+	}{
+		{
+			name:   "valid: default version",
+			expErr: nil,
+		},
+		{
+			name:        "valid: v2 only",
+			imdsVersion: cloud.IMDSVersionV2,
+			expErr:      nil,
+		},
+		{
+			name:        "invalid: unknown version",
+			imdsVersion: "v1",
+			expErr:      fmt.Errorf("IMDS version is not supported (actual: v1, supported: %v)", cloud.IMDSVersions),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateIMDSVersion(tc.imdsVersion)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestValidateCloudProvider(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cloudProvider string
+		expErr        error
+	}{
+		{
+			name:   "valid: default cloud provider",
+			expErr: nil,
+		},
+		{
+			name:          "valid: fake",
+			cloudProvider: cloud.CloudProviderFake,
+			expErr:        nil,
+		},
+		{
+			name:          "invalid: unknown cloud provider",
+			cloudProvider: "gcp",
+			expErr:        fmt.Errorf("Cloud provider is not supported (actual: gcp, supported: %v)", cloud.CloudProviders),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCloudProvider(tc.cloudProvider)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestValidateVolumePlacementStrategy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		strategy string
+		expErr   error
+	}{
+		{
+			name:   "valid: default strategy",
+			expErr: nil,
+		},
+		{
+			name:     "valid: round-robin",
+			strategy: PlacementStrategyRoundRobin,
+			expErr:   nil,
+		},
+		{
+			name:     "invalid: unknown strategy",
+			strategy: "random",
+			expErr:   fmt.Errorf("Volume placement strategy is not supported (actual: random, supported: %v)", PlacementStrategies),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateVolumePlacementStrategy(tc.strategy)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestValidateAdminEndpoint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		endpoint string
+		expErr   error
+	}{
+		{
+			name:   "valid: disabled",
+			expErr: nil,
+		},
+		{
+			name:     "valid: IPv4 loopback",
+			endpoint: "127.0.0.1:8082",
+			expErr:   nil,
+		},
+		{
+			name:     "valid: IPv6 loopback",
+			endpoint: "[::1]:8082",
+			expErr:   nil,
+		},
+		{
+			name:     "valid: localhost",
+			endpoint: "localhost:8082",
+			expErr:   nil,
+		},
+		{
+			name:     "invalid: all addresses",
+			endpoint: ":8082",
+			expErr:   fmt.Errorf("%q does not listen on a loopback address, e.g. 127.0.0.1", ":8082"),
+		},
+		{
+			name:     "invalid: pod address",
+			endpoint: "10.0.0.1:8082",
+			expErr:   fmt.Errorf("%q does not listen on a loopback address, e.g. 127.0.0.1", "10.0.0.1:8082"),
+		},
+		{
+			name:     "invalid: no port",
+			endpoint: "127.0.0.1",
+			expErr:   fmt.Errorf("%q is not a host:port address", "127.0.0.1"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAdminEndpoint(tc.endpoint)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestValidateDiscardVolumeTypes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		volumeTypes []string
+		expErr      error
+	}{
+		{
+			name:   "valid: no volume types",
+			expErr: nil,
+		},
+		{
+			name:        "valid: gp3 and io2",
+			volumeTypes: []string{cloud.VolumeTypeGP3, cloud.VolumeTypeIO2},
+			expErr:      nil,
+		},
+		{
+			name:        "invalid: unknown volume type",
+			volumeTypes: []string{cloud.VolumeTypeGP3, "gp9"},
+			expErr:      fmt.Errorf("Volume type is not supported (actual: gp9, supported: %v)", cloud.ValidVolumeTypes),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateDiscardVolumeTypes(tc.volumeTypes)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestValidateLeaderElectionOptions(t *testing.T) {
+	validOptions := LeaderElectionOptions{
+		Enabled:       true,
+		Namespace:     "kube-system",
+		LeaseName:     "ebs-csi-controller",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   5 * time.Second,
+	}
+	withOptions := func(modify func(*LeaderElectionOptions)) LeaderElectionOptions {
+		options := validOptions
+		modify(&options)
+		return options
+	}
+
+	testCases := []struct {
+		name    string
+		options LeaderElectionOptions
+		expErr  error
+	}{
+		{
+			name:    "valid: disabled",
+			options: LeaderElectionOptions{},
+			expErr:  nil,
+		},
+		{
+			name:    "valid: defaults",
+			options: validOptions,
+			expErr:  nil,
+		},
+		{
+			name:    "invalid: empty namespace",
+			options: withOptions(func(o *LeaderElectionOptions) { o.Namespace = "" }),
+			expErr:  fmt.Errorf("Lease namespace cannot be empty"),
+		},
+		{
+			name:    "invalid: empty lease name",
+			options: withOptions(func(o *LeaderElectionOptions) { o.LeaseName = "" }),
+			expErr:  fmt.Errorf("Lease name cannot be empty"),
+		},
+		{
+			name:    "invalid: zero retry period",
+			options: withOptions(func(o *LeaderElectionOptions) { o.RetryPeriod = 0 }),
+			expErr:  fmt.Errorf("Retry period must be positive (actual: 0s)"),
+		},
+		{
+			name:    "invalid: renew deadline too short for retry period",
+			options: withOptions(func(o *LeaderElectionOptions) { o.RenewDeadline = 6 * time.Second }),
+			expErr:  fmt.Errorf("Renew deadline must be greater than 1.2 times the retry period (actual: 6s, retry period: 5s)"),
+		},
+		{
+			name:    "invalid: lease duration not greater than renew deadline",
+			options: withOptions(func(o *LeaderElectionOptions) { o.LeaseDuration = 10 * time.Second }),
+			expErr:  fmt.Errorf("Lease duration must be greater than the renew deadline (actual: 10s, renew deadline: 10s)"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateLeaderElectionOptions(tc.options)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
 func TestValidateDriverOptions(t *testing.T) {
 	testCases := []struct {
 		name            string
 		mode            Mode
 		extraVolumeTags map[string]string
+		endpointOptions cloud.EndpointOptions
+		roleOptions     cloud.RoleOptions
+		retryAttempts   int
+		apiRetries      int
+		fsrInterval     time.Duration
 		expErr          error
 	}{
 		{
@@ -179,13 +498,49 @@ func TestValidateDriverOptions(t *testing.T) {
 			},
 			expErr: fmt.Errorf("Invalid extra tags: %w", fmt.Errorf("Tag key too long (actual: %d, limit: %d)", cloud.MaxTagKeyLength+1, cloud.MaxTagKeyLength)),
 		},
+		{
+			name:            "fail because endpoint options are invalid",
+			mode:            AllMode,
+			endpointOptions: cloud.EndpointOptions{EC2Endpoint: "ec2.us-west-2.amazonaws.com"},
+			expErr:          fmt.Errorf("Invalid endpoints: %w", fmt.Errorf("invalid EC2 endpoint: %w", fmt.Errorf("%q is not an http or https URL", "ec2.us-west-2.amazonaws.com"))),
+		},
+		{
+			name:        "fail because role options are invalid",
+			mode:        AllMode,
+			roleOptions: cloud.RoleOptions{ExternalID: "external-id"},
+			expErr:      fmt.Errorf("Invalid role: %w", fmt.Errorf("external ID %q set without a role to assume", "external-id")),
+		},
+		{
+			name:          "fail because metadata retry attempts are negative",
+			mode:          AllMode,
+			retryAttempts: -1,
+			expErr:        fmt.Errorf("Invalid metadata retry attempts: must not be negative"),
+		},
+		{
+			name:       "fail because Kubernetes API retry attempts are negative",
+			mode:       AllMode,
+			apiRetries: -1,
+			expErr:     fmt.Errorf("Invalid Kubernetes API retry attempts: must not be negative"),
+		},
+		{
+			name:        "fail because fast snapshot restore warm cache is enabled without cluster ID",
+			mode:        AllMode,
+			fsrInterval: time.Minute,
+			expErr:      fmt.Errorf("Invalid fast snapshot restore warm cache: k8s-tag-cluster-id must be set to tell the snapshots of this cluster apart"),
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			err := ValidateDriverOptions(&DriverOptions{
-				extraTags: tc.extraVolumeTags,
-				mode:      tc.mode,
+				extraTags:       tc.extraVolumeTags,
+				mode:            tc.mode,
+				endpointOptions: tc.endpointOptions,
+				roleOptions:     tc.roleOptions,
+
+				metadataRetryAttempts:      tc.retryAttempts,
+				kubernetesAPIRetryAttempts: tc.apiRetries,
+				fsrWarmCacheInterval:       tc.fsrInterval,
 			})
 			if !reflect.DeepEqual(err, tc.expErr) {
 				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
@@ -17,6 +17,8 @@ var (
 type metricRecorder struct {
 	registry metrics.KubeRegistry
 	metrics  map[string]interface{}
+	// mu guards metrics, which is registered lazily from concurrent callers
+	mu sync.Mutex
 }
 
 // Recorder returns the singleton instance of metricRecorder.
@@ -42,14 +44,14 @@ func (m *metricRecorder) IncreaseCount(name string, labels map[string]string) {
 		return // recorder is not initialized
 	}
 
+	m.mu.Lock()
 	metric, ok := m.metrics[name]
-
 	if !ok {
 		klog.V(4).InfoS("Metric not found, registering", "name", name, "labels", labels)
 		m.registerCounterVec(name, "ebs_csi_aws_com metric", getLabelNames(labels))
-		m.IncreaseCount(name, labels)
-		return
+		metric = m.metrics[name]
 	}
+	m.mu.Unlock()
 
 	metric.(*metrics.CounterVec).With(metrics.Labels(labels)).Inc()
 }
@@ -59,18 +61,35 @@ func (m *metricRecorder) ObserveHistogram(name string, value float64, labels map
 	if m == nil {
 		return // recorder is not initialized
 	}
+	m.mu.Lock()
 	metric, ok := m.metrics[name]
-
 	if !ok {
 		klog.V(4).InfoS("Metric not found, registering", "name", name, "labels", labels, "buckets", buckets)
 		m.registerHistogramVec(name, "ebs_csi_aws_com metric", getLabelNames(labels), buckets)
-		m.ObserveHistogram(name, value, labels, buckets)
-		return
+		metric = m.metrics[name]
 	}
+	m.mu.Unlock()
 
 	metric.(*metrics.HistogramVec).With(metrics.Labels(labels)).Observe(value)
 }
 
+// AddGauge adds the given value, which may be negative, to the gauge metric.
+func (m *metricRecorder) AddGauge(name string, value float64, labels map[string]string) {
+	if m == nil {
+		return // recorder is not initialized
+	}
+	m.mu.Lock()
+	metric, ok := m.metrics[name]
+	if !ok {
+		klog.V(4).InfoS("Metric not found, registering", "name", name, "labels", labels)
+		m.registerGaugeVec(name, "ebs_csi_aws_com metric", getLabelNames(labels))
+		metric = m.metrics[name]
+	}
+	m.mu.Unlock()
+
+	metric.(*metrics.GaugeVec).With(metrics.Labels(labels)).Add(value)
+}
+
 // InitializeMetricsHandler starts a new HTTP server to expose the metrics.
 func (m *metricRecorder) InitializeMetricsHandler(address, path string) {
 	if m == nil {
@@ -119,6 +138,15 @@ func (m *metricRecorder) registerCounterVec(name, help string, labels []string)
 	m.registry.MustRegister(counter)
 }
 
+func (m *metricRecorder) registerGaugeVec(name, help string, labels []string) {
+	if _, exists := m.metrics[name]; exists {
+		return
+	}
+	gauge := createGaugeVec(name, help, labels)
+	m.metrics[name] = gauge
+	m.registry.MustRegister(gauge)
+}
+
 func createHistogramVec(name, help string, labels []string, buckets []float64) *metrics.HistogramVec {
 	opts := &metrics.HistogramOpts{
 		Name:           name,
@@ -140,6 +168,17 @@ func createCounterVec(name, help string, labels []string) *metrics.CounterVec {
 	)
 }
 
+func createGaugeVec(name, help string, labels []string) *metrics.GaugeVec {
+	return metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           name,
+			Help:           help,
+			StabilityLevel: metrics.ALPHA,
+		},
+		labels,
+	)
+}
+
 func getLabelNames(labels map[string]string) []string {
 	names := make([]string, 0, len(labels))
 	for n := range labels {
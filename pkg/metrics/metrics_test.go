@@ -42,6 +42,20 @@ func TestMetricRecorder(t *testing.T) {
 			`,
 			recorder: true,
 		},
+		{
+			name: "TestMetricRecorder: AddGaugeMetric",
+			exec: func(m *metricRecorder) {
+				m.AddGauge("test_gauge", 1, map[string]string{"key": "value"})
+				m.AddGauge("test_gauge", 1, map[string]string{"key": "value"})
+				m.AddGauge("test_gauge", -1, map[string]string{"key": "value"})
+			},
+			expected: `
+			# HELP test_gauge ebs_csi_aws_com metric
+			# TYPE test_gauge gauge
+			test_gauge{key="value"} 1
+			`,
+			recorder: true,
+		},
 		{
 			name: "TestMetricRecorder: Re-register metric",
 			exec: func(m *metricRecorder) {
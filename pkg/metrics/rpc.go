@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// rpcDurationBuckets covers RPCs from quick node operations to attachments
+// and modifications that take minutes.
+var rpcDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// UnaryServerInterceptor records the number, latency and result code of the
+// RPCs served by the driver.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	m := Recorder()
+	if m == nil {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	labels := map[string]string{
+		"method": path.Base(info.FullMethod),
+		"code":   status.Code(err).String(),
+	}
+	m.IncreaseCount("ebs_csi_rpc_requests_total", labels)
+	m.ObserveHistogram("ebs_csi_rpc_duration_seconds", time.Since(start).Seconds(), labels, rpcDurationBuckets)
+	return resp, err
+}
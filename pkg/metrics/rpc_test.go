@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := InitializeRecorder()
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	failingHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "volume not found")
+	}
+
+	if _, err := UnaryServerInterceptor(context.Background(), "req", info, handler); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := UnaryServerInterceptor(context.Background(), "req", info, failingHandler); status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound error, got: %v", err)
+	}
+
+	expected := `
+	# HELP ebs_csi_rpc_requests_total [ALPHA] ebs_csi_aws_com metric
+	# TYPE ebs_csi_rpc_requests_total counter
+	ebs_csi_rpc_requests_total{code="NotFound",method="ControllerPublishVolume"} 1
+	ebs_csi_rpc_requests_total{code="OK",method="ControllerPublishVolume"} 1
+	`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected), "ebs_csi_rpc_requests_total"); err != nil {
+		t.Fatal(err)
+	}
+	durations, err := testutil.GetHistogramVecFromGatherer(m.registry, "ebs_csi_rpc_duration_seconds", map[string]string{"method": "ControllerPublishVolume"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := durations.GetAggregatedSampleCount(); count != 2 {
+		t.Fatalf("Expected 2 RPC durations, got %d", count)
+	}
+}
@@ -0,0 +1,243 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mounter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/mount-utils"
+	utilexec "k8s.io/utils/exec"
+)
+
+const nsenterCmd = "nsenter"
+
+// NewSafeMounterInNamespace returns a SafeFormatAndMount that mounts, unmounts
+// and formats volumes in the mount namespace referenced by namespacePath
+// (e.g. /proc/1/ns/mnt). All commands are run through nsenter, because the
+// mount namespace of a multi-threaded process cannot be switched in-process.
+func NewSafeMounterInNamespace(namespacePath string) (*mount.SafeFormatAndMount, error) {
+	if _, err := os.Stat(namespacePath); err != nil {
+		return nil, fmt.Errorf("mount namespace %q is not accessible: %w", namespacePath, err)
+	}
+	exec := newNamespaceExec(namespacePath, utilexec.New())
+	return &mount.SafeFormatAndMount{
+		Interface: newNamespaceMounter(namespacePath, exec),
+		Exec:      exec,
+	}, nil
+}
+
+// namespaceExec runs every command in a mount namespace.
+type namespaceExec struct {
+	utilexec.Interface
+	namespacePath string
+}
+
+func newNamespaceExec(namespacePath string, exec utilexec.Interface) utilexec.Interface {
+	return &namespaceExec{Interface: exec, namespacePath: namespacePath}
+}
+
+func (e *namespaceExec) Command(cmd string, args ...string) utilexec.Cmd {
+	return e.Interface.Command(nsenterCmd, e.nsenterArgs(cmd, args)...)
+}
+
+func (e *namespaceExec) CommandContext(ctx context.Context, cmd string, args ...string) utilexec.Cmd {
+	return e.Interface.CommandContext(ctx, nsenterCmd, e.nsenterArgs(cmd, args)...)
+}
+
+func (e *namespaceExec) nsenterArgs(cmd string, args []string) []string {
+	return append([]string{"--mount=" + e.namespacePath, "--", cmd}, args...)
+}
+
+// namespaceMounter implements mount.Interface for a mount namespace. Mount
+// table lookups read /proc/mounts from inside the namespace, while paths are
+// expected to be reachable from the driver's own namespace too.
+type namespaceMounter struct {
+	namespacePath string
+	exec          utilexec.Interface
+}
+
+var _ mount.Interface = &namespaceMounter{}
+
+func newNamespaceMounter(namespacePath string, exec utilexec.Interface) mount.Interface {
+	return &namespaceMounter{namespacePath: namespacePath, exec: exec}
+}
+
+func (m *namespaceMounter) Mount(source string, target string, fstype string, options []string) error {
+	return m.MountSensitive(source, target, fstype, options, nil)
+}
+
+func (m *namespaceMounter) MountSensitive(source string, target string, fstype string, options []string, sensitiveOptions []string) error {
+	return m.MountSensitiveWithoutSystemdWithMountFlags(source, target, fstype, options, sensitiveOptions, nil)
+}
+
+func (m *namespaceMounter) MountSensitiveWithoutSystemd(source string, target string, fstype string, options []string, sensitiveOptions []string) error {
+	return m.MountSensitiveWithoutSystemdWithMountFlags(source, target, fstype, options, sensitiveOptions, nil)
+}
+
+func (m *namespaceMounter) MountSensitiveWithoutSystemdWithMountFlags(source string, target string, fstype string, options []string, sensitiveOptions []string, mountFlags []string) error {
+	// Bind mounts with options other than "bind" require a remount, see mount.Mounter
+	bind, bindOpts, bindRemountOpts, bindRemountOptsSensitive := mount.MakeBindOptsSensitive(options, sensitiveOptions)
+	if bind {
+		if err := m.doMount(source, target, fstype, bindOpts, bindRemountOptsSensitive, mountFlags); err != nil {
+			return err
+		}
+		return m.doMount(source, target, fstype, bindRemountOpts, bindRemountOptsSensitive, mountFlags)
+	}
+	return m.doMount(source, target, fstype, options, sensitiveOptions, mountFlags)
+}
+
+func (m *namespaceMounter) doMount(source string, target string, fstype string, options []string, sensitiveOptions []string, mountFlags []string) error {
+	mountArgs, mountArgsLogStr := mount.MakeMountArgsSensitiveWithMountFlags(source, target, fstype, options, sensitiveOptions, mountFlags)
+	klog.V(4).InfoS("Mounting in mount namespace", "namespace", m.namespacePath, "args", mountArgsLogStr)
+	output, err := m.exec.Command("mount", mountArgs...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mount failed in mount namespace %q: %w\nMounting arguments: %s\nOutput: %s", m.namespacePath, err, mountArgsLogStr, string(output))
+	}
+	return nil
+}
+
+func (m *namespaceMounter) Unmount(target string) error {
+	klog.V(4).InfoS("Unmounting in mount namespace", "namespace", m.namespacePath, "target", target)
+	output, err := m.exec.Command("umount", target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unmount failed in mount namespace %q: %w\nUnmounting arguments: %s\nOutput: %s", m.namespacePath, err, target, string(output))
+	}
+	return nil
+}
+
+func (m *namespaceMounter) List() ([]mount.MountPoint, error) {
+	output, err := m.exec.Command("cat", "/proc/mounts").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mounts in mount namespace %q: %w: %s", m.namespacePath, err, string(output))
+	}
+	return parseMounts(output)
+}
+
+func (m *namespaceMounter) IsLikelyNotMountPoint(file string) (bool, error) {
+	mounted, err := m.IsMountPoint(file)
+	return !mounted, err
+}
+
+func (m *namespaceMounter) CanSafelySkipMountPointCheck() bool {
+	return false
+}
+
+func (m *namespaceMounter) IsMountPoint(file string) (bool, error) {
+	if _, err := os.Stat(file); err != nil {
+		return false, err
+	}
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		resolved = file
+	}
+	mps, err := m.List()
+	if err != nil {
+		return false, err
+	}
+	for _, mp := range mps {
+		if mp.Path == resolved {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *namespaceMounter) GetMountRefs(pathname string) ([]string, error) {
+	mps, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	device := ""
+	for _, mp := range mps {
+		if mp.Path == pathname {
+			device = mp.Device
+			break
+		}
+	}
+	if device == "" {
+		return nil, nil
+	}
+	var refs []string
+	for _, mp := range mps {
+		if mp.Device == device && mp.Path != pathname {
+			refs = append(refs, mp.Path)
+		}
+	}
+	return refs, nil
+}
+
+// parseMounts parses the content of /proc/mounts.
+func parseMounts(content []byte) ([]mount.MountPoint, error) {
+	var mps []mount.MountPoint
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("wrong number of fields (expected 6, got %d): %s", len(fields), line)
+		}
+		freq, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return nil, err
+		}
+		pass, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return nil, err
+		}
+		mps = append(mps, mount.MountPoint{
+			Device: unescapeMountField(fields[0]),
+			Path:   unescapeMountField(fields[1]),
+			Type:   unescapeMountField(fields[2]),
+			Opts:   strings.Split(unescapeMountField(fields[3]), ","),
+			Freq:   freq,
+			Pass:   pass,
+		})
+	}
+	return mps, nil
+}
+
+// unescapeMountField replaces the octal escapes of a field of /proc/mounts,
+// e.g. \040 for the spaces of a path, by the characters they stand for.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) && isOctal(field[i+1]) && isOctal(field[i+2]) && isOctal(field[i+3]) {
+			b.WriteByte((field[i+1]-'0')<<6 | (field[i+2]-'0')<<3 | (field[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
@@ -0,0 +1,157 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mounter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	utilexec "k8s.io/utils/exec"
+	fakeexec "k8s.io/utils/exec/testing"
+)
+
+const testNamespace = "/proc/1/ns/mnt"
+
+// newFakeNamespaceMounter returns a namespaceMounter whose commands return
+// outputs in order and records the command lines it ran.
+func newFakeNamespaceMounter(outputs []string, errs []error) (*namespaceMounter, *[][]string) {
+	var cmds [][]string
+	fexec := &fakeexec.FakeExec{}
+	for i := range outputs {
+		output, err := outputs[i], errs[i]
+		fcmd := &fakeexec.FakeCmd{
+			CombinedOutputScript: []fakeexec.FakeAction{
+				func() ([]byte, []byte, error) { return []byte(output), nil, err },
+			},
+		}
+		fexec.CommandScript = append(fexec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+			cmds = append(cmds, append([]string{cmd}, args...))
+			return fakeexec.InitFakeCmd(fcmd, cmd, args...)
+		})
+	}
+	exec := newNamespaceExec(testNamespace, fexec)
+	return &namespaceMounter{namespacePath: testNamespace, exec: exec}, &cmds
+}
+
+func TestNamespaceMounterMount(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options []string
+		expCmds [][]string
+	}{
+		{
+			name:    "mount",
+			options: []string{"defaults"},
+			expCmds: [][]string{
+				{"nsenter", "--mount=" + testNamespace, "--", "mount", "-t", "ext4", "-o", "defaults", "/dev/xvdba", "/mnt/target"},
+			},
+		},
+		{
+			name:    "read-only bind mount is remounted",
+			options: []string{"bind", "ro"},
+			expCmds: [][]string{
+				{"nsenter", "--mount=" + testNamespace, "--", "mount", "-t", "ext4", "-o", "bind", "/dev/xvdba", "/mnt/target"},
+				{"nsenter", "--mount=" + testNamespace, "--", "mount", "-t", "ext4", "-o", "bind,remount,ro", "/dev/xvdba", "/mnt/target"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			outputs := make([]string, len(tc.expCmds))
+			errs := make([]error, len(tc.expCmds))
+			m, cmds := newFakeNamespaceMounter(outputs, errs)
+
+			err := m.Mount("/dev/xvdba", "/mnt/target", "ext4", tc.options)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expCmds, *cmds)
+		})
+	}
+}
+
+func TestNamespaceMounterUnmount(t *testing.T) {
+	m, cmds := newFakeNamespaceMounter([]string{"umount: /mnt/target: not mounted."}, []error{errors.New("exit status 32")})
+
+	err := m.Unmount("/mnt/target")
+	assert.ErrorContains(t, err, "not mounted")
+	assert.Equal(t, [][]string{{"nsenter", "--mount=" + testNamespace, "--", "umount", "/mnt/target"}}, *cmds)
+}
+
+func TestNamespaceMounterList(t *testing.T) {
+	mounts := `proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/nvme1n1 /var/lib/kubelet/plugins/kubernetes.io/csi/ebs.csi.aws.com/staging/mount ext4 rw,relatime 0 0
+/dev/nvme1n1 /var/lib/kubelet/pods/pod/volumes/kubernetes.io~csi/pv/mount ext4 rw,relatime 0 0
+`
+	m, cmds := newFakeNamespaceMounter([]string{mounts, mounts}, []error{nil, nil})
+
+	mps, err := m.List()
+	assert.NoError(t, err)
+	assert.Len(t, mps, 3)
+	assert.Equal(t, "/dev/nvme1n1", mps[1].Device)
+	assert.Equal(t, []string{"rw", "relatime"}, mps[1].Opts)
+	assert.Equal(t, []string{"nsenter", "--mount=" + testNamespace, "--", "cat", "/proc/mounts"}, (*cmds)[0])
+
+	refs, err := m.GetMountRefs("/var/lib/kubelet/plugins/kubernetes.io/csi/ebs.csi.aws.com/staging/mount")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/var/lib/kubelet/pods/pod/volumes/kubernetes.io~csi/pv/mount"}, refs)
+}
+
+func TestParseMounts(t *testing.T) {
+	_, err := parseMounts([]byte("/dev/nvme1n1 /mnt ext4 rw\n"))
+	assert.Error(t, err)
+
+	mps, err := parseMounts([]byte(`/dev/nvme1n1 /mnt/with\040space\011tab\134backslash ext4 rw,relatime 0 0
+`))
+	assert.NoError(t, err)
+	assert.Len(t, mps, 1)
+	assert.Equal(t, "/mnt/with space\ttab\\backslash", mps[0].Path)
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	testCases := []struct {
+		field    string
+		expected string
+	}{
+		{field: "/mnt/plain", expected: "/mnt/plain"},
+		{field: `/mnt/a\040b`, expected: "/mnt/a b"},
+		{field: `\040`, expected: " "},
+		{field: `/mnt/a\012b`, expected: "/mnt/a\nb"},
+		{field: `/mnt/trailing\04`, expected: `/mnt/trailing\04`},
+		{field: `/mnt/not\octal\089`, expected: `/mnt/not\octal\089`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.field, func(t *testing.T) {
+			assert.Equal(t, tc.expected, unescapeMountField(tc.field))
+		})
+	}
+}
+
+func TestNamespaceMounterIsMountPoint(t *testing.T) {
+	dir := t.TempDir()
+	m, _ := newFakeNamespaceMounter([]string{"/dev/nvme1n1 " + dir + " ext4 rw 0 0\n"}, []error{nil})
+
+	mounted, err := m.IsMountPoint(dir)
+	assert.NoError(t, err)
+	assert.True(t, mounted)
+
+	_, err = m.IsMountPoint(dir + "/missing")
+	assert.Error(t, err)
+}
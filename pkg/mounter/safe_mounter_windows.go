@@ -510,3 +510,8 @@ func NewSafeMounter() (*mount_utils.SafeFormatAndMount, error) {
 		Exec:      utilexec.New(),
 	}, nil
 }
+
+// NewSafeMounterInNamespace is not supported on Windows, which has no mount namespaces.
+func NewSafeMounterInNamespace(_ string) (*mount_utils.SafeFormatAndMount, error) {
+	return nil, errors.New("mount namespaces are not supported on Windows")
+}
@@ -28,6 +28,7 @@ import (
 )
 
 const (
+	MiB = 1024 * 1024
 	GiB = 1024 * 1024 * 1024
 )
 